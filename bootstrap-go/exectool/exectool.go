@@ -0,0 +1,172 @@
+// Package exectool provides a sandboxed command execution tool for Inferable services.
+// It is the reusable core of the Go bootstrap: copy it as a template for your own tools,
+// or register it directly from a service.
+package exectool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/inferablehq/inferable/bootstrap-go/inflight"
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+// Config configures the exec tool.
+type Config struct {
+	// ToolName is the name the tool is registered under. Defaults to "exec".
+	ToolName string
+	// AllowedCommands lists the commands the agent may run. Defaults to ls and cat.
+	AllowedCommands []string
+	// PathPrefix is the prefix every argument must start with. Defaults to "./".
+	PathPrefix string
+	// Timeout bounds the duration of a single command. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+// DefaultConfig returns the configuration used by the bootstrap service.
+func DefaultConfig() Config {
+	return Config{
+		ToolName:        "exec",
+		AllowedCommands: []string{"ls", "cat"},
+		PathPrefix:      "./",
+		Timeout:         10 * time.Second,
+	}
+}
+
+// ConfigFromEnv returns the default configuration overridden by environment variables:
+// EXEC_TOOL_NAME, EXEC_ALLOWED_COMMANDS (comma separated), EXEC_PATH_PREFIX, and EXEC_TIMEOUT (e.g. "30s").
+func ConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+
+	if name := os.Getenv("EXEC_TOOL_NAME"); name != "" {
+		config.ToolName = name
+	}
+
+	if commands := os.Getenv("EXEC_ALLOWED_COMMANDS"); commands != "" {
+		config.AllowedCommands = nil
+		for _, command := range strings.Split(commands, ",") {
+			if command = strings.TrimSpace(command); command != "" {
+				config.AllowedCommands = append(config.AllowedCommands, command)
+			}
+		}
+	}
+
+	if prefix := os.Getenv("EXEC_PATH_PREFIX"); prefix != "" {
+		config.PathPrefix = prefix
+	}
+
+	if timeout := os.Getenv("EXEC_TIMEOUT"); timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid EXEC_TIMEOUT: %v", err)
+		}
+		config.Timeout = duration
+	}
+
+	return config, config.Validate()
+}
+
+// Validate checks that the configuration can be used to register the tool.
+func (c Config) Validate() error {
+	if c.ToolName == "" {
+		return fmt.Errorf("tool name is required")
+	}
+	if len(c.AllowedCommands) == 0 {
+		return fmt.Errorf("at least one allowed command is required")
+	}
+	if c.PathPrefix == "" {
+		return fmt.Errorf("path prefix is required")
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive, got %v", c.Timeout)
+	}
+	return nil
+}
+
+// Input is the input of the exec tool.
+type Input struct {
+	Command string `json:"command"`
+	Arg     string `json:"arg"`
+}
+
+// Output is the result of the exec tool.
+type Output struct {
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+	Error  string `json:"error"`
+}
+
+// Register registers the exec tool on the client, tracking its in-flight calls in calls. Call
+// client.Tools.Listen() afterwards to start serving it.
+func Register(client *inferable.Inferable, config Config, calls *inflight.Calls) error {
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid exec tool config: %v", err)
+	}
+
+	exec := New(config)
+	err := client.Tools.Register(inferable.Tool{
+		Func: func(input Input, ctx inferable.ContextInput) Output {
+			defer calls.Start()()
+			return exec(input, ctx)
+		},
+		Name:        config.ToolName,
+		Description: fmt.Sprintf("Executes a system command. Allowed commands: %s", strings.Join(config.AllowedCommands, ", ")),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register exec tool: %v", err)
+	}
+
+	return nil
+}
+
+// New returns the exec tool function for the configuration.
+// Disallowed or failing commands are reported in Output.Error so the agent can correct itself.
+func New(config Config) func(input Input, ctx inferable.ContextInput) Output {
+	allowed := map[string]bool{}
+	for _, command := range config.AllowedCommands {
+		allowed[command] = true
+	}
+
+	sorted := append([]string{}, config.AllowedCommands...)
+	sort.Strings(sorted)
+
+	return func(input Input, ctx inferable.ContextInput) Output {
+		if !allowed[input.Command] {
+			return Output{
+				Error: fmt.Sprintf("command not allowed: %s. Allowed commands: %v", input.Command, sorted),
+			}
+		}
+
+		// Only allow access to paths starting with the configured prefix
+		if !strings.HasPrefix(input.Arg, config.PathPrefix) || strings.Contains(input.Arg, "..") {
+			return Output{
+				Error: fmt.Sprintf("requires arg starting with '%s' and not containing '..'. Example: '%s %s'", config.PathPrefix, sorted[0], config.PathPrefix),
+			}
+		}
+
+		timeout, cancel := context.WithTimeout(context.Background(), config.Timeout)
+		defer cancel()
+
+		cmd := exec.CommandContext(timeout, input.Command, input.Arg)
+		stdout, err := cmd.Output()
+		if err != nil {
+			var stderr string
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				stderr = string(exitErr.Stderr)
+			}
+			return Output{
+				Stderr: stderr,
+				Error:  fmt.Sprintf("command failed: %s: %v", input.Command, err),
+			}
+		}
+
+		return Output{
+			Stdout: strings.TrimSpace(string(stdout)),
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package exectool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	config, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConfig(), config)
+
+	t.Setenv("EXEC_TOOL_NAME", "shell")
+	t.Setenv("EXEC_ALLOWED_COMMANDS", "ls, head ,")
+	t.Setenv("EXEC_PATH_PREFIX", "./data/")
+	t.Setenv("EXEC_TIMEOUT", "30s")
+
+	config, err = ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		ToolName:        "shell",
+		AllowedCommands: []string{"ls", "head"},
+		PathPrefix:      "./data/",
+		Timeout:         30 * time.Second,
+	}, config)
+
+	t.Setenv("EXEC_TIMEOUT", "soon")
+	_, err = ConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestExec(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "hello.txt"), []byte("hello\n"), 0o644))
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	exec := New(DefaultConfig())
+
+	output := exec(Input{Command: "cat", Arg: "./hello.txt"}, inferable.ContextInput{})
+	assert.Equal(t, Output{Stdout: "hello"}, output)
+
+	output = exec(Input{Command: "rm", Arg: "./hello.txt"}, inferable.ContextInput{})
+	assert.Contains(t, output.Error, "command not allowed: rm")
+
+	output = exec(Input{Command: "cat", Arg: "/etc/passwd"}, inferable.ContextInput{})
+	assert.Contains(t, output.Error, "requires arg starting with './'")
+
+	output = exec(Input{Command: "cat", Arg: "./../secret"}, inferable.ContextInput{})
+	assert.NotEmpty(t, output.Error)
+
+	output = exec(Input{Command: "cat", Arg: "./missing.txt"}, inferable.ContextInput{})
+	assert.Contains(t, output.Error, "command failed: cat")
+	assert.NotEmpty(t, output.Stderr)
+}
@@ -0,0 +1,337 @@
+// Package hntool provides Hacker News tools for Inferable services, backed by the typed
+// Hacker News Firebase API instead of scraped pages, so that agents work on structured fields
+// such as the title, score and comment count of each story.
+package hntool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inferablehq/inferable/bootstrap-go/inflight"
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+const (
+	// DefaultBaseURL is the Hacker News Firebase API.
+	DefaultBaseURL = "https://hacker-news.firebaseio.com/v0"
+	// DefaultPageSize is the number of stories or comments returned per page when none is requested.
+	DefaultPageSize = 10
+	// MaxPageSize bounds the number of items fetched per call.
+	MaxPageSize = 30
+)
+
+// ErrNotFound is returned for items that do not exist.
+var ErrNotFound = errors.New("item not found")
+
+// Config configures the Hacker News tools.
+type Config struct {
+	// BaseURL is the API endpoint. Defaults to DefaultBaseURL.
+	BaseURL string
+	// CacheTTL is how long story lists and items are cached. Defaults to 5 minutes.
+	CacheTTL time.Duration
+	// Timeout bounds a call of the tools, including all its API requests. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+// DefaultConfig returns the configuration used by the bootstrap service.
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:  DefaultBaseURL,
+		CacheTTL: 5 * time.Minute,
+		Timeout:  10 * time.Second,
+	}
+}
+
+// ConfigFromEnv returns the default configuration overridden by environment variables:
+// HN_API_URL, HN_CACHE_TTL (e.g. "1m"), and HN_TIMEOUT (e.g. "30s").
+func ConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+
+	if url := os.Getenv("HN_API_URL"); url != "" {
+		config.BaseURL = strings.TrimSuffix(url, "/")
+	}
+
+	if ttl := os.Getenv("HN_CACHE_TTL"); ttl != "" {
+		duration, err := time.ParseDuration(ttl)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid HN_CACHE_TTL: %v", err)
+		}
+		config.CacheTTL = duration
+	}
+
+	if timeout := os.Getenv("HN_TIMEOUT"); timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid HN_TIMEOUT: %v", err)
+		}
+		config.Timeout = duration
+	}
+
+	return config, config.Validate()
+}
+
+// Validate checks that the configuration can be used to register the tools.
+func (c Config) Validate() error {
+	if c.BaseURL == "" {
+		return fmt.Errorf("base URL is required")
+	}
+	if c.CacheTTL < 0 {
+		return fmt.Errorf("cache TTL must not be negative, got %v", c.CacheTTL)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("timeout must be positive, got %v", c.Timeout)
+	}
+	return nil
+}
+
+// Item is a story, comment, job or poll of the Hacker News API.
+type Item struct {
+	ID    int    `json:"id"`
+	Type  string `json:"type"`
+	By    string `json:"by,omitempty"`
+	Time  int64  `json:"time,omitempty"`
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Text  string `json:"text,omitempty"`
+	Score int    `json:"score,omitempty"`
+	// Comments is the total number of comments of a story, "descendants" in the API.
+	Comments int   `json:"descendants,omitempty"`
+	Kids     []int `json:"kids,omitempty"`
+	Parent   int   `json:"parent,omitempty"`
+	Deleted  bool  `json:"deleted,omitempty"`
+	Dead     bool  `json:"dead,omitempty"`
+}
+
+// TopStoriesInput is the input of the top stories tool.
+type TopStoriesInput struct {
+	// Page is the zero-based page of the ranking.
+	Page int `json:"page"`
+	// PageSize is the number of stories per page, up to MaxPageSize.
+	PageSize int `json:"pageSize"`
+}
+
+// TopStoriesOutput is a page of the top stories, in ranking order.
+type TopStoriesOutput struct {
+	Stories []Item `json:"stories"`
+	Page    int    `json:"page"`
+	HasMore bool   `json:"hasMore"`
+}
+
+// ItemInput is the input of the item tool.
+type ItemInput struct {
+	ID int `json:"id"`
+	// Page is the zero-based page of the item's direct comments.
+	Page int `json:"page"`
+	// PageSize is the number of comments per page, up to MaxPageSize.
+	PageSize int `json:"pageSize"`
+}
+
+// ItemOutput is an item with a page of its direct comments.
+type ItemOutput struct {
+	Item     Item   `json:"item"`
+	Comments []Item `json:"comments"`
+	Page     int    `json:"page"`
+	HasMore  bool   `json:"hasMore"`
+}
+
+// Client reads the Hacker News API, caching story lists and items for Config.CacheTTL.
+type Client struct {
+	config Config
+	http   *http.Client
+	now    func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	value   json.RawMessage
+	expires time.Time
+}
+
+// NewClient returns a client for the configuration.
+func NewClient(config Config) *Client {
+	return &Client{
+		config: config,
+		http:   &http.Client{Timeout: config.Timeout},
+		now:    time.Now,
+		cache:  map[string]cacheEntry{},
+	}
+}
+
+// TopStories returns a page of the current top stories, leaving out deleted and dead ones.
+func (c *Client) TopStories(ctx context.Context, input TopStoriesInput) (TopStoriesOutput, error) {
+	page, pageSize, err := pagination(input.Page, input.PageSize)
+	if err != nil {
+		return TopStoriesOutput{}, err
+	}
+
+	var ids []int
+	if err := c.get(ctx, "topstories", &ids); err != nil {
+		return TopStoriesOutput{}, err
+	}
+
+	stories, hasMore, err := c.page(ctx, ids, page, pageSize)
+	if err != nil {
+		return TopStoriesOutput{}, err
+	}
+	return TopStoriesOutput{Stories: stories, Page: page, HasMore: hasMore}, nil
+}
+
+// Item returns an item with a page of its direct comments, leaving out deleted and dead ones.
+func (c *Client) Item(ctx context.Context, input ItemInput) (ItemOutput, error) {
+	page, pageSize, err := pagination(input.Page, input.PageSize)
+	if err != nil {
+		return ItemOutput{}, err
+	}
+
+	item, err := c.item(ctx, input.ID)
+	if err != nil {
+		return ItemOutput{}, err
+	}
+
+	comments, hasMore, err := c.page(ctx, item.Kids, page, pageSize)
+	if err != nil {
+		return ItemOutput{}, err
+	}
+	return ItemOutput{Item: item, Comments: comments, Page: page, HasMore: hasMore}, nil
+}
+
+func pagination(page int, pageSize int) (int, int, error) {
+	if page < 0 {
+		return 0, 0, fmt.Errorf("page must not be negative, got %d", page)
+	}
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize < 0 || pageSize > MaxPageSize {
+		return 0, 0, fmt.Errorf("page size must be between 1 and %d, got %d", MaxPageSize, pageSize)
+	}
+	return page, pageSize, nil
+}
+
+// page fetches the items of a page of ids concurrently, in the order of ids.
+func (c *Client) page(ctx context.Context, ids []int, page int, pageSize int) ([]Item, bool, error) {
+	start := page * pageSize
+	if start >= len(ids) {
+		return []Item{}, false, nil
+	}
+	end := min(start+pageSize, len(ids))
+
+	items := make([]Item, end-start)
+	errs := make([]error, end-start)
+	var wg sync.WaitGroup
+	for n, id := range ids[start:end] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			items[n], errs[n] = c.item(ctx, id)
+		}()
+	}
+	wg.Wait()
+
+	visible := []Item{}
+	for n, item := range items {
+		if errors.Is(errs[n], ErrNotFound) || item.Deleted || item.Dead {
+			continue
+		}
+		if errs[n] != nil {
+			return nil, false, errs[n]
+		}
+		visible = append(visible, item)
+	}
+	return visible, end < len(ids), nil
+}
+
+func (c *Client) item(ctx context.Context, id int) (Item, error) {
+	var item *Item
+	if err := c.get(ctx, fmt.Sprintf("item/%d", id), &item); err != nil {
+		return Item{}, err
+	}
+	// The API answers null for items that do not exist
+	if item == nil {
+		return Item{}, fmt.Errorf("%w: %d", ErrNotFound, id)
+	}
+	return *item, nil
+}
+
+// get decodes the API resource at path into value, from the cache if it has not expired.
+func (c *Client) get(ctx context.Context, path string, value interface{}) error {
+	c.mu.Lock()
+	entry, ok := c.cache[path]
+	c.mu.Unlock()
+
+	if !ok || !c.now().Before(entry.expires) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s.json", c.config.BaseURL, path), nil)
+		if err != nil {
+			return err
+		}
+		response, err := c.http.Do(request)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s: %v", path, err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to fetch %s: status %d", path, response.StatusCode)
+		}
+
+		var body json.RawMessage
+		if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+			return fmt.Errorf("failed to decode %s: %v", path, err)
+		}
+
+		entry = cacheEntry{value: body, expires: c.now().Add(c.config.CacheTTL)}
+		c.mu.Lock()
+		c.cache[path] = entry
+		c.mu.Unlock()
+	}
+
+	return json.Unmarshal(entry.value, value)
+}
+
+// Register registers the hackerNewsTopStories and hackerNewsItem tools on the client, tracking
+// their in-flight calls in calls. Call client.Tools.Listen() afterwards to start serving them.
+func Register(client *inferable.Inferable, config Config, calls *inflight.Calls) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid Hacker News tool config: %v", err)
+	}
+
+	hn := NewClient(config)
+
+	err := client.Tools.Register(inferable.Tool{
+		Func: func(input TopStoriesInput, ctx inferable.ContextInput) (TopStoriesOutput, error) {
+			defer calls.Start()()
+			callCtx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+			defer cancel()
+			return hn.TopStories(callCtx, input)
+		},
+		Name:        "hackerNewsTopStories",
+		Description: fmt.Sprintf("Lists the current Hacker News top stories in ranking order, with their title, URL, score and comment count. Paginated with page (from 0) and pageSize (up to %d).", MaxPageSize),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register Hacker News top stories tool: %v", err)
+	}
+
+	err = client.Tools.Register(inferable.Tool{
+		Func: func(input ItemInput, ctx inferable.ContextInput) (ItemOutput, error) {
+			defer calls.Start()()
+			callCtx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+			defer cancel()
+			return hn.Item(callCtx, input)
+		},
+		Name:        "hackerNewsItem",
+		Description: fmt.Sprintf("Fetches a Hacker News story or comment by ID, with a page of its direct comments. Paginated with page (from 0) and pageSize (up to %d).", MaxPageSize),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register Hacker News item tool: %v", err)
+	}
+
+	return hn, nil
+}
@@ -0,0 +1,152 @@
+package hntool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestAPI serves the responses by path, counting the requests of each.
+func newTestAPI(t *testing.T, responses map[string]string) (*httptest.Server, map[string]int, *sync.Mutex) {
+	requests := map[string]int{}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests, &mu
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	config, err := ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConfig(), config)
+
+	t.Setenv("HN_API_URL", "http://localhost:8080/v0/")
+	t.Setenv("HN_CACHE_TTL", "1m")
+	t.Setenv("HN_TIMEOUT", "30s")
+
+	config, err = ConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, Config{BaseURL: "http://localhost:8080/v0", CacheTTL: time.Minute, Timeout: 30 * time.Second}, config)
+
+	t.Setenv("HN_CACHE_TTL", "soon")
+	_, err = ConfigFromEnv()
+	assert.Error(t, err)
+}
+
+func TestTopStories(t *testing.T) {
+	server, requests, mu := newTestAPI(t, map[string]string{
+		"/topstories.json": `[1, 2, 3, 4]`,
+		"/item/1.json":     `{"id": 1, "type": "story", "title": "First", "url": "https://example.com/1", "score": 120, "descendants": 42}`,
+		"/item/2.json":     `{"id": 2, "type": "story", "deleted": true}`,
+		"/item/3.json":     `{"id": 3, "type": "story", "title": "Third", "score": 30, "descendants": 5}`,
+		"/item/4.json":     `null`,
+	})
+
+	config := DefaultConfig()
+	config.BaseURL = server.URL
+	hn := NewClient(config)
+
+	page, err := hn.TopStories(context.Background(), TopStoriesInput{PageSize: 3})
+	require.NoError(t, err)
+	assert.True(t, page.HasMore)
+	require.Len(t, page.Stories, 2)
+	assert.Equal(t, Item{ID: 1, Type: "story", Title: "First", URL: "https://example.com/1", Score: 120, Comments: 42}, page.Stories[0])
+	assert.Equal(t, "Third", page.Stories[1].Title)
+
+	page, err = hn.TopStories(context.Background(), TopStoriesInput{Page: 1, PageSize: 3})
+	require.NoError(t, err)
+	assert.False(t, page.HasMore)
+	assert.Empty(t, page.Stories)
+
+	page, err = hn.TopStories(context.Background(), TopStoriesInput{Page: 5})
+	require.NoError(t, err)
+	assert.Empty(t, page.Stories)
+
+	// The ranking and items are cached
+	mu.Lock()
+	assert.Equal(t, 1, requests["/topstories.json"])
+	assert.Equal(t, 1, requests["/item/1.json"])
+	mu.Unlock()
+
+	_, err = hn.TopStories(context.Background(), TopStoriesInput{PageSize: MaxPageSize + 1})
+	assert.Error(t, err)
+}
+
+func TestItem(t *testing.T) {
+	server, requests, mu := newTestAPI(t, map[string]string{
+		"/item/1.json":  `{"id": 1, "type": "story", "title": "First", "score": 120, "descendants": 3, "kids": [10, 11, 12]}`,
+		"/item/10.json": `{"id": 10, "type": "comment", "by": "alice", "text": "Nice", "parent": 1}`,
+		"/item/11.json": `{"id": 11, "type": "comment", "dead": true, "parent": 1}`,
+		"/item/12.json": `{"id": 12, "type": "comment", "by": "bob", "text": "Agreed", "parent": 1}`,
+		"/item/2.json":  `null`,
+	})
+
+	config := DefaultConfig()
+	config.BaseURL = server.URL
+	hn := NewClient(config)
+	now := time.Now()
+	hn.now = func() time.Time { return now }
+
+	item, err := hn.Item(context.Background(), ItemInput{ID: 1, PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "First", item.Item.Title)
+	assert.Equal(t, 3, item.Item.Comments)
+	assert.True(t, item.HasMore)
+	require.Len(t, item.Comments, 1)
+	assert.Equal(t, "alice", item.Comments[0].By)
+
+	item, err = hn.Item(context.Background(), ItemInput{ID: 1, Page: 1, PageSize: 2})
+	require.NoError(t, err)
+	assert.False(t, item.HasMore)
+	require.Len(t, item.Comments, 1)
+	assert.Equal(t, "bob", item.Comments[0].By)
+
+	_, err = hn.Item(context.Background(), ItemInput{ID: 2})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Expired entries are fetched again
+	now = now.Add(config.CacheTTL)
+	_, err = hn.Item(context.Background(), ItemInput{ID: 1})
+	require.NoError(t, err)
+	mu.Lock()
+	assert.Equal(t, 2, requests["/item/1.json"])
+	mu.Unlock()
+}
+
+func TestCallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	config := DefaultConfig()
+	config.BaseURL = server.URL
+	hn := NewClient(config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := hn.TopStories(ctx, TopStoriesInput{})
+	assert.ErrorContains(t, err, "context deadline exceeded")
+}
@@ -0,0 +1,64 @@
+// Package inflight tracks the in-flight calls of the tools of a service, so that it can wait for
+// them to complete on shutdown.
+package inflight
+
+import (
+	"context"
+	"sync"
+)
+
+// Calls counts the in-flight calls of the tools registered with it. The zero value is ready to
+// use, and a nil *Calls does not track calls.
+type Calls struct {
+	mu    sync.Mutex
+	count int
+	// idle is closed when the last in-flight call completes.
+	idle chan struct{}
+}
+
+// Start records the start of a call, and returns the function that records its completion.
+func (c *Calls) Start() (done func()) {
+	if c == nil {
+		return func() {}
+	}
+	c.mu.Lock()
+	if c.count == 0 {
+		c.idle = make(chan struct{})
+	}
+	c.count++
+	c.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			c.count--
+			if c.count == 0 {
+				close(c.idle)
+			}
+			c.mu.Unlock()
+		})
+	}
+}
+
+// Wait blocks until in-flight calls have completed, or returns ctx.Err() if ctx is done first.
+// Call it after client.Tools.Unlisten() so that no new calls are started.
+func (c *Calls) Wait(ctx context.Context) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	if c.count == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	idle := c.idle
+	c.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,39 @@
+package inflight
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWait(t *testing.T) {
+	var calls Calls
+	assert.NoError(t, calls.Wait(context.Background()))
+
+	first := calls.Start()
+	second := calls.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, calls.Wait(ctx), context.DeadlineExceeded)
+
+	first()
+	// Completing a call twice does not complete another
+	first()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, calls.Wait(ctx), context.DeadlineExceeded)
+
+	second()
+	assert.NoError(t, calls.Wait(context.Background()))
+
+	// Calls can start again once idle
+	done := calls.Start()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		done()
+	}()
+	assert.NoError(t, calls.Wait(context.Background()))
+}
@@ -1,67 +1,44 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"os"
-	"os/exec"
-	"strings"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/inferablehq/inferable/bootstrap-go/exectool"
+	"github.com/inferablehq/inferable/bootstrap-go/hntool"
+	"github.com/inferablehq/inferable/bootstrap-go/inflight"
 	inferable "github.com/inferablehq/inferable/sdk-go"
 	"github.com/joho/godotenv"
 )
 
-type ExecOutput struct {
-	Stdout string `json:"stdout"`
-	Stderr string `json:"stderr"`
-	Error  string `json:"error"`
-}
+// shutdownTimeout bounds how long in-flight tool calls may take to complete on shutdown.
+const shutdownTimeout = 30 * time.Second
 
-func Exec(input struct {
-	Command string `json:"command"`
-	Arg     string `json:"arg"`
-}, ctx inferable.ContextInput) ExecOutput {
-	allowedCommands := map[string]bool{
-		"ls":  true,
-		"cat": true,
-	}
-	if !allowedCommands[input.Command] {
-		return ExecOutput{
-			Error: fmt.Sprintf("command not allowed: %s. Allowed commands: %v", input.Command, allowedCommands),
-		}
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
 	}
+}
 
-	// Only allow access to files starting with ./
-	if !strings.HasPrefix(input.Arg, "./") {
-		return ExecOutput{
-			Error: "requires arg starting with './'. Example: 'ls ./'",
-		}
+func run() error {
+	// Load vars from .env file, if present
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to load .env: %v", err)
 	}
 
-	cmd := exec.Command(input.Command, input.Arg)
-	stdout, err := cmd.Output()
+	config, err := exectool.ConfigFromEnv()
 	if err != nil {
-		var stderr string
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			stderr = string(exitErr.Stderr)
-		}
-		return ExecOutput{
-			Stdout: "",
-			Stderr: stderr,
-			Error:  fmt.Sprintf("command failed: %s", input.Command),
-		}
+		return err
 	}
 
-	return ExecOutput{
-		Stdout: strings.TrimSpace(string(stdout)),
-		Stderr: "",
-	}
-}
-
-func main() {
-	// Load vars from .env file
-	err := godotenv.Load()
+	hnConfig, err := hntool.ConfigFromEnv()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	// Instantiate the Inferable client
@@ -69,29 +46,48 @@ func main() {
 		APISecret:   os.Getenv("INFERABLE_API_SECRET"),
 		APIEndpoint: os.Getenv("INFERABLE_API_ENDPOINT"),
 	})
-
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to create Inferable client: %v", err)
 	}
 
+	// The in-flight calls of all tools, which shutdown waits for
+	calls := &inflight.Calls{}
+
 	// Register the exec function
-	err = client.Tools.Register(inferable.Tool{
-		Func:        Exec,
-		Name:        "exec",
-		Description: "Executes a system command",
-	})
-	if err != nil {
-		panic(err)
+	if err := exectool.Register(client, config, calls); err != nil {
+		return err
 	}
 
-	err = client.Tools.Listen()
-	if err != nil {
-		panic(err)
+	// Register the Hacker News functions
+	if _, err := hntool.Register(client, hnConfig, calls); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := client.Tools.Listen(); err != nil {
+		return fmt.Errorf("failed to start listening: %v", err)
 	}
 
 	fmt.Println("Inferable service started")
 	fmt.Println("Press CTRL+C to stop")
 
-	// Wait for CTRL+C
-	<-make(chan struct{})
+	// Wait for CTRL+C or SIGTERM
+	<-ctx.Done()
+	stop()
+
+	// Stop accepting new calls, then let in-flight calls finish so their results are not lost
+	fmt.Println("Shutting down, waiting for in-flight calls to complete")
+	client.Tools.Unlisten()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := calls.Wait(shutdownCtx); err != nil {
+		return fmt.Errorf("in-flight calls did not complete within %v", shutdownTimeout)
+	}
+
+	fmt.Println("Inferable service stopped")
+	return nil
 }
@@ -0,0 +1 @@
+ALTER TABLE "cluster_kv" ADD COLUMN "expires_at" timestamp with time zone;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE "cluster_kv" ADD COLUMN "version" integer DEFAULT 1 NOT NULL;
\ No newline at end of file
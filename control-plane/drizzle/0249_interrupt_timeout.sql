@@ -0,0 +1,3 @@
+ALTER TABLE "jobs" ADD COLUMN "interrupt_expires_at" timestamp with time zone;--> statement-breakpoint
+ALTER TABLE "jobs" ADD COLUMN "interrupt_on_timeout" text;--> statement-breakpoint
+ALTER TABLE "jobs" ADD COLUMN "interrupt_timed_out" boolean DEFAULT false NOT NULL;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE "jobs" ADD COLUMN "approval_presentation" json;--> statement-breakpoint
+ALTER TABLE "jobs" ADD COLUMN "approval_option" text;
\ No newline at end of file
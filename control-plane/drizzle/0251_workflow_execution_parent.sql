@@ -0,0 +1 @@
+ALTER TABLE "workflow_executions" ADD COLUMN "parent_execution_id" varchar(1024);
\ No newline at end of file
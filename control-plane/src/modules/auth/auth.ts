@@ -78,6 +78,8 @@ export const plugin = fastifyPlugin(async (fastify: FastifyInstance) => {
     "POST:/ephemeral-setup",
     "GET:/live",
     "GET:/contract",
+    // Resume tokens are their own credential
+    "POST:/resume-tokens/:token",
   ]);
 
   // Pre-handler hook to extract the auth state from the request and add it to the "auth" decorator property
@@ -36,10 +36,37 @@ export const notificationSchema = z.object({
   message: z.string().optional(),
 });
 
+export const approvalPresentationSchema = z.object({
+  // Choices offered instead of approve and deny. Options with deny set deny the call.
+  options: z
+    .array(
+      z.object({
+        id: z.string().min(1).max(64),
+        label: z.string().max(128),
+        deny: z.boolean().optional(),
+      }),
+    )
+    .max(10)
+    .optional(),
+  links: z
+    .array(
+      z.object({
+        label: z.string().max(128),
+        url: z.string().url(),
+      }),
+    )
+    .max(10)
+    .optional(),
+});
+
 export const interruptSchema = z.discriminatedUnion("type", [
   z.object({
     type: z.enum(["approval", "general"]),
     notification: notificationSchema.optional(),
+    // Resolves the interrupt with onTimeout if nobody responds within timeoutSeconds
+    timeoutSeconds: z.number().int().positive().optional(),
+    onTimeout: z.enum(["fail", "approve", "resume"]).optional(),
+    ...approvalPresentationSchema.shape,
   }),
 ]);
 
@@ -271,6 +298,23 @@ export const ToolConfigSchema = z.object({
   retryCountOnStall: z.number().optional(),
   timeoutSeconds: z.number().optional(),
   private: z.boolean().default(false).optional(),
+  tags: z.array(z.string().max(64)).max(20).optional(),
+  examples: z
+    .array(
+      z.object({
+        description: z.string().max(256).optional(),
+        input: z.record(z.unknown()),
+      }),
+    )
+    .max(10)
+    .optional(),
+  usageHints: z.string().max(1024).optional(),
+  capability: z
+    .enum(["read", "mutate"])
+    .optional()
+    .describe(
+      "Whether the tool only reads or also changes state. Tools that do not declare a capability are treated as mutating",
+    ),
 });
 
 const RunSchema = z.object({
@@ -314,6 +358,16 @@ const RunSchema = z.object({
     .array(functionReference)
     .optional()
     .describe("DEPRECATED, use tools instead"),
+  deniedTools: z
+    .array(z.string())
+    .optional()
+    .describe("An array of tool names to withhold from the run, even if listed in tools"),
+  toolCapabilities: z
+    .array(z.enum(["read", "mutate"]))
+    .optional()
+    .describe(
+      "Only make tools declaring one of these capabilities available to the run, e.g. ['read'] for read-only runs",
+    ),
   onStatusChange: onStatusChangeSchema
     .optional()
     .describe(
@@ -397,6 +451,8 @@ export const definition = {
         createdAt: z.date(),
         approved: z.boolean().nullable(),
         approvalRequested: z.boolean().nullable(),
+        approvalPresentation: approvalPresentationSchema.nullable(),
+        approvalOption: z.string().nullable(),
       }),
     },
   },
@@ -522,6 +578,8 @@ export const definition = {
           authContext: z.any().nullable(),
           runContext: z.any().nullable(),
           approved: z.boolean(),
+          interruptTimedOut: z.boolean().optional(),
+          approvalOption: z.string().nullable().optional(),
         }),
       ),
     },
@@ -538,12 +596,17 @@ export const definition = {
     }),
     responses: {
       204: z.undefined(),
+      400: z.object({
+        message: z.string(),
+      }),
       404: z.object({
         message: z.string(),
       }),
     },
     body: z.object({
       approved: z.boolean(),
+      // One of the options of the approval interrupt, delivered to the handler
+      option: z.string().optional(),
     }),
   },
 
@@ -1216,6 +1279,20 @@ export const definition = {
       clusterId: z.string(),
       workflowName: z.string(),
     }),
+    query: z.object({
+      parentExecutionId: z
+        .string()
+        .optional()
+        .describe(
+          "The workflow execution that triggered this one, which lists it among its children",
+        ),
+      resumeJobId: z
+        .string()
+        .optional()
+        .describe(
+          "The interrupted tool call that delegated to this execution, which is resumed once the execution resolves or is rejected",
+        ),
+    }),
     body: z
       .object({
         executionId: z.string(),
@@ -1266,6 +1343,39 @@ export const definition = {
     },
   },
 
+  createResumeToken: {
+    method: "POST",
+    path: "/clusters/:clusterId/workflows/:workflowName/executions/:executionId/resume-tokens",
+    headers: z.object({ authorization: z.string() }),
+    pathParams: z.object({
+      workflowName: z.string(),
+      clusterId: z.string(),
+      executionId: z.string(),
+    }),
+    body: z.object({
+      name: z.string().min(1).max(256),
+    }),
+    responses: {
+      201: z.object({
+        token: z.string(),
+      }),
+    },
+  },
+
+  redeemResumeToken: {
+    method: "POST",
+    path: "/resume-tokens/:token",
+    pathParams: z.object({
+      token: z.string(),
+    }),
+    body: z.object({
+      payload: z.unknown().optional(),
+    }),
+    responses: {
+      204: z.undefined(),
+    },
+  },
+
   createWorkflowNotification: {
     method: "POST",
     path: "/clusters/:clusterId/workflows/:workflowName/executions/:executionId/notification",
@@ -1354,6 +1464,64 @@ export const definition = {
     },
   },
 
+  listWorkflowExecutionChildren: {
+    method: "GET",
+    path: "/clusters/:clusterId/workflow-executions/:executionId/children",
+    headers: z.object({ authorization: z.string() }),
+    pathParams: z.object({
+      clusterId: z.string(),
+      executionId: z.string(),
+    }),
+    responses: {
+      404: z.undefined(),
+      200: z.object({
+        executions: z.array(
+          z.object({
+            id: z.string(),
+            workflowName: z.string(),
+            workflowVersion: z.number(),
+            status: z.string().nullable(),
+            createdAt: z.date(),
+          }),
+        ),
+        runs: z.array(
+          z.object({
+            id: z.string(),
+            name: z.string(),
+            status: z
+              .enum(["pending", "running", "paused", "done", "failed"])
+              .nullable(),
+            createdAt: z.date(),
+          }),
+        ),
+      }),
+    },
+  },
+
+  cancelWorkflowExecution: {
+    method: "POST",
+    path: "/clusters/:clusterId/workflow-executions/:executionId/cancel",
+    headers: z.object({ authorization: z.string() }),
+    pathParams: z.object({
+      clusterId: z.string(),
+      executionId: z.string(),
+    }),
+    body: z
+      .object({
+        cascade: z
+          .boolean()
+          .optional()
+          .describe(
+            "Also cancel the execution's agent runs and child executions, recursively",
+          ),
+      })
+      .optional(),
+    responses: {
+      204: z.undefined(),
+      404: z.undefined(),
+    },
+  },
+
   getWorkflowExecutionTimeline: {
     method: "GET",
     path: "/clusters/:clusterId/workflows/:workflowName/executions/:executionId/timeline",
@@ -1441,15 +1609,60 @@ export const definition = {
     body: z.object({
       onConflict: z.enum(["replace", "doNothing"]),
       value: z.string(),
+      expiresAt: z.coerce.date().optional(),
+      // Only write if the key is at this version, 0 meaning absent. Takes precedence over onConflict.
+      ifVersion: z.number().int().min(0).optional(),
     }),
     headers: z.object({ authorization: z.string() }),
     responses: {
       200: z.object({
         value: z.string(),
+        version: z.number().optional(),
+      }),
+      409: z.object({
+        message: z.string(),
       }),
     },
   },
 
+  listClusterKV: {
+    method: "GET",
+    path: "/clusters/:clusterId/keys",
+    pathParams: z.object({
+      clusterId: z.string(),
+    }),
+    query: z.object({
+      prefix: z.string().min(1),
+      limit: z.coerce.number().min(1).max(1000).default(100),
+      after: z.string().optional(),
+    }),
+    headers: z.object({ authorization: z.string() }),
+    responses: {
+      200: z.array(
+        z.object({
+          key: z.string(),
+          value: z.string(),
+          createdAt: z.date(),
+          expiresAt: z.date().nullable(),
+        }),
+      ),
+    },
+  },
+
+  deleteClusterKV: {
+    method: "DELETE",
+    path: "/clusters/:clusterId/keys/:key",
+    pathParams: z.object({
+      clusterId: z.string(),
+      key: z.string(),
+    }),
+    headers: z.object({ authorization: z.string() }),
+    body: z.undefined(),
+    responses: {
+      204: z.undefined(),
+    },
+  },
+
   getClusterKV: {
     method: "GET",
     path: "/clusters/:clusterId/keys/:key/value",
@@ -1461,6 +1674,7 @@ export const definition = {
     responses: {
       200: z.object({
         value: z.string(),
+        version: z.number().optional(),
       }),
     },
   },
@@ -1475,6 +1689,9 @@ export const definition = {
     pathParams: z.object({
       clusterId: z.string(),
     }),
+    query: z.object({
+      tag: z.string().optional(),
+    }),
     responses: {
       200: z.array(
         z.object({
@@ -1503,6 +1720,18 @@ export const definition = {
       input: z.string(),
       instructions: z.string().optional(),
       schema: z.record(z.any()),
+      generation: z
+        .object({
+          temperature: z.number().min(0).max(2).optional(),
+          topP: z.number().gt(0).max(1).optional(),
+          maxTokens: z.number().int().positive().optional(),
+          stopSequences: z.array(z.string()).optional(),
+          seed: z.number().int().optional(),
+        })
+        .optional()
+        .describe(
+          "Sampling and length options. Only supported with the default provider.",
+        ),
     }),
     headers: z.object({
       authorization: z.string(),
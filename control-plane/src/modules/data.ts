@@ -19,7 +19,7 @@ import { Pool } from "pg";
 import { env } from "../utilities/env";
 import { logger } from "./observability/logger";
 import { z } from "zod";
-import { onStatusChangeSchema } from "./contract";
+import { approvalPresentationSchema, onStatusChangeSchema } from "./contract";
 import { ToolConfig } from "./tools";
 
 export const createMutex = advisoryLock(env.DATABASE_URL);
@@ -108,6 +108,21 @@ export const jobs = pgTable(
     run_context: json("run_context"),
     approval_requested: boolean("approval_requested").notNull().default(false),
     approved: boolean("approved"),
+    // Interrupts with a deadline are resolved by the self-heal job once it passes
+    interrupt_expires_at: timestamp("interrupt_expires_at", {
+      withTimezone: true,
+    }),
+    interrupt_on_timeout: text("interrupt_on_timeout", {
+      enum: ["fail", "approve", "resume"],
+    }),
+    interrupt_timed_out: boolean("interrupt_timed_out")
+      .notNull()
+      .default(false),
+    // Options and links shown by the UI resolving an approval, and the option chosen
+    approval_presentation: json("approval_presentation").$type<
+      z.infer<typeof approvalPresentationSchema>
+    >(),
+    approval_option: text("approval_option"),
   },
   table => ({
     pk: primaryKey({
@@ -461,6 +476,8 @@ export const workflowExecutions = pgTable(
       .defaultNow()
       .notNull(),
     deleted_at: timestamp("deleted_at", { withTimezone: true }),
+    parent_execution_id: varchar("parent_execution_id", { length: 1024 }),
+    resume_job_id: varchar("resume_job_id", { length: 1024 }),
   },
   table => ({
     pk: primaryKey({
@@ -479,6 +496,8 @@ export const clusterKV = pgTable(
     created_at: timestamp("created_at", { withTimezone: true })
       .defaultNow()
       .notNull(),
+    expires_at: timestamp("expires_at", { withTimezone: true }),
+    version: integer("version").default(1).notNull(),
   },
   table => ({
     pk: primaryKey({ columns: [table.cluster_id, table.key] }),
@@ -2,6 +2,7 @@ import { logger } from "../observability/logger";
 import { registerCron } from "../cron";
 import { db, clusters, events, runs, workflowExecutions, jobs } from "../data";
 import { isNotNull, sql, and, isNull, lt, eq } from "drizzle-orm";
+import { kv } from "../kv";
 
 // Define the interval for the cron jobs (e.g., daily)
 const CRON_INTERVAL = 15 * 60 * 1000; // 15 minutes
@@ -146,6 +147,19 @@ export const expireJobs = async () => {
   }
 };
 
+export const expireKV = async () => {
+  logger.info("Running expireKV cron job");
+  try {
+    const deleted = await kv.deleteExpired();
+
+    logger.info(`Deleted expired KV entries`, {
+      count: deleted,
+    });
+  } catch (error) {
+    logger.error("Error in expireKV cron job", { error });
+  }
+};
+
 /**
  * Starts the expiration cron jobs.
  */
@@ -165,4 +179,8 @@ export const start = async () => {
   await registerCron(expireJobs, "expire-jobs", {
     interval: CRON_INTERVAL,
   });
+
+  await registerCron(expireKV, "expire-kv", {
+    interval: CRON_INTERVAL,
+  });
 };
@@ -1,4 +1,6 @@
-import { and, eq, isNull, sql } from "drizzle-orm";
+import { and, eq, isNotNull, isNull, sql } from "drizzle-orm";
+import { z } from "zod";
+import { approvalPresentationSchema } from "../contract";
 import * as data from "../data";
 import * as events from "../observability/events";
 import { logger } from "../observability/logger";
@@ -60,23 +62,38 @@ export async function acknowledgeJob({
   return job;
 }
 
+export type InterruptTimeout = {
+  timeoutSeconds: number;
+  onTimeout: "fail" | "approve" | "resume";
+};
+
 export async function persistJobInterrupt({
   jobId,
   clusterId,
   machineId,
-  approvalRequested
+  approvalRequested,
+  approvalPresentation,
+  timeout,
 }: {
     jobId: string;
     clusterId: string,
     machineId: string
     approvalRequested?: boolean
+    approvalPresentation?: z.infer<typeof approvalPresentationSchema>
+    timeout?: InterruptTimeout
   }) {
   const [updated] =  await data.db
     .update(data.jobs)
     .set({
       status: "interrupted",
       approval_requested: approvalRequested,
-      updated_at: sql`now()`
+      approval_presentation: approvalPresentation ?? null,
+      updated_at: sql`now()`,
+      interrupt_expires_at: timeout
+        ? sql`now() + interval '1 second' * ${timeout.timeoutSeconds}`
+        : null,
+      interrupt_on_timeout: timeout?.onTimeout ?? null,
+      interrupt_timed_out: false,
     })
     .where(
       and(
@@ -96,11 +113,95 @@ export async function persistJobInterrupt({
     logger.warn("Job interrupt was not persisted", {
       jobId,
     });
+  } else {
+    // A workflow execution this call delegated to may have resulted before the call was interrupted
+    const [resulted] = await data.db
+      .select({ id: data.workflowExecutions.id })
+      .from(data.workflowExecutions)
+      .innerJoin(
+        data.jobs,
+        and(
+          eq(data.workflowExecutions.job_id, data.jobs.id),
+          eq(data.workflowExecutions.cluster_id, data.jobs.cluster_id),
+        ),
+      )
+      .where(
+        and(
+          eq(data.workflowExecutions.cluster_id, clusterId),
+          eq(data.workflowExecutions.resume_job_id, jobId),
+          isNotNull(data.jobs.resulted_at),
+        ),
+      );
+
+    if (resulted) {
+      await resumeInterruptedJob({ jobId, clusterId });
+    }
   }
 
   return updated;
 }
 
+/**
+ * Moves an interrupted job back to pending, so that it runs again.
+ */
+async function resumeInterruptedJob({
+  jobId,
+  clusterId,
+}: {
+  jobId: string;
+  clusterId: string;
+}) {
+  const [resumed] = await data.db
+    .update(data.jobs)
+    .set({
+      status: "pending",
+      executing_machine_id: null,
+      last_retrieved_at: null,
+      remaining_attempts: sql`remaining_attempts + 1`,
+    })
+    .where(
+      and(
+        eq(data.jobs.id, jobId),
+        eq(data.jobs.cluster_id, clusterId),
+        eq(data.jobs.status, "interrupted"),
+      ),
+    )
+    .returning({ id: data.jobs.id });
+
+  if (resumed) {
+    logger.info("Resumed tool call delegated to a workflow execution", {
+      jobId,
+      clusterId,
+    });
+  }
+}
+
+/**
+ * Resumes the tool call that delegated to the workflow execution run by a job, if any, once the
+ * execution has resulted, so that the call picks up the execution's result.
+ */
+async function resumeDelegatingJob({
+  jobId,
+  clusterId,
+}: {
+  jobId: string;
+  clusterId: string;
+}) {
+  const [execution] = await data.db
+    .select({ resumeJobId: data.workflowExecutions.resume_job_id })
+    .from(data.workflowExecutions)
+    .where(
+      and(
+        eq(data.workflowExecutions.cluster_id, clusterId),
+        eq(data.workflowExecutions.job_id, jobId),
+      ),
+    );
+
+  if (execution?.resumeJobId) {
+    await resumeInterruptedJob({ jobId: execution.resumeJobId, clusterId });
+  }
+}
+
 
 export async function persistJobResult({
   result,
@@ -157,6 +258,8 @@ export async function persistJobResult({
       });
     }
 
+    await resumeDelegatingJob({ jobId, clusterId: owner.clusterId });
+
     events.write({
       type: "jobResulted",
       clusterId: owner.clusterId,
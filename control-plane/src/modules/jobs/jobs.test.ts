@@ -297,6 +297,62 @@ describe("selfHealCalls", () => {
     expect(job!.status).toBe("interrupted");
   });
 
+  it.each([
+    ["fail", "success", false],
+    ["approve", "pending", true],
+    ["resume", "pending", null],
+  ] as const)(
+    "should resolve an expired approval interrupt with %s",
+    async (onTimeout, status, approved) => {
+      const owner = await createOwner();
+
+      await upsertToolDefinition({
+        name: mockTargetFn,
+        schema: mockTargetSchema,
+        clusterId: owner.clusterId,
+      });
+
+      const createJobResult = await createJobV2({
+        targetFn: mockTargetFn,
+        targetArgs: mockTargetArgs,
+        owner,
+        runId: getClusterBackgroundRun(owner.clusterId),
+      });
+
+      await acknowledgeJob({
+        jobId: createJobResult.id,
+        clusterId: owner.clusterId,
+        machineId: "testMachineId",
+      });
+
+      await requestApproval({
+        jobId: createJobResult.id,
+        clusterId: owner.clusterId,
+        machineId: "testMachineId",
+        timeout: { timeoutSeconds: 1, onTimeout },
+      });
+
+      // The deadline has not passed yet
+      expect((await selfHealJobs()).expiredInterrupts).not.toContain(
+        createJobResult.id,
+      );
+
+      await new Promise(resolve => setTimeout(resolve, 1500));
+
+      expect((await selfHealJobs()).expiredInterrupts).toContain(
+        createJobResult.id,
+      );
+
+      const job = await getJob({
+        jobId: createJobResult.id,
+        clusterId: owner.clusterId,
+      });
+
+      expect(job!.status).toBe(status);
+      expect(job!.approved).toBe(approved);
+    },
+  );
+
   it("should not retry a job that has reached max attempts", async () => {
     const owner = await createOwner();
 
@@ -649,6 +705,59 @@ describe("submitApproval", () => {
     expect(retreivedJob3!.status).toBe("success");
     expect(retreivedJob3!.resultType).toBe("rejection");
   });
+
+  it("should record the chosen approval option", async () => {
+    const result = await createJobV2({
+      targetFn: mockTargetFn,
+      targetArgs: mockTargetArgs,
+      owner,
+      runId: getClusterBackgroundRun(owner.clusterId),
+    });
+
+    await acknowledgeJob({
+      jobId: result.id,
+      clusterId: owner.clusterId,
+      machineId: "testMachineId",
+    });
+
+    const presentation = {
+      options: [
+        { id: "full", label: "Refund in full" },
+        { id: "partial", label: "Refund half" },
+        { id: "reject", label: "Reject", deny: true },
+      ],
+      links: [{ label: "Order", url: "https://example.com/orders/42" }],
+    };
+
+    await requestApproval({
+      clusterId: owner.clusterId,
+      jobId: result.id,
+      machineId: "testMachineId",
+      presentation,
+    });
+
+    const requested = await getJob({
+      jobId: result.id,
+      clusterId: owner.clusterId,
+    });
+
+    expect(requested!.approvalPresentation).toEqual(presentation);
+
+    await submitApproval({
+      clusterId: owner.clusterId,
+      jobId: result.id,
+      approved: true,
+      option: "partial",
+    });
+
+    const approved = await getJob({
+      jobId: result.id,
+      clusterId: owner.clusterId,
+    });
+
+    expect(approved!.approved).toBe(true);
+    expect(approved!.approvalOption).toBe("partial");
+  });
 });
 
 describe("cleanupMarkedJobs", () => {
@@ -17,10 +17,10 @@ import { packer } from "../../utilities/packer";
 import { resumeRun } from "../runs";
 import { notifyApprovalRequest } from "../runs/notify";
 import { selfHealJobs } from "./self-heal-jobs";
-import { notificationSchema } from "../contract";
+import { approvalPresentationSchema, notificationSchema } from "../contract";
 import { z } from "zod";
 import { logger } from "../observability/logger";
-import { persistJobInterrupt } from "./job-results";
+import { InterruptTimeout, persistJobInterrupt } from "./job-results";
 
 export { createJobV2 } from "./create-job";
 export { acknowledgeJob, persistJobResult } from "./job-results";
@@ -105,6 +105,8 @@ export const getJob = async ({
       authContext: data.jobs.auth_context,
       approvalRequested: data.jobs.approval_requested,
       approved: data.jobs.approved,
+      approvalPresentation: data.jobs.approval_presentation,
+      approvalOption: data.jobs.approval_option,
     })
     .from(data.jobs)
     .where(and(eq(data.jobs.id, jobId), eq(data.jobs.cluster_id, clusterId)));
@@ -244,6 +246,8 @@ export const pollJobsByTools = async ({
     auth_context: unknown;
     run_context: unknown;
     approved: boolean;
+    interrupt_timed_out: boolean;
+    approval_option: string | null;
   };
 
   const results = await data.db.execute<Result>(sql`
@@ -264,7 +268,7 @@ export const pollJobsByTools = async ({
          FOR UPDATE SKIP LOCKED
        )
        AND cluster_id = ${clusterId}
-     RETURNING id, target_fn, target_args, auth_context, run_context, approved`);
+     RETURNING id, target_fn, target_args, auth_context, run_context, approved, interrupt_timed_out, approval_option`);
 
   const jobs: {
     id: string;
@@ -273,6 +277,8 @@ export const pollJobsByTools = async ({
     authContext: unknown;
     runContext: unknown;
     approved: boolean;
+    interruptTimedOut: boolean;
+    approvalOption: string | null;
   }[] = results.rows.map(row => ({
     id: row.id as string,
     targetFn: row.target_fn as string,
@@ -280,6 +286,8 @@ export const pollJobsByTools = async ({
     authContext: row.auth_context,
     runContext: row.run_context,
     approved: row.approved,
+    interruptTimedOut: row.interrupt_timed_out,
+    approvalOption: row.approval_option,
   }));
 
   jobs.forEach(job => {
@@ -303,17 +311,23 @@ export async function requestApproval({
   clusterId,
   notification,
   machineId,
+  presentation,
+  timeout,
 }: {
   jobId: string;
   clusterId: string;
   machineId: string;
   notification?: z.infer<typeof notificationSchema>;
+  presentation?: z.infer<typeof approvalPresentationSchema>;
+  timeout?: InterruptTimeout;
 }) {
   const updated = await persistJobInterrupt({
     jobId,
     clusterId,
     machineId,
     approvalRequested: true,
+    approvalPresentation: presentation,
+    timeout,
   });
 
   if (updated) {
@@ -380,16 +394,19 @@ export async function submitApproval({
   jobId,
   clusterId,
   approved,
+  option,
 }: {
   jobId: string;
   clusterId: string;
   approved: boolean;
+  option?: string;
 }) {
   if (approved) {
     const [updated] = await data.db
       .update(data.jobs)
       .set({
         approved: true,
+        approval_option: option ?? null,
         status: "pending",
         executing_machine_id: null,
         last_retrieved_at: null,
@@ -416,6 +433,7 @@ export async function submitApproval({
         clusterId,
         runId: updated.runId,
         targetFn: updated.targetFn,
+        meta: option ? { option } : undefined,
       });
     }
   } else {
@@ -423,6 +441,7 @@ export async function submitApproval({
       .update(data.jobs)
       .set({
         approved: false,
+        approval_option: option ?? null,
         status: "success",
         result_type: "rejection",
         result: packer.pack({
@@ -451,6 +470,7 @@ export async function submitApproval({
         clusterId,
         runId: updated.runId,
         targetFn: updated.targetFn,
+        meta: option ? { option } : undefined,
       });
     }
 
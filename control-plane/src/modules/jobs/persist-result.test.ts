@@ -1,6 +1,9 @@
 import { createOwner } from "../test/util";
 import { createJobV2, getJobStatusSync, persistJobResult } from "./jobs";
-import { acknowledgeJob } from "./job-results";
+import { acknowledgeJob, persistJobInterrupt } from "./job-results";
+import * as data from "../data";
+import { eq } from "drizzle-orm";
+import { ulid } from "ulid";
 import * as redis from "../dependencies/redis";
 import { getClusterBackgroundRun } from "../runs";
 import { upsertToolDefinition } from "../tools";
@@ -173,4 +176,53 @@ describe("persistJobResult", () => {
       status: "success",
     });
   });
+
+  it("should resume the tool call that delegated to a workflow execution", async () => {
+    const createAcknowledged = async () => {
+      const job = await createJobV2({
+        targetFn: "testTargetFn",
+        targetArgs: "testTargetArgs",
+        owner,
+        runId: getClusterBackgroundRun(owner.clusterId),
+      });
+      await acknowledgeJob({
+        jobId: job.id,
+        clusterId: owner.clusterId,
+        machineId: "testMachineId",
+      });
+      return job;
+    };
+
+    const toolCall = await createAcknowledged();
+    const workflowJob = await createAcknowledged();
+    await data.db.insert(data.workflowExecutions).values({
+      id: ulid(),
+      cluster_id: owner.clusterId,
+      job_id: workflowJob.id,
+      workflow_name: "testWorkflow",
+      workflow_version: 1,
+      resume_job_id: toolCall.id,
+    });
+
+    await persistJobInterrupt({
+      jobId: toolCall.id,
+      clusterId: owner.clusterId,
+      machineId: "testMachineId",
+    });
+
+    await persistJobResult({
+      result: "foo",
+      resultType: "resolution",
+      jobId: workflowJob.id,
+      owner,
+      machineId: "testMachineId",
+    });
+
+    const [resumed] = await data.db
+      .select({ status: data.jobs.status })
+      .from(data.jobs)
+      .where(eq(data.jobs.id, toolCall.id));
+
+    expect(resumed.status).toBe("pending");
+  });
 });
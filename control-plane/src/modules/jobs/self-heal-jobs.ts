@@ -1,8 +1,9 @@
-import { and, eq, gt, isNotNull, lt, or, sql } from "drizzle-orm";
+import { and, eq, gt, isNotNull, isNull, lt, or, sql } from "drizzle-orm";
 import * as data from "../data";
 import * as events from "../observability/events";
 import { logger } from "../observability/logger";
 import { resumeRun } from "../runs";
+import { packer } from "../../utilities/packer";
 
 export async function selfHealJobs() {
   logger.debug("Running Job Self-healing");
@@ -50,6 +51,81 @@ export async function selfHealJobs() {
     });
   });
 
+  // Interrupts past their deadline are resolved with their timeout outcome
+  const failedInterrupts = await data.db
+    .update(data.jobs)
+    .set({
+      status: "success",
+      result_type: "rejection",
+      result: packer.pack({
+        message: "This call was interrupted and nobody responded in time.",
+      }),
+      resulted_at: sql`now()`,
+      approved: false,
+      interrupt_timed_out: true,
+    })
+    .where(
+      and(
+        eq(data.jobs.status, "interrupted"),
+        lt(data.jobs.interrupt_expires_at, sql`now()`),
+        eq(data.jobs.interrupt_on_timeout, "fail"),
+      ),
+    )
+    .returning({
+      id: data.jobs.id,
+      targetFn: data.jobs.target_fn,
+      clusterId: data.jobs.cluster_id,
+      runId: data.jobs.run_id,
+      onTimeout: data.jobs.interrupt_on_timeout,
+    });
+
+  const resumedInterrupts = await data.db
+    .update(data.jobs)
+    .set({
+      status: "pending",
+      approved: sql`CASE WHEN interrupt_on_timeout = 'approve' THEN true ELSE approved END`,
+      executing_machine_id: null,
+      last_retrieved_at: null,
+      remaining_attempts: sql`remaining_attempts + 1`,
+      interrupt_timed_out: true,
+    })
+    .where(
+      and(
+        eq(data.jobs.status, "interrupted"),
+        lt(data.jobs.interrupt_expires_at, sql`now()`),
+        or(
+          eq(data.jobs.interrupt_on_timeout, "approve"),
+          eq(data.jobs.interrupt_on_timeout, "resume"),
+        ),
+      ),
+    )
+    .returning({
+      id: data.jobs.id,
+      targetFn: data.jobs.target_fn,
+      clusterId: data.jobs.cluster_id,
+      runId: data.jobs.run_id,
+      onTimeout: data.jobs.interrupt_on_timeout,
+    });
+
+  [...failedInterrupts, ...resumedInterrupts].forEach(row => {
+    events.write({
+      clusterId: row.clusterId,
+      jobId: row.id,
+      type: "interruptExpired",
+      runId: row.runId ?? undefined,
+      targetFn: row.targetFn,
+      meta: {
+        onTimeout: row.onTimeout,
+      },
+    });
+  });
+
+  for (const row of failedInterrupts) {
+    if (row.runId) {
+      await resumeRun({ id: row.runId, clusterId: row.clusterId });
+    }
+  }
+
   // We have an infrequent issue with jobs not being resumed correctly from the "interupted" state.
   const nonResumedInterruptions = await data.db
     .update(data.jobs)
@@ -68,6 +144,8 @@ export async function selfHealJobs() {
           sql`now() - interval '5 minutes'`,
         ),
         eq(data.jobs.approval_requested, false),
+        // Interrupts with a deadline are resolved by it
+        isNull(data.jobs.interrupt_expires_at),
       ),
     )
     .returning({
@@ -132,5 +210,8 @@ export async function selfHealJobs() {
       .filter(row => row.status === "pending")
       .map(row => row.id),
     nonResumedInterruptions: nonResumedInterruptions.map(row => row.id),
+    expiredInterrupts: [...failedInterrupts, ...resumedInterrupts].map(
+      row => row.id,
+    ),
   };
 }
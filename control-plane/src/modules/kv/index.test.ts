@@ -0,0 +1,136 @@
+import { ulid } from "ulid";
+import { createOwner } from "../test/util";
+import { kv } from "./index";
+
+describe("kv", () => {
+  describe("listByPrefix", () => {
+    it("should page through keys with a literal prefix", async () => {
+      const { clusterId } = await createOwner({
+        clusterId: `cluster-kv-${ulid()}`,
+      });
+
+      for (const key of ["exec_memo_a", "exec_memo_b", "exec_memo_c"]) {
+        await kv.setOrReplace(clusterId, key, key);
+      }
+      // Matches "exec_memo_" if "_" were a wildcard
+      await kv.setOrReplace(clusterId, "exec1memo2d", "other");
+
+      const first = await kv.listByPrefix(clusterId, "exec_memo_", {
+        limit: 2,
+      });
+      expect(first.map(e => e.key)).toEqual(["exec_memo_a", "exec_memo_b"]);
+
+      const second = await kv.listByPrefix(clusterId, "exec_memo_", {
+        limit: 2,
+        after: first[1].key,
+      });
+      expect(second.map(e => e.key)).toEqual(["exec_memo_c"]);
+    });
+  });
+
+  describe("setIfVersion", () => {
+    it("should only write at the expected version", async () => {
+      const { clusterId } = await createOwner({
+        clusterId: `cluster-kv-${ulid()}`,
+      });
+
+      expect(await kv.setIfVersion(clusterId, "counter", "1", 0)).toBe(1);
+      expect(await kv.setIfVersion(clusterId, "counter", "1", 0)).toBeNull();
+
+      expect(await kv.setIfVersion(clusterId, "counter", "2", 1)).toBe(2);
+      expect(await kv.setIfVersion(clusterId, "counter", "3", 1)).toBeNull();
+
+      expect(await kv.getVersioned(clusterId, "counter")).toEqual({
+        value: "2",
+        version: 2,
+      });
+
+      // Replacing a value also moves its version on
+      await kv.setOrReplace(clusterId, "counter", "10");
+      expect(await kv.getVersioned(clusterId, "counter")).toEqual({
+        value: "10",
+        version: 3,
+      });
+    });
+  });
+
+  describe("delete", () => {
+    it("should only delete the given key", async () => {
+      const { clusterId } = await createOwner({
+        clusterId: `cluster-kv-${ulid()}`,
+      });
+
+      await kv.setOrReplace(clusterId, "artifact_a", "a");
+      await kv.setOrReplace(clusterId, "artifact_a_0", "chunk");
+
+      await kv.delete(clusterId, "artifact_a");
+
+      expect(await kv.get(clusterId, "artifact_a")).toBeNull();
+      expect(await kv.get(clusterId, "artifact_a_0")).toBe("chunk");
+    });
+  });
+
+  describe("expiry", () => {
+    it("should treat expired entries as absent", async () => {
+      const { clusterId } = await createOwner({
+        clusterId: `cluster-kv-${ulid()}`,
+      });
+
+      const past = new Date(Date.now() - 60 * 1000);
+      const future = new Date(Date.now() + 60 * 60 * 1000);
+
+      await kv.setOrReplace(clusterId, "expired", "old", past);
+      await kv.setOrReplace(clusterId, "live", "current", future);
+      await kv.setOrReplace(clusterId, "forever", "value");
+
+      expect(await kv.get(clusterId, "expired")).toBeNull();
+      expect(await kv.get(clusterId, "live")).toBe("current");
+      expect(await kv.get(clusterId, "forever")).toBe("value");
+
+      const listed = await kv.getAllByPrefix(clusterId, "");
+      expect(listed.map(e => e.key).sort()).toEqual(["forever", "live"]);
+    });
+
+    it("should only set over an existing entry once it has expired", async () => {
+      const { clusterId } = await createOwner({
+        clusterId: `cluster-kv-${ulid()}`,
+      });
+
+      await kv.setOrReplace(
+        clusterId,
+        "lock",
+        "first",
+        new Date(Date.now() + 60 * 60 * 1000),
+      );
+      expect(await kv.setIfNotExists(clusterId, "lock", "second")).toBeNull();
+      expect(await kv.get(clusterId, "lock")).toBe("first");
+
+      await kv.setOrReplace(
+        clusterId,
+        "lock",
+        "first",
+        new Date(Date.now() - 60 * 1000),
+      );
+      expect(await kv.setIfNotExists(clusterId, "lock", "second")).toBe(
+        "second",
+      );
+      expect(await kv.get(clusterId, "lock")).toBe("second");
+    });
+
+    it("should delete expired entries", async () => {
+      const { clusterId } = await createOwner({
+        clusterId: `cluster-kv-${ulid()}`,
+      });
+
+      await kv.setOrReplace(
+        clusterId,
+        "expired",
+        "old",
+        new Date(Date.now() - 60 * 1000),
+      );
+
+      expect(await kv.deleteExpired()).toBeGreaterThanOrEqual(1);
+      expect(await kv.getAllByPrefix(clusterId, "")).toEqual([]);
+    });
+  });
+});
@@ -1,6 +1,14 @@
-import { and, eq, like } from "drizzle-orm";
+import { and, asc, eq, gt, isNull, like, lt, or, sql } from "drizzle-orm";
 import { clusterKV, db } from "../data";
 
+// Entries without an expiry live until they are replaced
+const notExpired = () =>
+  or(isNull(clusterKV.expires_at), gt(clusterKV.expires_at, sql`now()`));
+
+// Keys commonly contain "_", which LIKE would otherwise match against any character
+const prefixPattern = (prefix: string) =>
+  `${prefix.replace(/[\\%_]/g, c => `\\${c}`)}%`;
+
 export const kv = {
   get: async (clusterId: string, key: string) => {
     const result = await db
@@ -8,22 +16,47 @@ export const kv = {
         value: clusterKV.value,
       })
       .from(clusterKV)
-      .where(and(eq(clusterKV.key, key), eq(clusterKV.cluster_id, clusterId)));
+      .where(
+        and(
+          eq(clusterKV.key, key),
+          eq(clusterKV.cluster_id, clusterId),
+          notExpired(),
+        ),
+      );
 
     return result[0]?.value ?? null;
   },
+  getVersioned: async (clusterId: string, key: string) => {
+    const result = await db
+      .select({
+        value: clusterKV.value,
+        version: clusterKV.version,
+      })
+      .from(clusterKV)
+      .where(
+        and(
+          eq(clusterKV.key, key),
+          eq(clusterKV.cluster_id, clusterId),
+          notExpired(),
+        ),
+      );
+
+    return result[0] ?? null;
+  },
   getAllByPrefix: async (clusterId: string, prefix: string) => {
     const result = await db
       .select({
         value: clusterKV.value,
         key: clusterKV.key,
         createdAt: clusterKV.created_at,
+        expiresAt: clusterKV.expires_at,
       })
       .from(clusterKV)
       .where(
         and(
           eq(clusterKV.cluster_id, clusterId),
-          like(clusterKV.key, `${prefix}%`),
+          like(clusterKV.key, prefixPattern(prefix)),
+          notExpired(),
         ),
       );
 
@@ -31,15 +64,54 @@ export const kv = {
       value: r.value,
       key: r.key,
       createdAt: r.createdAt,
+      expiresAt: r.expiresAt,
     }));
   },
-  setOrReplace: async (clusterId: string, key: string, value: string) => {
+  listByPrefix: async (
+    clusterId: string,
+    prefix: string,
+    { limit, after }: { limit: number; after?: string },
+  ) => {
+    return db
+      .select({
+        key: clusterKV.key,
+        value: clusterKV.value,
+        createdAt: clusterKV.created_at,
+        expiresAt: clusterKV.expires_at,
+      })
+      .from(clusterKV)
+      .where(
+        and(
+          eq(clusterKV.cluster_id, clusterId),
+          like(clusterKV.key, prefixPattern(prefix)),
+          after ? gt(clusterKV.key, after) : undefined,
+          notExpired(),
+        ),
+      )
+      .orderBy(asc(clusterKV.key))
+      .limit(limit);
+  },
+  setOrReplace: async (
+    clusterId: string,
+    key: string,
+    value: string,
+    expiresAt?: Date,
+  ) => {
     const result = await db
       .insert(clusterKV)
-      .values({ key, value, cluster_id: clusterId })
+      .values({
+        key,
+        value,
+        cluster_id: clusterId,
+        expires_at: expiresAt ?? null,
+      })
       .onConflictDoUpdate({
         target: [clusterKV.cluster_id, clusterKV.key],
-        set: { value },
+        set: {
+          value,
+          expires_at: expiresAt ?? null,
+          version: sql`${clusterKV.version} + 1`,
+        },
       })
       .returning({
         value: clusterKV.value,
@@ -47,10 +119,31 @@ export const kv = {
 
     return result[0]?.value ?? null;
   },
-  setIfNotExists: async (clusterId: string, key: string, value: string) => {
+  setIfNotExists: async (
+    clusterId: string,
+    key: string,
+    value: string,
+    expiresAt?: Date,
+  ) => {
+    // An expired entry no longer counts as existing
+    await db
+      .delete(clusterKV)
+      .where(
+        and(
+          eq(clusterKV.cluster_id, clusterId),
+          eq(clusterKV.key, key),
+          lt(clusterKV.expires_at, sql`now()`),
+        ),
+      );
+
     const result = await db
       .insert(clusterKV)
-      .values({ key, value, cluster_id: clusterId })
+      .values({
+        key,
+        value,
+        cluster_id: clusterId,
+        expires_at: expiresAt ?? null,
+      })
       .returning({
         value: clusterKV.value,
       })
@@ -58,4 +151,74 @@ export const kv = {
 
     return result[0]?.value ?? null;
   },
+  // Writes value only if the key is at version, 0 meaning absent. Returns the new version, or null
+  // if the key is at another version.
+  setIfVersion: async (
+    clusterId: string,
+    key: string,
+    value: string,
+    version: number,
+    expiresAt?: Date,
+  ) => {
+    if (version === 0) {
+      await db
+        .delete(clusterKV)
+        .where(
+          and(
+            eq(clusterKV.cluster_id, clusterId),
+            eq(clusterKV.key, key),
+            lt(clusterKV.expires_at, sql`now()`),
+          ),
+        );
+
+      const result = await db
+        .insert(clusterKV)
+        .values({
+          key,
+          value,
+          cluster_id: clusterId,
+          expires_at: expiresAt ?? null,
+        })
+        .returning({
+          version: clusterKV.version,
+        })
+        .onConflictDoNothing();
+
+      return result[0]?.version ?? null;
+    }
+
+    const result = await db
+      .update(clusterKV)
+      .set({
+        value,
+        expires_at: expiresAt ?? null,
+        version: sql`${clusterKV.version} + 1`,
+      })
+      .where(
+        and(
+          eq(clusterKV.cluster_id, clusterId),
+          eq(clusterKV.key, key),
+          eq(clusterKV.version, version),
+          notExpired(),
+        ),
+      )
+      .returning({
+        version: clusterKV.version,
+      });
+
+    return result[0]?.version ?? null;
+  },
+  delete: async (clusterId: string, key: string) => {
+    await db
+      .delete(clusterKV)
+      .where(and(eq(clusterKV.cluster_id, clusterId), eq(clusterKV.key, key)));
+  },
+  deleteExpired: async () => {
+    const result = await db
+      .delete(clusterKV)
+      .where(lt(clusterKV.expires_at, sql`now()`))
+      .returning({ key: clusterKV.key });
+
+    return result.length;
+  },
 };
@@ -0,0 +1,102 @@
+import { buildDefaultStructuredProvider } from ".";
+import { BadRequestError } from "../../utilities/errors";
+import { buildModel } from "../models";
+
+const mockCall = jest.fn(async () => ({
+  raw: {
+    content: [{ type: "text", text: '{"merchant": "ACME"}' }],
+  },
+}));
+
+jest.mock("../models", () => ({
+  buildModel: jest.fn(() => ({
+    call: mockCall,
+  })),
+}));
+
+describe("buildDefaultStructuredProvider", () => {
+  beforeEach(() => {
+    jest.clearAllMocks();
+  });
+
+  it("should use the model of the X-Provider-Model header", async () => {
+    const provider = buildDefaultStructuredProvider({
+      clusterId: "cluster",
+      model: "claude-3-haiku",
+    });
+
+    expect(buildModel).toHaveBeenCalledWith(
+      expect.objectContaining({ identifier: "claude-3-haiku" }),
+    );
+
+    await (provider as any)({ input: "receipt" }, "prompt", []);
+    expect(mockCall).toHaveBeenCalledTimes(1);
+  });
+
+  it("should default to claude-3-5-sonnet", () => {
+    buildDefaultStructuredProvider({ clusterId: "cluster" });
+
+    expect(buildModel).toHaveBeenCalledWith(
+      expect.objectContaining({ identifier: "claude-3-5-sonnet" }),
+    );
+  });
+
+  it("should reject models the default provider does not route", () => {
+    expect(() =>
+      buildDefaultStructuredProvider({
+        clusterId: "cluster",
+        model: "gpt-4o",
+      }),
+    ).toThrow(BadRequestError);
+
+    expect(buildModel).not.toHaveBeenCalled();
+  });
+
+  it("should pass PDFs to the model as documents", async () => {
+    const provider = buildDefaultStructuredProvider({
+      clusterId: "cluster",
+      instructions: "Extract the total",
+    });
+
+    await (provider as any)(
+      { input: "JVBERi0xLjQ=", type: "application/pdf" },
+      "prompt",
+      [],
+    );
+
+    expect(mockCall).toHaveBeenCalledWith({
+      messages: [
+        {
+          role: "user",
+          content: [
+            {
+              type: "document",
+              source: {
+                type: "base64",
+                media_type: "application/pdf",
+                data: "JVBERi0xLjQ=",
+              },
+            },
+            { type: "text", text: "Extract the total prompt" },
+          ],
+        },
+      ],
+    });
+  });
+
+  it("should forward the generation options", async () => {
+    const provider = buildDefaultStructuredProvider({
+      clusterId: "cluster",
+      generation: { temperature: 0, maxTokens: 256, stopSequences: ["END"] },
+    });
+
+    expect(buildModel).toHaveBeenCalledWith(
+      expect.objectContaining({ modelOptions: { temperature: 0 } }),
+    );
+
+    await (provider as any)({ input: "receipt" }, "prompt", []);
+    expect(mockCall).toHaveBeenCalledWith(
+      expect.objectContaining({ maxTokens: 256, stopSequences: ["END"] }),
+    );
+  });
+});
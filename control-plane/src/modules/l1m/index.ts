@@ -0,0 +1,123 @@
+import Anthropic from "@anthropic-ai/sdk";
+import { structured } from "@l1m/core";
+import { BadRequestError } from "../../utilities/errors";
+import { buildModel } from "../models";
+import { ChatIdentifiers, isChatIdentifier } from "../models/routing";
+
+type StructuredProvider = Parameters<typeof structured>[0]["provider"];
+
+export type GenerationOptions = {
+  temperature?: number;
+  topP?: number;
+  maxTokens?: number;
+  stopSequences?: string[];
+  seed?: number;
+};
+
+export const DEFAULT_STRUCTURED_MODEL: ChatIdentifiers = "claude-3-5-sonnet";
+
+// PDFs are passed through to the default provider's models, which read them natively
+export const PDF_MIME_TYPE = "application/pdf";
+
+/**
+ * Builds the provider used for structured calls that do not bring their own provider.
+ * The model requested with the X-Provider-Model header is used when the default provider routes it.
+ */
+export const buildDefaultStructuredProvider = ({
+  clusterId,
+  model: requestedModel,
+  instructions,
+  generation,
+}: {
+  clusterId: string;
+  model?: string;
+  instructions?: string;
+  generation?: GenerationOptions;
+}): StructuredProvider => {
+  const identifier = requestedModel || DEFAULT_STRUCTURED_MODEL;
+  if (!isChatIdentifier(identifier)) {
+    throw new BadRequestError(
+      `Model ${identifier} is not available on the default provider`,
+    );
+  }
+
+  const model = buildModel({
+    identifier,
+    trackingOptions: {
+      clusterId,
+    },
+    modelOptions: {
+      temperature: generation?.temperature,
+    },
+  });
+
+  return async (params, prompt, previousAttempts) => {
+    const messages: Anthropic.MessageParam[] = [];
+
+    const { type, input } = params;
+
+    if (type === PDF_MIME_TYPE) {
+      messages.push({
+        role: "user",
+        content: [
+          {
+            type: "document",
+            source: {
+              type: "base64",
+              media_type: PDF_MIME_TYPE,
+              data: input,
+            },
+          },
+          { type: "text", text: `${instructions} ${prompt}` },
+        ],
+      });
+    } else if (type && type.startsWith("image/")) {
+      messages.push({
+        role: "user",
+        content: [
+          { type: "text", text: `${instructions} ${prompt}` },
+          {
+            type: "image",
+            source: {
+              type: "base64",
+              media_type: type as any,
+              data: input,
+            },
+          },
+        ],
+      });
+    } else {
+      messages.push({
+        role: "user",
+        content: `${input} ${instructions} ${prompt}`,
+      });
+    }
+
+    if (previousAttempts.length > 0) {
+      previousAttempts.forEach(attempt => {
+        messages.push({
+          role: "user",
+          content:
+            "You previously responded: " +
+            attempt.raw +
+            " which produced validation errors: " +
+            attempt.errors,
+        });
+      });
+    }
+
+    // Anthropic models do not support seeds, so the seed only keys the cache
+    const result = await model.call({
+      messages,
+      maxTokens: generation?.maxTokens,
+      topP: generation?.topP,
+      stopSequences: generation?.stopSequences,
+    });
+
+    if (result.raw.content[0]?.type === "text") {
+      return result.raw.content[0].text;
+    } else {
+      throw new Error("Anthropic API returned invalid response");
+    }
+  };
+};
@@ -24,6 +24,8 @@ type CallInput = {
   messages: Anthropic.MessageParam[];
   tools?: Anthropic.Tool[];
   maxTokens?: number;
+  topP?: number;
+  stopSequences?: string[];
 };
 
 type CallOutput = {
@@ -155,6 +157,8 @@ export const buildModel = ({
               temperature,
               stream: false,
               max_tokens: options.maxTokens ?? 2048,
+              top_p: options.topP,
+              stop_sequences: options.stopSequences,
               system: options.system,
               messages: options.messages,
               // This is enforced above
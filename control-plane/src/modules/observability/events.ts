@@ -20,6 +20,7 @@ export type EventTypes =
   | "approvalRequested"
   | "approvalGranted"
   | "approvalDenied"
+  | "interruptExpired"
 
   // Tool Calls (i.e Within an Agent Runs)
   | "toolInvocationCreated"
@@ -40,7 +41,8 @@ export type EventTypes =
   | "toolSearchCompleted"
 
   // Workflows
-  | "workflowLogCreated";
+  | "workflowLogCreated"
+  | "resumeTokenRedeemed";
 
 type Event = {
   type: EventTypes;
@@ -47,9 +47,11 @@ import {
 import { getRunMessagesForDisplayWithPolling } from "../runs/messages";
 import { timeline } from "../timeline";
 import {
+  getToolDefinitions,
   getWorkflowTools,
   listTools,
   recordPoll,
+  scopeRunTools,
   upsertToolDefinition,
 } from "../tools";
 import { persistJobInterrupt } from "../jobs/job-results";
@@ -57,16 +59,21 @@ import {
   createWorkflowExecution,
   listWorkflowExecutions,
   getWorkflowExecutionTimeline,
+  listWorkflowExecutionChildren,
+  cancelWorkflowExecution,
 } from "../workflows/executions";
 import { createWorkflowLog } from "../workflows/logs";
+import {
+  createResumeToken,
+  redeemResumeToken,
+} from "../workflows/resume-tokens";
 import {
   inferType,
   structured,
   validateJsonSchema,
   validTypes,
 } from "@l1m/core";
-import { buildModel } from "../models";
-import Anthropic from "@anthropic-ai/sdk";
+import { buildDefaultStructuredProvider, PDF_MIME_TYPE } from "../l1m";
 import { sendSlackNotification } from "../integrations/slack";
 
 import { sendEmail } from "../email";
@@ -255,12 +262,40 @@ export const router = initServer().router(contract, {
       }
     }
 
-    const attachedFunctions =
+    let attachedFunctions =
       body.tools ??
       body.attachedFunctions?.map(f =>
         typeof f === "string" ? f : f.function,
       );
 
+    if (body.deniedTools || body.toolCapabilities) {
+      if (!attachedFunctions) {
+        return {
+          status: 400,
+          body: {
+            message: "deniedTools and toolCapabilities require tools",
+          },
+        };
+      }
+
+      attachedFunctions = scopeRunTools({
+        tools: attachedFunctions,
+        deniedTools: body.deniedTools,
+        capabilities: body.toolCapabilities,
+        definitions: await getToolDefinitions({ clusterId }),
+      });
+
+      if (attachedFunctions.length == 0) {
+        return {
+          status: 400,
+          body: {
+            message:
+              "No tools remain after applying deniedTools and toolCapabilities",
+          },
+        };
+      }
+    }
+
     const runOptions: RunOptions = {
       id,
       initialPrompt: body.initialPrompt,
@@ -677,6 +712,13 @@ export const router = initServer().router(contract, {
         throw new BadRequestError(parsed.error.message);
       }
 
+      const timeout = parsed.data.timeoutSeconds
+        ? {
+            timeoutSeconds: parsed.data.timeoutSeconds,
+            onTimeout: parsed.data.onTimeout ?? "fail",
+          }
+        : undefined;
+
       if (parsed.data.type === "approval") {
         logger.info("Requesting approval", {
           jobId,
@@ -688,6 +730,11 @@ export const router = initServer().router(contract, {
           clusterId,
           notification: parsed.data.notification,
           machineId,
+          presentation:
+            parsed.data.options || parsed.data.links
+              ? { options: parsed.data.options, links: parsed.data.links }
+              : undefined,
+          timeout,
         });
       } else {
         // TODO: Should general interrupts allow notification?
@@ -695,6 +742,7 @@ export const router = initServer().router(contract, {
           jobId,
           clusterId,
           machineId,
+          timeout,
         });
       }
 
@@ -825,6 +873,8 @@ export const router = initServer().router(contract, {
           authContext: job.authContext,
           runContext: job.runContext,
           approved: job.approved,
+          interruptTimedOut: job.interruptTimedOut,
+          approvalOption: job.approvalOption,
         })) ?? [],
     };
   },
@@ -873,10 +923,37 @@ export const router = initServer().router(contract, {
       };
     }
 
+    const { approved, option } = request.body;
+
+    if (option !== undefined) {
+      const chosen = job.approvalPresentation?.options?.find(
+        o => o.id === option,
+      );
+
+      if (!chosen) {
+        return {
+          status: 400,
+          body: {
+            message: `Job has no approval option ${option}`,
+          },
+        };
+      }
+
+      if (!!chosen.deny === approved) {
+        return {
+          status: 400,
+          body: {
+            message: `Approval option ${option} ${chosen.deny ? "denies" : "approves"} the job`,
+          },
+        };
+      }
+    }
+
     await jobs.submitApproval({
       jobId,
       clusterId,
-      approved: request.body.approved,
+      approved,
+      option,
     });
 
     return {
@@ -1351,6 +1428,8 @@ export const router = initServer().router(contract, {
       clusterId,
       workflowName,
       request.body,
+      request.query.parentExecutionId,
+      request.query.resumeJobId,
     );
 
     return {
@@ -1418,6 +1497,35 @@ export const router = initServer().router(contract, {
       body: undefined,
     };
   },
+  createResumeToken: async request => {
+    const { clusterId, workflowName, executionId } = request.params;
+
+    const machine = request.request.getAuth();
+    await machine.canAccess({ cluster: { clusterId } });
+
+    const token = await createResumeToken({
+      clusterId,
+      workflowName,
+      executionId,
+      name: request.body.name,
+    });
+
+    return {
+      status: 201,
+      body: { token },
+    };
+  },
+  redeemResumeToken: async request => {
+    await redeemResumeToken({
+      token: request.params.token,
+      payload: request.body.payload,
+    });
+
+    return {
+      status: 204,
+      body: undefined,
+    };
+  },
 
   listWorkflowExecutions: async request => {
     const { clusterId } = request.params;
@@ -1447,6 +1555,41 @@ export const router = initServer().router(contract, {
     };
   },
 
+  listWorkflowExecutionChildren: async request => {
+    const { clusterId, executionId } = request.params;
+
+    const auth = request.request.getAuth();
+    await auth.canAccess({ cluster: { clusterId } });
+
+    const result = await listWorkflowExecutionChildren({
+      clusterId,
+      executionId,
+    });
+
+    return {
+      status: 200,
+      body: result,
+    };
+  },
+
+  cancelWorkflowExecution: async request => {
+    const { clusterId, executionId } = request.params;
+
+    const auth = request.request.getAuth();
+    await auth.canManage({ cluster: { clusterId } });
+
+    await cancelWorkflowExecution({
+      clusterId,
+      executionId,
+      cascade: request.body?.cascade,
+    });
+
+    return {
+      status: 204,
+      body: undefined,
+    };
+  },
+
   getWorkflowExecutionTimeline: async request => {
     const { clusterId, workflowName, executionId } = request.params;
 
@@ -1465,6 +1608,35 @@ export const router = initServer().router(contract, {
     };
   },
 
+  listClusterKV: async request => {
+    const { clusterId } = request.params;
+    const { prefix, limit, after } = request.query;
+
+    const machine = request.request.getAuth().isMachine();
+    await machine.canAccess({ cluster: { clusterId } });
+    machine.canCreate({ run: true });
+
+    const result = await kv.listByPrefix(clusterId, prefix, { limit, after });
+
+    return {
+      status: 200,
+      body: result,
+    };
+  },
+  deleteClusterKV: async request => {
+    const { clusterId, key } = request.params;
+
+    const machine = request.request.getAuth().isMachine();
+    await machine.canAccess({ cluster: { clusterId } });
+    machine.canCreate({ run: true });
+
+    await kv.delete(clusterId, key);
+
+    return {
+      status: 204,
+      body: undefined,
+    };
+  },
   getClusterKV: async request => {
     const { clusterId, key } = request.params;
 
@@ -1472,27 +1644,55 @@ export const router = initServer().router(contract, {
     await machine.canAccess({ cluster: { clusterId } });
     machine.canCreate({ run: true });
 
-    const result = await kv.get(clusterId, key);
+    const result = await kv.getVersioned(clusterId, key);
 
     return {
       status: 200,
       body: {
-        value: result,
+        value: result?.value ?? null,
+        version: result?.version ?? 0,
       },
     };
   },
   setClusterKV: async request => {
     const { clusterId, key } = request.params;
-    const { value, onConflict } = request.body;
+    const { value, onConflict, expiresAt, ifVersion } = request.body;
 
     const machine = request.request.getAuth().isMachine();
     await machine.canAccess({ cluster: { clusterId } });
     machine.canCreate({ run: true });
 
+    if (ifVersion !== undefined) {
+      const version = await kv.setIfVersion(
+        clusterId,
+        key,
+        value,
+        ifVersion,
+        expiresAt,
+      );
+
+      if (version === null) {
+        return {
+          status: 409,
+          body: {
+            message: `Key is no longer at version ${ifVersion}`,
+          },
+        };
+      }
+
+      return {
+        status: 200,
+        body: {
+          value,
+          version,
+        },
+      };
+    }
+
     const setter =
       onConflict === "replace" ? kv.setOrReplace : kv.setIfNotExists;
 
-    const result = await setter(clusterId, key, value);
+    const result = await setter(clusterId, key, value, expiresAt);
 
     return {
       status: 200,
@@ -1503,12 +1703,14 @@ export const router = initServer().router(contract, {
   },
   listTools: async request => {
     const { clusterId } = request.params;
+    const { tag } = request.query;
 
     const auth = request.request.getAuth();
     await auth.canAccess({ cluster: { clusterId } });
 
     const tools = await listTools({
       clusterId,
+      tag,
     });
 
     return {
@@ -1517,7 +1719,7 @@ export const router = initServer().router(contract, {
     };
   },
   l1mStructured: async request => {
-    const { input, instructions, schema } = request.body;
+    const { input, instructions, schema, generation } = request.body;
     const { clusterId } = request.params;
 
     const auth = request.request.getAuth();
@@ -1537,6 +1739,7 @@ export const router = initServer().router(contract, {
     providerKey && hash.update(providerKey);
     executionId && hash.update(executionId);
     instructions && hash.update(instructions);
+    generation && hash.update(JSON.stringify(generation));
 
     const messageKey = `${executionId}_structured_${hash.digest("hex")}`;
 
@@ -1562,7 +1765,7 @@ export const router = initServer().router(contract, {
 
     const type = await inferType(input);
 
-    if (type && !validTypes.includes(type)) {
+    if (type && !validTypes.includes(type) && type !== PDF_MIME_TYPE) {
       return {
         status: 400,
         body: {
@@ -1575,62 +1778,27 @@ export const router = initServer().router(contract, {
     let provider: Parameters<typeof structured>[0]["provider"] | undefined;
 
     if (!providerModel || !providerKey || !providerUrl) {
-      const model = buildModel({
-        identifier: "claude-3-5-sonnet",
-        trackingOptions: {
-          clusterId: clusterId,
-        },
+      provider = buildDefaultStructuredProvider({
+        clusterId,
+        model: providerModel,
+        instructions,
+        generation,
       });
-
-      provider = async (params, prompt, previousAttempts) => {
-        const messages: Anthropic.MessageParam[] = [];
-
-        const { type, input } = params;
-
-        if (type && type.startsWith("image/")) {
-          messages.push({
-            role: "user",
-            content: [
-              { type: "text", text: `${instructions} ${prompt}` },
-              {
-                type: "image",
-                source: {
-                  type: "base64",
-                  media_type: type as any,
-                  data: input,
-                },
-              },
-            ],
-          });
-        } else {
-          messages.push({
-            role: "user",
-            content: `${input} ${instructions} ${prompt}`,
-          });
-        }
-
-        if (previousAttempts.length > 0) {
-          previousAttempts.forEach(attempt => {
-            messages.push({
-              role: "user",
-              content:
-                "You previously responded: " +
-                attempt.raw +
-                " which produced validation errors: " +
-                attempt.errors,
-            });
-          });
-        }
-
-        const result = await model.call({
-          messages,
-        });
-
-        if (result.raw.content[0]?.type === "text") {
-          return result.raw.content[0].text;
-        } else {
-          throw new Error("Anthropic API returned invalid response");
-        }
+    } else if (generation) {
+      // Custom providers are called by l1m, which does not take sampling options
+      return {
+        status: 400,
+        body: {
+          message:
+            "Generation options are only supported with the default provider",
+        },
+      };
+    } else if (type === PDF_MIME_TYPE) {
+      return {
+        status: 400,
+        body: {
+          message: "PDF input is only supported with the default provider",
+        },
       };
     } else {
       provider = {
@@ -11,7 +11,7 @@ import { notifyStatusChange } from "../notify";
 import { generateTitle } from "../summarization";
 import { createRunGraph } from "./agent";
 import { buildTool } from "./tools/functions";
-import { getToolDefinition } from "../../tools";
+import { describeTool, getToolDefinition } from "../../tools";
 import { RunGraphState } from "./state";
 import { AgentTool } from "./tool";
 
@@ -106,7 +106,7 @@ export const processAgentRun = async (
         toolCallId: toolCall.id!,
         run: run,
         schema: tool.schema ?? undefined,
-        description: tool.description ?? undefined,
+        description: describeTool(tool),
       });
     },
     getAttachedTools: state => getAttachedTools(state),
@@ -234,9 +234,7 @@ const getAttachedTools = async (state: RunGraphState) => {
     tools.push(
       new AgentTool({
         name: definition.name,
-        description: (
-          definition.description ?? `${definition.name} function`
-        ).substring(0, 1024),
+        description: describeTool(definition),
         schema: definition.schema ?? undefined,
         func: async () => undefined,
       }),
@@ -1,5 +1,10 @@
 import { createCluster } from "../clusters/management";
-import { upsertToolDefinition, getWorkflowTools } from "./";
+import {
+  upsertToolDefinition,
+  getWorkflowTools,
+  describeTool,
+  scopeRunTools,
+} from "./";
 
 const schema =
   '{"type":"object","properties":{"foo":{"type":"string"}},"required":["foo"]}';
@@ -112,3 +117,68 @@ describe("getWorkflowTools", () => {
     expect(tools.length).toBe(0);
   });
 });
+
+describe("describeTool", () => {
+  it("should fall back to the tool name", () => {
+    expect(describeTool({ name: "search" })).toEqual("search function");
+  });
+
+  it("should add usage hints and examples", () => {
+    expect(
+      describeTool({
+        name: "search",
+        description: "Search orders",
+        config: {
+          usageHints: "Prefer over listOrders when a customer is known",
+          examples: [{ description: "By customer", input: { customerId: "42" } }],
+        },
+      }),
+    ).toEqual(
+      [
+        "Search orders",
+        "Usage: Prefer over listOrders when a customer is known",
+        'Examples:\n- By customer: {"customerId":"42"}',
+      ].join("\n\n"),
+    );
+  });
+
+  it("should keep the description when truncating", () => {
+    const description = describeTool({
+      name: "search",
+      description: "Search orders",
+      config: { usageHints: "x".repeat(2000) },
+    });
+
+    expect(description).toHaveLength(1024);
+    expect(description.startsWith("Search orders\n\nUsage: x")).toBe(true);
+  });
+});
+
+describe("scopeRunTools", () => {
+  const definitions = [
+    { name: "getOrder", config: { capability: "read" as const } },
+    { name: "refundOrder", config: { capability: "mutate" as const } },
+    { name: "cancelOrder", config: null },
+  ];
+  const tools = ["getOrder", "refundOrder", "cancelOrder"];
+
+  it("should withhold denied tools", () => {
+    expect(
+      scopeRunTools({ tools, deniedTools: ["refundOrder"], definitions }),
+    ).toEqual(["getOrder", "cancelOrder"]);
+  });
+
+  it("should treat tools without a capability as mutating", () => {
+    expect(
+      scopeRunTools({ tools, capabilities: ["read"], definitions }),
+    ).toEqual(["getOrder"]);
+    expect(
+      scopeRunTools({
+        tools,
+        deniedTools: ["refundOrder"],
+        capabilities: ["mutate"],
+        definitions,
+      }),
+    ).toEqual(["cancelOrder"]);
+  });
+});
@@ -146,7 +146,42 @@ export const getToolDefinitions = async ({
   return results;
 };
 
-export const listTools = async ({ clusterId }: { clusterId: string }) => {
+/**
+ * Narrows the tools requested for a run: denied tools are withheld and, if capabilities are
+ * given, only tools declaring one of them are kept. Tools without a declared capability, or
+ * without a definition, are treated as mutating.
+ */
+export const scopeRunTools = ({
+  tools,
+  deniedTools,
+  capabilities,
+  definitions,
+}: {
+  tools: string[];
+  deniedTools?: string[];
+  capabilities?: NonNullable<ToolConfig["capability"]>[];
+  definitions: { name: string; config?: ToolConfig | null }[];
+}) => {
+  const scoped = tools.filter(t => !deniedTools?.includes(t));
+
+  if (!capabilities) {
+    return scoped;
+  }
+
+  return scoped.filter(t => {
+    const capability =
+      definitions.find(d => d.name === t)?.config?.capability ?? "mutate";
+    return capabilities.includes(capability);
+  });
+};
+
+export const listTools = async ({
+  clusterId,
+  tag,
+}: {
+  clusterId: string;
+  tag?: string;
+}) => {
   const tools = await data.db
     .select({
       name: data.tools.name,
@@ -160,9 +195,52 @@ export const listTools = async ({ clusterId }: { clusterId: string }) => {
     .from(data.tools)
     .where(eq(data.tools.cluster_id, clusterId));
 
+  if (tag) {
+    return tools.filter(t => t.config?.tags?.includes(tag));
+  }
+
   return tools;
 };
 
+// The maximum length of a tool description given to the model
+const TOOL_DESCRIPTION_LIMIT = 1024;
+
+/**
+ * Builds the description of a tool given to the model, adding its usage hints and examples to
+ * the registered description. The registered description is kept whole, metadata is truncated.
+ */
+export const describeTool = ({
+  name,
+  description,
+  config,
+}: {
+  name: string;
+  description?: string | null;
+  config?: ToolConfig | null;
+}) => {
+  const parts = [
+    (description ?? `${name} function`).substring(0, TOOL_DESCRIPTION_LIMIT),
+  ];
+
+  if (config?.usageHints) {
+    parts.push(`Usage: ${config.usageHints}`);
+  }
+
+  if (config?.examples?.length) {
+    parts.push(
+      [
+        "Examples:",
+        ...config.examples.map(
+          e =>
+            `- ${e.description ? `${e.description}: ` : ""}${JSON.stringify(e.input)}`,
+        ),
+      ].join("\n"),
+    );
+  }
+
+  return parts.join("\n\n").substring(0, TOOL_DESCRIPTION_LIMIT);
+};
+
 export const getToolDefinition = async ({
   name,
   clusterId,
@@ -1,4 +1,8 @@
-import { cleanupMarkedWorkflowExecutions } from "./executions";
+import {
+  cancelWorkflowExecution,
+  cleanupMarkedWorkflowExecutions,
+  listWorkflowExecutionChildren,
+} from "./executions";
 import { createCluster } from "../clusters/management";
 import * as data from "../data";
 import { count, eq, or } from "drizzle-orm";
@@ -283,4 +287,78 @@ describe("workflows/executions", () => {
       expect(kvData3.length).toBe(1);
     });
   });
+
+  describe("cancelWorkflowExecution", () => {
+    it("should cascade to agent runs and child executions", async () => {
+      const cluster = await createCluster({
+        description: "Test cluster for workflow execution cancellation",
+        organizationId: "test-org-id",
+      });
+
+      const createExecution = async (parentExecutionId?: string) => {
+        const job = await createJobV2({
+          owner: { clusterId: cluster.id },
+          targetFn: "testWorkflow",
+          targetArgs: packer.pack({ test: "data" }),
+          runId: getClusterBackgroundRun(cluster.id),
+        });
+        const executionId = ulid();
+        await data.db.insert(data.workflowExecutions).values({
+          id: executionId,
+          cluster_id: cluster.id,
+          job_id: job.id,
+          workflow_name: "testWorkflow",
+          workflow_version: 1,
+          parent_execution_id: parentExecutionId,
+        });
+        return { executionId, jobId: job.id };
+      };
+
+      const parent = await createExecution();
+      const child = await createExecution(parent.executionId);
+      const grandchild = await createExecution(child.executionId);
+
+      const run = await createRun({
+        clusterId: cluster.id,
+        workflowExecutionId: parent.executionId,
+        workflowName: "testWorkflow",
+        workflowVersion: 1,
+      });
+
+      const children = await listWorkflowExecutionChildren({
+        clusterId: cluster.id,
+        executionId: parent.executionId,
+      });
+      expect(children.executions.map(e => e.id)).toEqual([child.executionId]);
+      expect(children.runs.map(r => r.id)).toEqual([run.id]);
+
+      await cancelWorkflowExecution({
+        clusterId: cluster.id,
+        executionId: parent.executionId,
+        cascade: true,
+      });
+
+      const cancelled = await data.db
+        .select({ resultType: data.jobs.result_type })
+        .from(data.jobs)
+        .where(
+          or(
+            eq(data.jobs.id, parent.jobId),
+            eq(data.jobs.id, child.jobId),
+            eq(data.jobs.id, grandchild.jobId),
+          ),
+        );
+      expect(cancelled.map(j => j.resultType)).toEqual([
+        "rejection",
+        "rejection",
+        "rejection",
+      ]);
+
+      const [runData] = await data.db
+        .select({ status: data.runs.status })
+        .from(data.runs)
+        .where(eq(data.runs.id, run.id));
+      expect(runData.status).toBe("failed");
+    });
+  });
 });
@@ -4,7 +4,16 @@ import { packer } from "../../utilities/packer";
 import { getClusterBackgroundRun } from "../runs";
 import { BadRequestError, NotFoundError } from "../../utilities/errors";
 import * as data from "../data";
-import { and, desc, eq, sql, isNotNull, or } from "drizzle-orm";
+import {
+  and,
+  desc,
+  eq,
+  sql,
+  isNotNull,
+  isNull,
+  or,
+  inArray,
+} from "drizzle-orm";
 import { getWorkflowTools } from "../tools";
 import { logger } from "../observability/logger";
 import { getEventsForJobId } from "../observability/events";
@@ -291,6 +300,8 @@ export const createWorkflowExecution = async (
   clusterId: string,
   workflowName: string,
   input: unknown,
+  parentExecutionId?: string,
+  resumeJobId?: string,
 ) => {
   const parsed = z
     .object({
@@ -345,6 +356,8 @@ export const createWorkflowExecution = async (
       job_id: jobId,
       workflow_name: workflowName,
       workflow_version: version,
+      parent_execution_id: parentExecutionId,
+      resume_job_id: resumeJobId,
     })
     .onConflictDoNothing();
 
@@ -421,6 +434,135 @@ export const resumeWorkflowExecution = async ({
   return { jobId: updated?.id };
 };
 
+export const listWorkflowExecutionChildren = async ({
+  clusterId,
+  executionId,
+}: {
+  clusterId: string;
+  executionId: string;
+}) => {
+  const [executions, runs] = await Promise.all([
+    data.db
+      .select({
+        id: data.workflowExecutions.id,
+        workflowName: data.workflowExecutions.workflow_name,
+        workflowVersion: data.workflowExecutions.workflow_version,
+        status: data.jobs.status,
+        createdAt: data.workflowExecutions.created_at,
+      })
+      .from(data.workflowExecutions)
+      .leftJoin(
+        data.jobs,
+        and(
+          eq(data.workflowExecutions.job_id, data.jobs.id),
+          eq(data.workflowExecutions.cluster_id, data.jobs.cluster_id),
+        ),
+      )
+      .where(
+        and(
+          eq(data.workflowExecutions.cluster_id, clusterId),
+          eq(data.workflowExecutions.parent_execution_id, executionId),
+          isNull(data.workflowExecutions.deleted_at),
+        ),
+      ),
+    data.db
+      .select({
+        id: data.runs.id,
+        name: data.runs.name,
+        status: data.runs.status,
+        createdAt: data.runs.created_at,
+      })
+      .from(data.runs)
+      .where(
+        and(
+          eq(data.runs.cluster_id, clusterId),
+          eq(data.runs.workflow_execution_id, executionId),
+          isNull(data.runs.deleted_at),
+        ),
+      ),
+  ]);
+
+  return { executions, runs };
+};
+
+/**
+ * Cancels a workflow execution that has not finished. With cascade, its unfinished agent runs are
+ * failed and its child executions are cancelled the same way.
+ */
+export const cancelWorkflowExecution = async ({
+  clusterId,
+  executionId,
+  cascade,
+}: {
+  clusterId: string;
+  executionId: string;
+  cascade?: boolean;
+}) => {
+  const [execution] = await data.db
+    .select({
+      jobId: data.workflowExecutions.job_id,
+      status: data.jobs.status,
+    })
+    .from(data.workflowExecutions)
+    .leftJoin(
+      data.jobs,
+      and(
+        eq(data.workflowExecutions.job_id, data.jobs.id),
+        eq(data.workflowExecutions.cluster_id, data.jobs.cluster_id),
+      ),
+    )
+    .where(
+      and(
+        eq(data.workflowExecutions.cluster_id, clusterId),
+        eq(data.workflowExecutions.id, executionId),
+      ),
+    );
+
+  if (!execution) {
+    throw new NotFoundError(`Workflow execution ${executionId} not found`);
+  }
+
+  // Finished executions keep their result
+  if (
+    execution.jobId &&
+    execution.status !== "success" &&
+    execution.status !== "failure"
+  ) {
+    await jobs.cancelJob({ jobId: execution.jobId, clusterId });
+  }
+
+  if (!cascade) {
+    return;
+  }
+
+  await data.db
+    .update(data.runs)
+    .set({
+      status: "failed",
+      failure_reason: `Cancelled with workflow execution ${executionId}`,
+    })
+    .where(
+      and(
+        eq(data.runs.cluster_id, clusterId),
+        eq(data.runs.workflow_execution_id, executionId),
+        inArray(data.runs.status, ["pending", "running", "paused"]),
+      ),
+    );
+
+  const { executions } = await listWorkflowExecutionChildren({
+    clusterId,
+    executionId,
+  });
+
+  for (const child of executions) {
+    await cancelWorkflowExecution({
+      clusterId,
+      executionId: child.id,
+      cascade,
+    });
+  }
+};
+
 export const getWorkflowRuns = async ({
   clusterId,
   executionId,
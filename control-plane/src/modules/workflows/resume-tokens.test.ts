@@ -0,0 +1,159 @@
+import { ulid } from "ulid";
+import { env } from "../../utilities/env";
+import { packer } from "../../utilities/packer";
+import { createCluster } from "../clusters/management";
+import * as data from "../data";
+import { createJobV2 } from "../jobs/create-job";
+import { kv } from "../kv";
+import * as events from "../observability/events";
+import { getClusterBackgroundRun } from "../runs";
+import { upsertToolDefinition } from "../tools";
+import * as executions from "./executions";
+import {
+  createResumeToken,
+  redeemResumeToken,
+  resumePayloadKey,
+  TOKEN_TTL_SECONDS,
+} from "./resume-tokens";
+
+describe("resume tokens", () => {
+  let clusterId: string;
+  const executionId = ulid();
+
+  beforeAll(async () => {
+    events.initialize();
+    env.RESUME_TOKEN_SECRET = "test-resume-token-secret-of-32-chars";
+
+    const cluster = await createCluster({
+      description: "Test cluster for resume tokens",
+      organizationId: "test-org-id",
+    });
+    clusterId = cluster.id;
+
+    await upsertToolDefinition({
+      name: "signingWorkflow",
+      schema: JSON.stringify({ type: "object" }),
+      clusterId,
+    });
+
+    const job = await createJobV2({
+      owner: { clusterId },
+      targetFn: "signingWorkflow",
+      targetArgs: packer.pack({ executionId }),
+      runId: getClusterBackgroundRun(clusterId),
+    });
+
+    await data.db.insert(data.workflowExecutions).values({
+      id: executionId,
+      cluster_id: clusterId,
+      job_id: job.id,
+      workflow_name: "signing",
+      workflow_version: 1,
+    });
+  });
+
+  it("should store the payload of a redeemed token once", async () => {
+    const token = await createResumeToken({
+      clusterId,
+      workflowName: "signing",
+      executionId,
+      name: "contractSigned",
+    });
+
+    await redeemResumeToken({ token, payload: { envelopeId: "42" } });
+
+    expect(
+      await kv.get(clusterId, resumePayloadKey(executionId, "contractSigned")),
+    ).toEqual(JSON.stringify({ envelopeId: "42" }));
+
+    await expect(
+      redeemResumeToken({ token, payload: { envelopeId: "43" } }),
+    ).rejects.toThrow("already been redeemed");
+  });
+
+  it("should allow retrying a redemption that failed to resume", async () => {
+    const token = await createResumeToken({
+      clusterId,
+      workflowName: "signing",
+      executionId,
+      name: "invoicePaid",
+    });
+
+    const resume = jest
+      .spyOn(executions, "resumeWorkflowExecution")
+      .mockRejectedValueOnce(new Error("Connection terminated"));
+
+    await expect(
+      redeemResumeToken({ token, payload: { invoiceId: "7" } }),
+    ).rejects.toThrow("Connection terminated");
+
+    await expect(
+      redeemResumeToken({ token, payload: { invoiceId: "8" } }),
+    ).rejects.toThrow("already been redeemed");
+
+    await redeemResumeToken({ token, payload: { invoiceId: "7" } });
+    expect(resume).toHaveBeenCalledTimes(2);
+    resume.mockRestore();
+
+    await expect(
+      redeemResumeToken({ token, payload: { invoiceId: "7" } }),
+    ).rejects.toThrow("already been redeemed");
+  });
+
+  it("should reject expired tokens", async () => {
+    const token = await createResumeToken({
+      clusterId,
+      workflowName: "signing",
+      executionId,
+      name: "documentReturned",
+    });
+
+    const now = jest
+      .spyOn(Date, "now")
+      .mockReturnValue(Date.now() + TOKEN_TTL_SECONDS * 1000);
+
+    await expect(redeemResumeToken({ token, payload: {} })).rejects.toThrow(
+      "Resume token has expired",
+    );
+    now.mockRestore();
+
+    expect(
+      await kv.get(
+        clusterId,
+        resumePayloadKey(executionId, "documentReturned"),
+      ),
+    ).toBeNull();
+  });
+
+  it("should reject tampered tokens", async () => {
+    const token = await createResumeToken({
+      clusterId,
+      workflowName: "signing",
+      executionId,
+      name: "paymentReceived",
+    });
+
+    const [version, , signature] = token.split(".");
+    const forged = Buffer.from(
+      JSON.stringify({ c: clusterId, w: "signing", e: executionId, n: "other" }),
+    ).toString("base64url");
+
+    await expect(
+      redeemResumeToken({
+        token: `${version}.${forged}.${signature}`,
+        payload: {},
+      }),
+    ).rejects.toThrow("Invalid resume token");
+  });
+
+  it("should not issue tokens for unknown executions", async () => {
+    await expect(
+      createResumeToken({
+        clusterId,
+        workflowName: "signing",
+        executionId: ulid(),
+        name: "contractSigned",
+      }),
+    ).rejects.toThrow("not found");
+  });
+});
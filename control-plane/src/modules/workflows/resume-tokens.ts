@@ -0,0 +1,164 @@
+import * as crypto from "crypto";
+import { and, eq } from "drizzle-orm";
+import { z } from "zod";
+import {
+  AuthenticationError,
+  BadRequestError,
+  NotFoundError,
+} from "../../utilities/errors";
+import { env } from "../../utilities/env";
+import * as data from "../data";
+import { kv } from "../kv";
+import * as events from "../observability/events";
+import { resumeWorkflowExecution } from "./executions";
+
+// Resume tokens let a third party, e.g. an e-signature callback, resume a workflow execution
+// waiting on an interrupt, without a cluster API key. A token is signed and names the execution
+// and the wait it resumes. It can be redeemed once, and its payload is stored in the cluster KV
+// for the workflow handler to read.
+
+const TOKEN_VERSION = "rt1";
+
+const claimsSchema = z.object({
+  c: z.string(),
+  w: z.string(),
+  e: z.string(),
+  n: z.string(),
+});
+
+// The key the SDK reads the payload of a redeemed token from
+export const resumePayloadKey = (executionId: string, name: string) =>
+  `resume_${executionId}_${name}`;
+
+const sign = (encoded: string) => {
+  if (!env.RESUME_TOKEN_SECRET) {
+    throw new BadRequestError("Resume tokens are not enabled on this cluster");
+  }
+
+  return crypto
+    .createHmac("sha256", env.RESUME_TOKEN_SECRET)
+    .update(`${TOKEN_VERSION}.${encoded}`)
+    .digest("base64url");
+};
+
+export const createResumeToken = async ({
+  clusterId,
+  workflowName,
+  executionId,
+  name,
+}: {
+  clusterId: string;
+  workflowName: string;
+  executionId: string;
+  name: string;
+}) => {
+  const [execution] = await data.db
+    .select({ id: data.workflowExecutions.id })
+    .from(data.workflowExecutions)
+    .where(
+      and(
+        eq(data.workflowExecutions.cluster_id, clusterId),
+        eq(data.workflowExecutions.id, executionId),
+        eq(data.workflowExecutions.workflow_name, workflowName),
+      ),
+    );
+
+  if (!execution) {
+    throw new NotFoundError(`Workflow execution ${executionId} not found`);
+  }
+
+  const encoded = Buffer.from(
+    JSON.stringify({
+      c: clusterId,
+      w: workflowName,
+      e: executionId,
+      n: name,
+      exp: Math.floor(Date.now() / 1000) + TOKEN_TTL_SECONDS,
+    }),
+  ).toString("base64url");
+
+  return `${TOKEN_VERSION}.${encoded}.${sign(encoded)}`;
+};
+
+const verify = (token: string) => {
+  const [version, encoded, signature] = token.split(".");
+
+  if (version !== TOKEN_VERSION || !encoded || !signature) {
+    throw new AuthenticationError("Malformed resume token");
+  }
+
+  const expected = Buffer.from(sign(encoded));
+  const actual = Buffer.from(signature);
+
+  if (
+    expected.length !== actual.length ||
+    !crypto.timingSafeEqual(expected, actual)
+  ) {
+    throw new AuthenticationError("Invalid resume token");
+  }
+
+  const claims = claimsSchema.safeParse(
+    JSON.parse(Buffer.from(encoded, "base64url").toString()),
+  );
+
+  if (!claims.success) {
+    throw new AuthenticationError("Malformed resume token");
+  }
+
+  if (claims.data.exp * 1000 <= Date.now()) {
+    throw new AuthenticationError("Resume token has expired");
+  }
+
+  return claims.data;
+};
+
+export const redeemResumeToken = async ({
+  token,
+  payload,
+}: {
+  token: string;
+  payload: unknown;
+}) => {
+  const claims = verify(token);
+  const value = JSON.stringify(payload ?? null);
+
+  // The payload is stored before resuming, for the handler to read. A redemption that failed
+  // to resume can be retried with the same payload until the execution has been resumed.
+  const stored = await kv.setIfNotExists(
+    claims.c,
+    resumePayloadKey(claims.e, claims.n),
+    value,
+  );
+
+  if (stored === null) {
+    const [existing, resumed] = await Promise.all([
+      kv.get(claims.c, resumePayloadKey(claims.e, claims.n)),
+      kv.get(claims.c, resumedKey(claims.e, claims.n)),
+    ]);
+
+    if (existing !== value || resumed !== null) {
+      throw new BadRequestError("Resume token has already been redeemed");
+    }
+  }
+
+  await resumeWorkflowExecution({ clusterId: claims.c, id: claims.e });
+
+  await kv.setIfNotExists(claims.c, resumedKey(claims.e, claims.n), "true");
+
+  events.write({
+    type: "resumeTokenRedeemed",
+    clusterId: claims.c,
+    meta: {
+      workflowName: claims.w,
+      executionId: claims.e,
+      name: claims.n,
+    },
+  });
+
+  return {
+    clusterId: claims.c,
+    workflowName: claims.w,
+    executionId: claims.e,
+    name: claims.n,
+  };
+};
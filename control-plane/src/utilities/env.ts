@@ -46,6 +46,9 @@ const envSchema = z
 
     SLACK_SIGNING_SECRET: z.string().optional(),
 
+    // Signs workflow resume tokens, which are disabled without it
+    RESUME_TOKEN_SECRET: z.string().min(32).optional(),
+
     LOAD_TEST_CLUSTER_ID: z.string().optional(),
 
     // Required in EE (Disabled by default)
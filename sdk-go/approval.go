@@ -0,0 +1,123 @@
+package inferable
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+)
+
+// maxInlineApprovals bounds how often a call is run again after approving it inline, for handlers
+// that keep asking for approval.
+const maxInlineApprovals = 5
+
+// ApprovalRequest is an approval interrupt raised by a call, to be resolved by an ApprovalResolver.
+type ApprovalRequest struct {
+	// Tool is the name of the tool or workflow handler that raised the interrupt.
+	Tool string
+	// CallID identifies the call.
+	CallID string
+	// Interrupt is the approval interrupt, with its message and options.
+	Interrupt Interrupt
+}
+
+// ApprovalDecision resolves an approval interrupt.
+type ApprovalDecision struct {
+	Approved bool
+	// Option is the ID of the chosen option, if the interrupt offers options.
+	Option string
+}
+
+// ApprovalResolver resolves approval interrupts on the machine instead of waiting for someone to
+// resolve them in the cluster, so that approval-gated workflows can be iterated on without a
+// dashboard. An approved call runs again right away with ContextInput.Approved set, and a denied
+// call fails. Returning a nil decision leaves the interrupt to the cluster.
+//
+// Use it only in development, e.g. with AutoApprove or PromptApproval.
+type ApprovalResolver func(request ApprovalRequest) (*ApprovalDecision, error)
+
+// AutoApprove approves every approval interrupt, choosing the first option that does not deny the
+// call if the interrupt offers options.
+func AutoApprove() ApprovalResolver {
+	return func(request ApprovalRequest) (*ApprovalDecision, error) {
+		decision := &ApprovalDecision{Approved: true}
+		for _, option := range request.Interrupt.Options {
+			if !option.Deny {
+				decision.Option = option.ID
+				break
+			}
+		}
+		return decision, nil
+	}
+}
+
+// PromptApproval asks on a terminal, reading answers from in and writing prompts to out (e.g.
+// os.Stdin and os.Stdout), whether to approve each approval interrupt. Prompts of concurrent calls
+// are asked one at a time.
+//
+//	client, _ := inferable.New(inferable.InferableOptions{
+//		APISecret:        os.Getenv("INFERABLE_API_SECRET"),
+//		ApprovalResolver: inferable.PromptApproval(os.Stdin, os.Stdout),
+//	})
+func PromptApproval(in io.Reader, out io.Writer) ApprovalResolver {
+	var mu sync.Mutex
+	reader := bufio.NewReader(in)
+
+	return func(request ApprovalRequest) (*ApprovalDecision, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fmt.Fprintf(out, "Approval requested by %s (call %s): %s\n", request.Tool, request.CallID, request.Interrupt.Error())
+		for _, link := range request.Interrupt.Links {
+			fmt.Fprintf(out, "  %s: %s\n", link.Label, link.URL)
+		}
+
+		options := request.Interrupt.Options
+		if len(options) == 0 {
+			options = []ApprovalOption{{ID: "y", Label: "Approve"}, {ID: "n", Label: "Deny", Deny: true}}
+		}
+		for {
+			for _, option := range options {
+				fmt.Fprintf(out, "  [%s] %s\n", option.ID, option.Label)
+			}
+			fmt.Fprint(out, "> ")
+
+			answer, err := reader.ReadString('\n')
+			answer = strings.TrimSpace(answer)
+			if answer == "" && err != nil {
+				return nil, fmt.Errorf("failed to read approval of call %s: %v", request.CallID, err)
+			}
+
+			for _, option := range options {
+				if option.ID != answer {
+					continue
+				}
+				if len(request.Interrupt.Options) == 0 {
+					return &ApprovalDecision{Approved: !option.Deny}, nil
+				}
+				return &ApprovalDecision{Approved: !option.Deny, Option: option.ID}, nil
+			}
+			fmt.Fprintf(out, "Unknown choice %q\n", answer)
+		}
+	}
+}
+
+// resolveApproval resolves an approval interrupt with the client's ApprovalResolver, and reports
+// false when the interrupt is left to the cluster.
+func (i *Inferable) resolveApproval(request ApprovalRequest) (*ApprovalDecision, bool) {
+	if i.approvalResolver == nil || request.Interrupt.Type != APPROVAL {
+		return nil, false
+	}
+	decision, err := i.approvalResolver(request)
+	if err != nil {
+		if i.logger != nil {
+			i.logger.Error("Failed to resolve approval inline", map[string]interface{}{"tool": request.Tool, "callId": request.CallID, "error": err.Error()})
+		} else {
+			log.Printf("Failed to resolve approval of call %s inline: %v", request.CallID, err)
+		}
+		return nil, false
+	}
+	return decision, decision != nil
+}
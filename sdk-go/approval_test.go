@@ -0,0 +1,76 @@
+package inferable
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlineApproval(t *testing.T) {
+	var requests []ApprovalRequest
+	i, results := newResultRecorder(t, InferableOptions{
+		ApprovalResolver: func(request ApprovalRequest) (*ApprovalDecision, error) {
+			requests = append(requests, request)
+			if request.CallID == "job-2" {
+				return &ApprovalDecision{Approved: false}, nil
+			}
+			if request.CallID == "job-3" {
+				return nil, nil
+			}
+			return AutoApprove()(request)
+		},
+	})
+
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "refund",
+		Func: func(input chargeInput, ctx ContextInput) (interface{}, error) {
+			if !ctx.Approved {
+				return ApprovalInterrupt("Refund requested").
+					WithOptions(ApprovalOption{ID: "reject", Label: "Reject", Deny: true}, ApprovalOption{ID: "partial", Label: "Refund half"}), nil
+			}
+			if ctx.ApprovalOption == "partial" {
+				return input.Amount / 2, nil
+			}
+			return input.Amount, nil
+		},
+	}))
+
+	for _, id := range []string{"job-1", "job-2", "job-3"} {
+		require.NoError(t, i.Tools.handleMessage(callMessage{Id: id, Function: "refund", Input: map[string]interface{}{"amount": 100}}))
+	}
+
+	require.Len(t, results(), 3)
+	assert.Equal(t, "resolution", results()[0].ResultType)
+	assert.Equal(t, 50.0, results()[0].Result)
+	assert.Equal(t, "rejection", results()[1].ResultType)
+	assert.Contains(t, results()[1].Result, "was denied")
+
+	// A nil decision leaves the interrupt to the cluster
+	assert.Equal(t, "interrupt", results()[2].ResultType)
+
+	require.Len(t, requests, 3)
+	assert.Equal(t, "refund", requests[0].Tool)
+	assert.Equal(t, "Refund requested", requests[0].Interrupt.Message)
+}
+
+func TestPromptApproval(t *testing.T) {
+	var out bytes.Buffer
+	resolve := PromptApproval(strings.NewReader("maybe\ny\nfull\n"), &out)
+
+	decision, err := resolve(ApprovalRequest{Tool: "refund", CallID: "job-1", Interrupt: *ApprovalInterrupt("Refund $100?")})
+	require.NoError(t, err)
+	assert.Equal(t, &ApprovalDecision{Approved: true}, decision)
+	assert.Contains(t, out.String(), "Approval requested by refund (call job-1): Refund $100?")
+	assert.Contains(t, out.String(), `Unknown choice "maybe"`)
+
+	decision, err = resolve(ApprovalRequest{Tool: "refund", CallID: "job-2", Interrupt: *ApprovalInterrupt("Refund?").
+		WithOptions(ApprovalOption{ID: "full", Label: "Refund in full"})})
+	require.NoError(t, err)
+	assert.Equal(t, &ApprovalDecision{Approved: true, Option: "full"}, decision)
+
+	_, err = resolve(ApprovalRequest{Tool: "refund", CallID: "job-3", Interrupt: *ApprovalInterrupt("Refund?")})
+	assert.ErrorContains(t, err, "failed to read approval of call job-3")
+}
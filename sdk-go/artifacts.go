@@ -0,0 +1,350 @@
+package inferable
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+	"github.com/inferablehq/inferable/sdk-go/kv"
+)
+
+// artifactChunkSize is the size of the pieces artifacts are split into in the cluster's key-value
+// store, chosen so that an encoded piece stays well below the request body limit.
+const artifactChunkSize = 512 * 1024
+
+// artifactIDPrefix starts every artifact ID.
+const artifactIDPrefix = "artifact_"
+
+// artifactPointerKey is the only key of a value moved to an artifact, see
+// WorkflowConfig.ArtifactThreshold.
+const artifactPointerKey = "$artifact"
+
+// ErrArtifactNotFound is returned by Artifacts.Get for artifacts that were never stored or have
+// been deleted.
+var ErrArtifactNotFound = fmt.Errorf("artifact not found")
+
+// ArtifactRef is a stable reference to a stored artifact. It is small enough to return from a
+// handler or pass in a workflow input in place of the artifact's content.
+//
+// Artifacts are addressed by content: storing the same bytes again returns the same reference,
+// so a handler that is re-executed does not store its artifacts twice.
+type ArtifactRef struct {
+	ID          string `json:"artifactId"`
+	Size        int    `json:"size"`
+	ContentType string `json:"contentType,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+// ArtifactOptions describes an artifact stored with Artifacts.Put.
+type ArtifactOptions struct {
+	// ContentType is the artifact's media type, e.g. "text/csv". It is carried on the reference only.
+	ContentType string
+	// Name is a human-readable name, e.g. a file name. It is carried on the reference only.
+	Name string
+}
+
+// ArtifactStore holds artifact content by ID. The default implementation stores artifacts in the
+// cluster; implement it to keep them in object storage instead.
+type ArtifactStore interface {
+	// Put stores data under id. Since IDs are derived from content, storing under an existing ID
+	// may be skipped.
+	Put(id string, data []byte) error
+	// Get returns the data stored under id, and false if there is none.
+	Get(id string) ([]byte, bool, error)
+	// Delete removes the data stored under id, if any.
+	Delete(id string) error
+}
+
+// Artifacts stores large binary or text outputs, such as reports and datasets, outside of
+// workflow payloads. It is exposed as client.Artifacts.
+//
+//	ref, err := client.Artifacts.Put(report, inferable.ArtifactOptions{ContentType: "text/csv"})
+//	...
+//	report, err := client.Artifacts.Get(*ref)
+type Artifacts struct {
+	inferable *Inferable
+}
+
+// artifactID derives the ID of an artifact from its content.
+func artifactID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return artifactIDPrefix + hex.EncodeToString(sum[:])
+}
+
+// store resolves the client's ArtifactStore, making sure the cluster is known for the cluster store.
+func (a *Artifacts) store() (ArtifactStore, error) {
+	if a.inferable.artifactStore != nil {
+		return a.inferable.artifactStore, nil
+	}
+	if _, err := a.inferable.getClusterId(); err != nil {
+		return nil, fmt.Errorf("failed to get cluster id: %w", err)
+	}
+	return &clusterArtifactStore{kv: &clusterKVStore{inferable: a.inferable}}, nil
+}
+
+// Put stores data and returns a reference to it.
+func (a *Artifacts) Put(data []byte, options ...ArtifactOptions) (*ArtifactRef, error) {
+	store, err := a.store()
+	if err != nil {
+		return nil, err
+	}
+
+	ref := &ArtifactRef{ID: artifactID(data), Size: len(data)}
+	for _, option := range options {
+		if option.ContentType != "" {
+			ref.ContentType = option.ContentType
+		}
+		if option.Name != "" {
+			ref.Name = option.Name
+		}
+	}
+
+	if err := store.Put(ref.ID, data); err != nil {
+		return nil, fmt.Errorf("failed to store artifact: %v", err)
+	}
+	return ref, nil
+}
+
+// Get returns the content of a stored artifact. It returns ErrArtifactNotFound if the artifact is
+// not stored, and an error if the stored content does not match the reference.
+func (a *Artifacts) Get(ref ArtifactRef) ([]byte, error) {
+	if !strings.HasPrefix(ref.ID, artifactIDPrefix) {
+		return nil, fmt.Errorf("invalid artifact id %q", ref.ID)
+	}
+
+	store, err := a.store()
+	if err != nil {
+		return nil, err
+	}
+
+	data, ok, err := store.Get(ref.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifact: %v", err)
+	}
+	if !ok {
+		return nil, ErrArtifactNotFound
+	}
+	if artifactID(data) != ref.ID {
+		return nil, fmt.Errorf("artifact %s does not match its content", ref.ID)
+	}
+	return data, nil
+}
+
+// Delete removes a stored artifact. References to it, including those to the same content stored
+// elsewhere, no longer resolve afterwards.
+func (a *Artifacts) Delete(ref ArtifactRef) error {
+	if !strings.HasPrefix(ref.ID, artifactIDPrefix) {
+		return fmt.Errorf("invalid artifact id %q", ref.ID)
+	}
+
+	store, err := a.store()
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(ref.ID); err != nil {
+		return fmt.Errorf("failed to delete artifact: %v", err)
+	}
+	return nil
+}
+
+// offload stores value as an artifact if its serialized form is larger than threshold bytes, and
+// returns a pointer to the artifact in its place. Smaller values, and any value for a threshold of
+// 0, are returned as is.
+func (a *Artifacts) offload(value interface{}, threshold int) (interface{}, error) {
+	if threshold <= 0 {
+		return value, nil
+	}
+	serialized, err := kv.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(serialized) <= threshold {
+		return value, nil
+	}
+
+	ref, err := a.Put([]byte(serialized), ArtifactOptions{ContentType: "application/json"})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{artifactPointerKey: ref}, nil
+}
+
+// rehydrate returns the value a pointer made by offload points to, either as returned or decoded
+// from JSON. Other values are returned as is.
+func (a *Artifacts) rehydrate(value interface{}) (interface{}, error) {
+	pointer, ok := value.(map[string]interface{})
+	if !ok || len(pointer) != 1 {
+		return value, nil
+	}
+	target, ok := pointer[artifactPointerKey]
+	if !ok {
+		return value, nil
+	}
+
+	var ref ArtifactRef
+	data, err := json.Marshal(target)
+	if err == nil {
+		err = json.Unmarshal(data, &ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifact pointer: %v", err)
+	}
+
+	content, err := a.Get(ref)
+	if err != nil {
+		return nil, err
+	}
+	return kv.Decode[interface{}](string(content))
+}
+
+// artifactManifest is stored under an artifact's ID once all of its chunks are stored.
+type artifactManifest struct {
+	Size   int `json:"size"`
+	Chunks int `json:"chunks"`
+}
+
+// clusterArtifactStore stores artifacts in the cluster's key-value store, as base64 chunks keyed
+// "<id>_<n>" followed by a manifest keyed by the ID. Readers only see an artifact once its
+// manifest exists, that is once every chunk has been written.
+type clusterArtifactStore struct {
+	kv *clusterKVStore
+}
+
+func (s *clusterArtifactStore) Put(id string, data []byte) error {
+	if _, ok, err := s.kv.Get(id); err != nil || ok {
+		return err
+	}
+
+	manifest := artifactManifest{Size: len(data)}
+	for start := 0; start < len(data); start += artifactChunkSize {
+		chunk := data[start:min(start+artifactChunkSize, len(data))]
+		key := fmt.Sprintf("%s_%d", id, manifest.Chunks)
+		if err := s.kv.SetIfAbsent(key, base64.StdEncoding.EncodeToString(chunk)); err != nil {
+			return err
+		}
+		manifest.Chunks++
+	}
+
+	serialized, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return s.kv.SetIfAbsent(id, string(serialized))
+}
+
+func (s *clusterArtifactStore) Get(id string) ([]byte, bool, error) {
+	serialized, ok, err := s.kv.Get(id)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	var manifest artifactManifest
+	if err := json.Unmarshal([]byte(serialized), &manifest); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal artifact manifest: %v", err)
+	}
+
+	data := make([]byte, 0, manifest.Size)
+	for n := 0; n < manifest.Chunks; n++ {
+		encoded, ok, err := s.kv.Get(fmt.Sprintf("%s_%d", id, n))
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			return nil, false, fmt.Errorf("artifact %s is missing chunk %d", id, n)
+		}
+		chunk, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to decode artifact chunk %d: %v", n, err)
+		}
+		data = append(data, chunk...)
+	}
+	return data, true, nil
+}
+
+func (s *clusterArtifactStore) Delete(id string) error {
+	serialized, ok, err := s.kv.Get(id)
+	if err != nil || !ok {
+		return err
+	}
+	var manifest artifactManifest
+	if err := json.Unmarshal([]byte(serialized), &manifest); err != nil {
+		return fmt.Errorf("failed to unmarshal artifact manifest: %v", err)
+	}
+
+	// The manifest goes first, so readers never see a partially deleted artifact
+	if err := s.delete(id); err != nil {
+		return err
+	}
+	for n := 0; n < manifest.Chunks; n++ {
+		if err := s.delete(fmt.Sprintf("%s_%d", id, n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *clusterArtifactStore) delete(key string) error {
+	_, _, err, statusCode := s.kv.inferable.client.FetchData(client.FetchDataOptions{
+		Path:   fmt.Sprintf("/clusters/%s/keys/%s", s.kv.inferable.clusterID, key),
+		Method: "DELETE",
+	})
+	if statusCode == 404 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete key: %w", err)
+	}
+	return nil
+}
+
+// MemoryArtifactStore is an in-process ArtifactStore for tests and local runs.
+type MemoryArtifactStore struct {
+	mu        sync.Mutex
+	artifacts map[string][]byte
+}
+
+// NewMemoryArtifactStore creates an empty MemoryArtifactStore.
+func NewMemoryArtifactStore() *MemoryArtifactStore {
+	return &MemoryArtifactStore{artifacts: map[string][]byte{}}
+}
+
+func (s *MemoryArtifactStore) Put(id string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.artifacts[id] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemoryArtifactStore) Get(id string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.artifacts[id]
+	if !ok {
+		return nil, false, nil
+	}
+	return append([]byte(nil), data...), true, nil
+}
+
+func (s *MemoryArtifactStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.artifacts, id)
+	return nil
+}
+
+// IDs returns the IDs of the stored artifacts in sorted order.
+func (s *MemoryArtifactStore) IDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := make([]string, 0, len(s.artifacts))
+	for id := range s.artifacts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
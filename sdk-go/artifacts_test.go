@@ -0,0 +1,147 @@
+package inferable
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactsMemoryStore(t *testing.T) {
+	store := NewMemoryArtifactStore()
+	i := newTestClient(t, InferableOptions{ArtifactStore: store}, nil)
+
+	ref, err := i.Artifacts.Put([]byte("id,total\n1,42\n"), ArtifactOptions{ContentType: "text/csv", Name: "report.csv"})
+	require.NoError(t, err)
+	assert.Equal(t, 14, ref.Size)
+	assert.Equal(t, "text/csv", ref.ContentType)
+
+	// The same content is stored once, under the same reference
+	again, err := i.Artifacts.Put([]byte("id,total\n1,42\n"))
+	require.NoError(t, err)
+	assert.Equal(t, ref.ID, again.ID)
+	assert.Len(t, store.IDs(), 1)
+
+	// References survive a round trip through a JSON payload
+	payload, err := json.Marshal(map[string]interface{}{"report": ref})
+	require.NoError(t, err)
+	var decoded struct {
+		Report ArtifactRef `json:"report"`
+	}
+	require.NoError(t, json.Unmarshal(payload, &decoded))
+
+	data, err := i.Artifacts.Get(decoded.Report)
+	require.NoError(t, err)
+	assert.Equal(t, "id,total\n1,42\n", string(data))
+
+	store.Put(ref.ID, []byte("tampered"))
+	_, err = i.Artifacts.Get(*ref)
+	assert.ErrorContains(t, err, "does not match its content")
+
+	require.NoError(t, i.Artifacts.Delete(*ref))
+	_, err = i.Artifacts.Get(*ref)
+	assert.ErrorIs(t, err, ErrArtifactNotFound)
+
+	_, err = i.Artifacts.Get(ArtifactRef{ID: "../keys"})
+	assert.ErrorContains(t, err, "invalid artifact id")
+}
+
+func TestArtifactsClusterStore(t *testing.T) {
+	var mu sync.Mutex
+	values := map[string]string{}
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/keys/": func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimPrefix(r.URL.Path, "/clusters/test-cluster/keys/")
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch r.Method {
+			case "GET":
+				key = strings.TrimSuffix(key, "/value")
+				value, ok := values[key]
+				if !ok {
+					json.NewEncoder(w).Encode(map[string]interface{}{"value": nil})
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+			case "PUT":
+				var body struct {
+					Value string `json:"value"`
+				}
+				data, _ := io.ReadAll(r.Body)
+				require.NoError(t, json.Unmarshal(data, &body))
+				assert.Less(t, len(data), 1024*1024)
+				if _, ok := values[key]; !ok {
+					values[key] = body.Value
+				}
+				json.NewEncoder(w).Encode(map[string]interface{}{"value": values[key]})
+			case "DELETE":
+				delete(values, key)
+				w.WriteHeader(http.StatusNoContent)
+			}
+		},
+	})
+
+	// Split into two chunks, stored alongside a manifest
+	dataset := bytes.Repeat([]byte("0123456789abcdef"), artifactChunkSize/8)
+	ref, err := i.Artifacts.Put(dataset)
+	require.NoError(t, err)
+	assert.Len(t, values, 3)
+
+	data, err := i.Artifacts.Get(*ref)
+	require.NoError(t, err)
+	assert.Equal(t, dataset, data)
+
+	require.NoError(t, i.Artifacts.Delete(*ref))
+	assert.Empty(t, values)
+	_, err = i.Artifacts.Get(*ref)
+	assert.ErrorIs(t, err, ErrArtifactNotFound)
+}
+
+func TestArtifactThreshold(t *testing.T) {
+	store := NewMemoryStore()
+	artifacts := NewMemoryArtifactStore()
+	i := newTestClient(t, InferableOptions{KVStore: store, ArtifactStore: artifacts}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "byref", InputSchema: WorkflowInput{}, ArtifactThreshold: 1024})
+
+	calls := 0
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		rows, err := ctx.Memo("rows", func() (interface{}, error) {
+			calls++
+			return strings.Repeat("row\n", 1000), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		small, err := ctx.Memo("small", func() (interface{}, error) {
+			return "small", nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"rows": rows, "small": small}, nil
+	})
+
+	for attempt := 0; attempt < 2; attempt++ {
+		result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"rows": strings.Repeat("row\n", 1000), "small": "small"}, result)
+	}
+	assert.Equal(t, 1, calls)
+
+	// Only a reference to the large result is memoized
+	memo, ok := store.Memo("exec-1", "rows")
+	require.True(t, ok)
+	assert.Contains(t, memo, artifactPointerKey)
+	memo, _ = store.Memo("exec-1", "small")
+	assert.Equal(t, "small", memo)
+
+	// The memo and the handler's result, which includes it
+	assert.Len(t, artifacts.IDs(), 2)
+}
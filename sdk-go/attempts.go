@@ -0,0 +1,146 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// unfinishedAttemptReason is the failure reason of an attempt that never reported an outcome.
+const unfinishedAttemptReason = "attempt did not finish, e.g. the machine stopped or the call timed out"
+
+// AttemptFailure is a failed attempt of an execution's handler, see WorkflowContext.PreviousFailures.
+type AttemptFailure struct {
+	// Attempt is the number of the failed attempt, starting at 1.
+	Attempt int `json:"attempt"`
+	// Reason is the error or panic the attempt failed with.
+	Reason string `json:"reason"`
+	// At is when the attempt failed, or the zero time if it did not finish.
+	At time.Time `json:"at,omitempty"`
+}
+
+// attemptRun is the record of one start of an execution's handler. Resuming an execution after an
+// interrupt starts a new run of the same attempt, and a run that fails or does not finish makes
+// the next run a new attempt.
+type attemptRun struct {
+	Attempt   int       `json:"attempt"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// attemptOutcome is how a run ended.
+type attemptOutcome struct {
+	Failed bool      `json:"failed,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// attemptRunKey is the key under which a run of an execution's handler is recorded.
+func attemptRunKey(executionId string, run int) string {
+	return fmt.Sprintf("%s_run_%d", executionId, run)
+}
+
+// attemptOutcomeKey is the key under which the outcome of a run is recorded.
+func attemptOutcomeKey(executionId string, run int) string {
+	return fmt.Sprintf("%s_run_%d_outcome", executionId, run)
+}
+
+// attemptTracker records the runs of an execution's handler in the store backing ctx.Memo, and
+// derives the attempt report of each run from those before it.
+type attemptTracker struct {
+	store       KVStore
+	executionId string
+	// run is the number of the current run, and 0 before start
+	run int
+
+	attempt          int
+	firstAttemptAt   time.Time
+	previousFailures []AttemptFailure
+}
+
+// startAttempt records the start of a run of the handler.
+func startAttempt(store KVStore, executionId string, now time.Time) (*attemptTracker, error) {
+	t := &attemptTracker{store: store, executionId: executionId, attempt: 1, firstAttemptAt: now}
+
+	for run := 1; ; run++ {
+		serialized, ok, err := store.Get(attemptRunKey(executionId, run))
+		if err != nil {
+			return t, fmt.Errorf("failed to read attempts: %v", err)
+		}
+		if !ok {
+			t.run = run
+			break
+		}
+
+		var previous attemptRun
+		if err := json.Unmarshal([]byte(serialized), &previous); err != nil {
+			return t, fmt.Errorf("failed to unmarshal attempt: %v", err)
+		}
+		if run == 1 {
+			t.firstAttemptAt = previous.StartedAt
+		}
+
+		serialized, ok, err = store.Get(attemptOutcomeKey(executionId, run))
+		if err != nil {
+			return t, fmt.Errorf("failed to read attempts: %v", err)
+		}
+		var outcome attemptOutcome
+		if ok {
+			if err := json.Unmarshal([]byte(serialized), &outcome); err != nil {
+				return t, fmt.Errorf("failed to unmarshal attempt outcome: %v", err)
+			}
+		}
+
+		switch {
+		case !ok:
+			t.previousFailures = append(t.previousFailures, AttemptFailure{Attempt: previous.Attempt, Reason: unfinishedAttemptReason})
+			t.attempt = previous.Attempt + 1
+		case outcome.Failed:
+			t.previousFailures = append(t.previousFailures, AttemptFailure{Attempt: previous.Attempt, Reason: outcome.Reason, At: outcome.At})
+			t.attempt = previous.Attempt + 1
+		default:
+			t.attempt = previous.Attempt
+		}
+	}
+
+	serialized, err := json.Marshal(attemptRun{Attempt: t.attempt, StartedAt: now})
+	if err != nil {
+		return t, err
+	}
+	if err := store.SetIfAbsent(attemptRunKey(executionId, t.run), string(serialized)); err != nil {
+		t.run = 0
+		return t, fmt.Errorf("failed to record attempt: %v", err)
+	}
+	return t, nil
+}
+
+// finish records the outcome of the run, once. A failed run makes the next run a new attempt.
+func (t *attemptTracker) finish(failed bool, reason string, now time.Time) error {
+	if t.run == 0 {
+		return nil
+	}
+	serialized, err := json.Marshal(attemptOutcome{Failed: failed, Reason: reason, At: now})
+	if err != nil {
+		return err
+	}
+	run := t.run
+	t.run = 0
+	if err := t.store.SetIfAbsent(attemptOutcomeKey(t.executionId, run), string(serialized)); err != nil {
+		return fmt.Errorf("failed to record attempt outcome: %v", err)
+	}
+	return nil
+}
+
+// report sets the attempt report of the run on ctx.
+func (t *attemptTracker) report(ctx *WorkflowContext) {
+	ctx.Attempt = t.attempt
+	ctx.FirstAttemptAt = t.firstAttemptAt
+	ctx.PreviousFailures = t.previousFailures
+}
+
+// SinceFirstAttempt returns the time since the first attempt of the execution's handler started.
+func (ctx WorkflowContext) SinceFirstAttempt() time.Duration {
+	if ctx.Now == nil || ctx.FirstAttemptAt.IsZero() {
+		return 0
+	}
+	return ctx.Now().Sub(ctx.FirstAttemptAt)
+}
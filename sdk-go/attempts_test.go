@@ -0,0 +1,77 @@
+package inferable
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartAttempt(t *testing.T) {
+	store := NewMemoryStore()
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	run, err := startAttempt(store, "exec-1", first)
+	require.NoError(t, err)
+	assert.Equal(t, 1, run.attempt)
+	assert.Empty(t, run.previousFailures)
+	require.NoError(t, run.finish(true, "provider unavailable", first.Add(time.Second)))
+
+	// Resuming after an interrupt continues the attempt
+	run, err = startAttempt(store, "exec-1", first.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, run.attempt)
+	require.NoError(t, run.finish(false, "", first.Add(2*time.Minute)))
+
+	run, err = startAttempt(store, "exec-1", first.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, run.attempt)
+
+	// A run that never finishes counts as a failure
+	run, err = startAttempt(store, "exec-1", first.Add(4*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 3, run.attempt)
+	assert.Equal(t, first, run.firstAttemptAt)
+	assert.Equal(t, []AttemptFailure{
+		{Attempt: 1, Reason: "provider unavailable", At: first.Add(time.Second)},
+		{Attempt: 2, Reason: unfinishedAttemptReason},
+	}, run.previousFailures)
+
+	// Runs of other executions are counted separately
+	other, err := startAttempt(store, "exec-2", first)
+	require.NoError(t, err)
+	assert.Equal(t, 1, other.attempt)
+}
+
+func TestWorkflowContextAttempts(t *testing.T) {
+	store := NewMemoryStore()
+	i := newTestClient(t, InferableOptions{KVStore: store}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "summarize", InputSchema: WorkflowInput{}})
+
+	var reports []WorkflowContext
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		reports = append(reports, ctx)
+		if ctx.Attempt < 3 {
+			return nil, errors.New("provider unavailable")
+		}
+		return "fallback", nil
+	})
+
+	for run := 0; run < 2; run++ {
+		_, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+		require.Error(t, err)
+	}
+	result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", result)
+
+	require.Len(t, reports, 3)
+	last := reports[2]
+	assert.Equal(t, 3, last.Attempt)
+	require.Len(t, last.PreviousFailures, 2)
+	assert.Equal(t, "provider unavailable", last.PreviousFailures[1].Reason)
+	assert.True(t, reports[0].FirstAttemptAt.Equal(last.FirstAttemptAt))
+	assert.GreaterOrEqual(t, last.SinceFirstAttempt(), time.Duration(0))
+}
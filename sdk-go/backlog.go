@@ -0,0 +1,126 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+// backlogLimit is the largest page the control plane returns when listing jobs.
+const backlogLimit = 20
+
+// Backlog is the work queued in the cluster for the tools and workflows this client registers,
+// as a signal for horizontal autoscalers.
+type Backlog struct {
+	// PendingExecutions is the number of workflow executions waiting for a machine.
+	PendingExecutions int `json:"pendingExecutions"`
+	// PendingToolCalls is the number of tool calls waiting for a machine.
+	PendingToolCalls int `json:"pendingToolCalls"`
+	// Total is PendingExecutions plus PendingToolCalls.
+	Total int `json:"total"`
+	// Saturated reports that the listing limit was reached, so the backlog is at least Total.
+	Saturated bool `json:"saturated"`
+	// InFlight is the number of calls this machine is handling right now.
+	InFlight   int64     `json:"inFlight"`
+	MeasuredAt time.Time `json:"measuredAt"`
+}
+
+// Backlog returns the pending work for the tools registered with the client and the workflows
+// created with it. The cluster lists at most 20 pending jobs per request, so a Saturated backlog
+// means "at least Total"; that is enough for an autoscaler to keep scaling out.
+func (i *Inferable) Backlog() (*Backlog, error) {
+	tools := i.registrationTools()
+
+	backlog := &Backlog{InFlight: i.Tools.inFlight.Load(), MeasuredAt: time.Now()}
+	if len(tools) == 0 {
+		return backlog, nil
+	}
+
+	clusterId, err := i.getClusterId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster id: %w", err)
+	}
+
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+
+	result, _, err, status := i.fetchData(client.FetchDataOptions{
+		Path:   fmt.Sprintf("/clusters/%s/jobs", clusterId),
+		Method: "GET",
+		Headers: map[string]string{
+			"Authorization":          "Bearer " + i.apiSecret,
+			"X-Machine-ID":           i.machineID,
+			"X-Machine-SDK-Version":  Version,
+			"X-Machine-SDK-Language": "go",
+		},
+		QueryParams: map[string]string{
+			"tools":       strings.Join(names, ","),
+			"status":      "pending",
+			"acknowledge": "false",
+			"limit":       fmt.Sprint(backlogLimit),
+		},
+		Idempotent: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending jobs: %w", err)
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("failed to list pending jobs: %w", client.UnexpectedStatus(status))
+	}
+
+	var jobs []struct {
+		Function string `json:"function"`
+	}
+	if err := json.Unmarshal(result, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal pending jobs: %v", err)
+	}
+
+	for _, job := range jobs {
+		if strings.HasPrefix(job.Function, "workflows_") {
+			backlog.PendingExecutions++
+		} else {
+			backlog.PendingToolCalls++
+		}
+	}
+	backlog.Total = len(jobs)
+	backlog.Saturated = len(jobs) >= backlogLimit
+
+	return backlog, nil
+}
+
+// BacklogHandler serves Backlog as JSON, for the KEDA metrics-api scaler or any autoscaler that
+// scrapes HTTP. Point the scaler's valueLocation at "total", or at "pendingExecutions" to scale on
+// workflow executions only:
+//
+//	http.Handle("/backlog", client.BacklogHandler())
+//
+//	# ScaledObject trigger
+//	- type: metrics-api
+//	  metadata:
+//	    url: "http://worker:8080/backlog"
+//	    valueLocation: "total"
+//	    targetValue: "5"
+func (i *Inferable) BacklogHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		backlog, err := i.Backlog()
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		json.NewEncoder(w).Encode(backlog)
+	})
+}
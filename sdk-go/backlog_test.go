@@ -0,0 +1,65 @@
+package inferable
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBacklog(t *testing.T) {
+	var query map[string]string
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/jobs": func(w http.ResponseWriter, r *http.Request) {
+			query = map[string]string{}
+			for key := range r.URL.Query() {
+				query[key] = r.URL.Query().Get(key)
+			}
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"id": "1", "function": "workflows_tickets_1"},
+				{"id": "2", "function": "workflows_tickets_1"},
+				{"id": "3", "function": "tool_tickets_lookup"},
+			})
+		},
+	})
+
+	// Nothing is registered yet, so there is no backlog to ask for
+	backlog, err := i.Backlog()
+	require.NoError(t, err)
+	assert.Equal(t, 0, backlog.Total)
+	assert.Nil(t, query)
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "tickets", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return nil, nil
+	})
+	workflow.Tools.Register(WorkflowTool{
+		Name: "lookup",
+		Func: func(input struct{}, ctx ContextInput) (string, error) { return "", nil },
+	})
+
+	backlog, err = i.Backlog()
+	require.NoError(t, err)
+	assert.Equal(t, 2, backlog.PendingExecutions)
+	assert.Equal(t, 1, backlog.PendingToolCalls)
+	assert.Equal(t, 3, backlog.Total)
+	assert.False(t, backlog.Saturated)
+	assert.Equal(t, map[string]string{
+		"tools":       "tool_tickets_lookup,workflows_tickets_1",
+		"status":      "pending",
+		"acknowledge": "false",
+		"limit":       "20",
+	}, query)
+
+	recorder := httptest.NewRecorder()
+	i.BacklogHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/backlog", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var served map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &served))
+	assert.Equal(t, 3.0, served["total"])
+	assert.Equal(t, 2.0, served["pendingExecutions"])
+}
@@ -0,0 +1,121 @@
+package inferable
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Blueprint is a reusable group of workflow steps, such as fetching a document, extracting
+// fields from it, and validating them, defined once and embedded in the handlers of many
+// workflows. Each embedding passes its own TParams, e.g. the schema or instructions of the
+// extraction, and runs the steps on a TInput to produce a TOutput.
+//
+// The steps of an embedding memoize their results under the embedding's name, see Step, so that
+// a workflow can embed several blueprints, or one blueprint several times, without their cached
+// results colliding.
+//
+//	extraction := inferable.NewBlueprint("extract", func(ctx inferable.WorkflowContext, params ExtractParams, input Document) (Fields, error) {
+//		text, err := inferable.Step(ctx, "fetch", func() (string, error) { return fetch(input.URL) })
+//		if err != nil {
+//			return Fields{}, err
+//		}
+//		return inferable.Step(ctx, "extract", func() (Fields, error) {
+//			return inferable.Structured[Fields](ctx.LLM, inferable.StructuredInput{Input: text, Instructions: params.Instructions})
+//		})
+//	})
+type Blueprint[TParams any, TInput any, TOutput any] struct {
+	name  string
+	steps func(ctx WorkflowContext, params TParams, input TInput) (TOutput, error)
+}
+
+// NewBlueprint defines a blueprint named name, whose steps are run by steps.
+func NewBlueprint[TParams any, TInput any, TOutput any](name string, steps func(ctx WorkflowContext, params TParams, input TInput) (TOutput, error)) *Blueprint[TParams, TInput, TOutput] {
+	if name == "" {
+		panic("blueprint name must not be empty")
+	}
+	return &Blueprint[TParams, TInput, TOutput]{name: name, steps: steps}
+}
+
+// Name returns the name of the blueprint.
+func (b *Blueprint[TParams, TInput, TOutput]) Name() string {
+	return b.name
+}
+
+// Run runs the blueprint's steps in a workflow handler, with their results memoized under the
+// blueprint's name.
+//
+//	invoice, err := extraction.Run(ctx, ExtractParams{Instructions: "Extract the invoice total"}, input.Document)
+func (b *Blueprint[TParams, TInput, TOutput]) Run(ctx WorkflowContext, params TParams, input TInput) (TOutput, error) {
+	return b.RunAs(ctx, b.name, params, input)
+}
+
+// RunAs runs the blueprint's steps like Run, with their results memoized under name instead, for
+// handlers that embed the blueprint more than once.
+//
+//	invoice, err := extraction.RunAs(ctx, "invoice", invoiceParams, input.Invoice)
+//	receipt, err := extraction.RunAs(ctx, "receipt", receiptParams, input.Receipt)
+func (b *Blueprint[TParams, TInput, TOutput]) RunAs(ctx WorkflowContext, name string, params TParams, input TInput) (TOutput, error) {
+	output, err := b.steps(scopeMemo(ctx, name), params, input)
+	if err != nil {
+		return output, fmt.Errorf("blueprint %s: %w", name, err)
+	}
+	return output, nil
+}
+
+// Handler returns a workflow handler that runs the blueprint with params on the workflow's
+// input, for workflows that consist of the blueprint alone.
+//
+//	workflow := inferable.CreateTyped[Document](client.Workflows, inferable.WorkflowConfig{Name: "invoices"})
+//	workflow.Version(1).Define(extraction.Handler(ExtractParams{Instructions: "Extract the invoice total"}))
+func (b *Blueprint[TParams, TInput, TOutput]) Handler(params TParams) func(ctx WorkflowContext, input TInput) (interface{}, error) {
+	return func(ctx WorkflowContext, input TInput) (interface{}, error) {
+		output, err := b.Run(ctx, params, input)
+		if err != nil {
+			return nil, err
+		}
+		return output, nil
+	}
+}
+
+// Step runs fn once per execution and returns its result decoded into a T, memoized under name
+// like WorkflowContext.Memo. Steps run by a Blueprint are memoized under the blueprint's name.
+//
+//	text, err := inferable.Step(ctx, "fetch", func() (string, error) {
+//		return fetch(input.URL)
+//	})
+func Step[T any](ctx WorkflowContext, name string, fn func() (T, error)) (T, error) {
+	var result T
+	value, err := ctx.Memo(name, func() (interface{}, error) {
+		return fn()
+	})
+	if err != nil {
+		return result, err
+	}
+
+	resultType := reflect.TypeOf((*T)(nil)).Elem()
+	decoded, err := decodeInto(resultType, value)
+	if err != nil {
+		return result, fmt.Errorf("failed to decode result of step %s into %v: %v", name, resultType, err)
+	}
+	return decoded.(T), nil
+}
+
+// scopeMemo returns a copy of ctx whose memoized results are named within scope.
+func scopeMemo(ctx WorkflowContext, scope string) WorkflowContext {
+	memoWithOptions := ctx.MemoWithOptions
+	ctx.MemoWithOptions = func(name string, options MemoOptions, fn func() (interface{}, error)) (interface{}, error) {
+		return memoWithOptions(scope+":"+name, options, fn)
+	}
+	ctx.Memo = func(name string, fn func() (interface{}, error)) (interface{}, error) {
+		return ctx.MemoWithOptions(name, MemoOptions{}, fn)
+	}
+
+	// Contexts bound to a request context stay in the scope
+	if withContext := ctx.withContext; withContext != nil {
+		ctx.withContext = func(requestCtx context.Context) WorkflowContext {
+			return scopeMemo(withContext(requestCtx), scope)
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,88 @@
+package inferable
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type extractParams struct {
+	Field string
+}
+
+type extractedField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type blueprintDocument struct {
+	ExecutionID string `json:"executionId"`
+	Text        string `json:"text"`
+}
+
+func TestBlueprint(t *testing.T) {
+	store := NewMemoryStore()
+	i, err := New(InferableOptions{KVStore: store, Offline: true})
+	require.NoError(t, err)
+
+	fetches := 0
+	extraction := NewBlueprint("extract", func(ctx WorkflowContext, params extractParams, input blueprintDocument) (extractedField, error) {
+		text, err := Step(ctx, "fetch", func() (string, error) {
+			fetches++
+			return strings.ToUpper(input.Text), nil
+		})
+		if err != nil {
+			return extractedField{}, err
+		}
+		field, err := Step(ctx, "extract", func() (extractedField, error) {
+			return extractedField{Name: params.Field, Value: text}, nil
+		})
+		if err != nil {
+			return extractedField{}, err
+		}
+		if field.Value == "" {
+			return extractedField{}, errors.New("nothing extracted")
+		}
+		return field, nil
+	})
+
+	workflow := CreateTyped[blueprintDocument](i.Workflows, WorkflowConfig{Name: "documents"})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input blueprintDocument) (interface{}, error) {
+		invoice, err := extraction.RunAs(ctx, "invoice", extractParams{Field: "total"}, input)
+		if err != nil {
+			return nil, err
+		}
+		receipt, err := extraction.RunAs(ctx, "receipt", extractParams{Field: "date"}, input)
+		if err != nil {
+			return nil, err
+		}
+		return []extractedField{invoice, receipt}, nil
+	})
+
+	for run := 0; run < 2; run++ {
+		result, err := workflow.Execute(1, map[string]interface{}{"executionId": "exec-1", "text": "42 eur"}, ContextInput{})
+		require.NoError(t, err)
+		assert.Equal(t, []extractedField{{Name: "total", Value: "42 EUR"}, {Name: "date", Value: "42 EUR"}}, result)
+	}
+
+	// Each embedding memoizes its steps under its own name, once per execution
+	assert.Equal(t, 2, fetches)
+	assert.Equal(t, []string{
+		"exec-1_memo_invoice:extract",
+		"exec-1_memo_invoice:fetch",
+		"exec-1_memo_receipt:extract",
+		"exec-1_memo_receipt:fetch",
+	}, memoKeys(store.Keys()))
+
+	// A workflow of the blueprint alone
+	single := CreateTyped[blueprintDocument](i.Workflows, WorkflowConfig{Name: "totals"})
+	single.Version(1).Define(extraction.Handler(extractParams{Field: "total"}))
+
+	result, err := single.Execute(1, map[string]interface{}{"executionId": "exec-2", "text": ""}, ContextInput{})
+	assert.Nil(t, result)
+	assert.EqualError(t, err, "blueprint extract: nothing extracted")
+	assert.Contains(t, store.Keys(), "exec-2_memo_extract:fetch")
+}
@@ -0,0 +1,289 @@
+package inferable
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned (wrapped in a *BudgetExceededError) when an LLM or agent call does
+// not fit the client's TokenBudget. Check for it with errors.Is.
+var ErrBudgetExceeded = errors.New("token budget exceeded")
+
+const (
+	// DefaultBudgetWindow is the window TokenBudget limits are counted over when Window is unset.
+	DefaultBudgetWindow = 24 * time.Hour
+	// DefaultBudgetMaxDelay bounds how long BudgetDelay holds a call when MaxDelay is unset.
+	DefaultBudgetMaxDelay = time.Minute
+)
+
+// BudgetAction is what happens to a call that does not fit a TokenBudget.
+type BudgetAction string
+
+const (
+	// BudgetDelay holds the call until enough earlier spend leaves the window, for up to MaxDelay,
+	// and fails it if that takes longer.
+	BudgetDelay BudgetAction = "delay"
+	// BudgetInterrupt pauses the execution for approval, offering the BudgetApprovalOption. Once
+	// approved with it, the execution's calls are no longer held to the budget, though their spend
+	// is still counted. Approvals of the handler's own interrupts do not exempt the execution.
+	BudgetInterrupt BudgetAction = "interrupt"
+	// BudgetFail fails the call.
+	BudgetFail BudgetAction = "fail"
+)
+
+// BudgetApprovalOption is the option that approves an execution past the budget, see
+// BudgetInterrupt.
+const BudgetApprovalOption = "exceed-budget"
+
+// TokenBudget limits the tokens the ctx.LLM and agent calls of a client's workflows spend within a
+// sliding window, to protect spend limits. Spend is estimated like the context window check, from
+// the prompts and structured results, and counted by the client, so that machines sharing a
+// cluster budget each need a share of it. Zero limits do not limit.
+type TokenBudget struct {
+	// Limit is the maximum number of tokens spent per Window across the client's workflows.
+	Limit int
+	// Workflows limits the tokens spent per Window by the workflows it names.
+	Workflows map[string]int
+	// Window is the period spend is counted over. Defaults to DefaultBudgetWindow.
+	Window time.Duration
+	// OnExceeded is what happens to calls that do not fit the budget. Defaults to BudgetDelay.
+	OnExceeded BudgetAction
+	// MaxDelay bounds how long BudgetDelay holds a call. Defaults to DefaultBudgetMaxDelay.
+	MaxDelay time.Duration
+}
+
+// BudgetExceededError describes a call that did not fit the client's TokenBudget.
+type BudgetExceededError struct {
+	// Workflow is the workflow whose budget was exceeded, or empty if it was the client's.
+	Workflow string
+	// Limit is the exceeded number of tokens per window, and Spent the tokens already spent.
+	Limit int
+	Spent int
+	// Tokens is the estimated size of the call.
+	Tokens int
+	// RetryAfter is the time until the call fits the budget, or zero if it never does.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *BudgetExceededError) Error() string {
+	scope := "the client"
+	if e.Workflow != "" {
+		scope = "workflow " + e.Workflow
+	}
+	return fmt.Sprintf("token budget of %d exceeded for %s: %d spent, call needs %d", e.Limit, scope, e.Spent, e.Tokens)
+}
+
+// Unwrap allows errors.Is(err, ErrBudgetExceeded).
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// budgetSpend is the estimated spend of one call.
+type budgetSpend struct {
+	workflow string
+	tokens   int
+	at       time.Time
+}
+
+// tokenBudgeter counts the spend of LLM and agent calls against a TokenBudget over a sliding window.
+type tokenBudgeter struct {
+	budget TokenBudget
+	clock  Clock
+	mu     sync.Mutex
+	spends []budgetSpend
+}
+
+func newTokenBudgeter(budget *TokenBudget, clock Clock) *tokenBudgeter {
+	if budget == nil {
+		return nil
+	}
+	b := &tokenBudgeter{budget: *budget, clock: clock}
+	if b.budget.Window <= 0 {
+		b.budget.Window = DefaultBudgetWindow
+	}
+	if b.budget.OnExceeded == "" {
+		b.budget.OnExceeded = BudgetDelay
+	}
+	if b.budget.MaxDelay <= 0 {
+		b.budget.MaxDelay = DefaultBudgetMaxDelay
+	}
+	return b
+}
+
+// admit counts a call of workflow estimated at tokens against the budget, holding it first if
+// the budget says so, or returns a *BudgetExceededError if it does not fit. With BudgetInterrupt,
+// calls of approved executions are counted without being held to the budget.
+func (b *tokenBudgeter) admit(ctx context.Context, workflow string, tokens int, approved bool) error {
+	if b == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	deadline := b.clock.Now().Add(b.budget.MaxDelay)
+	for {
+		err := b.reserve(workflow, tokens, approved && b.budget.OnExceeded == BudgetInterrupt)
+		var exceeded *BudgetExceededError
+		if !errors.As(err, &exceeded) {
+			return err
+		}
+		if b.budget.OnExceeded != BudgetDelay || exceeded.RetryAfter == 0 || b.clock.Now().Add(exceeded.RetryAfter).After(deadline) {
+			return err
+		}
+
+		select {
+		case <-b.clock.After(exceeded.RetryAfter):
+		case <-ctx.Done():
+			return fmt.Errorf("call held for token budget cancelled: %w", ctx.Err())
+		}
+	}
+}
+
+// reserve records the spend of a call if it fits the budget, or if force is set.
+func (b *tokenBudgeter) reserve(workflow string, tokens int, force bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	b.prune(now)
+	if !force {
+		if err := b.check(now, "", b.budget.Limit, tokens); err != nil {
+			return err
+		}
+		if err := b.check(now, workflow, b.budget.Workflows[workflow], tokens); err != nil {
+			return err
+		}
+	}
+	b.spends = append(b.spends, budgetSpend{workflow: workflow, tokens: tokens, at: now})
+	return nil
+}
+
+// record counts spend that is only known after a call, e.g. the tokens of its result.
+func (b *tokenBudgeter) record(workflow string, tokens int) {
+	if b == nil || tokens <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spends = append(b.spends, budgetSpend{workflow: workflow, tokens: tokens, at: b.clock.Now()})
+}
+
+// prune drops spend that has left the window. The caller holds b.mu.
+func (b *tokenBudgeter) prune(now time.Time) {
+	start := now.Add(-b.budget.Window)
+	kept := b.spends[:0]
+	for _, spend := range b.spends {
+		if spend.at.After(start) {
+			kept = append(kept, spend)
+		}
+	}
+	b.spends = kept
+}
+
+// check returns a *BudgetExceededError if tokens more do not fit limit, counting the spend of
+// workflow, or of all workflows if it is empty. The caller holds b.mu.
+func (b *tokenBudgeter) check(now time.Time, workflow string, limit int, tokens int) error {
+	if limit <= 0 {
+		return nil
+	}
+	spent := 0
+	for _, spend := range b.spends {
+		if workflow == "" || spend.workflow == workflow {
+			spent += spend.tokens
+		}
+	}
+	if spent+tokens <= limit {
+		return nil
+	}
+
+	err := &BudgetExceededError{Workflow: workflow, Limit: limit, Spent: spent, Tokens: tokens}
+	if tokens > limit {
+		return err
+	}
+	// The call fits once enough of the oldest spend leaves the window
+	freed := 0
+	for _, spend := range b.spends {
+		if workflow != "" && spend.workflow != workflow {
+			continue
+		}
+		freed += spend.tokens
+		if spent-freed+tokens <= limit {
+			err.RetryAfter = spend.at.Add(b.budget.Window).Sub(now)
+			break
+		}
+	}
+	return err
+}
+
+// approvedPastBudget reports whether an execution was approved past the budget. The approval is
+// recorded when the handler resumes from a budget interrupt, so that it still holds once the
+// handler's own interrupts are resolved.
+func (b *tokenBudgeter) approvedPastBudget(store KVStore, executionId string, input ContextInput) (bool, error) {
+	if b == nil || b.budget.OnExceeded != BudgetInterrupt || executionId == "" {
+		return false, nil
+	}
+
+	key := fmt.Sprintf("%s_budget_approved", executionId)
+	if input.Approved && input.ApprovalOption == BudgetApprovalOption {
+		if err := store.SetIfAbsent(key, "true"); err != nil {
+			return true, fmt.Errorf("failed to record budget approval: %v", err)
+		}
+		return true, nil
+	}
+
+	_, ok, err := store.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read budget approval: %v", err)
+	}
+	return ok, nil
+}
+
+// counted reports whether a call of an execution was already counted. The cluster answers the
+// calls a replay of the handler repeats from the execution's cache, without reaching the
+// provider, so they are only counted the first time. call identifies the call within the
+// execution, e.g. by its model and payload.
+func (b *tokenBudgeter) counted(store KVStore, executionId string, call string) (bool, string) {
+	if b == nil || store == nil || executionId == "" {
+		return false, ""
+	}
+	key := fmt.Sprintf("%s_budget_counted_%x", executionId, sha256.Sum256([]byte(call)))
+	_, ok, err := store.Get(key)
+	// Counting a call twice is safer than not counting it
+	return err == nil && ok, key
+}
+
+// markCounted records that the call of key, from counted, was counted.
+func markCounted(store KVStore, key string) {
+	if key != "" {
+		_ = store.SetIfAbsent(key, "true")
+	}
+}
+
+// interruptOnExceeded replaces the failure of a workflow handler on a call that did not fit a
+// budget with BudgetInterrupt by an approval interrupt.
+func (b *tokenBudgeter) interruptOnExceeded(results []reflect.Value) []reflect.Value {
+	if b == nil || b.budget.OnExceeded != BudgetInterrupt {
+		return results
+	}
+	err, ok := results[1].Interface().(error)
+	var exceeded *BudgetExceededError
+	if !ok || !errors.As(err, &exceeded) {
+		return results
+	}
+
+	var interrupt interface{} = ApprovalInterrupt(fmt.Sprintf("Approve exceeding the %s", exceeded.Error())).WithOptions(
+		ApprovalOption{ID: BudgetApprovalOption, Label: "Exceed the budget"},
+		ApprovalOption{ID: "deny", Label: "Deny", Deny: true},
+	)
+	return []reflect.Value{
+		reflect.ValueOf(&interrupt).Elem(),
+		reflect.Zero(reflect.TypeOf((*error)(nil)).Elem()),
+	}
+}
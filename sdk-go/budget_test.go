@@ -0,0 +1,173 @@
+package inferable
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// advancingClock moves to the end of each wait, so that held calls proceed without sleeping.
+type advancingClock struct {
+	fixedClock
+	waited time.Duration
+}
+
+func (c *advancingClock) After(d time.Duration) <-chan time.Time {
+	c.now = c.now.Add(d)
+	c.waited += d
+	return c.fixedClock.After(0)
+}
+
+func TestTokenBudget(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	budget := newTokenBudgeter(&TokenBudget{
+		Limit:      100,
+		Workflows:  map[string]int{"reports": 30},
+		Window:     time.Hour,
+		OnExceeded: BudgetFail,
+	}, clock)
+
+	require.NoError(t, budget.admit(nil, "orders", 40, false))
+	clock.now = clock.now.Add(10 * time.Minute)
+	require.NoError(t, budget.admit(nil, "reports", 20, false))
+
+	// The workflow's own limit applies before the client's
+	err := budget.admit(nil, "reports", 20, false)
+	var exceeded *BudgetExceededError
+	require.True(t, errors.As(err, &exceeded))
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Equal(t, BudgetExceededError{Workflow: "reports", Limit: 30, Spent: 20, Tokens: 20, RetryAfter: time.Hour}, *exceeded)
+	assert.EqualError(t, err, "token budget of 30 exceeded for workflow reports: 20 spent, call needs 20")
+
+	// Result tokens count too, and the call fits once the oldest spend leaves the window
+	budget.record("orders", 30)
+	err = budget.admit(nil, "orders", 20, false)
+	require.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, BudgetExceededError{Limit: 100, Spent: 90, Tokens: 20, RetryAfter: 50 * time.Minute}, *exceeded)
+	clock.now = clock.now.Add(50 * time.Minute)
+	require.NoError(t, budget.admit(nil, "orders", 20, false))
+
+	// Calls larger than the limit never fit
+	err = budget.admit(nil, "orders", 200, false)
+	require.True(t, errors.As(err, &exceeded))
+	assert.Zero(t, exceeded.RetryAfter)
+
+	// No budget admits everything
+	var none *tokenBudgeter
+	assert.NoError(t, none.admit(nil, "orders", 1000, false))
+}
+
+func TestTokenBudgetDelay(t *testing.T) {
+	clock := &advancingClock{fixedClock: fixedClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}}
+	budget := newTokenBudgeter(&TokenBudget{Limit: 100, Window: time.Minute, MaxDelay: 30 * time.Second}, clock)
+
+	require.NoError(t, budget.admit(nil, "orders", 60, false))
+	clock.now = clock.now.Add(40 * time.Second)
+	require.NoError(t, budget.admit(nil, "orders", 40, false))
+
+	// Held until the first call leaves the window
+	require.NoError(t, budget.admit(nil, "orders", 50, false))
+	assert.Equal(t, 20*time.Second, clock.waited)
+
+	// Failed if that takes longer than MaxDelay
+	err := budget.admit(nil, "orders", 60, false)
+	assert.ErrorIs(t, err, ErrBudgetExceeded)
+	assert.Equal(t, 20*time.Second, clock.waited)
+}
+
+func TestWorkflowTokenBudgetInterrupt(t *testing.T) {
+	calls := 0
+	i := newTestClient(t, InferableOptions{
+		KVStore:     NewMemoryStore(),
+		TokenBudget: &TokenBudget{Workflows: map[string]int{"summaries": 5}, OnExceeded: BudgetInterrupt},
+	}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/l1m/structured": func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"summary": "ok"}})
+		},
+	})
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "summaries", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		result, err := ctx.LLM.Structured(StructuredInput{Input: "Summarize the quarterly report for the board"})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	})
+
+	// The call over budget pauses the execution for approval instead of being made
+	result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	interrupt, ok := result.(*Interrupt)
+	require.True(t, ok, "result %v", result)
+	assert.Equal(t, APPROVAL, interrupt.Type)
+	assert.Contains(t, interrupt.Message, "Approve exceeding the token budget of 5 exceeded for workflow summaries")
+	assert.Zero(t, calls)
+
+	require.Len(t, interrupt.Options, 2)
+	assert.Equal(t, BudgetApprovalOption, interrupt.Options[0].ID)
+
+	// Approving another interrupt of the execution does not exempt it
+	result, err = workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{Approved: true})
+	require.NoError(t, err)
+	assert.IsType(t, &Interrupt{}, result)
+	assert.Zero(t, calls)
+
+	// Once approved past the budget, the call is made
+	result, err = workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{Approved: true, ApprovalOption: BudgetApprovalOption})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"summary": "ok"}, result)
+	assert.Equal(t, 1, calls)
+
+	// The approval holds once the handler's own interrupts are resolved
+	result, err = workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{Approved: true, ApprovalOption: "refund"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"summary": "ok"}, result)
+}
+
+func TestWorkflowTokenBudgetReplay(t *testing.T) {
+	i := newTestClient(t, InferableOptions{
+		KVStore:     NewMemoryStore(),
+		TokenBudget: &TokenBudget{Workflows: map[string]int{"summaries": 100}, OnExceeded: BudgetFail},
+	}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/l1m/structured": func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"summary": "ok"}})
+		},
+	})
+
+	approved := false
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "summaries", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		result, err := ctx.LLM.Structured(StructuredInput{Input: "Summarize the quarterly report for the board"})
+		if err != nil {
+			return nil, err
+		}
+		if !ctx.Approved {
+			return ApprovalInterrupt("Publish the summary?"), nil
+		}
+		approved = true
+		return result, nil
+	})
+
+	_, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	spent := i.budget.spends
+
+	// The replay gets the call's result from the execution's cache, so it is not counted again
+	result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{Approved: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"summary": "ok"}, result)
+	assert.True(t, approved)
+	assert.Equal(t, spent, i.budget.spends)
+
+	// Other executions are counted
+	_, err = workflow.Execute(1, WorkflowInput{ExecutionID: "exec-2"}, ContextInput{})
+	require.NoError(t, err)
+	assert.Len(t, i.budget.spends, 2*len(spent))
+}
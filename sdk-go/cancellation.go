@@ -0,0 +1,211 @@
+package inferable
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultCancellationSweepInterval is how often a listening workflow with an OnCancel callback
+	// checks for cancelled executions.
+	DefaultCancellationSweepInterval = 10 * time.Second
+	// cancellationClaimTTL is how long a machine holds a cancellation while running its OnCancel
+	// callback, after which another machine runs it again.
+	cancellationClaimTTL = 5 * time.Minute
+)
+
+// CancellationInfo describes the cancellation of a workflow execution, as passed to the
+// workflow's OnCancel callback.
+type CancellationInfo struct {
+	// ExecutionID is the ID of the cancelled execution.
+	ExecutionID string `json:"executionId"`
+	// Workflow is the name of the execution's workflow.
+	Workflow string `json:"workflow"`
+	// CancelledBy is CancelOptions.CancelledBy.
+	CancelledBy string `json:"cancelledBy,omitempty"`
+	// Reason is CancelOptions.Reason.
+	Reason string `json:"reason,omitempty"`
+	// CancelledAt is when Cancel was called.
+	CancelledAt time.Time `json:"cancelledAt"`
+	// CascadedFrom is the ID of the execution whose cascading cancellation cancelled this one, or
+	// empty if it was cancelled itself.
+	CascadedFrom string `json:"cascadedFrom,omitempty"`
+}
+
+// cancellationRecord is the stored state of a cancellation.
+type cancellationRecord struct {
+	CancellationInfo
+	// HandledAt is when the OnCancel callback succeeded, the zero time until then.
+	HandledAt time.Time `json:"handledAt"`
+}
+
+// cancellationKey is the key the cancellation of an execution is stored under, prefixed by the
+// workflow's name so that its machines can list them.
+func cancellationKey(workflow string, executionId string) string {
+	return fmt.Sprintf("cancellations_%s_%s", workflow, executionId)
+}
+
+// recordCancellation stores the cancellation of an unfinished execution, and with Cascade of its
+// unfinished child executions, for their workflows' OnCancel callbacks. The first cancellation of
+// an execution is kept.
+func (e *WorkflowExecutions) recordCancellation(executionId string, options CancelOptions) error {
+	execution, err := e.inferable.Workflows.GetExecution("", executionId)
+	if err != nil {
+		return fmt.Errorf("failed to record cancellation: %w", err)
+	}
+	if execution.Done() {
+		// Finished executions keep their result
+		return nil
+	}
+
+	info := CancellationInfo{
+		ExecutionID: executionId,
+		Workflow:    execution.WorkflowName,
+		CancelledBy: options.CancelledBy,
+		Reason:      options.Reason,
+		CancelledAt: e.inferable.clock.Now(),
+	}
+	if err := e.storeCancellation(info); err != nil {
+		return err
+	}
+	if !options.Cascade {
+		return nil
+	}
+	return e.recordChildCancellations(executionId, info)
+}
+
+// recordChildCancellations stores the cancellation of the unfinished descendants of an execution
+// cancelled with Cascade.
+func (e *WorkflowExecutions) recordChildCancellations(executionId string, info CancellationInfo) error {
+	children, err := e.Children(executionId)
+	if err != nil {
+		return fmt.Errorf("failed to record cancellation: %w", err)
+	}
+	for _, child := range children.Executions {
+		if child.Status == ExecutionSuccess || child.Status == ExecutionFailure {
+			continue
+		}
+		cascaded := info
+		cascaded.ExecutionID = child.ID
+		cascaded.Workflow = child.WorkflowName
+		cascaded.CascadedFrom = executionId
+		if err := e.storeCancellation(cascaded); err != nil {
+			return err
+		}
+		if err := e.recordChildCancellations(child.ID, cascaded); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *WorkflowExecutions) storeCancellation(info CancellationInfo) error {
+	serialized, err := json.Marshal(cancellationRecord{CancellationInfo: info})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cancellation: %v", err)
+	}
+	if err := e.inferable.KV.SetIfAbsent(cancellationKey(info.Workflow, info.ExecutionID), string(serialized)); err != nil {
+		return fmt.Errorf("failed to record cancellation of %s: %v", info.ExecutionID, err)
+	}
+	return nil
+}
+
+// OnCancel sets a callback run once for every cancelled execution of the workflow, by one of the
+// machines listening for it, to release the external resources the execution holds: e.g. undo
+// holds or close tickets. It receives the execution's State, where the handler can keep track of
+// them, and how the execution was cancelled. Cancellations are checked every
+// DefaultCancellationSweepInterval, including those of executions waiting on an interrupt, which
+// are not resumed once cancelled. A failed callback is retried on the next check.
+//
+//	workflow.OnCancel(func(state *inferable.State, info inferable.CancellationInfo) error {
+//		hold, ok, err := inferable.GetState[string](state, "hold")
+//		if err != nil || !ok {
+//			return err
+//		}
+//		return inventory.Release(hold, info.Reason)
+//	})
+func (w *Workflow) OnCancel(fn func(state *State, info CancellationInfo) error) {
+	w.onCancel = fn
+}
+
+// sweepCancellations runs the OnCancel callback for the workflow's cancelled executions until ctx
+// is done.
+func (w *Workflow) sweepCancellations(ctx context.Context) {
+	clock := w.inferable.clock
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(DefaultCancellationSweepInterval):
+		}
+		if err := w.handleCancellations(); err != nil {
+			w.inferable.logError("Failed to check for cancelled executions", map[string]interface{}{"workflow": w.name, "error": err.Error()})
+		}
+	}
+}
+
+// handleCancellations runs the OnCancel callback for each cancellation not handled yet.
+func (w *Workflow) handleCancellations() error {
+	entries, err := w.inferable.KV.ListAll(cancellationKey(w.name, ""))
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		record := cancellationRecord{}
+		if err := json.Unmarshal([]byte(entry.Value), &record); err != nil {
+			return fmt.Errorf("failed to unmarshal cancellation %s: %v", entry.Key, err)
+		}
+		// The prefix also matches the workflows whose names start with this one's
+		if record.Workflow != w.name || !record.HandledAt.IsZero() {
+			continue
+		}
+		if err := w.handleCancellation(entry.Key, record.CancellationInfo); err != nil {
+			w.executionLogger(record.ExecutionID).Error("Cancellation callback failed", map[string]interface{}{"error": err.Error()})
+		}
+	}
+	return nil
+}
+
+// handleCancellation runs the OnCancel callback for a cancellation, unless another machine is
+// running it or has run it.
+func (w *Workflow) handleCancellation(key string, info CancellationInfo) error {
+	lock, err := w.inferable.KV.Lock(key, LockOptions{TTL: cancellationClaimTTL})
+	if errors.Is(err, ErrLockHeld) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	// Another machine may have handled it since it was listed
+	current, _, err := w.inferable.KV.Get(key)
+	if err != nil {
+		return err
+	}
+	record := cancellationRecord{}
+	if err := json.Unmarshal([]byte(current), &record); err != nil {
+		return fmt.Errorf("failed to unmarshal cancellation %s: %v", key, err)
+	}
+	if !record.HandledAt.IsZero() {
+		return nil
+	}
+
+	if err := w.onCancel(newState(w.kvStore(nil), info.ExecutionID), info); err != nil {
+		return err
+	}
+
+	_, err = w.inferable.KV.Update(key, func(current string, ok bool) (string, error) {
+		record := cancellationRecord{}
+		if err := json.Unmarshal([]byte(current), &record); err != nil {
+			return "", fmt.Errorf("failed to unmarshal cancellation %s: %v", key, err)
+		}
+		record.HandledAt = w.inferable.clock.Now()
+		serialized, err := json.Marshal(record)
+		return string(serialized), err
+	})
+	return err
+}
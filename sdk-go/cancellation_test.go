@@ -0,0 +1,52 @@
+package inferable
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnCancel(t *testing.T) {
+	store := NewMemoryStore()
+	i := newTestClient(t, InferableOptions{KVStore: store}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: WorkflowInput{}})
+
+	released := []string{}
+	failures := 1
+	workflow.OnCancel(func(state *State, info CancellationInfo) error {
+		if failures > 0 {
+			failures--
+			return fmt.Errorf("inventory unavailable")
+		}
+		hold, ok, err := GetState[string](state, "hold")
+		require.NoError(t, err)
+		require.True(t, ok)
+		released = append(released, fmt.Sprintf("%s %s by %s: %s", info.ExecutionID, hold, info.CancelledBy, info.Reason))
+		return nil
+	})
+
+	_, err := newState(store, "exec-1").Set("hold", "hold-42")
+	require.NoError(t, err)
+	cancelledAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, i.Workflows.Executions.storeCancellation(CancellationInfo{ExecutionID: "exec-1", Workflow: "orders", CancelledBy: "ops", Reason: "order withdrawn", CancelledAt: cancelledAt}))
+	// Cancellations of workflows whose names start with this one's are not its own
+	require.NoError(t, i.Workflows.Executions.storeCancellation(CancellationInfo{ExecutionID: "exec-2", Workflow: "orders_archive", CancelledAt: cancelledAt}))
+
+	// A failed callback is retried on the next check
+	require.NoError(t, workflow.handleCancellations())
+	assert.Empty(t, released)
+	require.NoError(t, workflow.handleCancellations())
+	assert.Equal(t, []string{"exec-1 hold-42 by ops: order withdrawn"}, released)
+
+	// Handled cancellations are not run again
+	require.NoError(t, workflow.handleCancellations())
+	assert.Len(t, released, 1)
+
+	// The first cancellation of an execution is kept
+	require.NoError(t, i.Workflows.Executions.storeCancellation(CancellationInfo{ExecutionID: "exec-1", Workflow: "orders", Reason: "again"}))
+	require.NoError(t, workflow.handleCancellations())
+	assert.Len(t, released, 1)
+}
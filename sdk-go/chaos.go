@@ -0,0 +1,148 @@
+package inferable
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultChaosMaxDelay bounds the delays injected by chaos mode when ChaosOptions.MaxDelay is unset.
+const DefaultChaosMaxDelay = 2 * time.Second
+
+// ChaosOptions enables fault injection, to verify that tools and workflow handlers are idempotent
+// and retry-safe before they meet real failures. It is meant for local and test clusters only.
+// Each rate is a probability between 0 and 1.
+type ChaosOptions struct {
+	// Seed makes the injected faults reproducible. Defaults to a time-based seed.
+	Seed int64
+	// ErrorRate is the probability that a request to the cluster fails with a transient 503
+	// without reaching the cluster.
+	ErrorRate float64
+	// DelayRate is the probability that a request to the cluster is delayed by up to MaxDelay.
+	DelayRate float64
+	// MaxDelay bounds injected delays. Defaults to DefaultChaosMaxDelay.
+	MaxDelay time.Duration
+	// DuplicateRate is the probability that a polled tool call is delivered to its tool twice.
+	DuplicateRate float64
+	// RestartRate is the probability that a workflow handler is restarted from the top after a
+	// ctx.Memo step completes, as if the machine crashed and the execution was picked up again.
+	RestartRate float64
+}
+
+// chaos injects the faults configured by ChaosOptions. A nil *chaos injects nothing.
+type chaos struct {
+	options ChaosOptions
+	mu      sync.Mutex
+	random  *rand.Rand
+}
+
+func newChaos(options *ChaosOptions) *chaos {
+	if options == nil {
+		return nil
+	}
+
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	if options.MaxDelay <= 0 {
+		options.MaxDelay = DefaultChaosMaxDelay
+	}
+
+	return &chaos{options: *options, random: rand.New(rand.NewSource(seed))}
+}
+
+// roll reports whether a fault with the given probability should be injected.
+func (c *chaos) roll(rate float64) bool {
+	if c == nil || rate <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.random.Float64() < rate
+}
+
+func (c *chaos) delay() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.random.Int63n(int64(c.options.MaxDelay)) + 1)
+}
+
+// duplicate returns the polled messages with some of them delivered twice.
+func (c *chaos) duplicate(messages []callMessage) []callMessage {
+	if c == nil || c.options.DuplicateRate <= 0 {
+		return messages
+	}
+
+	delivered := []callMessage{}
+	for _, msg := range messages {
+		delivered = append(delivered, msg)
+		if c.roll(c.options.DuplicateRate) {
+			delivered = append(delivered, msg)
+		}
+	}
+	return delivered
+}
+
+// maybeRestart aborts the running workflow handler after the named memo step.
+// The panic is recovered by the handler wrapper, which runs the handler again.
+func (c *chaos) maybeRestart(step string) {
+	if c != nil && c.roll(c.options.RestartRate) {
+		panic(chaosRestart{step: step})
+	}
+}
+
+// chaosRestart is the panic value used to restart a workflow handler.
+type chaosRestart struct {
+	step string
+}
+
+// transport wraps next with injected errors and delays.
+func (c *chaos) transport(next http.RoundTripper) http.RoundTripper {
+	if c == nil {
+		return next
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &chaosTransport{chaos: c, next: next}
+}
+
+type chaosTransport struct {
+	chaos *chaos
+	next  http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.chaos.roll(t.chaos.options.DelayRate) {
+		select {
+		case <-time.After(t.chaos.delay()):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	if t.chaos.roll(t.chaos.options.ErrorRate) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		body := fmt.Sprintf(`{"error":"chaos: injected transient error for %s %s"}`, req.Method, req.URL.Path)
+		return &http.Response{
+			Status:     "503 Service Unavailable",
+			StatusCode: http.StatusServiceUnavailable,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+
+	return t.next.RoundTrip(req)
+}
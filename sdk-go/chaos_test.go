@@ -0,0 +1,87 @@
+package inferable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChaosRestartsHandlerAfterMemoSteps(t *testing.T) {
+	i := newTestClient(t, InferableOptions{
+		KVStore: NewMemoryStore(),
+		Chaos:   &ChaosOptions{RestartRate: 1},
+	}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "chaos", InputSchema: WorkflowInput{}})
+
+	runs, steps := 0, 0
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		runs++
+		for _, name := range []string{"first", "second"} {
+			if _, err := ctx.Memo(name, func() (interface{}, error) {
+				steps++
+				return name, nil
+			}); err != nil {
+				return nil, err
+			}
+		}
+		return ctx.Random.Int63(), nil
+	})
+
+	result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+
+	// Each step restarts the handler once; replays hit the memo and do not restart
+	assert.Equal(t, 3, runs)
+	assert.Equal(t, 2, steps)
+	assert.Equal(t, newExecutionRandom("exec-1").Int63(), result)
+}
+
+func TestChaosRestartPropagatesOtherPanics(t *testing.T) {
+	i := newTestClient(t, InferableOptions{Chaos: &ChaosOptions{RestartRate: 1}}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "chaos", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		panic("boom")
+	})
+
+	assert.PanicsWithValue(t, "boom", func() {
+		workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	})
+}
+
+func TestChaosTransport(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	t.Cleanup(server.Close)
+
+	failing := &http.Client{Transport: newChaos(&ChaosOptions{ErrorRate: 1}).transport(nil)}
+	resp, err := failing.Get(server.URL + "/jobs")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 0, requests)
+
+	delayed := &http.Client{Transport: newChaos(&ChaosOptions{DelayRate: 1, MaxDelay: 20 * time.Millisecond}).transport(nil)}
+	resp, err = delayed.Get(server.URL + "/jobs")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 1, requests)
+}
+
+func TestChaosDuplicate(t *testing.T) {
+	messages := []callMessage{{Id: "a"}, {Id: "b"}}
+
+	assert.Equal(t, messages, (*chaos)(nil).duplicate(messages))
+	assert.Len(t, newChaos(&ChaosOptions{DuplicateRate: 1}).duplicate(messages), 4)
+
+	// The same seed injects the same faults
+	first := newChaos(&ChaosOptions{Seed: 7, DuplicateRate: 0.5}).duplicate(messages)
+	second := newChaos(&ChaosOptions{Seed: 7, DuplicateRate: 0.5}).duplicate(messages)
+	assert.Equal(t, first, second)
+}
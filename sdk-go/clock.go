@@ -0,0 +1,48 @@
+package inferable
+
+import (
+	"fmt"
+	"time"
+)
+
+// Clock tells the time for workflow executions. The default uses the system clock; tests can
+// substitute a controllable clock (see testkit.Clock) to skip through ctx.Sleep and deadlines.
+type Clock interface {
+	Now() time.Time
+	// After returns a channel that receives the time once d has elapsed.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock backed by the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// durableSleep waits until the wake-up time memoized under name, so that an execution that is
+// re-executed mid-sleep only waits for the remainder.
+func durableSleep(clock Clock, memo func(name string, fn func() (interface{}, error)) (interface{}, error), name string, d time.Duration) error {
+	wake, err := memo("sleep_"+name, func() (interface{}, error) {
+		return clock.Now().Add(d).Format(time.RFC3339Nano), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	wakeString, _ := wake.(string)
+	wakeAt, err := time.Parse(time.RFC3339Nano, wakeString)
+	if err != nil {
+		return fmt.Errorf("invalid wake-up time for sleep %s: %v", name, err)
+	}
+
+	if remaining := wakeAt.Sub(clock.Now()); remaining > 0 {
+		<-clock.After(remaining)
+	}
+
+	return nil
+}
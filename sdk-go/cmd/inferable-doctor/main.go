@@ -0,0 +1,52 @@
+// Command inferable-doctor checks that an environment is set up to run Inferable workers: the
+// endpoint is reachable, the API secret is accepted, and the local clock agrees with the
+// control plane. It exits with status 1 when a check fails.
+//
+//	INFERABLE_API_SECRET=... go run github.com/inferablehq/inferable/sdk-go/cmd/inferable-doctor
+//
+// To also check the schemas and payload size of your tools and workflows, call
+// Inferable.Doctor from your own program after registering them.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/inferablehq/inferable/sdk-go"
+)
+
+func main() {
+	endpoint := flag.String("endpoint", os.Getenv("INFERABLE_API_ENDPOINT"), "API endpoint, defaults to $INFERABLE_API_ENDPOINT or "+inferable.DefaultAPIEndpoint)
+	secret := flag.String("secret", os.Getenv("INFERABLE_API_SECRET"), "API secret, defaults to $INFERABLE_API_SECRET")
+	maxSkew := flag.Duration("max-clock-skew", inferable.DefaultMaxClockSkew, "largest tolerated clock difference to the control plane")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	client, err := inferable.New(inferable.InferableOptions{
+		APIEndpoint: *endpoint,
+		APISecret:   *secret,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create client: %v\n", err)
+		os.Exit(2)
+	}
+
+	report := client.Doctor(inferable.DoctorOptions{MaxClockSkew: *maxSkew})
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to encode report: %v\n", err)
+			os.Exit(2)
+		}
+	} else {
+		fmt.Println(report)
+	}
+
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,233 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// InputViolation describes a tool input field that does not match the tool's input type.
+type InputViolation struct {
+	// Field is the JSON path of the field, e.g. "items[0].quantity".
+	Field string `json:"field"`
+	// Expected is the JSON type the field should have.
+	Expected string `json:"expected"`
+	// Got is the JSON type that was supplied.
+	Got string `json:"got"`
+	// Hint suggests how to correct the value.
+	Hint string `json:"hint"`
+}
+
+// InputValidationError rejects a tool call whose input does not match the tool's input type. It is
+// returned to the agent as the call's result, so that the model can correct its input and retry.
+type InputValidationError struct {
+	Message    string           `json:"message"`
+	Violations []InputViolation `json:"violations"`
+}
+
+// Error implements the error interface.
+func (e *InputValidationError) Error() string {
+	fields := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		fields = append(fields, fmt.Sprintf("%s: expected %s, got %s", violation.Field, violation.Expected, violation.Got))
+	}
+	return fmt.Sprintf("%s: %s", e.Message, strings.Join(fields, "; "))
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// decodeToolInput decodes a call's input into target, a pointer to the tool's input type. With
+// coerce set, mistyped scalars are converted where the intent is unambiguous: numeric and boolean
+// strings to numbers and booleans, numbers and booleans to strings, and single values to
+// one-element arrays. Values that still do not match are reported as an InputValidationError.
+func decodeToolInput(input interface{}, target interface{}, coerce bool) error {
+	// Normalize the input to decoded JSON
+	data, err := json.Marshal(input)
+	if err != nil {
+		return &InputValidationError{Message: "input is not valid JSON", Violations: []InputViolation{}}
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return &InputValidationError{Message: "input is not valid JSON", Violations: []InputViolation{}}
+	}
+
+	value, violations := coerceValue(reflect.TypeOf(target).Elem(), value, "$", coerce)
+	if len(violations) > 0 {
+		return &InputValidationError{Message: "input does not match the tool's schema", Violations: violations}
+	}
+
+	if data, err = json.Marshal(value); err == nil {
+		err = json.Unmarshal(data, target)
+	}
+	if err != nil {
+		return &InputValidationError{
+			Message:    "input does not match the tool's schema",
+			Violations: []InputViolation{{Field: "$", Expected: "valid input", Got: "invalid input", Hint: err.Error()}},
+		}
+	}
+	return nil
+}
+
+// coerceValue checks a decoded JSON value against the Go type it decodes into, converting it if
+// coerce is set, and returns the value to decode along with the violations found.
+func coerceValue(t reflect.Type, value interface{}, path string, coerce bool) (interface{}, []InputViolation) {
+	for t.Kind() == reflect.Ptr {
+		if value == nil {
+			return nil, nil
+		}
+		t = t.Elem()
+	}
+	// Types with their own decoding, e.g. time.Time, define what they accept
+	if value == nil || t.Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return value, nil
+	}
+
+	violation := func(expected string, hint string) []InputViolation {
+		return []InputViolation{{Field: path, Expected: expected, Got: jsonType(value), Hint: hint}}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			if coerce {
+				return strconv.FormatFloat(v, 'f', -1, 64), nil
+			}
+		case bool:
+			if coerce {
+				return strconv.FormatBool(v), nil
+			}
+		}
+		return value, violation("string", fmt.Sprintf("pass the value in quotes, e.g. \"%v\"", value))
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := value.(float64)
+		if s, isString := value.(string); isString && coerce {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			n, ok = parsed, err == nil
+		}
+		if ok && n == math.Trunc(n) {
+			return n, nil
+		}
+		return value, violation("integer", "pass a whole number without quotes, e.g. 42")
+
+	case reflect.Float32, reflect.Float64:
+		n, ok := value.(float64)
+		if s, isString := value.(string); isString && coerce {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			n, ok = parsed, err == nil
+		}
+		if ok {
+			return n, nil
+		}
+		return value, violation("number", "pass a number without quotes, e.g. 4.2")
+
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if s, isString := value.(string); isString && coerce {
+			parsed, err := strconv.ParseBool(strings.TrimSpace(s))
+			b, ok = parsed, err == nil
+		}
+		if ok {
+			return b, nil
+		}
+		return value, violation("boolean", "pass true or false without quotes")
+
+	case reflect.Slice, reflect.Array:
+		// Byte slices are decoded from base64 strings
+		if t.Elem().Kind() == reflect.Uint8 {
+			return value, nil
+		}
+		items, ok := value.([]interface{})
+		if !ok {
+			if !coerce {
+				return value, violation("array", fmt.Sprintf("wrap the value in a list, e.g. [%s]", formatJSON(value)))
+			}
+			items = []interface{}{value}
+		}
+		var violations []InputViolation
+		coerced := make([]interface{}, len(items))
+		for i, item := range items {
+			var itemViolations []InputViolation
+			coerced[i], itemViolations = coerceValue(t.Elem(), item, fmt.Sprintf("%s[%d]", path, i), coerce)
+			violations = append(violations, itemViolations...)
+		}
+		return coerced, violations
+
+	case reflect.Map:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return value, violation("object", "pass an object of key-value pairs")
+		}
+		var violations []InputViolation
+		coerced := make(map[string]interface{}, len(entries))
+		for key, entry := range entries {
+			var entryViolations []InputViolation
+			coerced[key], entryViolations = coerceValue(t.Elem(), entry, path+"."+key, coerce)
+			violations = append(violations, entryViolations...)
+		}
+		return coerced, violations
+
+	case reflect.Struct:
+		properties, ok := value.(map[string]interface{})
+		if !ok {
+			return value, violation("object", "pass an object with the fields described by the tool's schema")
+		}
+		var violations []InputViolation
+		coerced := make(map[string]interface{}, len(properties))
+		for key, property := range properties {
+			coerced[key] = property
+		}
+		for _, field := range reflect.VisibleFields(t) {
+			name, ok := jsonFieldName(field)
+			if !ok {
+				continue
+			}
+			property, present := properties[name]
+			if !present {
+				continue
+			}
+			var fieldViolations []InputViolation
+			coerced[name], fieldViolations = coerceValue(field.Type, property, path+"."+name, coerce)
+			violations = append(violations, fieldViolations...)
+		}
+		return coerced, violations
+	}
+
+	return value, nil
+}
+
+// jsonFieldName returns the name a struct field is decoded from, and false for fields that are not
+// decoded directly, such as embedded structs whose fields are promoted.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if field.Anonymous && name == "" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// formatJSON formats a value for a hint.
+func formatJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
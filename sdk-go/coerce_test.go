@@ -0,0 +1,90 @@
+package inferable
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderInput struct {
+	Quantity int       `json:"quantity"`
+	Price    float64   `json:"price"`
+	Express  bool      `json:"express"`
+	SKU      string    `json:"sku"`
+	Tags     []string  `json:"tags"`
+	Due      time.Time `json:"due"`
+	Items    []struct {
+		Quantity int `json:"quantity"`
+	} `json:"items"`
+}
+
+func TestDecodeToolInputCoercion(t *testing.T) {
+	input := map[string]interface{}{
+		"quantity": "42",
+		"price":    " 4.5",
+		"express":  "true",
+		"sku":      1234,
+		"tags":     "fragile",
+		"due":      "2025-01-01T00:00:00Z",
+		"items":    []interface{}{map[string]interface{}{"quantity": "2"}},
+	}
+
+	var order orderInput
+	require.NoError(t, decodeToolInput(input, &order, true))
+	assert.Equal(t, 42, order.Quantity)
+	assert.Equal(t, 4.5, order.Price)
+	assert.True(t, order.Express)
+	assert.Equal(t, "1234", order.SKU)
+	assert.Equal(t, []string{"fragile"}, order.Tags)
+	assert.Equal(t, 2025, order.Due.Year())
+	assert.Equal(t, 2, order.Items[0].Quantity)
+
+	// Without coercion, every mistyped field is reported
+	err := decodeToolInput(input, &orderInput{}, false)
+	require.IsType(t, &InputValidationError{}, err)
+	fields := []string{}
+	for _, violation := range err.(*InputValidationError).Violations {
+		fields = append(fields, violation.Field)
+	}
+	assert.ElementsMatch(t, []string{"$.quantity", "$.price", "$.express", "$.sku", "$.tags", "$.items[0].quantity"}, fields)
+
+	// Values that cannot be coerced are reported with a hint
+	err = decodeToolInput(map[string]interface{}{"quantity": "a few"}, &orderInput{}, true)
+	require.IsType(t, &InputValidationError{}, err)
+	assert.Equal(t, []InputViolation{{
+		Field:    "$.quantity",
+		Expected: "integer",
+		Got:      "string",
+		Hint:     "pass a whole number without quotes, e.g. 42",
+	}}, err.(*InputValidationError).Violations)
+}
+
+func TestInvalidToolInputIsRejectedWithViolations(t *testing.T) {
+	i, results := newResultRecorder(t, InferableOptions{})
+
+	calls := 0
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "charge",
+		Func: func(input chargeInput, ctx ContextInput) (interface{}, error) {
+			calls++
+			return input.Amount, nil
+		},
+	}))
+
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-1", Function: "charge", Input: map[string]interface{}{"amount": "100"}}))
+	assert.Equal(t, 0, calls)
+
+	require.Len(t, results(), 1)
+	assert.Equal(t, "rejection", results()[0].ResultType)
+	assert.Equal(t, map[string]interface{}{
+		"message": "input does not match the tool's schema",
+		"violations": []interface{}{map[string]interface{}{
+			"field":    "$.amount",
+			"expected": "integer",
+			"got":      "string",
+			"hint":     "pass a whole number without quotes, e.g. 42",
+		}},
+	}, results()[0].Result)
+}
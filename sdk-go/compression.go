@@ -0,0 +1,196 @@
+package inferable
+
+import (
+	"fmt"
+	"strings"
+)
+
+// truncationMarker is inserted where content was removed to fit a token budget.
+const truncationMarker = "\n[...truncated...]\n"
+
+// CompressionStrategy reduces text to fit within a token budget for the given model.
+type CompressionStrategy interface {
+	Compress(model string, text string, budget int) (string, error)
+}
+
+// CompressText returns text unchanged if it fits within budget tokens for the model,
+// and otherwise applies the strategy to shrink it. Use it to fit documents and transcripts
+// into agent inputs or Structured prompts.
+//
+//	text, err := CompressText(DefaultModel, transcript, 50000, TruncateMiddle)
+func CompressText(model string, text string, budget int, strategy CompressionStrategy) (string, error) {
+	if budget <= 0 {
+		return "", fmt.Errorf("token budget must be positive, got %d", budget)
+	}
+
+	if CountTokens(model, text) <= budget {
+		return text, nil
+	}
+
+	compressed, err := strategy.Compress(model, text, budget)
+	if err != nil {
+		return "", err
+	}
+
+	if tokens := CountTokens(model, compressed); tokens > budget {
+		return "", fmt.Errorf("compression produced %d tokens, exceeding the budget of %d", tokens, budget)
+	}
+
+	return compressed, nil
+}
+
+// truncateStrategy keeps the beginning and/or end of the text.
+type truncateStrategy struct {
+	keepHead bool
+	keepTail bool
+}
+
+var (
+	// TruncateHead keeps the beginning of the text, e.g. for documents whose key content comes first.
+	TruncateHead CompressionStrategy = truncateStrategy{keepHead: true}
+	// TruncateTail keeps the end of the text, e.g. for transcripts where recent messages matter most.
+	TruncateTail CompressionStrategy = truncateStrategy{keepTail: true}
+	// TruncateMiddle keeps the beginning and end of the text and removes the middle.
+	TruncateMiddle CompressionStrategy = truncateStrategy{keepHead: true, keepTail: true}
+)
+
+func (s truncateStrategy) Compress(model string, text string, budget int) (string, error) {
+	available := budget - CountTokens(model, truncationMarker)
+	if available <= 0 {
+		return "", fmt.Errorf("token budget of %d is too small to truncate into", budget)
+	}
+
+	runes := []rune(text)
+
+	switch {
+	case s.keepHead && s.keepTail:
+		headBudget := available / 2
+		head := runes[:prefixWithin(model, runes, headBudget)]
+		tail := runes[len(runes)-suffixWithin(model, runes, available-headBudget):]
+		return string(head) + truncationMarker + string(tail), nil
+	case s.keepTail:
+		return truncationMarker + string(runes[len(runes)-suffixWithin(model, runes, available):]), nil
+	default:
+		return string(runes[:prefixWithin(model, runes, available)]) + truncationMarker, nil
+	}
+}
+
+// prefixWithin returns the length of the longest prefix of runes that fits within budget tokens.
+func prefixWithin(model string, runes []rune, budget int) int {
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if CountTokens(model, string(runes[:mid])) <= budget {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo
+}
+
+// suffixWithin returns the length of the longest suffix of runes that fits within budget tokens.
+func suffixWithin(model string, runes []rune, budget int) int {
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if CountTokens(model, string(runes[len(runes)-mid:])) <= budget {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	return lo
+}
+
+// SummarizeStrategy compresses text by map-reduce summarization: the text is split into chunks,
+// each chunk is summarized by the LLM, and the summaries are combined. Rounds repeat until the
+// result fits the budget or MaxRounds is reached.
+type SummarizeStrategy struct {
+	// LLM performs the summarization, typically ctx.LLM.
+	LLM LLMClient
+	// ChunkTokens is the size of each chunk sent for summarization. Defaults to 8000.
+	ChunkTokens int
+	// Instructions guide what the summaries should preserve, e.g. "Keep all amounts and dates".
+	Instructions string
+	// MaxRounds bounds the number of summarization rounds. Defaults to 3.
+	MaxRounds int
+}
+
+func (s SummarizeStrategy) Compress(model string, text string, budget int) (string, error) {
+	if s.LLM == nil {
+		return "", fmt.Errorf("summarize strategy requires an LLM")
+	}
+
+	chunkTokens := s.ChunkTokens
+	if chunkTokens <= 0 {
+		chunkTokens = 8000
+	}
+	maxRounds := s.MaxRounds
+	if maxRounds <= 0 {
+		maxRounds = 3
+	}
+
+	for round := 0; round < maxRounds && CountTokens(model, text) > budget; round++ {
+		chunks := splitTokens(model, text, chunkTokens)
+
+		// Ask for summaries small enough that the combined result fits the budget
+		summaryTokens := budget / len(chunks)
+		if summaryTokens < 1 {
+			summaryTokens = 1
+		}
+
+		goals := []string{fmt.Sprintf("Summarize the section in at most %d tokens", summaryTokens)}
+		if s.Instructions != "" {
+			goals = append(goals, s.Instructions)
+		}
+
+		summaries := make([]string, 0, len(chunks))
+		for _, chunk := range chunks {
+			result, err := s.LLM.Structured(StructuredInput{
+				Input: Helpers.StructuredPrompt(struct {
+					Facts []string
+					Goals []string
+				}{
+					Facts: []string{"The following is a section of a longer text:\n" + chunk},
+					Goals: goals,
+				}),
+				Schema: struct {
+					Summary string `json:"summary"`
+				}{},
+				Model: model,
+			})
+			if err != nil {
+				return "", fmt.Errorf("failed to summarize chunk: %w", err)
+			}
+
+			resultMap, _ := result.(map[string]interface{})
+			summary, _ := resultMap["summary"].(string)
+			summaries = append(summaries, summary)
+		}
+
+		text = strings.Join(summaries, "\n\n")
+	}
+
+	if CountTokens(model, text) <= budget {
+		return text, nil
+	}
+
+	// Guarantee the budget even if the summaries were longer than requested
+	return TruncateHead.Compress(model, text, budget)
+}
+
+// splitTokens splits text into consecutive chunks of at most chunkTokens tokens.
+func splitTokens(model string, text string, chunkTokens int) []string {
+	runes := []rune(text)
+	chunks := []string{}
+	for len(runes) > 0 {
+		n := prefixWithin(model, runes, chunkTokens)
+		if n == 0 {
+			n = 1
+		}
+		chunks = append(chunks, string(runes[:n]))
+		runes = runes[n:]
+	}
+	return chunks
+}
@@ -0,0 +1,53 @@
+package inferable
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressText(t *testing.T) {
+	text := strings.Repeat("alpha ", 100) + strings.Repeat("omega ", 100)
+
+	unchanged, err := CompressText(DefaultModel, "short text", 100, TruncateHead)
+	require.NoError(t, err)
+	assert.Equal(t, "short text", unchanged)
+
+	head, err := CompressText(DefaultModel, text, 50, TruncateHead)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(head, "alpha"))
+	assert.NotContains(t, head, "omega")
+	assert.LessOrEqual(t, CountTokens(DefaultModel, head), 50)
+
+	tail, err := CompressText(DefaultModel, text, 50, TruncateTail)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(tail, "omega "))
+	assert.NotContains(t, tail, "alpha")
+
+	middle, err := CompressText(DefaultModel, text, 50, TruncateMiddle)
+	require.NoError(t, err)
+	assert.Contains(t, middle, "alpha")
+	assert.Contains(t, middle, "omega")
+	assert.Contains(t, middle, truncationMarker)
+
+	_, err = CompressText(DefaultModel, text, 0, TruncateHead)
+	assert.Error(t, err)
+}
+
+func TestSummarizeStrategy(t *testing.T) {
+	calls := 0
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		calls++
+		return 200, map[string]interface{}{"data": map[string]interface{}{"summary": "chunk summary"}}
+	})
+
+	text := strings.Repeat("lorem ipsum ", 1000)
+	summary, err := CompressText(DefaultModel, text, 100, SummarizeStrategy{LLM: llm, ChunkTokens: 500})
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, calls)
+	assert.Equal(t, strings.TrimSuffix(strings.Repeat("chunk summary\n\n", 6), "\n\n"), summary)
+}
@@ -0,0 +1,273 @@
+package inferable
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// DefaultConsoleRefresh is how often the console is redrawn when ConsoleOptions.Refresh is unset.
+	DefaultConsoleRefresh = 500 * time.Millisecond
+	// DefaultConsoleExecutions is the number of executions shown when ConsoleOptions.Executions is unset.
+	DefaultConsoleExecutions = 10
+	// DefaultConsoleTail is the number of events shown when ConsoleOptions.Tail is unset.
+	DefaultConsoleTail = 15
+)
+
+// ANSI sequences the console is drawn with
+const (
+	consoleClear  = "\x1b[H\x1b[2J"
+	consoleReset  = "\x1b[0m"
+	consoleRed    = "\x1b[31m"
+	consoleGreen  = "\x1b[32m"
+	consoleYellow = "\x1b[33m"
+)
+
+// ConsoleOptions configures Inferable.Console.
+type ConsoleOptions struct {
+	// Out is where the console is drawn. Defaults to os.Stdout.
+	Out io.Writer
+	// Refresh is how often the console is redrawn. Defaults to DefaultConsoleRefresh.
+	Refresh time.Duration
+	// Executions is the number of most recently active executions shown. Defaults to
+	// DefaultConsoleExecutions.
+	Executions int
+	// Tail is the number of most recent events shown below the executions. Defaults to
+	// DefaultConsoleTail.
+	Tail int
+	// Plain prints each event as a line instead of redrawing the screen, for terminals without
+	// ANSI support and for writing to files.
+	Plain bool
+}
+
+// consoleExecution is what the console shows of an execution handled by the machine.
+type consoleExecution struct {
+	id       string
+	workflow string
+	version  int
+	status   string
+	step     string
+	agent    string
+	detail   string
+	started  time.Time
+	updated  time.Time
+}
+
+// console tracks the executions handled by the machine from the client's events.
+type console struct {
+	options    ConsoleOptions
+	executions map[string]*consoleExecution
+	tail       []string
+}
+
+func newConsole(options ConsoleOptions) *console {
+	if options.Out == nil {
+		options.Out = os.Stdout
+	}
+	if options.Refresh <= 0 {
+		options.Refresh = DefaultConsoleRefresh
+	}
+	if options.Executions <= 0 {
+		options.Executions = DefaultConsoleExecutions
+	}
+	if options.Tail <= 0 {
+		options.Tail = DefaultConsoleTail
+	}
+	return &console{options: options, executions: map[string]*consoleExecution{}}
+}
+
+// observe updates the console with an event, and returns its line in the tail, or "" if the
+// event is not shown.
+func (c *console) observe(event Event) string {
+	if event.ExecutionID != "" {
+		execution, ok := c.executions[event.ExecutionID]
+		if !ok {
+			execution = &consoleExecution{id: event.ExecutionID, workflow: event.Workflow, version: event.Version, status: "running", started: event.Time}
+			c.executions[event.ExecutionID] = execution
+		}
+		execution.updated = event.Time
+
+		switch event.Type {
+		case EventHandlerStarted:
+			execution.status = "running"
+			execution.step = ""
+			execution.detail = ""
+			execution.started = event.Time
+		case EventHandlerFinished:
+			execution.status = map[string]string{"resolution": "done", "rejection": "failed", "interrupt": "interrupted"}[event.ResultType]
+			execution.detail = event.Message
+			if event.Err != nil {
+				execution.detail = event.Err.Error()
+			}
+		case EventStep:
+			execution.step = event.Step
+		case EventAgentRun:
+			execution.agent = fmt.Sprintf("%s: %s", event.Agent, event.Message)
+		case EventRetry, EventError:
+			if event.Err != nil {
+				execution.detail = event.Err.Error()
+			}
+		}
+	}
+
+	line := describeEvent(event)
+	if line == "" {
+		return ""
+	}
+	line = fmt.Sprintf("%s %s", event.Time.Format("15:04:05"), line)
+	c.tail = append(c.tail, line)
+	if len(c.tail) > c.options.Tail {
+		c.tail = c.tail[len(c.tail)-c.options.Tail:]
+	}
+	return line
+}
+
+// describeEvent returns the tail line of an event, or "" for events the console does not show.
+func describeEvent(event Event) string {
+	subject := event.ExecutionID
+	if event.Workflow != "" {
+		subject = fmt.Sprintf("%s/%s", event.Workflow, event.ExecutionID)
+	}
+
+	switch event.Type {
+	case EventHandlerStarted:
+		return fmt.Sprintf("%s started", subject)
+	case EventHandlerFinished:
+		switch {
+		case event.Err != nil:
+			return fmt.Sprintf("%s failed after %s: %v", subject, event.Duration.Round(time.Millisecond), event.Err)
+		case event.ResultType == "interrupt":
+			return fmt.Sprintf("%s interrupted: %s", subject, event.Message)
+		default:
+			return fmt.Sprintf("%s done in %s", subject, event.Duration.Round(time.Millisecond))
+		}
+	case EventStep:
+		return fmt.Sprintf("%s step %s", subject, event.Step)
+	case EventLog:
+		return fmt.Sprintf("%s log %s", subject, event.Message)
+	case EventAgentRun:
+		return fmt.Sprintf("%s agent %s %s", subject, event.Agent, event.Message)
+	case EventToolCallFinished:
+		if event.Err != nil {
+			return fmt.Sprintf("tool %s call %s failed: %v", event.Tool, event.CallID, event.Err)
+		}
+		return fmt.Sprintf("tool %s call %s %s in %s", event.Tool, event.CallID, event.ResultType, event.Duration.Round(time.Millisecond))
+	case EventRetry:
+		return strings.TrimSpace(fmt.Sprintf("%s retrying %s, attempt %d: %v", subject, event.Operation, event.Attempt, event.Err))
+	case EventError:
+		return fmt.Sprintf("error in %s: %v", event.Operation, event.Err)
+	}
+	return ""
+}
+
+// render draws the most recently active executions and the tail of events.
+func (c *console) render(w io.Writer, now time.Time) {
+	executions := make([]*consoleExecution, 0, len(c.executions))
+	for _, execution := range c.executions {
+		executions = append(executions, execution)
+	}
+	sort.Slice(executions, func(i, j int) bool {
+		return executions[i].updated.After(executions[j].updated)
+	})
+	if len(executions) > c.options.Executions {
+		executions = executions[:c.options.Executions]
+	}
+
+	fmt.Fprintf(w, "Executions handled by this machine (%d)\n\n", len(c.executions))
+	var rows strings.Builder
+	table := tabwriter.NewWriter(&rows, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "EXECUTION\tWORKFLOW\tSTATUS\tSTEP\tAGENT\tDETAIL")
+	for _, execution := range executions {
+		status := execution.status
+		if status == "running" {
+			status = fmt.Sprintf("running %s", now.Sub(execution.started).Round(time.Second))
+		}
+		fmt.Fprintf(table, "%s\t%s@%d\t%s\t%s\t%s\t%s\n", execution.id, execution.workflow, execution.version, status, execution.step, execution.agent, execution.detail)
+	}
+	table.Flush()
+
+	// Rows are colored after alignment, which does not account for escape sequences
+	lines := strings.SplitAfter(rows.String(), "\n")
+	fmt.Fprint(w, lines[0])
+	for i, execution := range executions {
+		fmt.Fprint(w, c.color(execution.status, lines[i+1]))
+	}
+
+	fmt.Fprintln(w)
+	for _, line := range c.tail {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// color highlights the row of an execution by its status on terminals.
+func (c *console) color(status string, row string) string {
+	if c.options.Plain {
+		return row
+	}
+	color := map[string]string{"done": consoleGreen, "failed": consoleRed, "interrupted": consoleYellow}[status]
+	if color == "" {
+		return row
+	}
+	return color + strings.TrimSuffix(row, "\n") + consoleReset + "\n"
+}
+
+// Console shows the executions handled by this machine live in the terminal, with each one's
+// current step, agent runs, interrupts and errors, followed by the most recent events. It runs
+// until ctx is done. Use it while developing, as a readable alternative to the logs.
+//
+//	go client.Console(ctx)
+//	client.Workflows.Listen()
+func (i *Inferable) Console(ctx context.Context, options ...ConsoleOptions) {
+	merged := ConsoleOptions{}
+	for _, option := range options {
+		if option.Out != nil {
+			merged.Out = option.Out
+		}
+		if option.Refresh > 0 {
+			merged.Refresh = option.Refresh
+		}
+		if option.Executions > 0 {
+			merged.Executions = option.Executions
+		}
+		if option.Tail > 0 {
+			merged.Tail = option.Tail
+		}
+		merged.Plain = merged.Plain || option.Plain
+	}
+	c := newConsole(merged)
+
+	events := i.Events(EventOptions{Buffer: 1024})
+	defer events.Close()
+
+	ticker := time.NewTicker(c.options.Refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events.C:
+			if !ok {
+				return
+			}
+			line := c.observe(event)
+			if c.options.Plain && line != "" {
+				fmt.Fprintln(c.options.Out, line)
+			}
+		case now := <-ticker.C:
+			if c.options.Plain {
+				continue
+			}
+			var frame strings.Builder
+			frame.WriteString(consoleClear)
+			c.render(&frame, now)
+			io.WriteString(c.options.Out, frame.String())
+		}
+	}
+}
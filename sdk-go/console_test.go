@@ -0,0 +1,55 @@
+package inferable
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsole(t *testing.T) {
+	i, err := New(InferableOptions{KVStore: NewMemoryStore(), Offline: true})
+	require.NoError(t, err)
+	events := i.Events()
+	defer events.Close()
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "refunds", InputSchema: WorkflowInput{}})
+	workflow.Version(2).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		if _, err := ctx.Memo("lookup", func() (interface{}, error) { return "order", nil }); err != nil {
+			return nil, err
+		}
+		if err := ctx.Log("lookedUp", nil); err != nil {
+			return nil, err
+		}
+		if input.ExecutionID == "exec-2" {
+			return nil, errors.New("card declined")
+		}
+		return ApprovalInterrupt("Refund over $100"), nil
+	})
+
+	_, err = workflow.Execute(2, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	_, err = workflow.Execute(2, WorkflowInput{ExecutionID: "exec-2"}, ContextInput{})
+	require.Error(t, err)
+
+	c := newConsole(ConsoleOptions{Plain: true, Tail: 5})
+	for len(events.C) > 0 {
+		c.observe(<-events.C)
+	}
+
+	var out strings.Builder
+	c.render(&out, time.Now())
+	rendered := out.String()
+
+	assert.Contains(t, rendered, "Executions handled by this machine (2)")
+	assert.Regexp(t, `exec-1\s+refunds@2\s+interrupted\s+lookup\s+Refund over \$100`, rendered)
+	assert.Regexp(t, `exec-2\s+refunds@2\s+failed\s+lookup\s+card declined`, rendered)
+	// The tail holds the most recent events
+	assert.Len(t, c.tail, 5)
+	assert.Contains(t, rendered, "refunds/exec-2 log lookedUp")
+	assert.Contains(t, rendered, "refunds/exec-2 failed after")
+	assert.NotContains(t, rendered, "refunds/exec-1 started")
+}
@@ -0,0 +1,74 @@
+package inferable
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHangingClient creates a client whose cluster only answers machine registrations and attempt
+// records, and holds every other request until the client gives up on it.
+func newHangingClient(t *testing.T) *Inferable {
+	return newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/": func(w http.ResponseWriter, r *http.Request) {
+			// Attempt records are kept before the handler starts, and are not what is under test
+			if strings.Contains(r.URL.Path, "_run_") {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			// The server notices the client going away once the request is read
+			_, _ = io.Copy(io.Discard, r.Body)
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		},
+	})
+}
+
+func TestTriggerContext(t *testing.T) {
+	i := newHangingClient(t)
+
+	requestCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err := i.Workflows.TriggerContext(requestCtx, "orders", "exec-1", map[string]interface{}{})
+	assert.ErrorContains(t, err, "context deadline exceeded")
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+func TestWorkflowContextWithContext(t *testing.T) {
+	i := newHangingClient(t)
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		requestCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		bounded := ctx.WithContext(requestCtx)
+
+		_, memoErr := bounded.Memo("lookup", func() (interface{}, error) { return "found", nil })
+		logErr := bounded.Log("lookedUp", nil)
+		_, _, agentErr := bounded.Agents.React(ReactAgentConfig{Name: "researcher", Input: "Find the order"})
+
+		return map[string]interface{}{
+			"memo":   memoErr != nil,
+			"log":    logErr != nil,
+			"agents": agentErr != nil && assert.ErrorIs(t, agentErr, context.DeadlineExceeded),
+			// The receiver keeps drawing from the same sequence
+			"sameRandom": bounded.Random == ctx.Random,
+		}, nil
+	})
+
+	started := time.Now()
+	result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"memo": true, "log": true, "agents": true, "sameRandom": true}, result)
+	assert.Less(t, time.Since(started), 2*time.Second)
+}
@@ -0,0 +1,228 @@
+package inferable
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+// DefaultConversationCheckInterval is how often an execution that continued a conversation is
+// resumed to check for the agent's reply.
+const DefaultConversationCheckInterval = 30 * time.Second
+
+// AgentConversation is a handle to the interactive run of an agent, which later executions can
+// continue with messages of their own, e.g. the replies of a human over several days. It
+// serializes to JSON, so that it can be stored with client.KV or passed in the input of the
+// executions that continue it.
+type AgentConversation struct {
+	// RunID identifies the agent's run.
+	RunID string `json:"runId"`
+	// Agent is the name of the agent.
+	Agent string `json:"agent"`
+}
+
+// ConversationRunner is an AgentRunner whose interactive runs can be continued by later
+// executions. The default runner implements it.
+type ConversationRunner interface {
+	AgentRunner
+	// Conversation returns the handle of the run React starts for config.
+	Conversation(config ReactAgentConfig) (AgentConversation, error)
+	// SendConversationMessage appends a human message to the conversation under id, a ULID.
+	SendConversationMessage(conversation AgentConversation, id string, message string) error
+	// ConversationReply returns the result of the conversation's run once the agent has replied
+	// to the message with ID after, and false while it has not.
+	ConversationReply(conversation AgentConversation, after string) (interface{}, bool, error)
+}
+
+// ConversationOf returns the handle of the run ctx.Agents.React starts for config, to continue
+// the conversation in a later execution with ContinueConversation.
+//
+//	conversation, err := inferable.ConversationOf(ctx, config)
+//	if err != nil {
+//		return nil, err
+//	}
+//	// Continue it when the customer replies
+//	_, err = client.Workflows.Trigger("ticket-reply", replyID, map[string]interface{}{"conversation": conversation, "reply": reply}, inferable.TriggerOptions{})
+func ConversationOf(ctx WorkflowContext, config ReactAgentConfig) (AgentConversation, error) {
+	runner, ok := ctx.Agents.(ConversationRunner)
+	if !ok {
+		return AgentConversation{}, fmt.Errorf("the AgentRunner does not implement ConversationRunner")
+	}
+	return runner.Conversation(config)
+}
+
+// ContinueConversation appends message to a conversation started by another execution and
+// returns the agent's result once it has replied, like React. Until then it returns an interrupt
+// that resumes the execution every DefaultConversationCheckInterval to check again. The message
+// is sent once per execution, however often the handler is re-executed.
+//
+//	result, interrupt, err := inferable.ContinueConversation(ctx, input.Conversation, input.Reply)
+//	if err != nil {
+//		return nil, err
+//	}
+//	if interrupt != nil {
+//		return interrupt, nil
+//	}
+func ContinueConversation(ctx WorkflowContext, conversation AgentConversation, message string) (interface{}, *Interrupt, error) {
+	runner, ok := ctx.Agents.(ConversationRunner)
+	if !ok {
+		return nil, nil, fmt.Errorf("the AgentRunner does not implement ConversationRunner")
+	}
+
+	// The message is named by its content, so that an execution can continue a conversation more than once
+	name := fmt.Sprintf("conversation_%x", sha256.Sum256([]byte(conversation.RunID+"\n"+message)))
+	sent, err := ctx.MemoWithOptions(name, MemoOptions{unversioned: true}, func() (interface{}, error) {
+		id, err := newMessageID(ctx.Now())
+		if err != nil {
+			return nil, err
+		}
+		if err := runner.SendConversationMessage(conversation, id, message); err != nil {
+			return nil, err
+		}
+		return id, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	id, ok := sent.(string)
+	if !ok {
+		return nil, nil, fmt.Errorf("failed to decode message ID of conversation %s", conversation.RunID)
+	}
+
+	result, replied, err := runner.ConversationReply(conversation, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !replied {
+		return nil, GeneralInterrupt(fmt.Sprintf("Agent %s is not done", conversation.Agent)).WithTimeout(DefaultConversationCheckInterval, InterruptTimeoutResume), nil
+	}
+	return result, nil, nil
+}
+
+// Conversation implements ConversationRunner.
+func (a *Agents) Conversation(config ReactAgentConfig) (AgentConversation, error) {
+	payload, err := a.runPayload(config, reactResultSchema(config.Schema))
+	if err != nil {
+		return AgentConversation{}, err
+	}
+	return AgentConversation{RunID: payload["id"].(string), Agent: config.Name}, nil
+}
+
+// SendConversationMessage implements ConversationRunner.
+func (a *Agents) SendConversationMessage(conversation AgentConversation, id string, message string) error {
+	body, err := json.Marshal(map[string]interface{}{"id": id, "message": message, "type": "human"})
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	_, _, err, status := a.client.FetchData(client.FetchDataOptions{
+		Path:    fmt.Sprintf("/clusters/%s/runs/%s/messages", a.clusterId, conversation.RunID),
+		Method:  "POST",
+		Headers: map[string]string{"Authorization": "Bearer " + a.apiSecret, "Content-Type": "application/json"},
+		Body:    string(body),
+		Context: a.ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send message to agent %s: %w", conversation.Agent, err)
+	}
+	if status != 201 {
+		return fmt.Errorf("failed to send message to agent %s: %w", conversation.Agent, client.UnexpectedStatus(status))
+	}
+	return nil
+}
+
+// ConversationReply implements ConversationRunner.
+func (a *Agents) ConversationReply(conversation AgentConversation, after string) (interface{}, bool, error) {
+	headers := map[string]string{"Authorization": "Bearer " + a.apiSecret}
+	result, _, err, status := a.client.FetchData(client.FetchDataOptions{
+		Path:       fmt.Sprintf("/clusters/%s/runs/%s", a.clusterId, conversation.RunID),
+		Method:     "GET",
+		Headers:    headers,
+		Context:    a.ctx,
+		Idempotent: true,
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get run of agent %s: %w", conversation.Agent, err)
+	}
+	if status != 200 {
+		return nil, false, fmt.Errorf("failed to get run of agent %s: %w", conversation.Agent, client.UnexpectedStatus(status))
+	}
+
+	var run struct {
+		Status        string      `json:"status"`
+		FailureReason string      `json:"failureReason"`
+		Result        interface{} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(result), &run); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal run of agent %s: %v", conversation.Agent, err)
+	}
+	switch run.Status {
+	case "failed":
+		return nil, false, fmt.Errorf("agent %s failed: %s", conversation.Agent, run.FailureReason)
+	case "done":
+	default:
+		return nil, false, nil
+	}
+
+	// The run is still done from its previous reply until the agent picks up the message
+	messages, _, err, status := a.client.FetchData(client.FetchDataOptions{
+		Path:        fmt.Sprintf("/clusters/%s/runs/%s/messages", a.clusterId, conversation.RunID),
+		Method:      "GET",
+		Headers:     headers,
+		QueryParams: map[string]string{"after": after, "limit": fmt.Sprint(transcriptPageSize)},
+		Context:     a.ctx,
+		Idempotent:  true,
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get messages of agent %s: %w", conversation.Agent, err)
+	}
+	if status != 200 {
+		return nil, false, fmt.Errorf("failed to get messages of agent %s: %w", conversation.Agent, client.UnexpectedStatus(status))
+	}
+
+	var replies []struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(messages), &replies); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal messages of agent %s: %v", conversation.Agent, err)
+	}
+	for _, reply := range replies {
+		if reply.Type == "agent" {
+			return run.Result, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// crockford is the alphabet of ULIDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newMessageID returns a ULID for a message created at now, which the cluster orders messages by.
+func newMessageID(now time.Time) (string, error) {
+	random := make([]byte, 10)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate message ID: %v", err)
+	}
+
+	var id strings.Builder
+	ms := uint64(now.UnixMilli())
+	for shift := 45; shift >= 0; shift -= 5 {
+		id.WriteByte(crockford[(ms>>uint(shift))&31])
+	}
+	// 80 random bits are 16 characters of 5 bits
+	bits, count := uint32(0), 0
+	for _, b := range random {
+		bits = bits<<8 | uint32(b)
+		count += 8
+		for count >= 5 {
+			count -= 5
+			id.WriteByte(crockford[(bits>>uint(count))&31])
+		}
+	}
+	return id.String(), nil
+}
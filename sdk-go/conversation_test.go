@@ -0,0 +1,120 @@
+package inferable
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type conversationInput struct {
+	ExecutionID  string            `json:"executionId"`
+	Conversation AgentConversation `json:"conversation"`
+	Reply        string            `json:"reply"`
+}
+
+func TestContinueConversation(t *testing.T) {
+	var mu sync.Mutex
+	runs := []string{}
+	sent := []map[string]interface{}{}
+	replied := false
+	i := newTestClient(t, InferableOptions{KVStore: NewMemoryStore()}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/runs": func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&payload)
+			mu.Lock()
+			runs = append(runs, payload["id"].(string))
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "done", "result": map[string]interface{}{"answer": "Have you tried restarting it?"}})
+		},
+		"POST /clusters/test-cluster/runs/{runId}/messages": func(w http.ResponseWriter, r *http.Request) {
+			var message map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&message)
+			mu.Lock()
+			sent = append(sent, message)
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		},
+		"GET /clusters/test-cluster/runs/{runId}/messages": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, sent[len(sent)-1]["id"], r.URL.Query().Get("after"))
+			messages := []map[string]interface{}{}
+			if replied {
+				messages = append(messages, map[string]interface{}{"type": "agent"})
+			}
+			json.NewEncoder(w).Encode(messages)
+		},
+		"/clusters/test-cluster/runs/{runId}": func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "done", "result": map[string]interface{}{"answer": "Glad it works now"}})
+		},
+	})
+
+	config := ReactAgentConfig{Name: "support", Instructions: "Help the customer", Input: "My router is broken"}
+	start := CreateTyped[conversationInput](i.Workflows, WorkflowConfig{Name: "tickets"})
+	start.Version(1).Define(func(ctx WorkflowContext, input conversationInput) (interface{}, error) {
+		if _, _, err := ctx.Agents.React(config); err != nil {
+			return nil, err
+		}
+		return ConversationOf(ctx, config)
+	})
+	reply := CreateTyped[conversationInput](i.Workflows, WorkflowConfig{Name: "ticket-replies"})
+	reply.Version(1).Define(func(ctx WorkflowContext, input conversationInput) (interface{}, error) {
+		result, interrupt, err := ContinueConversation(ctx, input.Conversation, input.Reply)
+		if err != nil {
+			return nil, err
+		}
+		if interrupt != nil {
+			return interrupt, nil
+		}
+		return result, nil
+	})
+
+	result, err := start.Execute(1, map[string]interface{}{"executionId": "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	conversation, ok := result.(AgentConversation)
+	require.True(t, ok, "result %v", result)
+	assert.Equal(t, AgentConversation{RunID: runs[0], Agent: "support"}, conversation)
+
+	// A later execution appends to the same run, and checks back until the agent has replied
+	input := map[string]interface{}{"executionId": "exec-2", "conversation": conversation, "reply": "Restarting fixed it"}
+	result, err = reply.Execute(1, input, ContextInput{})
+	require.NoError(t, err)
+	interrupt, ok := result.(*Interrupt)
+	require.True(t, ok, "result %v", result)
+	assert.Equal(t, "Agent support is not done", interrupt.Message)
+	assert.Equal(t, InterruptTimeoutResume, interrupt.OnTimeout)
+
+	mu.Lock()
+	replied = true
+	mu.Unlock()
+	result, err = reply.Execute(1, input, ContextInput{InterruptTimedOut: true})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"answer": "Glad it works now"}, result)
+
+	// The message was sent once, and no other run was created
+	require.Len(t, sent, 1)
+	assert.Equal(t, "Restarting fixed it", sent[0]["message"])
+	assert.Equal(t, "human", sent[0]["type"])
+	assert.Len(t, sent[0]["id"], 26)
+	assert.Len(t, runs, 1)
+}
+
+func TestNewMessageID(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	earlier, err := newMessageID(now)
+	require.NoError(t, err)
+	later, err := newMessageID(now.Add(time.Millisecond))
+	require.NoError(t, err)
+
+	assert.Len(t, earlier, 26)
+	assert.Regexp(t, "^[0-9A-HJKMNP-TV-Z]{26}$", earlier)
+	// IDs are ordered by time
+	assert.Less(t, earlier, later)
+	assert.Equal(t, "01JGG", earlier[:5])
+}
@@ -0,0 +1,95 @@
+package inferable
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newResultRecorder returns a client whose cluster records the job results reported to it.
+func newResultRecorder(t *testing.T, options InferableOptions) (*Inferable, func() []callResult) {
+	t.Helper()
+
+	var mu sync.Mutex
+	results := []callResult{}
+	i := newTestClient(t, options, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/jobs/{jobId}/result": func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			var result callResult
+			json.Unmarshal(data, &result)
+
+			mu.Lock()
+			results = append(results, result)
+			mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+	_, err := i.getClusterId()
+	require.NoError(t, err)
+
+	return i, func() []callResult {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]callResult{}, results...)
+	}
+}
+
+type chargeInput struct {
+	Amount int `json:"amount"`
+}
+
+func TestDedupeRedeliveredCall(t *testing.T) {
+	store := NewMemoryStore()
+	i, results := newResultRecorder(t, InferableOptions{KVStore: store})
+
+	charges := 0
+	require.NoError(t, i.Tools.Register(Tool{
+		Name:   "charge",
+		Dedupe: true,
+		Func: func(input chargeInput, ctx ContextInput) (map[string]interface{}, error) {
+			charges++
+			return map[string]interface{}{"charged": input.Amount}, nil
+		},
+	}))
+
+	msg := callMessage{Id: "job-1", Function: "charge", Input: map[string]interface{}{"amount": 42}}
+	require.NoError(t, i.Tools.handleMessage(msg))
+	require.NoError(t, i.Tools.handleMessage(msg))
+
+	assert.Equal(t, 1, charges)
+	reported := results()
+	require.Len(t, reported, 2)
+	assert.Equal(t, reported[0].Result, reported[1].Result)
+	assert.Equal(t, "resolution", reported[1].ResultType)
+	assert.Equal(t, []string{"call_job-1_result"}, store.Keys())
+
+	// A different call runs
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-2", Function: "charge", Input: map[string]interface{}{"amount": 1}}))
+	assert.Equal(t, 2, charges)
+}
+
+func TestToolsWithoutDedupeRunEveryDelivery(t *testing.T) {
+	store := NewMemoryStore()
+	i, _ := newResultRecorder(t, InferableOptions{KVStore: store})
+
+	runs := 0
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "lookup",
+		Func: func(input chargeInput, ctx ContextInput) (string, error) {
+			runs++
+			return "ok", nil
+		},
+	}))
+
+	msg := callMessage{Id: "job-1", Function: "lookup", Input: map[string]interface{}{}}
+	require.NoError(t, i.Tools.handleMessage(msg))
+	require.NoError(t, i.Tools.handleMessage(msg))
+
+	assert.Equal(t, 2, runs)
+	assert.Empty(t, store.Keys())
+}
@@ -0,0 +1,92 @@
+package inferable
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// WorkflowToolName returns the name of the tool a workflow created with WorkflowConfig.ExposeAsTool
+// is registered as, e.g. to list it in the tools of a run created through the API.
+func WorkflowToolName(workflowName string) string {
+	return "workflow_" + workflowName
+}
+
+// delegatedExecutionID is the ID of the execution a tool call delegates to. Deriving it from the
+// call makes a redelivered or resumed call find its execution instead of triggering another.
+func delegatedExecutionID(callId string) string {
+	return "delegated_" + callId
+}
+
+// validateExposure checks that a workflow exposed as a tool can be triggered by its tool.
+func (w *Workflow) validateExposure() error {
+	if !w.exposeAsTool {
+		return nil
+	}
+	if inputType := reflect.TypeOf(w.inputSchema); inputType == nil || inputType.Kind() != reflect.Struct {
+		return fmt.Errorf("workflow %s must have a struct input schema to be exposed as a tool", w.name)
+	}
+	if w.partitions > 0 {
+		return fmt.Errorf("partitioned workflow %s cannot be exposed as a tool, its executions need a partition key", w.name)
+	}
+	return nil
+}
+
+// delegationTool returns the tool exposing the workflow to agents. It takes the workflow's input,
+// without the executionId, which is derived from the call.
+func (w *Workflow) delegationTool() Tool {
+	inputType := reflect.TypeOf(w.inputSchema)
+	interfaceType := reflect.TypeOf((*interface{})(nil)).Elem()
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+
+	fnType := reflect.FuncOf([]reflect.Type{inputType, reflect.TypeOf(ContextInput{})}, []reflect.Type{interfaceType, errorType}, false)
+	fn := reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
+		result, err := w.delegate(args[0].Interface(), args[1].Interface().(ContextInput))
+
+		resultValue, errValue := reflect.Zero(interfaceType), reflect.Zero(errorType)
+		if result != nil {
+			resultValue = reflect.ValueOf(&result).Elem()
+		}
+		if err != nil {
+			errValue = reflect.ValueOf(&err).Elem()
+		}
+		return []reflect.Value{resultValue, errValue}
+	})
+
+	return Tool{
+		Name:         WorkflowToolName(w.name),
+		Description:  w.description,
+		Func:         fn.Interface(),
+		hiddenFields: []string{"executionId"},
+	}
+}
+
+// delegate runs a call to the workflow's tool: the first delivery triggers an execution and
+// interrupts the call, and the cluster resumes the call once the execution has resolved or was
+// rejected, to return its result.
+func (w *Workflow) delegate(input interface{}, ctx ContextInput) (interface{}, error) {
+	if ctx.CallID == "" {
+		return nil, fmt.Errorf("workflow %s can only be delegated to by a tool call", w.name)
+	}
+	executionId := delegatedExecutionID(ctx.CallID)
+	waiting := GeneralInterrupt(fmt.Sprintf("Waiting for workflow %s execution %s", w.name, executionId))
+
+	execution, err := w.inferable.Workflows.GetExecution(w.name, executionId)
+	if errors.Is(err, errExecutionNotFound) {
+		if _, err := w.inferable.Workflows.Trigger(w.name, executionId, input, TriggerOptions{ResumeCallID: ctx.CallID}); err != nil {
+			return nil, fmt.Errorf("failed to delegate to workflow %s: %v", w.name, err)
+		}
+		return waiting, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if execution.Failed() {
+		return nil, fmt.Errorf("workflow %s failed: %v", w.name, execution.Result)
+	}
+	if execution.Done() {
+		return execution.Result, nil
+	}
+	return waiting, nil
+}
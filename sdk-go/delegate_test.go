@@ -0,0 +1,122 @@
+package inferable
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type delegatedRefundInput struct {
+	ExecutionID string `json:"executionId"`
+	OrderID     string `json:"orderId"`
+}
+
+func TestWorkflowAsTool(t *testing.T) {
+	var mu sync.Mutex
+	var triggers []*http.Request
+	var triggered map[string]interface{}
+	results := []callResult{}
+	job := map[string]interface{}(nil)
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/refunds/executions": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			triggers = append(triggers, r)
+			_ = json.NewDecoder(r.Body).Decode(&triggered)
+			w.WriteHeader(http.StatusCreated)
+		},
+		"/clusters/test-cluster/workflow-executions": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			if job == nil {
+				_ = json.NewEncoder(w).Encode([]interface{}{})
+				return
+			}
+			_ = json.NewEncoder(w).Encode([]interface{}{map[string]interface{}{
+				"execution": map[string]interface{}{"id": r.URL.Query().Get("workflowExecutionId"), "workflowName": "refunds"},
+				"job":       job,
+			}})
+		},
+		"/clusters/test-cluster/jobs/{jobId}/result": func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			var result callResult
+			_ = json.NewDecoder(r.Body).Decode(&result)
+			results = append(results, result)
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+
+	refunds := i.Workflows.Create(WorkflowConfig{
+		Name:         "refunds",
+		Description:  "Refunds an order",
+		InputSchema:  delegatedRefundInput{},
+		ExposeAsTool: true,
+	})
+	refunds.Version(1).Define(func(ctx WorkflowContext, input delegatedRefundInput) (interface{}, error) {
+		return nil, nil
+	})
+	for _, tool := range refunds.clusterTools() {
+		require.NoError(t, i.Tools.Register(tool))
+	}
+
+	tool := i.Tools.Tools[WorkflowToolName("refunds")]
+	assert.Equal(t, "Refunds an order", tool.Description)
+	schema, err := json.Marshal(tool.schema)
+	require.NoError(t, err)
+	assert.NotContains(t, string(schema), "executionId")
+	assert.Contains(t, string(schema), "orderId")
+
+	call := callMessage{Id: "call-1", Function: WorkflowToolName("refunds"), Input: map[string]interface{}{"orderId": "42"}}
+
+	// The first delivery triggers the execution and waits for it
+	require.NoError(t, i.Tools.handleMessage(call))
+	require.Len(t, triggers, 1)
+	assert.Equal(t, "call-1", triggers[0].URL.Query().Get("resumeJobId"))
+	assert.Equal(t, map[string]interface{}{"executionId": "delegated_call-1", "orderId": "42"}, triggered)
+	assert.Equal(t, "interrupt", results[0].ResultType)
+
+	// Resumed while the execution is running, the call waits again
+	job = map[string]interface{}{"status": ExecutionRunning}
+	require.NoError(t, i.Tools.handleMessage(call))
+	assert.Equal(t, "interrupt", results[1].ResultType)
+
+	// Resumed once it is done, the call returns its result
+	job = map[string]interface{}{"status": ExecutionSuccess, "resultType": "resolution", "result": `{"refunded":true}`}
+	require.NoError(t, i.Tools.handleMessage(call))
+	assert.Equal(t, callResult{Result: map[string]interface{}{"refunded": true}, ResultType: "resolution"}, callResult{Result: results[2].Result, ResultType: results[2].ResultType})
+
+	job = map[string]interface{}{"status": ExecutionSuccess, "resultType": "rejection", "result": `"order not found"`}
+	require.NoError(t, i.Tools.handleMessage(call))
+	assert.Equal(t, callResult{Result: "workflow refunds failed: order not found", ResultType: "rejection"}, callResult{Result: results[3].Result, ResultType: results[3].ResultType})
+	assert.Len(t, triggers, 1)
+
+	partitioned := i.Workflows.Create(WorkflowConfig{Name: "shipping", InputSchema: WorkflowInput{}, ExposeAsTool: true, Partitions: 2})
+	assert.ErrorContains(t, partitioned.Listen(), "cannot be exposed as a tool")
+}
+
+func TestAgentWorkflowTools(t *testing.T) {
+	runs := make(chan map[string]interface{}, 1)
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/runs": func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			runs <- payload
+			w.WriteHeader(http.StatusBadRequest)
+		},
+	})
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "support", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		_, _, err := ctx.Agents.React(ReactAgentConfig{Name: "support", Tools: []string{"getOrder"}, Workflows: []string{"refunds"}})
+		return nil, err
+	})
+
+	_, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	assert.ErrorContains(t, err, "failed to create run")
+	assert.Equal(t, []interface{}{"tool_support_getOrder", "workflow_refunds"}, (<-runs)["tools"])
+}
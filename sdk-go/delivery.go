@@ -0,0 +1,70 @@
+package inferable
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Delivery semantics for Tool.Delivery.
+const (
+	// DeliveryAtLeastOnce runs a call, then reports its result. A call whose result was not
+	// reported, e.g. because the machine crashed, is delivered again and may run twice.
+	DeliveryAtLeastOnce = "at-least-once"
+	// DeliveryAtMostOnce claims a call before running it and is registered without retries. A call
+	// that was already claimed is rejected instead of run, so a crash mid-call loses the call
+	// rather than repeating it.
+	DeliveryAtMostOnce = "at-most-once"
+)
+
+// validateDelivery checks a tool's delivery semantics.
+func validateDelivery(fn Tool) error {
+	switch fn.Delivery {
+	case "", DeliveryAtLeastOnce, DeliveryAtMostOnce:
+		return nil
+	}
+	return fmt.Errorf("tool '%s' has unknown delivery semantics %q, use DeliveryAtLeastOnce or DeliveryAtMostOnce", fn.Name, fn.Delivery)
+}
+
+// deliveryOf returns a tool's delivery semantics, defaulting to at-least-once.
+func deliveryOf(fn Tool) string {
+	if fn.Delivery == "" {
+		return DeliveryAtLeastOnce
+	}
+	return fn.Delivery
+}
+
+// registrationConfig is the tool config sent when registering the machine. It declares the
+// delivery semantics, disables redelivery of stalled calls for at-most-once tools, and carries the
+// tool's metadata.
+func registrationConfig(fn Tool) map[string]interface{} {
+	config := map[string]interface{}{"delivery": deliveryOf(fn)}
+	if deliveryOf(fn) == DeliveryAtMostOnce {
+		config["retryCountOnStall"] = 0
+	}
+	addToolMetadata(config, fn)
+	return config
+}
+
+// claimCall records that this delivery of an at-most-once call is about to run. It reports false
+// when an earlier delivery already claimed the call.
+func (s *pollingAgent) claimCall(callId string) (bool, error) {
+	store := s.inferable.store()
+	key := fmt.Sprintf("call_%s_claim", callId)
+
+	if _, ok, err := store.Get(key); err != nil {
+		return false, err
+	} else if ok {
+		return false, nil
+	}
+
+	// Two deliveries may race to claim, the one whose value is stored wins
+	claim := fmt.Sprintf("%s-%d", s.inferable.machineID, rand.Int63())
+	if err := store.SetIfAbsent(key, claim); err != nil {
+		return false, err
+	}
+	stored, ok, err := store.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return ok && stored == claim, nil
+}
@@ -0,0 +1,69 @@
+package inferable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAtMostOnceDelivery(t *testing.T) {
+	store := NewMemoryStore()
+	i, results := newResultRecorder(t, InferableOptions{KVStore: store})
+
+	sends := 0
+	require.NoError(t, i.Tools.Register(Tool{
+		Name:     "notify",
+		Delivery: DeliveryAtMostOnce,
+		Func: func(input chargeInput, ctx ContextInput) (string, error) {
+			sends++
+			return "sent", nil
+		},
+	}))
+
+	msg := callMessage{Id: "job-1", Function: "notify", Input: map[string]interface{}{}}
+	require.NoError(t, i.Tools.handleMessage(msg))
+	require.NoError(t, i.Tools.handleMessage(msg))
+
+	assert.Equal(t, 1, sends)
+	reported := results()
+	require.Len(t, reported, 2)
+	assert.Equal(t, "resolution", reported[0].ResultType)
+	assert.Equal(t, "rejection", reported[1].ResultType)
+	assert.Contains(t, reported[1].Result, "not retried")
+}
+
+func TestAtMostOnceWithDedupeReturnsRecordedResult(t *testing.T) {
+	i, results := newResultRecorder(t, InferableOptions{KVStore: NewMemoryStore()})
+
+	require.NoError(t, i.Tools.Register(Tool{
+		Name:     "notify",
+		Delivery: DeliveryAtMostOnce,
+		Dedupe:   true,
+		Func: func(input chargeInput, ctx ContextInput) (string, error) {
+			return "sent", nil
+		},
+	}))
+
+	msg := callMessage{Id: "job-1", Function: "notify", Input: map[string]interface{}{}}
+	require.NoError(t, i.Tools.handleMessage(msg))
+	require.NoError(t, i.Tools.handleMessage(msg))
+
+	reported := results()
+	require.Len(t, reported, 2)
+	assert.Equal(t, reported[0], reported[1])
+}
+
+func TestDeliveryRegistration(t *testing.T) {
+	i := newTestClient(t, InferableOptions{}, nil)
+
+	err := i.Tools.Register(Tool{
+		Name:     "notify",
+		Delivery: "exactly-once",
+		Func:     func(input chargeInput, ctx ContextInput) (string, error) { return "", nil },
+	})
+	assert.ErrorContains(t, err, "unknown delivery semantics")
+
+	assert.Equal(t, map[string]interface{}{"delivery": DeliveryAtLeastOnce}, registrationConfig(Tool{}))
+	assert.Equal(t, map[string]interface{}{"delivery": DeliveryAtMostOnce, "retryCountOnStall": 0}, registrationConfig(Tool{Delivery: DeliveryAtMostOnce}))
+}
@@ -0,0 +1,356 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Changes in a ValueDiff
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// ValueDiff is a difference between the baseline and the candidate at a path, e.g. "order.items[0].sku".
+// The path is empty when the values differ as a whole.
+type ValueDiff struct {
+	Path      string      `json:"path"`
+	Change    string      `json:"change"`
+	Baseline  interface{} `json:"baseline,omitempty"`
+	Candidate interface{} `json:"candidate,omitempty"`
+}
+
+// AgentRunDiff compares the runs an agent was given in the two executions. Runs are matched by
+// name and the order they were started in, so Occurrence is 0 for the first run of an agent.
+type AgentRunDiff struct {
+	Name       string `json:"name"`
+	Occurrence int    `json:"occurrence"`
+	// BaselineRunID or CandidateRunID is empty when only the other execution ran the agent.
+	BaselineRunID          string `json:"baselineRunId,omitempty"`
+	CandidateRunID         string `json:"candidateRunId,omitempty"`
+	BaselineStatus         string `json:"baselineStatus,omitempty"`
+	CandidateStatus        string `json:"candidateStatus,omitempty"`
+	BaselineFailureReason  string `json:"baselineFailureReason,omitempty"`
+	CandidateFailureReason string `json:"candidateFailureReason,omitempty"`
+	// Transcript lists the differing lines of the runs' transcripts, at paths "transcript[n]".
+	Transcript []ValueDiff `json:"transcript,omitempty"`
+}
+
+// TimingDiff compares a duration in the two executions: "total" is the time from the execution
+// being created to it last being updated, and the others are the time from the execution being
+// created to an agent run or job event, e.g. "run:researcher" or "event:jobResulted#1" for the
+// second event of that type.
+type TimingDiff struct {
+	Name      string        `json:"name"`
+	Baseline  time.Duration `json:"baseline"`
+	Candidate time.Duration `json:"candidate"`
+}
+
+// Delta is how much slower the candidate was.
+func (d TimingDiff) Delta() time.Duration {
+	return d.Candidate - d.Baseline
+}
+
+// ExecutionDiff is a structured report of how a candidate execution differs from a baseline
+// execution of the same workflow.
+type ExecutionDiff struct {
+	WorkflowName string             `json:"workflowName"`
+	Baseline     *ExecutionSnapshot `json:"baseline"`
+	Candidate    *ExecutionSnapshot `json:"candidate"`
+	// Input compares the inputs, without their executionId.
+	Input []ValueDiff `json:"input,omitempty"`
+	// Memos compares the ctx.Memo results, at paths starting with the memo's name.
+	Memos  []ValueDiff    `json:"memos,omitempty"`
+	Result []ValueDiff    `json:"result,omitempty"`
+	Agents []AgentRunDiff `json:"agents,omitempty"`
+	// Timings lists every duration measured in both executions, whether or not it differs.
+	Timings []TimingDiff `json:"timings,omitempty"`
+}
+
+// Empty reports whether the executions have the same status, input, memo results, result and
+// agent transcripts. Timings are not compared.
+func (d *ExecutionDiff) Empty() bool {
+	return d.Baseline.Status == d.Candidate.Status &&
+		len(d.Input) == 0 && len(d.Memos) == 0 && len(d.Result) == 0 && len(d.Agents) == 0
+}
+
+// DiffExecutions compares two executions of a workflow, e.g. a failed execution against one that
+// succeeded with similar input, to find where they diverged: their inputs, memoized step outputs,
+// results, agent transcripts, and timings.
+//
+//	diff, err := client.Workflows.DiffExecutions("refunds", "exec-ok", "exec-failed")
+//	for _, memo := range diff.Memos {
+//		fmt.Printf("%s %s: %v -> %v\n", memo.Change, memo.Path, memo.Baseline, memo.Candidate)
+//	}
+func (w *Workflows) DiffExecutions(workflowName string, baselineId string, candidateId string) (*ExecutionDiff, error) {
+	baselineTimeline, err := w.fetchTimeline(workflowName, baselineId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baseline execution: %v", err)
+	}
+	candidateTimeline, err := w.fetchTimeline(workflowName, candidateId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get candidate execution: %v", err)
+	}
+
+	baseline, err := w.snapshotOf(workflowName, baselineId, baselineTimeline)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := w.snapshotOf(workflowName, candidateId, candidateTimeline)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ExecutionDiff{
+		WorkflowName: workflowName,
+		Baseline:     baseline,
+		Candidate:    candidate,
+		Input:        diffValues("", withoutExecutionId(baseline.Input), withoutExecutionId(candidate.Input), nil),
+		Memos:        diffValues("", baseline.Memos(), candidate.Memos(), nil),
+		Result:       diffValues("", baseline.Result, candidate.Result, nil),
+		Timings:      diffTimings(baselineTimeline, candidateTimeline),
+	}
+
+	diff.Agents, err = w.diffRuns(baselineTimeline, candidateTimeline)
+	if err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+func withoutExecutionId(input map[string]interface{}) map[string]interface{} {
+	if input == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(input))
+	for key, value := range input {
+		if key != "executionId" {
+			copied[key] = value
+		}
+	}
+	return copied
+}
+
+// diffValues appends the differences between two decoded JSON values to diffs.
+func diffValues(path string, baseline interface{}, candidate interface{}, diffs []ValueDiff) []ValueDiff {
+	switch {
+	case baseline == nil && candidate == nil:
+		return diffs
+	case baseline == nil:
+		return append(diffs, ValueDiff{Path: path, Change: DiffAdded, Candidate: candidate})
+	case candidate == nil:
+		return append(diffs, ValueDiff{Path: path, Change: DiffRemoved, Baseline: baseline})
+	}
+
+	baselineMap, baselineIsMap := baseline.(map[string]interface{})
+	candidateMap, candidateIsMap := candidate.(map[string]interface{})
+	if baselineIsMap && candidateIsMap {
+		keys := []string{}
+		for key := range baselineMap {
+			keys = append(keys, key)
+		}
+		for key := range candidateMap {
+			if _, ok := baselineMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			diffs = diffValues(keyPath, baselineMap[key], candidateMap[key], diffs)
+		}
+		return diffs
+	}
+
+	baselineSlice, baselineIsSlice := baseline.([]interface{})
+	candidateSlice, candidateIsSlice := candidate.([]interface{})
+	if baselineIsSlice && candidateIsSlice {
+		for n := 0; n < len(baselineSlice) || n < len(candidateSlice); n++ {
+			var baselineItem, candidateItem interface{}
+			if n < len(baselineSlice) {
+				baselineItem = baselineSlice[n]
+			}
+			if n < len(candidateSlice) {
+				candidateItem = candidateSlice[n]
+			}
+			diffs = diffValues(fmt.Sprintf("%s[%d]", path, n), baselineItem, candidateItem, diffs)
+		}
+		return diffs
+	}
+
+	if !reflect.DeepEqual(baseline, candidate) {
+		diffs = append(diffs, ValueDiff{Path: path, Change: DiffChanged, Baseline: baseline, Candidate: candidate})
+	}
+	return diffs
+}
+
+// occurrenceKey names the n-th occurrence of a name, counting from 0.
+func occurrenceKey(name string, n int) string {
+	if n == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s#%d", name, n)
+}
+
+// offsets returns the time from the execution being created to each of its runs and events.
+func offsets(timeline *executionTimeline) (map[string]time.Duration, []string) {
+	createdAt := timeline.Execution.CreatedAt
+	durations := map[string]time.Duration{
+		"total": timeline.Execution.UpdatedAt.Sub(createdAt),
+	}
+	names := []string{"total"}
+	seen := map[string]int{}
+	add := func(name string, at time.Time) {
+		key := occurrenceKey(name, seen[name])
+		seen[name]++
+		durations[key] = at.Sub(createdAt)
+		names = append(names, key)
+	}
+
+	for _, run := range timeline.Runs {
+		add("run:"+run.Name, run.CreatedAt)
+	}
+	for _, event := range timeline.Events {
+		add("event:"+event.Type, event.CreatedAt)
+	}
+	return durations, names
+}
+
+func diffTimings(baseline *executionTimeline, candidate *executionTimeline) []TimingDiff {
+	baselineOffsets, names := offsets(baseline)
+	candidateOffsets, _ := offsets(candidate)
+
+	timings := []TimingDiff{}
+	for _, name := range names {
+		if candidateOffset, ok := candidateOffsets[name]; ok {
+			timings = append(timings, TimingDiff{Name: name, Baseline: baselineOffsets[name], Candidate: candidateOffset})
+		}
+	}
+	return timings
+}
+
+// diffRuns matches the agent runs of the two executions and compares their transcripts.
+func (w *Workflows) diffRuns(baseline *executionTimeline, candidate *executionTimeline) ([]AgentRunDiff, error) {
+	type run struct {
+		name, id, status, failureReason string
+		occurrence                      int
+	}
+	byKey := func(timeline *executionTimeline) (map[string]run, []string) {
+		runs := map[string]run{}
+		keys := []string{}
+		seen := map[string]int{}
+		for _, r := range timeline.Runs {
+			key := occurrenceKey(r.Name, seen[r.Name])
+			seen[r.Name]++
+			matched := run{name: r.Name, occurrence: seen[r.Name] - 1, id: r.ID}
+			if r.Status != nil {
+				matched.status = *r.Status
+			}
+			if r.FailureReason != nil {
+				matched.failureReason = *r.FailureReason
+			}
+			runs[key] = matched
+			keys = append(keys, key)
+		}
+		return runs, keys
+	}
+
+	baselineRuns, keys := byKey(baseline)
+	candidateRuns, candidateKeys := byKey(candidate)
+	for _, key := range candidateKeys {
+		if _, ok := baselineRuns[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	diffs := []AgentRunDiff{}
+	for _, key := range keys {
+		baselineRun, candidateRun := baselineRuns[key], candidateRuns[key]
+		matched := baselineRun
+		if matched.id == "" {
+			matched = candidateRun
+		}
+
+		diff := AgentRunDiff{
+			Name:                   matched.name,
+			Occurrence:             matched.occurrence,
+			BaselineRunID:          baselineRun.id,
+			CandidateRunID:         candidateRun.id,
+			BaselineStatus:         baselineRun.status,
+			CandidateStatus:        candidateRun.status,
+			BaselineFailureReason:  baselineRun.failureReason,
+			CandidateFailureReason: candidateRun.failureReason,
+		}
+
+		baselineTranscript, err := w.transcript(baselineRun.id)
+		if err != nil {
+			return nil, err
+		}
+		candidateTranscript, err := w.transcript(candidateRun.id)
+		if err != nil {
+			return nil, err
+		}
+		diff.Transcript = diffValues("transcript", baselineTranscript, candidateTranscript, nil)
+
+		if diff.BaselineRunID == "" || diff.CandidateRunID == "" ||
+			diff.BaselineStatus != diff.CandidateStatus ||
+			diff.BaselineFailureReason != diff.CandidateFailureReason ||
+			len(diff.Transcript) > 0 {
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs, nil
+}
+
+// transcript returns the messages of a run as one line each, e.g. `call getOrder {"id":"A-1"}`.
+func (w *Workflows) transcript(runId string) ([]interface{}, error) {
+	lines := []interface{}{}
+	if runId == "" {
+		return lines, nil
+	}
+
+	clusterId, err := w.inferable.getClusterId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster id: %w", err)
+	}
+
+	messages, err := listRunMessages(w.inferable.client, clusterId, runId)
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		switch message.Type {
+		case "agent":
+			if message.Data.Message != "" {
+				lines = append(lines, "agent: "+message.Data.Message)
+			}
+			for _, invocation := range message.Data.Invocations {
+				lines = append(lines, fmt.Sprintf("call %s %s", invocation.ToolName, compactJSON(invocation.Input)))
+			}
+			if message.Data.Result != nil {
+				lines = append(lines, "agent result: "+compactJSON(message.Data.Result))
+			}
+		case "invocation-result":
+			lines = append(lines, fmt.Sprintf("result %s %s: %s", message.Data.ToolName, message.Data.ResultType, compactJSON(message.Data.Result)))
+		default:
+			lines = append(lines, fmt.Sprintf("%s: %s", message.Type, message.Data.Message))
+		}
+	}
+	return lines, nil
+}
+
+func compactJSON(value interface{}) string {
+	serialized, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(serialized)
+}
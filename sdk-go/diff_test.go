@@ -0,0 +1,130 @@
+package inferable
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffExecutions(t *testing.T) {
+	timelines := map[string]map[string]interface{}{
+		"exec-ok": {
+			"execution": map[string]interface{}{
+				"createdAt": "2025-01-01T00:00:00Z",
+				"updatedAt": "2025-01-01T00:00:10Z",
+				"job": map[string]interface{}{
+					"status":     "success",
+					"targetArgs": `{"value":{"executionId":"exec-ok","amount":30,"items":["a"]}}`,
+					"result":     `{"refunded":30}`,
+					"resultType": "resolution",
+				},
+			},
+			"runs": []map[string]interface{}{
+				{"id": "run-ok", "name": "researcher", "status": "done", "createdAt": "2025-01-01T00:00:01Z"},
+			},
+			"events": []map[string]interface{}{
+				{"type": "jobResulted", "createdAt": "2025-01-01T00:00:09Z"},
+			},
+			"memos": []map[string]interface{}{{"key": "exec-ok_memo_lookup", "value": `{"value":{"order":"A-1","total":30}}`}},
+		},
+		"exec-failed": {
+			"execution": map[string]interface{}{
+				"createdAt": "2025-01-01T01:00:00Z",
+				"updatedAt": "2025-01-01T01:00:30Z",
+				"job": map[string]interface{}{
+					"status":     "failure",
+					"targetArgs": `{"value":{"executionId":"exec-failed","amount":30,"items":["a","b"]}}`,
+					"result":     `{"message":"Order not found"}`,
+					"resultType": "rejection",
+				},
+			},
+			"runs": []map[string]interface{}{
+				{"id": "run-failed", "name": "researcher", "status": "failed", "failureReason": "Tool failed", "createdAt": "2025-01-01T01:00:05Z"},
+				{"id": "run-retry", "name": "researcher", "status": "done", "createdAt": "2025-01-01T01:00:20Z"},
+			},
+			"events": []map[string]interface{}{
+				{"type": "jobResulted", "createdAt": "2025-01-01T01:00:29Z"},
+			},
+			"memos": []map[string]interface{}{{"key": "exec-failed_memo_lookup", "value": `{"value":{"order":"A-1","total":45}}`}},
+		},
+	}
+	messages := map[string][]map[string]interface{}{
+		"run-ok": {
+			{"id": "1", "type": "human", "data": map[string]interface{}{"message": "Find order A-1"}},
+			{"id": "2", "type": "agent", "data": map[string]interface{}{"invocations": []map[string]interface{}{{"toolName": "getOrder", "input": map[string]interface{}{"id": "A-1"}}}}},
+			{"id": "3", "type": "invocation-result", "data": map[string]interface{}{"toolName": "getOrder", "resultType": "resolution", "result": map[string]interface{}{"total": 30}}},
+		},
+		"run-failed": {
+			{"id": "1", "type": "human", "data": map[string]interface{}{"message": "Find order A-1"}},
+			{"id": "2", "type": "agent", "data": map[string]interface{}{"invocations": []map[string]interface{}{{"toolName": "getOrder", "input": map[string]interface{}{"id": "A-1"}}}}},
+			{"id": "3", "type": "invocation-result", "data": map[string]interface{}{"toolName": "getOrder", "resultType": "rejection", "result": "timeout"}},
+		},
+		"run-retry": {},
+	}
+
+	// A store that cannot list its keys, so that memos come from the cluster
+	i := newTestClient(t, InferableOptions{KVStore: unlistedStore{NewMemoryStore()}}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/refunds/executions/exec-ok/timeline": func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(timelines["exec-ok"])
+		},
+		"/clusters/test-cluster/workflows/refunds/executions/exec-failed/timeline": func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(timelines["exec-failed"])
+		},
+		"/clusters/test-cluster/runs/{runId}/messages": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "0", r.URL.Query().Get("after"))
+			json.NewEncoder(w).Encode(messages[r.PathValue("runId")])
+		},
+	})
+
+	diff, err := i.Workflows.DiffExecutions("refunds", "exec-ok", "exec-failed")
+	require.NoError(t, err)
+	assert.False(t, diff.Empty())
+	assert.Equal(t, ExecutionSuccess, diff.Baseline.Status)
+	assert.Equal(t, ExecutionFailure, diff.Candidate.Status)
+
+	assert.Equal(t, []ValueDiff{{Path: "items[1]", Change: DiffAdded, Candidate: "b"}}, diff.Input)
+	assert.Equal(t, []ValueDiff{{Path: "lookup.total", Change: DiffChanged, Baseline: float64(30), Candidate: float64(45)}}, diff.Memos)
+	assert.Equal(t, []ValueDiff{
+		{Path: "message", Change: DiffAdded, Candidate: "Order not found"},
+		{Path: "refunded", Change: DiffRemoved, Baseline: float64(30)},
+	}, diff.Result)
+
+	require.Len(t, diff.Agents, 2)
+	assert.Equal(t, AgentRunDiff{
+		Name:                   "researcher",
+		BaselineRunID:          "run-ok",
+		CandidateRunID:         "run-failed",
+		BaselineStatus:         "done",
+		CandidateStatus:        "failed",
+		CandidateFailureReason: "Tool failed",
+		Transcript: []ValueDiff{{
+			Path:      "transcript[2]",
+			Change:    DiffChanged,
+			Baseline:  `result getOrder resolution: {"total":30}`,
+			Candidate: `result getOrder rejection: "timeout"`,
+		}},
+	}, diff.Agents[0])
+	assert.Equal(t, "researcher", diff.Agents[1].Name)
+	assert.Equal(t, 1, diff.Agents[1].Occurrence)
+	assert.Empty(t, diff.Agents[1].BaselineRunID)
+	assert.Equal(t, "run-retry", diff.Agents[1].CandidateRunID)
+
+	assert.Equal(t, []TimingDiff{
+		{Name: "total", Baseline: 10 * time.Second, Candidate: 30 * time.Second},
+		{Name: "run:researcher", Baseline: time.Second, Candidate: 5 * time.Second},
+		{Name: "event:jobResulted", Baseline: 9 * time.Second, Candidate: 29 * time.Second},
+	}, diff.Timings)
+	assert.Equal(t, 20*time.Second, diff.Timings[0].Delta())
+
+	// An execution compared with itself has no differences
+	same, err := i.Workflows.DiffExecutions("refunds", "exec-ok", "exec-ok")
+	require.NoError(t, err)
+	assert.True(t, same.Empty())
+
+	_, err = i.Workflows.DiffExecutions("refunds", "exec-ok", "exec-missing")
+	assert.ErrorContains(t, err, "failed to get candidate execution: workflow execution exec-missing not found")
+}
@@ -0,0 +1,216 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+const (
+	// MaxPayloadBytes is the largest request body the control plane accepts.
+	MaxPayloadBytes = 1 << 20
+	// DefaultMaxClockSkew is the clock difference to the control plane Doctor tolerates.
+	DefaultMaxClockSkew = 30 * time.Second
+)
+
+// Doctor check statuses.
+const (
+	DoctorOK      = "ok"
+	DoctorWarn    = "warn"
+	DoctorFail    = "fail"
+	DoctorSkipped = "skipped"
+)
+
+// DoctorOptions configures Doctor.
+type DoctorOptions struct {
+	// MaxClockSkew is the clock difference to the control plane reported as a failure.
+	// Defaults to DefaultMaxClockSkew.
+	MaxClockSkew time.Duration
+}
+
+// DoctorCheck is the outcome of one Doctor check.
+type DoctorCheck struct {
+	Name string `json:"name"`
+	// Status is one of the Doctor* statuses.
+	Status  string        `json:"status"`
+	Message string        `json:"message"`
+	Elapsed time.Duration `json:"elapsed"`
+}
+
+// DoctorReport is the outcome of Doctor.
+type DoctorReport struct {
+	Endpoint string        `json:"endpoint"`
+	Checks   []DoctorCheck `json:"checks"`
+}
+
+// OK reports whether no check failed. Warnings do not count as failures.
+func (r *DoctorReport) OK() bool {
+	for _, check := range r.Checks {
+		if check.Status == DoctorFail {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the report for the terminal.
+func (r *DoctorReport) String() string {
+	lines := []string{fmt.Sprintf("inferable doctor: %s", r.Endpoint)}
+	for _, check := range r.Checks {
+		lines = append(lines, fmt.Sprintf("  [%s] %s: %s", strings.ToUpper(check.Status), check.Name, check.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Doctor checks that this client can work against the configured environment: the endpoint is
+// reachable, the API secret is accepted, the local clock agrees with the control plane, every
+// registered tool and workflow reflects into a schema, and the registration payload fits within
+// the control plane's size limit. It registers no tools and does not poll.
+//
+//	report := client.Doctor()
+//	fmt.Println(report)
+//	if !report.OK() {
+//		os.Exit(1)
+//	}
+func (i *Inferable) Doctor(options ...DoctorOptions) *DoctorReport {
+	maxSkew := DefaultMaxClockSkew
+	for _, option := range options {
+		if option.MaxClockSkew > 0 {
+			maxSkew = option.MaxClockSkew
+		}
+	}
+
+	report := &DoctorReport{Endpoint: i.apiEndpoint}
+	run := func(name string, check func() (string, string)) {
+		start := time.Now()
+		status, message := check()
+		report.Checks = append(report.Checks, DoctorCheck{Name: name, Status: status, Message: message, Elapsed: time.Since(start)})
+	}
+
+	var serverDate string
+	var roundTrip time.Duration
+	var requested time.Time
+	reachable := false
+
+	run("endpoint", func() (string, string) {
+		// The system clock is measured, not the skew compensated one
+		requested = time.Now()
+		data, headers, err, _ := i.client.FetchData(client.FetchDataOptions{Path: "/live", Method: "GET", Idempotent: true})
+		roundTrip = time.Since(requested)
+		if err != nil {
+			return DoctorFail, fmt.Sprintf("%s is not reachable: %v", i.apiEndpoint, err)
+		}
+
+		var response struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal([]byte(data), &response); err != nil || response.Status != "ok" {
+			return DoctorFail, fmt.Sprintf("%s/live did not report ok, is this an Inferable endpoint?", i.apiEndpoint)
+		}
+
+		reachable = true
+		serverDate = headers.Get("Date")
+		return DoctorOK, fmt.Sprintf("reachable in %s", roundTrip.Round(time.Millisecond))
+	})
+
+	run("auth", func() (string, string) {
+		if !reachable {
+			return DoctorSkipped, "endpoint is not reachable"
+		}
+		if i.apiSecret == "" {
+			return DoctorFail, "no API secret configured"
+		}
+		clusterId, err := i.registerMachine(nil)
+		if err != nil {
+			return DoctorFail, fmt.Sprintf("API secret was rejected: %v", err)
+		}
+		if clusterId == "" {
+			return DoctorFail, "API secret did not resolve to a cluster"
+		}
+		i.setClusterId(clusterId)
+		return DoctorOK, fmt.Sprintf("authenticated to cluster %s", clusterId)
+	})
+
+	run("clock", func() (string, string) {
+		if serverDate == "" {
+			return DoctorSkipped, "the control plane did not report its time"
+		}
+		server, err := http.ParseTime(serverDate)
+		if err != nil {
+			return DoctorSkipped, fmt.Sprintf("failed to parse control plane time %q: %v", serverDate, err)
+		}
+
+		// The Date header has second precision and was set halfway through the round trip
+		skew := requested.Add(roundTrip / 2).Sub(server)
+		if skew < 0 {
+			skew = -skew
+		}
+		skew = skew.Round(time.Second)
+		if skew > maxSkew+time.Second {
+			return DoctorFail, fmt.Sprintf("local clock is %s off the control plane (limit %s), deadlines and sleeps will be wrong", skew, maxSkew)
+		}
+		return DoctorOK, fmt.Sprintf("local clock is within %s of the control plane", skew+time.Second)
+	})
+
+	tools := i.registrationTools()
+
+	run("schemas", func() (string, string) {
+		if len(tools) == 0 {
+			return DoctorSkipped, "no tools or workflows registered"
+		}
+		problems := []string{}
+		for _, tool := range tools {
+			if _, err := reflectToolSchema(tool); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+		if len(problems) > 0 {
+			sort.Strings(problems)
+			return DoctorFail, strings.Join(problems, "; ")
+		}
+		return DoctorOK, fmt.Sprintf("%d tools reflect into valid schemas", len(tools))
+	})
+
+	run("payload", func() (string, string) {
+		if len(tools) == 0 {
+			return DoctorSkipped, "no tools or workflows registered"
+		}
+
+		total, largest, largestSize := 0, "", 0
+		for _, tool := range tools {
+			schema, err := reflectToolSchema(tool)
+			if err != nil {
+				continue
+			}
+			// Schemas are sent as strings, as in registerMachine
+			schemaJSON, err := json.Marshal(schema)
+			if err != nil {
+				continue
+			}
+			data, err := json.Marshal(map[string]string{"name": tool.Name, "description": tool.Description, "schema": string(schemaJSON)})
+			if err != nil {
+				continue
+			}
+			total += len(data)
+			if len(data) > largestSize {
+				largest, largestSize = tool.Name, len(data)
+			}
+		}
+
+		message := fmt.Sprintf("registration payload is %d of %d bytes, largest tool is %s (%d bytes)", total, MaxPayloadBytes, largest, largestSize)
+		switch {
+		case total > MaxPayloadBytes:
+			return DoctorFail, message
+		case total > MaxPayloadBytes*8/10:
+			return DoctorWarn, message
+		}
+		return DoctorOK, message
+	})
+
+	return report
+}
@@ -0,0 +1,100 @@
+package inferable
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDoctorClient(t *testing.T, date string, status int) *Inferable {
+	t.Helper()
+
+	setDate := func(w http.ResponseWriter) {
+		if date != "" {
+			w.Header().Set("Date", date)
+		}
+	}
+	return newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/live": func(w http.ResponseWriter, r *http.Request) {
+			setDate(w)
+			w.Write([]byte(`{"status":"ok"}`))
+		},
+		"/machines": func(w http.ResponseWriter, r *http.Request) {
+			setDate(w)
+			if status != http.StatusOK {
+				w.WriteHeader(status)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"clusterId": "test-cluster"})
+		},
+	})
+}
+
+func doctorStatuses(report *DoctorReport) map[string]string {
+	statuses := map[string]string{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+	}
+	return statuses
+}
+
+func TestDoctor(t *testing.T) {
+	i := newDoctorClient(t, time.Now().UTC().Format(http.TimeFormat), http.StatusOK)
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "doctor", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return nil, nil
+	})
+
+	report := i.Doctor()
+	assert.True(t, report.OK(), report.String())
+	assert.Equal(t, map[string]string{
+		"endpoint": DoctorOK,
+		"auth":     DoctorOK,
+		"clock":    DoctorOK,
+		"schemas":  DoctorOK,
+		"payload":  DoctorOK,
+	}, doctorStatuses(report))
+	assert.Equal(t, "test-cluster", i.clusterID)
+}
+
+func TestDoctorFailures(t *testing.T) {
+	skewed := time.Now().Add(-5 * time.Minute).UTC().Format(http.TimeFormat)
+	i := newDoctorClient(t, skewed, http.StatusUnauthorized)
+
+	require.NoError(t, i.Tools.Register(Tool{
+		Name:        "huge",
+		Description: strings.Repeat("x", MaxPayloadBytes),
+		Func:        func(input struct{}, ctx ContextInput) (string, error) { return "", nil },
+	}))
+	i.Tools.Tools["broken"] = Tool{Name: "broken", Func: "not a function"}
+
+	report := i.Doctor(DoctorOptions{MaxClockSkew: time.Minute})
+	assert.False(t, report.OK())
+	assert.Equal(t, map[string]string{
+		"endpoint": DoctorOK,
+		"auth":     DoctorFail,
+		"clock":    DoctorFail,
+		"schemas":  DoctorFail,
+		"payload":  DoctorFail,
+	}, doctorStatuses(report))
+	assert.Contains(t, report.String(), "tool 'broken' must be a function")
+}
+
+func TestDoctorUnreachable(t *testing.T) {
+	i, err := New(InferableOptions{APIEndpoint: "http://127.0.0.1:1", APISecret: "test-secret"})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"endpoint": DoctorFail,
+		"auth":     DoctorSkipped,
+		"clock":    DoctorSkipped,
+		"schemas":  DoctorSkipped,
+		"payload":  DoctorSkipped,
+	}, doctorStatuses(i.Doctor()))
+}
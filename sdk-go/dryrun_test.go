@@ -0,0 +1,75 @@
+package inferable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListenDryRun(t *testing.T) {
+	i := newVerifyClient(t, map[string]interface{}{})
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) { return nil, nil })
+	workflow.Tools.Register(WorkflowTool{
+		Name: "lookup",
+		Func: func(input struct {
+			Id string `json:"id"`
+		}, ctx ContextInput) (string, error) {
+			return "", nil
+		},
+	})
+
+	require.NoError(t, workflow.Listen(ListenOptions{DryRun: true}))
+
+	// Nothing was registered
+	assert.Empty(t, i.Tools.Tools)
+}
+
+func TestListenDryRunProblems(t *testing.T) {
+	i := newVerifyClient(t, map[string]interface{}{})
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "tool_orders_lookup",
+		Func: func(input struct{}, ctx ContextInput) (string, error) { return "", nil },
+	}))
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders"})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input struct {
+		Id string `json:"id"`
+	}) (interface{}, error) {
+		return nil, nil
+	})
+	workflow.Tools.Register(WorkflowTool{Name: "lookup", Func: func(input string, ctx ContextInput) (string, error) { return "", nil }})
+	workflow.Tools.Register(WorkflowTool{Name: "lookup", Func: func(input struct{}, ctx ContextInput) (string, error) { return "", nil }})
+
+	err := workflow.Listen(ListenOptions{DryRun: true})
+
+	var dryRunErr *DryRunError
+	require.ErrorAs(t, err, &dryRunErr)
+	assert.Equal(t, []string{
+		"tool 'tool_orders_lookup' first argument must be a struct or a pointer to a struct",
+		"tool with name 'tool_orders_lookup' already registered",
+		"tool with name 'tool_orders_lookup' already registered",
+		"tool with name 'tool_orders_lookup' is defined more than once",
+		"version 1 input must contain an ExecutionId field with json tag \"executionId\"",
+	}, dryRunErr.Problems)
+}
+
+func TestListenDryRunUnauthorized(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(server.Close)
+
+	i, err := New(InferableOptions{APIEndpoint: server.URL, APISecret: "invalid"})
+	require.NoError(t, err)
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) { return nil, nil })
+
+	err = workflow.Listen(ListenOptions{DryRun: true})
+	assert.ErrorContains(t, err, "failed to authenticate with the cluster")
+}
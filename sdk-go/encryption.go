@@ -0,0 +1,197 @@
+package inferable
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// encryptedPrefix starts every value written by an encrypted workflow store.
+const encryptedPrefix = "enc:v1:"
+
+// KeyProvider supplies the AES keys (16, 24, or 32 bytes) that encrypt a workflow's ctx.Memo and
+// ctx.State values, e.g. from a KMS or secret manager. Keys are identified so that they can be
+// rotated: values are written with the current key and read with the key they were written with.
+type KeyProvider interface {
+	// CurrentKey returns the key new values of the workflow are encrypted with, and its ID.
+	CurrentKey(workflowName string) (id string, key []byte, err error)
+	// Key returns the workflow's key with the given ID.
+	Key(workflowName string, id string) ([]byte, error)
+}
+
+// StaticKey returns a KeyProvider with a single key for every workflow.
+func StaticKey(id string, key []byte) KeyProvider {
+	return staticKey{id: id, key: key}
+}
+
+type staticKey struct {
+	id  string
+	key []byte
+}
+
+func (k staticKey) CurrentKey(workflowName string) (string, []byte, error) {
+	return k.id, k.key, nil
+}
+
+func (k staticKey) Key(workflowName string, id string) ([]byte, error) {
+	if id != k.id {
+		return nil, fmt.Errorf("unknown key %s", id)
+	}
+	return k.key, nil
+}
+
+// encryption encrypts a workflow's values with keys from its KeyProvider.
+type encryption struct {
+	workflowName string
+	provider     KeyProvider
+
+	mu sync.Mutex
+	// ciphers caches a cipher per key ID
+	ciphers map[string]cipher.AEAD
+}
+
+func newEncryption(workflowName string, provider KeyProvider) *encryption {
+	return &encryption{workflowName: workflowName, provider: provider, ciphers: map[string]cipher.AEAD{}}
+}
+
+func (e *encryption) cipher(id string, key []byte) (cipher.AEAD, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if aead, ok := e.ciphers[id]; ok {
+		return aead, nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key %s: %v", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	e.ciphers[id] = aead
+	return aead, nil
+}
+
+// encrypt seals value with the current key. The store key is authenticated along with it, so a
+// value cannot be moved to another key undetected.
+func (e *encryption) encrypt(storeKey string, value string) (string, error) {
+	id, key, err := e.provider.CurrentKey(e.workflowName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get encryption key: %v", err)
+	}
+	if id == "" || strings.Contains(id, ":") {
+		return "", fmt.Errorf("invalid encryption key id %q", id)
+	}
+	aead, err := e.cipher(id, key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(storeKey))
+	return encryptedPrefix + id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt opens a value written by encrypt.
+func (e *encryption) decrypt(storeKey string, value string) (string, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return "", fmt.Errorf("value of %s is not encrypted", storeKey)
+	}
+	id, encoded, ok := strings.Cut(encoded, ":")
+	if !ok {
+		return "", fmt.Errorf("value of %s is malformed", storeKey)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("value of %s is malformed: %v", storeKey, err)
+	}
+
+	e.mu.Lock()
+	aead, cached := e.ciphers[id]
+	e.mu.Unlock()
+	if !cached {
+		key, err := e.provider.Key(e.workflowName, id)
+		if err != nil {
+			return "", fmt.Errorf("failed to get encryption key: %v", err)
+		}
+		if aead, err = e.cipher(id, key); err != nil {
+			return "", err
+		}
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("value of %s is malformed", storeKey)
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, sealed, []byte(storeKey))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %v", storeKey, err)
+	}
+	return string(plain), nil
+}
+
+// encryptedStore encrypts values on their way into a workflow's store and decrypts them on the
+// way out, so that only ciphertext reaches the cluster.
+type encryptedStore struct {
+	store      KVStore
+	encryption *encryption
+}
+
+func (s *encryptedStore) Get(key string) (string, bool, error) {
+	value, ok, err := s.store.Get(key)
+	if err != nil || !ok {
+		return "", ok, err
+	}
+	plain, err := s.encryption.decrypt(key, value)
+	if err != nil {
+		return "", false, err
+	}
+	return plain, true, nil
+}
+
+func (s *encryptedStore) SetIfAbsent(key string, value string) error {
+	sealed, err := s.encryption.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	return s.store.SetIfAbsent(key, sealed)
+}
+
+func (s *encryptedStore) SetIfAbsentUntil(key string, value string, expiresAt time.Time) error {
+	expiring, ok := s.store.(ExpiringKVStore)
+	if !ok {
+		return fmt.Errorf("the KVStore does not implement ExpiringKVStore")
+	}
+	sealed, err := s.encryption.encrypt(key, value)
+	if err != nil {
+		return err
+	}
+	return expiring.SetIfAbsentUntil(key, sealed, expiresAt)
+}
+
+func (s *encryptedStore) List(prefix string, options KVListOptions) (*KVPage, error) {
+	lister, ok := s.store.(KVLister)
+	if !ok {
+		return nil, fmt.Errorf("the KVStore does not implement KVLister")
+	}
+	page, err := lister.List(prefix, options)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range page.Entries {
+		if page.Entries[i].Value, err = s.encryption.decrypt(entry.Key, entry.Value); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,84 @@
+package inferable
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rotatingKeys is a KeyProvider holding several keys, the last of which is current.
+type rotatingKeys []string
+
+func (k rotatingKeys) CurrentKey(workflowName string) (string, []byte, error) {
+	id := k[len(k)-1]
+	return id, bytes.Repeat([]byte(id[:1]), 32), nil
+}
+
+func (k rotatingKeys) Key(workflowName string, id string) ([]byte, error) {
+	for _, known := range k {
+		if known == id {
+			return bytes.Repeat([]byte(id[:1]), 32), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key %s", id)
+}
+
+func TestEncryptedWorkflow(t *testing.T) {
+	store := NewMemoryStore()
+	i := newTestClient(t, InferableOptions{KVStore: store}, nil)
+
+	calls := 0
+	deploy := func(keys KeyProvider) *Workflow {
+		workflow := i.Workflows.Create(WorkflowConfig{Name: "patients", InputSchema: WorkflowInput{}, KeyProvider: keys})
+		workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+			diagnosis, err := ctx.Memo("diagnosis", func() (interface{}, error) {
+				calls++
+				return "confidential diagnosis", nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if _, err := ctx.State.Set("notes", "confidential notes"); err != nil {
+				return nil, err
+			}
+			notes, _, err := GetState[string](ctx.State, "notes")
+			return []interface{}{diagnosis, notes}, err
+		})
+		return workflow
+	}
+
+	result, err := deploy(StaticKey("a1", bytes.Repeat([]byte("a"), 32))).Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"confidential diagnosis", "confidential notes"}, result)
+
+	// Only ciphertext reaches the store
+	for _, key := range store.Keys() {
+		value, _, _ := store.Get(key)
+		assert.True(t, strings.HasPrefix(value, "enc:v1:a1:"), key)
+		assert.NotContains(t, value, "confidential")
+	}
+
+	// After rotating, values written with the old key are still read
+	result, err = deploy(rotatingKeys{"a1", "b2"}).Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"confidential diagnosis", "confidential notes"}, result)
+	assert.Equal(t, 1, calls)
+
+	value, _, _ := store.Get(stateKey("exec-1", "notes", 2))
+	assert.True(t, strings.HasPrefix(value, "enc:v1:b2:"))
+
+	// A value copied to another key does not decrypt
+	copied, _, _ := store.Get(memoKey("exec-1", "diagnosis"))
+	store.Set(memoKey("exec-2", "diagnosis"), copied)
+	encrypted := &encryptedStore{store: store, encryption: newEncryption("patients", rotatingKeys{"a1", "b2"})}
+	_, _, err = encrypted.Get(memoKey("exec-2", "diagnosis"))
+	assert.ErrorContains(t, err, "failed to decrypt")
+
+	// Without the key, the values are unreadable
+	_, err = deploy(StaticKey("c3", bytes.Repeat([]byte("c"), 32))).Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	assert.ErrorContains(t, err, "unknown key")
+}
@@ -0,0 +1,23 @@
+package inferable
+
+import "github.com/inferablehq/inferable/sdk-go/internal/client"
+
+// APIError describes a failed request to the Inferable API: its StatusCode, the RequestID to
+// quote when reporting it, and the API's Code and Message. Errors of requests made by the client
+// wrap it, so that callers can inspect it with errors.As:
+//
+//	var apiErr *inferable.APIError
+//	if errors.As(err, &apiErr) && apiErr.Temporary() {
+//		// retry later
+//	}
+type APIError = client.APIError
+
+// Sentinel errors matched by errors.Is for API errors of the corresponding status.
+var (
+	// ErrUnauthorized matches 401 and 403 responses, e.g. for an invalid or revoked API secret.
+	ErrUnauthorized = client.ErrUnauthorized
+	// ErrNotFound matches 404 responses.
+	ErrNotFound = client.ErrNotFound
+	// ErrRateLimited matches 429 responses.
+	ErrRateLimited = client.ErrRateLimited
+)
@@ -0,0 +1,53 @@
+package inferable
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIErrors(t *testing.T) {
+	i := newTestClient(t, InferableOptions{Retry: &RetryOptions{MaxAttempts: 1}}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/orders/executions": func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request-Id", "req-1")
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"error": map[string]interface{}{"message": "Invalid API secret", "code": "INVALID_SECRET"},
+			})
+		},
+		"/clusters/test-cluster/workflows/reports/executions": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte("slow down"))
+		},
+		"/clusters/test-cluster/workflows/shipping/executions": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		},
+	})
+
+	_, err := i.Workflows.Trigger("orders", "exec-1", map[string]interface{}{})
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, APIError{StatusCode: 401, RequestID: "req-1", Code: "INVALID_SECRET", Message: "Invalid API secret"}, *apiErr)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	assert.NotErrorIs(t, err, ErrRateLimited)
+	assert.False(t, apiErr.Temporary())
+	assert.EqualError(t, err, "failed to trigger workflow: API error: Invalid API secret (status code: 401, code: INVALID_SECRET, request ID: req-1)")
+
+	_, err = i.Workflows.Trigger("reports", "exec-1", map[string]interface{}{})
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "slow down", apiErr.Message)
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.True(t, apiErr.Temporary())
+
+	// A success status other than the expected one is reported with its status
+	_, err = i.Workflows.Trigger("shipping", "exec-1", map[string]interface{}{})
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 200, apiErr.StatusCode)
+
+	_, err = i.Workflows.Executions.Children("exec-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
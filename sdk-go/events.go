@@ -0,0 +1,203 @@
+package inferable
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Types of the events published by the client, see Inferable.Events.
+const (
+	// EventPoll is published for each successful poll, with the number of calls received.
+	EventPoll = "poll"
+	// EventRegistration is published when the machine registers its tools with the cluster.
+	EventRegistration = "registration"
+	// EventToolCallStarted and EventToolCallFinished bracket each call of a tool.
+	EventToolCallStarted  = "toolCallStarted"
+	EventToolCallFinished = "toolCallFinished"
+	// EventHandlerStarted and EventHandlerFinished bracket each execution of a workflow handler.
+	EventHandlerStarted  = "handlerStarted"
+	EventHandlerFinished = "handlerFinished"
+	// EventStep is published when a workflow handler starts computing a ctx.Memo step, e.g. an
+	// LLM call or a blueprint step, whose result is not cached yet.
+	EventStep = "step"
+	// EventLog is published for each ctx.Log of a workflow handler, with the status as Message.
+	EventLog = "log"
+	// EventAgentRun is published when an agent run of a workflow handler returns, with the run's
+	// status, e.g. "done" or "running", as Message.
+	EventAgentRun = "agentRun"
+	// EventRetry is published before an operation is attempted again, e.g. a failed poll or a
+	// structured LLM call whose response did not match the schema.
+	EventRetry = "retry"
+	// EventError is published for errors the client handles without returning them, e.g. a failed
+	// poll.
+	EventError = "error"
+)
+
+// DefaultEventBuffer is the number of events buffered for a subscriber.
+const DefaultEventBuffer = 256
+
+// Event describes activity of the client. Fields that do not apply to an event's type are zero.
+type Event struct {
+	Type string
+	Time time.Time
+	// Tool and CallID identify a tool call.
+	Tool   string
+	CallID string
+	// Workflow, Version and ExecutionID identify a workflow handler execution.
+	Workflow    string
+	Version     int
+	ExecutionID string
+	// Step names the ctx.Memo step of an EventStep, and Agent the agent of an EventAgentRun.
+	Step  string
+	Agent string
+	// Message is the status of an EventLog or EventAgentRun, or the message of the interrupt a
+	// handler finished with.
+	Message string
+	// Calls is the number of calls received by a poll.
+	Calls int
+	// ResultType is "resolution", "rejection" or "interrupt" for finished calls and handlers.
+	ResultType string
+	// Duration is how long a finished call or handler ran for.
+	Duration time.Duration
+	// Operation names the operation that failed or is retried, e.g. "poll", and Attempt is the
+	// number of a retry's next attempt.
+	Operation string
+	Attempt   int
+	// Err is the error of an EventError, the reason for an EventRetry, or the error returned by a
+	// finished handler.
+	Err error
+}
+
+// EventOptions configures Inferable.Events.
+type EventOptions struct {
+	// Buffer is the number of events held for the subscriber. Events published while the buffer is
+	// full are dropped rather than blocking the client. Defaults to DefaultEventBuffer.
+	Buffer int
+	// Types limits the subscription to events of these types. Defaults to all events.
+	Types []string
+}
+
+// EventSubscription receives the client's events on C until it is closed.
+type EventSubscription struct {
+	C       <-chan Event
+	ch      chan Event
+	types   map[string]bool
+	bus     *eventBus
+	dropped atomic.Int64
+}
+
+// Close stops the subscription and closes C.
+func (s *EventSubscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// Dropped returns the number of events dropped because the subscriber fell behind.
+func (s *EventSubscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// eventBus fans out the client's events to its subscribers.
+type eventBus struct {
+	mu          sync.RWMutex
+	subscribers map[*EventSubscription]bool
+	// metrics records the events, if set
+	metrics *MetricsRegistry
+}
+
+func newEventBus(metrics *MetricsRegistry) *eventBus {
+	return &eventBus{subscribers: map[*EventSubscription]bool{}, metrics: metrics}
+}
+
+func (b *eventBus) subscribe(options EventOptions) *EventSubscription {
+	if options.Buffer <= 0 {
+		options.Buffer = DefaultEventBuffer
+	}
+	ch := make(chan Event, options.Buffer)
+	subscription := &EventSubscription{C: ch, ch: ch, bus: b}
+	if len(options.Types) > 0 {
+		subscription.types = map[string]bool{}
+		for _, typ := range options.Types {
+			subscription.types[typ] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[subscription] = true
+	return subscription
+}
+
+func (b *eventBus) unsubscribe(subscription *EventSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers[subscription] {
+		delete(b.subscribers, subscription)
+		close(subscription.ch)
+	}
+}
+
+// publish delivers an event to the subscribers without blocking.
+func (b *eventBus) publish(event Event) {
+	if b == nil {
+		return
+	}
+	event.Time = time.Now()
+	if b.metrics != nil {
+		b.metrics.record(event)
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for subscription := range b.subscribers {
+		if subscription.types != nil && !subscription.types[event.Type] {
+			continue
+		}
+		select {
+		case subscription.ch <- event:
+		default:
+			subscription.dropped.Add(1)
+		}
+	}
+}
+
+// Events subscribes to the client's activity: polls, registrations, tool calls, workflow handler
+// executions and their steps, logs and agent runs, retries and handled errors. Use it for custom observability, admission control, or
+// assertions in tests. Subscribers that fall behind miss events rather than slowing the client.
+//
+//	events := client.Events(inferable.EventOptions{Types: []string{inferable.EventToolCallFinished}})
+//	defer events.Close()
+//
+//	for event := range events.C {
+//		metrics.Observe(event.Tool, event.Duration)
+//	}
+func (i *Inferable) Events(options ...EventOptions) *EventSubscription {
+	merged := EventOptions{}
+	for _, option := range options {
+		if option.Buffer > 0 {
+			merged.Buffer = option.Buffer
+		}
+		merged.Types = append(merged.Types, option.Types...)
+	}
+	return i.events.subscribe(merged)
+}
+
+// interruptMessage returns the message of the interrupt a workflow handler finished with.
+func interruptMessage(results []reflect.Value) string {
+	if interrupt, ok := results[0].Interface().(*Interrupt); ok && interrupt != nil {
+		return interrupt.Message
+	}
+	return ""
+}
+
+// handlerResultType classifies the results of a workflow handler like those of a tool call.
+func handlerResultType(results []reflect.Value) (string, error) {
+	if err, ok := results[1].Interface().(error); ok && err != nil {
+		return "rejection", err
+	}
+	if interrupt, ok := results[0].Interface().(*Interrupt); ok && interrupt != nil {
+		return "interrupt", nil
+	}
+	return "resolution", nil
+}
@@ -0,0 +1,73 @@
+package inferable
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventTypes drains the events published so far.
+func eventTypes(subscription *EventSubscription) []string {
+	types := []string{}
+	for {
+		select {
+		case event := <-subscription.C:
+			types = append(types, event.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	i, _ := newResultRecorder(t, InferableOptions{KVStore: NewMemoryStore()})
+	all := i.Events()
+	finished := i.Events(EventOptions{Types: []string{EventToolCallFinished, EventHandlerFinished}})
+
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "charge",
+		Func: func(input chargeInput, ctx ContextInput) (interface{}, error) {
+			return nil, fmt.Errorf("card declined")
+		},
+	}))
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-1", Function: "charge", Input: map[string]interface{}{"amount": 100}}))
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return ApprovalInterrupt("Ship order?"), nil
+	})
+	_, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{EventToolCallStarted, EventToolCallFinished, EventHandlerStarted, EventHandlerFinished}, eventTypes(all))
+
+	call := <-finished.C
+	assert.Equal(t, "charge", call.Tool)
+	assert.Equal(t, "job-1", call.CallID)
+	assert.Equal(t, "rejection", call.ResultType)
+
+	handler := <-finished.C
+	assert.Equal(t, "orders", handler.Workflow)
+	assert.Equal(t, 1, handler.Version)
+	assert.Equal(t, "exec-1", handler.ExecutionID)
+	assert.Equal(t, "interrupt", handler.ResultType)
+
+	// Closed subscriptions receive nothing
+	finished.Close()
+	_, open := <-finished.C
+	assert.False(t, open)
+}
+
+func TestEventsDropWhenSubscriberFallsBehind(t *testing.T) {
+	bus := newEventBus(nil)
+	subscription := bus.subscribe(EventOptions{Buffer: 1})
+
+	for n := 0; n < 3; n++ {
+		bus.publish(Event{Type: EventPoll, Calls: n})
+	}
+
+	assert.Equal(t, 0, (<-subscription.C).Calls)
+	assert.Equal(t, int64(2), subscription.Dropped())
+}
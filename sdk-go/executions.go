@@ -0,0 +1,413 @@
+package inferable
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+// Execution statuses reported by the cluster.
+const (
+	ExecutionPending     = "pending"
+	ExecutionRunning     = "running"
+	ExecutionSuccess     = "success"
+	ExecutionFailure     = "failure"
+	ExecutionStalled     = "stalled"
+	ExecutionInterrupted = "interrupted"
+)
+
+// WorkflowExecution is the state of a workflow execution in the cluster.
+type WorkflowExecution struct {
+	ID           string
+	WorkflowName string
+	Version      int
+	// Status is one of the Execution* statuses.
+	Status string
+	// ResultType is "resolution", "rejection", or "interrupt" once the handler has returned.
+	ResultType string
+	// Result is the decoded value returned by the handler, or the error message for a rejection.
+	Result            interface{}
+	ApprovalRequested bool
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+// Done reports whether the execution has finished: the handler resolved or was rejected,
+// rather than being interrupted or still pending.
+func (e *WorkflowExecution) Done() bool {
+	return (e.Status == ExecutionSuccess && e.ResultType != "interrupt") || e.Status == ExecutionFailure
+}
+
+// Failed reports whether the execution finished with an error.
+func (e *WorkflowExecution) Failed() bool {
+	return e.Status == ExecutionFailure || (e.Status == ExecutionSuccess && e.ResultType == "rejection")
+}
+
+// Interrupted reports whether the execution is paused on an interrupt, e.g. waiting for approval.
+func (e *WorkflowExecution) Interrupted() bool {
+	return e.Status == ExecutionInterrupted || e.ResultType == "interrupt"
+}
+
+// GetExecution returns the current state of a workflow execution.
+func (w *Workflows) GetExecution(workflowName string, executionId string) (*WorkflowExecution, error) {
+	return w.getExecution(context.Background(), workflowName, executionId)
+}
+
+func (w *Workflows) getExecution(ctx context.Context, workflowName string, executionId string) (*WorkflowExecution, error) {
+	clusterId, err := w.inferable.getClusterId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster id: %w", err)
+	}
+
+	query := map[string]string{
+		"workflowExecutionId": executionId,
+		"limit":               "10",
+	}
+	// Without a workflow name, the execution is looked up by its ID alone
+	if workflowName != "" {
+		query["workflowName"] = workflowName
+	}
+	result, _, err, status := w.inferable.fetchData(client.FetchDataOptions{
+		Path:        fmt.Sprintf("/clusters/%s/workflow-executions", clusterId),
+		Method:      "GET",
+		QueryParams: query,
+		Context:     ctx,
+		Idempotent:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow execution: %w", err)
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("failed to get workflow execution: %w", client.UnexpectedStatus(status))
+	}
+
+	var response []struct {
+		Execution struct {
+			ID              string    `json:"id"`
+			WorkflowName    string    `json:"workflowName"`
+			WorkflowVersion int       `json:"workflowVersion"`
+			CreatedAt       time.Time `json:"createdAt"`
+			UpdatedAt       time.Time `json:"updatedAt"`
+		} `json:"execution"`
+		Job struct {
+			Status            *string `json:"status"`
+			Result            *string `json:"result"`
+			ResultType        *string `json:"resultType"`
+			ApprovalRequested *bool   `json:"approvalRequested"`
+		} `json:"job"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workflow execution: %v", err)
+	}
+
+	for _, item := range response {
+		if item.Execution.ID != executionId {
+			continue
+		}
+
+		execution := &WorkflowExecution{
+			ID:           item.Execution.ID,
+			WorkflowName: item.Execution.WorkflowName,
+			Version:      item.Execution.WorkflowVersion,
+			Status:       ExecutionPending,
+			CreatedAt:    item.Execution.CreatedAt,
+			UpdatedAt:    item.Execution.UpdatedAt,
+		}
+		if item.Job.Status != nil {
+			execution.Status = *item.Job.Status
+		}
+		if item.Job.ResultType != nil {
+			execution.ResultType = *item.Job.ResultType
+		}
+		if item.Job.ApprovalRequested != nil {
+			execution.ApprovalRequested = *item.Job.ApprovalRequested
+		}
+		if item.Job.Result != nil {
+			// Results are stored serialized; keep the raw string if it is not JSON
+			if err := json.Unmarshal([]byte(*item.Job.Result), &execution.Result); err != nil {
+				execution.Result = *item.Job.Result
+			}
+		}
+		// Results above the workflow's ArtifactThreshold are resolved from their artifact
+		if execution.ResultType == "resolution" {
+			if execution.Result, err = w.inferable.Artifacts.rehydrate(execution.Result); err != nil {
+				return nil, fmt.Errorf("failed to get workflow execution result: %v", err)
+			}
+		}
+
+		return execution, nil
+	}
+
+	return nil, fmt.Errorf("workflow execution %s %w", executionId, errExecutionNotFound)
+}
+
+// errExecutionNotFound is returned by getExecution for executions the cluster does not list.
+var errExecutionNotFound = errors.New("not found")
+
+// executionPollInterval is the interval at which Execution.WaitForResult checks the execution.
+var executionPollInterval = time.Second
+
+// eventPollInterval is the interval at which a WaitEvents wait checks the execution after its
+// handler finished, until the result reported by the handler is visible.
+var eventPollInterval = 100 * time.Millisecond
+
+// Strategies for waiting on an execution, see WaitOptions.
+const (
+	// WaitPoll checks the execution every PollInterval.
+	WaitPoll = "poll"
+	// WaitEvents checks the execution as soon as a handler of this client finishes it, e.g. in
+	// tests or services that run their own workflows, and every PollInterval otherwise.
+	WaitEvents = "events"
+)
+
+// WaitOptions configures Workflows.TriggerAndWait.
+type WaitOptions struct {
+	// Timeout bounds the wait. Zero waits until the execution is done.
+	Timeout time.Duration
+	// PollInterval is the interval at which the execution is checked. Defaults to one second.
+	PollInterval time.Duration
+	// Strategy is WaitPoll (the default) or WaitEvents.
+	Strategy string
+	// TriggerOptions are the options the execution is triggered with.
+	TriggerOptions TriggerOptions
+}
+
+// TriggerAndWait triggers a workflow execution, like Trigger, and waits until it is done, like
+// Execution.WaitForResult. It returns the execution's result, or an error with the handler's
+// error message if it failed.
+//
+//	result, err := client.Workflows.TriggerAndWait("refunds", executionId, input, inferable.WaitOptions{
+//		Timeout: 5 * time.Minute,
+//	})
+func (w *Workflows) TriggerAndWait(workflowName string, executionId string, input interface{}, options ...WaitOptions) (interface{}, error) {
+	merged := WaitOptions{}
+	for _, option := range options {
+		merged = option
+	}
+	if merged.PollInterval <= 0 {
+		merged.PollInterval = executionPollInterval
+	}
+
+	var finished <-chan struct{}
+	switch merged.Strategy {
+	case "", WaitPoll:
+	case WaitEvents:
+		// Subscribed before the trigger, so that a quick handler is not missed
+		subscription := w.inferable.Events(EventOptions{Types: []string{EventHandlerFinished}})
+		defer subscription.Close()
+		finished = executionFinished(subscription, executionId)
+	default:
+		return nil, fmt.Errorf("unknown wait strategy %q, use WaitPoll or WaitEvents", merged.Strategy)
+	}
+
+	execution, err := w.Trigger(workflowName, executionId, input, merged.TriggerOptions)
+	if err != nil {
+		return nil, err
+	}
+	return execution.wait(context.Background(), merged.Timeout, merged.PollInterval, finished)
+}
+
+// executionFinished signals the handler finished events of an execution.
+func executionFinished(subscription *EventSubscription, executionId string) <-chan struct{} {
+	finished := make(chan struct{}, 1)
+	go func() {
+		for event := range subscription.C {
+			if event.ExecutionID != executionId {
+				continue
+			}
+			select {
+			case finished <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return finished
+}
+
+// Execution is a handle on a triggered workflow execution, returned by Workflows.Trigger.
+type Execution struct {
+	workflows *Workflows
+	// workflowName is the name the cluster knows the workflow by, i.e. the partition's name for a
+	// partitioned workflow.
+	workflowName string
+	id           string
+}
+
+// ID returns the execution's ID.
+func (e *Execution) ID() string {
+	return e.id
+}
+
+// Status returns the current state of the execution, like Workflows.GetExecution.
+func (e *Execution) Status(ctx context.Context) (*WorkflowExecution, error) {
+	return e.workflows.getExecution(ctx, e.workflowName, e.id)
+}
+
+// WaitForResult waits until the execution is done and returns its result. It returns an error
+// if the execution failed, with the handler's error message, or if it is not done within timeout
+// or before ctx is done. Interrupted executions are waited for until they are resumed. A timeout
+// of zero waits as long as ctx allows. Use WaitForResultAs for a typed result.
+func (e *Execution) WaitForResult(ctx context.Context, timeout time.Duration) (interface{}, error) {
+	return e.wait(ctx, timeout, executionPollInterval, nil)
+}
+
+// wait checks the execution every interval until it is done, and as soon as finished is signalled.
+func (e *Execution) wait(ctx context.Context, timeout time.Duration, interval time.Duration, finished <-chan struct{}) (interface{}, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	for {
+		execution, err := e.Status(ctx)
+		// The execution may not be listed yet right after the trigger
+		if err != nil && !errors.Is(err, errExecutionNotFound) {
+			if ctx.Err() != nil {
+				return nil, e.waitError(ctx, timeout)
+			}
+			return nil, err
+		}
+		if err == nil && execution.Failed() {
+			return nil, fmt.Errorf("workflow execution %s failed: %v", e.id, execution.Result)
+		}
+		if err == nil && execution.Done() {
+			return execution.Result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, e.waitError(ctx, timeout)
+		case <-finished:
+			// The handler reports its result right after it finished
+			interval = eventPollInterval
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (e *Execution) waitError(ctx context.Context, timeout time.Duration) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) && timeout > 0 {
+		return fmt.Errorf("workflow execution %s did not finish within %s: %w", e.id, timeout, ctx.Err())
+	}
+	return fmt.Errorf("stopped waiting for workflow execution %s: %w", e.id, ctx.Err())
+}
+
+// WorkflowExecutions lists and cancels workflow executions. It is exposed as
+// client.Workflows.Executions.
+type WorkflowExecutions struct {
+	inferable *Inferable
+}
+
+// ExecutionChildren is the work started by a workflow execution: the executions triggered with
+// it as their TriggerOptions.ParentExecutionID, and its agent runs.
+type ExecutionChildren struct {
+	Executions []ChildExecution
+	Runs       []ChildRun
+}
+
+// ChildExecution is a workflow execution started by another execution.
+type ChildExecution struct {
+	ID           string    `json:"id"`
+	WorkflowName string    `json:"workflowName"`
+	Version      int       `json:"workflowVersion"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// ChildRun is an agent run started by a workflow execution.
+type ChildRun struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// CancelOptions configures WorkflowExecutions.Cancel.
+type CancelOptions struct {
+	// Cascade also fails the execution's unfinished agent runs and cancels its child executions,
+	// recursively, so an aborted pipeline is cleaned up with one call.
+	Cascade bool
+	// CancelledBy identifies who cancelled the execution, e.g. a user or service, for the
+	// workflow's OnCancel callback.
+	CancelledBy string
+	// Reason tells the OnCancel callback why the execution was cancelled.
+	Reason string
+}
+
+// Children returns the executions and agent runs started by an execution.
+func (e *WorkflowExecutions) Children(executionId string) (*ExecutionChildren, error) {
+	clusterId, err := e.inferable.getClusterId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster id: %w", err)
+	}
+
+	result, _, err, status := e.inferable.fetchData(client.FetchDataOptions{
+		Path:       fmt.Sprintf("/clusters/%s/workflow-executions/%s/children", clusterId, executionId),
+		Method:     "GET",
+		Idempotent: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list execution children: %w", err)
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("failed to list execution children: %w", client.UnexpectedStatus(status))
+	}
+
+	children := &ExecutionChildren{}
+	if err := json.Unmarshal(result, children); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal execution children: %v", err)
+	}
+	return children, nil
+}
+
+// Cancel cancels an execution that has not finished. The handler's job is rejected, so the
+// execution fails, and an interrupted execution is not resumed. The cancellation is recorded in
+// the client's key-value store first, for the workflow's OnCancel callback to clean up after it.
+//
+//	err := client.Workflows.Executions.Cancel(executionId, inferable.CancelOptions{
+//		CancelledBy: userId,
+//		Reason:      "customer withdrew the order",
+//	})
+func (e *WorkflowExecutions) Cancel(executionId string, options ...CancelOptions) error {
+	clusterId, err := e.inferable.getClusterId()
+	if err != nil {
+		return fmt.Errorf("failed to get cluster id: %w", err)
+	}
+
+	merged := CancelOptions{}
+	for _, option := range options {
+		merged.Cascade = merged.Cascade || option.Cascade
+		if option.CancelledBy != "" {
+			merged.CancelledBy = option.CancelledBy
+		}
+		if option.Reason != "" {
+			merged.Reason = option.Reason
+		}
+	}
+	if err := e.recordCancellation(executionId, merged); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"cascade": merged.Cascade})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cancel options: %v", err)
+	}
+
+	_, _, err, status := e.inferable.fetchData(client.FetchDataOptions{
+		Path:   fmt.Sprintf("/clusters/%s/workflow-executions/%s/cancel", clusterId, executionId),
+		Method: "POST",
+		Body:   string(body),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel workflow execution: %w", err)
+	}
+	if status != 204 {
+		return fmt.Errorf("failed to cancel workflow execution: %w", client.UnexpectedStatus(status))
+	}
+	return nil
+}
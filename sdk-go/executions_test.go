@@ -0,0 +1,220 @@
+package inferable
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutionChildrenAndCancel(t *testing.T) {
+	var cancelled map[string]interface{}
+	var parent string
+	store := NewMemoryStore()
+	i := newTestClient(t, InferableOptions{KVStore: store}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/shipping/executions": func(w http.ResponseWriter, r *http.Request) {
+			parent = r.URL.Query().Get("parentExecutionId")
+			w.WriteHeader(http.StatusCreated)
+		},
+		"/clusters/test-cluster/workflow-executions/exec-1/children": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"executions": []map[string]interface{}{
+					{"id": "exec-2", "workflowName": "shipping", "workflowVersion": 1, "status": "running", "createdAt": "2024-01-01T00:00:00Z"},
+				},
+				"runs": []map[string]interface{}{
+					{"id": "run-1", "name": "orders_triage", "status": nil, "createdAt": "2024-01-01T00:00:00Z"},
+				},
+			})
+		},
+		"/clusters/test-cluster/workflow-executions/exec-2/children": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"executions": []interface{}{}, "runs": []interface{}{}})
+		},
+		"/clusters/test-cluster/workflow-executions": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{{"execution": map[string]interface{}{"id": "exec-1", "workflowName": "orders"}, "job": map[string]interface{}{"status": "interrupted"}}})
+		},
+		"/clusters/test-cluster/workflow-executions/exec-1/cancel": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&cancelled)
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+
+	_, err := i.Workflows.Trigger("shipping", "exec-2", map[string]interface{}{}, TriggerOptions{ParentExecutionID: "exec-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "exec-1", parent)
+
+	children, err := i.Workflows.Executions.Children("exec-1")
+	require.NoError(t, err)
+	require.Len(t, children.Executions, 1)
+	assert.Equal(t, "exec-2", children.Executions[0].ID)
+	assert.Equal(t, 1, children.Executions[0].Version)
+	require.Len(t, children.Runs, 1)
+	assert.Equal(t, "run-1", children.Runs[0].ID)
+	assert.Equal(t, "", children.Runs[0].Status)
+
+	require.NoError(t, i.Workflows.Executions.Cancel("exec-1", CancelOptions{Cascade: true, CancelledBy: "ops", Reason: "order withdrawn"}))
+	assert.Equal(t, map[string]interface{}{"cascade": true}, cancelled)
+
+	// The cancellation is recorded for the workflows' OnCancel callbacks, cascading to the children
+	recorded, ok, err := store.Get(cancellationKey("shipping", "exec-2"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	record := cancellationRecord{}
+	require.NoError(t, json.Unmarshal([]byte(recorded), &record))
+	assert.Equal(t, "ops", record.CancelledBy)
+	assert.Equal(t, "order withdrawn", record.Reason)
+	assert.Equal(t, "exec-1", record.CascadedFrom)
+	_, ok, err = store.Get(cancellationKey("orders", "exec-1"))
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	_, err = i.Workflows.Executions.Children("exec-3")
+	assert.ErrorContains(t, err, "failed to list execution children")
+}
+
+func TestTriggerWithStructInput(t *testing.T) {
+	var bodies []map[string]interface{}
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/orders/executions": func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			bodies = append(bodies, body)
+			w.WriteHeader(http.StatusCreated)
+		},
+	})
+
+	type orderInput struct {
+		ExecutionId string `json:"executionId"`
+		OrderID     string `json:"orderId"`
+	}
+	_, err := i.Workflows.Trigger("orders", "exec-1", orderInput{OrderID: "42"})
+	require.NoError(t, err)
+	_, err = i.Workflows.Trigger("orders", "exec-2", &orderInput{OrderID: "43"})
+	require.NoError(t, err)
+
+	input := map[string]interface{}{"orderId": "44"}
+	_, err = i.Workflows.Trigger("orders", "exec-3", input)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"orderId": "44"}, input)
+
+	require.Len(t, bodies, 3)
+	assert.Equal(t, map[string]interface{}{"executionId": "exec-1", "orderId": "42"}, bodies[0])
+	assert.Equal(t, map[string]interface{}{"executionId": "exec-2", "orderId": "43"}, bodies[1])
+	assert.Equal(t, map[string]interface{}{"executionId": "exec-3", "orderId": "44"}, bodies[2])
+
+	_, err = i.Workflows.Trigger("orders", "exec-4", []string{"42"})
+	assert.ErrorContains(t, err, "input must be a map or a struct")
+}
+
+func TestExecutionWaitForResult(t *testing.T) {
+	executionPollInterval = 10 * time.Millisecond
+	defer func() { executionPollInterval = time.Second }()
+
+	var polls atomic.Int64
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/": func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		},
+		"/clusters/test-cluster/workflow-executions": func(w http.ResponseWriter, r *http.Request) {
+			executionId := r.URL.Query().Get("workflowExecutionId")
+			job := map[string]interface{}{}
+			switch n := polls.Add(1); {
+			case executionId == "exec-failed":
+				job = map[string]interface{}{"status": ExecutionSuccess, "resultType": "rejection", "result": `"out of stock"`}
+			case executionId == "exec-stuck" || n == 1:
+				// Not listed yet
+				_ = json.NewEncoder(w).Encode([]interface{}{})
+				return
+			case n == 2:
+				job = map[string]interface{}{"status": ExecutionRunning}
+			default:
+				job = map[string]interface{}{"status": ExecutionSuccess, "resultType": "resolution", "result": `{"shipped":true}`}
+			}
+			_ = json.NewEncoder(w).Encode([]interface{}{map[string]interface{}{
+				"execution": map[string]interface{}{"id": executionId, "workflowName": "orders"},
+				"job":       job,
+			}})
+		},
+	})
+
+	execution, err := i.Workflows.Trigger("orders", "exec-1", map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "exec-1", execution.ID())
+
+	result, err := execution.WaitForResult(context.Background(), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"shipped": true}, result)
+	assert.Equal(t, int64(3), polls.Load())
+
+	type shipment struct {
+		Shipped bool `json:"shipped"`
+	}
+	typed, err := WaitForResultAs[shipment](context.Background(), execution, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, shipment{Shipped: true}, typed)
+
+	status, err := execution.Status(context.Background())
+	require.NoError(t, err)
+	assert.True(t, status.Done())
+
+	failed, err := i.Workflows.Trigger("orders", "exec-failed", map[string]interface{}{})
+	require.NoError(t, err)
+	_, err = failed.WaitForResult(context.Background(), time.Second)
+	assert.EqualError(t, err, "workflow execution exec-failed failed: out of stock")
+
+	stuck, err := i.Workflows.Trigger("orders", "exec-stuck", map[string]interface{}{})
+	require.NoError(t, err)
+	_, err = stuck.WaitForResult(context.Background(), 50*time.Millisecond)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorContains(t, err, "did not finish within 50ms")
+}
+
+func TestTriggerAndWait(t *testing.T) {
+	var done atomic.Bool
+	triggered := make(chan string, 2)
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/": func(w http.ResponseWriter, r *http.Request) {
+			var input map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&input)
+			triggered <- input["executionId"].(string)
+			w.WriteHeader(http.StatusCreated)
+		},
+		"/clusters/test-cluster/workflow-executions": func(w http.ResponseWriter, r *http.Request) {
+			job := map[string]interface{}{"status": ExecutionRunning}
+			if done.Load() {
+				job = map[string]interface{}{"status": ExecutionSuccess, "resultType": "resolution", "result": `{"total":3}`}
+			}
+			_ = json.NewEncoder(w).Encode([]interface{}{map[string]interface{}{
+				"execution": map[string]interface{}{"id": r.URL.Query().Get("workflowExecutionId"), "workflowName": "invoices"},
+				"job":       job,
+			}})
+		},
+	})
+
+	// The handler of this client finishing the execution ends the wait before the next poll
+	go func() {
+		executionId := <-triggered
+		done.Store(true)
+		i.events.publish(Event{Type: EventHandlerFinished, ExecutionID: executionId})
+	}()
+	started := time.Now()
+	result, err := i.Workflows.TriggerAndWait("invoices", "exec-1", map[string]interface{}{}, WaitOptions{
+		Timeout:      5 * time.Second,
+		PollInterval: time.Hour,
+		Strategy:     WaitEvents,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"total": float64(3)}, result)
+	assert.Less(t, time.Since(started), time.Second)
+
+	done.Store(false)
+	_, err = i.Workflows.TriggerAndWait("invoices", "exec-2", map[string]interface{}{}, WaitOptions{Timeout: 50 * time.Millisecond, PollInterval: 10 * time.Millisecond})
+	assert.ErrorContains(t, err, "workflow execution exec-2 did not finish within 50ms")
+
+	_, err = i.Workflows.TriggerAndWait("invoices", "exec-3", map[string]interface{}{}, WaitOptions{Strategy: "push"})
+	assert.ErrorContains(t, err, "unknown wait strategy")
+}
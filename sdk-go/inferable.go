@@ -2,10 +2,14 @@
 package inferable
 
 import (
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/inferablehq/inferable/sdk-go/internal/client"
 	"github.com/inferablehq/inferable/sdk-go/internal/util"
@@ -17,6 +21,12 @@ const Version = "0.1.44"
 const (
 	// DefaultAPIEndpoint is the default endpoint for the Inferable API.
 	DefaultAPIEndpoint = "https://api.inferable.ai"
+	// DefaultModel is the model used for structured LLM calls when none is configured.
+	// Without a custom Provider, the model must be one the default provider routes, such as
+	// "claude-3-5-sonnet" or "claude-3-haiku"; other models are rejected.
+	DefaultModel = "claude-3-5-sonnet"
+	// DefaultValidationAttempts is the number of attempts made when a structured response fails schema validation.
+	DefaultValidationAttempts = 3
 )
 
 // Inferable is the main client for interacting with the Inferable platform.
@@ -28,10 +38,61 @@ type Inferable struct {
 	apiSecret   string
 	machineID   string
 	clusterID   string
+	// clusterMu guards the lazy registration of clusterID, which pollers and workflows race for.
+	clusterMu sync.Mutex
+	// defaultModel is used by LLM and agent calls unless a workflow or call overrides it.
+	defaultModel string
+	// providerResolver derives the provider for LLM and agent calls unless a workflow overrides it.
+	providerResolver ProviderResolver
+	// modelPolicy restricts LLM and agent calls unless a workflow overrides it.
+	modelPolicy ModelPolicyResolver
+	// semanticCache is used by LLM calls unless a workflow overrides it.
+	semanticCache *SemanticCache
+	// tokenizer counts prompt tokens for pre-flight context window checks.
+	tokenizer Tokenizer
+	// llmFactory and agentRunnerFactory replace the execution backends unless a workflow overrides them.
+	llmFactory         LLMFactory
+	agentRunnerFactory AgentRunnerFactory
+	// kvStore backs ctx.Memo unless a workflow overrides it; nil uses the cluster.
+	kvStore KVStore
+	// artifactStore holds client.Artifacts; nil uses the cluster.
+	artifactStore ArtifactStore
+	// clock tells the time for ctx.Now and ctx.Sleep.
+	clock Clock
+	// chaos injects faults when chaos mode is enabled; nil otherwise.
+	chaos *chaos
+	// skew tracks the offset of the local clock from the control plane.
+	skew *skewTracker
+	// events publishes the client's activity to Events subscribers.
+	events *eventBus
+	// metrics records the client's metrics.
+	metrics *MetricsRegistry
+	// offline runs workflows without registering with a cluster.
+	offline bool
+	// logger receives the client's diagnostics; nil uses the standard logger.
+	logger Logger
+	// shutdown holds the hooks run when the machine shuts down, within shutdownTimeout.
+	shutdown        shutdownHooks
+	shutdownTimeout time.Duration
+	// drainTimeout bounds how long Unlisten waits for the calls in flight.
+	drainTimeout time.Duration
+	// triggers enforces the TriggerQuota; nil does not limit triggers.
+	triggers *triggerLimiter
+	// approvalResolver resolves approval interrupts on the machine; nil leaves them to the cluster.
+	approvalResolver ApprovalResolver
+	// budget counts the spend of LLM and agent calls; nil does not limit it
+	budget *tokenBudgeter
+	// messages and localeResolver render the messages of WorkflowContext.Message and Message
+	messages       Messages
+	localeResolver LocaleResolver
 	// Tools provides access to tool registration and management.
 	Tools *pollingAgent
 	// Workflows provides access to workflow creation and management.
 	Workflows *Workflows
+	// KV provides access to the key-value store backing ctx.Memo.
+	KV *KV
+	// Artifacts stores large outputs outside of workflow payloads.
+	Artifacts *Artifacts
 	// Convenience reference to a service with the name 'default'.
 	//
 	// Returns:
@@ -64,8 +125,97 @@ type InferableOptions struct {
 	APIEndpoint string
 	APISecret   string
 	MachineID   string
+	// DefaultModel is used by ctx.LLM and agents unless a workflow or call specifies a model.
+	DefaultModel string
+	// ProviderResolver derives the provider URL and key for ctx.LLM and agent calls from the
+	// execution's context, so each tenant's own provider key can be used.
+	ProviderResolver ProviderResolver
+	// ModelPolicy, when set, derives the ModelPolicy that routes and restricts ctx.LLM and agent
+	// calls from the execution's context, e.g. for data residency constraints per tenant.
+	ModelPolicy ModelPolicyResolver
+	// SemanticCache, when set, reuses ctx.LLM.Structured results for near-duplicate inputs.
+	SemanticCache *SemanticCache
+	// Tokenizer counts prompt tokens for the pre-flight context window check made before LLM and
+	// agent calls, which fails the calls whose prompt does not fit. Without one, prompts are only
+	// estimated by CountTokens, and those estimated not to fit are logged as a warning and sent.
+	Tokenizer Tokenizer
+	// LLMFactory, when set, provides ctx.LLM for workflow executions instead of the cluster-backed client.
+	LLMFactory LLMFactory
+	// AgentRunnerFactory, when set, provides ctx.Agents for workflow executions instead of the
+	// cluster-backed runner.
+	AgentRunnerFactory AgentRunnerFactory
+	// KVStore, when set, stores ctx.Memo results and deduplicated tool results instead of the
+	// cluster, e.g. a MemoryStore in tests.
+	KVStore KVStore
+	// ArtifactStore, when set, holds client.Artifacts instead of the cluster, e.g. object storage
+	// or a MemoryArtifactStore in tests.
+	ArtifactStore ArtifactStore
+	// Clock tells the time for ctx.Now and ctx.Sleep. Defaults to the system clock, compensated
+	// for skew from the control plane.
+	Clock Clock
+	// ClockSkewThreshold is the clock difference to the control plane, measured on every poll, above
+	// which a warning is logged and the default Clock is compensated. Defaults to DefaultClockSkewThreshold.
+	ClockSkewThreshold time.Duration
+	// Metrics records the client's metrics, served by the registry's Handler. Defaults to the
+	// process-wide registry served by MetricsHandler.
+	Metrics *MetricsRegistry
+	// Logger receives the client's diagnostics, such as registrations, failed polls and clock
+	// skew, e.g. a NewSlogLogger. Defaults to the standard logger.
+	Logger Logger
+	// Chaos, when set, injects transient errors, delays, duplicate tool deliveries, and handler
+	// restarts. Use it only against local and test clusters.
+	Chaos *ChaosOptions
+	// Offline runs workflow executions without a cluster, e.g. with the local package. The machine
+	// never registers, Listen fails, and ctx.Log only writes to the workflow logger. It requires a
+	// KVStore, and an LLMFactory and AgentRunnerFactory for handlers that use ctx.LLM or ctx.Agents.
+	Offline bool
+	// ShutdownTimeout bounds how long the hooks registered with RegisterShutdownHook run for.
+	// Defaults to DefaultShutdownTimeout.
+	ShutdownTimeout time.Duration
+	// DrainTimeout bounds how long Unlisten and Shutdown wait for the calls this machine received
+	// to finish. Defaults to DefaultDrainTimeout.
+	DrainTimeout time.Duration
+	// TriggerQuota, when set, limits the executions this client triggers per minute, per workflow
+	// and per tenant.
+	TriggerQuota *TriggerQuota
+	// ApprovalResolver, when set, resolves approval interrupts on the machine, e.g. AutoApprove or
+	// PromptApproval while developing, instead of waiting for approval in the cluster.
+	ApprovalResolver ApprovalResolver
+	// Retry configures how requests to the cluster that failed transiently are retried. Defaults
+	// to DefaultRetryOptions.
+	Retry *RetryOptions
+	// TokenBudget, when set, limits the tokens spent by the ctx.LLM and agent calls of the
+	// client's workflows within a window, holding or interrupting calls over the limit.
+	TokenBudget *TokenBudget
+	// Messages holds the message templates rendered by WorkflowContext.Message and
+	// Inferable.Message, by locale and then by key.
+	Messages Messages
+	// Locale, when set, derives the locale messages are rendered in from the execution's or tool
+	// call's context, e.g. the tenant's locale. Defaults to DefaultLocale.
+	Locale LocaleResolver
+	// Proxy, when set, is the URL of the proxy requests to the cluster are sent through, e.g.
+	// "http://egress.internal:3128", instead of the proxies of HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+	Proxy string
+	// TLSConfig, when set, configures the TLS connections to the cluster, e.g. the RootCAs of a
+	// self-hosted control plane with an internal certificate, or a MinVersion.
+	TLSConfig *tls.Config
+	// CAFile, when set, is the path of a PEM bundle of certificate authorities to trust for the
+	// cluster, in addition to the system's or those of TLSConfig.RootCAs.
+	CAFile string
+	// HTTPClient, when set, sends the requests to the cluster, e.g. to reuse a connection pool or
+	// instrumentation of your own. Its Timeout must exceed the 20 second wait of a poll.
+	HTTPClient *http.Client
+	// Transport, when set, sends the requests to the cluster instead of the transport of
+	// HTTPClient, e.g. to route them through a corporate proxy or to trace them.
+	Transport http.RoundTripper
+	// Middleware wraps every request to the cluster, including each retry, in order: the first
+	// middleware is the outermost. It sees the faults injected by Chaos.
+	Middleware []Middleware
 }
 
+// OfflineClusterID is the cluster ID reported by an offline client.
+const OfflineClusterID = "offline"
+
 // Input object for onStatusChange functions
 // https://docs.inferable.ai/pages/runs#onstatuschange
 type OnStatusChangeInput struct {
@@ -85,24 +235,83 @@ func New(options InferableOptions) (*Inferable, error) {
 	if options.APIEndpoint == "" {
 		options.APIEndpoint = DefaultAPIEndpoint
 	}
+	chaos := newChaos(options.Chaos)
+
+	transport, err := clusterTransport(options)
+	if err != nil {
+		return nil, err
+	}
 	client, err := client.NewClient(client.ClientOptions{
-		Endpoint: options.APIEndpoint,
-		Secret:   options.APISecret,
+		Endpoint:   options.APIEndpoint,
+		Secret:     options.APISecret,
+		HTTPClient: options.HTTPClient,
+		Transport:  withMiddleware(chaos.transport(transport), options.Middleware),
+		Retry:      options.Retry.policy(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("error creating client: %v", err)
 	}
 
+	if options.Offline && options.KVStore == nil {
+		return nil, fmt.Errorf("offline mode requires a KVStore")
+	}
+
+	if options.ShutdownTimeout <= 0 {
+		options.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if options.DrainTimeout <= 0 {
+		options.DrainTimeout = DefaultDrainTimeout
+	}
+	if options.Metrics == nil {
+		options.Metrics = defaultMetrics
+	}
+
+	if options.ClockSkewThreshold <= 0 {
+		options.ClockSkewThreshold = DefaultClockSkewThreshold
+	}
+	skew := &skewTracker{threshold: options.ClockSkewThreshold, logger: options.Logger}
+
+	// A clock passed in, e.g. a test clock, is used as is
+	if options.Clock == nil {
+		options.Clock = skewCompensatedClock{Clock: systemClock{}, tracker: skew}
+	}
+
 	machineID := options.MachineID
 	if machineID == "" {
 		machineID = util.GenerateMachineID(8)
 	}
 
 	inferable := &Inferable{
-		client:      client,
-		apiEndpoint: options.APIEndpoint,
-		apiSecret:   options.APISecret,
-		machineID:   machineID,
+		client:             client,
+		apiEndpoint:        options.APIEndpoint,
+		apiSecret:          options.APISecret,
+		machineID:          machineID,
+		defaultModel:       options.DefaultModel,
+		providerResolver:   options.ProviderResolver,
+		modelPolicy:        options.ModelPolicy,
+		semanticCache:      options.SemanticCache,
+		tokenizer:          options.Tokenizer,
+		llmFactory:         options.LLMFactory,
+		agentRunnerFactory: options.AgentRunnerFactory,
+		kvStore:            options.KVStore,
+		artifactStore:      options.ArtifactStore,
+		clock:              options.Clock,
+		chaos:              chaos,
+		skew:               skew,
+		events:             newEventBus(options.Metrics),
+		metrics:            options.Metrics,
+		offline:            options.Offline,
+		logger:             options.Logger,
+		shutdownTimeout:    options.ShutdownTimeout,
+		drainTimeout:       options.DrainTimeout,
+		triggers:           newTriggerLimiter(options.TriggerQuota, options.Clock),
+		approvalResolver:   options.ApprovalResolver,
+		budget:             newTokenBudgeter(options.TokenBudget, options.Clock),
+		messages:           options.Messages,
+		localeResolver:     options.Locale,
+	}
+	if options.Offline {
+		inferable.clusterID = OfflineClusterID
 	}
 
 	// Automatically register the default service
@@ -113,8 +322,11 @@ func New(options InferableOptions) (*Inferable, error) {
 
 	// Initialize the Workflows field
 	inferable.Workflows = &Workflows{
-		inferable: inferable,
+		inferable:  inferable,
+		Executions: &WorkflowExecutions{inferable: inferable},
 	}
+	inferable.KV = &KV{inferable: inferable}
+	inferable.Artifacts = &Artifacts{inferable: inferable}
 
 	return inferable, nil
 }
@@ -167,11 +379,12 @@ func (i *Inferable) fetchData(options client.FetchDataOptions) ([]byte, http.Hea
 
 func (i *Inferable) serverOk() error {
 	data, _, err, _ := i.client.FetchData(client.FetchDataOptions{
-		Path:   "/live",
-		Method: "GET",
+		Path:       "/live",
+		Method:     "GET",
+		Idempotent: true,
 	})
 	if err != nil {
-		return fmt.Errorf("error fetching data from /live: %v", err)
+		return fmt.Errorf("error fetching data from /live: %w", err)
 	}
 
 	var response struct {
@@ -191,10 +404,13 @@ func (i *Inferable) serverOk() error {
 }
 
 func (i *Inferable) getClusterId() (string, error) {
+	i.clusterMu.Lock()
+	defer i.clusterMu.Unlock()
+
 	if i.clusterID == "" {
 		clusterId, err := i.registerMachine(nil)
 		if err != nil {
-			return "", fmt.Errorf("failed to register machine: %v", err)
+			return "", fmt.Errorf("failed to register machine: %w", err)
 		}
 
 		i.clusterID = clusterId
@@ -203,15 +419,26 @@ func (i *Inferable) getClusterId() (string, error) {
 	return i.clusterID, nil
 }
 
+// setClusterId records the cluster the machine registered with.
+func (i *Inferable) setClusterId(clusterId string) {
+	i.clusterMu.Lock()
+	defer i.clusterMu.Unlock()
+	i.clusterID = clusterId
+}
+
 func (i *Inferable) registerMachine(s *pollingAgent) (string, error) {
+	if i.offline {
+		return "", fmt.Errorf("cannot register an offline client with a cluster")
+	}
 
 	// Prepare the payload for registration
 	payload := struct {
 		Service string `json:"service,omitempty"`
 		Tools   []struct {
-			Name        string `json:"name"`
-			Description string `json:"description,omitempty"`
-			Schema      string `json:"schema,omitempty"`
+			Name        string                 `json:"name"`
+			Description string                 `json:"description,omitempty"`
+			Schema      string                 `json:"schema,omitempty"`
+			Config      map[string]interface{} `json:"config,omitempty"`
 		} `json:"tools,omitempty"`
 	}{}
 
@@ -229,13 +456,15 @@ func (i *Inferable) registerMachine(s *pollingAgent) (string, error) {
 			}
 
 			payload.Tools = append(payload.Tools, struct {
-				Name        string `json:"name"`
-				Description string `json:"description,omitempty"`
-				Schema      string `json:"schema,omitempty"`
+				Name        string                 `json:"name"`
+				Description string                 `json:"description,omitempty"`
+				Schema      string                 `json:"schema,omitempty"`
+				Config      map[string]interface{} `json:"config,omitempty"`
 			}{
 				Name:        fn.Name,
 				Description: fn.Description,
 				Schema:      string(schemaJSON),
+				Config:      registrationConfig(fn),
 			})
 		}
 	}
@@ -260,11 +489,13 @@ func (i *Inferable) registerMachine(s *pollingAgent) (string, error) {
 		Method:  "POST",
 		Headers: headers,
 		Body:    string(jsonPayload),
+		// Registration replaces the machine's tool definitions
+		Idempotent: true,
 	}
 
 	responseData, _, err, _ := i.fetchData(options)
 	if err != nil {
-		return "", fmt.Errorf("failed to register machine: %v", err)
+		return "", fmt.Errorf("failed to register machine: %w", err)
 	}
 
 	// Parse the response
@@ -277,5 +508,36 @@ func (i *Inferable) registerMachine(s *pollingAgent) (string, error) {
 		return "", fmt.Errorf("failed to parse registration response: %v", err)
 	}
 
+	i.events.publish(Event{Type: EventRegistration})
+	i.logInfo("Registered machine", map[string]interface{}{"machineId": i.machineID, "clusterId": response.ClusterId})
 	return response.ClusterId, nil
 }
+
+// store returns the client's KVStore, else the cluster's.
+func (i *Inferable) store() KVStore {
+	if i.kvStore != nil {
+		return i.kvStore
+	}
+	return &clusterKVStore{inferable: i}
+}
+
+// registrationTools returns the tools registered with the client and those the client's workflows
+// will register when they listen.
+func (i *Inferable) registrationTools() []Tool {
+	seen := map[string]bool{}
+	tools := []Tool{}
+	for _, tool := range i.Tools.Tools {
+		seen[tool.Name] = true
+		tools = append(tools, tool)
+	}
+	for _, workflow := range i.Workflows.created {
+		for _, tool := range workflow.clusterTools() {
+			if !seen[tool.Name] {
+				seen[tool.Name] = true
+				tools = append(tools, tool)
+			}
+		}
+	}
+	sort.Slice(tools, func(a, b int) bool { return tools[a].Name < tools[b].Name })
+	return tools
+}
@@ -1,9 +1,18 @@
 package inferable
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -20,6 +29,102 @@ func TestNew(t *testing.T) {
 	assert.NotEmpty(t, i.machineID)
 }
 
+// headerTransport adds a header to every request it sends, counting them.
+type headerTransport struct {
+	requests atomic.Int64
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Proxy-Authorization", "corp")
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestCustomHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Proxy-Authorization") != "corp" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"clusterId": "test-cluster"})
+	}))
+	defer server.Close()
+
+	t.Run("transport", func(t *testing.T) {
+		transport := &headerTransport{}
+		i, err := New(InferableOptions{APIEndpoint: server.URL, APISecret: "test-secret", Transport: transport})
+		require.NoError(t, err)
+
+		clusterId, err := i.getClusterId()
+		require.NoError(t, err)
+		assert.Equal(t, "test-cluster", clusterId)
+		assert.Equal(t, int64(1), transport.requests.Load())
+	})
+
+	t.Run("client", func(t *testing.T) {
+		transport := &headerTransport{}
+		httpClient := &http.Client{Transport: transport, Timeout: time.Minute}
+		i, err := New(InferableOptions{APIEndpoint: server.URL, APISecret: "test-secret", HTTPClient: httpClient})
+		require.NoError(t, err)
+
+		_, err = i.getClusterId()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), transport.requests.Load())
+		// The caller's client is left as it was
+		assert.Same(t, transport, httpClient.Transport)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Proxy-Authorization") != "corp" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"clusterId": "test-cluster"})
+	}))
+	defer server.Close()
+
+	calls := []string{}
+	record := func(name string) Middleware {
+		return func(next RoundTrip) RoundTrip {
+			return func(req *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" "+req.URL.Path)
+				return next(req)
+			}
+		}
+	}
+	failures := 1
+	faulty := func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			if failures > 0 {
+				failures--
+				return nil, errors.New("injected fault")
+			}
+			return next(req)
+		}
+	}
+
+	transport := &headerTransport{}
+	i, err := New(InferableOptions{
+		APIEndpoint: server.URL,
+		APISecret:   "test-secret",
+		Transport:   transport,
+		Retry:       &RetryOptions{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+		Middleware:  []Middleware{record("outer"), faulty, record("inner")},
+	})
+	require.NoError(t, err)
+
+	clusterId, err := i.getClusterId()
+	require.NoError(t, err)
+	assert.Equal(t, "test-cluster", clusterId)
+
+	// Every attempt runs through the chain, and the injected fault was retried
+	assert.Equal(t, []string{"outer /machines", "outer /machines", "inner /machines"}, calls)
+	assert.Equal(t, int64(1), transport.requests.Load())
+}
+
 func TestCallFunc(t *testing.T) {
 	i, _ := New(InferableOptions{
 		APIEndpoint: DefaultAPIEndpoint,
@@ -81,3 +186,63 @@ func TestGetMachineID(t *testing.T) {
 	})
 	assert.Equal(t, machineID, i2.machineID)
 }
+
+func TestProxy(t *testing.T) {
+	var proxied atomic.Int64
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Requests through a proxy carry the absolute URL of the cluster
+		if r.URL.Host != "api.inferable.example" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		proxied.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"clusterId": "test-cluster"})
+	}))
+	defer proxy.Close()
+
+	i, err := New(InferableOptions{APIEndpoint: "http://api.inferable.example", APISecret: "test-secret", Proxy: proxy.URL})
+	require.NoError(t, err)
+
+	clusterId, err := i.getClusterId()
+	require.NoError(t, err)
+	assert.Equal(t, "test-cluster", clusterId)
+	assert.Equal(t, int64(1), proxied.Load())
+
+	_, err = New(InferableOptions{APISecret: "test-secret", Proxy: "ftp://proxy.internal"})
+	assert.ErrorContains(t, err, "scheme must be http, https or socks5")
+
+	_, err = New(InferableOptions{APISecret: "test-secret", Proxy: proxy.URL, Transport: &headerTransport{}})
+	assert.ErrorContains(t, err, "can only be set for an *http.Transport")
+}
+
+func TestTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"clusterId": "test-cluster"})
+	}))
+	defer server.Close()
+
+	// The server's certificate is not trusted by default
+	i, err := New(InferableOptions{APIEndpoint: server.URL, APISecret: "test-secret", Retry: &RetryOptions{MaxAttempts: 1}})
+	require.NoError(t, err)
+	_, err = i.getClusterId()
+	assert.ErrorContains(t, err, "certificate")
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	i, err = New(InferableOptions{APIEndpoint: server.URL, APISecret: "test-secret", TLSConfig: &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}})
+	require.NoError(t, err)
+	clusterId, err := i.getClusterId()
+	require.NoError(t, err)
+	assert.Equal(t, "test-cluster", clusterId)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+	i, err = New(InferableOptions{APIEndpoint: server.URL, APISecret: "test-secret", CAFile: caFile})
+	require.NoError(t, err)
+	_, err = i.getClusterId()
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+	_, err = New(InferableOptions{APIEndpoint: server.URL, APISecret: "test-secret", CAFile: caFile})
+	assert.ErrorContains(t, err, "contains no PEM certificates")
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -12,11 +13,20 @@ type Client struct {
 	endpoint   string
 	secret     string
 	httpClient *http.Client
+	retrier    *retrier
 }
 
 type ClientOptions struct {
 	Endpoint string
 	Secret   string
+	// HTTPClient sends the requests. Defaults to a client without a timeout when nil.
+	HTTPClient *http.Client
+	// Transport, when set, replaces the transport of HTTPClient. Defaults to http.DefaultTransport
+	// when neither is set.
+	Transport http.RoundTripper
+	// Retry configures the retries of idempotent requests that failed transiently. The zero
+	// policy does not retry.
+	Retry RetryPolicy
 }
 
 // NewClient creates a new Inferable API client
@@ -25,10 +35,21 @@ func NewClient(options ClientOptions) (*Client, error) {
 		return nil, fmt.Errorf("invalid URL: %s", options.Endpoint)
 	}
 
+	// The caller's client is copied, so that replacing its transport does not change it
+	httpClient := &http.Client{}
+	if options.HTTPClient != nil {
+		copied := *options.HTTPClient
+		httpClient = &copied
+	}
+	if options.Transport != nil {
+		httpClient.Transport = options.Transport
+	}
+
 	return &Client{
 		endpoint:   options.Endpoint,
 		secret:     options.Secret,
-		httpClient: &http.Client{},
+		httpClient: httpClient,
+		retrier:    newRetrier(options.Retry),
 	}, nil
 }
 
@@ -38,6 +59,11 @@ type FetchDataOptions struct {
 	QueryParams map[string]string
 	Body        string
 	Method      string
+	// Context bounds the request, including its retries. Defaults to context.Background() when nil.
+	Context context.Context
+	// Idempotent marks a GET, POST or PATCH request as safe to retry, e.g. a read or a request
+	// carrying an idempotency key. HEAD, OPTIONS, PUT and DELETE requests are always retried.
+	Idempotent bool
 }
 
 func (c *Client) FetchData(options FetchDataOptions) (string, http.Header, error, int) {
@@ -47,9 +73,35 @@ func (c *Client) FetchData(options FetchDataOptions) (string, http.Header, error
 		return "", nil, fmt.Errorf("invalid URL: %s", fullURL), -1
 	}
 
-	req, err := http.NewRequest(options.Method, fullURL, strings.NewReader(options.Body))
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	attempts := 1
+	if options.Idempotent || idempotentMethods[options.Method] {
+		attempts = c.retrier.policy.MaxAttempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.do(ctx, fullURL, options)
+		if attempt >= attempts || !retryable(ctx, resp, err) {
+			return c.read(resp, err)
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		if !wait(ctx, c.retrier.backoff(attempt, resp)) {
+			return c.read(nil, fmt.Errorf("error making request: %w", ctx.Err()))
+		}
+	}
+}
+
+// do makes one attempt of a request.
+func (c *Client) do(ctx context.Context, fullURL string, options FetchDataOptions) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, options.Method, fullURL, strings.NewReader(options.Body))
 	if err != nil {
-		return "", nil, fmt.Errorf("error creating request: %v", err), -1
+		return nil, fmt.Errorf("error creating request: %v", err)
 	}
 
 	req.Header.Set("Authorization", "Bearer "+c.secret)
@@ -73,10 +125,15 @@ func (c *Client) FetchData(options FetchDataOptions) (string, http.Header, error
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		if resp == nil {
-			return "", nil, fmt.Errorf("error making request: %v", err), -1
-		}
-		return "", nil, fmt.Errorf("error making request: %v", err), resp.StatusCode
+		return nil, fmt.Errorf("error making request: %v", err)
+	}
+	return resp, nil
+}
+
+// read returns the body of the final attempt of a request.
+func (c *Client) read(resp *http.Response, err error) (string, http.Header, error, int) {
+	if err != nil {
+		return "", nil, err, -1
 	}
 	defer resp.Body.Close()
 
@@ -86,7 +143,7 @@ func (c *Client) FetchData(options FetchDataOptions) (string, http.Header, error
 	}
 
 	if resp.StatusCode >= 400 {
-		return "", resp.Header, fmt.Errorf("API error: %s (status code: %d)", string(body), resp.StatusCode), resp.StatusCode
+		return "", resp.Header, newAPIError(resp.StatusCode, resp.Header, string(body)), resp.StatusCode
 	}
 
 	return string(body), resp.Header, nil, resp.StatusCode
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors matched by an *APIError of the corresponding status.
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrNotFound     = errors.New("not found")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+// RequestIDHeader is the response header carrying the ID the API assigned to the request.
+const RequestIDHeader = "X-Request-Id"
+
+// APIError is returned for responses with a 4xx or 5xx status.
+type APIError struct {
+	StatusCode int
+	// RequestID identifies the request in the API's logs, if the API reported it.
+	RequestID string
+	// Code is the API's machine-readable error code, if any.
+	Code string
+	// Message describes the error, or is the raw response body if it was not a JSON error.
+	Message string
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	message := fmt.Sprintf("API error: %s (status code: %d", e.Message, e.StatusCode)
+	if e.Code != "" {
+		message += ", code: " + e.Code
+	}
+	if e.RequestID != "" {
+		message += ", request ID: " + e.RequestID
+	}
+	return message + ")"
+}
+
+// Is matches the sentinel error of the status code.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// Temporary reports whether the request may succeed if it is retried.
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// newAPIError creates the APIError of a response, reading the message and code of the API's
+// {"error": {"message": ..., "code": ...}} body.
+func newAPIError(status int, header http.Header, body string) *APIError {
+	err := &APIError{StatusCode: status, Message: strings.TrimSpace(body)}
+	if header != nil {
+		err.RequestID = header.Get(RequestIDHeader)
+	}
+
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	if json.Unmarshal([]byte(body), &response) == nil && response.Error.Message != "" {
+		err.Message = response.Error.Message
+		err.Code = response.Error.Code
+	}
+	if err.Message == "" {
+		err.Message = http.StatusText(status)
+	}
+	return err
+}
+
+// UnexpectedStatus creates the APIError of a response whose status the caller did not expect,
+// although it is not an error status.
+func UnexpectedStatus(status int) *APIError {
+	return &APIError{StatusCode: status, Message: "unexpected status"}
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures how requests that failed transiently are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts made, including the first. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles with every retry.
+	InitialBackoff time.Duration
+	// MaxBackoff bounds the delay between attempts, including delays requested by Retry-After.
+	MaxBackoff time.Duration
+	// Jitter is the fraction of each delay, between 0 and 1, that is randomized.
+	Jitter float64
+}
+
+// retrier retries requests according to a RetryPolicy.
+type retrier struct {
+	policy RetryPolicy
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+func newRetrier(policy RetryPolicy) *retrier {
+	return &retrier{policy: policy, random: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// idempotentMethods are retried without the caller marking the request idempotent. GET is not
+// among them, since some GETs change state, e.g. the poll acknowledges the calls it returns.
+var idempotentMethods = map[string]bool{
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// retryable reports whether an attempt of a request failed transiently: with a network error, a
+// 429, or a 5xx status.
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// backoff returns the delay before the given retry, starting at 1, honoring the Retry-After
+// header of the failed attempt's response.
+func (r *retrier) backoff(retry int, resp *http.Response) time.Duration {
+	delay := r.policy.InitialBackoff << (retry - 1)
+	if delay <= 0 || delay > r.policy.MaxBackoff {
+		delay = r.policy.MaxBackoff
+	}
+
+	if r.policy.Jitter > 0 {
+		r.mu.Lock()
+		jitter := time.Duration(r.random.Float64() * r.policy.Jitter * float64(delay))
+		r.mu.Unlock()
+		delay -= jitter
+	}
+
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			if requested := time.Duration(seconds) * time.Second; requested > delay {
+				delay = requested
+			}
+		}
+	}
+	if delay > r.policy.MaxBackoff {
+		delay = r.policy.MaxBackoff
+	}
+	return delay
+}
+
+// wait sleeps for the delay, and returns false if ctx is done first.
+func wait(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
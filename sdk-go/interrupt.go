@@ -1,5 +1,11 @@
 package inferable
 
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
 // VALID_INTERRUPT_TYPES defines the valid types of interrupts that can occur during workflow execution.
 type VALID_INTERRUPT_TYPES string
 
@@ -10,6 +16,17 @@ const (
 	GENERAL VALID_INTERRUPT_TYPES = "general"
 )
 
+// Outcomes of an interrupt that nobody resolved before its deadline, see Interrupt.WithTimeout.
+const (
+	// InterruptTimeoutFail fails the call, as if it was denied.
+	InterruptTimeoutFail = "fail"
+	// InterruptTimeoutApprove approves the call and runs it again.
+	InterruptTimeoutApprove = "approve"
+	// InterruptTimeoutResume runs the call again without approving it. The handler sees
+	// ContextInput.InterruptTimedOut and decides what to do.
+	InterruptTimeoutResume = "resume"
+)
+
 // Interrupt represents an interruption in the normal flow of a workflow execution.
 // Interrupts can be used to pause execution for approval or to handle exceptional conditions.
 type Interrupt struct {
@@ -17,6 +34,33 @@ type Interrupt struct {
 	Type VALID_INTERRUPT_TYPES `json:"type"`
 	// Message provides additional context about the interrupt.
 	Message string `json:"message,omitempty"`
+	// TimeoutSeconds is the deadline for resolving the interrupt, after which the cluster
+	// resolves it with OnTimeout. Zero waits indefinitely.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+	// OnTimeout is InterruptTimeoutFail (the default), InterruptTimeoutApprove or
+	// InterruptTimeoutResume.
+	OnTimeout string `json:"onTimeout,omitempty"`
+	// Options are the choices a UI offers to resolve an approval interrupt instead of approve and
+	// deny. The chosen option is delivered back as ContextInput.ApprovalOption.
+	Options []ApprovalOption `json:"options,omitempty"`
+	// Links point the approver to context for the decision, e.g. the order under review.
+	Links []ApprovalLink `json:"links,omitempty"`
+}
+
+// ApprovalOption is a choice offered to resolve an approval interrupt.
+type ApprovalOption struct {
+	// ID identifies the option, and is delivered back to the handler when it is chosen.
+	ID string `json:"id"`
+	// Label is the text of the option's button.
+	Label string `json:"label"`
+	// Deny makes choosing the option deny the call instead of approving it.
+	Deny bool `json:"deny,omitempty"`
+}
+
+// ApprovalLink is a deep link shown alongside an approval interrupt.
+type ApprovalLink struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
 }
 
 // Error implements the error interface, allowing Interrupts to be used as errors.
@@ -28,6 +72,63 @@ func (i *Interrupt) Error() string {
 	return string(i.Type) + " interrupt"
 }
 
+// WithTimeout sets a deadline for resolving the interrupt, so that an execution waiting for a human
+// does not hang when nobody responds. The deadline is rounded up to whole seconds.
+//
+//	return inferable.ApprovalInterrupt("Refund over $100").WithTimeout(24*time.Hour, inferable.InterruptTimeoutFail), nil
+func (i *Interrupt) WithTimeout(timeout time.Duration, onTimeout string) *Interrupt {
+	i.TimeoutSeconds = int(math.Ceil(timeout.Seconds()))
+	i.OnTimeout = onTimeout
+	return i
+}
+
+// WithOptions offers choices beyond approve and deny on an approval interrupt.
+//
+//	return inferable.ApprovalInterrupt("Refund requested").WithOptions(
+//		inferable.ApprovalOption{ID: "full", Label: "Refund in full"},
+//		inferable.ApprovalOption{ID: "partial", Label: "Refund half"},
+//		inferable.ApprovalOption{ID: "reject", Label: "Reject", Deny: true},
+//	), nil
+func (i *Interrupt) WithOptions(options ...ApprovalOption) *Interrupt {
+	i.Options = append(i.Options, options...)
+	return i
+}
+
+// WithLinks adds deep links to an approval interrupt.
+func (i *Interrupt) WithLinks(links ...ApprovalLink) *Interrupt {
+	i.Links = append(i.Links, links...)
+	return i
+}
+
+// validate checks the interrupt's deadline and presentation before it is reported.
+func (i *Interrupt) validate() error {
+	if i.TimeoutSeconds < 0 {
+		return fmt.Errorf("interrupt timeout must be positive, got %ds", i.TimeoutSeconds)
+	}
+	switch i.OnTimeout {
+	case "", InterruptTimeoutFail, InterruptTimeoutApprove, InterruptTimeoutResume:
+	default:
+		return fmt.Errorf("interrupt has unknown timeout outcome %q, use InterruptTimeoutFail, InterruptTimeoutApprove or InterruptTimeoutResume", i.OnTimeout)
+	}
+	if i.OnTimeout != "" && i.TimeoutSeconds == 0 {
+		return fmt.Errorf("interrupt has a timeout outcome but no timeout")
+	}
+	if i.Type != APPROVAL && (len(i.Options) > 0 || len(i.Links) > 0) {
+		return fmt.Errorf("only approval interrupts have options and links")
+	}
+	seen := map[string]bool{}
+	for _, option := range i.Options {
+		if option.ID == "" {
+			return fmt.Errorf("approval option %q has no ID", option.Label)
+		}
+		if seen[option.ID] {
+			return fmt.Errorf("approval option %s is offered twice", option.ID)
+		}
+		seen[option.ID] = true
+	}
+	return nil
+}
+
 // NewInterrupt creates a new Interrupt with the specified type and message.
 // This is a general constructor for creating interrupts.
 func NewInterrupt(typ VALID_INTERRUPT_TYPES, message string) *Interrupt {
@@ -0,0 +1,99 @@
+package inferable
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterruptTimeout(t *testing.T) {
+	i, results := newResultRecorder(t, InferableOptions{})
+
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "refund",
+		Func: func(input chargeInput, ctx ContextInput) (interface{}, error) {
+			if ctx.InterruptTimedOut {
+				return "escalated", nil
+			}
+			if !ctx.Approved {
+				return ApprovalInterrupt("Refund requested").WithTimeout(90*time.Minute+time.Millisecond, InterruptTimeoutResume), nil
+			}
+			return "refunded", nil
+		},
+	}))
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "misconfigured",
+		Func: func(input chargeInput, ctx ContextInput) (interface{}, error) {
+			return GeneralInterrupt("Waiting").WithTimeout(time.Minute, "retry"), nil
+		},
+	}))
+
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-1", Function: "refund", Input: map[string]interface{}{"amount": 100}}))
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-1", Function: "refund", Input: map[string]interface{}{"amount": 100}, InterruptTimedOut: true}))
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-2", Function: "misconfigured", Input: map[string]interface{}{"amount": 100}}))
+
+	require.Len(t, results(), 3)
+	assert.Equal(t, "interrupt", results()[0].ResultType)
+	assert.Equal(t, map[string]interface{}{
+		"type":           "approval",
+		"message":        "Refund requested",
+		"timeoutSeconds": 5401.0,
+		"onTimeout":      "resume",
+	}, results()[0].Result)
+
+	// A timed out interrupt runs the call again
+	assert.Equal(t, "resolution", results()[1].ResultType)
+	assert.Equal(t, "escalated", results()[1].Result)
+
+	// Unknown outcomes are rejected instead of reported
+	assert.Equal(t, "rejection", results()[2].ResultType)
+	assert.Contains(t, results()[2].Result, "unknown timeout outcome")
+}
+
+func TestApprovalOptions(t *testing.T) {
+	i, results := newResultRecorder(t, InferableOptions{})
+
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "refund",
+		Func: func(input chargeInput, ctx ContextInput) (interface{}, error) {
+			if !ctx.Approved {
+				return ApprovalInterrupt("Refund requested").
+					WithOptions(ApprovalOption{ID: "full", Label: "Refund in full"}, ApprovalOption{ID: "partial", Label: "Refund half"}).
+					WithLinks(ApprovalLink{Label: "Order", URL: "https://example.com/orders/42"}), nil
+			}
+			if ctx.ApprovalOption == "partial" {
+				return input.Amount / 2, nil
+			}
+			return input.Amount, nil
+		},
+	}))
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "duplicated",
+		Func: func(input chargeInput, ctx ContextInput) (interface{}, error) {
+			return ApprovalInterrupt("Refund requested").WithOptions(ApprovalOption{ID: "full"}, ApprovalOption{ID: "full"}), nil
+		},
+	}))
+
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-1", Function: "refund", Input: map[string]interface{}{"amount": 100}}))
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-1", Function: "refund", Input: map[string]interface{}{"amount": 100}, Approved: true, ApprovalOption: "partial"}))
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-2", Function: "duplicated", Input: map[string]interface{}{"amount": 100}}))
+
+	require.Len(t, results(), 3)
+	assert.Equal(t, map[string]interface{}{
+		"type":    "approval",
+		"message": "Refund requested",
+		"options": []interface{}{
+			map[string]interface{}{"id": "full", "label": "Refund in full"},
+			map[string]interface{}{"id": "partial", "label": "Refund half"},
+		},
+		"links": []interface{}{map[string]interface{}{"label": "Order", "url": "https://example.com/orders/42"}},
+	}, results()[0].Result)
+
+	// The chosen option is delivered on resume
+	assert.Equal(t, 50.0, results()[1].Result)
+
+	assert.Equal(t, "rejection", results()[2].ResultType)
+	assert.Contains(t, results()[2].Result, "offered twice")
+}
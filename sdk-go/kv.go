@@ -0,0 +1,263 @@
+package inferable
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+	"github.com/inferablehq/inferable/sdk-go/kv"
+)
+
+// KVStore is the key-value backend behind ctx.Memo, ctx.State, ctx.Lock and client.KV. Values
+// are opaque strings. The default implementation stores values in the cluster; MemoryStore keeps
+// them in process for tests and local runs. Implement it to keep them elsewhere, e.g. in Redis or
+// Postgres where the cluster is too far away, along with ExpiringKVStore, VersionedKVStore and
+// KVLister for the features that need them.
+type KVStore interface {
+	// Get returns the value stored under key, and false if there is none.
+	Get(key string) (string, bool, error)
+	// SetIfAbsent stores value under key unless the key already has a value.
+	SetIfAbsent(key string, value string) error
+}
+
+// ExpiringKVStore is a KVStore whose entries can expire. Expired entries are reported as absent
+// and can be set again. The cluster store and MemoryStore implement it.
+type ExpiringKVStore interface {
+	KVStore
+	// SetIfAbsentUntil stores value under key unless the key has a value that has not expired.
+	// The entry expires at expiresAt, or never for the zero time.
+	SetIfAbsentUntil(key string, value string, expiresAt time.Time) error
+}
+
+// expiryOf resolves a TTL and an explicit expiry to the time an entry expires, the zero time for
+// entries that do not expire. An explicit expiry takes precedence.
+func expiryOf(ttl time.Duration, expiresAt time.Time) time.Time {
+	if !expiresAt.IsZero() {
+		return expiresAt
+	}
+	if ttl > 0 {
+		return time.Now().Add(ttl)
+	}
+	return time.Time{}
+}
+
+// clusterKVStore stores values in the cluster's key-value store.
+type clusterKVStore struct {
+	inferable *Inferable
+	// ctx bounds the store's requests. Nil does not bound them.
+	ctx context.Context
+}
+
+func (s *clusterKVStore) Get(key string) (string, bool, error) {
+	respBody, _, err, statusCode := s.inferable.client.FetchData(client.FetchDataOptions{
+		Path:       fmt.Sprintf("/clusters/%s/keys/%s/value", s.inferable.clusterID, key),
+		Method:     "GET",
+		Context:    s.ctx,
+		Idempotent: true,
+	})
+	if statusCode == 404 {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get key: %w", err)
+	}
+	if statusCode != 200 || respBody == "" {
+		return "", false, nil
+	}
+
+	var kvResponse struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(respBody), &kvResponse); err != nil {
+		return "", false, fmt.Errorf("failed to unmarshal key value: %v", err)
+	}
+
+	return kvResponse.Value, kvResponse.Value != "", nil
+}
+
+func (s *clusterKVStore) SetIfAbsent(key string, value string) error {
+	return s.SetIfAbsentUntil(key, value, time.Time{})
+}
+
+func (s *clusterKVStore) SetIfAbsentUntil(key string, value string, expiresAt time.Time) error {
+	payload := map[string]interface{}{
+		"value":      value,
+		"onConflict": "doNothing",
+	}
+	if !expiresAt.IsZero() {
+		payload["expiresAt"] = expiresAt.UTC().Format(time.RFC3339Nano)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	_, _, err, _ = s.inferable.client.FetchData(client.FetchDataOptions{
+		Path:    fmt.Sprintf("/clusters/%s/keys/%s", s.inferable.clusterID, key),
+		Method:  "PUT",
+		Context: s.ctx,
+		Body:    string(body),
+	})
+	return err
+}
+
+// KVWrite records a write made to a MemoryStore.
+type KVWrite struct {
+	Key   string
+	Value string
+	// Stored is false when the key already had a value and the write was discarded.
+	Stored bool
+}
+
+// MemoryStore is an in-process KVStore for tests and local runs. It records every write so that
+// memoization can be asserted on deterministically.
+//
+//	store := inferable.NewMemoryStore()
+//	client, err := inferable.New(inferable.InferableOptions{KVStore: store})
+//
+//	store.SeedMemo("exec-1", "classification", map[string]interface{}{"category": "billing"})
+type MemoryStore struct {
+	mu       sync.Mutex
+	values   map[string]string
+	expiries map[string]time.Time
+	versions map[string]int
+	writes   []KVWrite
+	// now is the time entries expire against
+	now func() time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		values:   map[string]string{},
+		expiries: map[string]time.Time{},
+		versions: map[string]int{},
+		now:      time.Now,
+	}
+}
+
+// live reports whether key has a value that has not expired. The caller holds s.mu.
+func (s *MemoryStore) live(key string) bool {
+	if _, ok := s.values[key]; !ok {
+		return false
+	}
+	expiresAt, ok := s.expiries[key]
+	return !ok || s.now().Before(expiresAt)
+}
+
+func (s *MemoryStore) Get(key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.live(key) {
+		return "", false, nil
+	}
+	return s.values[key], true, nil
+}
+
+func (s *MemoryStore) SetIfAbsent(key string, value string) error {
+	return s.SetIfAbsentUntil(key, value, time.Time{})
+}
+
+func (s *MemoryStore) SetIfAbsentUntil(key string, value string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exists := s.live(key)
+	if !exists {
+		s.store(key, value, 1, expiresAt)
+	}
+	s.writes = append(s.writes, KVWrite{Key: key, Value: value, Stored: !exists})
+	return nil
+}
+
+// store writes an entry at version. The caller holds s.mu.
+func (s *MemoryStore) store(key string, value string, version int, expiresAt time.Time) {
+	s.values[key] = value
+	s.versions[key] = version
+	delete(s.expiries, key)
+	if !expiresAt.IsZero() {
+		s.expiries[key] = expiresAt
+	}
+}
+
+// Set stores value under key, replacing any existing value. It is not recorded as a write.
+func (s *MemoryStore) Set(key string, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	version := 1
+	if s.live(key) {
+		version = s.versions[key] + 1
+	}
+	s.store(key, value, version, time.Time{})
+}
+
+// Keys returns the keys of unexpired entries in sorted order.
+func (s *MemoryStore) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		if s.live(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Writes returns the SetIfAbsent and SetIfVersion calls made so far, in order.
+func (s *MemoryStore) Writes() []KVWrite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]KVWrite{}, s.writes...)
+}
+
+// SeedMemo stores a ctx.Memo result for an execution, as if a previous attempt had computed it.
+func (s *MemoryStore) SeedMemo(executionId string, name string, value interface{}) error {
+	serialized, err := kv.Encode(value)
+	if err != nil {
+		return err
+	}
+	s.Set(memoKey(executionId, name), serialized)
+	return nil
+}
+
+// Memo returns the ctx.Memo result stored for an execution under name.
+func (s *MemoryStore) Memo(executionId string, name string) (interface{}, bool) {
+	value, ok, err := kv.NewTyped[interface{}](s).Get(memoKey(executionId, name))
+	if err != nil || !ok || value == nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// callResultKey is the key a deduplicated tool call's result is recorded under.
+func callResultKey(callId string) string {
+	return fmt.Sprintf("call_%s_result", callId)
+}
+
+// memoKey is the key under which ctx.Memo stores a result.
+func memoKey(executionId string, name string) string {
+	return fmt.Sprintf("%s_memo_%s", executionId, name)
+}
+
+// sharedMemoKey is the key under which a ctx.Memo result shared by a workflow's executions is
+// stored.
+func sharedMemoKey(workflowName string, name string) string {
+	return fmt.Sprintf("shared_%s_memo_%s", workflowName, name)
+}
+
+// versionedMemoName appends the non-empty code versions to a memo name, as "name@v1@v2".
+func versionedMemoName(name string, versions ...string) string {
+	for _, version := range versions {
+		if version != "" {
+			name += "@" + version
+		}
+	}
+	return name
+}
@@ -0,0 +1,172 @@
+// Package kv provides typed access to the key-value stores behind ctx.Memo and client.KV.
+//
+// Values are stored as JSON wrapped in a {"value": ...} envelope, the format ctx.Memo uses, so
+// typed values and memo results can be read interchangeably:
+//
+//	rates := kv.NewTyped[map[string]float64](client.KV)
+//	usd, err := rates.GetOrSet("rates_usd", fetchRates)
+//
+// Large values can be stored gzip-compressed in a {"gzip": ...} envelope instead, see Compress.
+// Decode reads both.
+package kv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Store is the subset of a key-value store that Typed needs. inferable.KVStore, its
+// implementations, and client.KV satisfy it.
+type Store interface {
+	// Get returns the value stored under key, and false if there is none.
+	Get(key string) (string, bool, error)
+	// SetIfAbsent stores value under key unless the key already has a value.
+	SetIfAbsent(key string, value string) error
+}
+
+// Encode serializes a value in the stored envelope format.
+func Encode(value interface{}) (string, error) {
+	serialized, err := json.Marshal(envelope[interface{}]{Value: value})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal value: %v", err)
+	}
+	return string(serialized), nil
+}
+
+// Compress gzips a value serialized by Encode if it is longer than threshold bytes and
+// compressing makes it shorter. Shorter values, and any value for a threshold of 0, are returned
+// as is.
+func Compress(serialized string, threshold int) (string, error) {
+	if threshold <= 0 || len(serialized) <= threshold {
+		return serialized, nil
+	}
+
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write([]byte(serialized)); err != nil {
+		return "", fmt.Errorf("failed to compress value: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to compress value: %v", err)
+	}
+
+	compressed, err := json.Marshal(compressedEnvelope{Gzip: buffer.Bytes()})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal value: %v", err)
+	}
+	if len(compressed) >= len(serialized) {
+		return serialized, nil
+	}
+	return string(compressed), nil
+}
+
+// Decode deserializes a value stored in the envelope format, compressed or not.
+func Decode[T any](serialized string) (T, error) {
+	var stored struct {
+		envelope[T]
+		compressedEnvelope
+	}
+	if err := json.Unmarshal([]byte(serialized), &stored); err != nil {
+		return stored.Value, fmt.Errorf("failed to unmarshal value: %v", err)
+	}
+	if stored.Gzip == nil {
+		return stored.Value, nil
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(stored.Gzip))
+	if err != nil {
+		return stored.Value, fmt.Errorf("failed to decompress value: %v", err)
+	}
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return stored.Value, fmt.Errorf("failed to decompress value: %v", err)
+	}
+	return Decode[T](string(decompressed))
+}
+
+type envelope[T any] struct {
+	Value T `json:"value"`
+}
+
+// compressedEnvelope holds a gzipped envelope.
+type compressedEnvelope struct {
+	Gzip []byte `json:"gzip"`
+}
+
+// Options configures a Typed.
+type Options struct {
+	// CompressionThreshold, when set, is the serialized size in bytes above which values are
+	// stored compressed, see Compress.
+	CompressionThreshold int
+}
+
+// Typed reads and writes values of type T in a Store. Like the stores, values are write-once:
+// the first value set under a key is kept.
+type Typed[T any] struct {
+	store   Store
+	options Options
+}
+
+// NewTyped creates a Typed over store.
+func NewTyped[T any](store Store, options ...Options) *Typed[T] {
+	typed := &Typed[T]{store: store}
+	for _, option := range options {
+		if option.CompressionThreshold > 0 {
+			typed.options.CompressionThreshold = option.CompressionThreshold
+		}
+	}
+	return typed
+}
+
+// Get returns the value stored under key, and false if there is none.
+func (t *Typed[T]) Get(key string) (T, bool, error) {
+	var zero T
+
+	serialized, ok, err := t.store.Get(key)
+	if err != nil || !ok {
+		return zero, false, err
+	}
+
+	value, err := Decode[T](serialized)
+	if err != nil {
+		return zero, false, fmt.Errorf("failed to decode %s: %v", key, err)
+	}
+	return value, true, nil
+}
+
+// Set stores value under key unless the key already has a value.
+func (t *Typed[T]) Set(key string, value T) error {
+	serialized, err := Encode(value)
+	if err != nil {
+		return err
+	}
+	if serialized, err = Compress(serialized, t.options.CompressionThreshold); err != nil {
+		return err
+	}
+	return t.store.SetIfAbsent(key, serialized)
+}
+
+// GetOrSet returns the value stored under key, computing and storing it with fn if there is
+// none. When concurrent callers race, all of them return the value that was stored first.
+func (t *Typed[T]) GetOrSet(key string, fn func() (T, error)) (T, error) {
+	if value, ok, err := t.Get(key); err != nil || ok {
+		return value, err
+	}
+
+	value, err := fn()
+	if err != nil {
+		return value, err
+	}
+	if err := t.Set(key, value); err != nil {
+		return value, err
+	}
+
+	// Another caller may have set the key first
+	if stored, ok, err := t.Get(key); err == nil && ok {
+		return stored, nil
+	}
+	return value, nil
+}
@@ -0,0 +1,121 @@
+package kv
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapStore is a write-once Store backed by a map.
+type mapStore map[string]string
+
+func (s mapStore) Get(key string) (string, bool, error) {
+	value, ok := s[key]
+	return value, ok, nil
+}
+
+func (s mapStore) SetIfAbsent(key string, value string) error {
+	if _, ok := s[key]; !ok {
+		s[key] = value
+	}
+	return nil
+}
+
+type rates struct {
+	USD float64 `json:"usd"`
+}
+
+func TestTyped(t *testing.T) {
+	store := mapStore{}
+	typed := NewTyped[rates](store)
+
+	_, ok, err := typed.Get("rates")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, typed.Set("rates", rates{USD: 1.1}))
+	require.NoError(t, typed.Set("rates", rates{USD: 2}))
+	assert.Equal(t, `{"value":{"usd":1.1}}`, store["rates"])
+
+	value, ok, err := typed.Get("rates")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, rates{USD: 1.1}, value)
+
+	store["broken"] = "not json"
+	_, _, err = typed.Get("broken")
+	assert.ErrorContains(t, err, "failed to decode broken")
+}
+
+func TestGetOrSet(t *testing.T) {
+	store := mapStore{}
+	typed := NewTyped[int](store)
+
+	calls := 0
+	compute := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	for n := 0; n < 2; n++ {
+		value, err := typed.GetOrSet("count", compute)
+		require.NoError(t, err)
+		assert.Equal(t, 1, value)
+	}
+	assert.Equal(t, 1, calls)
+
+	_, err := typed.GetOrSet("failing", func() (int, error) { return 0, fmt.Errorf("unavailable") })
+	assert.ErrorContains(t, err, "unavailable")
+	assert.NotContains(t, store, "failing")
+
+	// A value set by another caller in the meantime wins
+	value, err := typed.GetOrSet("raced", func() (int, error) {
+		store["raced"] = `{"value":7}`
+		return 8, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 7, value)
+}
+
+func TestReadsMemoResults(t *testing.T) {
+	serialized, err := Encode(map[string]interface{}{"category": "billing"})
+	require.NoError(t, err)
+
+	value, err := Decode[struct {
+		Category string `json:"category"`
+	}](serialized)
+	require.NoError(t, err)
+	assert.Equal(t, "billing", value.Category)
+}
+
+func TestCompress(t *testing.T) {
+	small, err := Encode("short")
+	require.NoError(t, err)
+	compressed, err := Compress(small, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, small, compressed)
+
+	large, err := Encode(strings.Repeat("the same sentence again. ", 200))
+	require.NoError(t, err)
+	compressed, err = Compress(large, 1024)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(compressed, `{"gzip":`))
+	assert.Less(t, len(compressed), len(large)/10)
+
+	value, err := Decode[string](compressed)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("the same sentence again. ", 200), value)
+
+	// Typed values are compressed on write and decompressed on read
+	store := mapStore{}
+	typed := NewTyped[string](store, Options{CompressionThreshold: 1024})
+	require.NoError(t, typed.Set("large", value))
+	assert.Equal(t, compressed, store["large"])
+	stored, ok, err := typed.Get("large")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, value, stored)
+}
@@ -0,0 +1,283 @@
+package inferable
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	_, ok, err := store.Get("a")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, store.SetIfAbsent("b", "1"))
+	require.NoError(t, store.SetIfAbsent("b", "2"))
+	store.Set("a", "3")
+
+	value, ok, err := store.Get("b")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+
+	assert.Equal(t, []string{"a", "b"}, store.Keys())
+	assert.Equal(t, []KVWrite{
+		{Key: "b", Value: "1", Stored: true},
+		{Key: "b", Value: "2", Stored: false},
+	}, store.Writes())
+}
+
+// memoKeys returns the ctx.Memo keys among keys, leaving out the attempt records of handler runs.
+func memoKeys(keys []string) []string {
+	memo := []string{}
+	for _, key := range keys {
+		if strings.Contains(key, "_memo_") {
+			memo = append(memo, key)
+		}
+	}
+	return memo
+}
+
+// memoWrites returns the ctx.Memo writes among writes.
+func memoWrites(writes []KVWrite) []KVWrite {
+	memo := []KVWrite{}
+	for _, write := range writes {
+		if strings.Contains(write.Key, "_memo_") {
+			memo = append(memo, write)
+		}
+	}
+	return memo
+}
+
+func TestMemoWithMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	require.NoError(t, store.SeedMemo("exec-1", "seeded", "from a previous attempt"))
+
+	i := newTestClient(t, InferableOptions{KVStore: store}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "memo", InputSchema: WorkflowInput{}})
+
+	calls := 0
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		seeded, err := ctx.Memo("seeded", func() (interface{}, error) {
+			calls++
+			return "recomputed", nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		computed, err := ctx.Memo("computed", func() (interface{}, error) {
+			calls++
+			return map[string]interface{}{"total": 42}, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return []interface{}{seeded, computed}, nil
+	})
+
+	for attempt := 0; attempt < 2; attempt++ {
+		result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+		require.NoError(t, err)
+		assert.Equal(t, "from a previous attempt", result.([]interface{})[0])
+	}
+
+	// The computed result is stored once and reused by the second attempt
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []string{"exec-1_memo_computed", "exec-1_memo_seeded"}, memoKeys(store.Keys()))
+	assert.Len(t, memoWrites(store.Writes()), 1)
+
+	memo, ok := store.Memo("exec-1", "computed")
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"total": 42.0}, memo)
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.SetIfAbsentUntil("lock", "first", now.Add(time.Minute)))
+	require.NoError(t, store.SetIfAbsentUntil("lock", "second", now.Add(time.Minute)))
+	value, _, _ := store.Get("lock")
+	assert.Equal(t, "first", value)
+
+	// Expired entries are absent and can be set again
+	now = now.Add(2 * time.Minute)
+	_, ok, err := store.Get("lock")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Empty(t, store.Keys())
+
+	require.NoError(t, store.SetIfAbsent("lock", "third"))
+	now = now.Add(time.Hour)
+	value, ok, _ = store.Get("lock")
+	assert.True(t, ok)
+	assert.Equal(t, "third", value)
+}
+
+func TestMemoCompression(t *testing.T) {
+	store := NewMemoryStore()
+	i := newTestClient(t, InferableOptions{KVStore: store}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "summaries", InputSchema: WorkflowInput{}, CompressionThreshold: 512})
+
+	summary := strings.Repeat("The customer asked about their invoice. ", 100)
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		if _, err := ctx.State.Set("draft", summary); err != nil {
+			return nil, err
+		}
+		return ctx.Memo("summary", func() (interface{}, error) {
+			return summary, nil
+		})
+	})
+
+	for attempt := 0; attempt < 2; attempt++ {
+		result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+		require.NoError(t, err)
+		assert.Equal(t, summary, result)
+	}
+
+	for _, key := range []string{"exec-1_memo_summary", stateKey("exec-1", "draft", 1)} {
+		stored, ok, err := store.Get(key)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.True(t, strings.HasPrefix(stored, `{"gzip":`), key)
+		assert.Less(t, len(stored), len(summary)/4, key)
+	}
+
+	memo, ok := store.Memo("exec-1", "summary")
+	require.True(t, ok)
+	assert.Equal(t, summary, memo)
+	draft, _, err := GetState[string](newState(store, "exec-1"), "draft")
+	require.NoError(t, err)
+	assert.Equal(t, summary, draft)
+}
+
+func TestMemoVersion(t *testing.T) {
+	store := NewMemoryStore()
+	i := newTestClient(t, InferableOptions{KVStore: store}, nil)
+
+	calls := 0
+	deploy := func(build string, stepVersion string) interface{} {
+		workflow := i.Workflows.Create(WorkflowConfig{Name: "classify", InputSchema: WorkflowInput{}, MemoVersion: build})
+		workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+			if err := ctx.Sleep("cooldown", 0); err != nil {
+				return nil, err
+			}
+			return ctx.MemoWithOptions("category", MemoOptions{Version: stepVersion}, func() (interface{}, error) {
+				calls++
+				return fmt.Sprintf("%s/%s", build, stepVersion), nil
+			})
+		})
+		result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+		require.NoError(t, err)
+		return result
+	}
+
+	assert.Equal(t, "build-1/", deploy("build-1", ""))
+	assert.Equal(t, "build-1/", deploy("build-1", ""))
+	assert.Equal(t, 1, calls)
+
+	// A new build or step version recomputes the result
+	assert.Equal(t, "build-2/", deploy("build-2", ""))
+	assert.Equal(t, "build-2/v2", deploy("build-2", "v2"))
+	assert.Equal(t, 3, calls)
+
+	assert.Equal(t, []string{
+		"exec-1_memo_category@build-1",
+		"exec-1_memo_category@build-2",
+		"exec-1_memo_category@build-2@v2",
+		"exec-1_memo_sleep_cooldown",
+	}, memoKeys(store.Keys()))
+}
+
+func TestSharedMemo(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	i := newTestClient(t, InferableOptions{KVStore: store}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "pricing", InputSchema: WorkflowInput{}})
+
+	fetches := 0
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return ctx.MemoWithOptions("price-list", MemoOptions{Scope: MemoScopeWorkflow, TTL: time.Hour}, func() (interface{}, error) {
+			fetches++
+			return fmt.Sprintf("prices-%d", fetches), nil
+		})
+	})
+	run := func(executionId string) interface{} {
+		result, err := workflow.Execute(1, WorkflowInput{ExecutionID: executionId}, ContextInput{})
+		require.NoError(t, err)
+		return result
+	}
+
+	assert.Equal(t, "prices-1", run("exec-1"))
+	assert.Equal(t, "prices-1", run("exec-2"))
+	assert.Equal(t, 1, fetches)
+	assert.Equal(t, []string{
+		"exec-1_memo_price-list",
+		"exec-2_memo_price-list",
+		"shared_pricing_memo_price-list",
+	}, memoKeys(store.Keys()))
+
+	// Once the shared result expires it is fetched again, but executions keep the value they read
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, "prices-2", run("exec-3"))
+	assert.Equal(t, "prices-1", run("exec-1"))
+	assert.Equal(t, 2, fetches)
+
+	workflow.Version(2).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return ctx.MemoWithOptions("price-list", MemoOptions{Scope: MemoScopeWorkflow}, func() (interface{}, error) {
+			return nil, nil
+		})
+	})
+	_, err := workflow.Execute(2, WorkflowInput{ExecutionID: "exec-4"}, ContextInput{})
+	assert.ErrorContains(t, err, "needs a TTL or ExpiresAt")
+}
+
+func TestMemoWithTTL(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	i := newTestClient(t, InferableOptions{KVStore: store}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "rates", InputSchema: WorkflowInput{}})
+
+	calls := 0
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return ctx.MemoWithOptions("rates", MemoOptions{TTL: time.Hour}, func() (interface{}, error) {
+			calls++
+			return calls, nil
+		})
+	})
+
+	run := func() interface{} {
+		result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+		require.NoError(t, err)
+		return result
+	}
+	assert.Equal(t, 1, run())
+	assert.Equal(t, float64(1), run())
+
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, 2, run())
+
+	// Stores without expiry support reject memos with an expiry
+	unlisted := newTestClient(t, InferableOptions{KVStore: unlistedStore{NewMemoryStore()}}, nil)
+	other := unlisted.Workflows.Create(WorkflowConfig{Name: "rates", InputSchema: WorkflowInput{}})
+	other.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return ctx.MemoWithOptions("rates", MemoOptions{ExpiresAt: now.Add(time.Hour)}, func() (interface{}, error) {
+			return 1, nil
+		})
+	})
+	_, err := other.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	assert.ErrorContains(t, err, "does not implement ExpiringKVStore")
+}
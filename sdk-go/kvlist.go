@@ -0,0 +1,210 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+// DefaultKVListLimit is the page size of KVLister.List when no limit is given.
+const DefaultKVListLimit = 100
+
+// KVEntry is an entry listed from a KVStore.
+type KVEntry struct {
+	Key   string
+	Value string
+	// CreatedAt is the zero time for stores that do not record it.
+	CreatedAt time.Time
+	// ExpiresAt is the zero time for entries that do not expire.
+	ExpiresAt time.Time
+}
+
+// KVListOptions configures KVLister.List.
+type KVListOptions struct {
+	// Limit is the maximum number of entries returned. Defaults to DefaultKVListLimit.
+	Limit int
+	// After lists the keys after this one, i.e. the previous page's NextCursor.
+	After string
+}
+
+// KVPage is a page of listed entries, in key order.
+type KVPage struct {
+	Entries []KVEntry
+	// NextCursor is passed as KVListOptions.After to fetch the next page. It is empty on the
+	// last page.
+	NextCursor string
+}
+
+// KVLister is a KVStore that can enumerate its unexpired entries by key prefix. The cluster store
+// and MemoryStore implement it.
+type KVLister interface {
+	KVStore
+	List(prefix string, options KVListOptions) (*KVPage, error)
+}
+
+// KV gives direct access to the client's key-value store: the KVStore set on InferableOptions,
+// else the cluster's. It is exposed as client.KV.
+//
+//	page, err := client.KV.List("tenant-42_lock_", inferable.KVListOptions{Limit: 50})
+type KV struct {
+	inferable *Inferable
+}
+
+// store resolves the client's store, making sure the cluster is known for the cluster store.
+func (k *KV) store() (KVStore, error) {
+	if _, err := k.inferable.getClusterId(); err != nil {
+		return nil, fmt.Errorf("failed to get cluster id: %w", err)
+	}
+	return k.inferable.store(), nil
+}
+
+// Get returns the value stored under key, and false if there is none.
+func (k *KV) Get(key string) (string, bool, error) {
+	store, err := k.store()
+	if err != nil {
+		return "", false, err
+	}
+	return store.Get(key)
+}
+
+// SetIfAbsent stores value under key unless the key already has a value.
+func (k *KV) SetIfAbsent(key string, value string) error {
+	store, err := k.store()
+	if err != nil {
+		return err
+	}
+	return store.SetIfAbsent(key, value)
+}
+
+// List returns a page of the entries whose keys start with prefix.
+func (k *KV) List(prefix string, options ...KVListOptions) (*KVPage, error) {
+	store, err := k.store()
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := store.(KVLister)
+	if !ok {
+		return nil, fmt.Errorf("the KVStore does not implement KVLister")
+	}
+
+	listOptions := KVListOptions{}
+	if len(options) > 0 {
+		listOptions = options[0]
+	}
+	return lister.List(prefix, listOptions)
+}
+
+// ListAll returns every entry whose key starts with prefix, following pages until the last.
+func (k *KV) ListAll(prefix string) ([]KVEntry, error) {
+	store, err := k.store()
+	if err != nil {
+		return nil, err
+	}
+	lister, ok := store.(KVLister)
+	if !ok {
+		return nil, fmt.Errorf("the KVStore does not implement KVLister")
+	}
+	return listAll(lister, prefix)
+}
+
+// listAll follows a lister's pages until the last.
+func listAll(lister KVLister, prefix string) ([]KVEntry, error) {
+	entries := []KVEntry{}
+	options := KVListOptions{}
+	for {
+		page, err := lister.List(prefix, options)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, page.Entries...)
+		if page.NextCursor == "" {
+			return entries, nil
+		}
+		options.After = page.NextCursor
+	}
+}
+
+func (s *clusterKVStore) List(prefix string, options KVListOptions) (*KVPage, error) {
+	limit := options.Limit
+	if limit <= 0 {
+		limit = DefaultKVListLimit
+	}
+	query := map[string]string{"prefix": prefix, "limit": strconv.Itoa(limit)}
+	if options.After != "" {
+		query["after"] = options.After
+	}
+
+	respBody, _, err, statusCode := s.inferable.client.FetchData(client.FetchDataOptions{
+		Path:        fmt.Sprintf("/clusters/%s/keys", s.inferable.clusterID),
+		Method:      "GET",
+		Context:     s.ctx,
+		QueryParams: query,
+		Idempotent:  true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %w", err)
+	}
+	if statusCode != 200 {
+		return nil, fmt.Errorf("failed to list keys: %w", client.UnexpectedStatus(statusCode))
+	}
+
+	var response []struct {
+		Key       string     `json:"key"`
+		Value     string     `json:"value"`
+		CreatedAt time.Time  `json:"createdAt"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+	}
+	if err := json.Unmarshal([]byte(respBody), &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal keys: %v", err)
+	}
+
+	page := &KVPage{Entries: []KVEntry{}}
+	for _, item := range response {
+		// Guard against control planes that match the prefix as a LIKE pattern
+		if !strings.HasPrefix(item.Key, prefix) {
+			continue
+		}
+		entry := KVEntry{Key: item.Key, Value: item.Value, CreatedAt: item.CreatedAt}
+		if item.ExpiresAt != nil {
+			entry.ExpiresAt = *item.ExpiresAt
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+	if len(response) == limit {
+		page.NextCursor = response[len(response)-1].Key
+	}
+	return page, nil
+}
+
+func (s *MemoryStore) List(prefix string, options KVListOptions) (*KVPage, error) {
+	limit := options.Limit
+	if limit <= 0 {
+		limit = DefaultKVListLimit
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := []string{}
+	for key := range s.values {
+		if strings.HasPrefix(key, prefix) && key > options.After && s.live(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	page := &KVPage{Entries: []KVEntry{}}
+	for _, key := range keys {
+		if len(page.Entries) == limit {
+			page.NextCursor = page.Entries[limit-1].Key
+			break
+		}
+		page.Entries = append(page.Entries, KVEntry{Key: key, Value: s.values[key], ExpiresAt: s.expiries[key]})
+	}
+	return page, nil
+}
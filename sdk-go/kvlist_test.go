@@ -0,0 +1,87 @@
+package inferable
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreList(t *testing.T) {
+	store := NewMemoryStore()
+	for _, key := range []string{"exec-1_memo_c", "exec-1_memo_a", "exec-1_memo_b", "exec-10_memo_a", "other"} {
+		store.Set(key, key)
+	}
+
+	i := newTestClient(t, InferableOptions{KVStore: store}, nil)
+
+	page, err := i.KV.List("exec-1_", KVListOptions{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec-1_memo_a", "exec-1_memo_b"}, entryKeys(page.Entries))
+	assert.Equal(t, "exec-1_memo_b", page.NextCursor)
+
+	page, err = i.KV.List("exec-1_", KVListOptions{Limit: 2, After: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"exec-1_memo_c"}, entryKeys(page.Entries))
+	assert.Empty(t, page.NextCursor)
+
+	all, err := i.KV.ListAll("exec-1")
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+
+	_, err = newTestClient(t, InferableOptions{KVStore: unlistedStore{store}}, nil).KV.List("exec-1_")
+	assert.ErrorContains(t, err, "does not implement KVLister")
+}
+
+func TestClusterKVList(t *testing.T) {
+	queries := []map[string]string{}
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/keys": func(w http.ResponseWriter, r *http.Request) {
+			query := map[string]string{}
+			for key := range r.URL.Query() {
+				query[key] = r.URL.Query().Get(key)
+			}
+			queries = append(queries, query)
+
+			if query["after"] == "" {
+				json.NewEncoder(w).Encode([]map[string]interface{}{
+					{"key": "tenant_lock_a", "value": "1", "createdAt": "2025-01-01T00:00:00Z", "expiresAt": "2025-01-02T00:00:00Z"},
+					// Matched by an older control plane treating "_" as a wildcard
+					{"key": "tenant1lock2b", "value": "2", "createdAt": "2025-01-01T00:00:00Z", "expiresAt": nil},
+				})
+				return
+			}
+			json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"key": "tenant_lock_c", "value": "3", "createdAt": "2025-01-01T00:00:00Z", "expiresAt": nil},
+			})
+		},
+	})
+
+	page, err := i.KV.List("tenant_lock_", KVListOptions{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant_lock_a"}, entryKeys(page.Entries))
+	assert.Equal(t, 2025, page.Entries[0].ExpiresAt.Year())
+
+	// The next page starts after the last key the cluster returned
+	assert.Equal(t, "tenant1lock2b", page.NextCursor)
+	page, err = i.KV.List("tenant_lock_", KVListOptions{Limit: 2, After: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tenant_lock_c"}, entryKeys(page.Entries))
+	assert.True(t, page.Entries[0].ExpiresAt.IsZero())
+	assert.Empty(t, page.NextCursor)
+
+	assert.Equal(t, []map[string]string{
+		{"prefix": "tenant_lock_", "limit": "2"},
+		{"prefix": "tenant_lock_", "limit": "2", "after": "tenant1lock2b"},
+	}, queries)
+}
+
+func entryKeys(entries []KVEntry) []string {
+	keys := []string{}
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
@@ -0,0 +1,185 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+// ErrVersionConflict is returned by SetIfVersion when the key is no longer at the expected version.
+var ErrVersionConflict = fmt.Errorf("key was modified concurrently")
+
+// maxUpdateAttempts bounds the retries of KV.Update under contention.
+const maxUpdateAttempts = 10
+
+// VersionedKVStore is a KVStore with version-checked writes, for counters and state machines
+// shared between executions. Every write to a key moves its version on by one, and absent keys
+// are at version 0. The cluster store and MemoryStore implement it.
+type VersionedKVStore interface {
+	KVStore
+	// GetVersioned returns the value stored under key and its version, 0 if there is none.
+	GetVersioned(key string) (string, int, error)
+	// SetIfVersion stores value under key if the key is at version, and returns the new version.
+	// It returns ErrVersionConflict if the key is at another version.
+	SetIfVersion(key string, value string, version int) (int, error)
+}
+
+func (s *clusterKVStore) GetVersioned(key string) (string, int, error) {
+	respBody, _, err, statusCode := s.inferable.client.FetchData(client.FetchDataOptions{
+		Path:       fmt.Sprintf("/clusters/%s/keys/%s/value", s.inferable.clusterID, key),
+		Method:     "GET",
+		Context:    s.ctx,
+		Idempotent: true,
+	})
+	if statusCode == 404 {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get key: %w", err)
+	}
+
+	var kvResponse struct {
+		Value   string `json:"value"`
+		Version *int   `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(respBody), &kvResponse); err != nil {
+		return "", 0, fmt.Errorf("failed to unmarshal key value: %v", err)
+	}
+	if kvResponse.Value == "" {
+		return "", 0, nil
+	}
+	if kvResponse.Version == nil {
+		return "", 0, fmt.Errorf("the control plane does not report key versions")
+	}
+
+	return kvResponse.Value, *kvResponse.Version, nil
+}
+
+func (s *clusterKVStore) SetIfVersion(key string, value string, version int) (int, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"value":      value,
+		"onConflict": "doNothing",
+		"ifVersion":  version,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	respBody, _, err, statusCode := s.inferable.client.FetchData(client.FetchDataOptions{
+		Path:    fmt.Sprintf("/clusters/%s/keys/%s", s.inferable.clusterID, key),
+		Method:  "PUT",
+		Context: s.ctx,
+		Body:    string(body),
+	})
+	if statusCode == 409 {
+		return 0, ErrVersionConflict
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to set key: %w", err)
+	}
+
+	var kvResponse struct {
+		Version *int `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(respBody), &kvResponse); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal key value: %v", err)
+	}
+	if kvResponse.Version == nil {
+		return 0, fmt.Errorf("the control plane does not support versioned writes")
+	}
+
+	return *kvResponse.Version, nil
+}
+
+func (s *MemoryStore) GetVersioned(key string) (string, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.live(key) {
+		return "", 0, nil
+	}
+	return s.values[key], s.versions[key], nil
+}
+
+func (s *MemoryStore) SetIfVersion(key string, value string, version int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := 0
+	if s.live(key) {
+		current = s.versions[key]
+	}
+	if current != version {
+		s.writes = append(s.writes, KVWrite{Key: key, Value: value, Stored: false})
+		return 0, ErrVersionConflict
+	}
+
+	s.store(key, value, version+1, time.Time{})
+	s.writes = append(s.writes, KVWrite{Key: key, Value: value, Stored: true})
+	return version + 1, nil
+}
+
+// versioned returns the client's store if it supports version-checked writes.
+func (k *KV) versioned() (VersionedKVStore, error) {
+	store, err := k.store()
+	if err != nil {
+		return nil, err
+	}
+	versioned, ok := store.(VersionedKVStore)
+	if !ok {
+		return nil, fmt.Errorf("the KVStore does not implement VersionedKVStore")
+	}
+	return versioned, nil
+}
+
+// GetVersioned returns the value stored under key and its version, 0 if there is none.
+func (k *KV) GetVersioned(key string) (string, int, error) {
+	store, err := k.versioned()
+	if err != nil {
+		return "", 0, err
+	}
+	return store.GetVersioned(key)
+}
+
+// SetIfVersion stores value under key if the key is at version (0 for a key that must not exist
+// yet), and returns the new version. It returns ErrVersionConflict if the key is at another version.
+func (k *KV) SetIfVersion(key string, value string, version int) (int, error) {
+	store, err := k.versioned()
+	if err != nil {
+		return 0, err
+	}
+	return store.SetIfVersion(key, value, version)
+}
+
+// Update applies fn to the current value of key and stores the result, retrying with the new
+// value when a concurrent writer got there first. fn receives "" and false for an absent key.
+//
+//	_, err := client.KV.Update("tenant-42_count", func(current string, ok bool) (string, error) {
+//		n, _ := strconv.Atoi(current)
+//		return strconv.Itoa(n + 1), nil
+//	})
+func (k *KV) Update(key string, fn func(current string, ok bool) (string, error)) (string, error) {
+	store, err := k.versioned()
+	if err != nil {
+		return "", err
+	}
+
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		current, version, err := store.GetVersioned(key)
+		if err != nil {
+			return "", err
+		}
+		next, err := fn(current, version > 0)
+		if err != nil {
+			return "", err
+		}
+		if _, err := store.SetIfVersion(key, next, version); err == nil {
+			return next, nil
+		} else if err != ErrVersionConflict {
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("failed to update %s after %d attempts: %v", key, maxUpdateAttempts, ErrVersionConflict)
+}
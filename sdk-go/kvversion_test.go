@@ -0,0 +1,99 @@
+package inferable
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreVersions(t *testing.T) {
+	store := NewMemoryStore()
+
+	version, err := store.SetIfVersion("state", "open", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	_, err = store.SetIfVersion("state", "closed", 0)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	version, err = store.SetIfVersion("state", "pending", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+
+	store.Set("state", "closed")
+	value, version, err := store.GetVersioned("state")
+	require.NoError(t, err)
+	assert.Equal(t, "closed", value)
+	assert.Equal(t, 3, version)
+
+	_, version, _ = store.GetVersioned("missing")
+	assert.Equal(t, 0, version)
+}
+
+func TestKVUpdate(t *testing.T) {
+	i := newTestClient(t, InferableOptions{KVStore: NewMemoryStore()}, nil)
+	_, err := i.getClusterId()
+	require.NoError(t, err)
+
+	increment := func(current string, ok bool) (string, error) {
+		n, _ := strconv.Atoi(current)
+		return strconv.Itoa(n + 1), nil
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 5; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := i.KV.Update("count", increment)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	value, version, err := i.KV.GetVersioned("count")
+	require.NoError(t, err)
+	assert.Equal(t, "5", value)
+	assert.Equal(t, 5, version)
+
+	_, err = newTestClient(t, InferableOptions{KVStore: unlistedStore{NewMemoryStore()}}, nil).KV.Update("count", increment)
+	assert.ErrorContains(t, err, "does not implement VersionedKVStore")
+}
+
+func TestClusterKVSetIfVersion(t *testing.T) {
+	var body map[string]interface{}
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"GET /clusters/test-cluster/keys/": func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]interface{}{"value": "open", "version": 4})
+		},
+		"/clusters/test-cluster/keys/": func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			json.Unmarshal(data, &body)
+			if body["ifVersion"] != float64(4) {
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]interface{}{"message": "Key is no longer at version"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"value": body["value"], "version": 5})
+		},
+	})
+
+	value, version, err := i.KV.GetVersioned("state")
+	require.NoError(t, err)
+	assert.Equal(t, "open", value)
+	assert.Equal(t, 4, version)
+
+	version, err = i.KV.SetIfVersion("state", "closed", 4)
+	require.NoError(t, err)
+	assert.Equal(t, 5, version)
+	assert.Equal(t, "closed", body["value"])
+
+	_, err = i.KV.SetIfVersion("state", "closed", 3)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+}
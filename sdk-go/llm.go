@@ -0,0 +1,395 @@
+package inferable
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrLLMTimeout is returned when an LLM call does not complete before its timeout or context deadline.
+// Handlers can check for it with errors.Is to fall back or interrupt gracefully.
+var ErrLLMTimeout = errors.New("LLM call timed out")
+
+// WithContext returns a copy of the LLM whose calls are bound to ctx.
+// Cancelling ctx aborts in-flight calls, and a ctx deadline fails them with ErrLLMTimeout.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//
+//	result, err := wctx.LLM.WithContext(ctx).Structured(input)
+//	if errors.Is(err, ErrLLMTimeout) {
+//		// Fall back or interrupt
+//	}
+func (l *LLM) WithContext(ctx context.Context) LLMClient {
+	bound := *l
+	bound.ctx = ctx
+	return &bound
+}
+
+// GenerationOptions tunes how the LLM generates a response.
+// Fields left unset fall back to the provider defaults. The options are only supported with
+// the default provider: calls that combine them with a custom Provider fail.
+//
+//	temperature := 0.0
+//	result, err := ctx.LLM.Structured(StructuredInput{
+//		Input:  "Extract the total from this invoice",
+//		Schema: Invoice{},
+//		Generation: &GenerationOptions{
+//			Temperature: &temperature,
+//			MaxTokens:   512,
+//		},
+//	})
+type GenerationOptions struct {
+	// Temperature controls randomness, from 0 (deterministic) to 2.
+	Temperature *float64 `json:"temperature,omitempty"`
+	// TopP restricts sampling to the smallest token set whose cumulative probability exceeds TopP.
+	TopP *float64 `json:"topP,omitempty"`
+	// MaxTokens caps the number of tokens in the response.
+	MaxTokens int `json:"maxTokens,omitempty"`
+	// StopSequences stops generation when any of the sequences is produced.
+	StopSequences []string `json:"stopSequences,omitempty"`
+	// Seed requests deterministic sampling from providers that support it.
+	Seed *int64 `json:"seed,omitempty"`
+}
+
+// validate checks that the generation options are within the ranges accepted by providers.
+func (g *GenerationOptions) validate() error {
+	if g == nil {
+		return nil
+	}
+	if g.Temperature != nil && (*g.Temperature < 0 || *g.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2, got %v", *g.Temperature)
+	}
+	if g.TopP != nil && (*g.TopP <= 0 || *g.TopP > 1) {
+		return fmt.Errorf("topP must be greater than 0 and at most 1, got %v", *g.TopP)
+	}
+	if g.MaxTokens < 0 {
+		return fmt.Errorf("maxTokens must not be negative, got %d", g.MaxTokens)
+	}
+	return nil
+}
+
+// unionSchemaKey is the property the LLM uses to report which candidate schema matched.
+const unionSchemaKey = "schema"
+
+// StructuredUnionInput represents input for structured LLM generation against several candidate schemas.
+// The LLM classifies the input into one of the candidates and extracts the payload for it in a single call.
+type StructuredUnionInput struct {
+	// Input is the text prompt for the LLM.
+	Input string
+	// Schemas maps a candidate name (e.g. "invoice", "receipt") to the struct describing its payload.
+	Schemas map[string]interface{}
+	// Generation tunes sampling and length for this call. Nil uses the provider defaults.
+	// It is only supported with the default provider, not with a custom Provider.
+	Generation *GenerationOptions
+	// Model overrides the workflow and client default model for this call.
+	Model string
+	// Provider overrides the provider resolved for the execution for this call.
+	Provider *Provider
+	// Timeout bounds the call. When it elapses the call fails with ErrLLMTimeout.
+	Timeout time.Duration
+}
+
+// StructuredUnionResult is the result of a StructuredUnion call.
+type StructuredUnionResult struct {
+	// Schema is the name of the candidate schema that matched the input.
+	Schema string
+	// Data is the extracted payload, decoded into a value of the matched candidate's type.
+	Data interface{}
+}
+
+// StructuredUnion generates structured output that conforms to one of several candidate schemas.
+// The response indicates which schema matched and carries the payload decoded into that schema's type.
+//
+//	result, err := ctx.LLM.StructuredUnion(StructuredUnionInput{
+//		Input: documentText,
+//		Schemas: map[string]interface{}{
+//			"invoice": Invoice{},
+//			"receipt": Receipt{},
+//		},
+//	})
+//
+//	if err != nil {
+//		// Handle error
+//	}
+//
+//	switch result.Data.(type) {
+//	case Invoice:
+//		// Handle invoice
+//	case Receipt:
+//		// Handle receipt
+//	}
+func (l *LLM) StructuredUnion(input StructuredUnionInput) (*StructuredUnionResult, error) {
+	if len(input.Schemas) == 0 {
+		return nil, fmt.Errorf("at least one candidate schema is required")
+	}
+
+	if err := input.Generation.validate(); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(input.Schemas))
+	for name, schema := range input.Schemas {
+		if name == unionSchemaKey {
+			return nil, fmt.Errorf("candidate schema name '%s' is reserved", unionSchemaKey)
+		}
+		if schema == nil {
+			return nil, fmt.Errorf("candidate schema '%s' is nil", name)
+		}
+		names = append(names, name)
+	}
+
+	// Sort the names so the combined schema (and therefore the server-side cache key) is stable
+	sort.Strings(names)
+
+	properties := map[string]interface{}{
+		unionSchemaKey: map[string]interface{}{
+			"type":        "string",
+			"enum":        names,
+			"description": "The name of the schema that best describes the input",
+		},
+	}
+	for _, name := range names {
+		properties[name] = reflectStructuredSchema(input.Schemas[name])
+	}
+
+	data, err := l.structured(StructuredInput{
+		Input: input.Input,
+		Schema: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   []string{unionSchemaKey},
+		},
+		Generation: input.Generation,
+		Model:      input.Model,
+		Provider:   input.Provider,
+		Timeout:    input.Timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected structured union response type: %T", data)
+	}
+
+	matched, ok := response[unionSchemaKey].(string)
+	if !ok {
+		return nil, fmt.Errorf("structured union response did not indicate a matched schema")
+	}
+
+	candidate, ok := input.Schemas[matched]
+	if !ok {
+		return nil, fmt.Errorf("structured union response matched unknown schema '%s'", matched)
+	}
+
+	payload, ok := response[matched]
+	if !ok {
+		return nil, fmt.Errorf("structured union response is missing the payload for schema '%s'", matched)
+	}
+
+	typed, err := decodeInto(reflect.TypeOf(candidate), payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode payload for schema '%s': %v", matched, err)
+	}
+
+	return &StructuredUnionResult{
+		Schema: matched,
+		Data:   typed,
+	}, nil
+}
+
+// resolveModel returns the first non-empty model, ordered from most to least specific.
+func resolveModel(models ...string) string {
+	for _, model := range models {
+		if model != "" {
+			return model
+		}
+	}
+	return ""
+}
+
+// Provider identifies the LLM provider endpoint and credentials used for a call,
+// allowing calls to be billed against the caller's (or their customer's) own provider account.
+type Provider struct {
+	// URL is the provider API endpoint, e.g. "https://api.anthropic.com".
+	URL string
+	// Key is the provider API key.
+	Key string
+}
+
+// LLMClient is the structured generation backend exposed to handlers as ctx.LLM.
+// *LLM, which calls the cluster, is the default implementation.
+type LLMClient interface {
+	Structured(input StructuredInput) (interface{}, error)
+	StructuredUnion(input StructuredUnionInput) (*StructuredUnionResult, error)
+	StructuredBatch(inputs []StructuredInput, concurrency int) (*StructuredBatchResult, error)
+	WithContext(ctx context.Context) LLMClient
+}
+
+// LLMFactory creates the LLMClient for a workflow execution. It receives the default client for
+// the execution, which it may wrap (e.g. to record calls) or replace (e.g. with a fake in tests).
+type LLMFactory func(executionId string, defaultLLM LLMClient) LLMClient
+
+// ProviderResolver derives the provider for an execution's LLM and agent calls from its context,
+// e.g. to look up the API key of the tenant identified by ContextInput.AuthContext.
+// Returning a nil Provider uses the cluster's default provider.
+type ProviderResolver func(ctx ContextInput) (*Provider, error)
+
+// resolveProvider returns the first non-nil provider, ordered from most to least specific.
+func resolveProvider(providers ...*Provider) *Provider {
+	for _, provider := range providers {
+		if provider != nil {
+			return provider
+		}
+	}
+	return nil
+}
+
+// decodeInto converts a generic JSON value into a value of the given type by round-tripping it through JSON.
+func decodeInto(t reflect.Type, value interface{}) (interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	isPtr := t.Kind() == reflect.Ptr
+	if isPtr {
+		t = t.Elem()
+	}
+
+	target := reflect.New(t)
+	if err := json.Unmarshal(raw, target.Interface()); err != nil {
+		return nil, err
+	}
+
+	if isPtr {
+		return target.Interface(), nil
+	}
+	return target.Elem().Interface(), nil
+}
+
+// DefaultBatchItemAttempts is the number of attempts made for each item of a StructuredBatch call
+// that fails with a transient error.
+const DefaultBatchItemAttempts = 3
+
+// batchRetryDelay is the base delay between attempts of a failed batch item.
+var batchRetryDelay = 500 * time.Millisecond
+
+// StructuredBatchItem is the outcome of a single input of a StructuredBatch call.
+type StructuredBatchItem struct {
+	// Data is the structured result, or nil if the item failed.
+	Data interface{}
+	// Err is the error of the last attempt, or nil if the item succeeded.
+	Err error
+	// Attempts is the number of calls made for the item.
+	Attempts int
+}
+
+// BatchUsage aggregates the usage of a StructuredBatch call.
+type BatchUsage struct {
+	// Succeeded is the number of items that produced a result.
+	Succeeded int
+	// Failed is the number of items that failed after all attempts.
+	Failed int
+	// Attempts is the total number of LLM calls made, including retries.
+	Attempts int
+	// EstimatedInputTokens is the estimated number of prompt tokens sent, including retries.
+	EstimatedInputTokens int
+	// Duration is the wall-clock time taken by the batch.
+	Duration time.Duration
+}
+
+// StructuredBatchResult is the result of a StructuredBatch call.
+type StructuredBatchResult struct {
+	// Items holds one entry per input, in input order.
+	Items []StructuredBatchItem
+	// Usage aggregates attempts and token usage across all items.
+	Usage BatchUsage
+}
+
+// StructuredBatch runs Structured for each input with at most concurrency calls in flight.
+// Items that fail with a transient error are retried up to DefaultBatchItemAttempts times;
+// schema validation, context window, and cancellation errors are not retried.
+// A failing item does not fail the batch: check each item's Err.
+//
+//	batch, err := ctx.LLM.StructuredBatch(inputs, 8)
+//	if err != nil {
+//		// Handle error
+//	}
+//
+//	for i, item := range batch.Items {
+//		if item.Err != nil {
+//			// Handle failure of inputs[i]
+//		}
+//	}
+func (l *LLM) StructuredBatch(inputs []StructuredInput, concurrency int) (*StructuredBatchResult, error) {
+	if concurrency <= 0 {
+		return nil, fmt.Errorf("concurrency must be positive, got %d", concurrency)
+	}
+
+	start := time.Now()
+	items := make([]StructuredBatchItem, len(inputs))
+	tokens := make([]int, len(inputs))
+
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range inputs {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			model := resolveModel(inputs[i].Model, l.model, DefaultModel)
+			for attempt := 1; attempt <= DefaultBatchItemAttempts; attempt++ {
+				if attempt > 1 {
+					l.events.publish(Event{Type: EventRetry, ExecutionID: l.executionId, Operation: "structuredBatch", Attempt: attempt, Err: items[i].Err})
+					time.Sleep(time.Duration(attempt-1) * batchRetryDelay)
+				}
+
+				items[i].Attempts = attempt
+				tokens[i] += CountTokens(model, inputs[i].Input)
+
+				items[i].Data, items[i].Err = l.Structured(inputs[i])
+				if items[i].Err == nil || !isRetryableLLMError(items[i].Err) {
+					break
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	result := &StructuredBatchResult{Items: items}
+	for i, item := range items {
+		if item.Err == nil {
+			result.Usage.Succeeded++
+		} else {
+			result.Usage.Failed++
+		}
+		result.Usage.Attempts += item.Attempts
+		result.Usage.EstimatedInputTokens += tokens[i]
+	}
+	result.Usage.Duration = time.Since(start)
+
+	return result, nil
+}
+
+// isRetryableLLMError reports whether an LLM call error may succeed if the call is repeated unchanged.
+func isRetryableLLMError(err error) bool {
+	var validationErr *SchemaValidationError
+	switch {
+	case errors.As(err, &validationErr),
+		errors.Is(err, ErrContextWindowExceeded),
+		errors.Is(err, context.Canceled):
+		return false
+	}
+	return true
+}
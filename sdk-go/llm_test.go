@@ -0,0 +1,424 @@
+package inferable
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+// newTestLLM creates an LLM backed by a test server that answers structured calls with the given handler.
+func newTestLLM(t *testing.T, handler func(body map[string]interface{}, r *http.Request) (int, interface{})) *LLM {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		body := map[string]interface{}{}
+		_ = json.Unmarshal(raw, &body)
+
+		status, response := handler(body, r)
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := client.NewClient(client.ClientOptions{Endpoint: server.URL, Secret: "test-secret"})
+	require.NoError(t, err)
+
+	return &LLM{
+		client:      c,
+		apiSecret:   "test-secret",
+		clusterId:   "test-cluster",
+		executionId: "test-execution",
+	}
+}
+
+type testInvoice struct {
+	InvoiceNumber string  `json:"invoiceNumber"`
+	Total         float64 `json:"total"`
+}
+
+type testReceipt struct {
+	Merchant string `json:"merchant"`
+}
+
+func TestStructuredUnion(t *testing.T) {
+	var requestSchema map[string]interface{}
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		assert.Equal(t, "/clusters/test-cluster/l1m/structured", r.URL.Path)
+		requestSchema = body["schema"].(map[string]interface{})
+		return 200, map[string]interface{}{
+			"data": map[string]interface{}{
+				"schema":  "invoice",
+				"invoice": map[string]interface{}{"invoiceNumber": "INV-1", "total": 42.5},
+			},
+		}
+	})
+
+	result, err := llm.StructuredUnion(StructuredUnionInput{
+		Input: "Invoice INV-1, total $42.50",
+		Schemas: map[string]interface{}{
+			"invoice": testInvoice{},
+			"receipt": testReceipt{},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "invoice", result.Schema)
+	assert.Equal(t, testInvoice{InvoiceNumber: "INV-1", Total: 42.5}, result.Data)
+
+	properties := requestSchema["properties"].(map[string]interface{})
+	assert.Contains(t, properties, "invoice")
+	assert.Contains(t, properties, "receipt")
+	assert.Equal(t, []interface{}{"invoice", "receipt"}, properties["schema"].(map[string]interface{})["enum"])
+}
+
+func TestStructuredUnionValidation(t *testing.T) {
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		return 200, map[string]interface{}{
+			"data": map[string]interface{}{"schema": "statement"},
+		}
+	})
+
+	_, err := llm.StructuredUnion(StructuredUnionInput{Input: "test"})
+	assert.Error(t, err)
+
+	_, err = llm.StructuredUnion(StructuredUnionInput{
+		Input:   "test",
+		Schemas: map[string]interface{}{"schema": testReceipt{}},
+	})
+	assert.Error(t, err)
+
+	// The LLM reports a schema that was not a candidate
+	_, err = llm.StructuredUnion(StructuredUnionInput{
+		Input:   "test",
+		Schemas: map[string]interface{}{"receipt": testReceipt{}},
+	})
+	assert.Error(t, err)
+}
+
+func TestStructuredGenerationOptions(t *testing.T) {
+	var generation map[string]interface{}
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		generation, _ = body["generation"].(map[string]interface{})
+		return 200, map[string]interface{}{"data": map[string]interface{}{"merchant": "ACME"}}
+	})
+
+	temperature := 0.0
+	seed := int64(7)
+	_, err := llm.Structured(StructuredInput{
+		Input:  "test",
+		Schema: testReceipt{},
+		Generation: &GenerationOptions{
+			Temperature:   &temperature,
+			MaxTokens:     256,
+			StopSequences: []string{"END"},
+			Seed:          &seed,
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"temperature":   0.0,
+		"maxTokens":     256.0,
+		"stopSequences": []interface{}{"END"},
+		"seed":          7.0,
+	}, generation)
+
+	// Omitting the options leaves the provider defaults untouched
+	_, err = llm.Structured(StructuredInput{Input: "test", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Nil(t, generation)
+
+	invalid := 3.0
+	_, err = llm.Structured(StructuredInput{
+		Input:      "test",
+		Schema:     testReceipt{},
+		Generation: &GenerationOptions{Temperature: &invalid},
+	})
+	assert.Error(t, err)
+
+	// Custom providers are called without the options, so combining them fails
+	_, err = llm.Structured(StructuredInput{
+		Input:      "test",
+		Schema:     testReceipt{},
+		Generation: &GenerationOptions{Temperature: &temperature},
+		Provider:   &Provider{URL: "https://api.openai.com", Key: "call-key"},
+	})
+	assert.ErrorContains(t, err, "only supported with the default provider")
+}
+
+func TestStructuredModelResolution(t *testing.T) {
+	var model string
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		model = r.Header.Get("X-Provider-Model")
+		return 200, map[string]interface{}{"data": map[string]interface{}{"merchant": "ACME"}}
+	})
+
+	_, err := llm.Structured(StructuredInput{Input: "test", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultModel, model)
+
+	llm.model = "claude-3-5-haiku"
+	_, err = llm.Structured(StructuredInput{Input: "test", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Equal(t, "claude-3-5-haiku", model)
+
+	_, err = llm.Structured(StructuredInput{Input: "test", Schema: testReceipt{}, Model: "claude-3-7-sonnet"})
+	require.NoError(t, err)
+	assert.Equal(t, "claude-3-7-sonnet", model)
+}
+
+func TestStructuredProvider(t *testing.T) {
+	var url, key string
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		url = r.Header.Get("X-Provider-Url")
+		key = r.Header.Get("X-Provider-Key")
+		return 200, map[string]interface{}{"data": map[string]interface{}{"merchant": "ACME"}}
+	})
+
+	_, err := llm.Structured(StructuredInput{Input: "test", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Empty(t, url)
+	assert.Empty(t, key)
+
+	// The provider resolved for the execution (e.g. per tenant) is used by default
+	llm.provider = &Provider{URL: "https://api.anthropic.com", Key: "tenant-key"}
+	_, err = llm.Structured(StructuredInput{Input: "test", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.anthropic.com", url)
+	assert.Equal(t, "tenant-key", key)
+
+	_, err = llm.Structured(StructuredInput{
+		Input:    "test",
+		Schema:   testReceipt{},
+		Provider: &Provider{URL: "https://api.openai.com", Key: "call-key"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.openai.com", url)
+	assert.Equal(t, "call-key", key)
+}
+
+func TestStructuredTimeout(t *testing.T) {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+		return 200, map[string]interface{}{"data": map[string]interface{}{"merchant": "ACME"}}
+	})
+
+	_, err := llm.Structured(StructuredInput{
+		Input:   "test",
+		Schema:  testReceipt{},
+		Timeout: 50 * time.Millisecond,
+	})
+	assert.ErrorIs(t, err, ErrLLMTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = llm.WithContext(ctx).Structured(StructuredInput{Input: "test", Schema: testReceipt{}})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestStructuredValidationRetry(t *testing.T) {
+	var prompts []string
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		prompts = append(prompts, body["input"].(string))
+		if len(prompts) == 1 {
+			return 200, map[string]interface{}{"data": map[string]interface{}{"invoiceNumber": 12, "total": "42"}}
+		}
+		return 200, map[string]interface{}{"data": map[string]interface{}{"invoiceNumber": "INV-12", "total": 42}}
+	})
+
+	result, err := llm.Structured(StructuredInput{Input: "Extract the invoice", Schema: testInvoice{}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"invoiceNumber": "INV-12", "total": 42.0}, result)
+
+	require.Len(t, prompts, 2)
+	assert.Equal(t, "Extract the invoice", prompts[0])
+	assert.Contains(t, prompts[1], "$.invoiceNumber: expected string, got number")
+	assert.Contains(t, prompts[1], "$.total: expected number, got string")
+}
+
+func TestStructuredValidationExhausted(t *testing.T) {
+	calls := 0
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		calls++
+		return 200, map[string]interface{}{"data": map[string]interface{}{"total": 42}}
+	})
+
+	_, err := llm.Structured(StructuredInput{Input: "test", Schema: testInvoice{}, ValidationAttempts: 2})
+
+	var validationErr *SchemaValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, []string{"$.invoiceNumber: is required"}, validationErr.Errors)
+	assert.Equal(t, 2, calls)
+}
+
+func TestStructuredBatch(t *testing.T) {
+	batchRetryDelay = time.Millisecond
+
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	inFlight, maxInFlight := 0, 0
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		input := body["input"].(string)
+
+		mu.Lock()
+		attempts[input]++
+		attempt := attempts[input]
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+
+		switch {
+		case input == "flaky" && attempt == 1:
+			return 500, map[string]interface{}{"message": "internal error"}
+		case input == "broken":
+			return 500, map[string]interface{}{"message": "internal error"}
+		}
+		return 200, map[string]interface{}{"data": map[string]interface{}{"merchant": input}}
+	})
+
+	inputs := []StructuredInput{}
+	for _, input := range []string{"a", "b", "flaky", "c", "broken", "d"} {
+		inputs = append(inputs, StructuredInput{Input: input, Schema: testReceipt{}})
+	}
+
+	batch, err := llm.StructuredBatch(inputs, 2)
+	require.NoError(t, err)
+	require.Len(t, batch.Items, 6)
+
+	assert.Equal(t, map[string]interface{}{"merchant": "a"}, batch.Items[0].Data)
+	assert.Equal(t, map[string]interface{}{"merchant": "flaky"}, batch.Items[2].Data)
+	assert.Equal(t, 2, batch.Items[2].Attempts)
+	assert.Error(t, batch.Items[4].Err)
+	assert.Equal(t, DefaultBatchItemAttempts, batch.Items[4].Attempts)
+
+	assert.Equal(t, 5, batch.Usage.Succeeded)
+	assert.Equal(t, 1, batch.Usage.Failed)
+	assert.Equal(t, 4+2+DefaultBatchItemAttempts, batch.Usage.Attempts)
+	assert.LessOrEqual(t, maxInFlight, 2)
+
+	_, err = llm.StructuredBatch(inputs, 0)
+	assert.Error(t, err)
+}
+
+// newTestClient creates a client against a test server that registers the machine in
+// "test-cluster" and serves routes, keyed by http.ServeMux pattern, answering 404 otherwise.
+// Routes can replace the "/machines" registration.
+func newTestClient(t *testing.T, options InferableOptions, routes map[string]http.HandlerFunc) *Inferable {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	if _, ok := routes["/machines"]; !ok {
+		mux.HandleFunc("/machines", func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"clusterId": "test-cluster"})
+		})
+	}
+	for pattern, handler := range routes {
+		mux.HandleFunc(pattern, handler)
+	}
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	options.APIEndpoint = server.URL
+	options.APISecret = "test-secret"
+	i, err := New(options)
+	require.NoError(t, err)
+
+	return i
+}
+
+type fakeLLM struct {
+	LLMClient
+	inputs []string
+}
+
+func (f *fakeLLM) Structured(input StructuredInput) (interface{}, error) {
+	f.inputs = append(f.inputs, input.Input)
+	return map[string]interface{}{"merchant": "ACME"}, nil
+}
+
+type fakeAgents struct {
+	names []string
+}
+
+func (f *fakeAgents) React(config ReactAgentConfig) (interface{}, *Interrupt, error) {
+	f.names = append(f.names, config.Name)
+	return "done", nil, nil
+}
+
+func TestBackendFactories(t *testing.T) {
+	clientLLM := &fakeLLM{}
+	workflowLLM := &fakeLLM{}
+	agents := &fakeAgents{}
+
+	var defaultLLM LLMClient
+	var executionIds []string
+	i := newTestClient(t, InferableOptions{
+		LLMFactory: func(executionId string, llm LLMClient) LLMClient {
+			defaultLLM = llm
+			return clientLLM
+		},
+		AgentRunnerFactory: func(executionId string, runner AgentRunner) AgentRunner {
+			executionIds = append(executionIds, executionId)
+			return agents
+		},
+	}, nil)
+
+	handler := func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		if _, err := ctx.LLM.Structured(StructuredInput{Input: "receipt", Schema: testReceipt{}}); err != nil {
+			return nil, err
+		}
+		result, _, err := ctx.Agents.React(ReactAgentConfig{Name: "checker"})
+		return result, err
+	}
+
+	receipts := i.Workflows.Create(WorkflowConfig{Name: "receipts", InputSchema: WorkflowInput{}})
+	receipts.Version(1).Define(handler)
+
+	// A workflow factory takes precedence over the client's
+	invoices := i.Workflows.Create(WorkflowConfig{
+		Name:        "invoices",
+		InputSchema: WorkflowInput{},
+		LLMFactory: func(executionId string, llm LLMClient) LLMClient {
+			return workflowLLM
+		},
+	})
+	invoices.Version(1).Define(handler)
+
+	result, err := receipts.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "done", result)
+
+	_, err = invoices.Execute(1, map[string]interface{}{"executionId": "exec-2"}, ContextInput{})
+	require.NoError(t, err)
+
+	assert.IsType(t, &LLM{}, defaultLLM)
+	assert.Equal(t, []string{"receipt"}, clientLLM.inputs)
+	assert.Equal(t, []string{"receipt"}, workflowLLM.inputs)
+	assert.Equal(t, []string{"checker", "checker"}, agents.names)
+	assert.Equal(t, []string{"exec-1", "exec-2"}, executionIds)
+}
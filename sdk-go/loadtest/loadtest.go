@@ -0,0 +1,296 @@
+// Package loadtest triggers a workflow at a fixed rate and reports end-to-end latency, outcome
+// rates, and worker saturation, for capacity planning before production traffic.
+//
+//	report, err := loadtest.Run(ctx, loadtest.Options{
+//		Client:   client,
+//		Workflow: "tickets",
+//		Rate:     5,
+//		Duration: time.Minute,
+//		Input: func(i int) map[string]interface{} {
+//			return map[string]interface{}{"ticket": fmt.Sprintf("Ticket %d", i)}
+//		},
+//	})
+//	fmt.Println(report)
+package loadtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go"
+)
+
+const (
+	// DefaultTimeout is how long an execution may take before it is counted as timed out.
+	DefaultTimeout = 5 * time.Minute
+	// DefaultPollInterval is how often execution status is checked.
+	DefaultPollInterval = time.Second
+)
+
+// Options configures a load test.
+type Options struct {
+	// Client triggers the executions. If the workflow's workers run in the same process, their
+	// polling stats are included in the report.
+	Client *inferable.Inferable
+	// Workflow is the name of the workflow to trigger.
+	Workflow string
+	// Input returns the input of the i-th execution. The execution ID is added automatically.
+	// Defaults to an empty input.
+	Input func(i int) map[string]interface{}
+	// Rate is the number of executions triggered per second.
+	Rate float64
+	// Duration is how long executions are triggered for.
+	Duration time.Duration
+	// Timeout bounds how long each execution is waited on. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// PollInterval is how often execution status is checked; it bounds the latency resolution.
+	// Defaults to DefaultPollInterval.
+	PollInterval time.Duration
+	// ExecutionPrefix prefixes the execution IDs. Defaults to "loadtest-<unix time>".
+	ExecutionPrefix string
+}
+
+// LatencyStats summarizes the end-to-end latency of finished executions.
+type LatencyStats struct {
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+}
+
+// Report is the outcome of a load test.
+type Report struct {
+	Triggered     int
+	TriggerErrors int
+	Succeeded     int
+	Failed        int
+	Interrupted   int
+	TimedOut      int
+	// Latency covers succeeded and failed executions, from trigger to the observed final status.
+	Latency LatencyStats
+	// Elapsed is the wall time of the whole test, including waiting for the last executions.
+	Elapsed time.Duration
+	// Saturation is the fraction of the elapsed time the local polling loop spent handling jobs.
+	// It is zero when no workers run in this process.
+	Saturation float64
+	// SaturatedPolls is the number of local polls that returned a full batch of jobs.
+	SaturatedPolls int64
+}
+
+// FailureRate is the fraction of triggered executions that failed or timed out.
+func (r *Report) FailureRate() float64 {
+	if r.Triggered == 0 {
+		return 0
+	}
+	return float64(r.Failed+r.TimedOut) / float64(r.Triggered)
+}
+
+// InterruptRate is the fraction of triggered executions that were interrupted.
+func (r *Report) InterruptRate() float64 {
+	if r.Triggered == 0 {
+		return 0
+	}
+	return float64(r.Interrupted) / float64(r.Triggered)
+}
+
+// String formats the report for the terminal.
+func (r *Report) String() string {
+	lines := []string{
+		fmt.Sprintf("triggered:   %d (%d trigger errors) in %s", r.Triggered, r.TriggerErrors, r.Elapsed.Round(time.Millisecond)),
+		fmt.Sprintf("succeeded:   %d", r.Succeeded),
+		fmt.Sprintf("failed:      %d (%.1f%% incl. %d timed out)", r.Failed, r.FailureRate()*100, r.TimedOut),
+		fmt.Sprintf("interrupted: %d (%.1f%%)", r.Interrupted, r.InterruptRate()*100),
+		fmt.Sprintf("latency:     min %s, mean %s, p50 %s, p90 %s, p99 %s, max %s",
+			r.Latency.Min, r.Latency.Mean, r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max),
+		fmt.Sprintf("saturation:  %.1f%% busy, %d saturated polls", r.Saturation*100, r.SaturatedPolls),
+	}
+	return strings.Join(lines, "\n")
+}
+
+type outcome int
+
+const (
+	outcomeSucceeded outcome = iota
+	outcomeFailed
+	outcomeInterrupted
+	outcomeTimedOut
+	outcomeTriggerError
+)
+
+type sample struct {
+	outcome outcome
+	latency time.Duration
+}
+
+// Run triggers executions at the configured rate for the configured duration, waits for them to
+// finish, and reports the results. Cancelling ctx stops triggering and counts executions still
+// running as timed out.
+func Run(ctx context.Context, options Options) (*Report, error) {
+	if options.Client == nil {
+		return nil, fmt.Errorf("a client is required")
+	}
+	if options.Workflow == "" {
+		return nil, fmt.Errorf("a workflow name is required")
+	}
+	if options.Rate <= 0 {
+		return nil, fmt.Errorf("rate must be positive, got %v", options.Rate)
+	}
+	if options.Duration <= 0 {
+		return nil, fmt.Errorf("duration must be positive, got %v", options.Duration)
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.PollInterval <= 0 {
+		options.PollInterval = DefaultPollInterval
+	}
+	if options.ExecutionPrefix == "" {
+		options.ExecutionPrefix = fmt.Sprintf("loadtest-%d", time.Now().Unix())
+	}
+	if options.Input == nil {
+		options.Input = func(i int) map[string]interface{} { return map[string]interface{}{} }
+	}
+
+	before := options.Client.Tools.Stats()
+	start := time.Now()
+
+	var mu sync.Mutex
+	samples := []sample{}
+	record := func(s sample) {
+		mu.Lock()
+		defer mu.Unlock()
+		samples = append(samples, s)
+	}
+
+	var wg sync.WaitGroup
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / options.Rate))
+	defer ticker.Stop()
+	deadline := time.After(options.Duration)
+
+	for i := 0; ; i++ {
+		executionId := fmt.Sprintf("%s-%d", options.ExecutionPrefix, i)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			record(runExecution(ctx, options, executionId, options.Input(i)))
+		}(i)
+
+		select {
+		case <-ticker.C:
+			continue
+		case <-deadline:
+		case <-ctx.Done():
+		}
+		break
+	}
+
+	wg.Wait()
+
+	after := options.Client.Tools.Stats()
+	return buildReport(samples, time.Since(start), before, after), nil
+}
+
+// runExecution triggers one execution and polls it until it finishes or times out.
+func runExecution(ctx context.Context, options Options, executionId string, input map[string]interface{}) sample {
+	triggered := time.Now()
+	if _, err := options.Client.Workflows.Trigger(options.Workflow, executionId, input); err != nil {
+		return sample{outcome: outcomeTriggerError}
+	}
+
+	timeout := time.NewTimer(options.Timeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return sample{outcome: outcomeTimedOut}
+		case <-timeout.C:
+			return sample{outcome: outcomeTimedOut}
+		case <-ticker.C:
+		}
+
+		execution, err := options.Client.Workflows.GetExecution(options.Workflow, executionId)
+		if err != nil {
+			// The execution may not be listed yet, or the request failed transiently
+			continue
+		}
+
+		switch {
+		case execution.Interrupted():
+			return sample{outcome: outcomeInterrupted, latency: time.Since(triggered)}
+		case execution.Failed():
+			return sample{outcome: outcomeFailed, latency: time.Since(triggered)}
+		case execution.Done():
+			return sample{outcome: outcomeSucceeded, latency: time.Since(triggered)}
+		}
+	}
+}
+
+func buildReport(samples []sample, elapsed time.Duration, before inferable.PollingStats, after inferable.PollingStats) *Report {
+	report := &Report{Elapsed: elapsed}
+
+	latencies := []time.Duration{}
+	for _, s := range samples {
+		switch s.outcome {
+		case outcomeTriggerError:
+			report.TriggerErrors++
+			continue
+		case outcomeSucceeded:
+			report.Succeeded++
+			latencies = append(latencies, s.latency)
+		case outcomeFailed:
+			report.Failed++
+			latencies = append(latencies, s.latency)
+		case outcomeInterrupted:
+			report.Interrupted++
+		case outcomeTimedOut:
+			report.TimedOut++
+		}
+		report.Triggered++
+	}
+
+	report.Latency = summarizeLatencies(latencies)
+
+	if elapsed > 0 {
+		report.Saturation = float64(after.Busy-before.Busy) / float64(elapsed)
+	}
+	report.SaturatedPolls = after.SaturatedPolls - before.SaturatedPolls
+
+	return report
+}
+
+// summarizeLatencies computes the distribution of latencies using nearest-rank percentiles.
+func summarizeLatencies(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+
+	percentile := func(p float64) time.Duration {
+		rank := int(p*float64(len(latencies))+0.999999) - 1
+		return latencies[min(max(rank, 0), len(latencies)-1)]
+	}
+
+	return LatencyStats{
+		Min:  latencies[0],
+		Mean: total / time.Duration(len(latencies)),
+		P50:  percentile(0.50),
+		P90:  percentile(0.90),
+		P99:  percentile(0.99),
+		Max:  latencies[len(latencies)-1],
+	}
+}
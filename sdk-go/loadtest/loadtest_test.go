@@ -0,0 +1,143 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inferablehq/inferable/sdk-go"
+)
+
+// newFakeCluster serves executions whose outcome depends on the execution number: every fourth
+// fails, every fourth interrupts, and the rest succeed after a couple of polls.
+func newFakeCluster(t *testing.T) *inferable.Inferable {
+	var mu sync.Mutex
+	polls := map[string]int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/machines":
+			w.Write([]byte(`{"clusterId":"test-cluster"}`))
+		case r.Method == "POST" && r.URL.Path == "/clusters/test-cluster/workflows/tickets/executions":
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == "GET" && r.URL.Path == "/clusters/test-cluster/workflow-executions":
+			id := r.URL.Query().Get("workflowExecutionId")
+
+			mu.Lock()
+			polls[id]++
+			count := polls[id]
+			mu.Unlock()
+
+			status, resultType := "running", ""
+			if count >= 2 {
+				switch id[strings.LastIndex(id, "-")+1:] {
+				case "1", "5", "9":
+					status, resultType = "failure", "rejection"
+				case "2", "6":
+					status, resultType = "interrupted", "interrupt"
+				default:
+					status, resultType = "success", "resolution"
+				}
+			}
+
+			json.NewEncoder(w).Encode([]map[string]interface{}{{
+				"execution": map[string]interface{}{"id": id, "workflowName": "tickets", "workflowVersion": 1},
+				"job":       map[string]interface{}{"status": status, "resultType": resultType, "result": `{"ok":true}`},
+			}})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := inferable.New(inferable.InferableOptions{
+		APIEndpoint: server.URL,
+		APISecret:   "test-secret",
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestRun(t *testing.T) {
+	client := newFakeCluster(t)
+
+	report, err := Run(context.Background(), Options{
+		Client:          client,
+		Workflow:        "tickets",
+		Rate:            100,
+		Duration:        75 * time.Millisecond,
+		PollInterval:    5 * time.Millisecond,
+		Timeout:         time.Second,
+		ExecutionPrefix: "load",
+	})
+	require.NoError(t, err)
+
+	assert.Greater(t, report.Triggered, 4)
+	assert.Equal(t, 0, report.TriggerErrors)
+	assert.Equal(t, 0, report.TimedOut)
+	assert.Equal(t, report.Triggered, report.Succeeded+report.Failed+report.Interrupted)
+	assert.Greater(t, report.Failed, 0)
+	assert.Greater(t, report.Interrupted, 0)
+	assert.InDelta(t, float64(report.Failed)/float64(report.Triggered), report.FailureRate(), 0.0001)
+
+	assert.GreaterOrEqual(t, report.Latency.Min, 10*time.Millisecond)
+	assert.LessOrEqual(t, report.Latency.P50, report.Latency.P90)
+	assert.LessOrEqual(t, report.Latency.P99, report.Latency.Max)
+	assert.Contains(t, report.String(), "interrupted:")
+}
+
+func TestRunTimeout(t *testing.T) {
+	client := newFakeCluster(t)
+
+	report, err := Run(context.Background(), Options{
+		Client:       client,
+		Workflow:     "tickets",
+		Input:        func(i int) map[string]interface{} { return map[string]interface{}{"ticket": i} },
+		Rate:         10,
+		Duration:     time.Millisecond,
+		PollInterval: 50 * time.Millisecond,
+		Timeout:      10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, report.Triggered)
+	assert.Equal(t, 1, report.TimedOut)
+	assert.Equal(t, 1.0, report.FailureRate())
+}
+
+func TestRunValidatesOptions(t *testing.T) {
+	client := newFakeCluster(t)
+
+	_, err := Run(context.Background(), Options{Client: client, Workflow: "tickets", Duration: time.Second})
+	assert.ErrorContains(t, err, "rate must be positive")
+
+	_, err = Run(context.Background(), Options{Client: client, Rate: 1, Duration: time.Second})
+	assert.ErrorContains(t, err, "workflow name is required")
+}
+
+func TestSummarizeLatencies(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	stats := summarizeLatencies(latencies)
+	assert.Equal(t, LatencyStats{
+		Min:  time.Millisecond,
+		Mean: 50500 * time.Microsecond,
+		P50:  50 * time.Millisecond,
+		P90:  90 * time.Millisecond,
+		P99:  99 * time.Millisecond,
+		Max:  100 * time.Millisecond,
+	}, stats)
+
+	assert.Equal(t, LatencyStats{}, summarizeLatencies(nil))
+}
@@ -0,0 +1,298 @@
+package local
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+type testRow struct {
+	value     string
+	version   int64
+	createdAt int64
+	expiresAt driver.Value
+}
+
+// testSQLiteDriver keeps the store's table in memory, interpreting the statements the store
+// issues.
+type testSQLiteDriver struct {
+	mu   sync.Mutex
+	rows map[string]*testRow
+}
+
+func (d *testSQLiteDriver) Open(name string) (driver.Conn, error) {
+	return &testSQLiteConn{driver: d}, nil
+}
+
+type testSQLiteConn struct{ driver *testSQLiteDriver }
+
+func (c *testSQLiteConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *testSQLiteConn) Close() error                              { return nil }
+func (c *testSQLiteConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf("not supported") }
+
+func (c *testSQLiteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE"):
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(query, "INSERT OR IGNORE"):
+		key := args[0].Value.(string)
+		if _, ok := c.driver.rows[key]; ok {
+			return driver.RowsAffected(0), nil
+		}
+		c.driver.rows[key] = &testRow{value: args[1].Value.(string), version: args[2].Value.(int64), createdAt: args[3].Value.(int64), expiresAt: args[4].Value}
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "UPDATE"):
+		row, ok := c.driver.rows[args[4].Value.(string)]
+		if !ok || row.version != args[5].Value.(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		*row = testRow{value: args[0].Value.(string), version: args[1].Value.(int64), createdAt: args[2].Value.(int64), expiresAt: args[3].Value}
+		return driver.RowsAffected(1), nil
+	}
+	return nil, fmt.Errorf("unexpected statement: %s", query)
+}
+
+func (c *testSQLiteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+
+	rows := &testSQLiteRows{}
+	if strings.HasPrefix(query, "SELECT value, version, expires_at") {
+		rows.columns = []string{"value", "version", "expires_at"}
+		if row, ok := c.driver.rows[args[0].Value.(string)]; ok {
+			rows.values = append(rows.values, []driver.Value{row.value, row.version, row.expiresAt})
+		}
+		return rows, nil
+	}
+
+	rows.columns = []string{"key", "value", "created_at", "expires_at"}
+	after, prefix, now, limit := args[0].Value.(string), args[2].Value.(string), args[3].Value.(int64), int(args[4].Value.(int64))
+	keys := []string{}
+	for key, row := range c.driver.rows {
+		if key > after && strings.HasPrefix(key, prefix) && (row.expiresAt == nil || row.expiresAt.(int64) > now) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if len(rows.values) == limit {
+			break
+		}
+		row := c.driver.rows[key]
+		rows.values = append(rows.values, []driver.Value{key, row.value, row.createdAt, row.expiresAt})
+	}
+	return rows, nil
+}
+
+type testSQLiteRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *testSQLiteRows) Columns() []string { return r.columns }
+func (r *testSQLiteRows) Close() error      { return nil }
+
+func (r *testSQLiteRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// testConnector opens connections of a driver without registering it, as a driver name can
+// only be registered once per process.
+type testConnector struct{ driver driver.Driver }
+
+func (c testConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c testConnector) Driver() driver.Driver                        { return c.driver }
+
+func openTestStore(t *testing.T) *Store {
+	db := sql.OpenDB(testConnector{driver: &testSQLiteDriver{rows: map[string]*testRow{}}})
+	store, err := Open(db, StoreOptions{})
+	require.NoError(t, err)
+	return store
+}
+
+func TestStore(t *testing.T) {
+	store := openTestStore(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.SetIfAbsent("a_1", "first"))
+	require.NoError(t, store.SetIfAbsent("a_1", "second"))
+	value, ok, err := store.Get("a_1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "first", value)
+
+	// Expired entries are absent and can be set again
+	require.NoError(t, store.SetIfAbsentUntil("a_2", "lease", now.Add(time.Minute)))
+	now = now.Add(2 * time.Minute)
+	_, ok, err = store.Get("a_2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.NoError(t, store.SetIfAbsent("a_2", "renewed"))
+	value, _, _ = store.Get("a_2")
+	assert.Equal(t, "renewed", value)
+
+	version, err := store.SetIfVersion("counter", "1", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+	_, err = store.SetIfVersion("counter", "1", 0)
+	assert.ErrorIs(t, err, inferable.ErrVersionConflict)
+	_, err = store.SetIfVersion("counter", "2", 1)
+	require.NoError(t, err)
+	value, version, err = store.GetVersioned("counter")
+	require.NoError(t, err)
+	assert.Equal(t, "2", value)
+	assert.Equal(t, 2, version)
+
+	page, err := store.List("a_", inferable.KVListOptions{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	assert.Equal(t, "a_1", page.Entries[0].Key)
+	page, err = store.List("a_", inferable.KVListOptions{Limit: 1, After: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, "a_2", page.Entries[0].Key)
+}
+
+type refundInput struct {
+	ExecutionID string `json:"executionId"`
+	Amount      int    `json:"amount"`
+}
+
+// newTestWorkflow creates an offline client and a refund workflow on store, as a new process
+// would. The refund is looked up once per execution, then waits for approval.
+func newTestWorkflow(t *testing.T, store *Store, lookups *int, interrupt *inferable.Interrupt) *inferable.Workflow {
+	client, err := inferable.New(inferable.InferableOptions{KVStore: store, Offline: true})
+	require.NoError(t, err)
+
+	workflow := client.Workflows.Create(inferable.WorkflowConfig{Name: "refunds", InputSchema: refundInput{}})
+	workflow.Version(1).Define(func(ctx inferable.WorkflowContext, input refundInput) (interface{}, error) {
+		amount, err := ctx.Memo("lookup", func() (interface{}, error) {
+			*lookups++
+			return input.Amount, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ctx.InterruptTimedOut {
+			return map[string]interface{}{"refunded": amount, "escalated": true}, nil
+		}
+		if !ctx.Approved {
+			return interrupt, nil
+		}
+		if ctx.ApprovalOption == "partial" {
+			return map[string]interface{}{"refunded": amount.(float64) / 2}, nil
+		}
+		return map[string]interface{}{"refunded": amount}, nil
+	})
+	return workflow
+}
+
+func TestRunnerSurvivesRestarts(t *testing.T) {
+	store := openTestStore(t)
+	lookups := 0
+	interrupt := inferable.ApprovalInterrupt("Refund?")
+
+	runner := NewRunner(store, newTestWorkflow(t, store, &lookups, interrupt))
+	execution, err := runner.Trigger("refunds", "refund-1", 1, map[string]interface{}{"amount": 100})
+	require.NoError(t, err)
+	assert.Equal(t, StatusInterrupted, execution.Status)
+	assert.Equal(t, "Refund?", execution.Interrupt.Message)
+
+	// Triggering again returns the existing execution
+	again, err := runner.Trigger("refunds", "refund-1", 1, map[string]interface{}{"amount": 500})
+	require.NoError(t, err)
+	assert.Equal(t, 1, again.Runs)
+
+	// A new process resumes the interrupted execution, replaying the memoized lookup
+	runner = NewRunner(store, newTestWorkflow(t, store, &lookups, interrupt))
+	execution, err = runner.Resume("refund-1", ResumeOptions{Approved: true, ApprovalOption: "partial"})
+	require.NoError(t, err)
+	assert.Equal(t, StatusDone, execution.Status)
+	assert.Equal(t, 2, execution.Runs)
+	assert.Equal(t, 1, lookups)
+
+	var result map[string]interface{}
+	require.NoError(t, execution.Decode(&result))
+	assert.Equal(t, map[string]interface{}{"refunded": 50.0}, result)
+
+	_, err = runner.Resume("refund-1", ResumeOptions{Approved: true})
+	assert.ErrorContains(t, err, "is done, not interrupted")
+}
+
+func TestRunnerRecover(t *testing.T) {
+	store := openTestStore(t)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	lookups := 0
+
+	runner := NewRunner(store,
+		newTestWorkflow(t, store, &lookups, inferable.ApprovalInterrupt("Refund?").WithTimeout(time.Hour, inferable.InterruptTimeoutResume)))
+	_, err := runner.Trigger("refunds", "refund-1", 1, map[string]interface{}{"amount": 100})
+	require.NoError(t, err)
+
+	// An execution whose process died while its handler was running
+	crashed := &Execution{ID: "refund-2", Workflow: "refunds", Version: 1, Input: json.RawMessage(`{"executionId":"refund-2","amount":30}`), Status: StatusRunning, Runs: 1}
+	require.NoError(t, runner.save(crashed))
+
+	runner = NewRunner(store,
+		newTestWorkflow(t, store, &lookups, inferable.ApprovalInterrupt("Refund?").WithTimeout(time.Hour, inferable.InterruptTimeoutResume)))
+	recovered, err := runner.Recover()
+	require.NoError(t, err)
+	require.Len(t, recovered, 1)
+	assert.Equal(t, "refund-2", recovered[0].ID)
+	assert.Equal(t, StatusInterrupted, recovered[0].Status)
+	assert.Equal(t, 2, recovered[0].Runs)
+
+	// Interrupts past their deadline are resolved with their timeout outcome
+	now = now.Add(2 * time.Hour)
+	recovered, err = runner.Recover()
+	require.NoError(t, err)
+	require.Len(t, recovered, 2)
+
+	var result map[string]interface{}
+	require.NoError(t, recovered[0].Decode(&result))
+	assert.Equal(t, map[string]interface{}{"refunded": 100.0, "escalated": true}, result)
+	assert.Equal(t, StatusDone, recovered[1].Status)
+	assert.Equal(t, 2, lookups)
+}
+
+func TestRunnerResolveApprovals(t *testing.T) {
+	store := openTestStore(t)
+	lookups := 0
+
+	var prompts bytes.Buffer
+	runner := NewRunner(store, newTestWorkflow(t, store, &lookups, inferable.ApprovalInterrupt("Refund?"))).
+		ResolveApprovals(inferable.PromptApproval(strings.NewReader("y\nn\n"), &prompts))
+
+	execution, err := runner.Trigger("refunds", "refund-1", 1, map[string]interface{}{"amount": 100})
+	require.NoError(t, err)
+	assert.Equal(t, StatusDone, execution.Status)
+	assert.Contains(t, prompts.String(), "Approval requested by refunds (call refund-1): Refund?")
+
+	execution, err = runner.Trigger("refunds", "refund-2", 1, map[string]interface{}{"amount": 100})
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, execution.Status)
+	assert.Contains(t, execution.Error, "was denied approval")
+}
@@ -0,0 +1,342 @@
+package local
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go"
+)
+
+// Statuses of an Execution.
+const (
+	StatusRunning     = "running"
+	StatusInterrupted = "interrupted"
+	StatusDone        = "done"
+	StatusFailed      = "failed"
+)
+
+// maxApprovals bounds how often an execution runs again after its approval interrupt was resolved
+// by the Runner's resolver, for handlers that keep asking for approval.
+const maxApprovals = 5
+
+// executionPrefix prefixes the keys execution records are stored under.
+const executionPrefix = "local_execution_"
+
+// Execution is the persisted record of a workflow execution run by a Runner.
+type Execution struct {
+	ID       string          `json:"id"`
+	Workflow string          `json:"workflow"`
+	Version  int             `json:"version"`
+	Input    json.RawMessage `json:"input"`
+	Status   string          `json:"status"`
+	// Result is the JSON result of a done execution.
+	Result json.RawMessage `json:"result,omitempty"`
+	// Error is the error returned by the handler of a failed execution.
+	Error string `json:"error,omitempty"`
+	// Interrupt is the interrupt an interrupted execution waits on, and InterruptExpiresAt its
+	// deadline if it has a timeout.
+	Interrupt          *inferable.Interrupt `json:"interrupt,omitempty"`
+	InterruptExpiresAt *time.Time           `json:"interruptExpiresAt,omitempty"`
+	// Runs is the number of times the handler was started.
+	Runs      int       `json:"runs"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// version is the version of the stored record
+	version int
+}
+
+// Decode unmarshals the result of a done execution into v.
+func (e *Execution) Decode(v interface{}) error {
+	if e.Status != StatusDone {
+		return fmt.Errorf("execution %s is %s", e.ID, e.Status)
+	}
+	return json.Unmarshal(e.Result, v)
+}
+
+// ResumeOptions configures Runner.Resume.
+type ResumeOptions struct {
+	// Approved and ApprovalOption resolve an approval interrupt, and are delivered to the handler
+	// as ContextInput.Approved and ContextInput.ApprovalOption.
+	Approved       bool
+	ApprovalOption string
+}
+
+// Runner starts, resumes, and recovers workflow executions offline. An execution runs its handler
+// synchronously in the calling goroutine, and is re-executed from the top when it is resumed or
+// recovered, replaying its memoized results like an execution resumed by the cluster.
+type Runner struct {
+	store     *Store
+	workflows map[string]*inferable.Workflow
+
+	mu sync.Mutex
+	// active holds the executions whose handler is running in this process
+	active map[string]bool
+	// approvals resolves approval interrupts as they are raised, see ResolveApprovals
+	approvals inferable.ApprovalResolver
+}
+
+// NewRunner creates a Runner for workflows whose client uses store as its KVStore.
+func NewRunner(store *Store, workflows ...*inferable.Workflow) *Runner {
+	r := &Runner{store: store, workflows: map[string]*inferable.Workflow{}, active: map[string]bool{}}
+	for _, workflow := range workflows {
+		r.workflows[workflow.Name()] = workflow
+	}
+	return r
+}
+
+// ResolveApprovals resolves the approval interrupts of executions as they are raised with
+// resolver, e.g. inferable.AutoApprove or inferable.PromptApproval, instead of leaving the
+// executions interrupted until Resume is called. An approved execution runs again right away, and
+// a denied one fails.
+func (r *Runner) ResolveApprovals(resolver inferable.ApprovalResolver) *Runner {
+	r.approvals = resolver
+	return r
+}
+
+// Get returns the execution with the given ID, and false if there is none.
+func (r *Runner) Get(executionId string) (*Execution, bool, error) {
+	serialized, version, err := r.store.GetVersioned(executionPrefix + executionId)
+	if err != nil || version == 0 {
+		return nil, false, err
+	}
+	var execution Execution
+	if err := json.Unmarshal([]byte(serialized), &execution); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal execution %s: %v", executionId, err)
+	}
+	execution.version = version
+	return &execution, true, nil
+}
+
+// save stores an execution if it is unchanged since it was read.
+func (r *Runner) save(execution *Execution) error {
+	execution.UpdatedAt = r.store.now()
+	serialized, err := json.Marshal(execution)
+	if err != nil {
+		return fmt.Errorf("failed to marshal execution %s: %v", execution.ID, err)
+	}
+	version, err := r.store.SetIfVersion(executionPrefix+execution.ID, string(serialized), execution.version)
+	if err == inferable.ErrVersionConflict {
+		return fmt.Errorf("execution %s was modified concurrently", execution.ID)
+	}
+	if err != nil {
+		return err
+	}
+	execution.version = version
+	return nil
+}
+
+// Trigger starts an execution of a workflow version and runs it until it finishes or is
+// interrupted. The execution ID is the idempotency key: triggering an existing execution returns
+// it as is.
+func (r *Runner) Trigger(workflowName string, executionId string, version int, input map[string]interface{}) (*Execution, error) {
+	if _, ok := r.workflows[workflowName]; !ok {
+		return nil, fmt.Errorf("workflow %s is not registered with the runner", workflowName)
+	}
+
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	input["executionId"] = executionId
+	serialized, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %v", err)
+	}
+
+	execution := &Execution{ID: executionId, Workflow: workflowName, Version: version, Input: serialized, Status: StatusRunning}
+	if err := r.save(execution); err != nil {
+		if existing, ok, _ := r.Get(executionId); ok {
+			return existing, nil
+		}
+		return nil, err
+	}
+
+	return r.run(execution, inferable.ContextInput{})
+}
+
+// Resume resolves the interrupt of an interrupted execution and runs it again.
+func (r *Runner) Resume(executionId string, options ResumeOptions) (*Execution, error) {
+	execution, ok, err := r.Get(executionId)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("execution %s not found", executionId)
+	}
+	if execution.Status != StatusInterrupted {
+		return nil, fmt.Errorf("execution %s is %s, not interrupted", executionId, execution.Status)
+	}
+
+	return r.resume(execution, inferable.ContextInput{Approved: options.Approved, ApprovalOption: options.ApprovalOption})
+}
+
+func (r *Runner) resume(execution *Execution, contextInput inferable.ContextInput) (*Execution, error) {
+	execution.Status = StatusRunning
+	execution.Interrupt = nil
+	execution.InterruptExpiresAt = nil
+	if err := r.save(execution); err != nil {
+		return nil, err
+	}
+	return r.run(execution, contextInput)
+}
+
+// Recover continues the executions left behind by a previous process: running executions are
+// re-executed, and interrupts past their deadline are resolved with their OnTimeout outcome. Call
+// it on start up, and periodically to enforce interrupt deadlines. It blocks until the recovered
+// executions finish or are interrupted again, and returns them.
+func (r *Runner) Recover() ([]*Execution, error) {
+	pending := []*Execution{}
+	options := inferable.KVListOptions{}
+	for {
+		page, err := r.store.List(executionPrefix, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range page.Entries {
+			execution, ok, err := r.Get(entry.Key[len(executionPrefix):])
+			if err != nil {
+				return nil, err
+			}
+			if ok && r.recoverable(execution) {
+				pending = append(pending, execution)
+			}
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		options.After = page.NextCursor
+	}
+
+	recovered := make([]*Execution, len(pending))
+	errs := make([]error, len(pending))
+	var wg sync.WaitGroup
+	for n, execution := range pending {
+		wg.Add(1)
+		go func(n int, execution *Execution) {
+			defer wg.Done()
+			recovered[n], errs[n] = r.recover(execution)
+		}(n, execution)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return recovered, err
+		}
+	}
+	return recovered, nil
+}
+
+// recoverable reports whether an execution needs recovering.
+func (r *Runner) recoverable(execution *Execution) bool {
+	r.mu.Lock()
+	active := r.active[execution.ID]
+	r.mu.Unlock()
+	if active {
+		return false
+	}
+
+	switch execution.Status {
+	case StatusRunning:
+		return true
+	case StatusInterrupted:
+		return execution.InterruptExpiresAt != nil && !r.store.now().Before(*execution.InterruptExpiresAt)
+	}
+	return false
+}
+
+func (r *Runner) recover(execution *Execution) (*Execution, error) {
+	if execution.Status == StatusRunning {
+		return r.run(execution, inferable.ContextInput{})
+	}
+
+	switch execution.Interrupt.OnTimeout {
+	case inferable.InterruptTimeoutApprove:
+		return r.resume(execution, inferable.ContextInput{Approved: true, InterruptTimedOut: true})
+	case inferable.InterruptTimeoutResume:
+		return r.resume(execution, inferable.ContextInput{InterruptTimedOut: true})
+	}
+
+	execution.Status = StatusFailed
+	execution.Error = fmt.Sprintf("interrupt timed out after %d seconds", execution.Interrupt.TimeoutSeconds)
+	execution.Interrupt = nil
+	execution.InterruptExpiresAt = nil
+	return execution, r.save(execution)
+}
+
+// run calls the handler of a running execution and stores its outcome.
+func (r *Runner) run(execution *Execution, contextInput inferable.ContextInput) (*Execution, error) {
+	workflow, ok := r.workflows[execution.Workflow]
+	if !ok {
+		return nil, fmt.Errorf("workflow %s is not registered with the runner", execution.Workflow)
+	}
+
+	r.mu.Lock()
+	if r.active[execution.ID] {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("execution %s is already running", execution.ID)
+	}
+	r.active[execution.ID] = true
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.active, execution.ID)
+		r.mu.Unlock()
+	}()
+
+	execution.Runs++
+	if err := r.save(execution); err != nil {
+		return nil, err
+	}
+
+	result, err := workflow.Execute(execution.Version, execution.Input, contextInput)
+	interrupt := interruptOf(result)
+	for n := 0; n < maxApprovals && err == nil && interrupt != nil && interrupt.Type == inferable.APPROVAL && r.approvals != nil; n++ {
+		decision, resolveErr := r.approvals(inferable.ApprovalRequest{Tool: execution.Workflow, CallID: execution.ID, Interrupt: *interrupt})
+		if resolveErr != nil || decision == nil {
+			break
+		}
+		if !decision.Approved {
+			err = fmt.Errorf("execution %s was denied approval: %s", execution.ID, interrupt.Error())
+			break
+		}
+		result, err = workflow.Execute(execution.Version, execution.Input, inferable.ContextInput{Approved: true, ApprovalOption: decision.Option})
+		interrupt = interruptOf(result)
+	}
+
+	switch {
+	case err != nil:
+		execution.Status = StatusFailed
+		execution.Error = err.Error()
+	case interrupt != nil:
+		execution.Status = StatusInterrupted
+		execution.Interrupt = interrupt
+		if interrupt.TimeoutSeconds > 0 {
+			expiresAt := r.store.now().Add(time.Duration(interrupt.TimeoutSeconds) * time.Second)
+			execution.InterruptExpiresAt = &expiresAt
+		}
+	default:
+		serialized, err := json.Marshal(result)
+		if err != nil {
+			execution.Status = StatusFailed
+			execution.Error = fmt.Sprintf("failed to marshal result: %v", err)
+			break
+		}
+		execution.Status = StatusDone
+		execution.Result = serialized
+	}
+
+	return execution, r.save(execution)
+}
+
+// interruptOf returns the interrupt a handler returned, or nil.
+func interruptOf(result interface{}) *inferable.Interrupt {
+	switch t := result.(type) {
+	case *inferable.Interrupt:
+		return t
+	case inferable.Interrupt:
+		return &t
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+// Package local runs workflow executions durably without a cluster, persisting them in SQLite so
+// that executions, ctx.Memo entries, ctx.Sleep timers, and interrupts survive process restarts.
+// Use it for edge and air-gapped deployments, and to test durability locally:
+//
+//	db, _ := sql.Open("sqlite", "inferable.db") // any database/sql SQLite driver
+//	store, _ := local.Open(db, local.StoreOptions{})
+//
+//	client, _ := inferable.New(inferable.InferableOptions{KVStore: store, Offline: true})
+//	workflow := client.Workflows.Create(inferable.WorkflowConfig{...})
+//	workflow.Version(1).Define(handler)
+//
+//	runner := local.NewRunner(store, workflow)
+//	runner.Recover() // continue the executions of the previous process
+//	execution, _ := runner.Trigger("orders", "order-42", 1, map[string]interface{}{"orderId": "42"})
+//
+// The store is an inferable.KVStore, so all durable state of a handler is kept in its single
+// table. Handlers that use ctx.LLM or ctx.Agents need an LLMFactory and AgentRunnerFactory
+// offline.
+package local
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go"
+)
+
+// DefaultTable is the name of the table entries are stored in.
+const DefaultTable = "inferable_kv"
+
+// StoreOptions configures a Store.
+type StoreOptions struct {
+	// Table is the name of the table, see CreateTableSQL. Defaults to DefaultTable.
+	Table string
+}
+
+// Store is an inferable.KVStore kept in a SQLite database. It supports expiring, versioned, and
+// listed entries, and is safe for use by several processes sharing the database file.
+type Store struct {
+	db    *sql.DB
+	table string
+	// now is the time entries expire against
+	now func() time.Time
+}
+
+var (
+	_ inferable.ExpiringKVStore  = (*Store)(nil)
+	_ inferable.VersionedKVStore = (*Store)(nil)
+	_ inferable.KVLister         = (*Store)(nil)
+)
+
+// NewStore creates a Store on a database whose table already exists, see CreateTableSQL.
+func NewStore(db *sql.DB, options StoreOptions) *Store {
+	if options.Table == "" {
+		options.Table = DefaultTable
+	}
+	return &Store{db: db, table: options.Table, now: time.Now}
+}
+
+// Open creates a Store, creating its table if it does not exist.
+func Open(db *sql.DB, options StoreOptions) (*Store, error) {
+	store := NewStore(db, options)
+	if _, err := db.Exec(store.CreateTableSQL()); err != nil {
+		return nil, fmt.Errorf("failed to create table %s: %v", store.table, err)
+	}
+	return store, nil
+}
+
+// CreateTableSQL returns a statement creating the store's table, for use in a migration. Times are
+// stored as Unix nanoseconds.
+func (s *Store) CreateTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  key TEXT PRIMARY KEY,
+  value TEXT NOT NULL,
+  version INTEGER NOT NULL,
+  created_at INTEGER NOT NULL,
+  expires_at INTEGER NULL
+)`, s.table)
+}
+
+// entry is a stored row. Expired entries are kept until they are written again.
+type entry struct {
+	exists  bool
+	value   string
+	version int
+	expired bool
+}
+
+func (s *Store) read(key string) (entry, error) {
+	var e entry
+	var expiresAt sql.NullInt64
+	err := s.db.QueryRow(
+		fmt.Sprintf("SELECT value, version, expires_at FROM %s WHERE key = ?", s.table),
+		key,
+	).Scan(&e.value, &e.version, &expiresAt)
+	if err == sql.ErrNoRows {
+		return entry{}, nil
+	}
+	if err != nil {
+		return entry{}, fmt.Errorf("failed to get key: %v", err)
+	}
+	e.exists = true
+	e.expired = expiresAt.Valid && !s.now().Before(time.Unix(0, expiresAt.Int64))
+	return e, nil
+}
+
+// live returns the version of an entry, 0 if it is absent or expired.
+func (e entry) live() int {
+	if !e.exists || e.expired {
+		return 0
+	}
+	return e.version
+}
+
+// write replaces a previously read entry with value at version, and reports false if another
+// writer changed it first.
+func (s *Store) write(key string, previous entry, value string, version int, expiresAt time.Time) (bool, error) {
+	var expires sql.NullInt64
+	if !expiresAt.IsZero() {
+		expires = sql.NullInt64{Int64: expiresAt.UnixNano(), Valid: true}
+	}
+	now := s.now().UnixNano()
+
+	var result sql.Result
+	var err error
+	if previous.exists {
+		result, err = s.db.Exec(
+			fmt.Sprintf("UPDATE %s SET value = ?, version = ?, created_at = ?, expires_at = ? WHERE key = ? AND version = ?", s.table),
+			value, version, now, expires, key, previous.version,
+		)
+	} else {
+		result, err = s.db.Exec(
+			fmt.Sprintf("INSERT OR IGNORE INTO %s (key, value, version, created_at, expires_at) VALUES (?, ?, ?, ?, ?)", s.table),
+			key, value, version, now, expires,
+		)
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to set key: %v", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to set key: %v", err)
+	}
+	return affected == 1, nil
+}
+
+func (s *Store) Get(key string) (string, bool, error) {
+	e, err := s.read(key)
+	if err != nil || e.live() == 0 {
+		return "", false, err
+	}
+	return e.value, true, nil
+}
+
+func (s *Store) SetIfAbsent(key string, value string) error {
+	return s.SetIfAbsentUntil(key, value, time.Time{})
+}
+
+func (s *Store) SetIfAbsentUntil(key string, value string, expiresAt time.Time) error {
+	e, err := s.read(key)
+	if err != nil || e.live() != 0 {
+		return err
+	}
+	// Losing the race to another writer leaves its value, as if it had been set first
+	_, err = s.write(key, e, value, 1, expiresAt)
+	return err
+}
+
+func (s *Store) GetVersioned(key string) (string, int, error) {
+	e, err := s.read(key)
+	if err != nil || e.live() == 0 {
+		return "", 0, err
+	}
+	return e.value, e.version, nil
+}
+
+func (s *Store) SetIfVersion(key string, value string, version int) (int, error) {
+	e, err := s.read(key)
+	if err != nil {
+		return 0, err
+	}
+	if e.live() != version {
+		return 0, inferable.ErrVersionConflict
+	}
+	stored, err := s.write(key, e, value, version+1, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+	if !stored {
+		return 0, inferable.ErrVersionConflict
+	}
+	return version + 1, nil
+}
+
+func (s *Store) List(prefix string, options inferable.KVListOptions) (*inferable.KVPage, error) {
+	limit := options.Limit
+	if limit <= 0 {
+		limit = inferable.DefaultKVListLimit
+	}
+
+	rows, err := s.db.Query(
+		fmt.Sprintf("SELECT key, value, created_at, expires_at FROM %s WHERE key > ? AND substr(key, 1, ?) = ? AND (expires_at IS NULL OR expires_at > ?) ORDER BY key LIMIT ?", s.table),
+		options.After, len(prefix), prefix, s.now().UnixNano(), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keys: %v", err)
+	}
+	defer rows.Close()
+
+	page := &inferable.KVPage{Entries: []inferable.KVEntry{}}
+	for rows.Next() {
+		var entry inferable.KVEntry
+		var createdAt int64
+		var expiresAt sql.NullInt64
+		if err := rows.Scan(&entry.Key, &entry.Value, &createdAt, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to list keys: %v", err)
+		}
+		entry.CreatedAt = time.Unix(0, createdAt)
+		if expiresAt.Valid {
+			entry.ExpiresAt = time.Unix(0, expiresAt.Int64)
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list keys: %v", err)
+	}
+
+	if len(page.Entries) == limit {
+		page.NextCursor = page.Entries[limit-1].Key
+	}
+	return page, nil
+}
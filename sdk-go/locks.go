@@ -0,0 +1,181 @@
+package inferable
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLockHeld is returned when a lock is held by another holder for longer than the caller waits.
+var ErrLockHeld = errors.New("lock is held by another holder")
+
+// ErrLockLost is returned by Lock.Extend and Lock.Release when the lock expired and was acquired
+// by another holder.
+var ErrLockLost = errors.New("lock was lost")
+
+const (
+	// DefaultLockTTL is how long a lock is held when LockOptions.TTL is unset.
+	DefaultLockTTL = 30 * time.Second
+	// lockRetryInterval is how often a held lock is checked while waiting for it.
+	lockRetryInterval = 250 * time.Millisecond
+)
+
+// LockOptions configures the acquisition of a lock.
+type LockOptions struct {
+	// TTL is how long the lock is held unless it is extended or released first, so that the
+	// locks of crashed holders are freed. Defaults to DefaultLockTTL.
+	TTL time.Duration
+	// Wait is how long to wait for a lock held by another holder. Zero tries once.
+	Wait time.Duration
+}
+
+// Lock is a named lock held in the KV store with version-checked writes, so that it is shared
+// by every machine using the same store.
+type Lock struct {
+	// Name is the name the lock was acquired under.
+	Name string
+	// ExpiresAt is when the lock is freed unless it is extended or released first.
+	ExpiresAt time.Time
+
+	store   VersionedKVStore
+	clock   Clock
+	holder  string
+	version int
+}
+
+// lockState is the stored value of a lock. A released lock has no holder.
+type lockState struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// lockKey is the key under which a lock is stored.
+func lockKey(name string) string {
+	return fmt.Sprintf("lock_%s", name)
+}
+
+// acquireLock acquires the lock name for holder, which acquires a lock it already holds again.
+func acquireLock(store KVStore, clock Clock, name string, holder string, options LockOptions) (*Lock, error) {
+	// Locks hold no secrets, and encrypted stores do not support version-checked writes
+	if encrypted, ok := store.(*encryptedStore); ok {
+		store = encrypted.store
+	}
+	versioned, ok := store.(VersionedKVStore)
+	if !ok {
+		return nil, fmt.Errorf("the KVStore does not implement VersionedKVStore")
+	}
+	if options.TTL <= 0 {
+		options.TTL = DefaultLockTTL
+	}
+
+	lock := &Lock{Name: name, store: versioned, clock: clock, holder: holder}
+	deadline := clock.Now().Add(options.Wait)
+	for {
+		value, version, err := versioned.GetVersioned(lockKey(name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get lock %s: %w", name, err)
+		}
+		current := lockState{}
+		if version > 0 {
+			if err := json.Unmarshal([]byte(value), &current); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal lock %s: %v", name, err)
+			}
+		}
+
+		now := clock.Now()
+		if current.Holder == "" || current.Holder == holder || !now.Before(current.ExpiresAt) {
+			lock.version = version
+			err := lock.write(now.Add(options.TTL))
+			if err == nil {
+				return lock, nil
+			}
+			if !errors.Is(err, ErrLockLost) {
+				return nil, err
+			}
+			// Another holder wrote the lock since it was read
+			continue
+		}
+
+		if !now.Before(deadline) {
+			return nil, fmt.Errorf("%w: %s is held until %s", ErrLockHeld, name, current.ExpiresAt.Format(time.RFC3339))
+		}
+		wait := lockRetryInterval
+		if remaining := deadline.Sub(now); remaining < wait {
+			wait = remaining
+		}
+		<-clock.After(wait)
+	}
+}
+
+// write stores the lock as held by its holder until expiresAt, or as released for the zero time.
+func (l *Lock) write(expiresAt time.Time) error {
+	state := lockState{ExpiresAt: expiresAt}
+	if !expiresAt.IsZero() {
+		state.Holder = l.holder
+	}
+	value, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lock %s: %v", l.Name, err)
+	}
+
+	version, err := l.store.SetIfVersion(lockKey(l.Name), string(value), l.version)
+	if errors.Is(err, ErrVersionConflict) {
+		return fmt.Errorf("%w: %s", ErrLockLost, l.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set lock %s: %w", l.Name, err)
+	}
+	l.version = version
+	l.ExpiresAt = expiresAt
+	return nil
+}
+
+// Extend holds the lock for ttl from now. It returns ErrLockLost if the lock has been acquired
+// by another holder since it expired.
+func (l *Lock) Extend(ttl time.Duration) error {
+	return l.write(l.clock.Now().Add(ttl))
+}
+
+// Release frees the lock for other holders. It returns ErrLockLost if the lock has been acquired
+// by another holder since it expired.
+func (l *Lock) Release() error {
+	return l.write(time.Time{})
+}
+
+// Lock acquires the named lock in the client's KV store, e.g. to let one machine at a time
+// refresh a shared token. Each call acquires the lock as a holder of its own.
+//
+//	lock, err := client.KV.Lock("refresh-token", inferable.LockOptions{TTL: time.Minute, Wait: 10 * time.Second})
+//	if err != nil {
+//		return err
+//	}
+//	defer lock.Release()
+func (k *KV) Lock(name string, options ...LockOptions) (*Lock, error) {
+	store, err := k.store()
+	if err != nil {
+		return nil, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate lock holder: %v", err)
+	}
+	holder := fmt.Sprintf("%s_%s", k.inferable.machineID, hex.EncodeToString(id))
+	return acquireLock(store, k.inferable.clock, name, holder, mergeLockOptions(options))
+}
+
+// mergeLockOptions merges the variadic options of Lock, later options taking precedence.
+func mergeLockOptions(options []LockOptions) LockOptions {
+	merged := LockOptions{}
+	for _, option := range options {
+		if option.TTL > 0 {
+			merged.TTL = option.TTL
+		}
+		if option.Wait > 0 {
+			merged.Wait = option.Wait
+		}
+	}
+	return merged
+}
@@ -0,0 +1,59 @@
+package inferable
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKVLock(t *testing.T) {
+	clock := &advancingClock{fixedClock: fixedClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}}
+	i, err := New(InferableOptions{KVStore: NewMemoryStore(), Offline: true, Clock: clock})
+	require.NoError(t, err)
+
+	lock, err := i.KV.Lock("refresh-token")
+	require.NoError(t, err)
+	assert.Equal(t, clock.now.Add(DefaultLockTTL), lock.ExpiresAt)
+
+	// Other holders wait for the lock, up to Wait
+	_, err = i.KV.Lock("refresh-token", LockOptions{Wait: 10 * time.Second})
+	assert.ErrorIs(t, err, ErrLockHeld)
+	assert.Equal(t, 10*time.Second, clock.waited)
+
+	require.NoError(t, lock.Release())
+	other, err := i.KV.Lock("refresh-token", LockOptions{TTL: 5 * time.Second})
+	require.NoError(t, err)
+
+	// An expired lock is free for other holders, and lost to its previous one
+	clock.now = clock.now.Add(6 * time.Second)
+	_, err = i.KV.Lock("refresh-token")
+	require.NoError(t, err)
+	assert.ErrorIs(t, other.Extend(time.Minute), ErrLockLost)
+	assert.ErrorIs(t, other.Release(), ErrLockLost)
+}
+
+func TestWorkflowLock(t *testing.T) {
+	store := NewMemoryStore()
+	i, err := New(InferableOptions{KVStore: store, Offline: true})
+	require.NoError(t, err)
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "refunds", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		if _, err := ctx.Lock("customer-42", LockOptions{TTL: time.Hour}); err != nil {
+			return nil, err
+		}
+		return "refunded", nil
+	})
+
+	_, err = workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	// The execution acquires the lock it holds again when it is re-executed
+	_, err = workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+
+	_, err = workflow.Execute(1, WorkflowInput{ExecutionID: "exec-2"}, ContextInput{})
+	assert.ErrorIs(t, err, ErrLockHeld)
+	assert.Contains(t, store.Keys(), "lock_customer-42")
+}
@@ -0,0 +1,366 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogLevel is the severity of a log entry. Its values match those of slog.Level.
+type LogLevel int
+
+const (
+	// LogLevelDebug is for verbose tracing, e.g. of every workflow handler run.
+	LogLevelDebug LogLevel = -4
+	// LogLevelInfo is the default minimum level.
+	LogLevelInfo LogLevel = 0
+	// LogLevelWarn is for unexpected conditions the client recovers from.
+	LogLevelWarn LogLevel = 4
+	// LogLevelError is for failures.
+	LogLevelError LogLevel = 8
+)
+
+// String returns the name of the level as written by JSONLogger, e.g. "debug".
+func (l LogLevel) String() string {
+	switch {
+	case l <= LogLevelDebug:
+		return "debug"
+	case l <= LogLevelInfo:
+		return "info"
+	case l <= LogLevelWarn:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// ParseLogLevel parses the name of a level, e.g. from an environment variable.
+func ParseLogLevel(name string) (LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LogLevelDebug, nil
+	case "info", "":
+		return LogLevelInfo, nil
+	case "warn", "warning":
+		return LogLevelWarn, nil
+	case "error":
+		return LogLevelError, nil
+	}
+	return LogLevelInfo, fmt.Errorf("unknown log level %q", name)
+}
+
+// JSONLoggerOptions configures NewJSONLogger.
+type JSONLoggerOptions struct {
+	// Writer receives one JSON object per line. Defaults to os.Stdout.
+	Writer io.Writer
+	// Level is the minimum level of the entries written. Defaults to LogLevelInfo, so that Debug
+	// entries are only written when asked for.
+	Level LogLevel
+	// Fields are added to every entry, e.g. the service or pod name.
+	Fields map[string]interface{}
+}
+
+// JSONLogger is a Logger that writes single-line JSON entries, for log pipelines such as those of
+// Kubernetes that parse container output:
+//
+//	{"time":"2025-01-01T00:00:00Z","level":"info","msg":"Workflow log: shipped","workflow":"orders","executionId":"exec-1","fields":{"orderId":"42"}}
+//
+// Entries logged for an execution, including those logged through ctx.Logger, carry the workflow
+// name and execution ID at the top level.
+type JSONLogger struct {
+	mu     *sync.Mutex
+	writer io.Writer
+	level  LogLevel
+	fields map[string]interface{}
+}
+
+// NewJSONLogger creates a JSONLogger.
+//
+//	workflow := client.Workflows.Create(inferable.WorkflowConfig{
+//		Name:   "orders",
+//		Logger: inferable.NewJSONLogger(),
+//	})
+func NewJSONLogger(options ...JSONLoggerOptions) *JSONLogger {
+	logger := &JSONLogger{mu: &sync.Mutex{}, writer: os.Stdout, fields: map[string]interface{}{}}
+	for _, option := range options {
+		if option.Writer != nil {
+			logger.writer = option.Writer
+		}
+		if option.Level != 0 {
+			logger.level = option.Level
+		}
+		for key, value := range option.Fields {
+			logger.fields[key] = value
+		}
+	}
+	return logger
+}
+
+// With returns a logger that adds fields to every entry. It writes to the same writer.
+func (l *JSONLogger) With(fields map[string]interface{}) *JSONLogger {
+	merged := make(map[string]interface{}, len(l.fields)+len(fields))
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &JSONLogger{mu: l.mu, writer: l.writer, level: l.level, fields: merged}
+}
+
+func (l *JSONLogger) Debug(message string, meta map[string]interface{}) {
+	l.write(LogLevelDebug, message, meta)
+}
+
+func (l *JSONLogger) Info(message string, meta map[string]interface{}) {
+	l.write(LogLevelInfo, message, meta)
+}
+
+func (l *JSONLogger) Warn(message string, meta map[string]interface{}) {
+	l.write(LogLevelWarn, message, meta)
+}
+
+func (l *JSONLogger) Error(message string, meta map[string]interface{}) {
+	l.write(LogLevelError, message, meta)
+}
+
+// jsonLogEntry is a line written by JSONLogger.
+type jsonLogEntry struct {
+	Time        string                 `json:"time"`
+	Level       string                 `json:"level"`
+	Message     string                 `json:"msg"`
+	Workflow    string                 `json:"workflow,omitempty"`
+	ExecutionID string                 `json:"executionId,omitempty"`
+	Fields      map[string]interface{} `json:"fields,omitempty"`
+}
+
+func (l *JSONLogger) write(level LogLevel, message string, meta map[string]interface{}) {
+	if level < l.level {
+		return
+	}
+	entry := jsonLogEntry{
+		Time:    time.Now().UTC().Format(time.RFC3339Nano),
+		Level:   level.String(),
+		Message: message,
+		Fields:  map[string]interface{}{},
+	}
+	for _, fields := range []map[string]interface{}{l.fields, meta} {
+		for key, value := range fields {
+			entry.Fields[key] = value
+		}
+	}
+
+	// The execution context is promoted so that pipelines can index it
+	if workflow, ok := entry.Fields[logFieldWorkflow].(string); ok {
+		entry.Workflow = workflow
+		delete(entry.Fields, logFieldWorkflow)
+	}
+	if executionId, ok := entry.Fields[logFieldExecutionID].(string); ok {
+		entry.ExecutionID = executionId
+		delete(entry.Fields, logFieldExecutionID)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		// Keep the entry, without the fields that could not be marshaled
+		entry.Fields = map[string]interface{}{"marshalError": err.Error()}
+		line, _ = json.Marshal(entry)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.writer.Write(append(line, '\n'))
+}
+
+// Fields carrying the execution context of a log entry.
+const (
+	logFieldWorkflow    = "workflow"
+	logFieldExecutionID = "executionId"
+)
+
+// fieldLogger adds fields to the metadata of every entry it passes on to a Logger. Fields in an
+// entry's own metadata take precedence.
+type fieldLogger struct {
+	logger Logger
+	fields map[string]interface{}
+}
+
+func (l fieldLogger) Debug(message string, meta map[string]interface{}) {
+	if l.logger != nil {
+		l.logger.Debug(message, l.merge(meta))
+	}
+}
+
+func (l fieldLogger) Info(message string, meta map[string]interface{}) {
+	if l.logger != nil {
+		l.logger.Info(message, l.merge(meta))
+	}
+}
+
+func (l fieldLogger) Warn(message string, meta map[string]interface{}) {
+	if l.logger != nil {
+		l.logger.Warn(message, l.merge(meta))
+	}
+}
+
+func (l fieldLogger) Error(message string, meta map[string]interface{}) {
+	if l.logger != nil {
+		l.logger.Error(message, l.merge(meta))
+	}
+}
+
+func (l fieldLogger) merge(meta map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(l.fields)+len(meta))
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range meta {
+		merged[key] = value
+	}
+	return redactMeta(merged)
+}
+
+// executionLogger returns the workflow's logger with the execution context added to every entry.
+// It discards entries if the workflow has no logger.
+func (w *Workflow) executionLogger(executionId string) Logger {
+	return fieldLogger{logger: w.logger, fields: map[string]interface{}{
+		logFieldWorkflow:    w.name,
+		logFieldExecutionID: executionId,
+	}}
+}
+
+// levelLogger passes the entries at or above a minimum level on to a Logger.
+type levelLogger struct {
+	logger Logger
+	level  LogLevel
+}
+
+// WithMinLevel returns a logger that discards the entries of logger below level, e.g. to turn on
+// Debug tracing in development only:
+//
+//	level, err := inferable.ParseLogLevel(os.Getenv("LOG_LEVEL"))
+//	workflow := client.Workflows.Create(inferable.WorkflowConfig{
+//		Name:   "orders",
+//		Logger: inferable.WithMinLevel(logger, level),
+//	})
+func WithMinLevel(logger Logger, level LogLevel) Logger {
+	return levelLogger{logger: logger, level: level}
+}
+
+func (l levelLogger) Debug(message string, meta map[string]interface{}) {
+	if l.level <= LogLevelDebug {
+		l.logger.Debug(message, meta)
+	}
+}
+
+func (l levelLogger) Info(message string, meta map[string]interface{}) {
+	if l.level <= LogLevelInfo {
+		l.logger.Info(message, meta)
+	}
+}
+
+func (l levelLogger) Warn(message string, meta map[string]interface{}) {
+	if l.level <= LogLevelWarn {
+		l.logger.Warn(message, meta)
+	}
+}
+
+func (l levelLogger) Error(message string, meta map[string]interface{}) {
+	if l.level <= LogLevelError {
+		l.logger.Error(message, meta)
+	}
+}
+
+// SlogLogger is a Logger that writes to a *slog.Logger, so that the SDK's entries go through the
+// same handler as the rest of the application's logs. Metadata becomes attributes, in key order.
+// Entries are filtered by the level of the logger's handler.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger wraps logger in a Logger. A nil logger uses slog.Default().
+//
+//	client, err := inferable.New(inferable.InferableOptions{
+//		Logger: inferable.NewSlogLogger(slog.New(slog.NewJSONHandler(os.Stdout, nil))),
+//	})
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{logger: logger}
+}
+
+func (l *SlogLogger) Debug(message string, meta map[string]interface{}) {
+	l.logger.Debug(message, slogArgs(meta)...)
+}
+
+func (l *SlogLogger) Info(message string, meta map[string]interface{}) {
+	l.logger.Info(message, slogArgs(meta)...)
+}
+
+func (l *SlogLogger) Warn(message string, meta map[string]interface{}) {
+	l.logger.Warn(message, slogArgs(meta)...)
+}
+
+func (l *SlogLogger) Error(message string, meta map[string]interface{}) {
+	l.logger.Error(message, slogArgs(meta)...)
+}
+
+// slogArgs converts metadata to slog attributes, in key order.
+func slogArgs(meta map[string]interface{}) []any {
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	args := make([]any, 0, len(keys))
+	for _, key := range keys {
+		args = append(args, slog.Any(key, meta[key]))
+	}
+	return args
+}
+
+// logInfo reports a diagnostic of the client, such as a registration, to its Logger, or else to
+// the standard logger.
+func (i *Inferable) logInfo(message string, meta map[string]interface{}) {
+	if i.logger != nil {
+		i.logger.Info(message, meta)
+		return
+	}
+	log.Print(formatDiagnostic(message, meta))
+}
+
+// logError reports an error the client handles without returning it, such as a failed poll, to
+// its Logger, or else to the standard logger.
+func (i *Inferable) logError(message string, meta map[string]interface{}) {
+	if i.logger != nil {
+		i.logger.Error(message, meta)
+		return
+	}
+	log.Print(formatDiagnostic(message, meta))
+}
+
+// formatDiagnostic formats a diagnostic for the standard logger, as the message followed by the
+// metadata as key=value pairs in key order.
+func formatDiagnostic(message string, meta map[string]interface{}) string {
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var line strings.Builder
+	line.WriteString(message)
+	for _, key := range keys {
+		fmt.Fprintf(&line, " %s=%v", key, meta[key])
+	}
+	return line.String()
+}
@@ -0,0 +1,141 @@
+package inferable
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONLogger(t *testing.T) {
+	var output bytes.Buffer
+	logger := NewJSONLogger(JSONLoggerOptions{Writer: &output, Fields: map[string]interface{}{"service": "orders"}})
+
+	logger.Info("Started", map[string]interface{}{"port": 8080})
+	logger.With(map[string]interface{}{"region": "eu"}).Error("Failed", map[string]interface{}{"error": "timeout", "unmarshalable": func() {}})
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "Started", entry["msg"])
+	assert.NotEmpty(t, entry["time"])
+	assert.Equal(t, map[string]interface{}{"service": "orders", "port": 8080.0}, entry["fields"])
+
+	// Entries that cannot be marshaled are still written
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.Equal(t, "error", entry["level"])
+	assert.Contains(t, entry["fields"], "marshalError")
+}
+
+func TestLogLevels(t *testing.T) {
+	var output bytes.Buffer
+	logger := NewJSONLogger(JSONLoggerOptions{Writer: &output})
+	logger.Debug("Hidden", nil)
+	logger.Warn("Slow poll", nil)
+	assert.Equal(t, 1, strings.Count(output.String(), "\n"))
+	assert.Contains(t, output.String(), `"level":"warn"`)
+
+	// Debug tracing of workflow handlers is written when asked for
+	output.Reset()
+	i := newTestClient(t, InferableOptions{KVStore: NewMemoryStore()}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{
+		Name:        "orders",
+		InputSchema: WorkflowInput{},
+		Logger:      NewJSONLogger(JSONLoggerOptions{Writer: &output, Level: LogLevelDebug}),
+	})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return nil, nil
+	})
+	_, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	assert.Contains(t, output.String(), "Workflow handler started")
+	assert.Contains(t, output.String(), "Workflow handler finished")
+
+	recorder := &recordingLogger{}
+	filtered := WithMinLevel(recorder, LogLevelWarn)
+	filtered.Debug("debug", nil)
+	filtered.Info("info", nil)
+	filtered.Warn("warn", nil)
+	filtered.Error("error", nil)
+	assert.Empty(t, recorder.debugs)
+	assert.Empty(t, recorder.infos)
+	assert.Equal(t, []string{"warn"}, recorder.warns)
+	assert.Equal(t, []string{"error"}, recorder.errors)
+
+	level, err := ParseLogLevel("DEBUG")
+	require.NoError(t, err)
+	assert.Equal(t, LogLevelDebug, level)
+	assert.Equal(t, "debug", level.String())
+	_, err = ParseLogLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestJSONLoggerExecutionContext(t *testing.T) {
+	var output bytes.Buffer
+	i := newTestClient(t, InferableOptions{KVStore: NewMemoryStore()}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{
+		Name:        "orders",
+		InputSchema: WorkflowInput{},
+		Logger:      NewJSONLogger(JSONLoggerOptions{Writer: &output}),
+	})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		ctx.Logger.Info("Shipping order", map[string]interface{}{"orderId": "42"})
+		return nil, nil
+	})
+	output.Reset()
+
+	_, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+
+	var entry jsonLogEntry
+	require.NoError(t, json.Unmarshal(output.Bytes(), &entry))
+	assert.Equal(t, "Shipping order", entry.Message)
+	assert.Equal(t, "orders", entry.Workflow)
+	assert.Equal(t, "exec-1", entry.ExecutionID)
+	assert.Equal(t, map[string]interface{}{"orderId": "42"}, entry.Fields)
+
+	// Workflows without a logger discard entries
+	silent := i.Workflows.Create(WorkflowConfig{Name: "silent", InputSchema: WorkflowInput{}})
+	silent.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		ctx.Logger.Error("Discarded", nil)
+		return nil, nil
+	})
+	_, err = silent.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+}
+
+func TestSlogLogger(t *testing.T) {
+	var output bytes.Buffer
+	handler := slog.NewJSONHandler(&output, &slog.HandlerOptions{
+		// Drop the time for a stable output
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+	i := newTestClient(t, InferableOptions{Logger: NewSlogLogger(slog.New(handler))}, nil)
+
+	_, err := i.getClusterId()
+	require.NoError(t, err)
+	// The SDK's own diagnostics go through the logger
+	_ = i.Tools.handleMessage(callMessage{Id: "job-1", Function: "missing"})
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, `{"level":"INFO","msg":"Registered machine","clusterId":"test-cluster","machineId":"`+i.machineID+`"}`, lines[0])
+	assert.JSONEq(t, `{"level":"ERROR","msg":"Received call for unknown tool","callId":"job-1","tool":"missing"}`, lines[1])
+}
+
+func TestFormatDiagnostic(t *testing.T) {
+	assert.Equal(t, "Failed to poll attempt=3 error=timeout", formatDiagnostic("Failed to poll", map[string]interface{}{"error": "timeout", "attempt": 3}))
+	assert.Equal(t, "Started", formatDiagnostic("Started", nil))
+}
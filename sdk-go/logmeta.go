@@ -0,0 +1,214 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"unicode/utf8"
+)
+
+// Default limits of ctx.Log metadata, used for the LogLimits fields that are not set.
+const (
+	DefaultLogMaxDepth        = 8
+	DefaultLogMaxItems        = 100
+	DefaultLogMaxStringLength = 4096
+	DefaultLogMaxBytes        = 64 * 1024
+)
+
+// LogLimits bounds the metadata passed to ctx.Log, so that large or cyclic values are recorded
+// with truncation markers instead of failing to serialize or shipping megabytes of noise. Fields
+// left zero use the Default* limits.
+type LogLimits struct {
+	// MaxDepth is how deeply maps, slices and structs are nested before being replaced with a
+	// "[max depth exceeded]" marker.
+	MaxDepth int
+	// MaxItems is how many entries of a map or elements of a slice are kept. The remaining map
+	// entries are replaced with a "..." entry, and the remaining slice elements with a marker.
+	MaxItems int
+	// MaxStringLength is how many bytes of a string are kept.
+	MaxStringLength int
+	// MaxBytes is the serialized size of the whole metadata. Top-level entries that do not fit
+	// are dropped and listed under the "_truncated" key.
+	MaxBytes int
+}
+
+func (l LogLimits) withDefaults() LogLimits {
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = DefaultLogMaxDepth
+	}
+	if l.MaxItems <= 0 {
+		l.MaxItems = DefaultLogMaxItems
+	}
+	if l.MaxStringLength <= 0 {
+		l.MaxStringLength = DefaultLogMaxStringLength
+	}
+	if l.MaxBytes <= 0 {
+		l.MaxBytes = DefaultLogMaxBytes
+	}
+	return l
+}
+
+// Truncation markers of serialized log metadata
+const (
+	logCycleMarker    = "[cycle]"
+	logDepthMarker    = "[max depth exceeded]"
+	logTruncatedKey   = "_truncated"
+	logMoreItemsKey   = "..."
+	logMoreItemsValue = "[%d more]"
+)
+
+// serializeLogMeta returns the metadata as plain JSON values within limits.
+func serializeLogMeta(meta map[string]interface{}, limits LogLimits) map[string]interface{} {
+	if meta == nil {
+		return nil
+	}
+	limits = limits.withDefaults()
+	s := &logSerializer{limits: limits, visiting: map[uintptr]bool{}}
+
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	serialized := make(map[string]interface{}, len(meta))
+	dropped := []string{}
+	// The braces of the object
+	size := 2
+	for _, key := range keys {
+		value := s.value(reflect.ValueOf(meta[key]), 1)
+		entry, err := json.Marshal(map[string]interface{}{key: value})
+		if err != nil {
+			value = fmt.Sprintf("[unserializable: %v]", err)
+			entry, _ = json.Marshal(map[string]interface{}{key: value})
+		}
+		// Each entry adds its own size, less its braces, and a comma
+		entrySize := len(entry) - 1
+		if size+entrySize > limits.MaxBytes {
+			dropped = append(dropped, key)
+			continue
+		}
+		size += entrySize
+		serialized[key] = value
+	}
+	if len(dropped) > 0 {
+		serialized[logTruncatedKey] = dropped
+	}
+	return serialized
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+type logSerializer struct {
+	limits LogLimits
+	// visiting holds the maps, slices and pointers on the path to the current value
+	visiting map[uintptr]bool
+}
+
+func (s *logSerializer) value(v reflect.Value, depth int) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return s.value(v.Elem(), depth)
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return nil
+		}
+	}
+
+	// Values with their own serialization, structs, and []byte are serialized as encoding/json
+	// would, and the result is bounded like any other value
+	if v.Type().Implements(jsonMarshalerType) || v.Kind() == reflect.Struct ||
+		(v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8) {
+		if depth > s.limits.MaxDepth {
+			return logDepthMarker
+		}
+		serialized, err := json.Marshal(v.Interface())
+		if err != nil {
+			return fmt.Sprintf("[unserializable: %v]", err)
+		}
+		var generic interface{}
+		if err := json.Unmarshal(serialized, &generic); err != nil {
+			return fmt.Sprintf("[unserializable: %v]", err)
+		}
+		return s.value(reflect.ValueOf(generic), depth)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		pointer := v.Pointer()
+		if s.visiting[pointer] {
+			return logCycleMarker
+		}
+		s.visiting[pointer] = true
+		defer delete(s.visiting, pointer)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		return s.value(v.Elem(), depth)
+	case reflect.String:
+		return s.string(v.String())
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return v.Interface()
+	case reflect.Map:
+		if depth > s.limits.MaxDepth {
+			return logDepthMarker
+		}
+		entries := make(map[string]interface{}, v.Len())
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]reflect.Value, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			keys = append(keys, key)
+			values[key] = iter.Value()
+		}
+		sort.Strings(keys)
+		for n, key := range keys {
+			if n == s.limits.MaxItems {
+				entries[logMoreItemsKey] = fmt.Sprintf(logMoreItemsValue, len(keys)-n)
+				break
+			}
+			entries[key] = s.value(values[key], depth+1)
+		}
+		return entries
+	case reflect.Slice, reflect.Array:
+		if depth > s.limits.MaxDepth {
+			return logDepthMarker
+		}
+		items := []interface{}{}
+		for n := 0; n < v.Len(); n++ {
+			if n == s.limits.MaxItems {
+				items = append(items, fmt.Sprintf(logMoreItemsValue, v.Len()-n))
+				break
+			}
+			items = append(items, s.value(v.Index(n), depth+1))
+		}
+		return items
+	default:
+		// Channels and functions cannot be serialized
+		return fmt.Sprintf("[unserializable %s]", v.Type())
+	}
+}
+
+// string truncates s to MaxStringLength bytes, at a rune boundary.
+func (s *logSerializer) string(value string) string {
+	if len(value) <= s.limits.MaxStringLength {
+		return value
+	}
+	cut := s.limits.MaxStringLength
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... [truncated, %d bytes]", value[:cut], len(value))
+}
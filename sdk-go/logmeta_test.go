@@ -0,0 +1,106 @@
+package inferable
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerializeLogMeta(t *testing.T) {
+	cyclic := map[string]interface{}{"name": "root"}
+	cyclic["self"] = cyclic
+
+	type node struct {
+		Name     string    `json:"name"`
+		Children []*node   `json:"children,omitempty"`
+		At       time.Time `json:"at"`
+	}
+	at := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	meta := serializeLogMeta(map[string]interface{}{
+		"cyclic":  cyclic,
+		"deep":    map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": "too deep"}}},
+		"long":    strings.Repeat("é", 10),
+		"items":   []int{1, 2, 3, 4, 5},
+		"struct":  node{Name: "parent", Children: []*node{{Name: "child", At: at}}, At: at},
+		"raw":     json.RawMessage(`{"from":"marshaler"}`),
+		"func":    func() {},
+		"nothing": nil,
+	}, LogLimits{MaxDepth: 2, MaxItems: 3, MaxStringLength: 6})
+
+	assert.Equal(t, map[string]interface{}{"name": "root", "self": "[cycle]"}, meta["cyclic"])
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"b": "[max depth exceeded]"}}, meta["deep"])
+	assert.Equal(t, "ééé... [truncated, 20 bytes]", meta["long"])
+	assert.Equal(t, []interface{}{1, 2, 3, "[2 more]"}, meta["items"])
+	assert.Equal(t, map[string]interface{}{
+		"name":     "parent",
+		"at":       "2025-0... [truncated, 20 bytes]",
+		"children": []interface{}{"[max depth exceeded]"},
+	}, meta["struct"])
+	assert.Equal(t, map[string]interface{}{"from": "marsha... [truncated, 9 bytes]"}, meta["raw"])
+	assert.Equal(t, "[unserializable func()]", meta["func"])
+	assert.Nil(t, meta["nothing"])
+
+	// The serialized metadata is always JSON
+	_, err := json.Marshal(meta)
+	require.NoError(t, err)
+
+	// Map entries beyond MaxItems are summarized
+	many := map[string]interface{}{}
+	for _, key := range []string{"a", "b", "c", "d"} {
+		many[key] = key
+	}
+	meta = serializeLogMeta(map[string]interface{}{"many": many}, LogLimits{MaxItems: 2})
+	assert.Equal(t, map[string]interface{}{"a": "a", "b": "b", "...": "[2 more]"}, meta["many"])
+
+	// Top-level entries that do not fit are dropped
+	meta = serializeLogMeta(map[string]interface{}{
+		"a": "small",
+		"b": strings.Repeat("x", 100),
+		"c": "small",
+	}, LogLimits{MaxBytes: 40})
+	assert.Equal(t, map[string]interface{}{"a": "small", "c": "small", "_truncated": []string{"b"}}, meta)
+	serialized, err := json.Marshal(meta)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(serialized)-len(`,"_truncated":["b"]`), 40)
+}
+
+func TestWorkflowLogLimits(t *testing.T) {
+	logged := make(chan map[string]interface{}, 1)
+	i := newTestClient(t, InferableOptions{KVStore: NewMemoryStore()}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflow-executions/{executionId}/logs": func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			body := map[string]interface{}{}
+			json.Unmarshal(data, &body)
+			logged <- body
+			w.WriteHeader(http.StatusCreated)
+		},
+	})
+
+	workflow := i.Workflows.Create(WorkflowConfig{
+		Name:        "orders",
+		InputSchema: WorkflowInput{},
+		LogLimits:   LogLimits{MaxStringLength: 4},
+	})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		cyclic := map[string]interface{}{}
+		cyclic["self"] = cyclic
+		return nil, ctx.Log("loaded", map[string]interface{}{"order": cyclic, "note": "shipped today"})
+	})
+
+	_, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"status": "loaded",
+		"data": map[string]interface{}{
+			"order": map[string]interface{}{"self": "[cycle]"},
+			"note":  "ship... [truncated, 13 bytes]",
+		},
+	}, <-logged)
+}
@@ -0,0 +1,127 @@
+package inferable
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"text/template"
+)
+
+// DefaultLocale is the locale whose templates are used when a message has none for the
+// execution's locale or its language.
+const DefaultLocale = "en"
+
+// Messages holds the message templates shown to customers and agents, such as interrupt messages,
+// approval options, and agent instructions, by locale and then by key. Templates use text/template
+// syntax, and are rendered in the execution's locale with WorkflowContext.Message.
+//
+//	messages := inferable.Messages{
+//		"en": {"refund.approval": "Approve a refund of {{.Amount}} EUR?"},
+//		"de": {"refund.approval": "Rückerstattung von {{.Amount}} EUR genehmigen?"},
+//	}
+type Messages map[string]map[string]string
+
+// Render renders the template of key for locale. Locales without the key fall back to their
+// language, e.g. "de" for "de-CH", and then to DefaultLocale.
+func (m Messages) Render(locale string, key string, data interface{}) (string, error) {
+	text, ok := m.lookup(locale, key)
+	if !ok {
+		return "", fmt.Errorf("no message %s for locale %s", key, locale)
+	}
+
+	tmpl, err := template.New(key).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse message %s: %v", key, err)
+	}
+	var rendered strings.Builder
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", fmt.Errorf("failed to render message %s: %v", key, err)
+	}
+	return rendered.String(), nil
+}
+
+// lookup returns the template of key for the first of locale, its language, and DefaultLocale
+// that has one.
+func (m Messages) lookup(locale string, key string) (string, bool) {
+	candidates := []string{locale}
+	if language, _, ok := strings.Cut(locale, "-"); ok {
+		candidates = append(candidates, language)
+	}
+	candidates = append(candidates, DefaultLocale)
+
+	for _, candidate := range candidates {
+		if text, ok := m[candidate][key]; ok {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// LocaleResolver derives the locale of an execution or tool call from its context, e.g. the
+// locale of the tenant identified by ContextInput.AuthContext. Returning an empty locale uses
+// DefaultLocale.
+type LocaleResolver func(ctx ContextInput) (string, error)
+
+// LocalesByTenant resolves the locale of the tenant that tenantOf identifies from a call's context,
+// so that the locales can be kept in configuration. Tenants without a locale get fallback.
+//
+//	client, err := inferable.New(inferable.InferableOptions{
+//		Messages: messages,
+//		Locale:   inferable.LocalesByTenant(tenantOf, map[string]string{"acme-de": "de-DE"}, "en"),
+//	})
+func LocalesByTenant(tenantOf func(ctx ContextInput) (string, error), locales map[string]string, fallback string) LocaleResolver {
+	return func(ctx ContextInput) (string, error) {
+		tenant, err := tenantOf(ctx)
+		if err != nil {
+			return "", err
+		}
+		if locale, ok := locales[tenant]; ok {
+			return locale, nil
+		}
+		return fallback, nil
+	}
+}
+
+// resolveLocale returns the locale of a call, falling back to DefaultLocale when it has none or
+// it cannot be resolved.
+func (i *Inferable) resolveLocale(ctx ContextInput) string {
+	if i.localeResolver == nil {
+		return DefaultLocale
+	}
+	locale, err := i.localeResolver(ctx)
+	if err != nil {
+		i.logMessageError("Failed to resolve locale", err)
+		return DefaultLocale
+	}
+	if locale == "" {
+		return DefaultLocale
+	}
+	return locale
+}
+
+// message renders a message in locale, or returns its key if it cannot be rendered, so that a
+// missing translation does not fail the call.
+func (i *Inferable) message(locale string, key string, data interface{}) string {
+	rendered, err := i.messages.Render(locale, key, data)
+	if err != nil {
+		i.logMessageError("Failed to render message", err)
+		return key
+	}
+	return rendered
+}
+
+// Message renders the message template of key in the locale of a tool call, see
+// InferableOptions.Messages, or returns key if it cannot be rendered.
+//
+//	return nil, inferable.ApprovalInterrupt(client.Message(ctx, "refund.approval", input))
+func (i *Inferable) Message(ctx ContextInput, key string, data interface{}) string {
+	return i.message(i.resolveLocale(ctx), key, data)
+}
+
+func (i *Inferable) logMessageError(message string, err error) {
+	if i.logger != nil {
+		i.logger.Error(message, map[string]interface{}{"error": err.Error()})
+	} else {
+		log.Printf("%s: %v", message, err)
+	}
+}
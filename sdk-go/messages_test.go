@@ -0,0 +1,67 @@
+package inferable
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMessages = Messages{
+	"en":    {"refund.approval": "Approve a refund of {{.Amount}} EUR?", "refund.deny": "Deny"},
+	"de":    {"refund.approval": "Rückerstattung von {{.Amount}} EUR genehmigen?"},
+	"de-CH": {"refund.deny": "Ablehnen"},
+}
+
+func TestMessagesRender(t *testing.T) {
+	data := map[string]interface{}{"Amount": 42}
+
+	rendered, err := testMessages.Render("de-CH", "refund.approval", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Rückerstattung von 42 EUR genehmigen?", rendered)
+
+	rendered, err = testMessages.Render("de-CH", "refund.deny", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Ablehnen", rendered)
+
+	// Locales without a translation fall back to DefaultLocale
+	rendered, err = testMessages.Render("fr", "refund.approval", data)
+	require.NoError(t, err)
+	assert.Equal(t, "Approve a refund of 42 EUR?", rendered)
+
+	_, err = testMessages.Render("en", "refund.unknown", nil)
+	assert.ErrorContains(t, err, "no message refund.unknown for locale en")
+	_, err = testMessages.Render("en", "refund.approval", map[string]interface{}{})
+	assert.ErrorContains(t, err, "failed to render message refund.approval")
+}
+
+func TestWorkflowContextMessage(t *testing.T) {
+	tenantOf := func(ctx ContextInput) (string, error) {
+		auth, _ := ctx.AuthContext.(map[string]interface{})
+		tenant, _ := auth["tenant"].(string)
+		if tenant == "" {
+			return "", errors.New("no tenant")
+		}
+		return tenant, nil
+	}
+	i := newTestClient(t, InferableOptions{
+		Messages: testMessages,
+		Locale:   LocalesByTenant(tenantOf, map[string]string{"acme-de": "de-DE"}, "en"),
+	}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "refunds", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return []string{ctx.Locale, ctx.Message("refund.approval", map[string]interface{}{"Amount": 10}), ctx.Message("refund.unknown", nil)}, nil
+	})
+
+	result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{AuthContext: map[string]interface{}{"tenant": "acme-de"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"de-DE", "Rückerstattung von 10 EUR genehmigen?", "refund.unknown"}, result)
+
+	// Executions whose locale cannot be resolved use DefaultLocale
+	result, err = workflow.Execute(1, WorkflowInput{ExecutionID: "exec-2"}, ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"en", "Approve a refund of 10 EUR?", "refund.unknown"}, result)
+
+	assert.Equal(t, "Deny", i.Message(ContextInput{AuthContext: map[string]interface{}{"tenant": "acme-us"}}, "refund.deny", nil))
+}
@@ -0,0 +1,233 @@
+package inferable
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultMetricsBuckets are the upper bounds, in seconds, of the duration histograms served by
+// MetricsHandler.
+var DefaultMetricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// Kinds of metric families, as named in the Prometheus text format.
+const (
+	metricCounter   = "counter"
+	metricGauge     = "gauge"
+	metricHistogram = "histogram"
+)
+
+// metricFamily is a named metric with a value per combination of label values.
+type metricFamily struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	series map[string]*metricSeries
+}
+
+// metricSeries is the value of a metric for one combination of label values.
+type metricSeries struct {
+	labelValues []string
+	value       float64
+	// buckets counts the observations of a histogram per upper bound, not cumulatively
+	buckets []uint64
+	count   uint64
+}
+
+// MetricsRegistry holds the metrics of the clients recording into it, see
+// InferableOptions.Metrics. By default, all clients in the process record into the registry served
+// by MetricsHandler, like the default registry of the Prometheus client.
+type MetricsRegistry struct {
+	mu       sync.Mutex
+	families []*metricFamily
+
+	// Metrics recorded from the clients' events. Calls and executions are labelled with their
+	// result type, "resolution", "rejection" or "interrupt".
+	toolCalls         *metricFamily
+	toolCallDuration  *metricFamily
+	executions        *metricFamily
+	executionDuration *metricFamily
+	polls             *metricFamily
+	polledCalls       *metricFamily
+	queueDepth        *metricFamily
+	inFlight          *metricFamily
+	retries           *metricFamily
+	errors            *metricFamily
+}
+
+// NewMetricsRegistry returns a registry with the metrics of the clients, for clients whose
+// metrics are served separately from those of the rest of the process, and for tests.
+func NewMetricsRegistry() *MetricsRegistry {
+	r := &MetricsRegistry{}
+	r.toolCalls = r.family("inferable_tool_calls_total", metricCounter, "Tool calls handled by the machine.", "tool", "result")
+	r.toolCallDuration = r.family("inferable_tool_call_duration_seconds", metricHistogram, "Duration of the tool calls handled by the machine.", "tool")
+	r.executions = r.family("inferable_workflow_executions_total", metricCounter, "Workflow handler executions run by the machine.", "workflow", "result")
+	r.executionDuration = r.family("inferable_workflow_execution_duration_seconds", metricHistogram, "Duration of the workflow handler executions run by the machine.", "workflow")
+	r.polls = r.family("inferable_polls_total", metricCounter, "Successful polls for calls.")
+	r.polledCalls = r.family("inferable_polled_calls_total", metricCounter, "Calls received by polls.")
+	r.queueDepth = r.family("inferable_queue_depth", metricGauge, "Calls received by polls and not handled yet.")
+	r.inFlight = r.family("inferable_calls_in_flight", metricGauge, "Calls being handled by the machine.")
+	r.retries = r.family("inferable_retries_total", metricCounter, "Operations attempted again, by operation.", "operation")
+	r.errors = r.family("inferable_errors_total", metricCounter, "Errors handled by the client without returning them, by operation.", "operation")
+	return r
+}
+
+// defaultMetrics is the registry of clients without InferableOptions.Metrics.
+var defaultMetrics = NewMetricsRegistry()
+
+// family registers a metric family.
+func (r *MetricsRegistry) family(name string, kind string, help string, labels ...string) *metricFamily {
+	family := &metricFamily{name: name, help: help, kind: kind, labels: labels, series: map[string]*metricSeries{}}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, family)
+	return family
+}
+
+// seriesOf returns the series of a family for the label values. The caller holds r.mu.
+func (f *metricFamily) seriesOf(labelValues []string) *metricSeries {
+	key := strings.Join(labelValues, "\x00")
+	series, ok := f.series[key]
+	if !ok {
+		series = &metricSeries{labelValues: labelValues}
+		if f.kind == metricHistogram {
+			series.buckets = make([]uint64, len(DefaultMetricsBuckets))
+		}
+		f.series[key] = series
+	}
+	return series
+}
+
+// add adds delta to a counter or gauge.
+func (r *MetricsRegistry) add(family *metricFamily, delta float64, labelValues ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	family.seriesOf(labelValues).value += delta
+}
+
+// observe records a value in a histogram.
+func (r *MetricsRegistry) observe(family *metricFamily, value float64, labelValues ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	series := family.seriesOf(labelValues)
+	series.value += value
+	series.count++
+	for i, bound := range DefaultMetricsBuckets {
+		if value <= bound {
+			series.buckets[i]++
+			break
+		}
+	}
+}
+
+// record updates the metrics with an event of a client.
+func (r *MetricsRegistry) record(event Event) {
+	switch event.Type {
+	case EventToolCallFinished:
+		r.add(r.toolCalls, 1, event.Tool, event.ResultType)
+		r.observe(r.toolCallDuration, event.Duration.Seconds(), event.Tool)
+	case EventHandlerFinished:
+		r.add(r.executions, 1, event.Workflow, event.ResultType)
+		r.observe(r.executionDuration, event.Duration.Seconds(), event.Workflow)
+	case EventPoll:
+		r.add(r.polls, 1)
+		r.add(r.polledCalls, float64(event.Calls))
+	case EventRetry:
+		r.add(r.retries, 1, event.Operation)
+	case EventError:
+		r.add(r.errors, 1, event.Operation)
+	}
+}
+
+// write writes the metrics in the Prometheus text format.
+func (r *MetricsRegistry) write(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var out strings.Builder
+	for _, family := range r.families {
+		fmt.Fprintf(&out, "# HELP %s %s\n# TYPE %s %s\n", family.name, family.help, family.name, family.kind)
+
+		keys := make([]string, 0, len(family.series))
+		for key := range family.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		// Families without labels are reported as zero until they are first updated
+		if len(keys) == 0 && len(family.labels) == 0 {
+			family.seriesOf(nil)
+			keys = append(keys, "")
+		}
+
+		for _, key := range keys {
+			series := family.series[key]
+			labels := formatMetricLabels(family.labels, series.labelValues)
+			if family.kind != metricHistogram {
+				fmt.Fprintf(&out, "%s%s %s\n", family.name, labels, formatMetricValue(series.value))
+				continue
+			}
+			cumulative := uint64(0)
+			for i, bound := range DefaultMetricsBuckets {
+				cumulative += series.buckets[i]
+				le := formatMetricLabels(append(append([]string{}, family.labels...), "le"), append(append([]string{}, series.labelValues...), formatMetricValue(bound)))
+				fmt.Fprintf(&out, "%s_bucket%s %d\n", family.name, le, cumulative)
+			}
+			inf := formatMetricLabels(append(append([]string{}, family.labels...), "le"), append(append([]string{}, series.labelValues...), "+Inf"))
+			fmt.Fprintf(&out, "%s_bucket%s %d\n", family.name, inf, series.count)
+			fmt.Fprintf(&out, "%s_sum%s %s\n", family.name, labels, formatMetricValue(series.value))
+			fmt.Fprintf(&out, "%s_count%s %d\n", family.name, labels, series.count)
+		}
+	}
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// formatMetricLabels formats label pairs as {name="value",...}, or "" for no labels.
+func formatMetricLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	escaper := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, escaper.Replace(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// formatMetricValue formats a sample value as Prometheus expects it.
+func formatMetricValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// MetricsHandler serves the metrics of the clients in the process in the Prometheus text format:
+// tool calls, failures and durations, workflow execution results and durations, polls, the
+// depth of the queue of polled calls, calls in flight, retries and handled errors. Clients with
+// their own InferableOptions.Metrics are served by the registry's Handler instead.
+//
+//	http.Handle("/metrics", inferable.MetricsHandler())
+func MetricsHandler() http.Handler {
+	return defaultMetrics.Handler()
+}
+
+// Handler serves the metrics of the registry in the Prometheus text format, see MetricsHandler.
+func (r *MetricsRegistry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.write(w)
+	})
+}
@@ -0,0 +1,72 @@
+package inferable
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsRegistry(t *testing.T) {
+	registry := &MetricsRegistry{}
+	calls := registry.family("test_calls_total", metricCounter, "Calls.", "tool")
+	duration := registry.family("test_duration_seconds", metricHistogram, "Durations.")
+	registry.family("test_depth", metricGauge, "Depth.")
+
+	registry.add(calls, 1, `say "hi"`)
+	registry.add(calls, 2, "charge")
+	registry.observe(duration, 0.02)
+	registry.observe(duration, 400)
+
+	var out strings.Builder
+	require.NoError(t, registry.write(&out))
+	rendered := out.String()
+
+	assert.Contains(t, rendered, "# HELP test_calls_total Calls.\n# TYPE test_calls_total counter\n"+
+		"test_calls_total{tool=\"charge\"} 2\n"+
+		"test_calls_total{tool=\"say \\\"hi\\\"\"} 1\n")
+	assert.Contains(t, rendered, "test_duration_seconds_bucket{le=\"0.01\"} 0\n"+
+		"test_duration_seconds_bucket{le=\"0.025\"} 1\n")
+	assert.Contains(t, rendered, "test_duration_seconds_bucket{le=\"300\"} 1\n"+
+		"test_duration_seconds_bucket{le=\"+Inf\"} 2\n"+
+		"test_duration_seconds_sum 400.02\n"+
+		"test_duration_seconds_count 2\n")
+	// Unlabelled metrics are reported before they are updated
+	assert.Contains(t, rendered, "# TYPE test_depth gauge\ntest_depth 0\n")
+}
+
+func TestMetricsHandler(t *testing.T) {
+	metrics := NewMetricsRegistry()
+	i, err := New(InferableOptions{KVStore: NewMemoryStore(), Offline: true, Metrics: metrics})
+	require.NoError(t, err)
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "metrics-test", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return "done", nil
+	})
+	_, err = workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	i.events.publish(Event{Type: EventToolCallFinished, Tool: "metrics-test-tool", ResultType: "rejection", Duration: time.Second})
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `inferable_workflow_executions_total{workflow="metrics-test",result="resolution"} 1`)
+	assert.Contains(t, body, `inferable_workflow_execution_duration_seconds_count{workflow="metrics-test"} 1`)
+	assert.Contains(t, body, `inferable_tool_calls_total{tool="metrics-test-tool",result="rejection"} 1`)
+	assert.Contains(t, body, `inferable_tool_call_duration_seconds_bucket{tool="metrics-test-tool",le="1"} 1`)
+	assert.Contains(t, body, "# TYPE inferable_queue_depth gauge")
+
+	// Clients without their own registry record into the one served by MetricsHandler
+	recorder = httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, recorder.Body.String(), "# TYPE inferable_tool_calls_total counter")
+	assert.NotContains(t, recorder.Body.String(), "metrics-test-tool")
+}
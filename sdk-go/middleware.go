@@ -0,0 +1,41 @@
+package inferable
+
+import "net/http"
+
+// RoundTrip sends a request to the cluster and returns its response. It implements
+// http.RoundTripper.
+type RoundTrip func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (r RoundTrip) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r(req)
+}
+
+// Middleware wraps the requests the client sends to the cluster, e.g. to decorate them with
+// headers, log them, record metrics or inject faults. It returns a RoundTrip that calls next to
+// send the request, or returns a response or error of its own without calling it.
+//
+//	func timing(next inferable.RoundTrip) inferable.RoundTrip {
+//		return func(req *http.Request) (*http.Response, error) {
+//			start := time.Now()
+//			resp, err := next(req)
+//			log.Printf("%s %s took %s", req.Method, req.URL.Path, time.Since(start))
+//			return resp, err
+//		}
+//	}
+type Middleware func(next RoundTrip) RoundTrip
+
+// withMiddleware wraps next with the middleware, the first of which is the outermost.
+func withMiddleware(next http.RoundTripper, middleware []Middleware) http.RoundTripper {
+	if len(middleware) == 0 {
+		return next
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	roundTrip := RoundTrip(next.RoundTrip)
+	for i := len(middleware) - 1; i >= 0; i-- {
+		roundTrip = middleware[i](roundTrip)
+	}
+	return roundTrip
+}
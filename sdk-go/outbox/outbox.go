@@ -0,0 +1,272 @@
+// Package outbox keeps workflow triggers consistent with business database writes using the
+// transactional outbox pattern. Executions are enqueued in the same transaction as the writes
+// they follow from, and a relay triggers them once the transaction has committed:
+//
+//	box := outbox.New(outbox.Options{})
+//
+//	tx, _ := db.BeginTx(ctx, nil)
+//	tx.ExecContext(ctx, "UPDATE orders SET status = 'paid' WHERE id = $1", orderId)
+//	box.Enqueue(ctx, tx, outbox.Message{
+//		Workflow:    "fulfilment",
+//		ExecutionID: "fulfil-" + orderId,
+//		Input:       map[string]interface{}{"orderId": orderId},
+//	})
+//	tx.Commit()
+//
+//	// In a long-running process
+//	go box.Relay(ctx, db, client.Workflows, outbox.RelayOptions{})
+//
+// The relay delivers each message at least once. The execution ID is the idempotency key: the
+// cluster creates one execution per ID, so a message triggered twice starts one execution.
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go"
+)
+
+const (
+	// DefaultTable is the name of the outbox table.
+	DefaultTable = "inferable_outbox"
+	// DefaultRelayInterval is how often the relay looks for messages when the outbox is empty.
+	DefaultRelayInterval = time.Second
+	// DefaultBatchSize is the number of messages the relay triggers per query.
+	DefaultBatchSize = 50
+	// DefaultMaxAttempts is the number of failed triggers after which a message is left for an operator.
+	DefaultMaxAttempts = 10
+)
+
+// Execer runs statements, e.g. a *sql.Tx or *sql.DB.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Trigger starts workflow executions. It is implemented by *inferable.Workflows.
+type Trigger interface {
+	Trigger(workflowName string, executionId string, input interface{}, options ...inferable.TriggerOptions) (*inferable.Execution, error)
+}
+
+// ContextTrigger is a Trigger whose requests can be bound to a context. The relay uses it, when
+// implemented, to stop waiting on the cluster when its context is cancelled.
+type ContextTrigger interface {
+	TriggerContext(ctx context.Context, workflowName string, executionId string, input interface{}, options ...inferable.TriggerOptions) (*inferable.Execution, error)
+}
+
+var (
+	_ Trigger        = (*inferable.Workflows)(nil)
+	_ ContextTrigger = (*inferable.Workflows)(nil)
+)
+
+// Options configures an Outbox.
+type Options struct {
+	// Table is the name of the outbox table, see CreateTableSQL. Defaults to DefaultTable.
+	Table string
+	// Placeholder returns the bind parameter for the n-th argument, counting from 1. Defaults to
+	// PostgreSQL's "$n"; use QuestionPlaceholder for MySQL and SQLite.
+	Placeholder func(n int) string
+}
+
+// QuestionPlaceholder is the Placeholder for drivers that bind "?" parameters.
+func QuestionPlaceholder(n int) string {
+	return "?"
+}
+
+// Message is an execution to trigger once the enqueuing transaction commits.
+type Message struct {
+	Workflow    string
+	ExecutionID string
+	Input       map[string]interface{}
+	// PartitionKey is passed as TriggerOptions.PartitionKey.
+	PartitionKey string
+}
+
+// Outbox enqueues and relays messages stored in a database table.
+type Outbox struct {
+	table       string
+	placeholder func(n int) string
+}
+
+// New creates an Outbox.
+func New(options Options) *Outbox {
+	if options.Table == "" {
+		options.Table = DefaultTable
+	}
+	if options.Placeholder == nil {
+		options.Placeholder = func(n int) string { return fmt.Sprintf("$%d", n) }
+	}
+	return &Outbox{table: options.Table, placeholder: options.Placeholder}
+}
+
+// CreateTableSQL returns a statement creating the outbox table, for use in a migration.
+func (o *Outbox) CreateTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  workflow VARCHAR(255) NOT NULL,
+  execution_id VARCHAR(255) NOT NULL,
+  partition_key VARCHAR(255) NOT NULL DEFAULT '',
+  input TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  triggered_at TIMESTAMP NULL,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT NULL,
+  PRIMARY KEY (workflow, execution_id)
+)`, o.table)
+}
+
+// Enqueue records a message in the caller's transaction. It is triggered only if the transaction
+// commits, and enqueuing the same workflow and execution ID twice fails on the primary key.
+func (o *Outbox) Enqueue(ctx context.Context, tx Execer, message Message) error {
+	if message.Workflow == "" || message.ExecutionID == "" {
+		return fmt.Errorf("outbox messages need a workflow and an execution ID")
+	}
+	if message.Input == nil {
+		message.Input = map[string]interface{}{}
+	}
+
+	input, err := json.Marshal(message.Input)
+	if err != nil {
+		return fmt.Errorf("failed to marshal input: %v", err)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (workflow, execution_id, partition_key, input, created_at) VALUES (%s)",
+		o.table, o.placeholders(1, 5))
+	if _, err := tx.ExecContext(ctx, query, message.Workflow, message.ExecutionID, message.PartitionKey, string(input), time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to enqueue message: %v", err)
+	}
+
+	return nil
+}
+
+// RelayOptions configures Relay.
+type RelayOptions struct {
+	// Interval is how often the outbox is checked while it is empty. Defaults to DefaultRelayInterval.
+	Interval time.Duration
+	// BatchSize is the number of messages read per query. Defaults to DefaultBatchSize.
+	BatchSize int
+	// MaxAttempts is the number of failed triggers after which a message is skipped. Defaults to
+	// DefaultMaxAttempts.
+	MaxAttempts int
+}
+
+// Relay triggers enqueued messages until ctx is cancelled. Run it in one or more long-running
+// processes; concurrent relays may trigger a message twice, which starts one execution.
+func (o *Outbox) Relay(ctx context.Context, db *sql.DB, trigger Trigger, options RelayOptions) error {
+	if options.Interval <= 0 {
+		options.Interval = DefaultRelayInterval
+	}
+
+	for {
+		relayed, err := o.RelayOnce(ctx, db, trigger, options)
+		if err != nil {
+			log.Printf("Failed to relay outbox messages: %v", err)
+		}
+
+		// Keep draining while there is a backlog
+		if err == nil && relayed > 0 {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(options.Interval):
+		}
+	}
+}
+
+// RelayOnce triggers one batch of enqueued messages, oldest first, and returns how many were
+// triggered. Failed triggers are recorded on the message and retried by later calls.
+func (o *Outbox) RelayOnce(ctx context.Context, db *sql.DB, trigger Trigger, options RelayOptions) (int, error) {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultBatchSize
+	}
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = DefaultMaxAttempts
+	}
+
+	query := fmt.Sprintf("SELECT workflow, execution_id, partition_key, input FROM %s WHERE triggered_at IS NULL AND attempts < %s ORDER BY created_at LIMIT %d",
+		o.table, o.placeholder(1), options.BatchSize)
+	rows, err := db.QueryContext(ctx, query, options.MaxAttempts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %v", err)
+	}
+
+	messages := []Message{}
+	for rows.Next() {
+		var message Message
+		var input string
+		if err := rows.Scan(&message.Workflow, &message.ExecutionID, &message.PartitionKey, &input); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox message: %v", err)
+		}
+		if err := json.Unmarshal([]byte(input), &message.Input); err != nil {
+			message.Input = nil
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("failed to read outbox: %v", err)
+	}
+	rows.Close()
+
+	relayed := 0
+	for _, message := range messages {
+		if err := o.relay(ctx, db, trigger, message); err != nil {
+			return relayed, err
+		}
+		relayed++
+	}
+
+	return relayed, nil
+}
+
+// relay triggers one message and records the outcome. A trigger failure is recorded rather than
+// returned, so one bad message does not hold up the rest.
+func (o *Outbox) relay(ctx context.Context, db *sql.DB, trigger Trigger, message Message) error {
+	var triggerErr error
+	if message.Input == nil {
+		triggerErr = fmt.Errorf("stored input is not a JSON object")
+	} else {
+		options := inferable.TriggerOptions{PartitionKey: message.PartitionKey}
+		if bounded, ok := trigger.(ContextTrigger); ok {
+			_, triggerErr = bounded.TriggerContext(ctx, message.Workflow, message.ExecutionID, message.Input, options)
+		} else {
+			_, triggerErr = trigger.Trigger(message.Workflow, message.ExecutionID, message.Input, options)
+		}
+	}
+
+	if triggerErr != nil {
+		query := fmt.Sprintf("UPDATE %s SET attempts = attempts + 1, last_error = %s WHERE workflow = %s AND execution_id = %s",
+			o.table, o.placeholder(1), o.placeholder(2), o.placeholder(3))
+		if _, err := db.ExecContext(ctx, query, triggerErr.Error(), message.Workflow, message.ExecutionID); err != nil {
+			return fmt.Errorf("failed to record trigger failure: %v", err)
+		}
+		return nil
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET triggered_at = %s WHERE workflow = %s AND execution_id = %s",
+		o.table, o.placeholder(1), o.placeholder(2), o.placeholder(3))
+	if _, err := db.ExecContext(ctx, query, time.Now().UTC(), message.Workflow, message.ExecutionID); err != nil {
+		return fmt.Errorf("failed to mark message as triggered: %v", err)
+	}
+
+	return nil
+}
+
+func (o *Outbox) placeholders(from int, count int) string {
+	placeholders := make([]string, count)
+	for n := range placeholders {
+		placeholders[n] = o.placeholder(from + n)
+	}
+	return strings.Join(placeholders, ", ")
+}
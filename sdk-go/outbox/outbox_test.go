@@ -0,0 +1,221 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+type testRow struct {
+	workflow, executionId, partitionKey, input string
+	triggered                                  bool
+	attempts                                   int64
+	lastError                                  string
+}
+
+// testOutboxDriver keeps the outbox table in memory. Inserts made in a transaction are applied
+// when it commits.
+type testOutboxDriver struct {
+	mu   sync.Mutex
+	rows []*testRow
+}
+
+func (d *testOutboxDriver) Open(name string) (driver.Conn, error) {
+	return &testOutboxConn{driver: d}, nil
+}
+
+func (d *testOutboxDriver) row(workflow, executionId driver.Value) *testRow {
+	for _, row := range d.rows {
+		if row.workflow == workflow && row.executionId == executionId {
+			return row
+		}
+	}
+	return nil
+}
+
+type testOutboxConn struct {
+	driver  *testOutboxDriver
+	pending []*testRow
+	inTx    bool
+}
+
+func (c *testOutboxConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *testOutboxConn) Close() error                              { return nil }
+func (c *testOutboxConn) Begin() (driver.Tx, error)                 { c.inTx = true; return c, nil }
+
+func (c *testOutboxConn) Commit() error {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+	c.driver.rows = append(c.driver.rows, c.pending...)
+	c.pending, c.inTx = nil, false
+	return nil
+}
+
+func (c *testOutboxConn) Rollback() error {
+	c.pending, c.inTx = nil, false
+	return nil
+}
+
+func (c *testOutboxConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO inferable_outbox"):
+		if c.driver.row(args[0].Value, args[1].Value) != nil {
+			return nil, fmt.Errorf("duplicate key")
+		}
+		row := &testRow{
+			workflow:     args[0].Value.(string),
+			executionId:  args[1].Value.(string),
+			partitionKey: args[2].Value.(string),
+			input:        args[3].Value.(string),
+		}
+		if c.inTx {
+			c.pending = append(c.pending, row)
+		} else {
+			c.driver.rows = append(c.driver.rows, row)
+		}
+	case strings.Contains(query, "SET attempts = attempts + 1"):
+		row := c.driver.row(args[1].Value, args[2].Value)
+		row.attempts++
+		row.lastError = args[0].Value.(string)
+	case strings.Contains(query, "SET triggered_at"):
+		c.driver.row(args[1].Value, args[2].Value).triggered = true
+	default:
+		return nil, fmt.Errorf("unexpected statement: %s", query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *testOutboxConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.mu.Lock()
+	defer c.driver.mu.Unlock()
+
+	rows := &testOutboxRows{}
+	for _, row := range c.driver.rows {
+		if !row.triggered && row.attempts < args[0].Value.(int64) {
+			rows.values = append(rows.values, []driver.Value{row.workflow, row.executionId, row.partitionKey, row.input})
+		}
+	}
+	return rows, nil
+}
+
+type testOutboxRows struct{ values [][]driver.Value }
+
+func (r *testOutboxRows) Columns() []string {
+	return []string{"workflow", "execution_id", "partition_key", "input"}
+}
+func (r *testOutboxRows) Close() error { return nil }
+
+func (r *testOutboxRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+type testTrigger struct {
+	triggered []string
+	keys      []string
+	fail      map[string]bool
+}
+
+func (t *testTrigger) Trigger(workflowName string, executionId string, input interface{}, options ...inferable.TriggerOptions) (*inferable.Execution, error) {
+	if t.fail[executionId] {
+		return nil, fmt.Errorf("cluster unavailable")
+	}
+	t.triggered = append(t.triggered, fmt.Sprintf("%s/%s", workflowName, executionId))
+	t.keys = append(t.keys, options[0].PartitionKey)
+	return nil, nil
+}
+
+// testConnector opens connections of a driver without registering it, as a driver name can
+// only be registered once per process.
+type testConnector struct{ driver driver.Driver }
+
+func (c testConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open("") }
+func (c testConnector) Driver() driver.Driver                        { return c.driver }
+
+func openTestDB(t *testing.T) (*sql.DB, *testOutboxDriver) {
+	d := &testOutboxDriver{}
+	db := sql.OpenDB(testConnector{driver: d})
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestEnqueueIsTransactional(t *testing.T) {
+	db, _ := openTestDB(t)
+	box := New(Options{})
+	ctx := context.Background()
+
+	committed, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, box.Enqueue(ctx, committed, Message{Workflow: "fulfilment", ExecutionID: "fulfil-1", PartitionKey: "customer-1"}))
+	require.NoError(t, committed.Commit())
+
+	rolledBack, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, box.Enqueue(ctx, rolledBack, Message{Workflow: "fulfilment", ExecutionID: "fulfil-2"}))
+	require.NoError(t, rolledBack.Rollback())
+
+	assert.ErrorContains(t, box.Enqueue(ctx, db, Message{Workflow: "fulfilment"}), "execution ID")
+	assert.ErrorContains(t, box.Enqueue(ctx, db, Message{Workflow: "fulfilment", ExecutionID: "fulfil-1"}), "duplicate key")
+
+	trigger := &testTrigger{}
+	relayed, err := box.RelayOnce(ctx, db, trigger, RelayOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, relayed)
+	assert.Equal(t, []string{"fulfilment/fulfil-1"}, trigger.triggered)
+	assert.Equal(t, []string{"customer-1"}, trigger.keys)
+
+	// Triggered messages are not relayed again
+	relayed, err = box.RelayOnce(ctx, db, trigger, RelayOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, relayed)
+}
+
+func TestRelayRetriesFailedTriggers(t *testing.T) {
+	db, d := openTestDB(t)
+	box := New(Options{})
+	ctx := context.Background()
+
+	require.NoError(t, box.Enqueue(ctx, db, Message{Workflow: "fulfilment", ExecutionID: "fulfil-1"}))
+	require.NoError(t, box.Enqueue(ctx, db, Message{Workflow: "fulfilment", ExecutionID: "fulfil-2"}))
+
+	trigger := &testTrigger{fail: map[string]bool{"fulfil-1": true}}
+	for n := 0; n < 3; n++ {
+		_, err := box.RelayOnce(ctx, db, trigger, RelayOptions{MaxAttempts: 2})
+		require.NoError(t, err)
+	}
+
+	// The failing message does not hold up the other, and is given up on after MaxAttempts
+	assert.Equal(t, []string{"fulfilment/fulfil-2"}, trigger.triggered)
+	assert.Equal(t, int64(2), d.rows[0].attempts)
+	assert.Equal(t, "cluster unavailable", d.rows[0].lastError)
+
+	trigger.fail = nil
+	_, err := box.RelayOnce(ctx, db, trigger, RelayOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fulfilment/fulfil-2", "fulfilment/fulfil-1"}, trigger.triggered)
+}
+
+func TestOptions(t *testing.T) {
+	box := New(Options{Table: "events_outbox", Placeholder: QuestionPlaceholder})
+	assert.Contains(t, box.CreateTableSQL(), "CREATE TABLE IF NOT EXISTS events_outbox")
+	assert.Equal(t, "?, ?, ?", box.placeholders(1, 3))
+	assert.Equal(t, "$2, $3", New(Options{}).placeholders(2, 2))
+}
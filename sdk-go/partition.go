@@ -0,0 +1,134 @@
+package inferable
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// PartitionKeyField is the input field that carries the partition key of an execution triggered
+// with TriggerOptions.PartitionKey. Add it to the input struct to read the key in the handler.
+const PartitionKeyField = "partitionKey"
+
+// TriggerOptions configures Workflows.Trigger.
+type TriggerOptions struct {
+	// PartitionKey routes the execution of a partitioned workflow to the partition owning the key,
+	// so the handler runs for the same key are processed one at a time, in the order they are
+	// picked up. An execution paused by an interrupt or a sleep does not hold up the others.
+	PartitionKey string
+	// Partitions is the partition count of the workflow. Defaults to WorkflowConfig.Partitions of
+	// the workflow with the same name created with this client.
+	Partitions int
+	// ParentExecutionID makes the execution a child of the given execution, so that it is listed
+	// by WorkflowExecutions.Children and cancelled with it by a cascading Cancel. ctx.Publish sets
+	// it to the publishing execution.
+	ParentExecutionID string
+	// TenantKey identifies the caller the execution is triggered for, and counts the trigger
+	// against its TriggerQuota.PerTenant.
+	TenantKey string
+	// ResumeCallID is an interrupted tool call that the cluster resumes once the execution has
+	// resolved or was rejected, e.g. a call delegating to a workflow exposed as a tool.
+	ResumeCallID string
+}
+
+// PartitionFor returns the partition a key belongs to, out of partitions. The mapping is stable,
+// and changing the partition count moves as few keys as possible (jump consistent hashing).
+func PartitionFor(key string, partitions int) int {
+	if partitions <= 1 {
+		return 0
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(key))
+	h := hash.Sum64()
+
+	b, j := int64(-1), int64(0)
+	for j < int64(partitions) {
+		b = j
+		h = h*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((h>>33)+1)))
+	}
+	return int(b)
+}
+
+// PartitionWorkflowName returns the name the cluster knows a partition of a workflow by, e.g. to
+// look up a partitioned execution with Workflows.GetExecution.
+func PartitionWorkflowName(workflowName string, partition int) string {
+	return fmt.Sprintf("%s_p%d", workflowName, partition)
+}
+
+// PartitionsFor spreads partitions evenly over replicas and returns the ones owned by replica
+// (numbered from 0), e.g. a StatefulSet ordinal:
+//
+//	workflow.Listen(inferable.ListenOptions{
+//		Partitions: inferable.PartitionsFor(ordinal, replicas, 16),
+//	})
+func PartitionsFor(replica int, replicas int, partitions int) []int {
+	owned := []int{}
+	if replicas <= 0 {
+		return owned
+	}
+	for partition := 0; partition < partitions; partition++ {
+		if partition%replicas == replica {
+			owned = append(owned, partition)
+		}
+	}
+	return owned
+}
+
+// partitionLocks serializes the handler runs of executions with the same partition key within
+// this machine. A lock is held for one run of the handler: an execution paused by an interrupt
+// or a sleep releases it, so other executions for the key may run before it resumes.
+type partitionLocks struct {
+	mu    sync.Mutex
+	locks map[string]*partitionLock
+}
+
+// partitionLock is the lock of a key, counting the runs holding or waiting for it, so that it is
+// dropped when the last one unlocks.
+type partitionLock struct {
+	sync.Mutex
+	refs int
+}
+
+func (p *partitionLocks) lock(key string) func() {
+	p.mu.Lock()
+	if p.locks == nil {
+		p.locks = map[string]*partitionLock{}
+	}
+	lock, ok := p.locks[key]
+	if !ok {
+		lock = &partitionLock{}
+		p.locks[key] = lock
+	}
+	lock.refs++
+	p.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(p.locks, key)
+		}
+	}
+}
+
+// partitionKeyOf returns the partition key of a handler input, read from the field tagged
+// PartitionKeyField, or "" if the input has none.
+func partitionKeyOf(input reflect.Value) string {
+	inputType := input.Type()
+	for i := 0; i < inputType.NumField(); i++ {
+		field := inputType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == PartitionKeyField && input.Field(i).Kind() == reflect.String {
+			return input.Field(i).String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,161 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type partitionedInput struct {
+	ExecutionID  string `json:"executionId"`
+	PartitionKey string `json:"partitionKey"`
+}
+
+func TestPartitionFor(t *testing.T) {
+	assert.Equal(t, 0, PartitionFor("customer-1", 0))
+	assert.Equal(t, PartitionFor("customer-1", 8), PartitionFor("customer-1", 8))
+
+	seen := map[int]bool{}
+	moved := 0
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("customer-%d", i)
+		partition := PartitionFor(key, 8)
+		require.True(t, partition >= 0 && partition < 8)
+		seen[partition] = true
+		if PartitionFor(key, 9) != partition {
+			moved++
+		}
+	}
+	assert.Len(t, seen, 8)
+	// Adding a partition only moves the keys it takes over, about one in nine
+	assert.InDelta(t, 1000/9, moved, 40)
+}
+
+func TestPartitionsFor(t *testing.T) {
+	assert.Equal(t, []int{0, 3, 6}, PartitionsFor(0, 3, 8))
+	assert.Equal(t, []int{2, 5}, PartitionsFor(2, 3, 8))
+	assert.Empty(t, PartitionsFor(0, 0, 8))
+}
+
+func TestPartitionedWorkflowTools(t *testing.T) {
+	i := newTestClient(t, InferableOptions{}, nil)
+
+	plain := i.Workflows.Create(WorkflowConfig{Name: "plain", InputSchema: WorkflowInput{}})
+	assert.ErrorContains(t, plain.Listen(ListenOptions{Partitions: []int{0}}), "not partitioned")
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: partitionedInput{}, Partitions: 3})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input partitionedInput) (interface{}, error) {
+		return nil, nil
+	})
+	assert.ErrorContains(t, workflow.Listen(ListenOptions{Partitions: []int{3}}), "out of range")
+
+	names := func() []string {
+		names := []string{}
+		for _, tool := range workflow.clusterTools() {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"workflows_orders_p0_1", "workflows_orders_p1_1", "workflows_orders_p2_1"}, names())
+
+	workflow.ownedPartitions = []int{1}
+	assert.Equal(t, []string{"workflows_orders_p1_1"}, names())
+}
+
+func TestTriggerWithPartitionKey(t *testing.T) {
+	var mu sync.Mutex
+	paths := []string{}
+	bodies := []map[string]interface{}{}
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/": func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			body := map[string]interface{}{}
+			json.Unmarshal(data, &body)
+
+			mu.Lock()
+			paths = append(paths, r.URL.Path)
+			bodies = append(bodies, body)
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		},
+	})
+	i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: partitionedInput{}, Partitions: 4})
+
+	trigger := func(workflowName string, executionId string, options ...TriggerOptions) error {
+		_, err := i.Workflows.Trigger(workflowName, executionId, map[string]interface{}{}, options...)
+		return err
+	}
+	require.NoError(t, trigger("orders", "exec-1", TriggerOptions{PartitionKey: "customer-1"}))
+	require.NoError(t, trigger("other", "exec-2", TriggerOptions{PartitionKey: "customer-1", Partitions: 2}))
+	require.NoError(t, trigger("other", "exec-3"))
+	assert.ErrorContains(t, trigger("unknown", "exec-4", TriggerOptions{PartitionKey: "customer-1"}), "partition count")
+
+	assert.Equal(t, []string{
+		fmt.Sprintf("/clusters/test-cluster/workflows/orders_p%d/executions", PartitionFor("customer-1", 4)),
+		fmt.Sprintf("/clusters/test-cluster/workflows/other_p%d/executions", PartitionFor("customer-1", 2)),
+		"/clusters/test-cluster/workflows/other/executions",
+	}, paths)
+	assert.Equal(t, "customer-1", bodies[0]["partitionKey"])
+	assert.NotContains(t, bodies[2], "partitionKey")
+}
+
+func TestPartitionLocksAreReleased(t *testing.T) {
+	var locks partitionLocks
+	unlock := locks.lock("customer-1")
+
+	acquired := make(chan func())
+	go func() { acquired <- locks.lock("customer-1") }()
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	unlock()
+	(<-acquired)()
+	locks.lock("customer-2")()
+
+	// Locks of keys without runs are dropped
+	assert.Empty(t, locks.locks)
+}
+
+func TestPartitionedExecutionsAreSerial(t *testing.T) {
+	i := newTestClient(t, InferableOptions{KVStore: NewMemoryStore()}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: partitionedInput{}, Partitions: 2})
+
+	var running, overlaps atomic.Int32
+	workflow.Version(1).Define(func(ctx WorkflowContext, input partitionedInput) (interface{}, error) {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		return nil, nil
+	})
+
+	// Resolve the cluster before executing concurrently
+	_, err := i.getClusterId()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for n := 0; n < 5; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			input := partitionedInput{ExecutionID: fmt.Sprintf("exec-%d", n), PartitionKey: "customer-1"}
+			_, err := workflow.Execute(1, input, ContextInput{})
+			assert.NoError(t, err)
+		}(n)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(0), overlaps.Load())
+}
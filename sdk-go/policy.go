@@ -0,0 +1,147 @@
+package inferable
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrPolicyViolation is returned (wrapped in a *PolicyViolationError) when an LLM or agent call
+// would use a model or provider its ModelPolicy does not allow. Check for it with errors.Is.
+var ErrPolicyViolation = errors.New("model policy violation")
+
+// ModelPolicy routes an execution's LLM and agent calls, and restricts the models and providers
+// they may use, e.g. so that EU tenants only reach providers through EU endpoints. Policies are
+// resolved per execution by a ModelPolicyResolver, and checked before every ctx.LLM and
+// ctx.Agents call; calls they do not allow fail with a *PolicyViolationError without reaching
+// the provider.
+type ModelPolicy struct {
+	// Name identifies the policy in errors, e.g. "eu-tenants".
+	Name string `json:"name"`
+	// Model is used by calls that specify no model, unless the workflow has a Model.
+	Model string `json:"model,omitempty"`
+	// Provider is used by calls that specify no provider, unless a ProviderResolver returns one.
+	Provider *Provider `json:"provider,omitempty"`
+	// AllowedModels lists the models calls may use. Empty allows any model.
+	AllowedModels []string `json:"allowedModels,omitempty"`
+	// AllowedProviders lists the provider endpoints calls may use, as URLs whose scheme and host
+	// must match and whose path must prefix the provider's, e.g. "https://eu.api.example.com".
+	// When set, calls through the cluster's default provider are not allowed. Empty allows any
+	// provider.
+	AllowedProviders []string `json:"allowedProviders,omitempty"`
+}
+
+// ModelPolicyResolver derives the ModelPolicy of an execution's LLM and agent calls from its
+// context, e.g. from the tenant identified by ContextInput.AuthContext. Returning a nil policy
+// does not restrict the calls.
+type ModelPolicyResolver func(ctx ContextInput) (*ModelPolicy, error)
+
+// PoliciesByTenant resolves the policy of the tenant that tenantOf identifies from a call's
+// context, so that the rules can be kept in configuration. Tenants without a policy get
+// fallback, which may be nil to not restrict them.
+//
+//	client, err := inferable.New(inferable.InferableOptions{
+//		ModelPolicy: inferable.PoliciesByTenant(tenantOf, map[string]*inferable.ModelPolicy{
+//			"acme-eu": {Name: "eu", AllowedProviders: []string{"https://eu.api.example.com"}},
+//		}, nil),
+//	})
+func PoliciesByTenant(tenantOf func(ctx ContextInput) (string, error), policies map[string]*ModelPolicy, fallback *ModelPolicy) ModelPolicyResolver {
+	return func(ctx ContextInput) (*ModelPolicy, error) {
+		tenant, err := tenantOf(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if policy, ok := policies[tenant]; ok {
+			return policy, nil
+		}
+		return fallback, nil
+	}
+}
+
+// PolicyViolationError describes a call rejected by its ModelPolicy.
+type PolicyViolationError struct {
+	// Policy is the name of the policy.
+	Policy string
+	// Model is the model the call would have used.
+	Model string
+	// ProviderURL is the endpoint of the provider the call would have used, or empty for the
+	// cluster's default provider.
+	ProviderURL string
+	// Reason says what the policy does not allow.
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("model policy %s does not allow the call: %s", e.Policy, e.Reason)
+}
+
+// Unwrap allows errors.Is(err, ErrPolicyViolation).
+func (e *PolicyViolationError) Unwrap() error {
+	return ErrPolicyViolation
+}
+
+// check returns a *PolicyViolationError if the policy does not allow a call with model through
+// provider. A nil policy allows any call.
+func (p *ModelPolicy) check(model string, provider *Provider) error {
+	if p == nil {
+		return nil
+	}
+
+	providerURL := ""
+	if provider != nil {
+		providerURL = provider.URL
+	}
+	violation := func(reason string) error {
+		return &PolicyViolationError{Policy: p.Name, Model: model, ProviderURL: providerURL, Reason: reason}
+	}
+
+	if len(p.AllowedModels) > 0 && !contains(p.AllowedModels, model) {
+		return violation(fmt.Sprintf("model %q is not allowed", model))
+	}
+
+	if len(p.AllowedProviders) > 0 {
+		if providerURL == "" {
+			return violation("the cluster's default provider is not allowed")
+		}
+		allowed := false
+		for _, endpoint := range p.AllowedProviders {
+			if endpointAllows(endpoint, providerURL) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return violation(fmt.Sprintf("provider %s is not allowed", providerURL))
+		}
+	}
+	return nil
+}
+
+// endpointAllows reports whether providerURL is on the allowed endpoint: same scheme and host,
+// and a path under the endpoint's.
+func endpointAllows(endpoint string, providerURL string) bool {
+	allowed, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	actual, err := url.Parse(providerURL)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(allowed.Scheme, actual.Scheme) || !strings.EqualFold(allowed.Host, actual.Host) {
+		return false
+	}
+	prefix := strings.TrimSuffix(allowed.Path, "/")
+	return actual.Path == prefix || strings.HasPrefix(actual.Path, prefix+"/")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package inferable
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModelPolicyCheck(t *testing.T) {
+	policy := &ModelPolicy{
+		Name:             "eu",
+		AllowedModels:    []string{"claude-sonnet"},
+		AllowedProviders: []string{"https://eu.api.example.com/v1"},
+	}
+
+	assert.NoError(t, policy.check("claude-sonnet", &Provider{URL: "https://eu.api.example.com/v1"}))
+	assert.NoError(t, policy.check("claude-sonnet", &Provider{URL: "https://EU.api.example.com/v1/messages"}))
+
+	for _, provider := range []*Provider{
+		nil,
+		{URL: "https://us.api.example.com/v1"},
+		{URL: "https://eu.api.example.com.attacker.test/v1"},
+		{URL: "https://eu.api.example.com/v10"},
+		{URL: "http://eu.api.example.com/v1"},
+	} {
+		err := policy.check("claude-sonnet", provider)
+		assert.ErrorIs(t, err, ErrPolicyViolation, "provider %v", provider)
+	}
+
+	err := policy.check("claude-opus", &Provider{URL: "https://eu.api.example.com/v1"})
+	var violation *PolicyViolationError
+	require.True(t, errors.As(err, &violation))
+	assert.Equal(t, "claude-opus", violation.Model)
+	assert.EqualError(t, err, `model policy eu does not allow the call: model "claude-opus" is not allowed`)
+
+	// No policy allows everything
+	var none *ModelPolicy
+	assert.NoError(t, none.check("any", nil))
+}
+
+func TestWorkflowModelPolicy(t *testing.T) {
+	var mu sync.Mutex
+	providers := []string{}
+	record := func(r *http.Request) {
+		mu.Lock()
+		providers = append(providers, r.Header.Get("X-Provider-Url"))
+		mu.Unlock()
+	}
+
+	tenantOf := func(ctx ContextInput) (string, error) {
+		auth, _ := ctx.AuthContext.(map[string]interface{})
+		tenant, _ := auth["tenant"].(string)
+		return tenant, nil
+	}
+	i := newTestClient(t, InferableOptions{
+		KVStore: NewMemoryStore(),
+		ModelPolicy: PoliciesByTenant(tenantOf, map[string]*ModelPolicy{
+			"acme-eu": {
+				Name:             "eu",
+				Provider:         &Provider{URL: "https://eu.api.example.com", Key: "eu-key"},
+				AllowedProviders: []string{"https://eu.api.example.com"},
+			},
+		}, nil),
+	}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/l1m/structured": func(w http.ResponseWriter, r *http.Request) {
+			record(r)
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"summary": "ok"}})
+		},
+		"/clusters/test-cluster/runs": func(w http.ResponseWriter, r *http.Request) {
+			record(r)
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]interface{}{"status": "done", "result": "done"})
+		},
+	})
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "summaries", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		if _, err := ctx.LLM.Structured(StructuredInput{Input: "Summarize"}); err != nil {
+			return nil, err
+		}
+		if _, _, err := ctx.Agents.React(ReactAgentConfig{Name: "researcher", Input: "Research"}); err != nil {
+			return nil, err
+		}
+		// A call overriding the tenant's provider is rejected
+		_, err := ctx.LLM.Structured(StructuredInput{Input: "Summarize", Provider: &Provider{URL: "https://us.api.example.com"}})
+		return nil, err
+	})
+
+	// The EU tenant's calls are routed through, and restricted to, the EU endpoint
+	_, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{AuthContext: map[string]interface{}{"tenant": "acme-eu"}})
+	assert.ErrorIs(t, err, ErrPolicyViolation)
+	assert.ErrorContains(t, err, "provider https://us.api.example.com is not allowed")
+	assert.Equal(t, []string{"https://eu.api.example.com", "https://eu.api.example.com"}, providers)
+
+	// Other tenants are not restricted
+	providers = []string{}
+	workflow.Version(2).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return ctx.LLM.Structured(StructuredInput{Input: "Summarize", Provider: &Provider{URL: "https://us.api.example.com"}})
+	})
+	_, err = workflow.Execute(2, WorkflowInput{ExecutionID: "exec-2"}, ContextInput{AuthContext: map[string]interface{}{"tenant": "acme-us"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://us.api.example.com"}, providers)
+}
@@ -4,10 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/invopop/jsonschema"
@@ -26,20 +27,139 @@ type Tool struct {
 	schema      interface{}
 	Config      interface{}
 	Func        interface{}
+	// Dedupe records the result of each call in the KVStore before reporting it, and answers a
+	// redelivered call (after a timeout, or a crash between running the call and reporting its
+	// result) with the recorded result instead of running Func again. Use it for side effects such
+	// as charging a card.
+	Dedupe bool
+	// Delivery is DeliveryAtLeastOnce (the default) or DeliveryAtMostOnce, and is declared in the
+	// tool's registration config.
+	Delivery string
+	// Coerce converts mistyped input values, such as "42" for an int field, to the input type's
+	// field types instead of rejecting the call. Inputs that still do not match are rejected with
+	// an InputValidationError either way.
+	Coerce bool
+	// Examples are example invocations shown to the model with the description. Each must decode
+	// into the input type.
+	Examples []ToolExample
+	// Tags group tools for catalog tooling, see Catalog.
+	Tags []string
+	// UsageHints tell the model when and how to use the tool, e.g. which tool to prefer instead.
+	UsageHints string
+	// Capability is ToolCapabilityRead or ToolCapabilityMutate, and lets agent runs scoped to
+	// read-only tools exclude the tool, see ReactAgentConfig.Capabilities. Tools without one are
+	// treated as mutating.
+	Capability string
+	// PostProcessors transform the tool's results, in order, before they are sent into the agent's
+	// context, e.g. TruncateResult to bound their size. Rejections and interrupts are sent as is.
+	PostProcessors []ResultProcessor
+	// hiddenFields are input fields left out of the schema shown to the model.
+	hiddenFields []string
 }
 
 type ContextInput struct {
 	AuthContext interface{} `json:"authContext,omitempty"`
 	RunContext  interface{} `json:"runContext,omitempty"`
 	Approved    bool        `json:"approved"`
+	// InterruptTimedOut reports that the call runs again because nobody resolved its interrupt
+	// before the deadline, see Interrupt.WithTimeout.
+	InterruptTimedOut bool `json:"interruptTimedOut,omitempty"`
+	// ApprovalOption is the option chosen to approve the call, see Interrupt.WithOptions.
+	ApprovalOption string `json:"approvalOption,omitempty"`
+	// CallID identifies the tool call, and is the same for every delivery of the call.
+	CallID string `json:"callId,omitempty"`
 }
 
 type pollingAgent struct {
-	Tools      map[string]Tool
-	inferable  *Inferable
-	ctx        context.Context
-	cancel     context.CancelFunc
-	retryAfter int
+	Tools     map[string]Tool
+	inferable *Inferable
+	ctx       context.Context
+	cancel    context.CancelFunc
+	options   PollOptions
+	// retryAfter is the delay in seconds the cluster asked for before the next poll
+	retryAfter atomic.Int64
+	// stopped is closed when the polling loops have returned, after handling the calls they received
+	stopped chan struct{}
+	// stopOnce runs stop once per Listen
+	stopOnce *sync.Once
+	// registerMu serializes the re-registration of the machine by the pollers
+	registerMu sync.Mutex
+
+	// Counters behind Stats
+	polls          atomic.Int64
+	saturatedPolls atomic.Int64
+	jobs           atomic.Int64
+	busy           atomic.Int64
+	inFlight       atomic.Int64
+}
+
+const (
+	// DefaultPollBatchSize is the most calls acknowledged per poll when PollOptions.BatchSize is
+	// unset.
+	DefaultPollBatchSize = 10
+	// MaxPollBatchSize is the most calls the cluster acknowledges per poll.
+	MaxPollBatchSize = 20
+	// pollWaitTime is how long, in seconds, a poll waits for calls before returning none.
+	pollWaitTime = 20
+)
+
+// PollOptions tunes the polling loop of Listen.
+type PollOptions struct {
+	// Interval is the pause between the polls of each poller, to reduce requests to the cluster
+	// from low-priority workers. Each poll already waits up to 20 seconds for calls, so by default
+	// a poller polls again as soon as it has handled the calls it received.
+	Interval time.Duration
+	// BatchSize is the most calls acknowledged per poll, up to MaxPollBatchSize. Calls of a batch
+	// are handled one after the other. Defaults to DefaultPollBatchSize.
+	BatchSize int
+	// Pollers is the number of concurrent polling loops, each handling the calls it receives, for
+	// workers that need to pick up calls faster than one loop handles them. Defaults to 1.
+	Pollers int
+}
+
+// mergePollOptions merges the variadic options of Listen, later options taking precedence, and
+// validates them.
+func mergePollOptions(options []PollOptions) (PollOptions, error) {
+	merged := PollOptions{BatchSize: DefaultPollBatchSize, Pollers: 1}
+	for _, option := range options {
+		if option.Interval < 0 || option.BatchSize < 0 || option.Pollers < 0 {
+			return PollOptions{}, fmt.Errorf("poll options must not be negative")
+		}
+		if option.Interval > 0 {
+			merged.Interval = option.Interval
+		}
+		if option.BatchSize > 0 {
+			merged.BatchSize = option.BatchSize
+		}
+		if option.Pollers > 0 {
+			merged.Pollers = option.Pollers
+		}
+	}
+	if merged.BatchSize > MaxPollBatchSize {
+		return PollOptions{}, fmt.Errorf("poll batch size %d is above the maximum of %d", merged.BatchSize, MaxPollBatchSize)
+	}
+	return merged, nil
+}
+
+// PollingStats summarizes the work done by this machine's polling loop since it was created.
+type PollingStats struct {
+	Polls int64
+	// SaturatedPolls counts polls that returned a full batch, meaning more jobs were likely queued.
+	SaturatedPolls int64
+	// Jobs is the number of tool and workflow calls handled.
+	Jobs int64
+	// Busy is the total time spent handling jobs rather than waiting for them.
+	Busy time.Duration
+}
+
+// Stats returns counters for the polling loop, e.g. to measure worker saturation under load.
+func (s *pollingAgent) Stats() PollingStats {
+	return PollingStats{
+		Polls:          s.polls.Load(),
+		SaturatedPolls: s.saturatedPolls.Load(),
+		Jobs:           s.jobs.Load(),
+		Busy:           time.Duration(s.busy.Load()),
+	}
 }
 
 type callMessage struct {
@@ -49,6 +169,10 @@ type callMessage struct {
 	AuthContext interface{} `json:"authContext,omitempty"`
 	RunContext  interface{} `json:"runContext,omitempty"`
 	Approved    bool        `json:"approved"`
+	// InterruptTimedOut is set when the call runs again because its interrupt expired.
+	InterruptTimedOut bool `json:"interruptTimedOut,omitempty"`
+	// ApprovalOption is the option chosen to approve the call.
+	ApprovalOption string `json:"approvalOption,omitempty"`
 }
 
 type callResultMeta struct {
@@ -97,16 +221,53 @@ func (s *pollingAgent) Register(fn Tool) error {
 		return fmt.Errorf("tool with name '%s' already registered", fn.Name)
 	}
 
+	if err := validateDelivery(fn); err != nil {
+		return err
+	}
+
+	if err := validateToolCapability(fn); err != nil {
+		return err
+	}
+
+	schema, err := reflectToolSchema(fn)
+	if err != nil {
+		return err
+	}
+	for _, field := range fn.hiddenFields {
+		schema.Properties.Delete(field)
+		required := []string{}
+		for _, name := range schema.Required {
+			if name != field {
+				required = append(required, name)
+			}
+		}
+		schema.Required = required
+	}
+	fn.schema = schema
+
+	if err := validateToolExamples(fn); err != nil {
+		return err
+	}
+
+	s.Tools[fn.Name] = fn
+	return nil
+}
+
+// reflectToolSchema validates the signature of a tool's function and reflects the JSON schema of its input.
+func reflectToolSchema(fn Tool) (*jsonschema.Schema, error) {
 	// Validate that the function has exactly one argument and it's a struct
 	fnType := reflect.TypeOf(fn.Func)
+	if fnType == nil || fnType.Kind() != reflect.Func {
+		return nil, fmt.Errorf("tool '%s' must be a function", fn.Name)
+	}
 	if fnType.NumIn() != 2 {
-		return fmt.Errorf("tool '%s' must have exactly two arguments", fn.Name)
+		return nil, fmt.Errorf("tool '%s' must have exactly two arguments", fn.Name)
 	}
 	arg1Type := fnType.In(0)
 	arg2Type := fnType.In(1)
 
 	if arg2Type.Kind() != reflect.Struct {
-		return fmt.Errorf("tool '%s' second argument must be a struct (ContextInput)", fn.Name)
+		return nil, fmt.Errorf("tool '%s' second argument must be a struct (ContextInput)", fn.Name)
 	}
 
 	// Set the argument type to the referenced type
@@ -115,7 +276,7 @@ func (s *pollingAgent) Register(fn Tool) error {
 	}
 
 	if arg1Type.Kind() != reflect.Struct {
-		return fmt.Errorf("tool '%s' first argument must be a struct or a pointer to a struct", fn.Name)
+		return nil, fmt.Errorf("tool '%s' first argument must be a struct or a pointer to a struct", fn.Name)
 	}
 
 	// Get the schema for the input struct
@@ -123,7 +284,7 @@ func (s *pollingAgent) Register(fn Tool) error {
 	schema := reflector.Reflect(reflect.New(arg1Type).Interface())
 
 	if schema == nil {
-		return fmt.Errorf("failed to get schema for tool '%s'", fn.Name)
+		return nil, fmt.Errorf("failed to get schema for tool '%s'", fn.Name)
 	}
 
 	// Extract the relevant part of the schema
@@ -138,64 +299,132 @@ func (s *pollingAgent) Register(fn Tool) error {
 
 	defsString, err := json.Marshal(defs)
 	if err != nil {
-		return fmt.Errorf("failed to marshal schema for tool '%s': %v", fn.Name, err)
+		return nil, fmt.Errorf("failed to marshal schema for tool '%s': %v", fn.Name, err)
 	}
 
 	if strings.Contains(string(defsString), "\"$ref\":\"#/$defs") {
-		return fmt.Errorf("schema for tool '%s' contains a $ref to an external definition. this is currently not supported. see https://go.inferable.ai/go-schema-limitation for details", fn.Name)
+		return nil, fmt.Errorf("schema for tool '%s' contains a $ref to an external definition. this is currently not supported. see https://go.inferable.ai/go-schema-limitation for details", fn.Name)
 	}
 
 	defs.AdditionalProperties = jsonschema.FalseSchema
-	fn.schema = defs
-
-	s.Tools[fn.Name] = fn
-	return nil
+	return defs, nil
 }
 
 // Start polling for jobs, registers the machine, and starts polling for messages
-func (s *pollingAgent) Listen() error {
-	_, err := s.inferable.registerMachine(s)
+func (s *pollingAgent) Listen(options ...PollOptions) error {
+	pollOptions, err := mergePollOptions(options)
 	if err != nil {
-		return fmt.Errorf("failed to register machine: %v", err)
+		return err
 	}
 
-	s.ctx, s.cancel = context.WithCancel(context.Background())
-	s.retryAfter = 0
+	clusterId, err := s.inferable.registerMachine(s)
+	if err != nil {
+		return fmt.Errorf("failed to register machine: %w", err)
+	}
+	// Recorded before the pollers start, so that they do not race to register again
+	s.inferable.setClusterId(clusterId)
 
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.options = pollOptions
+	s.retryAfter.Store(0)
+	s.stopped = make(chan struct{})
+	s.stopOnce = &sync.Once{}
+
+	var pollers sync.WaitGroup
+	for n := 0; n < pollOptions.Pollers; n++ {
+		pollers.Add(1)
+		go func() {
+			defer pollers.Done()
+			s.pollLoop()
+		}()
+	}
 	go func() {
-		failureCount := DefaultRetryAfter
-		for {
-			time.Sleep(time.Duration(s.retryAfter) * time.Second)
+		pollers.Wait()
+		close(s.stopped)
+	}()
+
+	s.inferable.logInfo("Started polling for messages", map[string]interface{}{"machineId": s.inferable.machineID, "pollers": pollOptions.Pollers})
+	return nil
+}
 
-			select {
-			case <-s.ctx.Done():
-				return
-			default:
-				err := s.poll()
+// pollLoop polls for calls and handles them until polling is cancelled.
+func (s *pollingAgent) pollLoop() {
+	failureCount := DefaultRetryAfter
+	wait := time.Duration(0)
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
 
-				if err != nil {
-					failureCount++
+		err := s.poll()
+		if s.ctx.Err() != nil {
+			// The poll was aborted by Unlisten, after handling the calls it received
+			return
+		}
 
-					if failureCount > MaxConsecutivePollFailures {
-						log.Printf("Too many consecutive poll failures, exiting service")
-						s.Unlisten()
-					}
+		if err != nil {
+			failureCount++
 
-					log.Printf("Failed to poll: %v", err)
-				}
+			if failureCount > MaxConsecutivePollFailures {
+				s.inferable.logError("Too many consecutive poll failures, stopping polling", map[string]interface{}{"failures": failureCount})
+				s.stop()
 			}
+
+			s.inferable.logError("Failed to poll", map[string]interface{}{"error": err.Error()})
+			s.inferable.events.publish(Event{Type: EventError, Operation: "poll", Err: err})
 		}
-	}()
 
-	log.Printf("started and polling for messages")
-	return nil
+		// The cluster's Retry-After takes precedence over a shorter interval
+		wait = s.options.Interval
+		if retryAfter := time.Duration(s.retryAfter.Load()) * time.Second; retryAfter > wait {
+			wait = retryAfter
+		}
+	}
 }
 
-// Stop stops the service and cancels the polling
-func (s *pollingAgent) Unlisten() {
-	if s.cancel != nil {
+// Unlisten stops polling for new calls, after running the client's shutdown hooks, and waits up
+// to InferableOptions.DrainTimeout for the calls already received to finish, so that a rolling
+// deploy does not drop them. It returns ErrDrainTimeout if calls were still in flight.
+func (s *pollingAgent) Unlisten() error {
+	if s.cancel == nil {
+		return nil
+	}
+	s.stop()
+
+	var err error
+	select {
+	case <-s.stopped:
+	case <-time.After(s.inferable.drainTimeout):
+		inFlight := s.inFlight.Load()
+		s.inferable.logError("Calls still in flight at the drain deadline", map[string]interface{}{"inFlight": inFlight, "timeout": s.inferable.drainTimeout.String()})
+		err = fmt.Errorf("%w: %d after %s", ErrDrainTimeout, inFlight, s.inferable.drainTimeout)
+	}
+
+	// The hooks run once the calls in flight are done with what the hooks release, or were given
+	// up on
+	s.inferable.runShutdownHooks()
+	return err
+}
+
+// stop cancels polling, without waiting for the calls in flight. Only the first call of each
+// Listen has an effect, as every poller stops polling after too many failures.
+func (s *pollingAgent) stop() {
+	s.stopOnce.Do(func() {
 		s.cancel()
-		log.Printf("stopped polling for messages")
+		s.inferable.logInfo("Stopped polling for messages", map[string]interface{}{"machineId": s.inferable.machineID})
+	})
+}
+
+// reregister registers the machine again after the cluster forgot it. Pollers told so at the same
+// time re-register one after the other.
+func (s *pollingAgent) reregister() {
+	s.registerMu.Lock()
+	defer s.registerMu.Unlock()
+
+	if _, err := s.inferable.registerMachine(s); err != nil {
+		s.inferable.logError("Failed to register machine again", map[string]interface{}{"error": err.Error()})
 	}
 }
 
@@ -209,7 +438,7 @@ func (s *pollingAgent) poll() error {
 
 	clusterId, err := s.inferable.getClusterId()
 	if err != nil {
-		return fmt.Errorf("failed to get cluster id: %v", err)
+		return fmt.Errorf("failed to get cluster id: %w", err)
 	}
 
 	// Build comma-seperated tools list
@@ -222,25 +451,31 @@ func (s *pollingAgent) poll() error {
 	}
 
 	options := client.FetchDataOptions{
-		Path:    fmt.Sprintf("/clusters/%s/jobs?acknowledge=true&tools=%s&status=pending&limit=10&waitTime=20", clusterId, toolList),
+		Path:    fmt.Sprintf("/clusters/%s/jobs?acknowledge=true&tools=%s&status=pending&limit=%d&waitTime=%d", clusterId, toolList, s.options.BatchSize, pollWaitTime),
 		Method:  "GET",
 		Headers: headers,
+		// Unlisten aborts the poll, so that no more calls are acknowledged by this machine
+		Context: s.ctx,
+		// The poll acknowledges the calls it returns, so a retry of a poll whose response was
+		// lost would leave them acknowledged but never handled until they stall
+		Idempotent: false,
 	}
 
 	result, respHeaders, err, status := s.inferable.fetchData(options)
+	s.inferable.skew.observe(respHeaders, time.Now())
 
 	if status == 410 {
-		s.inferable.registerMachine(s)
+		s.reregister()
 	}
 
 	if err != nil {
-		return fmt.Errorf("failed to poll jobs: %v", err)
+		return fmt.Errorf("failed to poll jobs: %w", err)
 	}
 
 	if retryAfter, ok := respHeaders["Retry-After"]; ok {
 		for _, v := range retryAfter {
 			if i, err := strconv.Atoi(v); err == nil {
-				s.retryAfter = i
+				s.retryAfter.Store(int64(i))
 			}
 		}
 	}
@@ -252,9 +487,26 @@ func (s *pollingAgent) poll() error {
 		return fmt.Errorf("failed to parse poll response: %v", err)
 	}
 
+	s.polls.Add(1)
+	s.inferable.events.publish(Event{Type: EventPoll, Calls: len(parsed)})
+	if len(parsed) >= s.options.BatchSize {
+		s.saturatedPolls.Add(1)
+	}
+
+	parsed = s.inferable.chaos.duplicate(parsed)
+	s.inferable.metrics.add(s.inferable.metrics.queueDepth, float64(len(parsed)))
+
 	errors := []string{}
 	for _, msg := range parsed {
+		start := time.Now()
+		s.inferable.metrics.add(s.inferable.metrics.queueDepth, -1)
+		s.inFlight.Add(1)
+		s.inferable.metrics.add(s.inferable.metrics.inFlight, 1)
 		err := s.handleMessage(msg)
+		s.inFlight.Add(-1)
+		s.inferable.metrics.add(s.inferable.metrics.inFlight, -1)
+		s.busy.Add(int64(time.Since(start)))
+		s.jobs.Add(1)
 		if err != nil {
 			errors = append(errors, err.Error())
 		}
@@ -271,52 +523,136 @@ func (s *pollingAgent) handleMessage(msg callMessage) error {
 	// Find the target function
 	fn, ok := s.Tools[msg.Function]
 	if !ok {
-		log.Printf("Received call for unknown function: %s", msg.Function)
+		// The call was acknowledged by this machine, reject it rather than leaving it to time out
+		s.inferable.logError("Received call for unknown tool", map[string]interface{}{"tool": msg.Function, "callId": msg.Id})
+		result := callResult{
+			Result:     fmt.Sprintf("machine %s does not implement tool %s", s.inferable.machineID, msg.Function),
+			ResultType: "rejection",
+		}
+		if err := s.persistJobResult(msg.Id, result); err != nil {
+			return fmt.Errorf("failed to persist job result: %v", err)
+		}
 		return nil
 	}
 
+	received := time.Now()
+	s.inferable.events.publish(Event{Type: EventToolCallStarted, Tool: fn.Name, CallID: msg.Id})
+	report := func(result callResult) error {
+		s.inferable.events.publish(Event{
+			Type:       EventToolCallFinished,
+			Tool:       fn.Name,
+			CallID:     msg.Id,
+			ResultType: result.ResultType,
+			Duration:   time.Since(received),
+		})
+		return s.persistJobResult(msg.Id, result)
+	}
+
 	// Create a new instance of the function's input type
 	fnType := reflect.TypeOf(fn.Func)
 	argType := fnType.In(0)
 	argPtr := reflect.New(argType)
 
-	inputJson, err := json.Marshal(msg.Input)
-
-	if err != nil {
+	// Invalid input is rejected with the violations, for the agent to correct its call
+	if err := decodeToolInput(msg.Input, argPtr.Interface(), fn.Coerce); err != nil {
 		result := callResult{
-			Result:     err.Error(),
+			Result:     err,
 			ResultType: "rejection",
 		}
 
 		// Persist the job result
-		if err := s.persistJobResult(msg.Id, result); err != nil {
+		if err := report(result); err != nil {
 			return fmt.Errorf("failed to persist job result: %v", err)
 		}
+		return nil
 	}
 
-	err = json.Unmarshal(inputJson, argPtr.Interface())
-	if err != nil {
-		result := callResult{
-			Result:     err.Error(),
-			ResultType: "rejection",
+	if fn.Dedupe {
+		if recorded, ok := s.recordedResult(msg.Id); ok {
+			if err := report(recorded); err != nil {
+				return fmt.Errorf("failed to persist job result: %v", err)
+			}
+			return nil
 		}
+	}
 
-		// Persist the job result
-		if err := s.persistJobResult(msg.Id, result); err != nil {
-			return fmt.Errorf("failed to persist job result: %v", err)
+	if deliveryOf(fn) == DeliveryAtMostOnce {
+		claimed, err := s.claimCall(msg.Id)
+		if err != nil {
+			// Without a claim the call might run twice, leave it unreported instead
+			return fmt.Errorf("failed to claim at-most-once call %s: %v", msg.Id, err)
+		}
+		if !claimed {
+			result := callResult{
+				Result:     fmt.Sprintf("call %s to at-most-once tool %s was already delivered and is not retried", msg.Id, fn.Name),
+				ResultType: "rejection",
+			}
+			if err := report(result); err != nil {
+				return fmt.Errorf("failed to persist job result: %v", err)
+			}
+			return nil
 		}
 	}
 
 	context := ContextInput{
-		AuthContext: msg.AuthContext,
-		RunContext:  msg.RunContext,
-		Approved:    msg.Approved,
+		AuthContext:       msg.AuthContext,
+		RunContext:        msg.RunContext,
+		Approved:          msg.Approved,
+		InterruptTimedOut: msg.InterruptTimedOut,
+		ApprovalOption:    msg.ApprovalOption,
+		CallID:            msg.Id,
 	}
 
 	start := time.Now()
+	resultType, resultValue := callTool(fn, argPtr.Elem(), context)
+
+	// Approval interrupts resolved on the machine run the call again, or deny it
+	for n := 0; resultType == "interrupt" && n < maxInlineApprovals; n++ {
+		decision, ok := s.inferable.resolveApproval(ApprovalRequest{Tool: fn.Name, CallID: msg.Id, Interrupt: resultValue.(Interrupt)})
+		if !ok {
+			break
+		}
+		if !decision.Approved {
+			resultType = "rejection"
+			resultValue = fmt.Sprintf("call %s to %s was denied", msg.Id, fn.Name)
+			break
+		}
+		context.Approved = true
+		context.ApprovalOption = decision.Option
+		resultType, resultValue = callTool(fn, argPtr.Elem(), context)
+	}
+
+	// Redacted fields are masked before the result leaves the machine, or reaches a post-processor
+	if resultType == "resolution" {
+		resultValue = s.processResult(fn, Redact(resultValue))
+	}
+
+	result := callResult{
+		Result:     resultValue,
+		ResultType: resultType,
+		Meta: callResultMeta{
+			FunctionExecutionTime: int64(time.Since(start).Milliseconds()),
+		},
+	}
+
+	if fn.Dedupe {
+		s.recordResult(msg.Id, result)
+	}
+
+	// Persist the job result
+	if err := report(result); err != nil {
+		return fmt.Errorf("failed to persist job result: %v", err)
+	}
+
+	return nil
+}
+
+// callTool calls a tool's function, and classifies its result as a resolution, rejection or
+// interrupt.
+func callTool(fn Tool, input reflect.Value, context ContextInput) (string, interface{}) {
 	// Call the function with the unmarshaled argument
 	fnValue := reflect.ValueOf(fn.Func)
-	returnValues := fnValue.Call([]reflect.Value{argPtr.Elem(), reflect.ValueOf(context)})
+	returnValues := fnValue.Call([]reflect.Value{input, reflect.ValueOf(context)})
 
 	resultType := "resolution"
 	resultValue := returnValues[0].Interface()
@@ -346,20 +682,43 @@ func (s *pollingAgent) handleMessage(msg callMessage) error {
 		}
 	}
 
-	result := callResult{
-		Result:     resultValue,
-		ResultType: resultType,
-		Meta: callResultMeta{
-			FunctionExecutionTime: int64(time.Since(start).Milliseconds()),
-		},
+	if interrupt, ok := resultValue.(Interrupt); ok && resultType == "interrupt" {
+		if err := interrupt.validate(); err != nil {
+			resultType = "rejection"
+			resultValue = err.Error()
+		}
 	}
 
-	// Persist the job result
-	if err := s.persistJobResult(msg.Id, result); err != nil {
-		return fmt.Errorf("failed to persist job result: %v", err)
+	return resultType, resultValue
+}
+
+// recordedResult returns the result recorded for a deduplicated call, if it ran before.
+func (s *pollingAgent) recordedResult(callId string) (callResult, bool) {
+	serialized, ok, err := s.inferable.store().Get(callResultKey(callId))
+	if err != nil {
+		s.inferable.logError("Failed to check recorded result of call", map[string]interface{}{"callId": callId, "error": err.Error()})
+		return callResult{}, false
+	}
+	if !ok {
+		return callResult{}, false
 	}
 
-	return nil
+	var result callResult
+	if err := json.Unmarshal([]byte(serialized), &result); err != nil || result.ResultType == "" {
+		return callResult{}, false
+	}
+	return result, true
+}
+
+// recordResult records the result of a deduplicated call before it is reported to the cluster.
+func (s *pollingAgent) recordResult(callId string, result callResult) {
+	serialized, err := json.Marshal(result)
+	if err == nil {
+		err = s.inferable.store().SetIfAbsent(callResultKey(callId), string(serialized))
+	}
+	if err != nil {
+		s.inferable.logError("Failed to record result of call", map[string]interface{}{"callId": callId, "error": err.Error()})
+	}
 }
 
 func (s *pollingAgent) persistJobResult(jobID string, result callResult) error {
@@ -377,7 +736,7 @@ func (s *pollingAgent) persistJobResult(jobID string, result callResult) error {
 
 	clusterId, err := s.inferable.getClusterId()
 	if err != nil {
-		return fmt.Errorf("failed to get cluster id: %v", err)
+		return fmt.Errorf("failed to get cluster id: %w", err)
 	}
 
 	options := client.FetchDataOptions{
@@ -389,7 +748,7 @@ func (s *pollingAgent) persistJobResult(jobID string, result callResult) error {
 
 	_, _, err, _ = s.inferable.fetchData(options)
 	if err != nil {
-		return fmt.Errorf("failed to persist job result: %v", err)
+		return fmt.Errorf("failed to persist job result: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,117 @@
+package inferable
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPollOptions(t *testing.T) {
+	var polling, concurrent atomic.Int64
+	limits := make(chan string, 10)
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/jobs": func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case limits <- r.URL.Query().Get("limit"):
+			default:
+			}
+			n := polling.Add(1)
+			defer polling.Add(-1)
+			for {
+				if current := concurrent.Load(); n <= current || concurrent.CompareAndSwap(current, n) {
+					break
+				}
+			}
+			// Long polls wait until they are aborted
+			<-r.Context().Done()
+		},
+	})
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "charge",
+		Func: func(input chargeInput, ctx ContextInput) (int, error) { return input.Amount, nil },
+	}))
+
+	require.NoError(t, i.Tools.Listen(PollOptions{BatchSize: 5, Pollers: 3}))
+	assert.Eventually(t, func() bool { return concurrent.Load() == 3 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "5", <-limits)
+	require.NoError(t, i.Tools.Unlisten())
+	assert.Eventually(t, func() bool { return polling.Load() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestPollInterval(t *testing.T) {
+	var polls atomic.Int64
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/jobs": func(w http.ResponseWriter, r *http.Request) {
+			polls.Add(1)
+			json.NewEncoder(w).Encode([]interface{}{})
+		},
+	})
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "charge",
+		Func: func(input chargeInput, ctx ContextInput) (int, error) { return input.Amount, nil },
+	}))
+
+	require.NoError(t, i.Tools.Listen(PollOptions{Interval: 100 * time.Millisecond}))
+	time.Sleep(250 * time.Millisecond)
+	require.NoError(t, i.Tools.Unlisten())
+	assert.GreaterOrEqual(t, polls.Load(), int64(2))
+	assert.LessOrEqual(t, polls.Load(), int64(3))
+}
+
+// Run with -race: the pollers share the cluster ID, the re-registration and the stop.
+func TestPollersStopOnce(t *testing.T) {
+	var registrations, hooks atomic.Int64
+	i := newTestClient(t, InferableOptions{Logger: &recordingLogger{}}, map[string]http.HandlerFunc{
+		"/machines": func(w http.ResponseWriter, r *http.Request) {
+			registrations.Add(1)
+			json.NewEncoder(w).Encode(map[string]interface{}{"clusterId": "test-cluster"})
+		},
+		"/clusters/test-cluster/jobs": func(w http.ResponseWriter, r *http.Request) {
+			// The cluster forgot the machine, so every poll fails and re-registers
+			w.WriteHeader(http.StatusGone)
+		},
+	})
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "charge",
+		Func: func(input chargeInput, ctx ContextInput) (int, error) { return input.Amount, nil },
+	}))
+	i.RegisterShutdownHook(func(ctx context.Context) error {
+		hooks.Add(1)
+		return nil
+	})
+
+	require.NoError(t, i.Tools.Listen(PollOptions{Pollers: 4}))
+	select {
+	case <-i.Tools.stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatal("pollers did not stop after too many failures")
+	}
+	require.NoError(t, i.Tools.Unlisten())
+
+	assert.Equal(t, int64(1), hooks.Load())
+	assert.Greater(t, registrations.Load(), int64(4))
+	clusterId, err := i.getClusterId()
+	require.NoError(t, err)
+	assert.Equal(t, "test-cluster", clusterId)
+}
+
+func TestMergePollOptions(t *testing.T) {
+	merged, err := mergePollOptions(nil)
+	require.NoError(t, err)
+	assert.Equal(t, PollOptions{BatchSize: DefaultPollBatchSize, Pollers: 1}, merged)
+
+	merged, err = mergePollOptions([]PollOptions{{BatchSize: 20, Pollers: 4}, {Interval: time.Second}})
+	require.NoError(t, err)
+	assert.Equal(t, PollOptions{Interval: time.Second, BatchSize: 20, Pollers: 4}, merged)
+
+	_, err = mergePollOptions([]PollOptions{{BatchSize: 50}})
+	assert.ErrorContains(t, err, "poll batch size 50 is above the maximum of 20")
+	_, err = mergePollOptions([]PollOptions{{Pollers: -1}})
+	assert.Error(t, err)
+}
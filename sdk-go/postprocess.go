@@ -0,0 +1,161 @@
+package inferable
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ResultProcessor transforms the result of a tool call before it is reported to the cluster and
+// enters the agent's context, e.g. to keep large results from filling the model's context window.
+type ResultProcessor func(result interface{}) (interface{}, error)
+
+// minBinaryLength is the length from which StripBinaryResult treats base64 strings as binary data.
+const minBinaryLength = 64
+
+var base64Pattern = regexp.MustCompile(`^[A-Za-z0-9+/]+={0,2}$`)
+
+// TruncateResult returns a processor that cuts results of more than maxTokens tokens, as
+// estimated by CountTokens of their JSON, to their first maxTokens tokens of JSON followed by a
+// note of the truncation. Smaller results are kept as they are.
+func TruncateResult(maxTokens int) ResultProcessor {
+	return func(result interface{}) (interface{}, error) {
+		serialized, tokens, err := serializeResult(result)
+		if err != nil || tokens <= maxTokens {
+			return result, err
+		}
+
+		runes := []rune(serialized)
+		// The longest prefix within maxTokens, CountTokens grows with the prefix
+		low, high := 0, len(runes)
+		for low < high {
+			mid := (low + high + 1) / 2
+			if CountTokens("", string(runes[:mid])) <= maxTokens {
+				low = mid
+			} else {
+				high = mid - 1
+			}
+		}
+		return fmt.Sprintf("%s... [truncated, result has %d tokens]", string(runes[:low]), tokens), nil
+	}
+}
+
+// StripBinaryResult returns a processor that replaces binary data in results, such as []byte
+// fields and base64 data URIs, with a placeholder giving its size. Base64 strings are taken for
+// binary data when they are at least 64 characters long and do not decode to UTF-8 text without
+// NUL bytes.
+func StripBinaryResult() ResultProcessor {
+	return func(result interface{}) (interface{}, error) {
+		serialized, err := json.Marshal(result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal result: %v", err)
+		}
+		var generic interface{}
+		if err := json.Unmarshal(serialized, &generic); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal result: %v", err)
+		}
+		return stripBinary(generic), nil
+	}
+}
+
+func stripBinary(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			v[key] = stripBinary(field)
+		}
+	case []interface{}:
+		for n, item := range v {
+			v[n] = stripBinary(item)
+		}
+	case string:
+		if size, ok := binarySize(v); ok {
+			return fmt.Sprintf("[binary data, %d bytes]", size)
+		}
+	}
+	return value
+}
+
+// binarySize returns the number of bytes encoded by a base64 string or data URI holding binary data.
+func binarySize(value string) (int, bool) {
+	if strings.HasPrefix(value, "data:") {
+		if _, data, ok := strings.Cut(value, ";base64,"); ok {
+			return base64.StdEncoding.DecodedLen(len(data)), true
+		}
+		return 0, false
+	}
+
+	if len(value) < minBinaryLength || !base64Pattern.MatchString(value) {
+		return 0, false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || (utf8.Valid(decoded) && !bytes.ContainsRune(decoded, 0)) {
+		return 0, false
+	}
+	return len(decoded), true
+}
+
+// SummarizeResult returns a processor that replaces results of more than maxTokens tokens with
+// the summary summarize makes of their JSON, e.g. with an LLM call. Smaller results are kept as
+// they are.
+//
+//	Tool{
+//		...
+//		PostProcessors: []inferable.ResultProcessor{
+//			inferable.SummarizeResult(2000, func(text string) (string, error) {
+//				return summarizer.Summarize(text)
+//			}),
+//			inferable.TruncateResult(2000),
+//		},
+//	}
+func SummarizeResult(maxTokens int, summarize func(text string) (string, error)) ResultProcessor {
+	return func(result interface{}) (interface{}, error) {
+		serialized, tokens, err := serializeResult(result)
+		if err != nil || tokens <= maxTokens {
+			return result, err
+		}
+
+		summary, err := summarize(serialized)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize result: %v", err)
+		}
+		return summary, nil
+	}
+}
+
+// serializeResult returns the JSON of a result and its estimated number of tokens. Results
+// that are strings are counted as is, as they are in the agent's context.
+func serializeResult(result interface{}) (string, int, error) {
+	serialized, ok := result.(string)
+	if !ok {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to marshal result: %v", err)
+		}
+		serialized = string(raw)
+	}
+	return serialized, CountTokens("", serialized), nil
+}
+
+// processResult applies the tool's post-processors to a result in order. A processor that fails
+// is logged and skipped, since the call itself succeeded.
+func (s *pollingAgent) processResult(fn Tool, result interface{}) interface{} {
+	for n, processor := range fn.PostProcessors {
+		processed, err := processor(result)
+		if err != nil {
+			if s.inferable.logger != nil {
+				s.inferable.logger.Error("Tool result post-processor failed", map[string]interface{}{"tool": fn.Name, "processor": n, "error": err.Error()})
+			} else {
+				log.Printf("Post-processor %d of tool %s failed: %v", n, fn.Name, err)
+			}
+			continue
+		}
+		result = processed
+	}
+	return result
+}
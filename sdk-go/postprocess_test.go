@@ -0,0 +1,102 @@
+package inferable
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultProcessors(t *testing.T) {
+	long := strings.Repeat("order ", 200)
+
+	truncated, err := TruncateResult(50)(map[string]interface{}{"notes": long})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(truncated.(string), `{"notes":"order order`))
+	assert.Contains(t, truncated, "... [truncated, result has 303 tokens]")
+	assert.LessOrEqual(t, CountTokens("", strings.Split(truncated.(string), "...")[0]), 50)
+
+	small := map[string]interface{}{"id": "42"}
+	kept, err := TruncateResult(50)(small)
+	require.NoError(t, err)
+	assert.Equal(t, small, kept)
+
+	pdf := append([]byte("%PDF-"), make([]byte, 100)...)
+	stripped, err := StripBinaryResult()(struct {
+		Name     string   `json:"name"`
+		Contents []byte   `json:"contents"`
+		Images   []string `json:"images"`
+		Token    string   `json:"token"`
+	}{
+		Name:     "invoice.pdf",
+		Contents: pdf,
+		Images:   []string{"data:image/png;base64," + base64.StdEncoding.EncodeToString(pdf)},
+		// Base64 of text is kept
+		Token: base64.StdEncoding.EncodeToString([]byte(long)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"name":     "invoice.pdf",
+		"contents": "[binary data, 105 bytes]",
+		"images":   []interface{}{"[binary data, 105 bytes]"},
+		"token":    base64.StdEncoding.EncodeToString([]byte(long)),
+	}, stripped)
+
+	summarized, err := SummarizeResult(50, func(text string) (string, error) {
+		return fmt.Sprintf("summary of %d characters", len(text)), nil
+	})(long)
+	require.NoError(t, err)
+	assert.Equal(t, "summary of 1200 characters", summarized)
+}
+
+func TestToolPostProcessors(t *testing.T) {
+	logger := &recordingLogger{}
+	i, results := newResultRecorder(t, InferableOptions{Logger: logger})
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "search",
+		Func: func(input chargeInput, ctx ContextInput) (interface{}, error) {
+			if input.Amount < 0 {
+				return nil, fmt.Errorf("negative amount")
+			}
+			return map[string]interface{}{"hits": strings.Repeat("hit ", 100*input.Amount)}, nil
+		},
+		PostProcessors: []ResultProcessor{
+			SummarizeResult(10, func(text string) (string, error) {
+				return "", fmt.Errorf("summarizer unavailable")
+			}),
+			TruncateResult(10),
+		},
+	}))
+
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-1", Function: "search", Input: map[string]interface{}{"amount": 1}}))
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-2", Function: "search", Input: map[string]interface{}{"amount": -1}}))
+
+	// The failed summarizer is skipped, the result still truncated
+	assert.Contains(t, results()[0].Result, "[truncated, result has 103 tokens]")
+	assert.Equal(t, callResult{Result: "negative amount", ResultType: "rejection"}, results()[1])
+	require.Len(t, logger.errors, 1)
+	assert.Contains(t, logger.errors[0], "post-processor")
+}
+
+func TestWorkflowToolPostProcessors(t *testing.T) {
+	i, err := New(InferableOptions{KVStore: NewMemoryStore(), Offline: true})
+	require.NoError(t, err)
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "search", InputSchema: WorkflowInput{}})
+	workflow.Tools.Register(WorkflowTool{
+		Name:           "documents",
+		Func:           func(input chargeInput, ctx ContextInput) (string, error) { return "", nil },
+		Coerce:         true,
+		Capability:     ToolCapabilityRead,
+		PostProcessors: []ResultProcessor{TruncateResult(100)},
+	})
+
+	tools := workflow.clusterTools()
+	assert.Equal(t, "tool_search_documents", tools[0].Name)
+	assert.True(t, tools[0].Coerce)
+	assert.Equal(t, ToolCapabilityRead, tools[0].Capability)
+	assert.Len(t, tools[0].PostProcessors, 1)
+}
@@ -0,0 +1,94 @@
+package inferable
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// subscriptionKey is the key the subscribers of a topic are stored under, as a JSON array of
+// workflow names.
+func subscriptionKey(topic string) string {
+	return "subscriptions_" + topic
+}
+
+// Subscribe makes the workflow run for every event published to one of topics with ctx.Publish,
+// with the event's payload as its input. Subscriptions are recorded in the client's key-value
+// store when the workflow listens, so that publishers trigger the workflow without naming it.
+//
+//	shipping.Subscribe("order.placed")
+func (w *Workflow) Subscribe(topics ...string) {
+	w.topics = append(w.topics, topics...)
+}
+
+// recordSubscriptions adds the workflow to the subscribers of its topics.
+func (w *Workflow) recordSubscriptions() error {
+	for _, topic := range w.topics {
+		_, err := w.inferable.KV.Update(subscriptionKey(topic), func(current string, ok bool) (string, error) {
+			subscribers, err := decodeSubscribers(current, ok)
+			if err != nil {
+				return "", err
+			}
+			for _, subscriber := range subscribers {
+				if subscriber == w.name {
+					return current, nil
+				}
+			}
+			subscribers = append(subscribers, w.name)
+			sort.Strings(subscribers)
+			serialized, err := json.Marshal(subscribers)
+			return string(serialized), err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe workflow %s to topic %s: %v", w.name, topic, err)
+		}
+	}
+	return nil
+}
+
+func decodeSubscribers(serialized string, ok bool) ([]string, error) {
+	subscribers := []string{}
+	if !ok {
+		return subscribers, nil
+	}
+	if err := json.Unmarshal([]byte(serialized), &subscribers); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal subscribers: %v", err)
+	}
+	return subscribers, nil
+}
+
+// publish triggers an execution of every workflow subscribed to topic, with payload as its input.
+// The execution IDs derive from the publishing execution, topic and payload, so a re-executed
+// handler publishing the same event does not trigger the subscribers again.
+func (w *Workflow) publish(requestCtx context.Context, executionId string, topic string, payload interface{}) error {
+	serialized, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload of topic %s: %v", topic, err)
+	}
+
+	current, ok, err := w.inferable.KV.Get(subscriptionKey(topic))
+	if err != nil {
+		return fmt.Errorf("failed to get subscribers of topic %s: %v", topic, err)
+	}
+	subscribers, err := decodeSubscribers(current, ok)
+	if err != nil {
+		return err
+	}
+
+	for _, subscriber := range subscribers {
+		// Each subscriber gets its own copy of the input
+		var input map[string]interface{}
+		if err := json.Unmarshal(serialized, &input); err != nil || input == nil {
+			return fmt.Errorf("payload of topic %s must be a JSON object", topic)
+		}
+
+		hash := sha256.Sum256([]byte(topic + "\x00" + subscriber + "\x00" + string(serialized)))
+		eventExecutionId := fmt.Sprintf("%s_%x", executionId, hash[:8])
+		if _, err := w.inferable.Workflows.TriggerContext(requestCtx, subscriber, eventExecutionId, input, TriggerOptions{ParentExecutionID: executionId}); err != nil {
+			return fmt.Errorf("failed to publish topic %s to workflow %s: %v", topic, subscriber, err)
+		}
+	}
+	return nil
+}
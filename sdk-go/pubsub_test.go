@@ -0,0 +1,63 @@
+package inferable
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishSubscribe(t *testing.T) {
+	var mu sync.Mutex
+	triggered := map[string][]map[string]interface{}{}
+	i := newTestClient(t, InferableOptions{KVStore: NewMemoryStore()}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/{workflowName}/executions": func(w http.ResponseWriter, r *http.Request) {
+			var input map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&input)
+			workflow := r.PathValue("workflowName")
+			mu.Lock()
+			triggered[workflow] = append(triggered[workflow], input)
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		},
+	})
+
+	for _, name := range []string{"shipping", "invoicing"} {
+		subscriber := i.Workflows.Create(WorkflowConfig{Name: name, InputSchema: WorkflowInput{}})
+		subscriber.Subscribe("order.placed")
+		require.NoError(t, subscriber.recordSubscriptions())
+		// Subscribing again, e.g. on the next deploy, is a no-op
+		require.NoError(t, subscriber.recordSubscriptions())
+	}
+	subscribers, _, err := i.KV.Get(subscriptionKey("order.placed"))
+	require.NoError(t, err)
+	assert.Equal(t, `["invoicing","shipping"]`, subscribers)
+
+	orders := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: WorkflowInput{}})
+	orders.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		if err := ctx.Publish("order.placed", map[string]interface{}{"orderId": "42"}); err != nil {
+			return nil, err
+		}
+		if err := ctx.Publish("order.cancelled", map[string]interface{}{"orderId": "42"}); err != nil {
+			return nil, err
+		}
+		return nil, ctx.Publish("order.placed", "42")
+	})
+
+	// The handler publishes the same events when it is re-executed
+	for run := 0; run < 2; run++ {
+		_, err = orders.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+		assert.ErrorContains(t, err, "payload of topic order.placed must be a JSON object")
+	}
+
+	require.Len(t, triggered, 2)
+	require.Len(t, triggered["shipping"], 2)
+	assert.Equal(t, "42", triggered["shipping"][0]["orderId"])
+	assert.Equal(t, triggered["shipping"][0], triggered["shipping"][1])
+	assert.True(t, strings.HasPrefix(triggered["shipping"][0]["executionId"].(string), "exec-1_"))
+	assert.NotEqual(t, triggered["shipping"][0]["executionId"], triggered["invoicing"][0]["executionId"])
+}
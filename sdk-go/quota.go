@@ -0,0 +1,155 @@
+package inferable
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned (wrapped in a *QuotaExceededError) when a trigger exceeds the
+// client's TriggerQuota. Check for it with errors.Is.
+var ErrQuotaExceeded = errors.New("trigger quota exceeded")
+
+// quotaWindow is the window TriggerQuota limits are counted over.
+const quotaWindow = time.Minute
+
+// TriggerQuota limits how many executions a client triggers per minute, so that a misbehaving
+// caller cannot flood the cluster with executions. Triggers over a limit fail with a
+// *QuotaExceededError without reaching the cluster, and triggers the cluster does not accept do
+// not count. Zero limits do not limit.
+type TriggerQuota struct {
+	// PerWorkflow is the maximum number of triggers per minute of each workflow.
+	PerWorkflow int
+	// Workflows overrides PerWorkflow for the workflows it names.
+	Workflows map[string]int
+	// PerTenant is the maximum number of triggers per minute with each TriggerOptions.TenantKey,
+	// across workflows. Triggers without a tenant key are not limited per tenant.
+	PerTenant int
+}
+
+// QuotaExceededError describes a trigger rejected by the client's TriggerQuota.
+type QuotaExceededError struct {
+	// Workflow is the workflow that was triggered.
+	Workflow string
+	// TenantKey is the tenant whose quota was exceeded, or empty if it was the workflow's.
+	TenantKey string
+	// Limit is the exceeded number of triggers per minute.
+	Limit int
+	// RetryAfter is the time until the next trigger fits the quota.
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface.
+func (e *QuotaExceededError) Error() string {
+	if e.TenantKey != "" {
+		return fmt.Sprintf("trigger quota of %d per minute exceeded for tenant %s, retry after %s", e.Limit, e.TenantKey, e.RetryAfter)
+	}
+	return fmt.Sprintf("trigger quota of %d per minute exceeded for workflow %s, retry after %s", e.Limit, e.Workflow, e.RetryAfter)
+}
+
+// Unwrap allows errors.Is(err, ErrQuotaExceeded).
+func (e *QuotaExceededError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// triggerLimiter counts triggers against a TriggerQuota over a sliding window.
+type triggerLimiter struct {
+	quota    TriggerQuota
+	clock    Clock
+	mu       sync.Mutex
+	triggers map[string][]time.Time
+}
+
+func newTriggerLimiter(quota *TriggerQuota, clock Clock) *triggerLimiter {
+	if quota == nil {
+		return nil
+	}
+	return &triggerLimiter{quota: *quota, clock: clock, triggers: map[string][]time.Time{}}
+}
+
+// allow reserves a trigger of workflow for tenantKey, or returns a *QuotaExceededError if it
+// exceeds either quota. A rejected trigger does not count, and a trigger that fails to reach the
+// cluster calls release to give its reservation back.
+func (l *triggerLimiter) allow(workflow string, tenantKey string) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	limit, ok := l.quota.Workflows[workflow]
+	if !ok {
+		limit = l.quota.PerWorkflow
+	}
+
+	type check struct {
+		key   string
+		limit int
+		err   QuotaExceededError
+	}
+	checks := []check{{key: "workflow:" + workflow, limit: limit, err: QuotaExceededError{Workflow: workflow, Limit: limit}}}
+	if tenantKey != "" {
+		checks = append(checks, check{key: "tenant:" + tenantKey, limit: l.quota.PerTenant, err: QuotaExceededError{Workflow: workflow, TenantKey: tenantKey, Limit: l.quota.PerTenant}})
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	for _, c := range checks {
+		if c.limit <= 0 {
+			continue
+		}
+		recent := l.prune(c.key, now)
+		if len(recent) >= c.limit {
+			err := c.err
+			err.RetryAfter = recent[len(recent)-c.limit].Add(quotaWindow).Sub(now)
+			return nil, &err
+		}
+	}
+	for _, c := range checks {
+		if c.limit > 0 {
+			l.triggers[c.key] = append(l.triggers[c.key], now)
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			for _, c := range checks {
+				if c.limit > 0 {
+					l.unreserve(c.key, now)
+				}
+			}
+		})
+	}, nil
+}
+
+// unreserve drops a trigger of key made at the given time, if it is still in the window.
+func (l *triggerLimiter) unreserve(key string, at time.Time) {
+	recent := l.triggers[key]
+	for i := len(recent) - 1; i >= 0; i-- {
+		if recent[i].Equal(at) {
+			l.triggers[key] = append(recent[:i:i], recent[i+1:]...)
+			break
+		}
+	}
+	if len(l.triggers[key]) == 0 {
+		delete(l.triggers, key)
+	}
+}
+
+// prune drops the triggers of key that fell out of the window, and returns the others.
+func (l *triggerLimiter) prune(key string, now time.Time) []time.Time {
+	recent := l.triggers[key]
+	for len(recent) > 0 && !recent[0].After(now.Add(-quotaWindow)) {
+		recent = recent[1:]
+	}
+	if len(recent) == 0 {
+		delete(l.triggers, key)
+		return nil
+	}
+	l.triggers[key] = recent
+	return recent
+}
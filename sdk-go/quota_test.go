@@ -0,0 +1,94 @@
+package inferable
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerQuota(t *testing.T) {
+	var triggered atomic.Int64
+	clock := &fixedClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	i := newTestClient(t, InferableOptions{
+		Clock: clock,
+		TriggerQuota: &TriggerQuota{
+			PerWorkflow: 2,
+			Workflows:   map[string]int{"reports": 10},
+			PerTenant:   2,
+		},
+	}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/": func(w http.ResponseWriter, r *http.Request) {
+			triggered.Add(1)
+			w.WriteHeader(http.StatusCreated)
+		},
+	})
+
+	trigger := func(workflow string, tenantKey string) error {
+		_, err := i.Workflows.Trigger(workflow, "exec", map[string]interface{}{}, TriggerOptions{TenantKey: tenantKey})
+		return err
+	}
+
+	require.NoError(t, trigger("orders", ""))
+	clock.now = clock.now.Add(10 * time.Second)
+	require.NoError(t, trigger("orders", "acme"))
+
+	err := trigger("orders", "acme")
+	var quotaErr *QuotaExceededError
+	require.True(t, errors.As(err, &quotaErr))
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, QuotaExceededError{Workflow: "orders", Limit: 2, RetryAfter: 50 * time.Second}, *quotaErr)
+
+	// The rejected trigger did not count against the tenant
+	require.NoError(t, trigger("reports", "acme"))
+	err = trigger("reports", "acme")
+	require.True(t, errors.As(err, &quotaErr))
+	assert.Equal(t, "acme", quotaErr.TenantKey)
+	assert.Equal(t, 2, quotaErr.Limit)
+	require.NoError(t, trigger("reports", "globex"))
+
+	// Triggers leave the window after a minute
+	clock.now = clock.now.Add(50 * time.Second)
+	require.NoError(t, trigger("orders", ""))
+	assert.ErrorIs(t, trigger("orders", ""), ErrQuotaExceeded)
+
+	assert.Equal(t, int64(5), triggered.Load())
+}
+
+func TestTriggerQuotaRefundsFailedTriggers(t *testing.T) {
+	var failing atomic.Bool
+	clock := &fixedClock{now: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	i := newTestClient(t, InferableOptions{
+		Clock:        clock,
+		TriggerQuota: &TriggerQuota{PerWorkflow: 1, PerTenant: 1},
+	}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/": func(w http.ResponseWriter, r *http.Request) {
+			if failing.Load() {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		},
+	})
+
+	trigger := func() error {
+		_, err := i.Workflows.Trigger("orders", "exec", map[string]interface{}{}, TriggerOptions{TenantKey: "acme"})
+		return err
+	}
+
+	// Failed triggers do not use up the workflow's or the tenant's quota
+	failing.Store(true)
+	for n := 0; n < 3; n++ {
+		err := trigger()
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrQuotaExceeded)
+	}
+
+	failing.Store(false)
+	require.NoError(t, trigger())
+	assert.ErrorIs(t, trigger(), ErrQuotaExceeded)
+}
@@ -0,0 +1,33 @@
+package inferable
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+)
+
+// uuidNamespace is the name-based UUID namespace for IDs created by ctx.NewUUID.
+var uuidNamespace = [16]byte{0x6b, 0xa7, 0xb8, 0x14, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// newExecutionRandom returns a random source seeded from the execution ID, so a handler that is
+// re-executed after an interrupt draws the same values in the same order.
+func newExecutionRandom(executionId string) *rand.Rand {
+	sum := sha256.Sum256([]byte("random:" + executionId))
+	return rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(sum[:8]))))
+}
+
+// newExecutionUUID returns a version 5 UUID derived from the execution ID and name.
+// The same name always yields the same UUID within an execution, and different UUIDs across executions.
+func newExecutionUUID(executionId string, name string) string {
+	hash := sha1.New()
+	hash.Write(uuidNamespace[:])
+	hash.Write([]byte(executionId + "/" + name))
+	sum := hash.Sum(nil)
+
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
@@ -0,0 +1,35 @@
+package inferable
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeterministicRandomness(t *testing.T) {
+	i := newTestClient(t, InferableOptions{KVStore: NewMemoryStore()}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: WorkflowInput{}})
+
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return []interface{}{ctx.Random.Int63(), ctx.Random.Intn(1000), ctx.NewUUID("order"), ctx.NewUUID("invoice")}, nil
+	})
+
+	first, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+
+	// A re-execution after an interrupt draws the same values
+	resumed, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{Approved: true})
+	require.NoError(t, err)
+	assert.Equal(t, first, resumed)
+
+	other, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-2"}, ContextInput{})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.([]interface{})[0], other.([]interface{})[0])
+	assert.NotEqual(t, first.([]interface{})[2], other.([]interface{})[2])
+
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	assert.Regexp(t, uuid, first.([]interface{})[2])
+	assert.NotEqual(t, first.([]interface{})[2], first.([]interface{})[3])
+}
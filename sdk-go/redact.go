@@ -0,0 +1,186 @@
+package inferable
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces the values of redacted fields.
+const RedactedValue = "[REDACTED]"
+
+// redactTag is the struct tag option marking a field as redacted, as in `inferable:"redact"`.
+const redactTag = "redact"
+
+// redactedTypes caches whether a type contains redacted fields.
+var redactedTypes sync.Map
+
+// Redact returns value as it should be stored outside the handler: fields tagged
+// `inferable:"redact"` are replaced with RedactedValue, at any depth. Values of types without
+// redacted fields are returned as is, others as the maps and slices they marshal to.
+//
+// The SDK redacts tool and workflow results before reporting them to the cluster, and the
+// metadata of ctx.Log and execution log entries. Use Redact for values passed to agents and LLMs
+// in their input:
+//
+//	type Customer struct {
+//		Name string `json:"name"`
+//		IBAN string `json:"iban" inferable:"redact"`
+//	}
+//
+//	data, _ := json.Marshal(inferable.Redact(customer))
+//	ctx.Agents.React(inferable.ReactAgentConfig{Input: string(data), ...})
+func Redact(value interface{}) interface{} {
+	if value == nil || !hasRedactedFields(reflect.TypeOf(value)) {
+		return value
+	}
+
+	// Masking the marshaled value keeps the result identical to what encoding/json produces
+	serialized, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var generic interface{}
+	if err := json.Unmarshal(serialized, &generic); err != nil {
+		return value
+	}
+	return mask(reflect.TypeOf(value), generic)
+}
+
+// hasRedactedFields reports whether values of t can contain redacted fields.
+func hasRedactedFields(t reflect.Type) bool {
+	if cached, ok := redactedTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	redacted := inspectRedactedFields(t, map[reflect.Type]bool{})
+	redactedTypes.Store(t, redacted)
+	return redacted
+}
+
+// inspectRedactedFields looks for redacted fields in t. A type seen again in a recursive type is
+// already being inspected further up.
+func inspectRedactedFields(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return inspectRedactedFields(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			// Embedded structs are inspected like encoding/json marshals them, exported or not
+			if (field.IsExported() || field.Anonymous) && (isRedacted(field) || inspectRedactedFields(field.Type, visiting)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isRedacted(field reflect.StructField) bool {
+	for _, option := range strings.Split(field.Tag.Get("inferable"), ",") {
+		if option == redactTag {
+			return true
+		}
+	}
+	return false
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// mask replaces the redacted fields of t in its marshaled form.
+func mask(t reflect.Type, value interface{}) interface{} {
+	if value == nil || t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+		return value
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return mask(t.Elem(), value)
+	case reflect.Slice, reflect.Array:
+		if items, ok := value.([]interface{}); ok {
+			for n, item := range items {
+				items[n] = mask(t.Elem(), item)
+			}
+		}
+	case reflect.Map:
+		if entries, ok := value.(map[string]interface{}); ok {
+			for key, entry := range entries {
+				entries[key] = mask(t.Elem(), entry)
+			}
+		}
+	case reflect.Struct:
+		if fields, ok := value.(map[string]interface{}); ok {
+			maskStruct(t, fields)
+		}
+	}
+	return value
+}
+
+// maskStruct masks the fields of a struct under their JSON names, including the fields of
+// embedded structs, which encoding/json flattens.
+func maskStruct(t reflect.Type, fields map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+
+		embedded := field.Type
+		if embedded.Kind() == reflect.Ptr {
+			embedded = embedded.Elem()
+		}
+		if field.Anonymous && name == "" && embedded.Kind() == reflect.Struct {
+			maskStruct(embedded, fields)
+			continue
+		}
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := fields[name]; !ok {
+			continue
+		}
+		if isRedacted(field) {
+			fields[name] = RedactedValue
+		} else {
+			fields[name] = mask(field.Type, fields[name])
+		}
+	}
+}
+
+// redactMeta redacts the values of log metadata.
+func redactMeta(meta map[string]interface{}) map[string]interface{} {
+	var redacted map[string]interface{}
+	for key, value := range meta {
+		if value == nil || !hasRedactedFields(reflect.TypeOf(value)) {
+			continue
+		}
+		// The caller's map is copied rather than modified
+		if redacted == nil {
+			redacted = make(map[string]interface{}, len(meta))
+			for key, value := range meta {
+				redacted[key] = value
+			}
+		}
+		redacted[key] = Redact(value)
+	}
+	if redacted == nil {
+		return meta
+	}
+	return redacted
+}
+
+// redactResult redacts the successful result of a workflow handler.
+func redactResult(results []reflect.Value) []reflect.Value {
+	if !results[1].IsNil() {
+		return results
+	}
+	result := Redact(results[0].Interface())
+	return []reflect.Value{reflect.ValueOf(&result).Elem(), results[1]}
+}
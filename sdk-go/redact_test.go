@@ -0,0 +1,87 @@
+package inferable
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type redactedAccount struct {
+	IBAN  string `json:"iban" inferable:"redact"`
+	Label string `json:"label,omitempty"`
+}
+
+type redactedCustomer struct {
+	redactedAccount
+	Name     string             `json:"name"`
+	Email    string             `json:"email,omitempty" inferable:"redact"`
+	Internal string             `json:"-"`
+	Accounts []*redactedAccount `json:"accounts"`
+	Referrer *redactedCustomer  `json:"referrer"`
+}
+
+func TestRedact(t *testing.T) {
+	customer := redactedCustomer{
+		redactedAccount: redactedAccount{IBAN: "DE89370400440532013000", Label: "primary"},
+		Name:            "Ada",
+		Internal:        "note",
+		Accounts:        []*redactedAccount{{IBAN: "GB33BUKB20201555555555", Label: "savings"}},
+		Referrer:        &redactedCustomer{Name: "Grace", Email: "grace@example.com"},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"iban":     RedactedValue,
+		"label":    "primary",
+		"name":     "Ada",
+		"accounts": []interface{}{map[string]interface{}{"iban": RedactedValue, "label": "savings"}},
+		"referrer": map[string]interface{}{
+			"iban":     RedactedValue,
+			"name":     "Grace",
+			"email":    RedactedValue,
+			"accounts": nil,
+			"referrer": nil,
+		},
+	}, Redact(customer))
+
+	// Values without redacted fields are returned as is
+	unredacted := chargeInput{Amount: 100}
+	assert.Equal(t, unredacted, Redact(unredacted))
+	assert.Equal(t, "ok", Redact("ok"))
+}
+
+func TestRedactedResultsAndLogs(t *testing.T) {
+	i, results := newResultRecorder(t, InferableOptions{})
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "lookup",
+		Func: func(input chargeInput, ctx ContextInput) (redactedAccount, error) {
+			return redactedAccount{IBAN: "DE89370400440532013000", Label: "main"}, nil
+		},
+	}))
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-1", Function: "lookup", Input: map[string]interface{}{"amount": 1}}))
+	assert.Equal(t, map[string]interface{}{"iban": RedactedValue, "label": "main"}, results()[0].Result)
+
+	var logs bytes.Buffer
+	offline, err := New(InferableOptions{KVStore: NewMemoryStore(), Offline: true})
+	require.NoError(t, err)
+	workflow := offline.Workflows.Create(WorkflowConfig{
+		Name:        "accounts",
+		InputSchema: WorkflowInput{},
+		Logger:      NewJSONLogger(JSONLoggerOptions{Writer: &logs}),
+	})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		account := redactedAccount{IBAN: "DE89370400440532013000"}
+		// The handler sees the value, logs and results do not
+		assert.Equal(t, "DE89370400440532013000", account.IBAN)
+		require.NoError(t, ctx.Log("lookedUp", map[string]interface{}{"account": account}))
+		ctx.Logger.Info("Looked up account", map[string]interface{}{"account": &account})
+		return account, nil
+	})
+
+	result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"iban": RedactedValue}, result)
+	assert.Equal(t, 2, bytes.Count(logs.Bytes(), []byte(`"account":{"iban":"[REDACTED]"}`)))
+	assert.NotContains(t, logs.String(), "DE89")
+}
@@ -0,0 +1,102 @@
+package inferable
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+// resumeTokenMemoName is the memo caching the resume token created for name.
+func resumeTokenMemoName(name string) string {
+	return fmt.Sprintf("resume_token_%s", name)
+}
+
+// resumePayloadKey is the cluster key a redeemed resume token's payload is stored under.
+func resumePayloadKey(executionId string, name string) string {
+	return fmt.Sprintf("resume_%s_%s", executionId, name)
+}
+
+// createResumeToken asks the cluster to sign a resume token for the execution.
+func (w *Workflow) createResumeToken(ctx context.Context, executionId string, name string) (string, error) {
+	clusterId, err := w.inferable.getClusterId()
+	if err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"name": name})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal resume token request: %v", err)
+	}
+
+	result, _, err, status := w.inferable.client.FetchData(client.FetchDataOptions{
+		Path:    fmt.Sprintf("/clusters/%s/workflows/%s/executions/%s/resume-tokens", clusterId, w.name, executionId),
+		Method:  "POST",
+		Body:    string(body),
+		Context: ctx,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create resume token: %w", err)
+	}
+	if status != 201 {
+		return "", fmt.Errorf("failed to create resume token: %w", client.UnexpectedStatus(status))
+	}
+
+	var response struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal([]byte(result), &response); err != nil {
+		return "", fmt.Errorf("failed to unmarshal resume token: %v", err)
+	}
+
+	return response.Token, nil
+}
+
+// resumePayload decodes the payload of a redeemed resume token. Payloads are written by the
+// cluster, so they are read from the cluster's key-value store even when the client has its own.
+func (w *Workflow) resumePayload(ctx context.Context, executionId string, name string, target interface{}) (bool, error) {
+	if _, err := w.inferable.getClusterId(); err != nil {
+		return false, err
+	}
+
+	store := &clusterKVStore{inferable: w.inferable, ctx: ctx}
+	serialized, ok, err := store.Get(resumePayloadKey(executionId, name))
+	if err != nil || !ok {
+		return false, err
+	}
+
+	if err := json.Unmarshal([]byte(serialized), target); err != nil {
+		return false, fmt.Errorf("failed to unmarshal resume payload %s: %v", name, err)
+	}
+	return true, nil
+}
+
+// RedeemResumeToken resumes the execution waiting on a token created with ctx.CreateResumeToken,
+// delivering payload to the handler's ctx.ResumePayload. A token expires after 30 days and can
+// be redeemed once, though a redemption that failed can be retried with the same payload.
+// External systems can also redeem a token without the SDK:
+//
+//	POST /resume-tokens/<token>
+//	{"payload": {"envelopeId": "42"}}
+func (i *Inferable) RedeemResumeToken(token string, payload interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{"payload": payload})
+	if err != nil {
+		return fmt.Errorf("failed to marshal resume payload: %v", err)
+	}
+
+	_, _, err, status := i.client.FetchData(client.FetchDataOptions{
+		Path:   fmt.Sprintf("/resume-tokens/%s", url.PathEscape(token)),
+		Method: "POST",
+		Body:   string(body),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to redeem resume token: %w", err)
+	}
+	if status != 204 {
+		return fmt.Errorf("failed to redeem resume token: %w", client.UnexpectedStatus(status))
+	}
+
+	return nil
+}
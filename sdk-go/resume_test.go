@@ -0,0 +1,70 @@
+package inferable
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResumeToken(t *testing.T) {
+	issued := 0
+	var redeemed string
+	i := newTestClient(t, InferableOptions{KVStore: NewMemoryStore()}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/signing/executions/exec-1/resume-tokens": func(w http.ResponseWriter, r *http.Request) {
+			issued++
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"token": "rt1.claims.signature"})
+		},
+		"/resume-tokens/rt1.claims.signature": func(w http.ResponseWriter, r *http.Request) {
+			var body struct {
+				Payload json.RawMessage `json:"payload"`
+			}
+			data, _ := io.ReadAll(r.Body)
+			require.NoError(t, json.Unmarshal(data, &body))
+			redeemed = string(body.Payload)
+			w.WriteHeader(http.StatusNoContent)
+		},
+		"/clusters/test-cluster/keys/resume_exec-1_contractSigned/value": func(w http.ResponseWriter, r *http.Request) {
+			if redeemed == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"value": redeemed})
+		},
+	})
+
+	type signedEvent struct {
+		EnvelopeID string `json:"envelopeId"`
+	}
+
+	var token string
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "signing", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		var err error
+		if token, err = ctx.CreateResumeToken("contractSigned"); err != nil {
+			return nil, err
+		}
+		var signed signedEvent
+		if ok, err := ctx.ResumePayload("contractSigned", &signed); err != nil || !ok {
+			return GeneralInterrupt("Waiting for signature"), err
+		}
+		return signed.EnvelopeID, nil
+	})
+
+	result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	assert.IsType(t, &Interrupt{}, result)
+	assert.Equal(t, "rt1.claims.signature", token)
+
+	require.NoError(t, i.RedeemResumeToken(token, signedEvent{EnvelopeID: "42"}))
+
+	// The re-executed handler reads the payload, and reuses its token
+	result, err = workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "42", result)
+	assert.Equal(t, 1, issued)
+}
@@ -0,0 +1,57 @@
+package inferable
+
+import (
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+// RetryOptions configures how the client retries requests to the cluster that failed with a
+// network error, a 429 or a 5xx status. Only requests that are safe to repeat are retried: reads,
+// writes that replace a value, and triggers, which are deduplicated by execution ID.
+type RetryOptions struct {
+	// MaxAttempts is the number of attempts made, including the first. 1 disables retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. It doubles with every retry.
+	InitialBackoff time.Duration
+	// MaxBackoff bounds the delay between attempts, including delays the cluster requests with
+	// Retry-After.
+	MaxBackoff time.Duration
+	// Jitter is the fraction of each delay, between 0 and 1, that is randomized, so that clients
+	// failing together do not retry together.
+	Jitter float64
+}
+
+// DefaultRetryOptions are used unless InferableOptions.Retry is set.
+var DefaultRetryOptions = RetryOptions{
+	MaxAttempts:    4,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Jitter:         0.5,
+}
+
+// policy returns the client's retry policy, filling unset options from DefaultRetryOptions.
+func (o *RetryOptions) policy() client.RetryPolicy {
+	if o == nil {
+		o = &DefaultRetryOptions
+	}
+	policy := client.RetryPolicy{
+		MaxAttempts:    o.MaxAttempts,
+		InitialBackoff: o.InitialBackoff,
+		MaxBackoff:     o.MaxBackoff,
+		Jitter:         o.Jitter,
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DefaultRetryOptions.MaxAttempts
+	}
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = DefaultRetryOptions.InitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = DefaultRetryOptions.MaxBackoff
+	}
+	if policy.Jitter < 0 || policy.Jitter > 1 {
+		policy.Jitter = DefaultRetryOptions.Jitter
+	}
+	return policy
+}
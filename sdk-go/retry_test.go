@@ -0,0 +1,117 @@
+package inferable
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRetries(t *testing.T) {
+	var gets, triggers, cancels atomic.Int64
+	routes := map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflow-executions/exec-1/children": func(w http.ResponseWriter, r *http.Request) {
+			if gets.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"executions": []interface{}{}, "runs": []interface{}{}})
+		},
+		"/clusters/test-cluster/workflows/orders/executions": func(w http.ResponseWriter, r *http.Request) {
+			if triggers.Add(1) < 2 {
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+		},
+		"/clusters/test-cluster/workflow-executions": func(w http.ResponseWriter, r *http.Request) {
+			// Cancel looks up the execution by its ID alone
+			if r.URL.Query().Get("workflowName") == "" {
+				_ = json.NewEncoder(w).Encode([]map[string]interface{}{{"execution": map[string]interface{}{"id": "exec-1", "workflowName": "orders"}, "job": map[string]interface{}{"status": "running"}}})
+				return
+			}
+			gets.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+		"/clusters/test-cluster/workflow-executions/exec-1/cancel": func(w http.ResponseWriter, r *http.Request) {
+			cancels.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+	i := newTestClient(t, InferableOptions{
+		KVStore: NewMemoryStore(),
+		Retry:   &RetryOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+	}, routes)
+
+	_, err := i.Workflows.Executions.Children("exec-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), gets.Load())
+
+	// Retry-After is capped by MaxBackoff
+	start := time.Now()
+	_, err = i.Workflows.Trigger("orders", "exec-1", map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), triggers.Load())
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Requests that are not idempotent are not retried
+	err = i.Workflows.Executions.Cancel("exec-1")
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+	assert.Equal(t, int64(1), cancels.Load())
+
+	// Retries stop when the request's context is done
+	slow := newTestClient(t, InferableOptions{
+		Retry: &RetryOptions{MaxAttempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Minute},
+	}, routes)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	gets.Store(0)
+	execution := &Execution{workflows: slow.Workflows, workflowName: "orders", id: "exec-1"}
+	_, err = execution.Status(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int64(1), gets.Load())
+}
+
+func TestPollIsNotRetried(t *testing.T) {
+	var polls, reads atomic.Int64
+	i := newTestClient(t, InferableOptions{
+		Retry: &RetryOptions{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond},
+	}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/jobs": func(w http.ResponseWriter, r *http.Request) {
+			// The calls may have been acknowledged before the response was lost
+			polls.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+		"/clusters/test-cluster/tools": func(w http.ResponseWriter, r *http.Request) {
+			reads.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	})
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "charge",
+		Func: func(input chargeInput, ctx ContextInput) (int, error) { return input.Amount, nil },
+	}))
+	i.Tools.ctx = context.Background()
+
+	assert.Error(t, i.Tools.poll())
+	assert.Equal(t, int64(1), polls.Load())
+
+	// GETs are only retried when marked idempotent
+	_, _, _, status := i.client.FetchData(client.FetchDataOptions{Path: "/clusters/test-cluster/tools", Method: "GET"})
+	assert.Equal(t, http.StatusBadGateway, status)
+	assert.Equal(t, int64(1), reads.Load())
+
+	_, _, _, status = i.client.FetchData(client.FetchDataOptions{Path: "/clusters/test-cluster/tools", Method: "GET", Idempotent: true})
+	assert.Equal(t, http.StatusBadGateway, status)
+	assert.Equal(t, int64(4), reads.Load())
+}
@@ -0,0 +1,225 @@
+package inferable
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultSemanticCacheThreshold is the minimum cosine similarity for a cached result to be reused.
+	DefaultSemanticCacheThreshold = 0.95
+	// DefaultSemanticCacheMaxEntries is the number of results kept before the oldest are evicted.
+	DefaultSemanticCacheMaxEntries = 1000
+)
+
+// Embedder converts text into an embedding vector.
+// Implementations typically call an embedding model such as OpenAI's text-embedding-3-small.
+type Embedder interface {
+	Embed(text string) ([]float64, error)
+}
+
+// EmbedderFunc adapts a function to the Embedder interface.
+type EmbedderFunc func(text string) ([]float64, error)
+
+// Embed calls f(text).
+func (f EmbedderFunc) Embed(text string) ([]float64, error) {
+	return f(text)
+}
+
+// SemanticCacheOptions configures a SemanticCache.
+type SemanticCacheOptions struct {
+	// Embedder computes the embeddings used to compare inputs. Required.
+	Embedder Embedder
+	// Threshold is the minimum cosine similarity (0-1) between inputs for a cached result to be reused.
+	// Defaults to DefaultSemanticCacheThreshold.
+	Threshold float64
+	// MaxEntries bounds the number of cached results. Defaults to DefaultSemanticCacheMaxEntries.
+	MaxEntries int
+}
+
+// SemanticCache reuses LLM.Structured results for inputs that are semantically similar to a
+// previously seen input with the same schema and model, e.g. templated support tickets.
+// Results are only reused within the process and are shared by all workflows the cache is configured on.
+//
+// The result of each call is recorded in the workflow's key-value store for its execution, so
+// that a handler re-executed after an interrupt or a restart gets the same result again, and the
+// cache is only consulted for calls the execution has not made before.
+type SemanticCache struct {
+	embedder   Embedder
+	threshold  float64
+	maxEntries int
+
+	mu      sync.RWMutex
+	entries []semanticCacheEntry
+}
+
+type semanticCacheEntry struct {
+	key    string
+	vector []float64
+	result interface{}
+}
+
+// NewSemanticCache creates a SemanticCache with the given options.
+func NewSemanticCache(options SemanticCacheOptions) (*SemanticCache, error) {
+	if options.Embedder == nil {
+		return nil, fmt.Errorf("semantic cache requires an embedder")
+	}
+
+	if options.Threshold == 0 {
+		options.Threshold = DefaultSemanticCacheThreshold
+	}
+	if options.Threshold < 0 || options.Threshold > 1 {
+		return nil, fmt.Errorf("semantic cache threshold must be between 0 and 1, got %v", options.Threshold)
+	}
+
+	if options.MaxEntries <= 0 {
+		options.MaxEntries = DefaultSemanticCacheMaxEntries
+	}
+
+	return &SemanticCache{
+		embedder:   options.Embedder,
+		threshold:  options.Threshold,
+		maxEntries: options.MaxEntries,
+	}, nil
+}
+
+// lookup returns the most similar cached result for the key, if one is above the threshold.
+func (c *SemanticCache) lookup(key string, vector []float64) (interface{}, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var best interface{}
+	bestScore := -1.0
+	for _, entry := range c.entries {
+		if entry.key != key {
+			continue
+		}
+		if score := cosineSimilarity(entry.vector, vector); score > bestScore {
+			best, bestScore = entry.result, score
+		}
+	}
+
+	if bestScore < c.threshold {
+		return nil, false
+	}
+	// Callers own their result, so that changing it does not change the cache
+	return copyJSONValue(best), true
+}
+
+// store adds a result to the cache, evicting the oldest entry when full.
+func (c *SemanticCache) store(key string, vector []float64, result interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.maxEntries {
+		c.entries = c.entries[1:]
+	}
+	c.entries = append(c.entries, semanticCacheEntry{key: key, vector: vector, result: copyJSONValue(result)})
+}
+
+// copyJSONValue deep copies a decoded JSON value, i.e. maps, slices and scalars.
+func copyJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyJSONValue(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyJSONValue(item)
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// semanticMemoKey identifies a call of an execution made with the semantic cache, by its cache
+// key and input.
+func semanticMemoKey(executionId string, cacheKey string, input string) string {
+	if executionId == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s_semantic_%x", executionId, sha256.Sum256([]byte(cacheKey+"\x00"+input)))
+}
+
+// semanticMemo returns the result recorded for a call by memoizeSemantic.
+func (l *LLM) semanticMemo(key string) (interface{}, bool) {
+	if l.store == nil || key == "" {
+		return nil, false
+	}
+	serialized, ok, err := l.store.Get(key)
+	if err != nil || !ok {
+		return nil, false
+	}
+	var result interface{}
+	if err := json.Unmarshal([]byte(serialized), &result); err != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+// memoizeSemantic records the result of a call. Failures only leave a replay to consult the
+// cache again, since the result was valid either way.
+func (l *LLM) memoizeSemantic(key string, result interface{}) {
+	if l.store == nil || key == "" {
+		return
+	}
+	serialized, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	_ = l.store.SetIfAbsent(key, string(serialized))
+}
+
+// semanticCacheKey identifies the schema, model, instructions and generation options a cached
+// result was produced for. Results are never reused across different ones. Instructions differing
+// only in whitespace, and unset generation options, share a key.
+func semanticCacheKey(schema interface{}, model string, instructions string, generation *GenerationOptions) (string, error) {
+	schemaJSON, err := json.Marshal(schema)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal schema for semantic cache: %v", err)
+	}
+
+	if generation == nil {
+		generation = &GenerationOptions{}
+	}
+	generationJSON, err := json.Marshal(generation)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal generation options for semantic cache: %v", err)
+	}
+
+	hash := sha256.New()
+	for _, part := range [][]byte{schemaJSON, []byte(model), []byte(strings.Join(strings.Fields(instructions), " ")), generationJSON} {
+		// Separate the parts, so that moving text from one to the next changes the key
+		hash.Write(part)
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 if they are not comparable.
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,133 @@
+package inferable
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemanticCache(t *testing.T) {
+	// Embed inputs by whether they mention a refund, so templated tickets map to the same vector
+	embedder := EmbedderFunc(func(text string) ([]float64, error) {
+		if strings.Contains(text, "refund") {
+			return []float64{1, 0.01}, nil
+		}
+		return []float64{0, 1}, nil
+	})
+
+	cache, err := NewSemanticCache(SemanticCacheOptions{Embedder: embedder})
+	require.NoError(t, err)
+
+	calls := 0
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		calls++
+		return 200, map[string]interface{}{"data": map[string]interface{}{"merchant": "ACME"}}
+	})
+	llm.semanticCache = cache
+
+	_, err = llm.Structured(StructuredInput{Input: "Ticket #1: I want a refund", Schema: testReceipt{}})
+	require.NoError(t, err)
+
+	result, err := llm.Structured(StructuredInput{Input: "Ticket #2: I want a refund", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"merchant": "ACME"}, result)
+	assert.Equal(t, 1, calls)
+
+	// Dissimilar inputs, other schemas, and explicit opt-outs all reach the LLM
+	_, err = llm.Structured(StructuredInput{Input: "Where is my order?", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	_, err = llm.Structured(StructuredInput{Input: "Ticket #3: I want a refund", Schema: struct {
+		Merchant string `json:"merchant"`
+		Reason   string `json:"reason,omitempty"`
+	}{}})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	_, err = llm.Structured(StructuredInput{Input: "Ticket #4: I want a refund", Schema: testReceipt{}, SkipSemanticCache: true})
+	require.NoError(t, err)
+	assert.Equal(t, 4, calls)
+
+	// Other instructions and generation options reach the LLM, whitespace in instructions does not
+	_, err = llm.Structured(StructuredInput{Input: "Ticket #5: I want a refund", Schema: testReceipt{}, Instructions: "Extract the  merchant"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, calls)
+
+	_, err = llm.Structured(StructuredInput{Input: "Ticket #6: I want a refund", Schema: testReceipt{}, Instructions: " Extract the merchant\n"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, calls)
+
+	temperature := 0.2
+	_, err = llm.Structured(StructuredInput{Input: "Ticket #7: I want a refund", Schema: testReceipt{}, Generation: &GenerationOptions{Temperature: &temperature}})
+	require.NoError(t, err)
+	assert.Equal(t, 6, calls)
+
+	_, err = llm.Structured(StructuredInput{Input: "Ticket #8: I want a refund", Schema: testReceipt{}, Generation: &GenerationOptions{}})
+	require.NoError(t, err)
+	assert.Equal(t, 6, calls)
+}
+
+func TestSemanticCacheReplay(t *testing.T) {
+	embedder := EmbedderFunc(func(text string) ([]float64, error) { return []float64{1, 0}, nil })
+	newCache := func() *SemanticCache {
+		cache, err := NewSemanticCache(SemanticCacheOptions{Embedder: embedder})
+		require.NoError(t, err)
+		return cache
+	}
+
+	calls := 0
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		calls++
+		return 200, map[string]interface{}{"data": map[string]interface{}{"merchant": fmt.Sprintf("merchant %d", calls)}}
+	})
+	llm.semanticCache = newCache()
+	llm.store = NewMemoryStore()
+	execution := func(id string) *LLM {
+		bound := *llm
+		bound.executionId = id
+		return &bound
+	}
+
+	first, err := execution("exec-1").Structured(StructuredInput{Input: "Ticket #1: I want a refund", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"merchant": "merchant 1"}, first)
+
+	// Results are copied out of the cache
+	first.(map[string]interface{})["merchant"] = "changed"
+	hit, err := execution("exec-2").Structured(StructuredInput{Input: "Ticket #2: I want a refund", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"merchant": "merchant 1"}, hit)
+	assert.Equal(t, 1, calls)
+
+	// After a restart, another execution caches a different result for a similar input
+	llm.semanticCache = newCache()
+	_, err = execution("exec-3").Structured(StructuredInput{Input: "Ticket #3: I want a refund", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// Replays get the results of their first run, whether the cluster or the cache answered it
+	replay, err := execution("exec-1").Structured(StructuredInput{Input: "Ticket #1: I want a refund", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"merchant": "merchant 1"}, replay)
+
+	replay, err = execution("exec-2").Structured(StructuredInput{Input: "Ticket #2: I want a refund", Schema: testReceipt{}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"merchant": "merchant 1"}, replay)
+	assert.Equal(t, 2, calls)
+}
+
+func TestNewSemanticCacheValidation(t *testing.T) {
+	_, err := NewSemanticCache(SemanticCacheOptions{})
+	assert.Error(t, err)
+
+	_, err = NewSemanticCache(SemanticCacheOptions{
+		Embedder:  EmbedderFunc(func(string) ([]float64, error) { return nil, nil }),
+		Threshold: 1.5,
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package inferable
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultShutdownTimeout is the time shutdown hooks get to finish, unless
+// InferableOptions.ShutdownTimeout is set.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// DefaultDrainTimeout is the time the calls in flight get to finish when the machine stops
+// polling, unless InferableOptions.DrainTimeout is set.
+const DefaultDrainTimeout = 30 * time.Second
+
+// ErrDrainTimeout is returned by Unlisten and Shutdown when calls were still in flight at the
+// drain deadline.
+var ErrDrainTimeout = errors.New("calls still in flight at the drain deadline")
+
+// Health is the state of the machine, e.g. for readiness and liveness probes.
+type Health struct {
+	// Polling reports whether the machine is polling for jobs.
+	Polling bool
+	// ShuttingDown reports that the machine stopped polling, drained its calls in flight and ran
+	// its shutdown hooks.
+	ShuttingDown bool
+	// ShutdownErrors are the errors of the shutdown hooks that failed or missed the deadline.
+	ShutdownErrors []string
+}
+
+// shutdownHooks runs the hooks registered with RegisterShutdownHook once per client.
+type shutdownHooks struct {
+	mu      sync.Mutex
+	hooks   []func(ctx context.Context) error
+	once    sync.Once
+	started bool
+	errs    []string
+}
+
+// RegisterShutdownHook adds a hook run when the machine shuts down, by Shutdown or the first
+// Unlisten, once it stopped polling and its calls in flight finished or the drain timed out: e.g.
+// to flush caches the calls wrote to, or notify peers. Hooks run in the
+// order they were registered, with a ctx that expires after InferableOptions.ShutdownTimeout for
+// all of them together. Failed hooks, including those running or not yet run at the deadline, are
+// logged to the Logger and listed in Health.
+//
+//	client.RegisterShutdownHook(func(ctx context.Context) error {
+//		return cache.Flush(ctx)
+//	})
+func (i *Inferable) RegisterShutdownHook(hook func(ctx context.Context) error) {
+	i.shutdown.mu.Lock()
+	defer i.shutdown.mu.Unlock()
+	i.shutdown.hooks = append(i.shutdown.hooks, hook)
+}
+
+// Shutdown stops polling, waits for the calls in flight to finish and runs the shutdown hooks. It
+// returns an error listing the hooks that failed, or ErrDrainTimeout.
+func (i *Inferable) Shutdown() error {
+	drainErr := i.Tools.Unlisten()
+	// Unlisten runs the hooks only if the machine was listening
+	i.runShutdownHooks()
+
+	health := i.Health()
+	if len(health.ShutdownErrors) > 0 {
+		return fmt.Errorf("shutdown hooks failed: %s", strings.Join(health.ShutdownErrors, "; "))
+	}
+	return drainErr
+}
+
+// Health returns the state of the machine.
+func (i *Inferable) Health() Health {
+	i.shutdown.mu.Lock()
+	defer i.shutdown.mu.Unlock()
+	return Health{
+		Polling:        i.Tools.isPolling() && i.Tools.ctx.Err() == nil,
+		ShuttingDown:   i.shutdown.started,
+		ShutdownErrors: append([]string(nil), i.shutdown.errs...),
+	}
+}
+
+// runShutdownHooks runs the shutdown hooks, the first time it is called.
+func (i *Inferable) runShutdownHooks() {
+	i.shutdown.once.Do(func() {
+		i.shutdown.mu.Lock()
+		i.shutdown.started = true
+		hooks := i.shutdown.hooks
+		i.shutdown.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), i.shutdownTimeout)
+		defer cancel()
+
+		for n, hook := range hooks {
+			var err error
+			if ctx.Err() != nil {
+				err = fmt.Errorf("did not run, the shutdown deadline passed")
+			} else {
+				done := make(chan error, 1)
+				go func() { done <- hook(ctx) }()
+
+				select {
+				case err = <-done:
+				case <-ctx.Done():
+					err = fmt.Errorf("did not finish within %s", i.shutdownTimeout)
+				}
+			}
+			if err == nil {
+				continue
+			}
+
+			message := fmt.Sprintf("shutdown hook %d: %v", n, err)
+			i.shutdown.mu.Lock()
+			i.shutdown.errs = append(i.shutdown.errs, message)
+			i.shutdown.mu.Unlock()
+			if i.logger != nil {
+				i.logger.Error("Shutdown hook failed", map[string]interface{}{"hook": n, "error": err.Error()})
+			} else {
+				log.Printf("Shutdown hook %d failed: %v", n, err)
+			}
+		}
+	})
+}
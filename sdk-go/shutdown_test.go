@@ -0,0 +1,166 @@
+package inferable
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownHooks(t *testing.T) {
+	logger := &recordingLogger{}
+	i := newTestClient(t, InferableOptions{Logger: logger, ShutdownTimeout: 100 * time.Millisecond}, nil)
+
+	ran := []string{}
+	i.RegisterShutdownHook(func(ctx context.Context) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		ran = append(ran, "flush")
+		return nil
+	})
+	i.RegisterShutdownHook(func(ctx context.Context) error {
+		ran = append(ran, "notify")
+		return fmt.Errorf("peer unreachable")
+	})
+	// A hook ignoring its ctx does not hold up shutdown
+	i.RegisterShutdownHook(func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	i.RegisterShutdownHook(func(ctx context.Context) error {
+		ran = append(ran, "late")
+		return nil
+	})
+
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "charge",
+		Func: func(input chargeInput, ctx ContextInput) (int, error) { return input.Amount, nil },
+	}))
+	require.NoError(t, i.Tools.Listen())
+	assert.Equal(t, Health{Polling: true}, i.Health())
+
+	started := time.Now()
+	i.Tools.Unlisten()
+	assert.Less(t, time.Since(started), 500*time.Millisecond)
+	assert.Equal(t, []string{"flush", "notify"}, ran)
+
+	health := i.Health()
+	assert.False(t, health.Polling)
+	assert.True(t, health.ShuttingDown)
+	assert.Equal(t, []string{
+		"shutdown hook 1: peer unreachable",
+		"shutdown hook 2: did not finish within 100ms",
+		"shutdown hook 3: did not run, the shutdown deadline passed",
+	}, health.ShutdownErrors)
+	// The client's other diagnostics, e.g. of polls failing against the test server, are logged too
+	assert.Len(t, logger.errorsOf("Shutdown hook failed"), 3)
+
+	// Hooks run once
+	err := i.Shutdown()
+	assert.ErrorContains(t, err, "shutdown hooks failed: shutdown hook 1: peer unreachable")
+	assert.Len(t, ran, 2)
+}
+
+// newDrainTestClient returns a client whose first poll receives a call to the tool "slow", and
+// whose later polls wait for a call until they are aborted.
+func newDrainTestClient(t *testing.T, drainTimeout time.Duration, persisted chan<- string) *Inferable {
+	t.Helper()
+
+	var polls atomic.Int64
+	i := newTestClient(t, InferableOptions{DrainTimeout: drainTimeout}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/jobs": func(w http.ResponseWriter, r *http.Request) {
+			if polls.Add(1) == 1 {
+				json.NewEncoder(w).Encode([]map[string]interface{}{{"id": "call-1", "function": "slow", "input": map[string]interface{}{}}})
+				return
+			}
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+				t.Error("poll was not aborted by Unlisten")
+			}
+		},
+		"/clusters/test-cluster/jobs/call-1/result": func(w http.ResponseWriter, r *http.Request) {
+			persisted <- "call-1"
+		},
+	})
+	return i
+}
+
+func TestUnlistenDrainsCallsInFlight(t *testing.T) {
+	persisted := make(chan string, 1)
+	i := newDrainTestClient(t, 5*time.Second, persisted)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished, finishedBeforeHook atomic.Bool
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "slow",
+		Func: func(input struct{}, ctx ContextInput) (string, error) {
+			close(started)
+			<-release
+			finished.Store(true)
+			return "done", nil
+		},
+	}))
+	i.RegisterShutdownHook(func(ctx context.Context) error {
+		finishedBeforeHook.Store(finished.Load())
+		return nil
+	})
+	require.NoError(t, i.Tools.Listen())
+	<-started
+
+	unlistened := make(chan error, 1)
+	go func() { unlistened <- i.Tools.Unlisten() }()
+
+	// Unlisten waits for the call in flight
+	select {
+	case <-unlistened:
+		t.Fatal("Unlisten returned with a call in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.False(t, i.Health().Polling)
+	assert.False(t, i.Health().ShuttingDown)
+
+	close(release)
+	require.NoError(t, <-unlistened)
+	assert.Equal(t, "call-1", <-persisted)
+
+	// The hooks ran after the call
+	assert.True(t, i.Health().ShuttingDown)
+	assert.True(t, finishedBeforeHook.Load())
+}
+
+func TestUnlistenDrainTimeout(t *testing.T) {
+	i := newDrainTestClient(t, 50*time.Millisecond, make(chan string, 1))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	require.NoError(t, i.Tools.Register(Tool{
+		Name: "slow",
+		Func: func(input struct{}, ctx ContextInput) (string, error) {
+			close(started)
+			<-release
+			return "done", nil
+		},
+	}))
+	var hooks atomic.Int64
+	i.RegisterShutdownHook(func(ctx context.Context) error {
+		hooks.Add(1)
+		return nil
+	})
+	require.NoError(t, i.Tools.Listen())
+	<-started
+
+	err := i.Shutdown()
+	assert.ErrorIs(t, err, ErrDrainTimeout)
+	assert.ErrorContains(t, err, "1 after 50ms")
+	// The hooks still run once the drain timed out
+	assert.Equal(t, int64(1), hooks.Load())
+}
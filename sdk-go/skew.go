@@ -0,0 +1,78 @@
+package inferable
+
+import (
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultClockSkewThreshold is the clock difference to the control plane above which a warning is
+// logged and ctx.Now and ctx.Sleep are compensated.
+const DefaultClockSkewThreshold = 2 * time.Second
+
+// skewTracker measures the offset of the local clock from the control plane's Date headers.
+type skewTracker struct {
+	threshold time.Duration
+	logger    Logger
+	// offset is the control plane time minus the local time, in nanoseconds.
+	offset atomic.Int64
+	skewed atomic.Bool
+}
+
+// observe records the offset from a response's Date header, received at the given local time.
+func (t *skewTracker) observe(headers http.Header, received time.Time) {
+	date := headers.Get("Date")
+	if date == "" {
+		return
+	}
+	server, err := http.ParseTime(date)
+	if err != nil {
+		return
+	}
+
+	// The Date header is truncated to the second, on average it is half a second behind
+	offset := server.Add(500 * time.Millisecond).Sub(received)
+	t.offset.Store(int64(offset))
+
+	skewed := offset > t.threshold || offset < -t.threshold
+	if skewed == t.skewed.Swap(skewed) {
+		return
+	}
+
+	meta := map[string]interface{}{"offset": offset.Round(time.Millisecond).String(), "threshold": t.threshold.String()}
+	switch {
+	case skewed && t.logger != nil:
+		t.logger.Error("Local clock is skewed from the control plane, compensating ctx.Now and ctx.Sleep", meta)
+	case skewed:
+		log.Printf("Local clock is %s off the control plane, compensating ctx.Now and ctx.Sleep", offset.Round(time.Millisecond))
+	case t.logger != nil:
+		t.logger.Info("Local clock is back in sync with the control plane", meta)
+	default:
+		log.Printf("Local clock is back in sync with the control plane")
+	}
+}
+
+// compensation returns the offset to apply to local time, or zero when within the threshold.
+func (t *skewTracker) compensation() time.Duration {
+	if !t.skewed.Load() {
+		return 0
+	}
+	return time.Duration(t.offset.Load())
+}
+
+// skewCompensatedClock is a Clock whose Now follows the control plane when the local clock is skewed.
+type skewCompensatedClock struct {
+	Clock
+	tracker *skewTracker
+}
+
+func (c skewCompensatedClock) Now() time.Time {
+	return c.Clock.Now().Add(c.tracker.compensation())
+}
+
+// ClockSkew returns the last measured offset of this machine's clock from the control plane,
+// positive when the local clock is behind. It is measured on every poll.
+func (i *Inferable) ClockSkew() time.Duration {
+	return time.Duration(i.skew.offset.Load())
+}
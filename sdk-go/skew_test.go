@@ -0,0 +1,117 @@
+package inferable
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingLogger struct {
+	mu     sync.Mutex
+	debugs []string
+	infos  []string
+	warns  []string
+	errors []string
+}
+
+func (l *recordingLogger) Debug(message string, meta map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.debugs = append(l.debugs, message)
+}
+
+func (l *recordingLogger) Info(message string, meta map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.infos = append(l.infos, message)
+}
+
+func (l *recordingLogger) Warn(message string, meta map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.warns = append(l.warns, message)
+}
+
+func (l *recordingLogger) Error(message string, meta map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errors = append(l.errors, message)
+}
+
+// errorsOf returns the errors logged with message.
+func (l *recordingLogger) errorsOf(message string) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	matching := []string{}
+	for _, logged := range l.errors {
+		if logged == message {
+			matching = append(matching, logged)
+		}
+	}
+	return matching
+}
+
+func dateHeader(t time.Time) http.Header {
+	return http.Header{"Date": []string{t.UTC().Format(http.TimeFormat)}}
+}
+
+func TestClockSkewCompensation(t *testing.T) {
+	logger := &recordingLogger{}
+	i, err := New(InferableOptions{APISecret: "test-secret", Logger: logger})
+	require.NoError(t, err)
+
+	// Within the threshold, the system clock is used as is
+	now := time.Now()
+	i.skew.observe(dateHeader(now), now)
+	assert.InDelta(t, 0, i.ClockSkew().Seconds(), 1)
+	assert.WithinDuration(t, time.Now(), i.clock.Now(), 100*time.Millisecond)
+	assert.Empty(t, logger.errors)
+
+	// The control plane is ten minutes ahead
+	now = time.Now()
+	i.skew.observe(dateHeader(now.Add(10*time.Minute)), now)
+	assert.InDelta(t, (10 * time.Minute).Seconds(), i.ClockSkew().Seconds(), 1)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), i.clock.Now(), time.Second)
+	assert.Len(t, logger.errors, 1)
+
+	// Warnings are logged when the state changes, not on every poll
+	i.skew.observe(dateHeader(now.Add(10*time.Minute)), now)
+	assert.Len(t, logger.errors, 1)
+
+	now = time.Now()
+	i.skew.observe(dateHeader(now), now)
+	assert.WithinDuration(t, time.Now(), i.clock.Now(), 100*time.Millisecond)
+	assert.Len(t, logger.infos, 1)
+}
+
+func TestClockSkewIgnoresCustomClock(t *testing.T) {
+	clock := &fixedClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	i, err := New(InferableOptions{APISecret: "test-secret", Clock: clock})
+	require.NoError(t, err)
+
+	i.skew.observe(dateHeader(time.Now().Add(time.Hour)), time.Now())
+	assert.Equal(t, clock.now, i.clock.Now())
+
+	// Responses without a usable Date header are ignored
+	i.skew.observe(http.Header{"Date": []string{"yesterday"}}, time.Now())
+	i.skew.observe(nil, time.Now())
+	assert.InDelta(t, time.Hour.Seconds(), i.ClockSkew().Seconds(), 1)
+}
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fixedClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- c.now.Add(d)
+	return ch
+}
@@ -0,0 +1,280 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+	"github.com/inferablehq/inferable/sdk-go/kv"
+)
+
+// snapshotFormatVersion is bumped when ExecutionSnapshot changes incompatibly.
+const snapshotFormatVersion = 1
+
+// ExecutionSnapshot is a portable copy of an execution's durable state: its input, its status and
+// pending interrupt, and every key-value entry it recorded (ctx.Memo results, ctx.State versions,
+// structured outputs). It marshals to JSON, so it can be saved to a file, imported into another
+// cluster with ImportExecution, or loaded into a MemoryStore to debug the execution locally.
+type ExecutionSnapshot struct {
+	FormatVersion   int                    `json:"formatVersion"`
+	ExecutionID     string                 `json:"executionId"`
+	WorkflowName    string                 `json:"workflowName"`
+	WorkflowVersion int                    `json:"workflowVersion"`
+	Input           map[string]interface{} `json:"input,omitempty"`
+	// Status is the execution's Execution* status when it was exported.
+	Status     string      `json:"status"`
+	ResultType string      `json:"resultType,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	Approved   bool        `json:"approved"`
+	// Entries holds the execution's key-value entries, keyed without the "<executionId>_" prefix so
+	// that they can be imported under another execution ID.
+	Entries    map[string]string `json:"entries"`
+	ExportedAt time.Time         `json:"exportedAt"`
+}
+
+// Interrupt returns the interrupt the execution was paused on, if any.
+func (s *ExecutionSnapshot) Interrupt() *Interrupt {
+	if s.ResultType != "interrupt" {
+		return nil
+	}
+	interrupt := &Interrupt{Type: GENERAL}
+	if data, err := json.Marshal(s.Result); err == nil {
+		json.Unmarshal(data, interrupt)
+	}
+	return interrupt
+}
+
+// Memos returns the decoded ctx.Memo results in the snapshot, by name.
+func (s *ExecutionSnapshot) Memos() map[string]interface{} {
+	memos := map[string]interface{}{}
+	for key, serialized := range s.Entries {
+		name, ok := strings.CutPrefix(key, "memo_")
+		if !ok {
+			continue
+		}
+		if value, err := kv.Decode[interface{}](serialized); err == nil && value != nil {
+			memos[name] = value
+		}
+	}
+	return memos
+}
+
+// MemoryStore returns a MemoryStore holding the snapshot's entries, for re-running the execution
+// in-process, e.g. with Workflow.Execute or the testkit package.
+func (s *ExecutionSnapshot) MemoryStore() *MemoryStore {
+	store := NewMemoryStore()
+	for key, value := range s.Entries {
+		store.Set(s.ExecutionID+"_"+key, value)
+	}
+	return store
+}
+
+// ExportExecution captures an execution's durable state as a snapshot. Entries are read from the
+// store backing the workflow's ctx.Memo; stores that cannot list their keys only contribute the
+// memo and structured output entries the cluster reports for the execution.
+func (w *Workflows) ExportExecution(workflowName string, executionId string) (*ExecutionSnapshot, error) {
+	timeline, err := w.fetchTimeline(workflowName, executionId)
+	if err != nil {
+		return nil, err
+	}
+	return w.snapshotOf(workflowName, executionId, timeline)
+}
+
+type timelineEntry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// executionTimeline is the cluster's record of an execution: its job, the agent runs it started,
+// the events of its job, and its memo and structured output entries.
+type executionTimeline struct {
+	Execution struct {
+		WorkflowVersion int       `json:"workflowVersion"`
+		CreatedAt       time.Time `json:"createdAt"`
+		UpdatedAt       time.Time `json:"updatedAt"`
+		Job             struct {
+			Status     string  `json:"status"`
+			TargetArgs string  `json:"targetArgs"`
+			Result     *string `json:"result"`
+			ResultType *string `json:"resultType"`
+			Approved   *bool   `json:"approved"`
+		} `json:"job"`
+	} `json:"execution"`
+	Runs []struct {
+		ID            string    `json:"id"`
+		Name          string    `json:"name"`
+		Status        *string   `json:"status"`
+		FailureReason *string   `json:"failureReason"`
+		CreatedAt     time.Time `json:"createdAt"`
+	} `json:"runs"`
+	Events []struct {
+		Type      string    `json:"type"`
+		TargetFn  *string   `json:"targetFn"`
+		CreatedAt time.Time `json:"createdAt"`
+	} `json:"events"`
+	Memos      []timelineEntry `json:"memos"`
+	Structured []timelineEntry `json:"structured"`
+}
+
+// fetchTimeline returns the cluster's timeline of an execution.
+func (w *Workflows) fetchTimeline(workflowName string, executionId string) (*executionTimeline, error) {
+	clusterId, err := w.inferable.getClusterId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster id: %w", err)
+	}
+
+	result, _, err, status := w.inferable.fetchData(client.FetchDataOptions{
+		Path:       fmt.Sprintf("/clusters/%s/workflows/%s/executions/%s/timeline", clusterId, workflowName, executionId),
+		Method:     "GET",
+		Idempotent: true,
+	})
+	if status == 404 {
+		return nil, fmt.Errorf("workflow execution %s not found", executionId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow execution: %w", err)
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("failed to get workflow execution: %w", client.UnexpectedStatus(status))
+	}
+
+	var timeline executionTimeline
+	if err := json.Unmarshal(result, &timeline); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workflow execution: %v", err)
+	}
+	return &timeline, nil
+}
+
+// snapshotOf captures an execution's durable state from its timeline and the workflow's store.
+func (w *Workflows) snapshotOf(workflowName string, executionId string, timeline *executionTimeline) (*ExecutionSnapshot, error) {
+	job := timeline.Execution.Job
+	snapshot := &ExecutionSnapshot{
+		FormatVersion:   snapshotFormatVersion,
+		ExecutionID:     executionId,
+		WorkflowName:    workflowName,
+		WorkflowVersion: timeline.Execution.WorkflowVersion,
+		Status:          job.Status,
+		Entries:         map[string]string{},
+		ExportedAt:      time.Now().UTC(),
+	}
+	// Job arguments are stored in the same envelope as memo results
+	if input, err := kv.Decode[map[string]interface{}](job.TargetArgs); err == nil {
+		snapshot.Input = input
+	}
+	if job.ResultType != nil {
+		snapshot.ResultType = *job.ResultType
+	}
+	if job.Result != nil {
+		// Results are stored serialized; keep the raw string if it is not JSON
+		if err := json.Unmarshal([]byte(*job.Result), &snapshot.Result); err != nil {
+			snapshot.Result = *job.Result
+		}
+	}
+	if job.Approved != nil {
+		snapshot.Approved = *job.Approved
+	}
+
+	prefix := executionId + "_"
+	entries := []KVEntry{}
+	if lister, ok := w.storeFor(workflowName).(KVLister); ok {
+		var err error
+		if entries, err = listAll(lister, prefix); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, e := range append(timeline.Memos, timeline.Structured...) {
+			entries = append(entries, KVEntry{Key: e.Key, Value: e.Value})
+		}
+	}
+	for _, entry := range entries {
+		if name, ok := strings.CutPrefix(entry.Key, prefix); ok {
+			snapshot.Entries[name] = entry.Value
+		}
+	}
+
+	return snapshot, nil
+}
+
+// ImportOptions configures ImportExecution.
+type ImportOptions struct {
+	// ExecutionID imports the snapshot under another execution ID, e.g. to reproduce an execution
+	// next to the original. Defaults to the snapshot's.
+	ExecutionID string
+	// Trigger starts the execution with the snapshot's input once its entries are imported. The
+	// handler then resumes from the imported state, and re-raises an interrupt it was paused on.
+	Trigger bool
+}
+
+// ImportExecution writes a snapshot's entries into the store backing the workflow's ctx.Memo, and
+// optionally triggers the execution. Entries that already exist with the same value are skipped;
+// it fails, after importing the rest, if any exist with a different value.
+func (w *Workflows) ImportExecution(snapshot *ExecutionSnapshot, options ...ImportOptions) error {
+	if snapshot.FormatVersion > snapshotFormatVersion {
+		return fmt.Errorf("snapshot format %d is newer than this SDK supports (%d)", snapshot.FormatVersion, snapshotFormatVersion)
+	}
+
+	executionId := snapshot.ExecutionID
+	trigger := false
+	for _, option := range options {
+		if option.ExecutionID != "" {
+			executionId = option.ExecutionID
+		}
+		trigger = trigger || option.Trigger
+	}
+
+	if _, err := w.inferable.getClusterId(); err != nil {
+		return fmt.Errorf("failed to get cluster id: %w", err)
+	}
+
+	store := w.storeFor(snapshot.WorkflowName)
+
+	names := make([]string, 0, len(snapshot.Entries))
+	for name := range snapshot.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	conflicts := []string{}
+	for _, name := range names {
+		key := executionId + "_" + name
+		value := snapshot.Entries[name]
+		if err := store.SetIfAbsent(key, value); err != nil {
+			return fmt.Errorf("failed to import %s: %v", key, err)
+		}
+		stored, _, err := store.Get(key)
+		if err != nil {
+			return fmt.Errorf("failed to import %s: %v", key, err)
+		}
+		if stored != value {
+			conflicts = append(conflicts, key)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d entries already exist with different values: %s", len(conflicts), strings.Join(conflicts, ", "))
+	}
+
+	if !trigger {
+		return nil
+	}
+
+	input := map[string]interface{}{}
+	for key, value := range snapshot.Input {
+		input[key] = value
+	}
+	_, err := w.Trigger(snapshot.WorkflowName, executionId, input)
+	return err
+}
+
+// storeFor returns the store backing ctx.Memo for the named workflow created with this client, or
+// the client's store.
+func (w *Workflows) storeFor(workflowName string) KVStore {
+	for _, workflow := range w.created {
+		if workflow.name == workflowName {
+			return workflow.kvStore(nil)
+		}
+	}
+	return w.inferable.store()
+}
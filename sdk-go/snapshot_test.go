@@ -0,0 +1,118 @@
+package inferable
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSnapshotCluster serves an interrupted execution's timeline and records triggered executions.
+func newSnapshotCluster(t *testing.T, options InferableOptions) (*Inferable, *[]map[string]interface{}) {
+	t.Helper()
+
+	triggered := []map[string]interface{}{}
+	i := newTestClient(t, options, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/refunds/executions/exec-1/timeline": func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"execution": map[string]interface{}{
+					"workflowVersion": 2,
+					"job": map[string]interface{}{
+						"status":     "interrupted",
+						"targetArgs": `{"value":{"executionId":"exec-1","amount":30}}`,
+						"result":     `{"type":"approval","message":"Refund over limit"}`,
+						"resultType": "interrupt",
+						"approved":   false,
+					},
+				},
+				"memos":      []map[string]interface{}{{"key": "exec-1_memo_lookup", "value": `{"value":"from cluster"}`}},
+				"structured": []map[string]interface{}{},
+			})
+		},
+		"/clusters/test-cluster/workflows/refunds/executions": func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			body := map[string]interface{}{}
+			json.Unmarshal(data, &body)
+			triggered = append(triggered, body)
+			w.WriteHeader(http.StatusCreated)
+		},
+	})
+	return i, &triggered
+}
+
+func TestExportAndImportExecution(t *testing.T) {
+	source := NewMemoryStore()
+	require.NoError(t, source.SeedMemo("exec-1", "lookup", map[string]interface{}{"order": "A-1"}))
+	_, err := newState(source, "exec-1").Set("attempts", 2)
+	require.NoError(t, err)
+	require.NoError(t, source.SeedMemo("exec-10", "lookup", "another execution"))
+
+	i, _ := newSnapshotCluster(t, InferableOptions{KVStore: source})
+	snapshot, err := i.Workflows.ExportExecution("refunds", "exec-1")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, snapshot.WorkflowVersion)
+	assert.Equal(t, ExecutionInterrupted, snapshot.Status)
+	assert.Equal(t, map[string]interface{}{"executionId": "exec-1", "amount": float64(30)}, snapshot.Input)
+	assert.Equal(t, &Interrupt{Type: APPROVAL, Message: "Refund over limit"}, snapshot.Interrupt())
+	assert.Equal(t, map[string]interface{}{"lookup": map[string]interface{}{"order": "A-1"}}, snapshot.Memos())
+	assert.ElementsMatch(t, []string{"memo_lookup", "state_attempts_1"}, keysOf(snapshot.Entries))
+
+	// Snapshots survive a round trip through JSON
+	data, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+	var loaded ExecutionSnapshot
+	require.NoError(t, json.Unmarshal(data, &loaded))
+
+	target := NewMemoryStore()
+	other, triggered := newSnapshotCluster(t, InferableOptions{KVStore: target})
+	require.NoError(t, other.Workflows.ImportExecution(&loaded, ImportOptions{ExecutionID: "exec-1-copy", Trigger: true}))
+
+	assert.Equal(t, []string{"exec-1-copy_memo_lookup", "exec-1-copy_state_attempts_1"}, target.Keys())
+	attempts, _, err := GetState[int](newState(target, "exec-1-copy"), "attempts")
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	require.Len(t, *triggered, 1)
+	assert.Equal(t, "exec-1-copy", (*triggered)[0]["executionId"])
+	assert.Equal(t, float64(30), (*triggered)[0]["amount"])
+
+	// Importing again is a no-op, importing over different values is reported
+	require.NoError(t, other.Workflows.ImportExecution(&loaded, ImportOptions{ExecutionID: "exec-1-copy"}))
+	loaded.Entries["memo_lookup"] = `{"value":"changed"}`
+	assert.ErrorContains(t, other.Workflows.ImportExecution(&loaded, ImportOptions{ExecutionID: "exec-1-copy"}), "exec-1-copy_memo_lookup")
+
+	local := snapshot.MemoryStore()
+	value, ok := local.Memo("exec-1", "lookup")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"order": "A-1"}, value)
+}
+
+func TestExportFromStoreWithoutListing(t *testing.T) {
+	i, _ := newSnapshotCluster(t, InferableOptions{KVStore: unlistedStore{NewMemoryStore()}})
+
+	snapshot, err := i.Workflows.ExportExecution("refunds", "exec-1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"memo_lookup": `{"value":"from cluster"}`}, snapshot.Entries)
+
+	_, err = i.Workflows.ExportExecution("refunds", "missing")
+	assert.ErrorContains(t, err, "not found")
+}
+
+// unlistedStore hides MemoryStore's listing, like a custom KVStore.
+type unlistedStore struct{ store *MemoryStore }
+
+func (s unlistedStore) Get(key string) (string, bool, error) { return s.store.Get(key) }
+func (s unlistedStore) SetIfAbsent(key string, value string) error {
+	return s.store.SetIfAbsent(key, value)
+}
+
+func keysOf(entries map[string]string) []string {
+	keys := []string{}
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,192 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/inferablehq/inferable/sdk-go/kv"
+)
+
+// State is named, mutable state scoped to a workflow execution, exposed to handlers as ctx.State.
+// Unlike ctx.Memo, which returns the first result recorded for a call site, a state value can be
+// replaced any number of times and Get always returns the latest write, including writes made
+// before the execution was interrupted and resumed. Every write creates a new version.
+//
+// Treat state like a database row rather than a replayed result: a handler that is re-executed
+// from the top repeats its Set calls, so derive writes from the current value.
+//
+//	seen, _, err := inferable.GetState[[]string](ctx.State, "seen")
+//	if err != nil {
+//		return nil, err
+//	}
+//	_, err = inferable.SetState(ctx.State, "seen", append(seen, input.TicketID))
+type State struct {
+	store       KVStore
+	executionId string
+	// compressionThreshold is the serialized size above which values are stored compressed
+	compressionThreshold int
+
+	mu sync.Mutex
+	// versions caches the latest version seen per name, so that reads only probe newer versions
+	versions map[string]int
+}
+
+// ErrStateConflict is returned by State.Set when another writer stored the same version first.
+var ErrStateConflict = fmt.Errorf("state was modified concurrently")
+
+func newState(store KVStore, executionId string) *State {
+	return &State{store: store, executionId: executionId, versions: map[string]int{}}
+}
+
+// stateKey is the key under which a version of a state value is stored.
+func stateKey(executionId string, name string, version int) string {
+	return fmt.Sprintf("%s_state_%s_%d", executionId, name, version)
+}
+
+// Get decodes the latest value of name into target, e.g. a pointer to a struct, and returns its
+// version. It reports false, with version 0, when the value has never been set.
+func (s *State) Get(name string, target interface{}) (int, bool, error) {
+	version, serialized, err := s.latest(name)
+	if err != nil || version == 0 {
+		return 0, false, err
+	}
+
+	value, err := kv.Decode[json.RawMessage](serialized)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to unmarshal state %s: %v", name, err)
+	}
+	if err := json.Unmarshal(value, target); err != nil {
+		return 0, false, fmt.Errorf("failed to unmarshal state %s: %v", name, err)
+	}
+
+	return version, true, nil
+}
+
+// Version returns the latest version of name, or 0 if it has never been set.
+func (s *State) Version(name string) (int, error) {
+	version, _, err := s.latest(name)
+	return version, err
+}
+
+// Set stores value as the next version of name and returns that version. It returns
+// ErrStateConflict if a concurrent writer stored that version first.
+func (s *State) Set(name string, value interface{}) (int, error) {
+	current, _, err := s.latest(name)
+	if err != nil {
+		return 0, err
+	}
+	return s.setVersion(name, current+1, value)
+}
+
+// SetVersion stores value as the version after expected, for read-modify-write updates. It returns
+// ErrStateConflict if name is no longer at version expected.
+func (s *State) SetVersion(name string, expected int, value interface{}) (int, error) {
+	current, _, err := s.latest(name)
+	if err != nil {
+		return 0, err
+	}
+	if current != expected {
+		return 0, ErrStateConflict
+	}
+	return s.setVersion(name, expected+1, value)
+}
+
+func (s *State) setVersion(name string, version int, value interface{}) (int, error) {
+	serialized, err := kv.Encode(value)
+	if err == nil {
+		serialized, err = kv.Compress(serialized, s.compressionThreshold)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal state %s: %v", name, err)
+	}
+
+	key := stateKey(s.executionId, name, version)
+	if err := s.store.SetIfAbsent(key, serialized); err != nil {
+		return 0, fmt.Errorf("failed to set state %s: %v", name, err)
+	}
+
+	// Versions are never overwritten, so a different stored value means another writer won
+	stored, ok, err := s.store.Get(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set state %s: %v", name, err)
+	}
+	if !ok || stored != serialized {
+		return 0, ErrStateConflict
+	}
+
+	s.mu.Lock()
+	s.versions[name] = max(s.versions[name], version)
+	s.mu.Unlock()
+
+	return version, nil
+}
+
+// latest finds the newest stored version of name. Versions are contiguous from 1, so it gallops
+// past the last known version and then binary searches for the end.
+func (s *State) latest(name string) (int, string, error) {
+	s.mu.Lock()
+	known := s.versions[name]
+	s.mu.Unlock()
+
+	get := func(version int) (string, bool, error) {
+		value, ok, err := s.store.Get(stateKey(s.executionId, name, version))
+		if err != nil {
+			return "", false, fmt.Errorf("failed to get state %s: %v", name, err)
+		}
+		return value, ok, nil
+	}
+
+	// found is a version known to exist (0 if none), missing one known not to
+	found, missing := known, 0
+	for step := 1; missing == 0; step *= 2 {
+		_, ok, err := get(known + step)
+		if err != nil {
+			return 0, "", err
+		}
+		if ok {
+			found = known + step
+		} else {
+			missing = known + step
+		}
+	}
+	for missing-found > 1 {
+		mid := (found + missing) / 2
+		_, ok, err := get(mid)
+		if err != nil {
+			return 0, "", err
+		}
+		if ok {
+			found = mid
+		} else {
+			missing = mid
+		}
+	}
+
+	if found == 0 {
+		return 0, "", nil
+	}
+
+	value, _, err := get(found)
+	if err != nil {
+		return 0, "", err
+	}
+
+	s.mu.Lock()
+	s.versions[name] = max(s.versions[name], found)
+	s.mu.Unlock()
+
+	return found, value, nil
+}
+
+// GetState returns the latest value of name in state as a T, and false if it has never been set.
+func GetState[T any](state *State, name string) (T, bool, error) {
+	var value T
+	_, ok, err := state.Get(name, &value)
+	return value, ok, err
+}
+
+// SetState stores value as the next version of name in state and returns that version.
+func SetState[T any](state *State, name string, value T) (int, error) {
+	return state.Set(name, value)
+}
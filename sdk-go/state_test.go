@@ -0,0 +1,98 @@
+package inferable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ticketTally struct {
+	Seen  []string `json:"seen"`
+	Total int      `json:"total"`
+}
+
+func TestStateVersions(t *testing.T) {
+	store := NewMemoryStore()
+	state := newState(store, "exec-1")
+
+	_, ok, err := GetState[ticketTally](state, "tally")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	for n := 1; n <= 10; n++ {
+		tally, _, err := GetState[ticketTally](state, "tally")
+		require.NoError(t, err)
+		tally.Total++
+		version, err := SetState(state, "tally", tally)
+		require.NoError(t, err)
+		assert.Equal(t, n, version)
+	}
+
+	// A resumed execution reads the latest write
+	resumed := newState(store, "exec-1")
+	tally, ok, err := GetState[ticketTally](resumed, "tally")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 10, tally.Total)
+
+	version, err := resumed.Version("tally")
+	require.NoError(t, err)
+	assert.Equal(t, 10, version)
+
+	// State is scoped to the execution
+	_, ok, err = GetState[ticketTally](newState(store, "exec-2"), "tally")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestStateConflicts(t *testing.T) {
+	store := NewMemoryStore()
+	first := newState(store, "exec-1")
+	second := newState(store, "exec-1")
+
+	_, err := first.Set("status", "open")
+	require.NoError(t, err)
+
+	version, err := second.Version("status")
+	require.NoError(t, err)
+	_, err = first.Set("status", "pending")
+	require.NoError(t, err)
+
+	_, err = second.SetVersion("status", version, "closed")
+	assert.ErrorIs(t, err, ErrStateConflict)
+
+	// A writer that lost the race for a version fails instead of overwriting it
+	store.Set(stateKey("exec-1", "status", 3), `{"value":"escalated"}`)
+	first.versions["status"] = 1
+	_, err = first.setVersion("status", 3, "closed")
+	assert.ErrorIs(t, err, ErrStateConflict)
+
+	status, _, err := GetState[string](second, "status")
+	require.NoError(t, err)
+	assert.Equal(t, "escalated", status)
+}
+
+func TestStateInWorkflow(t *testing.T) {
+	store := NewMemoryStore()
+	i := newTestClient(t, InferableOptions{KVStore: store}, nil)
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "tickets", InputSchema: WorkflowInput{}})
+
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		runs, _, err := GetState[int](ctx.State, "runs")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := SetState(ctx.State, "runs", runs+1); err != nil {
+			return nil, err
+		}
+		return runs + 1, nil
+	})
+
+	for n := 1; n <= 3; n++ {
+		result, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+		require.NoError(t, err)
+		assert.Equal(t, n, result)
+	}
+	assert.Contains(t, store.Keys(), "exec-1_state_runs_3")
+}
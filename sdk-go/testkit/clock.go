@@ -0,0 +1,120 @@
+package testkit
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a controllable inferable.Clock. Time only moves when Advance is called, or on every
+// sleep when auto-advancing, so workflows using ctx.Sleep and deadlines run in milliseconds.
+type Clock struct {
+	mu          sync.Mutex
+	now         time.Time
+	autoAdvance bool
+	timers      []*clockTimer
+	changed     chan struct{}
+}
+
+type clockTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewClock creates a Clock stopped at start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start, changed: make(chan struct{})}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the time once the clock has been advanced by d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+
+	if c.autoAdvance {
+		c.now = c.now.Add(d)
+		c.fire()
+		ch <- c.now
+		return ch
+	}
+
+	c.timers = append(c.timers, &clockTimer{at: c.now.Add(d), ch: ch})
+	c.notify()
+	return ch
+}
+
+// Advance moves the clock forward by d, firing the timers that become due in order.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	c.fire()
+}
+
+// AutoAdvance makes every sleep complete immediately by moving the clock forward by its duration.
+func (c *Clock) AutoAdvance() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.autoAdvance = true
+	c.fire()
+}
+
+// Sleepers returns the number of timers waiting for the clock to advance.
+func (c *Clock) Sleepers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntilSleepers waits until at least n timers are waiting, e.g. before calling Advance on a
+// handler running in another goroutine.
+func (c *Clock) BlockUntilSleepers(n int) {
+	for {
+		c.mu.Lock()
+		if len(c.timers) >= n {
+			c.mu.Unlock()
+			return
+		}
+		changed := c.changed
+		c.mu.Unlock()
+		<-changed
+	}
+}
+
+// fire delivers due timers. It must be called with the lock held.
+func (c *Clock) fire() {
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if c.autoAdvance && timer.at.After(c.now) {
+			c.now = timer.at
+		}
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.ch <- timer.at
+	}
+	c.timers = pending
+	c.notify()
+}
+
+// notify wakes BlockUntilSleepers. It must be called with the lock held.
+func (c *Clock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
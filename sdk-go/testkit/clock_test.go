@@ -0,0 +1,92 @@
+package testkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inferablehq/inferable/sdk-go"
+)
+
+func newReminderWorkflow(h *Harness) *inferable.Workflow {
+	workflow := h.Client.Workflows.Create(inferable.WorkflowConfig{
+		Name:        "reminders",
+		InputSchema: inferable.WorkflowInput{},
+	})
+
+	workflow.Version(1).Define(func(ctx inferable.WorkflowContext, input inferable.WorkflowInput) (interface{}, error) {
+		deadline := ctx.Now().Add(90 * time.Minute)
+
+		if err := ctx.Sleep("first-reminder", time.Hour); err != nil {
+			return nil, err
+		}
+		if err := ctx.Sleep("second-reminder", time.Hour); err != nil {
+			return nil, err
+		}
+
+		return map[string]interface{}{"late": ctx.Now().After(deadline), "at": ctx.Now()}, nil
+	})
+
+	return workflow
+}
+
+func TestClockAdvance(t *testing.T) {
+	h := New(t)
+	workflow := newReminderWorkflow(h)
+
+	done := make(chan Result, 1)
+	go func() {
+		done <- h.Run(workflow, 1, inferable.WorkflowInput{ExecutionID: "exec-1"})
+	}()
+
+	h.Clock.BlockUntilSleepers(1)
+	h.Clock.Advance(30 * time.Minute)
+	assert.Equal(t, 1, h.Clock.Sleepers())
+
+	h.Clock.Advance(30 * time.Minute)
+	h.Clock.BlockUntilSleepers(1)
+	h.Clock.Advance(time.Hour)
+
+	select {
+	case result := <-done:
+		require.NoError(t, result.Err)
+		assert.Equal(t, true, result.Value.(map[string]interface{})["late"])
+		assert.Equal(t, StartTime.Add(2*time.Hour), result.Value.(map[string]interface{})["at"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("workflow did not finish")
+	}
+}
+
+func TestClockAutoAdvance(t *testing.T) {
+	h := New(t)
+	workflow := newReminderWorkflow(h)
+	h.Clock.AutoAdvance()
+
+	result := h.Run(workflow, 1, inferable.WorkflowInput{ExecutionID: "exec-1"})
+	require.NoError(t, result.Err)
+	assert.Equal(t, StartTime.Add(2*time.Hour), h.Clock.Now())
+}
+
+func TestSleepResumesWithRemainder(t *testing.T) {
+	h := New(t)
+	workflow := newReminderWorkflow(h)
+
+	// A previous attempt started the first sleep 45 minutes ago
+	h.SeedMemo("exec-1", "sleep_first-reminder", StartTime.Add(15*time.Minute).Format(time.RFC3339Nano))
+
+	done := make(chan Result, 1)
+	go func() {
+		done <- h.Run(workflow, 1, inferable.WorkflowInput{ExecutionID: "exec-1"})
+	}()
+
+	h.Clock.BlockUntilSleepers(1)
+	h.Clock.Advance(15 * time.Minute)
+	h.Clock.BlockUntilSleepers(1)
+	h.Clock.Advance(time.Hour)
+
+	result := <-done
+	require.NoError(t, result.Err)
+	assert.Equal(t, StartTime.Add(75*time.Minute), h.Clock.Now())
+}
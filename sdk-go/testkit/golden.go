@@ -0,0 +1,230 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// UpdateGoldenEnv is the environment variable that, when set to 1, makes AssertGolden rewrite
+// golden files with the actual values instead of comparing against them.
+const UpdateGoldenEnv = "INFERABLE_UPDATE_GOLDEN"
+
+// GoldenOptions configures how results are compared with golden files.
+// Paths use the same notation as schema validation errors, e.g. "$.items[0].total", where
+// "[*]" matches any index and ".*" any field name.
+type GoldenOptions struct {
+	// Dir holds the golden files. Defaults to "testdata".
+	Dir string
+	// Ignore lists volatile fields left out of the comparison, e.g. "$.id" or "$.items[*].createdAt".
+	Ignore []string
+	// Tolerance is the absolute difference allowed between numbers.
+	Tolerance float64
+	// FieldTolerance overrides Tolerance for specific fields.
+	FieldTolerance map[string]float64
+}
+
+// AssertGolden compares actual, typically a structured LLM or agent result, with the golden file
+// <Dir>/<name>.golden.json and fails the test on differences. Run the test with
+// INFERABLE_UPDATE_GOLDEN=1 to create or update the file.
+//
+//	testkit.AssertGolden(t, "invoice", result.Value, testkit.GoldenOptions{
+//		Ignore:    []string{"$.extractedAt"},
+//		Tolerance: 0.01,
+//	})
+func AssertGolden(t testing.TB, name string, actual interface{}, options GoldenOptions) {
+	t.Helper()
+
+	dir := options.Dir
+	if dir == "" {
+		dir = "testdata"
+	}
+	path := filepath.Join(dir, name+".golden.json")
+
+	normalized, err := normalizeJSON(actual)
+	if err != nil {
+		t.Fatalf("failed to marshal actual value: %v", err)
+	}
+
+	if os.Getenv(UpdateGoldenEnv) == "1" {
+		data, err := json.MarshalIndent(normalized, "", "  ")
+		if err != nil {
+			t.Fatalf("failed to marshal golden file: %v", err)
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden file %s does not exist, run with %s=1 to create it", path, UpdateGoldenEnv)
+	}
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+
+	var expected interface{}
+	if err := json.Unmarshal(data, &expected); err != nil {
+		t.Fatalf("failed to parse golden file %s: %v", path, err)
+	}
+
+	differences, err := CompareGolden(expected, normalized, options)
+	if err != nil {
+		t.Fatalf("failed to compare with golden file: %v", err)
+	}
+	if len(differences) > 0 {
+		t.Errorf("result does not match golden file %s (run with %s=1 to update):\n%s",
+			path, UpdateGoldenEnv, strings.Join(differences, "\n"))
+	}
+}
+
+// CompareGolden compares two JSON-compatible values under the tolerance rules of options and
+// returns a description of each difference, ordered by path.
+func CompareGolden(expected interface{}, actual interface{}, options GoldenOptions) ([]string, error) {
+	expected, err := normalizeJSON(expected)
+	if err != nil {
+		return nil, err
+	}
+	actual, err = normalizeJSON(actual)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &goldenComparer{fieldTolerance: map[*regexp.Regexp]float64{}, tolerance: options.Tolerance}
+	for _, pattern := range options.Ignore {
+		c.ignore = append(c.ignore, compileGoldenPath(pattern))
+	}
+	for pattern, tolerance := range options.FieldTolerance {
+		c.fieldTolerance[compileGoldenPath(pattern)] = tolerance
+	}
+
+	c.compare("$", expected, actual)
+	sort.Strings(c.differences)
+	return c.differences, nil
+}
+
+type goldenComparer struct {
+	ignore         []*regexp.Regexp
+	tolerance      float64
+	fieldTolerance map[*regexp.Regexp]float64
+	differences    []string
+}
+
+func (c *goldenComparer) compare(path string, expected interface{}, actual interface{}) {
+	for _, pattern := range c.ignore {
+		if pattern.MatchString(path) {
+			return
+		}
+	}
+
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			c.differ(path, expected, actual)
+			return
+		}
+		for key, value := range e {
+			if _, ok := a[key]; !ok {
+				if !c.ignored(path + "." + key) {
+					c.differences = append(c.differences, fmt.Sprintf("%s.%s: missing", path, key))
+				}
+				continue
+			}
+			c.compare(path+"."+key, value, a[key])
+		}
+		for key, value := range a {
+			if _, ok := e[key]; !ok && !c.ignored(path+"."+key) {
+				c.differences = append(c.differences, fmt.Sprintf("%s.%s: unexpected %s", path, key, formatGolden(value)))
+			}
+		}
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			c.differ(path, expected, actual)
+			return
+		}
+		if len(e) != len(a) {
+			c.differences = append(c.differences, fmt.Sprintf("%s: expected %d items, got %d", path, len(e), len(a)))
+			return
+		}
+		for i := range e {
+			c.compare(fmt.Sprintf("%s[%d]", path, i), e[i], a[i])
+		}
+	case float64:
+		a, ok := actual.(float64)
+		if !ok || math.Abs(e-a) > c.toleranceFor(path) {
+			c.differ(path, expected, actual)
+		}
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			c.differ(path, expected, actual)
+		}
+	}
+}
+
+func (c *goldenComparer) ignored(path string) bool {
+	for _, pattern := range c.ignore {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *goldenComparer) toleranceFor(path string) float64 {
+	for pattern, tolerance := range c.fieldTolerance {
+		if pattern.MatchString(path) {
+			return tolerance
+		}
+	}
+	return c.tolerance
+}
+
+func (c *goldenComparer) differ(path string, expected interface{}, actual interface{}) {
+	c.differences = append(c.differences, fmt.Sprintf("%s: expected %s, got %s", path, formatGolden(expected), formatGolden(actual)))
+}
+
+// compileGoldenPath converts a path pattern into a regular expression matching concrete paths.
+func compileGoldenPath(pattern string) *regexp.Regexp {
+	if !strings.HasPrefix(pattern, "$") {
+		pattern = "$." + pattern
+	}
+	expression := regexp.QuoteMeta(pattern)
+	expression = strings.ReplaceAll(expression, `\[\*\]`, `\[\d+\]`)
+	expression = strings.ReplaceAll(expression, `\.\*`, `\.[^.\[]+`)
+	return regexp.MustCompile("^" + expression + "$")
+}
+
+// normalizeJSON round trips v through JSON so that structs and maps compare alike.
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+func formatGolden(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
@@ -0,0 +1,85 @@
+package testkit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type extractedInvoice struct {
+	ID          string         `json:"id"`
+	Total       float64        `json:"total"`
+	LineItems   []extractedRow `json:"lineItems"`
+	ExtractedAt string         `json:"extractedAt"`
+}
+
+type extractedRow struct {
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+	Confidence  float64 `json:"confidence"`
+}
+
+func TestCompareGolden(t *testing.T) {
+	expected := extractedInvoice{
+		ID:          "run-1",
+		Total:       42.5,
+		LineItems:   []extractedRow{{Description: "Widget", Amount: 40, Confidence: 0.91}},
+		ExtractedAt: "2024-01-01T00:00:00Z",
+	}
+	actual := extractedInvoice{
+		ID:          "run-2",
+		Total:       42.504,
+		LineItems:   []extractedRow{{Description: "Widget", Amount: 40, Confidence: 0.84}},
+		ExtractedAt: "2024-06-01T00:00:00Z",
+	}
+
+	options := GoldenOptions{
+		Ignore:         []string{"$.id", "extractedAt"},
+		Tolerance:      0.01,
+		FieldTolerance: map[string]float64{"$.lineItems[*].confidence": 0.1},
+	}
+
+	differences, err := CompareGolden(expected, actual, options)
+	require.NoError(t, err)
+	assert.Empty(t, differences)
+
+	actual.Total = 45
+	actual.LineItems[0].Description = "Gadget"
+	actual.LineItems = append(actual.LineItems, extractedRow{Description: "Extra"})
+	differences, err = CompareGolden(expected, actual, options)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"$.lineItems: expected 1 items, got 2",
+		"$.total: expected 42.5, got 45",
+	}, differences)
+
+	differences, err = CompareGolden(
+		map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": true}},
+		map[string]interface{}{"b": map[string]interface{}{"c": "yes"}, "d": nil},
+		GoldenOptions{},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"$.a: missing",
+		"$.b.c: expected true, got \"yes\"",
+		"$.d: unexpected null",
+	}, differences)
+}
+
+func TestAssertGolden(t *testing.T) {
+	dir := t.TempDir()
+	options := GoldenOptions{Dir: dir, Ignore: []string{"$.id"}}
+
+	t.Setenv(UpdateGoldenEnv, "1")
+	AssertGolden(t, "invoice", extractedInvoice{ID: "run-1", Total: 10}, options)
+
+	data, err := os.ReadFile(filepath.Join(dir, "invoice.golden.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"total": 10`)
+
+	t.Setenv(UpdateGoldenEnv, "")
+	AssertGolden(t, "invoice", extractedInvoice{ID: "run-2", Total: 10}, options)
+}
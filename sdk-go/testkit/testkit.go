@@ -0,0 +1,353 @@
+// Package testkit runs workflow handlers in-process against a fake cluster, so that business logic
+// can be unit tested without a live cluster or polling for job results.
+//
+//	h := testkit.New(t)
+//	workflow := h.Client.Workflows.Create(inferable.WorkflowConfig{...})
+//	workflow.Version(1).Define(handler)
+//
+//	h.OnStructured(func(call testkit.StructuredCall) (interface{}, error) {
+//		return map[string]interface{}{"category": "billing"}, nil
+//	})
+//	h.OnAgent("researcher", func(call testkit.AgentCall) testkit.AgentResult {
+//		return testkit.Done(map[string]interface{}{"summary": "..."})
+//	})
+//
+//	result := h.Run(workflow, 1, map[string]interface{}{"executionId": "test-1"})
+//	require.NoError(t, result.Err)
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/inferablehq/inferable/sdk-go"
+)
+
+// ClusterID is the cluster the fake reports for the client.
+const ClusterID = "test-cluster"
+
+// StartTime is the initial time of a harness Clock.
+var StartTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// StructuredCall records a ctx.LLM structured generation request.
+type StructuredCall struct {
+	ExecutionID  string
+	Input        string
+	Instructions string
+	Schema       map[string]interface{}
+	Model        string
+}
+
+// AgentCall records a ctx.Agents.React request.
+type AgentCall struct {
+	// Name is the agent name passed to React, without the workflow prefix.
+	Name         string
+	RunID        string
+	ExecutionID  string
+	SystemPrompt string
+	Input        string
+	Tools        []string
+	ResultSchema map[string]interface{}
+}
+
+// AgentResult is the state of an agent run returned to the handler.
+type AgentResult struct {
+	// Status is "done", "failed", or any other run status, which interrupts the workflow.
+	Status string
+	Result interface{}
+}
+
+// Done returns a completed agent run with the given result.
+func Done(result interface{}) AgentResult {
+	return AgentResult{Status: "done", Result: result}
+}
+
+// Pending returns an agent run that is still running, which interrupts the workflow.
+func Pending() AgentResult {
+	return AgentResult{Status: "running"}
+}
+
+// Failed returns a failed agent run.
+func Failed() AgentResult {
+	return AgentResult{Status: "failed"}
+}
+
+// LogEntry records a ctx.Log call.
+type LogEntry struct {
+	ExecutionID string
+	Status      string
+	Data        map[string]interface{}
+}
+
+// Result is the outcome of running a workflow handler.
+type Result struct {
+	// Value is the value returned by the handler, nil if it returned an interrupt.
+	Value interface{}
+	// Err is the error returned by the handler.
+	Err error
+	// Interrupt is set when the handler paused the workflow, e.g. for approval or a pending agent.
+	Interrupt *inferable.Interrupt
+}
+
+// Decode unmarshals the handler's result into v.
+func (r Result) Decode(v interface{}) error {
+	data, err := json.Marshal(r.Value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %v", err)
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Harness is a fake cluster and a client connected to it.
+// Calls made by handlers through ctx.LLM, ctx.Agents, and ctx.Log are answered by the fakes
+// registered on the harness and recorded for assertions. ctx.Memo is backed by Store.
+type Harness struct {
+	// Client is connected to the fake cluster. Create the workflows under test with it.
+	Client *inferable.Inferable
+	// Store holds the results memoized by handlers.
+	Store *inferable.MemoryStore
+	// Clock is the time seen by ctx.Now and ctx.Sleep. It starts at StartTime and only moves
+	// when advanced.
+	Clock *Clock
+
+	t      testing.TB
+	server *httptest.Server
+
+	mu              sync.Mutex
+	structured      func(call StructuredCall) (interface{}, error)
+	agents          map[string]func(call AgentCall) AgentResult
+	logs            []LogEntry
+	structuredCalls []StructuredCall
+	agentCalls      []AgentCall
+}
+
+// New starts a fake cluster that is closed when the test finishes.
+func New(t testing.TB) *Harness {
+	t.Helper()
+
+	h := &Harness{
+		t:      t,
+		Store:  inferable.NewMemoryStore(),
+		Clock:  NewClock(StartTime),
+		agents: map[string]func(call AgentCall) AgentResult{},
+	}
+
+	h.server = httptest.NewServer(http.HandlerFunc(h.serve))
+	t.Cleanup(h.server.Close)
+
+	client, err := inferable.New(inferable.InferableOptions{
+		APIEndpoint: h.server.URL,
+		APISecret:   "test-secret",
+		KVStore:     h.Store,
+		Clock:       h.Clock,
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	h.Client = client
+
+	return h
+}
+
+// OnStructured sets the fake for ctx.LLM structured generation. The returned value is the
+// generated data. Without a fake, structured calls fail.
+func (h *Harness) OnStructured(fn func(call StructuredCall) (interface{}, error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.structured = fn
+}
+
+// OnAgent sets the fake for React agents with the given name. Without a fake, the agent fails.
+func (h *Harness) OnAgent(name string, fn func(call AgentCall) AgentResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.agents[name] = fn
+}
+
+// SeedMemo stores a memoized value, as if a previous attempt of the execution had computed it.
+func (h *Harness) SeedMemo(executionId string, name string, value interface{}) {
+	if err := h.Store.SeedMemo(executionId, name, value); err != nil {
+		h.t.Fatalf("failed to seed memo: %v", err)
+	}
+}
+
+// Memo returns the value memoized by an execution under name.
+func (h *Harness) Memo(executionId string, name string) (interface{}, bool) {
+	return h.Store.Memo(executionId, name)
+}
+
+// Logs returns the ctx.Log calls made so far.
+func (h *Harness) Logs() []LogEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]LogEntry{}, h.logs...)
+}
+
+// StructuredCalls returns the structured generation requests made so far.
+func (h *Harness) StructuredCalls() []StructuredCall {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]StructuredCall{}, h.structuredCalls...)
+}
+
+// AgentCalls returns the agent runs created so far.
+func (h *Harness) AgentCalls() []AgentCall {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]AgentCall{}, h.agentCalls...)
+}
+
+// Run invokes a version of the workflow's handler directly with the given input.
+// The input may be a value of the handler's input type or a map with the same JSON fields.
+func (h *Harness) Run(workflow *inferable.Workflow, version int, input interface{}) Result {
+	return h.RunWithContext(workflow, version, input, inferable.ContextInput{})
+}
+
+// RunWithContext is like Run, with the context the cluster would pass to the handler, e.g. to
+// resume an execution after approval.
+func (h *Harness) RunWithContext(workflow *inferable.Workflow, version int, input interface{}, contextInput inferable.ContextInput) Result {
+	value, err := workflow.Execute(version, input, contextInput)
+
+	switch v := value.(type) {
+	case *inferable.Interrupt:
+		if v != nil {
+			return Result{Err: err, Interrupt: v}
+		}
+		value = nil
+	case inferable.Interrupt:
+		return Result{Err: err, Interrupt: &v}
+	}
+
+	if interrupt, ok := err.(*inferable.Interrupt); ok {
+		return Result{Value: value, Interrupt: interrupt}
+	}
+
+	return Result{Value: value, Err: err}
+}
+
+// serve answers the subset of the cluster API used by workflow handlers.
+func (h *Harness) serve(w http.ResponseWriter, r *http.Request) {
+	raw, _ := io.ReadAll(r.Body)
+
+	if r.Method == http.MethodPost && r.URL.Path == "/machines" {
+		respond(w, http.StatusOK, map[string]interface{}{"clusterId": ClusterID})
+		return
+	}
+
+	prefix := "/clusters/" + ClusterID + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		respond(w, http.StatusNotFound, map[string]interface{}{"message": "not found"})
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")
+
+	switch {
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[0] == "l1m" && parts[1] == "structured":
+		h.serveStructured(w, r, raw)
+	case r.Method == http.MethodPost && len(parts) == 1 && parts[0] == "runs":
+		h.serveRun(w, raw)
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "workflow-executions" && parts[2] == "logs":
+		var body struct {
+			Status string                 `json:"status"`
+			Data   map[string]interface{} `json:"data"`
+		}
+		_ = json.Unmarshal(raw, &body)
+		h.mu.Lock()
+		h.logs = append(h.logs, LogEntry{ExecutionID: parts[1], Status: body.Status, Data: body.Data})
+		h.mu.Unlock()
+		respond(w, http.StatusCreated, map[string]interface{}{})
+	default:
+		respond(w, http.StatusNotFound, map[string]interface{}{"message": "not found"})
+	}
+}
+
+func (h *Harness) serveStructured(w http.ResponseWriter, r *http.Request, raw []byte) {
+	var body struct {
+		Input        string                 `json:"input"`
+		Instructions string                 `json:"instructions"`
+		Schema       map[string]interface{} `json:"schema"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		respond(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+		return
+	}
+
+	call := StructuredCall{
+		ExecutionID:  r.Header.Get("X-Workflow-Execution-Id"),
+		Input:        body.Input,
+		Instructions: body.Instructions,
+		Schema:       body.Schema,
+		Model:        r.Header.Get("X-Provider-Model"),
+	}
+
+	h.mu.Lock()
+	h.structuredCalls = append(h.structuredCalls, call)
+	fn := h.structured
+	h.mu.Unlock()
+
+	if fn == nil {
+		respond(w, http.StatusInternalServerError, map[string]interface{}{"message": "testkit: no structured fake registered"})
+		return
+	}
+
+	data, err := fn(call)
+	if err != nil {
+		respond(w, http.StatusInternalServerError, map[string]interface{}{"message": err.Error()})
+		return
+	}
+	respond(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+func (h *Harness) serveRun(w http.ResponseWriter, raw []byte) {
+	var body struct {
+		ID            string                 `json:"id"`
+		Name          string                 `json:"name"`
+		SystemPrompt  string                 `json:"systemPrompt"`
+		InitialPrompt string                 `json:"initialPrompt"`
+		Tools         []string               `json:"tools"`
+		ResultSchema  map[string]interface{} `json:"resultSchema"`
+		Tags          map[string]string      `json:"tags"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		respond(w, http.StatusBadRequest, map[string]interface{}{"message": err.Error()})
+		return
+	}
+
+	call := AgentCall{
+		Name:         strings.TrimPrefix(body.Name, body.Tags["workflow.name"]+"_"),
+		RunID:        body.ID,
+		ExecutionID:  body.Tags["workflow.executionId"],
+		SystemPrompt: body.SystemPrompt,
+		Input:        body.InitialPrompt,
+		Tools:        body.Tools,
+		ResultSchema: body.ResultSchema,
+	}
+
+	h.mu.Lock()
+	h.agentCalls = append(h.agentCalls, call)
+	fn := h.agents[call.Name]
+	h.mu.Unlock()
+
+	result := Failed()
+	if fn != nil {
+		result = fn(call)
+	}
+	respond(w, http.StatusCreated, map[string]interface{}{
+		"id":     body.ID,
+		"status": result.Status,
+		"result": result.Result,
+	})
+}
+
+func respond(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,173 @@
+package testkit
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/inferablehq/inferable/sdk-go"
+)
+
+type ticketInput struct {
+	ExecutionId string `json:"executionId"`
+	Ticket      string `json:"ticket"`
+}
+
+type ticketResult struct {
+	Category string `json:"category"`
+	Reply    string `json:"reply"`
+}
+
+func newTicketWorkflow(h *Harness) *inferable.Workflow {
+	workflow := h.Client.Workflows.Create(inferable.WorkflowConfig{
+		Name:        "tickets",
+		InputSchema: ticketInput{},
+	})
+
+	workflow.Version(1).Define(func(ctx inferable.WorkflowContext, input ticketInput) (interface{}, error) {
+		classification, err := ctx.Memo("classification", func() (interface{}, error) {
+			return ctx.LLM.Structured(inferable.StructuredInput{
+				Input: input.Ticket,
+				Schema: struct {
+					Category string `json:"category"`
+				}{},
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
+		category := classification.(map[string]interface{})["category"].(string)
+
+		if err := ctx.Log("classified", map[string]interface{}{"category": category}); err != nil {
+			return nil, err
+		}
+
+		if category == "refund" && !ctx.Approved {
+			return inferable.ApprovalInterrupt("Refunds need approval"), nil
+		}
+
+		reply, interrupt, err := ctx.Agents.React(inferable.ReactAgentConfig{
+			Name:         "replier",
+			Instructions: "Reply to the ticket",
+			Input:        input.Ticket,
+			Schema: struct {
+				Reply string `json:"reply"`
+			}{},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if interrupt != nil {
+			return interrupt, nil
+		}
+
+		return ticketResult{
+			Category: category,
+			Reply:    reply.(map[string]interface{})["reply"].(string),
+		}, nil
+	})
+
+	return workflow
+}
+
+func TestRun(t *testing.T) {
+	h := New(t)
+	workflow := newTicketWorkflow(h)
+
+	h.OnStructured(func(call StructuredCall) (interface{}, error) {
+		return map[string]interface{}{"category": "question"}, nil
+	})
+	h.OnAgent("replier", func(call AgentCall) AgentResult {
+		return Done(map[string]interface{}{"reply": "Thanks for asking"})
+	})
+
+	result := h.Run(workflow, 1, ticketInput{ExecutionId: "exec-1", Ticket: "How do I export?"})
+	require.NoError(t, result.Err)
+	require.Nil(t, result.Interrupt)
+
+	var output ticketResult
+	require.NoError(t, result.Decode(&output))
+	assert.Equal(t, ticketResult{Category: "question", Reply: "Thanks for asking"}, output)
+
+	structured := h.StructuredCalls()
+	require.Len(t, structured, 1)
+	assert.Equal(t, "How do I export?", structured[0].Input)
+	assert.Equal(t, "exec-1", structured[0].ExecutionID)
+
+	agents := h.AgentCalls()
+	require.Len(t, agents, 1)
+	assert.Equal(t, "replier", agents[0].Name)
+	assert.Equal(t, "Reply to the ticket", agents[0].SystemPrompt)
+
+	assert.Equal(t, []LogEntry{{
+		ExecutionID: "exec-1",
+		Status:      "classified",
+		Data:        map[string]interface{}{"category": "question"},
+	}}, h.Logs())
+
+	memo, ok := h.Memo("exec-1", "classification")
+	require.True(t, ok)
+	assert.Equal(t, map[string]interface{}{"category": "question"}, memo)
+}
+
+func TestRunInterrupts(t *testing.T) {
+	h := New(t)
+	workflow := newTicketWorkflow(h)
+
+	h.OnStructured(func(call StructuredCall) (interface{}, error) {
+		return map[string]interface{}{"category": "refund"}, nil
+	})
+	h.OnAgent("replier", func(call AgentCall) AgentResult {
+		return Pending()
+	})
+
+	input := map[string]interface{}{"executionId": "exec-2", "ticket": "I want my money back"}
+
+	result := h.Run(workflow, 1, input)
+	require.NoError(t, result.Err)
+	require.NotNil(t, result.Interrupt)
+	assert.Equal(t, inferable.APPROVAL, result.Interrupt.Type)
+
+	// Once approved the workflow waits on the agent
+	result = h.RunWithContext(workflow, 1, input, inferable.ContextInput{Approved: true})
+	require.NoError(t, result.Err)
+	require.NotNil(t, result.Interrupt)
+	assert.Equal(t, inferable.GENERAL, result.Interrupt.Type)
+
+	// The classification was memoized by the first run
+	assert.Len(t, h.StructuredCalls(), 1)
+}
+
+func TestRunSeededMemo(t *testing.T) {
+	h := New(t)
+	workflow := newTicketWorkflow(h)
+
+	h.SeedMemo("exec-3", "classification", map[string]interface{}{"category": "question"})
+	h.OnAgent("replier", func(call AgentCall) AgentResult {
+		return Failed()
+	})
+
+	result := h.Run(workflow, 1, ticketInput{ExecutionId: "exec-3", Ticket: "Hello"})
+	assert.ErrorContains(t, result.Err, "agent replier failed")
+	assert.Empty(t, h.StructuredCalls())
+}
+
+func TestRunErrors(t *testing.T) {
+	h := New(t)
+	workflow := newTicketWorkflow(h)
+
+	h.OnStructured(func(call StructuredCall) (interface{}, error) {
+		return nil, fmt.Errorf("provider unavailable")
+	})
+
+	result := h.Run(workflow, 1, ticketInput{ExecutionId: "exec-4", Ticket: "Hello"})
+	assert.ErrorContains(t, result.Err, "provider unavailable")
+
+	result = h.Run(workflow, 2, ticketInput{ExecutionId: "exec-4"})
+	assert.ErrorContains(t, result.Err, "no handler for version 2")
+
+	result = h.Run(workflow, 1, map[string]interface{}{"ticket": 42})
+	assert.Error(t, result.Err)
+}
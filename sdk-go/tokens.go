@@ -0,0 +1,134 @@
+package inferable
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrContextWindowExceeded is returned (wrapped in a *ContextWindowError) when a prompt counted by
+// the configured Tokenizer is too large for the model's context window. Check for it with
+// errors.Is.
+var ErrContextWindowExceeded = errors.New("prompt exceeds model context window")
+
+// ContextWindowError describes a prompt that does not fit in the model's context window.
+type ContextWindowError struct {
+	// Model is the model the prompt was checked against.
+	Model string
+	// Tokens is the number of tokens in the prompt.
+	Tokens int
+	// Limit is the model's context window size in tokens.
+	Limit int
+}
+
+// Error implements the error interface.
+func (e *ContextWindowError) Error() string {
+	return fmt.Sprintf("prompt of %d tokens exceeds the %d token context window of model '%s'", e.Tokens, e.Limit, e.Model)
+}
+
+// Unwrap allows errors.Is(err, ErrContextWindowExceeded).
+func (e *ContextWindowError) Unwrap() error {
+	return ErrContextWindowExceeded
+}
+
+// Tokenizer counts the tokens a model would use for a piece of text.
+// Plug in a model-specific tokenizer via InferableOptions.Tokenizer for exact counts, which
+// prompts must fit the context window by.
+type Tokenizer interface {
+	CountTokens(model string, text string) (int, error)
+}
+
+// ModelContextWindows maps model name prefixes to their context window size in tokens.
+// Models without an entry are not checked before calls are made.
+var ModelContextWindows = map[string]int{
+	"claude-3-5-sonnet": 200000,
+	"claude-3-5-haiku":  200000,
+	"claude-3-7-sonnet": 200000,
+	"claude-3-opus":     200000,
+	"claude-3-haiku":    200000,
+}
+
+// ContextWindow returns the context window size of a model, matching the longest known name prefix.
+func ContextWindow(model string) (int, bool) {
+	limit, matched := 0, ""
+	for prefix, size := range ModelContextWindows {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(matched) {
+			limit, matched = size, prefix
+		}
+	}
+	return limit, matched != ""
+}
+
+// CountTokens estimates the number of tokens in text for the given model. It is a heuristic, not a
+// tokenizer: it does not know the model's vocabulary and counts roughly four characters per token
+// for Latin scripts, one token per character for scripts such as CJK that tokenize densely, and at
+// least one token per word. Real counts can differ either way, e.g. for code, numbers or rare
+// words, so use it for budgets and sizing rather than hard limits.
+func CountTokens(model string, text string) int {
+	latin, dense, words := 0, 0, 0
+	inWord := false
+	for _, r := range text {
+		if r <= unicode.MaxASCII || unicode.Is(unicode.Latin, r) {
+			latin++
+		} else if !unicode.IsSpace(r) {
+			dense++
+		}
+
+		if unicode.IsSpace(r) {
+			inWord = false
+		} else if !inWord {
+			inWord = true
+			words++
+		}
+	}
+
+	estimate := (latin+3)/4 + dense
+	if words > estimate {
+		estimate = words
+	}
+	return estimate
+}
+
+// estimateTokens counts the tokens of text with tokenizer, falling back to the CountTokens estimate.
+func estimateTokens(tokenizer Tokenizer, model string, text string) int {
+	if tokenizer != nil {
+		if tokens, err := tokenizer.CountTokens(model, text); err == nil {
+			return tokens
+		}
+	}
+	return CountTokens(model, text)
+}
+
+// checkContextWindow returns a *ContextWindowError if the combined prompt parts do not fit the
+// model by the count of tokenizer. Without a tokenizer, the prompt is only estimated by
+// CountTokens, which is too rough to reject calls on: a prompt estimated not to fit is logged to
+// logger as a warning and sent.
+func checkContextWindow(tokenizer Tokenizer, logger Logger, model string, parts ...string) error {
+	limit, ok := ContextWindow(model)
+	if !ok {
+		return nil
+	}
+
+	prompt := strings.Join(parts, "\n")
+	if tokenizer == nil {
+		if tokens := CountTokens(model, prompt); tokens > limit && logger != nil {
+			logger.Warn("Prompt may exceed the model context window", map[string]interface{}{
+				"model":           model,
+				"estimatedTokens": tokens,
+				"limit":           limit,
+			})
+		}
+		return nil
+	}
+
+	tokens, err := tokenizer.CountTokens(model, prompt)
+	if err != nil {
+		return fmt.Errorf("failed to count tokens: %v", err)
+	}
+
+	if tokens > limit {
+		return &ContextWindowError{Model: model, Tokens: tokens, Limit: limit}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package inferable
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountTokens(t *testing.T) {
+	assert.Equal(t, 0, CountTokens(DefaultModel, ""))
+	assert.Equal(t, 4, CountTokens(DefaultModel, "Hello, world!"))
+	// Every word costs at least one token
+	assert.Equal(t, 5, CountTokens(DefaultModel, "a b c d e"))
+	// Dense scripts cost a token per character
+	assert.Equal(t, 4, CountTokens(DefaultModel, "你好世界"))
+}
+
+func TestContextWindow(t *testing.T) {
+	limit, ok := ContextWindow("claude-3-5-sonnet-20241022")
+	assert.True(t, ok)
+	assert.Equal(t, 200000, limit)
+
+	_, ok = ContextWindow("unknown-model")
+	assert.False(t, ok)
+}
+
+// wordTokenizer counts a token per word.
+type wordTokenizer struct{}
+
+func (wordTokenizer) CountTokens(model string, text string) (int, error) {
+	return len(strings.Fields(text)), nil
+}
+
+func TestStructuredContextWindowCheck(t *testing.T) {
+	calls := 0
+	llm := newTestLLM(t, func(body map[string]interface{}, r *http.Request) (int, interface{}) {
+		calls++
+		return 200, map[string]interface{}{"data": map[string]interface{}{"merchant": "ACME"}}
+	})
+	logger := &recordingLogger{}
+	llm.logger = logger
+	input := StructuredInput{
+		Input:  strings.Repeat("word ", 250000),
+		Schema: testReceipt{},
+	}
+
+	// A prompt only estimated not to fit is sent, with a warning
+	_, err := llm.Structured(input)
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []string{"Prompt may exceed the model context window"}, logger.warns)
+
+	// A prompt counted not to fit by the configured tokenizer is rejected
+	llm.tokenizer = wordTokenizer{}
+	_, err = llm.Structured(input)
+
+	var windowErr *ContextWindowError
+	require.ErrorAs(t, err, &windowErr)
+	assert.ErrorIs(t, err, ErrContextWindowExceeded)
+	assert.Equal(t, DefaultModel, windowErr.Model)
+	assert.Equal(t, 200000, windowErr.Limit)
+	assert.Equal(t, 1, calls)
+}
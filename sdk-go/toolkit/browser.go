@@ -0,0 +1,271 @@
+package toolkit
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+const (
+	// DefaultBrowserTimeout bounds the duration of a browser tool call.
+	DefaultBrowserTimeout = 30 * time.Second
+	// DefaultBrowserRenderBudget is the time pages are given to run JavaScript before they are captured.
+	DefaultBrowserRenderBudget = 5 * time.Second
+)
+
+// browserExecutables are looked up on the PATH when BrowserOptions.ExecPath is not set.
+var browserExecutables = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "headless-shell"}
+
+// runBrowser runs the browser binary. It is replaced in tests.
+var runBrowser = func(ctx context.Context, path string, args []string) ([]byte, error) {
+	return exec.CommandContext(ctx, path, args...).Output()
+}
+
+// BrowserOptions configures the browser tools.
+type BrowserOptions struct {
+	// AllowedHosts lists the hosts pages may be loaded from, e.g. "example.com" or "*.example.com".
+	// Requests to any other host, including redirects, subresources and fetch calls, are refused by
+	// the proxy the browser connects through. Use "*" to allow any public host.
+	AllowedHosts []string
+	// ExecPath is the Chrome or Chromium executable. Defaults to the first of chromium, chromium-browser,
+	// google-chrome, google-chrome-stable, or headless-shell found on the PATH.
+	ExecPath string
+	// Timeout bounds the duration of a call, including browser startup. Defaults to DefaultBrowserTimeout.
+	Timeout time.Duration
+	// RenderBudget is the time a page is given to run JavaScript before it is captured.
+	// Defaults to DefaultBrowserRenderBudget.
+	RenderBudget time.Duration
+	// MaxBodyBytes bounds the extracted content. Defaults to DefaultFetchMaxBodyBytes.
+	MaxBodyBytes int
+	// WindowWidth and WindowHeight set the viewport size in pixels. Default to 1280x800.
+	WindowWidth  int
+	WindowHeight int
+	// AllowPrivateNetworks permits connections to hosts that resolve to loopback, private, or
+	// link-local addresses. By default they are refused for every request of the page.
+	AllowPrivateNetworks bool
+}
+
+// BrowserInput is the input of the browser tools.
+type BrowserInput struct {
+	URL string `json:"url" jsonschema:"description=http(s) URL of the page to load"`
+	Raw bool   `json:"raw,omitempty" jsonschema:"description=Return the rendered HTML instead of markdown (extract only)"`
+}
+
+// BrowserExtractOutput is the output of the browserExtract tool.
+type BrowserExtractOutput struct {
+	URL       string `json:"url"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// BrowserScreenshotOutput is the output of the browserScreenshot tool.
+type BrowserScreenshotOutput struct {
+	URL string `json:"url"`
+	// Image is the base64-encoded PNG screenshot, which can be passed to the vision tool.
+	Image string `json:"image"`
+}
+
+// Browser returns browserExtract and browserScreenshot tools, which load pages in headless Chrome so
+// JavaScript-rendered content is available, unlike with httpFetch. Each call loads the page in a fresh,
+// isolated browser profile, with its traffic sent through a local proxy that checks every connection.
+//
+// Chrome is run with its command line capture flags rather than driven over the DevTools protocol,
+// e.g. with chromedp, to keep the toolkit free of a protocol client and its dependencies: loading a
+// page and capturing its DOM or a screenshot is all the tools do.
+func Browser(options BrowserOptions) ([]inferable.WorkflowTool, error) {
+	if len(options.AllowedHosts) == 0 {
+		return nil, fmt.Errorf("browser tools require at least one allowed host")
+	}
+
+	if options.ExecPath == "" {
+		for _, name := range browserExecutables {
+			if path, err := exec.LookPath(name); err == nil {
+				options.ExecPath = path
+				break
+			}
+		}
+		if options.ExecPath == "" {
+			return nil, fmt.Errorf("no Chrome or Chromium executable found on the PATH, set BrowserOptions.ExecPath")
+		}
+	}
+
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultBrowserTimeout
+	}
+	if options.RenderBudget <= 0 {
+		options.RenderBudget = DefaultBrowserRenderBudget
+	}
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = DefaultFetchMaxBodyBytes
+	}
+	if options.WindowWidth <= 0 || options.WindowHeight <= 0 {
+		options.WindowWidth, options.WindowHeight = 1280, 800
+	}
+
+	b := &browser{options: options}
+	hosts := strings.Join(options.AllowedHosts, ", ")
+
+	return []inferable.WorkflowTool{
+		{
+			Name:        "browserExtract",
+			Description: fmt.Sprintf("Loads a web page in a browser, running its JavaScript, and returns its content as markdown. Allowed hosts: %s", hosts),
+			InputSchema: BrowserInput{},
+			Func: func(input BrowserInput, ctx inferable.ContextInput) (BrowserExtractOutput, error) {
+				return b.extract(input)
+			},
+		},
+		{
+			Name:        "browserScreenshot",
+			Description: fmt.Sprintf("Loads a web page in a browser and returns a PNG screenshot. Allowed hosts: %s", hosts),
+			InputSchema: BrowserInput{},
+			Func: func(input BrowserInput, ctx inferable.ContextInput) (BrowserScreenshotOutput, error) {
+				return b.screenshot(input)
+			},
+		},
+	}, nil
+}
+
+type browser struct {
+	options BrowserOptions
+}
+
+func (b *browser) extract(input BrowserInput) (BrowserExtractOutput, error) {
+	target, err := b.checkURL(input.URL)
+	if err != nil {
+		return BrowserExtractOutput{}, err
+	}
+
+	document, err := b.run(target, "--dump-dom")
+	if err != nil {
+		return BrowserExtractOutput{}, err
+	}
+
+	content := string(document)
+	if !input.Raw {
+		content = HTMLToMarkdown(content)
+	}
+
+	output := BrowserExtractOutput{URL: target.String()}
+	if len(content) > b.options.MaxBodyBytes {
+		content = strings.ToValidUTF8(content[:b.options.MaxBodyBytes], "")
+		output.Truncated = true
+	}
+	output.Content = content
+	return output, nil
+}
+
+func (b *browser) screenshot(input BrowserInput) (BrowserScreenshotOutput, error) {
+	target, err := b.checkURL(input.URL)
+	if err != nil {
+		return BrowserScreenshotOutput{}, err
+	}
+
+	dir, err := os.MkdirTemp("", "inferable-screenshot-")
+	if err != nil {
+		return BrowserScreenshotOutput{}, fmt.Errorf("failed to create screenshot directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "screenshot.png")
+	if _, err := b.run(target, "--screenshot="+path); err != nil {
+		return BrowserScreenshotOutput{}, err
+	}
+
+	image, err := os.ReadFile(path)
+	if err != nil {
+		return BrowserScreenshotOutput{}, fmt.Errorf("browser did not produce a screenshot: %v", err)
+	}
+
+	return BrowserScreenshotOutput{URL: target.String(), Image: base64.StdEncoding.EncodeToString(image)}, nil
+}
+
+// checkURL validates the URL against the allowlist and, unless allowed, rejects hosts resolving to private addresses.
+func (b *browser) checkURL(rawURL string) (*url.URL, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	if err := checkHost(target, b.options.AllowedHosts); err != nil {
+		return nil, err
+	}
+
+	if !b.options.AllowPrivateNetworks {
+		ctx, cancel := context.WithTimeout(context.Background(), b.options.Timeout)
+		defer cancel()
+
+		addresses, err := net.DefaultResolver.LookupIPAddr(ctx, target.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %q: %v", target.Hostname(), err)
+		}
+		for _, address := range addresses {
+			if !isPublicIP(address.IP) {
+				return nil, fmt.Errorf("%w: %s resolves to the non-public address %s", ErrFetchBlocked, target.Hostname(), address.IP)
+			}
+		}
+	}
+
+	return target, nil
+}
+
+// run loads the URL in a fresh headless browser with the given capture flag and returns its stdout.
+func (b *browser) run(target *url.URL, capture string) ([]byte, error) {
+	profile, err := os.MkdirTemp("", "inferable-browser-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create browser profile: %v", err)
+	}
+	defer os.RemoveAll(profile)
+
+	args := []string{
+		"--headless",
+		"--disable-gpu",
+		"--no-first-run",
+		"--disable-extensions",
+		"--disable-background-networking",
+		"--disable-sync",
+		"--mute-audio",
+		"--hide-scrollbars",
+		"--user-data-dir=" + profile,
+		fmt.Sprintf("--window-size=%d,%d", b.options.WindowWidth, b.options.WindowHeight),
+		fmt.Sprintf("--virtual-time-budget=%d", b.options.RenderBudget.Milliseconds()),
+	}
+	// Every connection of the browser goes through the proxy, which enforces the allowlist and
+	// refuses private addresses, including for loopback hosts, which Chrome does not proxy by default
+	proxy, err := startBrowserProxy(b.options.AllowedHosts, b.options.Timeout, b.options.AllowPrivateNetworks)
+	if err != nil {
+		return nil, err
+	}
+	defer proxy.Close()
+	args = append(args,
+		"--proxy-server="+proxy.URL(),
+		"--proxy-bypass-list=<-loopback>",
+		// WebRTC would otherwise connect over UDP, around the proxy
+		"--force-webrtc-ip-handling-policy=disable_non_proxied_udp",
+	)
+	args = append(args, capture, target.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.options.Timeout)
+	defer cancel()
+
+	output, err := runBrowser(ctx, b.options.ExecPath, args)
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("browser timed out after %v", b.options.Timeout)
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("browser failed: %v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("browser failed: %v", err)
+	}
+	return output, nil
+}
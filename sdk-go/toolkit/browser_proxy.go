@@ -0,0 +1,178 @@
+package toolkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// hopHeaders are connection-specific headers that a proxy does not forward.
+var hopHeaders = []string{
+	"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// browserProxy is a local HTTP proxy that the browser sends all its traffic through. It checks
+// every connection, including redirects, subresources, fetch calls and WebSockets, against the
+// allowed hosts and, unless private networks are allowed, against the resolved address, which
+// the browser's own flags cannot do.
+type browserProxy struct {
+	allowedHosts []string
+	dialer       *net.Dialer
+	transport    *http.Transport
+	listener     net.Listener
+	server       *http.Server
+
+	mu      sync.Mutex
+	tunnels map[net.Conn]bool
+}
+
+// startBrowserProxy starts a proxy on a loopback port. Close it when the browser exits.
+func startBrowserProxy(allowedHosts []string, timeout time.Duration, allowPrivateNetworks bool) (*browserProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start browser proxy: %v", err)
+	}
+
+	dialer := newPublicDialer(timeout, allowPrivateNetworks)
+	p := &browserProxy{
+		allowedHosts: allowedHosts,
+		dialer:       dialer,
+		transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+		},
+		listener: listener,
+		tunnels:  map[net.Conn]bool{},
+	}
+	p.server = &http.Server{Handler: p, ReadHeaderTimeout: timeout}
+	go p.server.Serve(listener)
+	return p, nil
+}
+
+// URL is the proxy's address, for the browser's --proxy-server flag.
+func (p *browserProxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// Close stops the proxy and closes its connections.
+func (p *browserProxy) Close() {
+	p.server.Close()
+	p.transport.CloseIdleConnections()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for conn := range p.tunnels {
+		conn.Close()
+	}
+}
+
+func (p *browserProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+
+	if !r.URL.IsAbs() {
+		http.Error(w, "proxy requests must use absolute URLs", http.StatusBadRequest)
+		return
+	}
+	if err := checkHost(r.URL, p.allowedHosts); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	outbound := r.Clone(r.Context())
+	outbound.RequestURI = ""
+	for _, header := range hopHeaders {
+		outbound.Header.Del(header)
+	}
+
+	resp, err := p.transport.RoundTrip(outbound)
+	if err != nil {
+		http.Error(w, unwrapURLError(err).Error(), proxyErrorStatus(err))
+		return
+	}
+	defer resp.Body.Close()
+
+	for _, header := range hopHeaders {
+		resp.Header.Del(header)
+	}
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+// tunnel handles CONNECT requests, used for HTTPS and WebSockets, by checking the target and
+// relaying bytes between the browser and the target.
+func (p *browserProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	if err := checkHost(&url.URL{Host: r.Host}, p.allowedHosts); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	target, err := p.dialer.DialContext(r.Context(), "tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), proxyErrorStatus(err))
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		target.Close()
+		http.Error(w, "proxy does not support tunnels", http.StatusInternalServerError)
+		return
+	}
+	client, _, err := hijacker.Hijack()
+	if err != nil {
+		target.Close()
+		return
+	}
+	if _, err := io.WriteString(client, "HTTP/1.1 200 Connection Established\r\n\r\n"); err != nil {
+		client.Close()
+		target.Close()
+		return
+	}
+
+	p.mu.Lock()
+	p.tunnels[client], p.tunnels[target] = true, true
+	p.mu.Unlock()
+
+	var relays sync.WaitGroup
+	relay := func(dst net.Conn, src net.Conn) {
+		defer relays.Done()
+		io.Copy(dst, src)
+		// Unblock the other direction
+		dst.Close()
+		src.Close()
+	}
+	relays.Add(2)
+	go relay(target, client)
+	go relay(client, target)
+	relays.Wait()
+
+	p.mu.Lock()
+	delete(p.tunnels, client)
+	delete(p.tunnels, target)
+	p.mu.Unlock()
+}
+
+// proxyErrorStatus is 403 for blocked connections and 502 for other failures.
+func proxyErrorStatus(err error) int {
+	if errors.Is(err, ErrFetchBlocked) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
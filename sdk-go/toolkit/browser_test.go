@@ -0,0 +1,128 @@
+package toolkit
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+func TestBrowser(t *testing.T) {
+	var calls [][]string
+	original := runBrowser
+	t.Cleanup(func() { runBrowser = original })
+
+	runBrowser = func(ctx context.Context, path string, args []string) ([]byte, error) {
+		calls = append(calls, args)
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "--screenshot=") {
+				return nil, os.WriteFile(strings.TrimPrefix(arg, "--screenshot="), []byte("png"), 0o644)
+			}
+		}
+		return []byte("<html><body><h1>Rendered</h1></body></html>"), nil
+	}
+
+	tools, err := Browser(BrowserOptions{
+		AllowedHosts:         []string{"localhost", "*.example.com"},
+		ExecPath:             "chromium",
+		AllowPrivateNetworks: true,
+	})
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+
+	extract := tools[0].Func.(func(BrowserInput, inferable.ContextInput) (BrowserExtractOutput, error))
+	screenshot := tools[1].Func.(func(BrowserInput, inferable.ContextInput) (BrowserScreenshotOutput, error))
+
+	output, err := extract(BrowserInput{URL: "http://localhost:3000/app"}, inferable.ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, BrowserExtractOutput{URL: "http://localhost:3000/app", Content: "# Rendered"}, output)
+
+	args := calls[0]
+	assert.Contains(t, args, "--headless")
+	assert.Contains(t, args, "--dump-dom")
+	assert.Contains(t, args, "--proxy-bypass-list=<-loopback>")
+	assert.Contains(t, strings.Join(args, " "), "--proxy-server=http://127.0.0.1:")
+	assert.Equal(t, "http://localhost:3000/app", args[len(args)-1])
+
+	shot, err := screenshot(BrowserInput{URL: "http://localhost:3000/app"}, inferable.ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("png")), shot.Image)
+
+	_, err = extract(BrowserInput{URL: "https://evil.com"}, inferable.ContextInput{})
+	assert.ErrorIs(t, err, ErrFetchBlocked)
+	assert.Len(t, calls, 2)
+}
+
+func TestBrowserPrivateNetworks(t *testing.T) {
+	tools, err := Browser(BrowserOptions{AllowedHosts: []string{"*"}, ExecPath: "chromium"})
+	require.NoError(t, err)
+
+	extract := tools[0].Func.(func(BrowserInput, inferable.ContextInput) (BrowserExtractOutput, error))
+	_, err = extract(BrowserInput{URL: "http://127.0.0.1:8080"}, inferable.ContextInput{})
+	assert.ErrorIs(t, err, ErrFetchBlocked)
+
+	_, err = Browser(BrowserOptions{ExecPath: "chromium"})
+	assert.Error(t, err)
+}
+
+func TestBrowserProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal over TLS"))
+	}))
+	defer tlsServer.Close()
+
+	get := func(proxy *browserProxy, target string) (int, string) {
+		proxyURL, err := url.Parse(proxy.URL())
+		require.NoError(t, err)
+		transport := tlsServer.Client().Transport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(proxyURL)
+
+		resp, err := (&http.Client{Transport: transport}).Get(target)
+		if err != nil {
+			// CONNECT failures are reported by the client
+			return 0, err.Error()
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	// Private addresses are refused for every request, even when any host is allowed
+	proxy, err := startBrowserProxy([]string{"*"}, time.Second, false)
+	require.NoError(t, err)
+	status, body := get(proxy, server.URL)
+	assert.Equal(t, http.StatusForbidden, status)
+	assert.Contains(t, body, "is not a public address")
+	_, body = get(proxy, tlsServer.URL)
+	assert.Contains(t, body, "Forbidden")
+	proxy.Close()
+
+	proxy, err = startBrowserProxy([]string{"127.0.0.1"}, time.Second, true)
+	require.NoError(t, err)
+	defer proxy.Close()
+	status, body = get(proxy, server.URL)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "internal", body)
+	status, body = get(proxy, tlsServer.URL)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "internal over TLS", body)
+
+	// Hosts outside the allowlist are refused
+	status, _ = get(proxy, strings.Replace(server.URL, "127.0.0.1", "localhost", 1))
+	assert.Equal(t, http.StatusForbidden, status)
+}
@@ -0,0 +1,240 @@
+package toolkit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+// CalculatorInput is the input of the calculate tool.
+type CalculatorInput struct {
+	Expression string `json:"expression" jsonschema:"description=Arithmetic expression, e.g. (2 + 3) * 4 ^ 2 / sqrt(16)"`
+}
+
+// CalculatorOutput is the output of the calculate tool.
+type CalculatorOutput struct {
+	Result float64 `json:"result"`
+}
+
+// Calculator returns a tool that evaluates arithmetic expressions, so agents don't do math in their heads.
+// It supports + - * / % ^, parentheses, and the functions sqrt, abs, floor, ceil, round, ln, log10.
+func Calculator() inferable.WorkflowTool {
+	return inferable.WorkflowTool{
+		Name:        "calculate",
+		Description: "Evaluates an arithmetic expression and returns the numeric result",
+		InputSchema: CalculatorInput{},
+		Func: func(input CalculatorInput, ctx inferable.ContextInput) (CalculatorOutput, error) {
+			result, err := Evaluate(input.Expression)
+			if err != nil {
+				return CalculatorOutput{}, err
+			}
+			return CalculatorOutput{Result: result}, nil
+		},
+	}
+}
+
+// Evaluate evaluates an arithmetic expression.
+func Evaluate(expression string) (float64, error) {
+	p := &exprParser{input: []rune(expression)}
+	result, err := p.parseExpression()
+	if err != nil {
+		return 0, err
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos)
+	}
+
+	if math.IsInf(result, 0) || math.IsNaN(result) {
+		return 0, fmt.Errorf("expression does not have a finite result")
+	}
+
+	return result, nil
+}
+
+var calculatorFunctions = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"abs":   math.Abs,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"round": math.Round,
+	"ln":    math.Log,
+	"log10": math.Log10,
+}
+
+// exprParser is a recursive descent parser for arithmetic expressions.
+type exprParser struct {
+	input []rune
+	pos   int
+}
+
+func (p *exprParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *exprParser) peek() rune {
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+// parseExpression handles addition and subtraction.
+func (p *exprParser) parseExpression() (float64, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		switch p.peek() {
+		case '+':
+			p.pos++
+			right, err := p.parseTerm()
+			if err != nil {
+				return 0, err
+			}
+			left += right
+		case '-':
+			p.pos++
+			right, err := p.parseTerm()
+			if err != nil {
+				return 0, err
+			}
+			left -= right
+		default:
+			return left, nil
+		}
+	}
+}
+
+// parseTerm handles multiplication, division, and modulo.
+func (p *exprParser) parseTerm() (float64, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+
+		switch op {
+		case '*':
+			left *= right
+		case '/':
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			left /= right
+		case '%':
+			if right == 0 {
+				return 0, fmt.Errorf("modulo by zero")
+			}
+			left = math.Mod(left, right)
+		}
+	}
+}
+
+// parseUnary handles unary signs.
+func (p *exprParser) parseUnary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.pos++
+		value, err := p.parseUnary()
+		return -value, err
+	case '+':
+		p.pos++
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+// parsePower handles right-associative exponentiation.
+func (p *exprParser) parsePower() (float64, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return 0, err
+	}
+
+	if p.peek() == '^' {
+		p.pos++
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		return math.Pow(base, exponent), nil
+	}
+	return base, nil
+}
+
+// parsePrimary handles numbers, parentheses, and function calls.
+func (p *exprParser) parsePrimary() (float64, error) {
+	r := p.peek()
+
+	switch {
+	case r == '(':
+		p.pos++
+		value, err := p.parseExpression()
+		if err != nil {
+			return 0, err
+		}
+		if p.peek() != ')' {
+			return 0, fmt.Errorf("missing closing parenthesis at position %d", p.pos)
+		}
+		p.pos++
+		return value, nil
+	case unicode.IsDigit(r) || r == '.':
+		start := p.pos
+		for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		return strconv.ParseFloat(string(p.input[start:p.pos]), 64)
+	case unicode.IsLetter(r):
+		start := p.pos
+		for p.pos < len(p.input) && (unicode.IsLetter(p.input[p.pos]) || unicode.IsDigit(p.input[p.pos])) {
+			p.pos++
+		}
+		name := strings.ToLower(string(p.input[start:p.pos]))
+
+		switch name {
+		case "pi":
+			return math.Pi, nil
+		case "e":
+			return math.E, nil
+		}
+
+		fn, ok := calculatorFunctions[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown function or constant %q", name)
+		}
+		if p.peek() != '(' {
+			return 0, fmt.Errorf("expected ( after function %q", name)
+		}
+		arg, err := p.parsePrimary()
+		if err != nil {
+			return 0, err
+		}
+		return fn(arg), nil
+	case r == 0:
+		return 0, fmt.Errorf("unexpected end of expression")
+	}
+
+	return 0, fmt.Errorf("unexpected %q at position %d", r, p.pos)
+}
@@ -0,0 +1,308 @@
+package toolkit
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+// sendMail delivers a message over SMTP. It is replaced in tests.
+var sendMail = smtp.SendMail
+
+// SMTPOptions configures the sendEmail tool.
+type SMTPOptions struct {
+	// Host is the SMTP server, e.g. "smtp.example.com". Required.
+	Host string
+	// Port is the SMTP port. Defaults to 587; STARTTLS is used when the server supports it.
+	Port int
+	// Username and Password authenticate with PLAIN auth, if set.
+	Username string
+	Password string
+	// From is the sender address, e.g. "Support <support@example.com>". Required.
+	From string
+	// AllowedRecipients, if set, restricts recipients to these addresses or "@domain" suffixes.
+	AllowedRecipients []string
+	// Templates are named text/template bodies the agent can render with data instead of writing the whole body,
+	// e.g. {"receipt": "Hi {{.name}}, your order {{.order}} has shipped."}.
+	Templates map[string]string
+}
+
+// EmailAttachment is a file attached to an outgoing email.
+type EmailAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType,omitempty" jsonschema:"description=MIME type. Defaults to application/octet-stream"`
+	// Content is the base64-encoded file content.
+	Content string `json:"content" jsonschema:"description=Base64-encoded file content"`
+}
+
+// OutgoingEmail is the input of the sendEmail tool.
+type OutgoingEmail struct {
+	To      []string `json:"to"`
+	Cc      []string `json:"cc,omitempty"`
+	Subject string   `json:"subject"`
+	// Body is the plain text body. Ignored when Template is set.
+	Body string `json:"body,omitempty"`
+	// Template names a configured template to render with Data as the body.
+	Template    string                 `json:"template,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	Attachments []EmailAttachment      `json:"attachments,omitempty"`
+	// InReplyTo is the Message-ID of the email being replied to, e.g. "<id@example.com>", so the reply is threaded.
+	InReplyTo string `json:"inReplyTo,omitempty"`
+}
+
+// SendEmailOutput is the output of the sendEmail tool.
+type SendEmailOutput struct {
+	MessageID string `json:"messageId"`
+}
+
+// SendEmail returns a tool that sends email over SMTP, optionally rendering a configured template.
+func SendEmail(options SMTPOptions) (inferable.WorkflowTool, error) {
+	if options.Host == "" || options.From == "" {
+		return inferable.WorkflowTool{}, fmt.Errorf("email tool requires a host and from address")
+	}
+	from, err := mail.ParseAddress(options.From)
+	if err != nil {
+		return inferable.WorkflowTool{}, fmt.Errorf("invalid from address: %v", err)
+	}
+	if options.Port == 0 {
+		options.Port = 587
+	}
+
+	templates := map[string]*template.Template{}
+	names := []string{}
+	for name, body := range options.Templates {
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(body)
+		if err != nil {
+			return inferable.WorkflowTool{}, fmt.Errorf("invalid email template %q: %v", name, err)
+		}
+		templates[name] = tmpl
+		names = append(names, name)
+	}
+
+	description := "Sends an email"
+	if len(names) > 0 {
+		description += fmt.Sprintf(". Available templates: %s", strings.Join(names, ", "))
+	}
+	if len(options.AllowedRecipients) > 0 {
+		description += fmt.Sprintf(". Allowed recipients: %s", strings.Join(options.AllowedRecipients, ", "))
+	}
+
+	var auth smtp.Auth
+	if options.Username != "" {
+		auth = smtp.PlainAuth("", options.Username, options.Password, options.Host)
+	}
+	addr := net.JoinHostPort(options.Host, strconv.Itoa(options.Port))
+
+	return inferable.WorkflowTool{
+		Name:        "sendEmail",
+		Description: description,
+		InputSchema: OutgoingEmail{},
+		Func: func(input OutgoingEmail, ctx inferable.ContextInput) (SendEmailOutput, error) {
+			if input.Template != "" {
+				tmpl, ok := templates[input.Template]
+				if !ok {
+					return SendEmailOutput{}, fmt.Errorf("unknown template %q", input.Template)
+				}
+				var body bytes.Buffer
+				if err := tmpl.Execute(&body, input.Data); err != nil {
+					return SendEmailOutput{}, fmt.Errorf("failed to render template %q: %v", input.Template, err)
+				}
+				input.Body = body.String()
+			}
+
+			recipients := []string{}
+			for _, recipient := range append(append([]string{}, input.To...), input.Cc...) {
+				address, err := mail.ParseAddress(recipient)
+				if err != nil {
+					return SendEmailOutput{}, fmt.Errorf("invalid recipient %q: %v", recipient, err)
+				}
+				if !recipientAllowed(address.Address, options.AllowedRecipients) {
+					return SendEmailOutput{}, fmt.Errorf("recipient %q is not allowed", address.Address)
+				}
+				recipients = append(recipients, address.Address)
+			}
+			if len(recipients) == 0 {
+				return SendEmailOutput{}, fmt.Errorf("at least one recipient is required")
+			}
+
+			messageID, message, err := ComposeEmail(options.From, input)
+			if err != nil {
+				return SendEmailOutput{}, err
+			}
+
+			if err := sendMail(addr, auth, from.Address, recipients, message); err != nil {
+				return SendEmailOutput{}, fmt.Errorf("failed to send email: %v", err)
+			}
+
+			return SendEmailOutput{MessageID: messageID}, nil
+		},
+	}, nil
+}
+
+// ComposeEmail renders an email as an RFC 5322 message and returns it with its generated Message-ID.
+func ComposeEmail(from string, email OutgoingEmail) (string, []byte, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid from address: %v", err)
+	}
+
+	messageID, err := newMessageID(sender.Address)
+	if err != nil {
+		return "", nil, err
+	}
+
+	inReplyTo := strings.TrimSpace(email.InReplyTo)
+	if inReplyTo != "" && !validMessageID(inReplyTo) {
+		return "", nil, fmt.Errorf("invalid inReplyTo %q: must be a message ID such as <id@example.com>", email.InReplyTo)
+	}
+
+	var message bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&message, "%s: %s\r\n", name, value)
+	}
+
+	header("From", sender.String())
+	header("To", strings.Join(email.To, ", "))
+	if len(email.Cc) > 0 {
+		header("Cc", strings.Join(email.Cc, ", "))
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", email.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	if inReplyTo != "" {
+		header("In-Reply-To", inReplyTo)
+		header("References", inReplyTo)
+	}
+	header("MIME-Version", "1.0")
+
+	if len(email.Attachments) == 0 {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		message.WriteString("\r\n")
+		if err := writeQuotedPrintable(&message, email.Body); err != nil {
+			return "", nil, err
+		}
+		return messageID, message.Bytes(), nil
+	}
+
+	writer := multipart.NewWriter(&message)
+	header("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%q", writer.Boundary()))
+	message.WriteString("\r\n")
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to write email body: %v", err)
+	}
+	if err := writeQuotedPrintable(part, email.Body); err != nil {
+		return "", nil, err
+	}
+
+	for _, attachment := range email.Attachments {
+		content, err := base64.StdEncoding.DecodeString(attachment.Content)
+		if err != nil {
+			return "", nil, fmt.Errorf("attachment %q must be base64-encoded: %v", attachment.Filename, err)
+		}
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to write attachment %q: %v", attachment.Filename, err)
+		}
+
+		// Wrap base64 at 76 characters per line, as required by RFC 2045
+		encoded := base64.StdEncoding.EncodeToString(content)
+		for len(encoded) > 76 {
+			fmt.Fprintf(part, "%s\r\n", encoded[:76])
+			encoded = encoded[76:]
+		}
+		fmt.Fprintf(part, "%s\r\n", encoded)
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", nil, fmt.Errorf("failed to write email: %v", err)
+	}
+	return messageID, message.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return fmt.Errorf("failed to write email body: %v", err)
+	}
+	if err := qp.Close(); err != nil {
+		return fmt.Errorf("failed to write email body: %v", err)
+	}
+	return nil
+}
+
+func newMessageID(sender string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate message id: %v", err)
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(sender, "@"); at != -1 {
+		domain = sender[at+1:]
+	}
+	return fmt.Sprintf("<%x@%s>", random, domain), nil
+}
+
+// validMessageID reports whether id is a single msg-id, "<left@right>", without whitespace,
+// control characters or nested angle brackets, so that it cannot add headers to the message.
+func validMessageID(id string) bool {
+	if len(id) < 5 || id[0] != '<' || id[len(id)-1] != '>' {
+		return false
+	}
+	inner := id[1 : len(id)-1]
+	at := strings.Index(inner, "@")
+	if at <= 0 || at == len(inner)-1 || strings.Count(inner, "@") != 1 {
+		return false
+	}
+	for _, r := range inner {
+		if r <= ' ' || r >= 0x7f || r == '<' || r == '>' {
+			return false
+		}
+	}
+	return true
+}
+
+// recipientAllowed reports whether address matches an allowed address or "@domain" suffix.
+func recipientAllowed(address string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	address = strings.ToLower(address)
+	for _, entry := range allowed {
+		entry = strings.ToLower(entry)
+		if address == entry || (strings.HasPrefix(entry, "@") && strings.HasSuffix(address, entry)) {
+			return true
+		}
+	}
+	return false
+}
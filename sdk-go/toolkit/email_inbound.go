@@ -0,0 +1,334 @@
+package toolkit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"time"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+// IMAPOptions configures polling a mailbox over IMAP.
+type IMAPOptions struct {
+	// Addr is the IMAP server address, e.g. "imap.example.com:993". Required.
+	Addr     string
+	Username string
+	Password string
+	// Mailbox is the mailbox to poll. Defaults to "INBOX".
+	Mailbox string
+	// Insecure connects without TLS, e.g. to a local test server.
+	Insecure bool
+	// Timeout bounds a poll, from connecting to logging out. Defaults to 30 seconds.
+	Timeout time.Duration
+	// MaxMessages bounds the number of messages handled per poll. Defaults to 10.
+	MaxMessages int
+	// MaxAttachmentBytes bounds the size of attachments whose content is included. Larger attachments
+	// are listed without content. Defaults to 5 MB.
+	MaxAttachmentBytes int
+}
+
+func (o IMAPOptions) withDefaults() IMAPOptions {
+	if o.Mailbox == "" {
+		o.Mailbox = "INBOX"
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 30 * time.Second
+	}
+	if o.MaxMessages <= 0 {
+		o.MaxMessages = 10
+	}
+	if o.MaxAttachmentBytes <= 0 {
+		o.MaxAttachmentBytes = 5 << 20
+	}
+	return o
+}
+
+// InboundEmail is a received email.
+type InboundEmail struct {
+	MessageID string   `json:"messageId"`
+	From      string   `json:"from"`
+	To        []string `json:"to"`
+	Cc        []string `json:"cc,omitempty"`
+	Subject   string   `json:"subject"`
+	Date      string   `json:"date,omitempty"`
+	// Text is the plain text body, or the HTML body converted to markdown if there is no plain text part.
+	Text        string            `json:"text"`
+	Attachments []EmailAttachment `json:"attachments,omitempty"`
+}
+
+// CheckEmailOutput is the output of the checkEmail tool.
+type CheckEmailOutput struct {
+	Emails []InboundEmail `json:"emails"`
+}
+
+// CheckEmail returns a tool that fetches unseen emails from a mailbox and marks them as seen.
+func CheckEmail(options IMAPOptions) (inferable.WorkflowTool, error) {
+	if options.Addr == "" {
+		return inferable.WorkflowTool{}, fmt.Errorf("email tool requires an IMAP address")
+	}
+	options = options.withDefaults()
+
+	return inferable.WorkflowTool{
+		Name:        "checkEmail",
+		Description: "Fetches unread emails from the inbox and marks them as read",
+		InputSchema: struct{}{},
+		Func: func(input struct{}, ctx inferable.ContextInput) (CheckEmailOutput, error) {
+			output := CheckEmailOutput{Emails: []InboundEmail{}}
+			err := pollEmails(options, func(email InboundEmail) error {
+				output.Emails = append(output.Emails, email)
+				return nil
+			})
+			return output, err
+		},
+	}, nil
+}
+
+// WorkflowTrigger starts workflow executions. It is implemented by *inferable.Workflows.
+type WorkflowTrigger interface {
+	Trigger(workflowName string, executionId string, input interface{}, options ...inferable.TriggerOptions) (*inferable.Execution, error)
+}
+
+var _ WorkflowTrigger = (*inferable.Workflows)(nil)
+
+// EmailTriggerOptions configures TriggerOnEmail.
+type EmailTriggerOptions struct {
+	// IMAP is the mailbox to poll. Required.
+	IMAP IMAPOptions
+	// Workflow is the name of the workflow to trigger. Required.
+	Workflow string
+	// Interval is the time between polls. Defaults to one minute.
+	Interval time.Duration
+	// Logger receives poll and trigger failures.
+	Logger inferable.Logger
+}
+
+// TriggerOnEmail polls a mailbox and triggers the workflow once for every unseen email, until ctx is done.
+// The workflow input has the fields of InboundEmail, so the workflow's InputSchema can embed it:
+//
+//	type SupportInput struct {
+//		ExecutionId string `json:"executionId"`
+//		toolkit.InboundEmail
+//	}
+//
+// The execution ID is derived from the Message-ID, so a message is never handled twice, and an email
+// is only marked as seen once its execution has been triggered. Reply with the sendEmail tool, passing
+// the MessageID as InReplyTo.
+func TriggerOnEmail(ctx context.Context, workflows WorkflowTrigger, options EmailTriggerOptions) error {
+	if options.IMAP.Addr == "" || options.Workflow == "" {
+		return fmt.Errorf("email trigger requires an IMAP address and workflow name")
+	}
+	imap := options.IMAP.withDefaults()
+	if options.Interval <= 0 {
+		options.Interval = time.Minute
+	}
+
+	trigger := func(email InboundEmail) error {
+		input, err := emailWorkflowInput(email)
+		if err != nil {
+			return err
+		}
+		if _, err := workflows.Trigger(options.Workflow, emailExecutionID(email), input); err != nil {
+			return fmt.Errorf("failed to trigger workflow for email %s: %v", email.MessageID, err)
+		}
+		return nil
+	}
+
+	ticker := time.NewTicker(options.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := pollEmails(imap, trigger); err != nil && options.Logger != nil {
+			options.Logger.Error("Failed to poll email", map[string]interface{}{
+				"workflow": options.Workflow,
+				"error":    err.Error(),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollEmails fetches unseen messages and marks each one seen once handle succeeds.
+func pollEmails(options IMAPOptions, handle func(InboundEmail) error) error {
+	conn, err := dialIMAP(options)
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	uids, err := conn.searchUnseen()
+	if err != nil {
+		return fmt.Errorf("failed to search mailbox: %v", err)
+	}
+	if len(uids) > options.MaxMessages {
+		uids = uids[:options.MaxMessages]
+	}
+
+	for _, uid := range uids {
+		raw, err := conn.fetch(uid)
+		if err != nil {
+			return fmt.Errorf("failed to fetch message %s: %v", uid, err)
+		}
+
+		email, err := ParseEmail(raw, options.MaxAttachmentBytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse message %s: %v", uid, err)
+		}
+
+		if err := handle(email); err != nil {
+			return err
+		}
+
+		if err := conn.markSeen(uid); err != nil {
+			return fmt.Errorf("failed to mark message %s as seen: %v", uid, err)
+		}
+	}
+	return nil
+}
+
+// ParseEmail parses a raw RFC 5322 message. Attachment content larger than maxAttachmentBytes is omitted.
+func ParseEmail(raw []byte, maxAttachmentBytes int) (InboundEmail, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return InboundEmail{}, err
+	}
+
+	decoder := &mime.WordDecoder{}
+	decode := func(value string) string {
+		if decoded, err := decoder.DecodeHeader(value); err == nil {
+			return decoded
+		}
+		return value
+	}
+	addresses := func(header string) []string {
+		list, err := message.Header.AddressList(header)
+		if err != nil {
+			return nil
+		}
+		result := make([]string, 0, len(list))
+		for _, address := range list {
+			result = append(result, address.String())
+		}
+		return result
+	}
+
+	email := InboundEmail{
+		MessageID: message.Header.Get("Message-ID"),
+		From:      decode(message.Header.Get("From")),
+		To:        addresses("To"),
+		Cc:        addresses("Cc"),
+		Subject:   decode(message.Header.Get("Subject")),
+		Date:      message.Header.Get("Date"),
+	}
+
+	var text, html string
+	err = walkEmailPart(message.Header.Get("Content-Type"), message.Header.Get("Content-Transfer-Encoding"), "", message.Body,
+		func(contentType string, filename string, content []byte) {
+			switch {
+			case filename != "":
+				attachment := EmailAttachment{Filename: decode(filename), ContentType: contentType}
+				if len(content) <= maxAttachmentBytes {
+					attachment.Content = base64.StdEncoding.EncodeToString(content)
+				}
+				email.Attachments = append(email.Attachments, attachment)
+			case contentType == "text/plain" && text == "":
+				text = string(content)
+			case contentType == "text/html" && html == "":
+				html = string(content)
+			}
+		})
+	if err != nil {
+		return InboundEmail{}, err
+	}
+
+	email.Text = strings.TrimSpace(text)
+	if email.Text == "" && html != "" {
+		email.Text = HTMLToMarkdown(html)
+	}
+	return email, nil
+}
+
+// walkEmailPart decodes a MIME part, recursing into multiparts, and calls visit for each leaf part.
+func walkEmailPart(contentType string, encoding string, disposition string, body io.Reader, visit func(contentType string, filename string, content []byte)) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			err = walkEmailPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part.Header.Get("Content-Disposition"), part, visit)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		// The decoder ignores the line breaks of wrapped content
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	filename := params["name"]
+	if _, dispositionParams, err := mime.ParseMediaType(disposition); err == nil && dispositionParams["filename"] != "" {
+		filename = dispositionParams["filename"]
+	}
+	if strings.HasPrefix(mediaType, "text/") && filename == "" {
+		if decoded, err := decodeCharset(content, params["charset"]); err == nil {
+			content = []byte(decoded)
+		}
+	}
+
+	visit(mediaType, filename, content)
+	return nil
+}
+
+// emailExecutionID derives a stable execution ID from an email, so it triggers at most one execution.
+func emailExecutionID(email InboundEmail) string {
+	key := email.MessageID
+	if key == "" {
+		key = email.From + "\n" + email.Date + "\n" + email.Subject
+	}
+	return fmt.Sprintf("email-%x", sha256.Sum256([]byte(key)))[:38]
+}
+
+func emailWorkflowInput(email InboundEmail) (map[string]interface{}, error) {
+	data, err := json.Marshal(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal email: %v", err)
+	}
+	input := map[string]interface{}{}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal email: %v", err)
+	}
+	return input, nil
+}
@@ -0,0 +1,229 @@
+package toolkit
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+func TestSendEmail(t *testing.T) {
+	var sentTo []string
+	var sent []byte
+	original := sendMail
+	sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		assert.Equal(t, "smtp.example.com:587", addr)
+		assert.Equal(t, "support@example.com", from)
+		sentTo, sent = to, msg
+		return nil
+	}
+	t.Cleanup(func() { sendMail = original })
+
+	tool, err := SendEmail(SMTPOptions{
+		Host:              "smtp.example.com",
+		From:              "Support <support@example.com>",
+		AllowedRecipients: []string{"@customer.com"},
+		Templates:         map[string]string{"shipped": "Hi {{.name}}, order {{.order}} has shipped."},
+	})
+	require.NoError(t, err)
+	send := tool.Func.(func(OutgoingEmail, inferable.ContextInput) (SendEmailOutput, error))
+
+	output, err := send(OutgoingEmail{
+		To:          []string{"Jane <jane@customer.com>"},
+		Subject:     "Your order",
+		Template:    "shipped",
+		Data:        map[string]interface{}{"name": "Jane", "order": "A-1"},
+		Attachments: []EmailAttachment{{Filename: "invoice.txt", ContentType: "text/plain", Content: base64.StdEncoding.EncodeToString([]byte("total: 42"))}},
+		InReplyTo:   "<original@customer.com>",
+	}, inferable.ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"jane@customer.com"}, sentTo)
+
+	email, err := ParseEmail(sent, 1024)
+	require.NoError(t, err)
+	assert.Equal(t, output.MessageID, email.MessageID)
+	assert.Equal(t, "Your order", email.Subject)
+	assert.Equal(t, "Hi Jane, order A-1 has shipped.", email.Text)
+	require.Len(t, email.Attachments, 1)
+	assert.Equal(t, "invoice.txt", email.Attachments[0].Filename)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("total: 42")), email.Attachments[0].Content)
+
+	message, err := mail.ReadMessage(strings.NewReader(string(sent)))
+	require.NoError(t, err)
+	assert.Equal(t, "<original@customer.com>", message.Header.Get("In-Reply-To"))
+
+	_, err = send(OutgoingEmail{To: []string{"someone@elsewhere.com"}, Subject: "hi", Body: "hi"}, inferable.ContextInput{})
+	assert.ErrorContains(t, err, "not allowed")
+
+	_, err = send(OutgoingEmail{To: []string{"jane@customer.com"}, Template: "shipped"}, inferable.ContextInput{})
+	assert.ErrorContains(t, err, "failed to render template")
+}
+
+func TestComposeEmailInReplyTo(t *testing.T) {
+	_, message, err := ComposeEmail("support@example.com", OutgoingEmail{To: []string{"jane@customer.com"}, Body: "hi", InReplyTo: " <original@customer.com> "})
+	require.NoError(t, err)
+	parsed, err := mail.ReadMessage(strings.NewReader(string(message)))
+	require.NoError(t, err)
+	assert.Equal(t, "<original@customer.com>", parsed.Header.Get("In-Reply-To"))
+	assert.Equal(t, "<original@customer.com>", parsed.Header.Get("References"))
+
+	for _, inReplyTo := range []string{
+		"<original@customer.com>\r\nBcc: attacker@evil.com",
+		"<original@customer.com>\nBcc: attacker@evil.com",
+		"original@customer.com",
+		"<original>",
+		"<a@b> <c@d>",
+		"<<a@b>>",
+	} {
+		_, _, err := ComposeEmail("support@example.com", OutgoingEmail{To: []string{"jane@customer.com"}, Body: "hi", InReplyTo: inReplyTo})
+		assert.ErrorContains(t, err, "invalid inReplyTo", inReplyTo)
+	}
+}
+
+func TestParseEmailHTMLOnly(t *testing.T) {
+	raw := "From: =?utf-8?q?Jos=C3=A9?= <jose@example.com>\r\n" +
+		"To: support@example.com\r\n" +
+		"Subject: Help\r\n" +
+		"Message-ID: <1@example.com>\r\n" +
+		"Content-Type: text/html; charset=iso-8859-1\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n" +
+		"\r\n" +
+		"<p>Caf=E9 is <b>closed</b></p>\r\n"
+
+	email, err := ParseEmail([]byte(raw), 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "José <jose@example.com>", email.From)
+	assert.Equal(t, []string{"<support@example.com>"}, email.To)
+	assert.Equal(t, "Café is **closed**", email.Text)
+}
+
+// fakeIMAPServer serves a mailbox of messages over a scripted subset of IMAP.
+type fakeIMAPServer struct {
+	mu       sync.Mutex
+	messages map[string]string
+	seen     map[string]bool
+}
+
+func (s *fakeIMAPServer) serve(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func (s *fakeIMAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	fmt.Fprint(conn, "* OK ready\r\n")
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		tag, command := fields[0], strings.Join(fields[1:], " ")
+
+		s.mu.Lock()
+		switch {
+		case strings.HasPrefix(command, "UID SEARCH UNSEEN"):
+			uids := []string{}
+			for _, uid := range []string{"1", "2"} {
+				if !s.seen[uid] {
+					uids = append(uids, uid)
+				}
+			}
+			fmt.Fprintf(conn, "* SEARCH %s\r\n", strings.Join(uids, " "))
+		case strings.HasPrefix(command, "UID FETCH"):
+			message := s.messages[fields[3]]
+			fmt.Fprintf(conn, "* 1 FETCH (UID %s BODY[] {%d}\r\n%s)\r\n", fields[3], len(message), message)
+		case strings.HasPrefix(command, "UID STORE"):
+			s.seen[fields[3]] = true
+		case strings.HasPrefix(command, "LOGOUT"):
+			fmt.Fprintf(conn, "* BYE\r\n%s OK done\r\n", tag)
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+func TestCheckEmailAndTrigger(t *testing.T) {
+	server := &fakeIMAPServer{
+		messages: map[string]string{
+			"1": "From: a@example.com\r\nTo: support@example.com\r\nSubject: First\r\nMessage-ID: <1@example.com>\r\n\r\nHello",
+			"2": "From: b@example.com\r\nTo: support@example.com\r\nSubject: Second\r\nMessage-ID: <2@example.com>\r\n\r\nWorld",
+		},
+		seen: map[string]bool{},
+	}
+	addr := server.serve(t)
+	options := IMAPOptions{Addr: addr, Username: "user", Password: `p"ss`, Insecure: true, Timeout: time.Second}
+
+	trigger := &testTrigger{fail: map[string]bool{"Second": true}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := TriggerOnEmail(ctx, trigger, EmailTriggerOptions{IMAP: options, Workflow: "support"})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	require.Len(t, trigger.calls, 2)
+	assert.Equal(t, "support", trigger.calls[0].workflow)
+	assert.Equal(t, emailExecutionID(InboundEmail{MessageID: "<1@example.com>"}), trigger.calls[0].executionId)
+	assert.Equal(t, "Hello", trigger.calls[0].input["text"])
+
+	// Only the successfully triggered email is marked as seen
+	assert.True(t, server.seen["1"])
+	assert.False(t, server.seen["2"])
+
+	tool, err := CheckEmail(options)
+	require.NoError(t, err)
+	check := tool.Func.(func(struct{}, inferable.ContextInput) (CheckEmailOutput, error))
+
+	output, err := check(struct{}{}, inferable.ContextInput{})
+	require.NoError(t, err)
+	require.Len(t, output.Emails, 1)
+	assert.Equal(t, "Second", output.Emails[0].Subject)
+	assert.True(t, server.seen["2"])
+}
+
+type testTriggerCall struct {
+	workflow    string
+	executionId string
+	input       map[string]interface{}
+}
+
+type testTrigger struct {
+	calls []testTriggerCall
+	fail  map[string]bool
+}
+
+func (t *testTrigger) Trigger(workflowName string, executionId string, input interface{}, options ...inferable.TriggerOptions) (*inferable.Execution, error) {
+	inputMap := input.(map[string]interface{})
+	t.calls = append(t.calls, testTriggerCall{workflow: workflowName, executionId: executionId, input: inputMap})
+	if t.fail[inputMap["subject"].(string)] {
+		return nil, fmt.Errorf("unavailable")
+	}
+	return nil, nil
+}
@@ -0,0 +1,364 @@
+package toolkit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+const (
+	// DefaultFetchTimeout bounds the duration of a fetch, including redirects and reading the body.
+	DefaultFetchTimeout = 10 * time.Second
+	// DefaultFetchMaxBodyBytes bounds the response body read by a fetch. Longer bodies are truncated.
+	DefaultFetchMaxBodyBytes = 1 << 20
+	// DefaultFetchMaxRedirects bounds the number of redirects a fetch follows.
+	DefaultFetchMaxRedirects = 5
+)
+
+// ErrFetchBlocked is returned when a fetch targets a host that is not allowed or resolves to a private address.
+var ErrFetchBlocked = errors.New("fetch blocked")
+
+// FetchOptions configures Fetch and the httpFetch tool.
+type FetchOptions struct {
+	// AllowedHosts lists the hosts that may be requested, e.g. "api.example.com" or "*.example.com".
+	// Use "*" to allow any public host.
+	AllowedHosts []string
+	// Timeout bounds the duration of a fetch. Defaults to DefaultFetchTimeout.
+	Timeout time.Duration
+	// MaxBodyBytes bounds the response body that is read. Defaults to DefaultFetchMaxBodyBytes.
+	MaxBodyBytes int64
+	// MaxRedirects bounds the number of redirects followed. Defaults to DefaultFetchMaxRedirects.
+	// Every redirect target must also be an allowed host.
+	MaxRedirects int
+	// AllowPrivateNetworks permits connections to loopback, private, and link-local addresses.
+	// By default they are refused to protect internal services from server-side request forgery.
+	AllowPrivateNetworks bool
+}
+
+func (o FetchOptions) withDefaults() FetchOptions {
+	if o.Timeout <= 0 {
+		o.Timeout = DefaultFetchTimeout
+	}
+	if o.MaxBodyBytes <= 0 {
+		o.MaxBodyBytes = DefaultFetchMaxBodyBytes
+	}
+	if o.MaxRedirects <= 0 {
+		o.MaxRedirects = DefaultFetchMaxRedirects
+	}
+	return o
+}
+
+// FetchResult is the decoded response of a fetch.
+type FetchResult struct {
+	// URL is the final URL after redirects.
+	URL    string `json:"url"`
+	Status int    `json:"status"`
+	// ContentType is the media type of the response, e.g. "text/html".
+	ContentType string `json:"contentType"`
+	// Body is the response decoded to UTF-8. HTML is converted to markdown unless fetched raw, and
+	// PDFs to their text.
+	Body      string `json:"body"`
+	Truncated bool   `json:"truncated,omitempty"`
+	// Size is the size of a PDF in bytes, from its Content-Length if it was truncated, or -1 if unknown.
+	Size int64 `json:"size,omitempty"`
+	// Pages is the page count of a PDF.
+	Pages int `json:"pages,omitempty"`
+	// Note explains how the body was derived from content that is not text, such as a PDF.
+	Note string `json:"note,omitempty"`
+}
+
+// HTTPFetchInput is the input of the httpFetch tool.
+type HTTPFetchInput struct {
+	URL string `json:"url" jsonschema:"description=http(s) URL to fetch with a GET request"`
+	Raw bool   `json:"raw,omitempty" jsonschema:"description=Return HTML as-is instead of converting it to markdown"`
+}
+
+// HTTPFetch returns a tool that fetches URLs on the allowed hosts with GET requests.
+// See Fetch for how responses are handled.
+func HTTPFetch(options FetchOptions) inferable.WorkflowTool {
+	return inferable.WorkflowTool{
+		Name:        "httpFetch",
+		Description: fmt.Sprintf("Fetches a URL with a GET request and returns its content as text. The text of PDFs is extracted without their layout, images, or tables. Allowed hosts: %s", strings.Join(options.AllowedHosts, ", ")),
+		InputSchema: HTTPFetchInput{},
+		Func: func(input HTTPFetchInput, ctx inferable.ContextInput) (FetchResult, error) {
+			return Fetch(context.Background(), input.URL, input.Raw, options)
+		},
+	}
+}
+
+// Fetch performs a GET request and decodes the response for an LLM:
+//   - HTML is converted to markdown with HTMLToMarkdown (unless raw is set),
+//   - JSON is validated and returned as-is, other text types are returned as text,
+//   - PDFs are converted to their text with PDFText, with a Note on what was lost. PDFs without
+//     extractable text, such as scans, return their size and page count with an empty Body,
+//   - other binary content is rejected with an error.
+//
+// Bodies are decoded from their declared charset (UTF-8, ISO-8859-1, Windows-1252, or US-ASCII) to UTF-8.
+// Requests to hosts outside options.AllowedHosts, and connections to private addresses, fail with ErrFetchBlocked.
+func Fetch(ctx context.Context, rawURL string, raw bool, options FetchOptions) (FetchResult, error) {
+	options = options.withDefaults()
+
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return FetchResult{}, fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	if err := checkHost(target, options.AllowedHosts); err != nil {
+		return FetchResult{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "text/html, application/json, text/plain;q=0.9, */*;q=0.5")
+
+	resp, err := newFetchClient(options).Do(req)
+	if err != nil {
+		if errors.Is(err, ErrFetchBlocked) {
+			return FetchResult{}, unwrapURLError(err)
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return FetchResult{}, fmt.Errorf("request timed out after %v", options.Timeout)
+		}
+		return FetchResult{}, fmt.Errorf("request failed: %v", unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, options.MaxBodyBytes+1))
+	if err != nil {
+		return FetchResult{}, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	result := FetchResult{
+		URL:    resp.Request.URL.String(),
+		Status: resp.StatusCode,
+	}
+	size := int64(len(body))
+	if size > options.MaxBodyBytes {
+		body = body[:options.MaxBodyBytes]
+		result.Truncated = true
+		size = resp.ContentLength
+	}
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = http.DetectContentType(body)
+		mediaType, params, _ = mime.ParseMediaType(mediaType)
+	}
+	result.ContentType = mediaType
+
+	if mediaType == "application/pdf" {
+		result.Size = size
+		return fetchPDF(result, body), nil
+	}
+
+	kind := contentKind(mediaType)
+	if kind == "" {
+		return result, fmt.Errorf("unsupported content type %q: only HTML, JSON, PDF, and text can be fetched", mediaType)
+	}
+
+	charset := params["charset"]
+	if charset == "" && kind == "html" {
+		charset = sniffHTMLCharset(body)
+	}
+	text, err := decodeCharset(body, charset)
+	if err != nil {
+		return result, err
+	}
+
+	switch {
+	case kind == "html" && !raw:
+		text = HTMLToMarkdown(text)
+	case kind == "json" && !result.Truncated && !json.Valid([]byte(text)):
+		return result, fmt.Errorf("response declared as %s is not valid JSON", mediaType)
+	}
+	result.Body = text
+
+	return result, nil
+}
+
+// fetchPDF sets the body of a PDF result to the PDF's text, noting what was lost.
+func fetchPDF(result FetchResult, body []byte) FetchResult {
+	text, pages, ok := PDFText(body)
+	result.Pages = pages
+
+	size := "of unknown size"
+	if result.Size >= 0 {
+		size = fmt.Sprintf("of %d bytes", result.Size)
+	}
+	if !ok {
+		result.Note = fmt.Sprintf("No text could be extracted from this PDF %s, e.g. because it is scanned or uses embedded font encodings.", size)
+		if result.Truncated {
+			result.Note += fmt.Sprintf(" Only the first %d bytes were read.", len(body))
+		}
+		return result
+	}
+
+	result.Body = text
+	result.Note = fmt.Sprintf("Text extracted from a PDF %s. Layout, images, and tables are not preserved.", size)
+	if result.Truncated {
+		result.Note += fmt.Sprintf(" Only the first %d bytes were read, so later pages may be missing.", len(body))
+	}
+	return result
+}
+
+// newFetchClient returns a client that enforces the redirect limit and host allowlist on every hop,
+// and (unless private networks are allowed) refuses connections to non-public addresses.
+func newFetchClient(options FetchOptions) *http.Client {
+	dialer := newPublicDialer(options.Timeout, options.AllowPrivateNetworks)
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   options.Timeout,
+			ResponseHeaderTimeout: options.Timeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > options.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", options.MaxRedirects)
+			}
+			return checkHost(req.URL, options.AllowedHosts)
+		},
+	}
+}
+
+// newPublicDialer returns a dialer that, unless private networks are allowed, refuses connections
+// to non-public addresses.
+func newPublicDialer(timeout time.Duration, allowPrivateNetworks bool) *net.Dialer {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		// Checked on the resolved address at connect time, so DNS rebinding cannot bypass it
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s is not a public address", ErrFetchBlocked, host)
+			}
+			return nil
+		}
+	}
+	return dialer
+}
+
+// checkHost returns ErrFetchBlocked if the URL's host is not allowed.
+func checkHost(target *url.URL, allowedHosts []string) error {
+	if !hostAllowed(target.Hostname(), allowedHosts) {
+		return fmt.Errorf("%w: host %q is not allowed. Allowed hosts: %s", ErrFetchBlocked, target.Hostname(), strings.Join(allowedHosts, ", "))
+	}
+	return nil
+}
+
+// hostAllowed reports whether host matches one of the allowed host patterns.
+func hostAllowed(host string, allowedHosts []string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" {
+			return true
+		}
+		if strings.HasPrefix(allowed, "*.") {
+			if strings.HasSuffix(host, allowed[1:]) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// carrierGradeNAT is the shared address space of RFC 6598, which is not publicly routable.
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPublicIP reports whether ip is a publicly routable unicast address.
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4[0] != 0 && !carrierGradeNAT.Contains(ip4) && !ip4.Equal(net.IPv4bcast)
+	}
+	return true
+}
+
+// contentKind classifies a media type as "html", "json", or "text", or returns "" if it is not textual.
+func contentKind(mediaType string) string {
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return "html"
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return "json"
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml") ||
+		mediaType == "application/javascript" || mediaType == "application/x-yaml" || mediaType == "application/yaml":
+		return "text"
+	}
+	return ""
+}
+
+// sniffHTMLCharset looks for a <meta charset> declaration near the start of an HTML document.
+func sniffHTMLCharset(body []byte) string {
+	head := strings.ToLower(string(body[:min(len(body), 1024)]))
+
+	i := strings.Index(head, "charset=")
+	if i == -1 {
+		return ""
+	}
+	value := strings.TrimLeft(head[i+len("charset="):], `"' `)
+	if end := strings.IndexAny(value, `"'; />`); end != -1 {
+		value = value[:end]
+	}
+	return value
+}
+
+// windows1252 maps the bytes 0x80-0x9F, which differ from ISO-8859-1, to their Unicode code points.
+var windows1252 = [32]rune{
+	'€', utf8.RuneError, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', utf8.RuneError, 'Ž', utf8.RuneError,
+	utf8.RuneError, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', utf8.RuneError, 'ž', 'Ÿ',
+}
+
+// decodeCharset decodes body from the given charset to UTF-8. An empty charset is treated as UTF-8.
+func decodeCharset(body []byte, charset string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return strings.ToValidUTF8(string(body), string(utf8.RuneError)), nil
+	case "iso-8859-1", "latin1", "latin-1", "windows-1252", "cp1252":
+		var b strings.Builder
+		b.Grow(len(body))
+		for _, c := range body {
+			if c >= 0x80 && c <= 0x9f {
+				b.WriteRune(windows1252[c-0x80])
+			} else {
+				b.WriteRune(rune(c))
+			}
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("unsupported charset %q", charset)
+}
+
+// unwrapURLError strips the "Get <url>:" prefix added by the http client.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,184 @@
+package toolkit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+func newFetchServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("hello"))
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<h1>Hello</h1><script>tracking()</script>"))
+	})
+	mux.HandleFunc("/latin1", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<meta charset=\"windows-1252\"><p>Caf\xe9 \x93quoted\x94</p>"))
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	})
+	mux.HandleFunc("/broken-json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok": `))
+	})
+	mux.HandleFunc("/pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write(testPDF(t, "BT /F1 12 Tf 72 720 Td (Quarterly report) Tj 0 -14 Td [(Revenue) -300 (grew)] TJ ET"))
+	})
+	mux.HandleFunc("/scan", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.7\n1 0 obj << /Type /Page >> endobj"))
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte("\x89PNG"))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write(make([]byte, 100))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/redirect", http.StatusFound)
+	})
+	mux.HandleFunc("/external", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com/", http.StatusFound)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFetch(t *testing.T) {
+	server := newFetchServer(t)
+	options := FetchOptions{AllowedHosts: []string{"127.0.0.1"}, AllowPrivateNetworks: true}
+	ctx := context.Background()
+
+	result, err := Fetch(ctx, server.URL+"/text", false, options)
+	require.NoError(t, err)
+	assert.Equal(t, FetchResult{URL: server.URL + "/text", Status: 200, ContentType: "text/plain", Body: "hello"}, result)
+
+	result, err = Fetch(ctx, server.URL+"/page", false, options)
+	require.NoError(t, err)
+	assert.Equal(t, "# Hello", result.Body)
+
+	result, err = Fetch(ctx, server.URL+"/page", true, options)
+	require.NoError(t, err)
+	assert.Equal(t, "<h1>Hello</h1><script>tracking()</script>", result.Body)
+
+	result, err = Fetch(ctx, server.URL+"/latin1", false, options)
+	require.NoError(t, err)
+	assert.Equal(t, "Café “quoted”", result.Body)
+
+	result, err = Fetch(ctx, server.URL+"/json", false, options)
+	require.NoError(t, err)
+	assert.Equal(t, `{"ok": true}`, result.Body)
+
+	_, err = Fetch(ctx, server.URL+"/broken-json", false, options)
+	assert.ErrorContains(t, err, "not valid JSON")
+
+	result, err = Fetch(ctx, server.URL+"/pdf", false, options)
+	require.NoError(t, err)
+	assert.Equal(t, "Quarterly report\nRevenue grew", result.Body)
+	assert.Equal(t, 1, result.Pages)
+	assert.Contains(t, result.Note, "Text extracted from a PDF of")
+
+	// PDFs without text are described rather than rejected
+	result, err = Fetch(ctx, server.URL+"/scan", false, options)
+	require.NoError(t, err)
+	assert.Empty(t, result.Body)
+	assert.Equal(t, 1, result.Pages)
+	assert.Equal(t, "No text could be extracted from this PDF of 41 bytes, e.g. because it is scanned or uses embedded font encodings.", result.Note)
+
+	_, err = Fetch(ctx, server.URL+"/image", false, options)
+	assert.ErrorContains(t, err, "unsupported content type \"image/png\"")
+
+	limited := options
+	limited.MaxBodyBytes = 10
+	result, err = Fetch(ctx, server.URL+"/large", false, limited)
+	require.NoError(t, err)
+	assert.Len(t, result.Body, 10)
+	assert.True(t, result.Truncated)
+}
+
+func TestFetchLimits(t *testing.T) {
+	server := newFetchServer(t)
+	options := FetchOptions{AllowedHosts: []string{"127.0.0.1"}, AllowPrivateNetworks: true}
+	ctx := context.Background()
+
+	timed := options
+	timed.Timeout = 50 * time.Millisecond
+	_, err := Fetch(ctx, server.URL+"/slow", false, timed)
+	assert.ErrorContains(t, err, "timed out")
+
+	_, err = Fetch(ctx, server.URL+"/redirect", false, options)
+	assert.ErrorContains(t, err, "stopped after 5 redirects")
+
+	// Redirects must stay on allowed hosts
+	_, err = Fetch(ctx, server.URL+"/external", false, options)
+	assert.ErrorIs(t, err, ErrFetchBlocked)
+
+	_, err = Fetch(ctx, "https://example.com", false, options)
+	assert.ErrorIs(t, err, ErrFetchBlocked)
+
+	_, err = Fetch(ctx, "file:///etc/passwd", false, options)
+	assert.Error(t, err)
+
+	// Private addresses are refused by default, even for allowed hosts
+	_, err = Fetch(ctx, server.URL+"/text", false, FetchOptions{AllowedHosts: []string{"*"}})
+	assert.ErrorIs(t, err, ErrFetchBlocked)
+}
+
+func TestHTTPFetch(t *testing.T) {
+	server := newFetchServer(t)
+
+	fetch := HTTPFetch(FetchOptions{AllowedHosts: []string{"127.0.0.1"}, AllowPrivateNetworks: true}).Func.(func(HTTPFetchInput, inferable.ContextInput) (FetchResult, error))
+
+	result, err := fetch(HTTPFetchInput{URL: server.URL + "/page"}, inferable.ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "# Hello", result.Body)
+}
+
+func TestHostAllowed(t *testing.T) {
+	allowed := []string{"api.example.com", "*.internal.dev"}
+
+	assert.True(t, hostAllowed("api.example.com", allowed))
+	assert.True(t, hostAllowed("API.example.com", allowed))
+	assert.True(t, hostAllowed("svc.internal.dev", allowed))
+	assert.False(t, hostAllowed("internal.dev", allowed))
+	assert.False(t, hostAllowed("example.com", allowed))
+	assert.False(t, hostAllowed("evilinternal.dev", allowed))
+	assert.True(t, hostAllowed("anything.com", []string{"*"}))
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"8.8.8.8", "2606:4700:4700::1111"} {
+		assert.True(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"127.0.0.1", "10.0.0.1", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fd00::1", "fe80::1", "::ffff:127.0.0.1"} {
+		assert.False(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+}
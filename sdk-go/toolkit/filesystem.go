@@ -0,0 +1,315 @@
+package toolkit
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+const (
+	// DefaultMaxFileBytes bounds the size of a file read or written by the filesystem tools.
+	DefaultMaxFileBytes = 1 << 20
+	// DefaultMaxTotalBytes bounds the total size of the files under the filesystem root.
+	DefaultMaxTotalBytes = 100 << 20
+	// maxListEntries bounds the number of entries returned by the listFiles tool.
+	maxListEntries = 1000
+)
+
+// FilesystemOptions configures the filesystem tools.
+type FilesystemOptions struct {
+	// Root is the directory the tools are confined to. Required.
+	Root string
+	// ReadOnly omits the writeFile tool.
+	ReadOnly bool
+	// MaxFileBytes bounds the size of a single read or write. Defaults to DefaultMaxFileBytes.
+	MaxFileBytes int64
+	// MaxTotalBytes bounds the total size of the files under Root; writes that would exceed it fail.
+	// Defaults to DefaultMaxTotalBytes.
+	MaxTotalBytes int64
+}
+
+// FileEntry describes a file or directory returned by the listFiles tool.
+type FileEntry struct {
+	Name string `json:"name"`
+	Dir  bool   `json:"dir,omitempty"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// ListFilesInput is the input of the listFiles tool.
+type ListFilesInput struct {
+	Path string `json:"path,omitempty" jsonschema:"description=Directory to list, relative to the root. Defaults to the root"`
+}
+
+// ListFilesOutput is the output of the listFiles tool.
+type ListFilesOutput struct {
+	Entries   []FileEntry `json:"entries"`
+	Truncated bool        `json:"truncated,omitempty"`
+}
+
+// ReadFileInput is the input of the readFile tool.
+type ReadFileInput struct {
+	Path string `json:"path" jsonschema:"description=File to read, relative to the root"`
+}
+
+// ReadFileOutput is the output of the readFile tool.
+type ReadFileOutput struct {
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// WriteFileInput is the input of the writeFile tool.
+type WriteFileInput struct {
+	Path    string `json:"path" jsonschema:"description=File to write, relative to the root. Parent directories are created"`
+	Content string `json:"content"`
+	Append  bool   `json:"append,omitempty" jsonschema:"description=Append to the file instead of replacing it"`
+}
+
+// WriteFileOutput is the output of the writeFile tool.
+type WriteFileOutput struct {
+	Size int64 `json:"size"`
+}
+
+// Filesystem returns listFiles, readFile, and (unless read-only) writeFile tools confined to a root directory.
+// Paths are interpreted relative to the root; paths that escape it, including through symlinks, are rejected.
+//
+//	tools, err := toolkit.Filesystem(toolkit.FilesystemOptions{Root: "./reports"})
+//	for _, tool := range tools {
+//		workflow.Tools.Register(tool)
+//	}
+func Filesystem(options FilesystemOptions) ([]inferable.WorkflowTool, error) {
+	if options.Root == "" {
+		return nil, fmt.Errorf("filesystem tools require a root directory")
+	}
+
+	root, err := filepath.Abs(options.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve root: %v", err)
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, fmt.Errorf("failed to resolve root: %v", err)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("root %q is not a directory", options.Root)
+	}
+
+	if options.MaxFileBytes <= 0 {
+		options.MaxFileBytes = DefaultMaxFileBytes
+	}
+	if options.MaxTotalBytes <= 0 {
+		options.MaxTotalBytes = DefaultMaxTotalBytes
+	}
+
+	s := &sandbox{root: root, options: options}
+
+	tools := []inferable.WorkflowTool{
+		{
+			Name:        "listFiles",
+			Description: "Lists the files and directories in a directory",
+			InputSchema: ListFilesInput{},
+			Func: func(input ListFilesInput, ctx inferable.ContextInput) (ListFilesOutput, error) {
+				return s.list(input.Path)
+			},
+		},
+		{
+			Name:        "readFile",
+			Description: "Reads a text file",
+			InputSchema: ReadFileInput{},
+			Func: func(input ReadFileInput, ctx inferable.ContextInput) (ReadFileOutput, error) {
+				return s.read(input.Path)
+			},
+		},
+	}
+
+	if !options.ReadOnly {
+		tools = append(tools, inferable.WorkflowTool{
+			Name:        "writeFile",
+			Description: "Writes a text file, creating or replacing it",
+			InputSchema: WriteFileInput{},
+			Func: func(input WriteFileInput, ctx inferable.ContextInput) (WriteFileOutput, error) {
+				return s.write(input)
+			},
+		})
+	}
+
+	return tools, nil
+}
+
+// sandbox confines file operations to a root directory.
+type sandbox struct {
+	root    string
+	options FilesystemOptions
+}
+
+// resolve maps a root-relative path to an absolute path, rejecting paths that escape the root.
+func (s *sandbox) resolve(name string) (string, error) {
+	// Rooting the path first means ".." can never climb above the root
+	full := filepath.Join(s.root, filepath.Clean("/"+filepath.FromSlash(name)))
+
+	// Resolve symlinks in the longest existing prefix, so links pointing outside the root are caught
+	existing, rest := full, ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path %q: %v", name, err)
+	}
+	resolved = filepath.Join(resolved, rest)
+
+	if resolved != s.root && !strings.HasPrefix(resolved, s.root+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside the root directory", name)
+	}
+	return resolved, nil
+}
+
+func (s *sandbox) list(name string) (ListFilesOutput, error) {
+	dir, err := s.resolve(name)
+	if err != nil {
+		return ListFilesOutput{}, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ListFilesOutput{}, fmt.Errorf("failed to list %q: %v", name, trimPathError(err))
+	}
+
+	output := ListFilesOutput{Entries: []FileEntry{}}
+	for _, entry := range entries {
+		if len(output.Entries) == maxListEntries {
+			output.Truncated = true
+			break
+		}
+
+		file := FileEntry{Name: entry.Name(), Dir: entry.IsDir()}
+		if info, err := entry.Info(); err == nil && !entry.IsDir() {
+			file.Size = info.Size()
+		}
+		output.Entries = append(output.Entries, file)
+	}
+	return output, nil
+}
+
+func (s *sandbox) read(name string) (ReadFileOutput, error) {
+	path, err := s.resolve(name)
+	if err != nil {
+		return ReadFileOutput{}, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return ReadFileOutput{}, fmt.Errorf("failed to read %q: %v", name, trimPathError(err))
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(io.LimitReader(file, s.options.MaxFileBytes+1))
+	if err != nil {
+		return ReadFileOutput{}, fmt.Errorf("failed to read %q: %v", name, trimPathError(err))
+	}
+
+	output := ReadFileOutput{}
+	if int64(len(content)) > s.options.MaxFileBytes {
+		content = content[:s.options.MaxFileBytes]
+		output.Truncated = true
+	}
+	output.Content = strings.ToValidUTF8(string(content), string(utf8.RuneError))
+	return output, nil
+}
+
+func (s *sandbox) write(input WriteFileInput) (WriteFileOutput, error) {
+	path, err := s.resolve(input.Path)
+	if err != nil {
+		return WriteFileOutput{}, err
+	}
+	if path == s.root {
+		return WriteFileOutput{}, fmt.Errorf("path is required")
+	}
+
+	var existing int64
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return WriteFileOutput{}, fmt.Errorf("%q is a directory", input.Path)
+		}
+		existing = info.Size()
+	}
+
+	size := int64(len(input.Content))
+	if input.Append {
+		size += existing
+	}
+	if size > s.options.MaxFileBytes {
+		return WriteFileOutput{}, fmt.Errorf("file would be %d bytes, exceeding the limit of %d", size, s.options.MaxFileBytes)
+	}
+
+	usage, err := s.usage()
+	if err != nil {
+		return WriteFileOutput{}, err
+	}
+	if usage-existing+size > s.options.MaxTotalBytes {
+		return WriteFileOutput{}, fmt.Errorf("write would exceed the storage quota of %d bytes (%d used)", s.options.MaxTotalBytes, usage)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return WriteFileOutput{}, fmt.Errorf("failed to create directory: %v", trimPathError(err))
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if input.Append {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return WriteFileOutput{}, fmt.Errorf("failed to write %q: %v", input.Path, trimPathError(err))
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(input.Content); err != nil {
+		return WriteFileOutput{}, fmt.Errorf("failed to write %q: %v", input.Path, trimPathError(err))
+	}
+
+	return WriteFileOutput{Size: size}, nil
+}
+
+// usage returns the total size of the files under the root.
+func (s *sandbox) usage() (int64, error) {
+	var total int64
+	err := filepath.WalkDir(s.root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to compute storage usage: %v", err)
+	}
+	return total, nil
+}
+
+// trimPathError drops the absolute path from filesystem errors, so the host layout isn't revealed to the agent.
+func trimPathError(err error) error {
+	if pathErr, ok := err.(*fs.PathError); ok {
+		return fmt.Errorf("%s: %v", pathErr.Op, pathErr.Err)
+	}
+	return err
+}
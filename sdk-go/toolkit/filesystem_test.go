@@ -0,0 +1,67 @@
+package toolkit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+func TestFilesystem(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(root, "escape")))
+
+	tools, err := Filesystem(FilesystemOptions{Root: root, MaxFileBytes: 10, MaxTotalBytes: 15})
+	require.NoError(t, err)
+	require.Len(t, tools, 3)
+
+	list := tools[0].Func.(func(ListFilesInput, inferable.ContextInput) (ListFilesOutput, error))
+	read := tools[1].Func.(func(ReadFileInput, inferable.ContextInput) (ReadFileOutput, error))
+	write := tools[2].Func.(func(WriteFileInput, inferable.ContextInput) (WriteFileOutput, error))
+	ctx := inferable.ContextInput{}
+
+	_, err = write(WriteFileInput{Path: "reports/a.txt", Content: "hello"}, ctx)
+	require.NoError(t, err)
+	output, err := write(WriteFileInput{Path: "reports/a.txt", Content: " you", Append: true}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), output.Size)
+
+	content, err := read(ReadFileInput{Path: "/reports/a.txt"}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "hello you", content.Content)
+
+	listing, err := list(ListFilesInput{Path: "reports"}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []FileEntry{{Name: "a.txt", Size: 9}}, listing.Entries)
+
+	// Size limits and quota
+	_, err = write(WriteFileInput{Path: "big.txt", Content: "01234567890"}, ctx)
+	assert.ErrorContains(t, err, "exceeding the limit")
+	_, err = write(WriteFileInput{Path: "b.txt", Content: "0123456789"}, ctx)
+	assert.ErrorContains(t, err, "quota")
+
+	// Traversal is confined to the root
+	_, err = read(ReadFileInput{Path: "../" + filepath.Base(outside) + "/secret.txt"}, ctx)
+	assert.Error(t, err)
+	_, err = read(ReadFileInput{Path: "escape/secret.txt"}, ctx)
+	assert.ErrorContains(t, err, "outside the root")
+	_, err = write(WriteFileInput{Path: "escape/new.txt", Content: "x"}, ctx)
+	assert.ErrorContains(t, err, "outside the root")
+
+	_, err = read(ReadFileInput{Path: "missing.txt"}, ctx)
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), root)
+
+	readOnly, err := Filesystem(FilesystemOptions{Root: root, ReadOnly: true})
+	require.NoError(t, err)
+	assert.Len(t, readOnly, 2)
+
+	_, err = Filesystem(FilesystemOptions{Root: filepath.Join(root, "missing")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,362 @@
+package toolkit
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// htmlToken is a tag or text run in an HTML document.
+type htmlToken struct {
+	// tag is the lowercase tag name, empty for text.
+	tag     string
+	closing bool
+	attrs   map[string]string
+	text    string
+}
+
+// rawTextTags have contents that are not markup and are dropped entirely.
+var rawTextTags = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true, "iframe": true, "head": true,
+}
+
+// boilerplateTags hold navigation and chrome rather than main content.
+var boilerplateTags = map[string]bool{
+	"nav": true, "header": true, "footer": true, "aside": true, "form": true, "button": true,
+}
+
+// blockTags start and end on their own line.
+var blockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true, "table": true, "tr": true,
+	"ul": true, "ol": true, "blockquote": true, "dl": true, "dt": true, "dd": true, "figure": true, "hr": true,
+}
+
+var (
+	htmlAttrPattern = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)\s*(?:=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	blankLines      = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLToMarkdown converts an HTML document into markdown suitable for an LLM.
+// Scripts, styles, and comments are removed, entities are decoded, and when the document has a
+// <main> or <article> element only its content is kept. Navigation, headers, footers, and forms
+// are dropped as boilerplate.
+func HTMLToMarkdown(document string) string {
+	tokens := tokenizeHTML(document)
+	tokens = mainContent(tokens)
+
+	w := &markdownWriter{}
+	skip := 0
+	for _, token := range tokens {
+		if token.tag != "" && boilerplateTags[token.tag] {
+			if token.closing {
+				if skip > 0 {
+					skip--
+				}
+			} else {
+				skip++
+			}
+			continue
+		}
+		if skip > 0 {
+			continue
+		}
+		w.write(token)
+	}
+
+	return w.String()
+}
+
+// tokenizeHTML splits a document into tags and text, dropping comments, doctypes, and raw text elements.
+func tokenizeHTML(document string) []htmlToken {
+	tokens := []htmlToken{}
+
+	for i := 0; i < len(document); {
+		if document[i] != '<' {
+			end := strings.IndexByte(document[i:], '<')
+			if end == -1 {
+				end = len(document) - i
+			}
+			tokens = append(tokens, htmlToken{text: html.UnescapeString(document[i : i+end])})
+			i += end
+			continue
+		}
+
+		if strings.HasPrefix(document[i:], "<!--") {
+			end := strings.Index(document[i+4:], "-->")
+			if end == -1 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+
+		end := strings.IndexByte(document[i:], '>')
+		if end == -1 {
+			// An unterminated tag is treated as text
+			tokens = append(tokens, htmlToken{text: html.UnescapeString(document[i:])})
+			break
+		}
+
+		raw := document[i+1 : i+end]
+		i += end + 1
+
+		if strings.HasPrefix(raw, "!") || strings.HasPrefix(raw, "?") {
+			continue
+		}
+
+		token := htmlToken{}
+		if strings.HasPrefix(raw, "/") {
+			token.closing = true
+			raw = raw[1:]
+		}
+		raw = strings.TrimSuffix(raw, "/")
+
+		name := raw
+		if space := strings.IndexAny(raw, " \t\r\n"); space != -1 {
+			name, raw = raw[:space], raw[space:]
+		} else {
+			raw = ""
+		}
+		token.tag = strings.ToLower(name)
+		if token.tag == "" {
+			tokens = append(tokens, htmlToken{text: "<"})
+			continue
+		}
+
+		if !token.closing && raw != "" {
+			token.attrs = map[string]string{}
+			for _, match := range htmlAttrPattern.FindAllStringSubmatch(raw, -1) {
+				token.attrs[strings.ToLower(match[1])] = html.UnescapeString(match[2] + match[3] + match[4])
+			}
+		}
+
+		if rawTextTags[token.tag] && !token.closing {
+			// Skip to the matching closing tag
+			closing := indexClosingTag(document[i:], token.tag)
+			if closing == -1 {
+				break
+			}
+			i += closing
+			if gt := strings.IndexByte(document[i:], '>'); gt != -1 {
+				i += gt + 1
+			} else {
+				i = len(document)
+			}
+			continue
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
+
+// indexClosingTag returns the index of the first closing tag for name in s, ignoring case, or -1.
+func indexClosingTag(s string, name string) int {
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], "</")
+		if i == -1 {
+			return -1
+		}
+		start := offset + i
+		if end := start + 2 + len(name); end <= len(s) && strings.EqualFold(s[start+2:end], name) {
+			return start
+		}
+		offset = start + 2
+	}
+}
+
+// mainContent narrows the tokens to the first <main> element, or else the first <article>, if present.
+func mainContent(tokens []htmlToken) []htmlToken {
+	for _, tag := range []string{"main", "article"} {
+		start := -1
+		depth := 0
+		for i, token := range tokens {
+			if token.tag != tag {
+				continue
+			}
+			if !token.closing {
+				if start == -1 {
+					start = i
+				}
+				depth++
+				continue
+			}
+			if start != -1 {
+				depth--
+				if depth == 0 {
+					return tokens[start : i+1]
+				}
+			}
+		}
+		if start != -1 {
+			return tokens[start:]
+		}
+	}
+	return tokens
+}
+
+// markdownWriter renders tokens as markdown.
+type markdownWriter struct {
+	out   []byte
+	pre   int
+	links []int
+	hrefs []string
+	lists []int
+}
+
+func (w *markdownWriter) newline(count int) {
+	if len(w.out) == 0 {
+		return
+	}
+	trailing := 0
+	for trailing < len(w.out) && w.out[len(w.out)-1-trailing] == '\n' {
+		trailing++
+	}
+	for ; trailing < count; trailing++ {
+		w.out = append(w.out, '\n')
+	}
+}
+
+func (w *markdownWriter) write(token htmlToken) {
+	if token.tag == "" {
+		w.writeText(token.text)
+		return
+	}
+
+	switch tag := token.tag; {
+	case len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6':
+		w.newline(2)
+		if !token.closing {
+			w.writeString(strings.Repeat("#", int(tag[1]-'0')) + " ")
+		}
+	case tag == "br":
+		w.writeString("\n")
+	case tag == "hr":
+		w.newline(2)
+		w.writeString("---")
+		w.newline(2)
+	case tag == "li":
+		if token.closing {
+			return
+		}
+		w.newline(1)
+		indent := ""
+		if len(w.lists) > 1 {
+			indent = strings.Repeat("  ", len(w.lists)-1)
+		}
+		if len(w.lists) > 0 && w.lists[len(w.lists)-1] > 0 {
+			w.writeString(fmt.Sprintf("%s%d. ", indent, w.lists[len(w.lists)-1]))
+			w.lists[len(w.lists)-1]++
+		} else {
+			w.writeString(indent + "- ")
+		}
+	case tag == "ul" || tag == "ol":
+		if token.closing {
+			if len(w.lists) > 0 {
+				w.lists = w.lists[:len(w.lists)-1]
+			}
+		} else if tag == "ol" {
+			w.lists = append(w.lists, 1)
+		} else {
+			w.lists = append(w.lists, 0)
+		}
+		w.newline(2)
+	case tag == "pre":
+		if token.closing {
+			if w.pre > 0 {
+				w.pre--
+			}
+			w.newline(1)
+			w.writeString("```")
+			w.newline(2)
+		} else {
+			w.pre++
+			w.newline(2)
+			w.writeString("```\n")
+		}
+	case tag == "code":
+		if w.pre == 0 {
+			w.writeString("`")
+		}
+	case tag == "strong" || tag == "b":
+		w.writeString("**")
+	case tag == "em" || tag == "i":
+		w.writeString("_")
+	case tag == "td" || tag == "th":
+		if !token.closing {
+			w.writeString(" | ")
+		}
+	case tag == "img":
+		if alt := strings.TrimSpace(token.attrs["alt"]); alt != "" {
+			w.writeString(fmt.Sprintf("![%s](%s)", alt, token.attrs["src"]))
+		}
+	case tag == "a":
+		if !token.closing {
+			w.links = append(w.links, len(w.out))
+			w.hrefs = append(w.hrefs, token.attrs["href"])
+			return
+		}
+		if len(w.links) == 0 {
+			return
+		}
+		start, href := w.links[len(w.links)-1], w.hrefs[len(w.hrefs)-1]
+		w.links, w.hrefs = w.links[:len(w.links)-1], w.hrefs[:len(w.hrefs)-1]
+
+		text := strings.TrimSpace(string(w.out[start:]))
+		if href == "" || text == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+			return
+		}
+		w.out = append(w.out[:start], fmt.Sprintf("[%s](%s)", text, href)...)
+	case blockTags[tag]:
+		w.newline(2)
+	}
+}
+
+func (w *markdownWriter) writeText(text string) {
+	if w.pre > 0 {
+		w.writeString(text)
+		return
+	}
+
+	collapsed := strings.Join(strings.Fields(text), " ")
+	if collapsed == "" {
+		if text != "" {
+			w.space()
+		}
+		return
+	}
+
+	if isSpace(text[0]) {
+		w.space()
+	}
+	w.writeString(collapsed)
+	if isSpace(text[len(text)-1]) {
+		w.space()
+	}
+}
+
+// space writes a single separating space, unless at the start of a line or after another space.
+func (w *markdownWriter) space() {
+	if n := len(w.out); n > 0 && w.out[n-1] != ' ' && w.out[n-1] != '\n' {
+		w.out = append(w.out, ' ')
+	}
+}
+
+func (w *markdownWriter) writeString(s string) {
+	w.out = append(w.out, s...)
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+// String returns the rendered markdown with trailing spaces and excess blank lines removed.
+func (w *markdownWriter) String() string {
+	lines := strings.Split(string(w.out), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,48 @@
+package toolkit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	document := `<!DOCTYPE html>
+<html>
+<head><title>Ignored</title><style>body { color: red; }</style></head>
+<body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<main>
+  <h1>Release   notes</h1>
+  <!-- <p>commented out</p> -->
+  <p>Version 2 &amp; friends are <strong>faster</strong> &mdash; see <a href="https://example.com/docs">the docs</a>.</p>
+  <script>if (a < b) { document.write("<p>nope</p>") }</SCRIPT>
+  <ul>
+    <li>First</li>
+    <li>Second<ol><li>Nested</li></ol></li>
+  </ul>
+  <pre>func main() {
+	fmt.Println("hi")
+}</pre>
+  <p>Line one<br>Line two</p>
+</main>
+<footer>Copyright</footer>
+</body>
+</html>`
+
+	assert.Equal(t, "# Release notes\n\n"+
+		"Version 2 & friends are **faster** — see [the docs](https://example.com/docs).\n\n"+
+		"- First\n"+
+		"- Second\n\n"+
+		"  1. Nested\n\n"+
+		"```\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```\n\n"+
+		"Line one\nLine two", HTMLToMarkdown(document))
+}
+
+func TestHTMLToMarkdownWithoutMainContent(t *testing.T) {
+	document := `<header>Site</header><div><h2>Title</h2><p>Body with <a href="#top">anchor</a> and <img src="a.png" alt="chart"></p></div>`
+
+	assert.Equal(t, "## Title\n\nBody with anchor and ![chart](a.png)", HTMLToMarkdown(document))
+	assert.Equal(t, "plain text", HTMLToMarkdown("plain text"))
+	assert.Equal(t, "1 < 2", HTMLToMarkdown("1 &lt; 2"))
+}
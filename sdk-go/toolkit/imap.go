@@ -0,0 +1,173 @@
+package toolkit
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapResponse is an untagged IMAP response line, with any literals it contained.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+// imapConn is a minimal IMAP4rev1 client covering what is needed to poll a mailbox:
+// LOGIN, SELECT, UID SEARCH, UID FETCH, UID STORE, and LOGOUT.
+type imapConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+func dialIMAP(options IMAPOptions) (*imapConn, error) {
+	dialer := &net.Dialer{Timeout: options.Timeout}
+
+	var conn net.Conn
+	var err error
+	if options.Insecure {
+		conn, err = dialer.Dial("tcp", options.Addr)
+	} else {
+		host, _, _ := net.SplitHostPort(options.Addr)
+		conn, err = tls.DialWithDialer(dialer, "tcp", options.Addr, &tls.Config{ServerName: host})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to IMAP server: %v", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(options.Timeout))
+
+	c := &imapConn{conn: conn, r: bufio.NewReader(conn)}
+
+	greeting, err := c.readLine()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read IMAP greeting: %v", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected IMAP greeting: %s", greeting)
+	}
+
+	if _, err := c.command("LOGIN %s %s", imapQuote(options.Username), imapQuote(options.Password)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("IMAP login failed: %v", err)
+	}
+	if _, err := c.command("SELECT %s", imapQuote(options.Mailbox)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to select mailbox %q: %v", options.Mailbox, err)
+	}
+
+	return c, nil
+}
+
+// command sends a command and returns its untagged responses, or an error if it did not complete with OK.
+func (c *imapConn) command(format string, args ...interface{}) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("A%d", c.tag)
+
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+
+	responses := []imapResponse{}
+	for {
+		response, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(response.line, tag+" ") {
+			status := strings.TrimPrefix(response.line, tag+" ")
+			if !strings.HasPrefix(status, "OK") {
+				return nil, fmt.Errorf("%s", status)
+			}
+			return responses, nil
+		}
+		responses = append(responses, response)
+	}
+}
+
+// readResponse reads a response line, following any {n} literals it announces.
+func (c *imapConn) readResponse() (imapResponse, error) {
+	response := imapResponse{}
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return response, err
+		}
+		response.line += line
+
+		open := strings.LastIndexByte(line, '{')
+		if open == -1 || !strings.HasSuffix(line, "}") {
+			return response, nil
+		}
+		size, err := strconv.Atoi(line[open+1 : len(line)-1])
+		if err != nil {
+			return response, nil
+		}
+
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return response, err
+		}
+		response.literals = append(response.literals, literal)
+	}
+}
+
+func (c *imapConn) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// searchUnseen returns the UIDs of unseen messages.
+func (c *imapConn) searchUnseen() ([]string, error) {
+	responses, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+
+	uids := []string{}
+	for _, response := range responses {
+		if strings.HasPrefix(response.line, "* SEARCH") {
+			uids = append(uids, strings.Fields(strings.TrimPrefix(response.line, "* SEARCH"))...)
+		}
+	}
+	return uids, nil
+}
+
+// fetch returns the raw RFC 5322 message with the UID, without marking it seen.
+func (c *imapConn) fetch(uid string) ([]byte, error) {
+	responses, err := c.command("UID FETCH %s (BODY.PEEK[])", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, response := range responses {
+		if strings.Contains(response.line, "FETCH") && len(response.literals) > 0 {
+			return response.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("message %s not found", uid)
+}
+
+func (c *imapConn) markSeen(uid string) error {
+	_, err := c.command(`UID STORE %s +FLAGS (\Seen)`, uid)
+	return err
+}
+
+func (c *imapConn) close() {
+	_, _ = c.command("LOGOUT")
+	c.conn.Close()
+}
+
+// imapQuote quotes a string argument.
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
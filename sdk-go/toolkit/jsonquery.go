@@ -0,0 +1,135 @@
+package toolkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+// JSONQueryInput is the input of the jsonQuery tool.
+type JSONQueryInput struct {
+	JSON string `json:"json" jsonschema:"description=JSON document to query"`
+	Path string `json:"path" jsonschema:"description=Path to select, e.g. orders[0].items[*].sku. Use [*] to select every array element"`
+}
+
+// JSONQueryOutput is the output of the jsonQuery tool.
+type JSONQueryOutput struct {
+	Result interface{} `json:"result"`
+}
+
+// JSONQuery returns a tool that selects values from a JSON document by path.
+func JSONQuery() inferable.WorkflowTool {
+	return inferable.WorkflowTool{
+		Name:        "jsonQuery",
+		Description: "Selects a value from a JSON document using a dotted path such as a.b[0].c",
+		InputSchema: JSONQueryInput{},
+		Func: func(input JSONQueryInput, ctx inferable.ContextInput) (JSONQueryOutput, error) {
+			var document interface{}
+			if err := json.Unmarshal([]byte(input.JSON), &document); err != nil {
+				return JSONQueryOutput{}, fmt.Errorf("invalid JSON: %v", err)
+			}
+
+			result, err := QueryJSON(document, input.Path)
+			if err != nil {
+				return JSONQueryOutput{}, err
+			}
+			return JSONQueryOutput{Result: result}, nil
+		},
+	}
+}
+
+// QueryJSON selects a value from a decoded JSON document. Paths are dot-separated keys with
+// optional [index] or [*] array selectors, e.g. "orders[*].id". An empty path selects the document.
+func QueryJSON(document interface{}, path string) (interface{}, error) {
+	segments, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return queryJSON(document, segments, "$")
+}
+
+func queryJSON(value interface{}, segments []string, at string) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+
+	segment, rest := segments[0], segments[1:]
+
+	if segment == "[*]" {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s is not an array", at)
+		}
+		results := make([]interface{}, 0, len(items))
+		for i, item := range items {
+			result, err := queryJSON(item, rest, fmt.Sprintf("%s[%d]", at, i))
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, result)
+		}
+		return results, nil
+	}
+
+	if strings.HasPrefix(segment, "[") {
+		items, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s is not an array", at)
+		}
+		index, _ := strconv.Atoi(segment[1 : len(segment)-1])
+		if index < 0 {
+			index += len(items)
+		}
+		if index < 0 || index >= len(items) {
+			return nil, fmt.Errorf("index %s out of range at %s (length %d)", segment, at, len(items))
+		}
+		return queryJSON(items[index], rest, at+segment)
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not an object", at)
+	}
+	child, ok := object[segment]
+	if !ok {
+		return nil, fmt.Errorf("key %q not found at %s", segment, at)
+	}
+	return queryJSON(child, rest, at+"."+segment)
+}
+
+// parseJSONPath splits a path like a.b[0][*].c into ["a", "b", "[0]", "[*]", "c"].
+func parseJSONPath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+
+	segments := []string{}
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			open := strings.Index(part, "[")
+			if open == -1 {
+				segments = append(segments, part)
+				break
+			}
+			if open > 0 {
+				segments = append(segments, part[:open])
+			}
+
+			end := strings.Index(part, "]")
+			if end < open {
+				return nil, fmt.Errorf("unclosed [ in path %q", path)
+			}
+
+			selector := part[open : end+1]
+			if selector != "[*]" {
+				if _, err := strconv.Atoi(selector[1 : len(selector)-1]); err != nil {
+					return nil, fmt.Errorf("invalid array selector %s in path %q", selector, path)
+				}
+			}
+			segments = append(segments, selector)
+			part = part[end+1:]
+		}
+	}
+	return segments, nil
+}
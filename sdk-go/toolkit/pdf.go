@@ -0,0 +1,283 @@
+package toolkit
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// maxPDFStreamBytes bounds the decompressed size of a single PDF stream.
+const maxPDFStreamBytes = 16 << 20
+
+var (
+	pdfStreamPattern = regexp.MustCompile(`stream\r?\n`)
+	pdfPagePattern   = regexp.MustCompile(`/Type\s*/Page\b`)
+	// pdfBinaryStreamPattern matches the dictionaries of image and embedded font streams
+	pdfBinaryStreamPattern = regexp.MustCompile(`/Subtype\s*/Image|/Length[123]\b`)
+)
+
+// PDFText extracts the text of a PDF, along with its page count, from the text drawing
+// operators of its uncompressed and Flate compressed content streams. Layout, images and
+// tables are not preserved, and text drawn with embedded font encodings, as in many PDFs
+// produced from scans or with subset CID fonts, is not recovered: ok is false when no readable
+// text was found.
+func PDFText(data []byte) (text string, pages int, ok bool) {
+	var out strings.Builder
+	pages = len(pdfPagePattern.FindAll(data, -1))
+
+	for _, loc := range pdfStreamPattern.FindAllIndex(data, -1) {
+		// The stream dictionary is between the object header and the stream keyword
+		header := data[:loc[0]]
+		if i := bytes.LastIndex(header, []byte("obj")); i != -1 {
+			header = header[i:]
+		}
+		end := bytes.Index(data[loc[1]:], []byte("endstream"))
+		if end == -1 {
+			// Truncated
+			end = len(data) - loc[1]
+		}
+		if pdfBinaryStreamPattern.Match(header) {
+			continue
+		}
+		content, ok := decodePDFStream(header, data[loc[1]:loc[1]+end])
+		if !ok {
+			continue
+		}
+
+		if bytes.Contains(header, []byte("/ObjStm")) {
+			// Page objects of PDF 1.5 and later are compressed in object streams
+			pages += len(pdfPagePattern.FindAll(content, -1))
+			continue
+		}
+		if bytes.Contains(content, []byte("BT")) {
+			extractPDFTextOperators(content, &out)
+		}
+	}
+
+	text = strings.TrimSpace(blankLines.ReplaceAllString(out.String(), "\n\n"))
+	return text, pages, readableText(text)
+}
+
+// decodePDFStream returns the content of a stream without a filter or with FlateDecode, which
+// almost all content streams use.
+func decodePDFStream(header []byte, raw []byte) ([]byte, bool) {
+	switch {
+	case !bytes.Contains(header, []byte("/Filter")):
+		return raw, true
+	case bytes.Contains(header, []byte("/FlateDecode")) && bytes.Count(header, []byte("Decode")) == 1:
+		reader, err := zlib.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, false
+		}
+		defer reader.Close()
+		// Truncated streams still yield the content decompressed so far
+		content, _ := io.ReadAll(io.LimitReader(reader, maxPDFStreamBytes))
+		return content, len(content) > 0
+	}
+	return nil, false
+}
+
+// extractPDFTextOperators appends the strings shown by the text operators of a content stream
+// (Tj, TJ, ' and "), starting new lines on line moves and at the end of text objects.
+func extractPDFTextOperators(content []byte, out *strings.Builder) {
+	var operands []string
+	var numbers []float64
+	var inArray bool
+	var array strings.Builder
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, next := readPDFLiteral(content, i)
+			if inArray {
+				array.WriteString(s)
+			} else {
+				operands = append(operands, s)
+			}
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end == -1 {
+				return
+			}
+			s := decodePDFHex(content[i+1 : i+end])
+			if inArray {
+				array.WriteString(s)
+			} else {
+				operands = append(operands, s)
+			}
+			i += end + 1
+		case c == '[':
+			inArray = true
+			array.Reset()
+			i++
+		case c == ']':
+			inArray = false
+			operands = append(operands, array.String())
+			i++
+		case c == '/':
+			// Names, such as fonts, are not shown
+			i++
+			for i < len(content) && !bytes.ContainsRune([]byte(" \t\r\n/[]()<>%"), rune(content[i])) {
+				i++
+			}
+		case c == '%':
+			// Comment to the end of the line
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			start := i
+			for i < len(content) && (content[i] == '-' || content[i] == '.' || (content[i] >= '0' && content[i] <= '9')) {
+				i++
+			}
+			n, err := strconv.ParseFloat(string(content[start:i]), 64)
+			if err != nil {
+				continue
+			}
+			if !inArray {
+				numbers = append(numbers, n)
+			} else if n < -200 {
+				// Wide negative kerning in a TJ array separates words
+				array.WriteByte(' ')
+			}
+		case unicode.IsLetter(rune(c)) || c == '\'' || c == '"' || c == '*':
+			start := i
+			for i < len(content) && (unicode.IsLetter(rune(content[i])) || content[i] == '\'' || content[i] == '"' || content[i] == '*') {
+				i++
+			}
+			switch string(content[start:i]) {
+			case "Tj", "TJ":
+				for _, s := range operands {
+					out.WriteString(s)
+				}
+			case "'", "\"":
+				out.WriteByte('\n')
+				for _, s := range operands {
+					out.WriteString(s)
+				}
+			case "Td", "TD":
+				// Moves along the same line separate words
+				if len(numbers) >= 2 && numbers[len(numbers)-1] == 0 {
+					out.WriteByte(' ')
+				} else {
+					out.WriteByte('\n')
+				}
+			case "T*", "Tm", "ET":
+				out.WriteByte('\n')
+			}
+			if !inArray {
+				operands, numbers = operands[:0], numbers[:0]
+			}
+		default:
+			i++
+		}
+	}
+}
+
+// readPDFLiteral reads the literal string starting at the '(' at start, and returns it with the
+// index after its closing parenthesis.
+func readPDFLiteral(content []byte, start int) (string, int) {
+	var s []byte
+	depth := 0
+	i := start
+	for ; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch e := content[i]; e {
+			case 'n':
+				s = append(s, '\n')
+			case 'r':
+				s = append(s, '\r')
+			case 't':
+				s = append(s, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					j := i
+					for j < len(content) && j < i+3 && content[j] >= '0' && content[j] <= '7' {
+						j++
+					}
+					n, _ := strconv.ParseUint(string(content[i:j]), 8, 8)
+					s = append(s, byte(n))
+					i = j - 1
+				} else {
+					s = append(s, e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				s = append(s, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return decodePDFString(s), i + 1
+			}
+			s = append(s, c)
+		default:
+			s = append(s, c)
+		}
+	}
+	return decodePDFString(s), i
+}
+
+// decodePDFHex decodes a hex string, whose odd final digit is followed by an implicit 0.
+func decodePDFHex(hex []byte) string {
+	digits := make([]byte, 0, len(hex)+1)
+	for _, c := range hex {
+		if unicode.Is(unicode.ASCII_Hex_Digit, rune(c)) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	s := make([]byte, len(digits)/2)
+	for i := range s {
+		n, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		s[i] = byte(n)
+	}
+	return decodePDFString(s)
+}
+
+// decodePDFString decodes a UTF-16BE string with a byte order mark, and otherwise treats the
+// bytes as Latin-1, which PDFDocEncoding and the standard encodings match for most text.
+func decodePDFString(s []byte) string {
+	if len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff {
+		units := make([]uint16, 0, len(s)/2)
+		for i := 2; i+1 < len(s); i += 2 {
+			units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(s))
+	for i, c := range s {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// readableText reports whether extracted text is mostly letters, digits, punctuation and
+// spaces, rather than glyph IDs of fonts with custom encodings.
+func readableText(text string) bool {
+	total, readable := 0, 0
+	for _, r := range text {
+		total++
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r) {
+			readable++
+		}
+	}
+	return total > 0 && readable*10 >= total*9
+}
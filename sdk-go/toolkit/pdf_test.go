@@ -0,0 +1,47 @@
+package toolkit
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPDF returns a one page PDF drawing the content stream, Flate compressed.
+func testPDF(t *testing.T, content string) []byte {
+	var stream bytes.Buffer
+	w := zlib.NewWriter(&stream)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.7\n")
+	pdf.WriteString("1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	pdf.WriteString("2 0 obj << /Type /Pages /Kids [3 0 R] /Count 1 >> endobj\n")
+	pdf.WriteString("3 0 obj << /Type /Page /Parent 2 0 R /Contents 4 0 R >> endobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj << /Length %d /Filter /FlateDecode >> stream\n", stream.Len())
+	pdf.Write(stream.Bytes())
+	pdf.WriteString("\nendstream endobj\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestPDFText(t *testing.T) {
+	text, pages, ok := PDFText(testPDF(t, `BT /F1 12 Tf 72 720 Td (Caf\351 \(open\)) Tj T* <FEFF00480069> Tj 40 0 Td (there) Tj ET`))
+	assert.True(t, ok)
+	assert.Equal(t, 1, pages)
+	assert.Equal(t, "Café (open)\nHi there", text)
+
+	// Glyph IDs of fonts with custom encodings are not text
+	_, _, ok = PDFText(testPDF(t, `BT /F1 12 Tf <0102030405060708> Tj ET`))
+	assert.False(t, ok)
+
+	// Truncated PDFs yield the text decompressed so far
+	pdf := testPDF(t, `BT (First line) Tj T* (Second line) Tj ET`)
+	text, _, ok = PDFText(pdf[:len(pdf)-30])
+	assert.True(t, ok)
+	assert.Contains(t, text, "First line")
+}
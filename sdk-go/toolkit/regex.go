@@ -0,0 +1,70 @@
+package toolkit
+
+import (
+	"fmt"
+	"regexp"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+// maxRegexMatches bounds the number of matches returned by the regexExtract tool.
+const maxRegexMatches = 100
+
+// RegexExtractInput is the input of the regexExtract tool.
+type RegexExtractInput struct {
+	Pattern string `json:"pattern" jsonschema:"description=RE2 regular expression. Named groups (?P<name>...) are returned by name"`
+	Text    string `json:"text" jsonschema:"description=Text to search"`
+}
+
+// RegexMatch is a single match of the regexExtract tool.
+type RegexMatch struct {
+	Match  string            `json:"match"`
+	Groups map[string]string `json:"groups,omitempty"`
+}
+
+// RegexExtractOutput is the output of the regexExtract tool.
+type RegexExtractOutput struct {
+	Matches   []RegexMatch `json:"matches"`
+	Truncated bool         `json:"truncated,omitempty"`
+}
+
+// RegexExtract returns a tool that extracts substrings from text with a regular expression.
+func RegexExtract() inferable.WorkflowTool {
+	return inferable.WorkflowTool{
+		Name:        "regexExtract",
+		Description: "Extracts all matches of a regular expression from text, including capture groups",
+		InputSchema: RegexExtractInput{},
+		Func: func(input RegexExtractInput, ctx inferable.ContextInput) (RegexExtractOutput, error) {
+			re, err := regexp.Compile(input.Pattern)
+			if err != nil {
+				return RegexExtractOutput{}, fmt.Errorf("invalid pattern: %v", err)
+			}
+
+			names := re.SubexpNames()
+			found := re.FindAllStringSubmatch(input.Text, maxRegexMatches+1)
+
+			output := RegexExtractOutput{Matches: []RegexMatch{}}
+			if len(found) > maxRegexMatches {
+				found = found[:maxRegexMatches]
+				output.Truncated = true
+			}
+
+			for _, submatches := range found {
+				match := RegexMatch{Match: submatches[0]}
+				for i := 1; i < len(submatches); i++ {
+					if match.Groups == nil {
+						match.Groups = map[string]string{}
+					}
+					name := names[i]
+					if name == "" {
+						name = fmt.Sprintf("%d", i)
+					}
+					match.Groups[name] = submatches[i]
+				}
+				output.Matches = append(output.Matches, match)
+			}
+
+			return output, nil
+		},
+	}
+}
@@ -0,0 +1,297 @@
+package toolkit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+const (
+	// DefaultShellTimeout bounds the duration of a command run by the shell tool.
+	DefaultShellTimeout = 30 * time.Second
+	// DefaultShellMaxOutputBytes bounds the stdout and stderr returned by the shell tool, each.
+	DefaultShellMaxOutputBytes = 64 << 10
+)
+
+var placeholderPattern = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// ShellCommand is a pre-approved command the shell tool may run.
+// Commands are executed directly, without a shell, so parameter values cannot inject further commands.
+type ShellCommand struct {
+	// Name identifies the command to the agent, e.g. "restartService".
+	Name string
+	// Description tells the agent what the command does.
+	Description string
+	// Path is the executable to run, e.g. "systemctl".
+	Path string
+	// Args are the arguments, which may contain {param} placeholders, e.g. []string{"restart", "{service}"}.
+	Args []string
+	// Params maps each placeholder to the regular expression its value must fully match, e.g. `[a-z-]+`.
+	Params map[string]string
+}
+
+// ShellOptions configures the shell tool.
+type ShellOptions struct {
+	// Name is the tool name. Defaults to "runCommand".
+	Name string
+	// Commands lists the commands that may be run. Required.
+	Commands []ShellCommand
+	// Dir is the working directory of the commands. Defaults to the current directory.
+	Dir string
+	// Env, if set, replaces the environment of the commands, e.g. []string{"PATH=/usr/bin"}.
+	Env []string
+	// Timeout bounds the duration of a command. Defaults to DefaultShellTimeout.
+	Timeout time.Duration
+	// MaxOutputBytes bounds the captured stdout and stderr, each. Defaults to DefaultShellMaxOutputBytes.
+	MaxOutputBytes int
+	// Logger receives an audit entry for every requested command, including rejected ones.
+	Logger inferable.Logger
+}
+
+// ShellInput is the input of the shell tool.
+type ShellInput struct {
+	Command string            `json:"command" jsonschema:"description=Name of the pre-approved command to run"`
+	Params  map[string]string `json:"params,omitempty" jsonschema:"description=Values for the command parameters"`
+}
+
+// ShellOutput is the output of the shell tool.
+type ShellOutput struct {
+	ExitCode  int    `json:"exitCode"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Truncated bool   `json:"truncated,omitempty"`
+	TimedOut  bool   `json:"timedOut,omitempty"`
+}
+
+type shellCommand struct {
+	ShellCommand
+	params map[string]*regexp.Regexp
+}
+
+// Shell returns a tool that runs pre-approved command templates with validated parameters.
+//
+//	tool, err := toolkit.Shell(toolkit.ShellOptions{
+//		Commands: []toolkit.ShellCommand{{
+//			Name:   "restartService",
+//			Path:   "systemctl",
+//			Args:   []string{"restart", "{service}"},
+//			Params: map[string]string{"service": "(api|worker)"},
+//		}},
+//		Logger: logger,
+//	})
+func Shell(options ShellOptions) (inferable.WorkflowTool, error) {
+	if len(options.Commands) == 0 {
+		return inferable.WorkflowTool{}, fmt.Errorf("shell tool requires at least one command")
+	}
+	if options.Name == "" {
+		options.Name = "runCommand"
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultShellTimeout
+	}
+	if options.MaxOutputBytes <= 0 {
+		options.MaxOutputBytes = DefaultShellMaxOutputBytes
+	}
+
+	commands := map[string]*shellCommand{}
+	descriptions := []string{}
+	for _, command := range options.Commands {
+		if command.Name == "" || command.Path == "" {
+			return inferable.WorkflowTool{}, fmt.Errorf("shell commands require a name and path")
+		}
+		if _, ok := commands[command.Name]; ok {
+			return inferable.WorkflowTool{}, fmt.Errorf("duplicate shell command %q", command.Name)
+		}
+
+		compiled := &shellCommand{ShellCommand: command, params: map[string]*regexp.Regexp{}}
+		for name, pattern := range command.Params {
+			if pattern == "" {
+				return inferable.WorkflowTool{}, fmt.Errorf("parameter %q of command %q requires a pattern", name, command.Name)
+			}
+			re, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return inferable.WorkflowTool{}, fmt.Errorf("invalid pattern for parameter %q of command %q: %v", name, command.Name, err)
+			}
+			compiled.params[name] = re
+		}
+		for _, arg := range command.Args {
+			for _, match := range placeholderPattern.FindAllStringSubmatch(arg, -1) {
+				if _, ok := compiled.params[match[1]]; !ok {
+					return inferable.WorkflowTool{}, fmt.Errorf("placeholder {%s} of command %q has no parameter pattern", match[1], command.Name)
+				}
+			}
+		}
+		commands[command.Name] = compiled
+
+		descriptions = append(descriptions, describeShellCommand(command))
+	}
+	sort.Strings(descriptions)
+
+	return inferable.WorkflowTool{
+		Name:        options.Name,
+		Description: "Runs one of these pre-approved commands: " + strings.Join(descriptions, "; "),
+		InputSchema: ShellInput{},
+		Func: func(input ShellInput, ctx inferable.ContextInput) (ShellOutput, error) {
+			command, ok := commands[input.Command]
+			if !ok {
+				err := fmt.Errorf("command %q is not allowed", input.Command)
+				auditShell(options.Logger, input, ctx, nil, ShellOutput{}, 0, err)
+				return ShellOutput{}, err
+			}
+
+			args, err := command.render(input.Params)
+			if err != nil {
+				auditShell(options.Logger, input, ctx, nil, ShellOutput{}, 0, err)
+				return ShellOutput{}, err
+			}
+
+			start := time.Now()
+			output, err := runShellCommand(command.Path, args, options)
+			auditShell(options.Logger, input, ctx, args, output, time.Since(start), err)
+			return output, err
+		},
+	}, nil
+}
+
+// render validates the parameters and substitutes them into the argument templates.
+func (c *shellCommand) render(params map[string]string) ([]string, error) {
+	for name, value := range params {
+		re, ok := c.params[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown parameter %q for command %q", name, c.Name)
+		}
+		if !re.MatchString(value) {
+			return nil, fmt.Errorf("parameter %q must match %s", name, c.Params[name])
+		}
+	}
+
+	args := make([]string, 0, len(c.Args))
+	var missing []string
+	for _, arg := range c.Args {
+		args = append(args, placeholderPattern.ReplaceAllStringFunc(arg, func(placeholder string) string {
+			name := placeholder[1 : len(placeholder)-1]
+			value, ok := params[name]
+			if !ok {
+				missing = append(missing, name)
+			}
+			return value
+		}))
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing parameters for command %q: %s", c.Name, strings.Join(missing, ", "))
+	}
+	return args, nil
+}
+
+func runShellCommand(path string, args []string, options ShellOptions) (ShellOutput, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), options.Timeout)
+	defer cancel()
+
+	stdout := &cappedBuffer{limit: options.MaxOutputBytes}
+	stderr := &cappedBuffer{limit: options.MaxOutputBytes}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Dir = options.Dir
+	cmd.Env = options.Env
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Don't wait forever on output pipes held open by orphaned child processes
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+
+	output := ShellOutput{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.truncated || stderr.truncated,
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		output.TimedOut = true
+		output.ExitCode = -1
+		return output, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// A failing command is a result the agent can act on, not a tool error
+		output.ExitCode = exitErr.ExitCode()
+		return output, nil
+	}
+	if err != nil {
+		return output, fmt.Errorf("failed to run command: %v", err)
+	}
+
+	return output, nil
+}
+
+func auditShell(logger inferable.Logger, input ShellInput, ctx inferable.ContextInput, args []string, output ShellOutput, duration time.Duration, err error) {
+	if logger == nil {
+		return
+	}
+
+	meta := map[string]interface{}{
+		"command":     input.Command,
+		"params":      input.Params,
+		"authContext": ctx.AuthContext,
+		"runContext":  ctx.RunContext,
+	}
+
+	if err != nil {
+		meta["error"] = err.Error()
+		logger.Error("Shell command rejected", meta)
+		return
+	}
+
+	meta["args"] = args
+	meta["exitCode"] = output.ExitCode
+	meta["timedOut"] = output.TimedOut
+	meta["durationMs"] = duration.Milliseconds()
+	logger.Info("Shell command executed", meta)
+}
+
+func describeShellCommand(command ShellCommand) string {
+	description := command.Name
+	if len(command.Params) > 0 {
+		names := make([]string, 0, len(command.Params))
+		for name := range command.Params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		description += fmt.Sprintf(" (params: %s)", strings.Join(names, ", "))
+	}
+	if command.Description != "" {
+		description += " - " + command.Description
+	}
+	return description
+}
+
+// cappedBuffer keeps the first limit bytes written to it and discards the rest.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining < len(p) {
+		b.truncated = true
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,93 @@
+package toolkit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+type testLogger struct {
+	info  []map[string]interface{}
+	error []map[string]interface{}
+}
+
+func (l *testLogger) Debug(message string, meta map[string]interface{}) {}
+
+func (l *testLogger) Info(message string, meta map[string]interface{}) {
+	l.info = append(l.info, meta)
+}
+
+func (l *testLogger) Warn(message string, meta map[string]interface{}) {}
+
+func (l *testLogger) Error(message string, meta map[string]interface{}) {
+	l.error = append(l.error, meta)
+}
+
+func TestShell(t *testing.T) {
+	logger := &testLogger{}
+	tool, err := Shell(ShellOptions{
+		Commands: []ShellCommand{
+			{Name: "greet", Path: "echo", Args: []string{"hello", "{name}"}, Params: map[string]string{"name": "[a-z]+"}},
+			{Name: "fail", Path: "sh", Args: []string{"-c", "echo oops >&2; exit 3"}},
+			{Name: "flood", Path: "sh", Args: []string{"-c", "yes | head -c 1000"}},
+			{Name: "sleep", Path: "sleep", Args: []string{"5"}},
+		},
+		Timeout:        100 * time.Millisecond,
+		MaxOutputBytes: 10,
+		Logger:         logger,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, tool.Description, "greet (params: name)")
+
+	run := tool.Func.(func(ShellInput, inferable.ContextInput) (ShellOutput, error))
+	ctx := inferable.ContextInput{}
+
+	output, err := run(ShellInput{Command: "greet", Params: map[string]string{"name": "bob"}}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, ShellOutput{Stdout: "hello bob\n"}, output)
+
+	_, err = run(ShellInput{Command: "greet", Params: map[string]string{"name": "bob; rm -rf /"}}, ctx)
+	assert.ErrorContains(t, err, "must match")
+	_, err = run(ShellInput{Command: "greet"}, ctx)
+	assert.ErrorContains(t, err, "missing parameters")
+	_, err = run(ShellInput{Command: "greet", Params: map[string]string{"name": "bob", "extra": "x"}}, ctx)
+	assert.ErrorContains(t, err, "unknown parameter")
+	_, err = run(ShellInput{Command: "rm"}, ctx)
+	assert.ErrorContains(t, err, "not allowed")
+
+	output, err = run(ShellInput{Command: "fail"}, ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, output.ExitCode)
+	assert.Equal(t, "oops\n", output.Stderr)
+
+	output, err = run(ShellInput{Command: "flood"}, ctx)
+	require.NoError(t, err)
+	assert.Len(t, output.Stdout, 10)
+	assert.True(t, output.Truncated)
+
+	output, err = run(ShellInput{Command: "sleep"}, ctx)
+	require.NoError(t, err)
+	assert.True(t, output.TimedOut)
+
+	assert.Len(t, logger.info, 4)
+	assert.Len(t, logger.error, 4)
+	assert.Equal(t, []string{"hello", "bob"}, logger.info[0]["args"])
+}
+
+func TestShellValidation(t *testing.T) {
+	_, err := Shell(ShellOptions{})
+	assert.Error(t, err)
+
+	_, err = Shell(ShellOptions{Commands: []ShellCommand{{Name: "a", Path: "echo", Args: []string{"{missing}"}}}})
+	assert.ErrorContains(t, err, "no parameter pattern")
+
+	_, err = Shell(ShellOptions{Commands: []ShellCommand{{Name: "a", Path: "echo"}, {Name: "a", Path: "echo"}}})
+	assert.ErrorContains(t, err, "duplicate")
+
+	_, err = Shell(ShellOptions{Commands: []ShellCommand{{Name: "a", Path: "echo", Params: map[string]string{"x": "("}}}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,373 @@
+package toolkit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+const (
+	// DefaultSQLMaxRows bounds the number of rows returned by the sqlQuery tool.
+	DefaultSQLMaxRows = 100
+	// DefaultSQLTimeout bounds the duration of a query.
+	DefaultSQLTimeout = 30 * time.Second
+)
+
+// sqlWriteKeywords are rejected anywhere in a query, outside string literals and quoted identifiers.
+var sqlWriteKeywords = map[string]bool{
+	"insert": true, "update": true, "delete": true, "merge": true, "upsert": true,
+	"create": true, "alter": true, "drop": true, "truncate": true, "rename": true,
+	"grant": true, "revoke": true, "attach": true, "detach": true, "pragma": true, "vacuum": true,
+	"copy": true, "call": true, "exec": true, "execute": true, "into": true, "lock": true, "set": true,
+}
+
+// SQLOptions configures the sqlQuery tool.
+type SQLOptions struct {
+	// DB is the database to query. If nil, a database is opened with DriverName and DSN.
+	DB *sql.DB
+	// DriverName is the registered database/sql driver, e.g. "postgres". Used with DSN when DB is nil.
+	DriverName string
+	// DSN is the data source name passed to sql.Open.
+	DSN string
+	// Name is the tool name. Defaults to "sqlQuery".
+	Name string
+	// Description tells the agent what the database contains, e.g. the tables and columns it may query.
+	Description string
+	// MaxRows bounds the number of rows returned. Defaults to DefaultSQLMaxRows.
+	MaxRows int
+	// AllowedColumns, if set, lists the columns (case-insensitive) a query may select, e.g. to keep
+	// PII out of agent context. Every select list of the query, including those of subqueries, may
+	// only name allowed columns, without aliases, expressions or *. Filters, joins and ordering
+	// may still use other columns, so their values can be inferred from the rows returned: grant
+	// the database user access to a view of the allowed columns where that matters.
+	AllowedColumns []string
+	// Timeout bounds the duration of a query. Defaults to DefaultSQLTimeout.
+	Timeout time.Duration
+}
+
+// SQLQueryInput is the input of the sqlQuery tool.
+type SQLQueryInput struct {
+	Query string        `json:"query" jsonschema:"description=A single read-only SELECT statement. Use placeholders for values instead of inlining them"`
+	Args  []interface{} `json:"args,omitempty" jsonschema:"description=Values for the query placeholders, in order"`
+}
+
+// SQLQueryOutput is the output of the sqlQuery tool.
+type SQLQueryOutput struct {
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	Truncated bool            `json:"truncated,omitempty"`
+}
+
+// SQLQuery returns a tool that runs read-only, parameterized queries.
+// Only a single SELECT (or WITH ... SELECT) statement is accepted, and it is executed inside a
+// read-only transaction that is always rolled back, so the database user should still be read-only
+// for defense in depth.
+//
+//	tool, err := toolkit.SQLQuery(toolkit.SQLOptions{
+//		DB:          db,
+//		Description: "Queries the orders table (id, status, total, created_at)",
+//	})
+//	workflow.Tools.Register(tool)
+func SQLQuery(options SQLOptions) (inferable.WorkflowTool, error) {
+	if options.DB == nil {
+		if options.DriverName == "" || options.DSN == "" {
+			return inferable.WorkflowTool{}, fmt.Errorf("sql tool requires a DB or a DriverName and DSN")
+		}
+		db, err := sql.Open(options.DriverName, options.DSN)
+		if err != nil {
+			return inferable.WorkflowTool{}, fmt.Errorf("failed to open database: %v", err)
+		}
+		options.DB = db
+	}
+
+	if options.Name == "" {
+		options.Name = "sqlQuery"
+	}
+	if options.MaxRows <= 0 {
+		options.MaxRows = DefaultSQLMaxRows
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultSQLTimeout
+	}
+
+	description := "Runs a read-only, parameterized SQL SELECT query and returns the rows"
+	if options.Description != "" {
+		description += ". " + options.Description
+	}
+	if len(options.AllowedColumns) > 0 {
+		description += fmt.Sprintf(". Only these columns may be selected, by name and without aliases, expressions or *: %s", strings.Join(options.AllowedColumns, ", "))
+	}
+
+	allowed := map[string]bool{}
+	for _, column := range options.AllowedColumns {
+		allowed[strings.ToLower(column)] = true
+	}
+
+	return inferable.WorkflowTool{
+		Name:        options.Name,
+		Description: description,
+		InputSchema: SQLQueryInput{},
+		Func: func(input SQLQueryInput, ctx inferable.ContextInput) (SQLQueryOutput, error) {
+			if err := checkReadOnlyQuery(input.Query); err != nil {
+				return SQLQueryOutput{}, err
+			}
+			if len(allowed) > 0 {
+				if err := checkSelectedColumns(input.Query, allowed); err != nil {
+					return SQLQueryOutput{}, err
+				}
+			}
+
+			queryCtx, cancel := context.WithTimeout(context.Background(), options.Timeout)
+			defer cancel()
+
+			return runReadOnlyQuery(queryCtx, options.DB, input, options.MaxRows, allowed)
+		},
+	}, nil
+}
+
+func runReadOnlyQuery(ctx context.Context, db *sql.DB, input SQLQueryInput, maxRows int, allowed map[string]bool) (SQLQueryOutput, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return SQLQueryOutput{}, fmt.Errorf("failed to begin read-only transaction: %v", err)
+	}
+	// Nothing is ever committed
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, input.Query, input.Args...)
+	if err != nil {
+		return SQLQueryOutput{}, fmt.Errorf("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return SQLQueryOutput{}, fmt.Errorf("failed to read columns: %v", err)
+	}
+	if len(allowed) > 0 {
+		for _, column := range columns {
+			if !allowed[strings.ToLower(column)] {
+				return SQLQueryOutput{}, fmt.Errorf("column %q is not allowed", column)
+			}
+		}
+	}
+
+	output := SQLQueryOutput{Columns: columns, Rows: [][]interface{}{}}
+	for rows.Next() {
+		if len(output.Rows) == maxRows {
+			output.Truncated = true
+			break
+		}
+
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return SQLQueryOutput{}, fmt.Errorf("failed to scan row: %v", err)
+		}
+
+		for i, value := range values {
+			if b, ok := value.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		output.Rows = append(output.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return SQLQueryOutput{}, fmt.Errorf("query failed: %v", err)
+	}
+
+	return output, nil
+}
+
+// sqlToken is a word, quoted identifier, string literal, number, or punctuation of a query.
+type sqlToken struct {
+	// kind is 'w' for words, 'q' for quoted identifiers, 's' for string literals, 'n' for numbers,
+	// and 'p' for punctuation.
+	kind rune
+	// text is lowercase for words and unquoted for quoted identifiers.
+	text string
+}
+
+// tokenizeSQL splits a query into tokens, dropping comments and whitespace, so that the contents of
+// strings, quoted identifiers and comments cannot disguise or trigger a match.
+func tokenizeSQL(query string) ([]sqlToken, error) {
+	tokens := []sqlToken{}
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\'' || r == '"' || r == '`':
+			// Read the quoted section, treating a doubled quote as an escape
+			var text []rune
+			for i++; i < len(runes); i++ {
+				if runes[i] == r {
+					if i+1 < len(runes) && runes[i+1] == r {
+						i++
+					} else {
+						break
+					}
+				}
+				text = append(text, runes[i])
+			}
+			kind := 'q'
+			if r == '\'' {
+				kind = 's'
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: string(text)})
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i+1 < len(runes) && (runes[i] != '*' || runes[i+1] != '/'); i++ {
+			}
+			if i+1 >= len(runes) {
+				return nil, fmt.Errorf("unterminated comment in query")
+			}
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: 'w', text: strings.ToLower(string(runes[start:i]))})
+			i--
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: 'n', text: string(runes[start:i])})
+			i--
+		case !unicode.IsSpace(r):
+			tokens = append(tokens, sqlToken{kind: 'p', text: string(r)})
+		}
+	}
+	return tokens, nil
+}
+
+// checkReadOnlyQuery rejects anything but a single SELECT statement.
+func checkReadOnlyQuery(query string) error {
+	tokens, err := tokenizeSQL(query)
+	if err != nil {
+		return err
+	}
+
+	words := []string{}
+	for i, token := range tokens {
+		switch {
+		case token.kind == 'p' && token.text == ";" && i != len(tokens)-1:
+			return fmt.Errorf("only a single statement is allowed")
+		case token.kind == 'w':
+			words = append(words, token.text)
+		}
+	}
+
+	if len(words) == 0 || (words[0] != "select" && words[0] != "with") {
+		return fmt.Errorf("only SELECT queries are allowed")
+	}
+	for _, word := range words {
+		if sqlWriteKeywords[word] {
+			return fmt.Errorf("query contains disallowed keyword %q: only read-only SELECT queries are allowed", strings.ToUpper(word))
+		}
+	}
+	return nil
+}
+
+// sqlSelectListEnd lists the keywords ending a select list without a FROM clause.
+var sqlSelectListEnd = map[string]bool{
+	"from": true, "where": true, "group": true, "having": true, "order": true, "limit": true,
+	"offset": true, "union": true, "intersect": true, "except": true, "window": true, "fetch": true,
+}
+
+// checkSelectedColumns rejects queries selecting anything but allowed columns, in every SELECT of
+// the query, including subqueries and common table expressions. Items of select lists must be
+// plain column references, optionally qualified with a table: aliases, expressions and * are
+// rejected, as they could return a column that is not allowed under an allowed name.
+func checkSelectedColumns(query string, allowed map[string]bool) error {
+	tokens, err := tokenizeSQL(query)
+	if err != nil {
+		return err
+	}
+
+	for i, token := range tokens {
+		if token.kind != 'w' || token.text != "select" {
+			continue
+		}
+
+		start := i + 1
+		if start < len(tokens) && tokens[start].kind == 'w' && (tokens[start].text == "distinct" || tokens[start].text == "all") {
+			start++
+		}
+
+		// The select list runs to the next clause at the same nesting depth
+		items := [][]sqlToken{{}}
+		depth := 0
+	list:
+		for _, t := range tokens[start:] {
+			switch {
+			case t.kind == 'p' && t.text == "(":
+				depth++
+			case t.kind == 'p' && t.text == ")":
+				if depth == 0 {
+					break list
+				}
+				depth--
+			case depth == 0 && t.kind == 'p' && t.text == ";":
+				break list
+			case depth == 0 && t.kind == 'w' && sqlSelectListEnd[t.text]:
+				break list
+			case depth == 0 && t.kind == 'p' && t.text == ",":
+				items = append(items, []sqlToken{})
+				continue
+			}
+			items[len(items)-1] = append(items[len(items)-1], t)
+		}
+
+		for _, item := range items {
+			column, ok := sqlColumnReference(item)
+			if !ok {
+				return fmt.Errorf("selected %q is not a column: only the allowed columns may be selected, without aliases or expressions", sqlText(item))
+			}
+			if !allowed[strings.ToLower(column)] {
+				return fmt.Errorf("column %q is not allowed", column)
+			}
+		}
+	}
+	return nil
+}
+
+// sqlColumnReference returns the column of a select list item that is a column reference, such as
+// status, orders.status or "orders"."status".
+func sqlColumnReference(item []sqlToken) (string, bool) {
+	if len(item) == 0 || len(item)%2 == 0 {
+		return "", false
+	}
+	for i, t := range item {
+		if i%2 == 1 {
+			if t.kind != 'p' || t.text != "." {
+				return "", false
+			}
+		} else if t.kind != 'w' && t.kind != 'q' {
+			return "", false
+		}
+	}
+	return item[len(item)-1].text, true
+}
+
+// sqlText formats tokens for error messages.
+func sqlText(tokens []sqlToken) string {
+	parts := make([]string, len(tokens))
+	for i, t := range tokens {
+		parts[i] = t.text
+	}
+	return strings.Join(parts, " ")
+}
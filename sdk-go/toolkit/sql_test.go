@@ -0,0 +1,157 @@
+package toolkit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+// testSQLDriver answers every query with the same rows and records what was executed.
+type testSQLDriver struct {
+	columns  []string
+	rows     [][]driver.Value
+	query    string
+	args     []driver.NamedValue
+	readOnly bool
+}
+
+func (d *testSQLDriver) Open(name string) (driver.Conn, error) { return &testSQLConn{d}, nil }
+
+// The driver is its own connector, so that tests open it without registering a driver name,
+// which can only be done once per process.
+func (d *testSQLDriver) Connect(context.Context) (driver.Conn, error) { return d.Open("") }
+func (d *testSQLDriver) Driver() driver.Driver                        { return d }
+
+type testSQLConn struct{ driver *testSQLDriver }
+
+func (c *testSQLConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *testSQLConn) Close() error                              { return nil }
+func (c *testSQLConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *testSQLConn) Commit() error                             { return nil }
+func (c *testSQLConn) Rollback() error                           { return nil }
+
+func (c *testSQLConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.driver.readOnly = opts.ReadOnly
+	return c, nil
+}
+
+func (c *testSQLConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.query, c.driver.args = query, args
+	return &testSQLRows{driver: c.driver}, nil
+}
+
+type testSQLRows struct {
+	driver *testSQLDriver
+	next   int
+}
+
+func (r *testSQLRows) Columns() []string { return r.driver.columns }
+func (r *testSQLRows) Close() error      { return nil }
+
+func (r *testSQLRows) Next(dest []driver.Value) error {
+	if r.next == len(r.driver.rows) {
+		return io.EOF
+	}
+	copy(dest, r.driver.rows[r.next])
+	r.next++
+	return nil
+}
+
+func TestSQLQuery(t *testing.T) {
+	d := &testSQLDriver{
+		columns: []string{"id", "status"},
+		rows:    [][]driver.Value{{int64(1), []byte("paid")}, {int64(2), "open"}, {int64(3), "open"}},
+	}
+	db := sql.OpenDB(d)
+
+	tool, err := SQLQuery(SQLOptions{DB: db, MaxRows: 2, AllowedColumns: []string{"ID", "status"}})
+	require.NoError(t, err)
+	query := tool.Func.(func(SQLQueryInput, inferable.ContextInput) (SQLQueryOutput, error))
+
+	output, err := query(SQLQueryInput{Query: "SELECT id, status FROM orders WHERE status = $1", Args: []interface{}{"open"}}, inferable.ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, SQLQueryOutput{
+		Columns:   []string{"id", "status"},
+		Rows:      [][]interface{}{{int64(1), "paid"}, {int64(2), "open"}},
+		Truncated: true,
+	}, output)
+	assert.True(t, d.readOnly)
+	assert.Equal(t, "open", d.args[0].Value)
+
+	_, err = query(SQLQueryInput{Query: "SELECT id, email FROM orders"}, inferable.ContextInput{})
+	assert.ErrorContains(t, err, `column "email" is not allowed`)
+
+	// Result columns are checked too, e.g. for a view renaming a column
+	d.columns = []string{"id", "email"}
+	_, err = query(SQLQueryInput{Query: "SELECT id, status FROM orders"}, inferable.ContextInput{})
+	assert.ErrorContains(t, err, `column "email" is not allowed`)
+
+	_, err = query(SQLQueryInput{Query: "DELETE FROM orders"}, inferable.ContextInput{})
+	assert.Error(t, err)
+
+	_, err = SQLQuery(SQLOptions{})
+	assert.Error(t, err)
+}
+
+func TestCheckSelectedColumns(t *testing.T) {
+	allowed := map[string]bool{"id": true, "status": true}
+
+	for _, query := range []string{
+		"SELECT id, status FROM orders WHERE email = 'a@example.com'",
+		`SELECT DISTINCT orders.id, "orders"."Status" FROM orders`,
+		"SELECT id FROM orders WHERE id IN (SELECT id FROM refunds) ORDER BY status",
+		"SELECT id FROM orders UNION SELECT id FROM refunds",
+	} {
+		assert.NoError(t, checkSelectedColumns(query, allowed), query)
+	}
+
+	for _, query := range []string{
+		// Aliases of other columns
+		"SELECT ssn AS id FROM users",
+		"SELECT ssn id FROM users",
+		"SELECT id, lower(ssn) AS status FROM users",
+		"SELECT id || ssn FROM users",
+		"SELECT * FROM users",
+		"SELECT users.* FROM users",
+		// Other columns in subqueries and common table expressions
+		"WITH u AS (SELECT ssn AS id FROM users) SELECT id FROM u",
+		"SELECT id FROM (SELECT ssn AS id FROM users) u",
+		"SELECT id FROM orders UNION SELECT ssn FROM users",
+		"SELECT (SELECT ssn FROM users LIMIT 1) FROM orders",
+	} {
+		assert.Error(t, checkSelectedColumns(query, allowed), query)
+	}
+}
+
+func TestCheckReadOnlyQuery(t *testing.T) {
+	for _, query := range []string{
+		"SELECT * FROM orders",
+		"  select id from orders where note = 'please delete me';",
+		`WITH recent AS (SELECT * FROM orders) SELECT "update" FROM recent`,
+		"SELECT id -- drop table\nFROM orders",
+		"SELECT /* insert */ id FROM orders",
+		"SELECT 'it''s; drop' FROM orders",
+	} {
+		assert.NoError(t, checkReadOnlyQuery(query), query)
+	}
+
+	for _, query := range []string{
+		"",
+		"DROP TABLE orders",
+		"SELECT 1; DROP TABLE orders",
+		"WITH gone AS (DELETE FROM orders RETURNING *) SELECT * FROM gone",
+		"SELECT * INTO backup FROM orders",
+		"SELECT * FROM orders FOR UPDATE",
+		"EXPLAIN ANALYZE DELETE FROM orders",
+		"SELECT /* unterminated",
+	} {
+		assert.Error(t, checkReadOnlyQuery(query), query)
+	}
+}
@@ -0,0 +1,97 @@
+package toolkit
+
+import (
+	"fmt"
+	"time"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+// CurrentTimeInput is the input of the currentTime tool.
+type CurrentTimeInput struct {
+	Timezone string `json:"timezone,omitempty" jsonschema:"description=IANA timezone, e.g. Europe/London. Defaults to UTC"`
+}
+
+// TimeOutput describes a point in time in a timezone.
+type TimeOutput struct {
+	Time     string `json:"time"`
+	Timezone string `json:"timezone"`
+	Weekday  string `json:"weekday"`
+	Unix     int64  `json:"unix"`
+}
+
+// ConvertTimezoneInput is the input of the convertTimezone tool.
+type ConvertTimezoneInput struct {
+	Time string `json:"time" jsonschema:"description=RFC 3339 time, e.g. 2024-01-02T15:04:05Z or 2024-01-02T15:04:05 (interpreted in from)"`
+	From string `json:"from,omitempty" jsonschema:"description=IANA timezone of a time without an offset. Defaults to UTC"`
+	To   string `json:"to" jsonschema:"description=IANA timezone to convert to, e.g. America/New_York"`
+}
+
+// now is replaced in tests.
+var now = time.Now
+
+// CurrentTime returns a tool that reports the current time in a timezone.
+func CurrentTime() inferable.WorkflowTool {
+	return inferable.WorkflowTool{
+		Name:        "currentTime",
+		Description: "Returns the current date and time in the given timezone",
+		InputSchema: CurrentTimeInput{},
+		Func: func(input CurrentTimeInput, ctx inferable.ContextInput) (TimeOutput, error) {
+			location, err := loadLocation(input.Timezone)
+			if err != nil {
+				return TimeOutput{}, err
+			}
+			return describeTime(now().In(location)), nil
+		},
+	}
+}
+
+// ConvertTimezone returns a tool that converts a time between timezones.
+func ConvertTimezone() inferable.WorkflowTool {
+	return inferable.WorkflowTool{
+		Name:        "convertTimezone",
+		Description: "Converts a date and time from one timezone to another",
+		InputSchema: ConvertTimezoneInput{},
+		Func: func(input ConvertTimezoneInput, ctx inferable.ContextInput) (TimeOutput, error) {
+			from, err := loadLocation(input.From)
+			if err != nil {
+				return TimeOutput{}, err
+			}
+			to, err := loadLocation(input.To)
+			if err != nil {
+				return TimeOutput{}, err
+			}
+
+			t, err := time.Parse(time.RFC3339, input.Time)
+			if err != nil {
+				// Fall back to a local time in the source timezone
+				t, err = time.ParseInLocation("2006-01-02T15:04:05", input.Time, from)
+				if err != nil {
+					return TimeOutput{}, fmt.Errorf("time must be in RFC 3339 format, e.g. 2024-01-02T15:04:05Z")
+				}
+			}
+
+			return describeTime(t.In(to)), nil
+		},
+	}
+}
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q, expected an IANA name such as Europe/London", name)
+	}
+	return location, nil
+}
+
+func describeTime(t time.Time) TimeOutput {
+	return TimeOutput{
+		Time:     t.Format(time.RFC3339),
+		Timezone: t.Location().String(),
+		Weekday:  t.Weekday().String(),
+		Unix:     t.Unix(),
+	}
+}
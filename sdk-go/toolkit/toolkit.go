@@ -0,0 +1,34 @@
+// Package toolkit provides a standard library of tools that can be registered on a workflow,
+// giving agents common capabilities without bespoke implementations.
+//
+//	workflow := client.Workflows.Create(inferable.WorkflowConfig{...})
+//
+//	toolkit.Register(workflow.Tools, toolkit.Options{
+//		Fetch: toolkit.FetchOptions{AllowedHosts: []string{"api.example.com"}},
+//	})
+package toolkit
+
+import (
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+// Options configures the standard tools registered by Register.
+type Options struct {
+	// Fetch configures the httpFetch tool, which is only registered when at least one host is allowed.
+	Fetch FetchOptions
+}
+
+// Register registers the standard tools on a workflow: calculate, currentTime, convertTimezone,
+// jsonQuery, regexExtract, and (when hosts are allowed) httpFetch.
+// Use the individual constructors, e.g. Calculator(), to register a subset.
+func Register(tools *inferable.WorkflowTools, options Options) {
+	tools.Register(Calculator())
+	tools.Register(CurrentTime())
+	tools.Register(ConvertTimezone())
+	tools.Register(JSONQuery())
+	tools.Register(RegexExtract())
+
+	if len(options.Fetch.AllowedHosts) > 0 {
+		tools.Register(HTTPFetch(options.Fetch))
+	}
+}
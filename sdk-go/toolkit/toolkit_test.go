@@ -0,0 +1,128 @@
+package toolkit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	inferable "github.com/inferablehq/inferable/sdk-go"
+)
+
+func TestEvaluate(t *testing.T) {
+	cases := map[string]float64{
+		"1 + 2 * 3":          7,
+		"(1 + 2) * 3":        9,
+		"2 ^ 3 ^ 2":          512,
+		"-2 ^ 2":             -4,
+		"10 % 4":             2,
+		"sqrt(16) + abs(-3)": 7,
+		"round(2.5 * pi)":    8,
+		"1.5 / 0.5":          3,
+	}
+	for expression, expected := range cases {
+		result, err := Evaluate(expression)
+		require.NoError(t, err, expression)
+		assert.InDelta(t, expected, result, 1e-9, expression)
+	}
+
+	for _, expression := range []string{"", "1 +", "(1 + 2", "1 / 0", "foo(1)", "1 2", "sqrt(-1)"} {
+		_, err := Evaluate(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestQueryJSON(t *testing.T) {
+	var document interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"orders": [{"id": "a", "items": [{"sku": 1}, {"sku": 2}]}, {"id": "b", "items": []}]}`), &document))
+
+	result, err := QueryJSON(document, "orders[0].items[1].sku")
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, result)
+
+	result, err = QueryJSON(document, "$.orders[*].id")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", "b"}, result)
+
+	result, err = QueryJSON(document, "orders[-1].id")
+	require.NoError(t, err)
+	assert.Equal(t, "b", result)
+
+	result, err = QueryJSON(document, "")
+	require.NoError(t, err)
+	assert.Equal(t, document, result)
+
+	_, err = QueryJSON(document, "orders[5]")
+	assert.ErrorContains(t, err, "out of range")
+
+	_, err = QueryJSON(document, "orders[0].missing")
+	assert.ErrorContains(t, err, `key "missing" not found at $.orders[0]`)
+
+	_, err = QueryJSON(document, "orders[x]")
+	assert.Error(t, err)
+}
+
+func TestRegexExtract(t *testing.T) {
+	fn := RegexExtract().Func.(func(RegexExtractInput, inferable.ContextInput) (RegexExtractOutput, error))
+
+	output, err := fn(RegexExtractInput{
+		Pattern: `(?P<key>\w+)=(\d+)`,
+		Text:    "a=1, b=22",
+	}, inferable.ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, []RegexMatch{
+		{Match: "a=1", Groups: map[string]string{"key": "a", "2": "1"}},
+		{Match: "b=22", Groups: map[string]string{"key": "b", "2": "22"}},
+	}, output.Matches)
+	assert.False(t, output.Truncated)
+
+	_, err = fn(RegexExtractInput{Pattern: "("}, inferable.ContextInput{})
+	assert.Error(t, err)
+}
+
+func TestTimeTools(t *testing.T) {
+	now = func() time.Time { return time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+
+	current := CurrentTime().Func.(func(CurrentTimeInput, inferable.ContextInput) (TimeOutput, error))
+
+	output, err := current(CurrentTimeInput{}, inferable.ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-02T15:04:05Z", output.Time)
+	assert.Equal(t, "Tuesday", output.Weekday)
+
+	output, err = current(CurrentTimeInput{Timezone: "Asia/Tokyo"}, inferable.ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-03T00:04:05+09:00", output.Time)
+
+	_, err = current(CurrentTimeInput{Timezone: "Mars/Olympus"}, inferable.ContextInput{})
+	assert.Error(t, err)
+
+	convert := ConvertTimezone().Func.(func(ConvertTimezoneInput, inferable.ContextInput) (TimeOutput, error))
+
+	output, err = convert(ConvertTimezoneInput{Time: "2024-07-01T12:00:00Z", To: "America/New_York"}, inferable.ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-07-01T08:00:00-04:00", output.Time)
+
+	output, err = convert(ConvertTimezoneInput{Time: "2024-07-01T12:00:00", From: "Europe/London", To: "UTC"}, inferable.ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-07-01T11:00:00Z", output.Time)
+}
+
+func TestRegister(t *testing.T) {
+	client, err := inferable.New(inferable.InferableOptions{APIEndpoint: inferable.DefaultAPIEndpoint, APISecret: "test-secret"})
+	require.NoError(t, err)
+
+	workflow := client.Workflows.Create(inferable.WorkflowConfig{
+		Name: "toolkit",
+		InputSchema: struct {
+			ExecutionId string `json:"executionId"`
+		}{},
+	})
+
+	assert.NotPanics(t, func() {
+		Register(workflow.Tools, Options{Fetch: FetchOptions{AllowedHosts: []string{"api.example.com"}}})
+	})
+}
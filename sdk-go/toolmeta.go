@@ -0,0 +1,143 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+// Capabilities of a tool, declared in its registration config and matched against the
+// Capabilities of an agent run.
+const (
+	// ToolCapabilityRead is for tools that only read state.
+	ToolCapabilityRead = "read"
+	// ToolCapabilityMutate is for tools that change state. Tools that do not declare a capability
+	// are treated as mutating.
+	ToolCapabilityMutate = "mutate"
+)
+
+// ToolExample is an example invocation of a tool, shown to the model alongside the tool's
+// description.
+type ToolExample struct {
+	// Description says what the example does, e.g. "Look up an order by ID".
+	Description string `json:"description,omitempty"`
+	// Input is the input of the call. It must decode into the tool's input type.
+	Input interface{} `json:"input"`
+}
+
+// ToolInfo describes a tool registered in the cluster, as listed by Catalog.
+type ToolInfo struct {
+	Name        string
+	Description string
+	Schema      string
+	Tags        []string
+	Examples    []ToolExample
+	UsageHints  string
+}
+
+// validateToolCapability checks that a tool declares a known capability, if any.
+func validateToolCapability(fn Tool) error {
+	switch fn.Capability {
+	case "", ToolCapabilityRead, ToolCapabilityMutate:
+		return nil
+	}
+	return fmt.Errorf("tool '%s' has unknown capability %q, use ToolCapabilityRead or ToolCapabilityMutate", fn.Name, fn.Capability)
+}
+
+// validateToolExamples checks that each example of a tool decodes into its input type.
+func validateToolExamples(fn Tool) error {
+	if len(fn.Examples) == 0 {
+		return nil
+	}
+	inputType := reflect.TypeOf(fn.Func).In(0)
+	if inputType.Kind() == reflect.Ptr {
+		inputType = inputType.Elem()
+	}
+	for n, example := range fn.Examples {
+		if err := decodeToolInput(example.Input, reflect.New(inputType).Interface(), false); err != nil {
+			return fmt.Errorf("example %d of tool '%s' does not match its input: %v", n, fn.Name, err)
+		}
+	}
+	return nil
+}
+
+// addToolMetadata adds a tool's tags, examples, usage hints and capability to its registration
+// config.
+func addToolMetadata(config map[string]interface{}, fn Tool) {
+	if len(fn.Tags) > 0 {
+		config["tags"] = fn.Tags
+	}
+	if len(fn.Examples) > 0 {
+		config["examples"] = fn.Examples
+	}
+	if fn.UsageHints != "" {
+		config["usageHints"] = fn.UsageHints
+	}
+	if fn.Capability != "" {
+		config["capability"] = fn.Capability
+	}
+}
+
+// Catalog lists the tools registered in the cluster with their metadata, sorted by name. With a
+// tag, only the tools carrying it are listed.
+func (s *pollingAgent) Catalog(tag ...string) ([]ToolInfo, error) {
+	clusterId, err := s.inferable.getClusterId()
+	if err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/clusters/%s/tools", clusterId)
+	if len(tag) > 0 && tag[0] != "" {
+		path += "?tag=" + url.QueryEscape(tag[0])
+	}
+
+	result, _, err, status := s.inferable.client.FetchData(client.FetchDataOptions{
+		Path:       path,
+		Method:     "GET",
+		Idempotent: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster tools: %w", err)
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("failed to list cluster tools: %w", client.UnexpectedStatus(status))
+	}
+
+	var tools []struct {
+		Name        string  `json:"name"`
+		Description *string `json:"description"`
+		Schema      *string `json:"schema"`
+		Config      *struct {
+			Tags       []string      `json:"tags"`
+			Examples   []ToolExample `json:"examples"`
+			UsageHints string        `json:"usageHints"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(result), &tools); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cluster tools: %v", err)
+	}
+
+	catalog := make([]ToolInfo, 0, len(tools))
+	for _, tool := range tools {
+		info := ToolInfo{Name: tool.Name}
+		if tool.Description != nil {
+			info.Description = *tool.Description
+		}
+		if tool.Schema != nil {
+			info.Schema = *tool.Schema
+		}
+		if tool.Config != nil {
+			info.Tags = tool.Config.Tags
+			info.Examples = tool.Config.Examples
+			info.UsageHints = tool.Config.UsageHints
+		}
+		catalog = append(catalog, info)
+	}
+	sort.Slice(catalog, func(a, b int) bool { return catalog[a].Name < catalog[b].Name })
+
+	return catalog, nil
+}
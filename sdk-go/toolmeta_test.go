@@ -0,0 +1,107 @@
+package inferable
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolMetadata(t *testing.T) {
+	i := newTestClient(t, InferableOptions{}, nil)
+
+	charge := func(input chargeInput, ctx ContextInput) (int, error) { return input.Amount, nil }
+	err := i.Tools.Register(Tool{
+		Name:       "charge",
+		Func:       charge,
+		Tags:       []string{"billing"},
+		UsageHints: "Only charge after the customer confirmed the amount",
+		Examples:   []ToolExample{{Description: "Charge 1 EUR", Input: map[string]interface{}{"amount": 100}}},
+		Capability: ToolCapabilityMutate,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"delivery":   DeliveryAtLeastOnce,
+		"tags":       []string{"billing"},
+		"usageHints": "Only charge after the customer confirmed the amount",
+		"examples":   []ToolExample{{Description: "Charge 1 EUR", Input: map[string]interface{}{"amount": 100}}},
+		"capability": ToolCapabilityMutate,
+	}, registrationConfig(i.Tools.Tools["charge"]))
+
+	err = i.Tools.Register(Tool{Name: "lookup", Func: charge, Capability: "write"})
+	assert.ErrorContains(t, err, `tool 'lookup' has unknown capability "write"`)
+
+	// Examples must match the input type
+	err = i.Tools.Register(Tool{
+		Name:     "refund",
+		Func:     charge,
+		Examples: []ToolExample{{Input: map[string]interface{}{"amount": "a lot"}}},
+	})
+	assert.ErrorContains(t, err, "example 0 of tool 'refund' does not match its input")
+}
+
+func TestAgentToolScopes(t *testing.T) {
+	runs := make(chan map[string]interface{}, 1)
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/runs": func(w http.ResponseWriter, r *http.Request) {
+			var payload map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			runs <- payload
+			w.WriteHeader(http.StatusBadRequest)
+		},
+	})
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "tickets", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		_, _, err := ctx.Agents.React(ReactAgentConfig{
+			Name:         "triage",
+			Tools:        []string{"getOrder", "refundOrder"},
+			DeniedTools:  []string{"refundOrder"},
+			Capabilities: []string{ToolCapabilityRead},
+		})
+		return nil, err
+	})
+
+	_, err := workflow.Execute(1, WorkflowInput{ExecutionID: "exec-1"}, ContextInput{})
+	assert.ErrorContains(t, err, "failed to create run")
+
+	payload := <-runs
+	assert.Equal(t, []interface{}{"tool_tickets_getOrder", "tool_tickets_refundOrder"}, payload["tools"])
+	assert.Equal(t, []interface{}{"tool_tickets_refundOrder"}, payload["deniedTools"])
+	assert.Equal(t, []interface{}{ToolCapabilityRead}, payload["toolCapabilities"])
+}
+
+func TestToolCatalog(t *testing.T) {
+	var query string
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/tools": func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.RawQuery
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"name": "refund", "description": nil, "schema": nil, "config": nil},
+				{"name": "charge", "description": "Charge a card", "schema": "{}", "config": map[string]interface{}{
+					"tags":       []string{"billing"},
+					"usageHints": "Confirm the amount first",
+					"examples":   []map[string]interface{}{{"input": map[string]interface{}{"amount": 100}}},
+				}},
+			})
+		},
+	})
+
+	catalog, err := i.Tools.Catalog("billing")
+	require.NoError(t, err)
+	assert.Equal(t, "tag=billing", query)
+	assert.Equal(t, []ToolInfo{
+		{
+			Name:        "charge",
+			Description: "Charge a card",
+			Schema:      "{}",
+			Tags:        []string{"billing"},
+			Examples:    []ToolExample{{Input: map[string]interface{}{"amount": 100.0}}},
+			UsageHints:  "Confirm the amount first",
+		},
+		{Name: "refund"},
+	}, catalog)
+}
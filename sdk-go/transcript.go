@@ -0,0 +1,194 @@
+package inferable
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+// TranscriptFormat is a format Agent.ExportTranscript writes a run's messages in.
+type TranscriptFormat string
+
+const (
+	// TranscriptJSONL writes one message per line, as the cluster records it: its id, type,
+	// createdAt and data.
+	TranscriptJSONL TranscriptFormat = "jsonl"
+	// TranscriptOpenAI writes a JSON array of OpenAI chat messages, with the agent's tool calls
+	// and their results as tool_calls and tool messages, e.g. for fine-tuning datasets.
+	TranscriptOpenAI TranscriptFormat = "openai"
+)
+
+// transcriptPageSize is the most messages the cluster returns per request.
+const transcriptPageSize = 50
+
+// runMessage is a message of an agent run, as listed by the cluster.
+type runMessage struct {
+	ID   string         `json:"id"`
+	Type string         `json:"type"`
+	Data runMessageData `json:"data"`
+	// raw is the message as the cluster returned it.
+	raw json.RawMessage
+}
+
+type runMessageData struct {
+	// ID identifies the tool call an invocation-result message answers.
+	ID          string      `json:"id"`
+	Message     string      `json:"message"`
+	Result      interface{} `json:"result"`
+	ResultType  string      `json:"resultType"`
+	ToolName    string      `json:"toolName"`
+	Invocations []struct {
+		ID       string      `json:"id"`
+		ToolName string      `json:"toolName"`
+		Input    interface{} `json:"input"`
+	} `json:"invocations"`
+}
+
+// listRunMessages returns all the messages of a run, following pages until the last.
+func listRunMessages(c *client.Client, clusterId string, runId string) ([]runMessage, error) {
+	messages := []runMessage{}
+	after := "0"
+	for {
+		result, _, err, status := c.FetchData(client.FetchDataOptions{
+			Path:        fmt.Sprintf("/clusters/%s/runs/%s/messages", clusterId, runId),
+			Method:      "GET",
+			QueryParams: map[string]string{"after": after, "limit": fmt.Sprint(transcriptPageSize)},
+			Idempotent:  true,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get messages of run %s: %w", runId, err)
+		}
+		if status != 200 {
+			return nil, fmt.Errorf("failed to get messages of run %s: %w", runId, client.UnexpectedStatus(status))
+		}
+
+		var page []json.RawMessage
+		if err := json.Unmarshal([]byte(result), &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal messages of run %s: %v", runId, err)
+		}
+		for _, raw := range page {
+			message := runMessage{raw: raw}
+			if err := json.Unmarshal(raw, &message); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal messages of run %s: %v", runId, err)
+			}
+			messages = append(messages, message)
+		}
+
+		if len(page) < transcriptPageSize {
+			return messages, nil
+		}
+		after = messages[len(messages)-1].ID
+	}
+}
+
+// Agent returns a handle to the agent run runId, e.g. a ChildRun of an execution or the run of
+// an AgentConversation.
+func (i *Inferable) Agent(runId string) (*Agent, error) {
+	clusterId, err := i.getClusterId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster id: %w", err)
+	}
+	return &Agent{client: i.client, apiSecret: i.apiSecret, clusterId: clusterId, runId: runId}, nil
+}
+
+// ExportTranscript returns the messages of the agent's run in format, so that transcripts can be
+// fed into evaluation harnesses and fine-tuning pipelines as they are.
+//
+//	agent, err := client.Agent(conversation.RunID)
+//	if err != nil {
+//		return err
+//	}
+//	transcript, err := agent.ExportTranscript(inferable.TranscriptOpenAI)
+func (a *Agent) ExportTranscript(format TranscriptFormat) ([]byte, error) {
+	if format != TranscriptJSONL && format != TranscriptOpenAI {
+		return nil, fmt.Errorf("unknown transcript format %q", format)
+	}
+	messages, err := listRunMessages(a.client, a.clusterId, a.runId)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case TranscriptJSONL:
+		var out bytes.Buffer
+		for _, message := range messages {
+			if err := json.Compact(&out, message.raw); err != nil {
+				return nil, fmt.Errorf("failed to export message %s: %v", message.ID, err)
+			}
+			out.WriteByte('\n')
+		}
+		return out.Bytes(), nil
+	default:
+		exported, err := json.Marshal(openAIMessages(messages))
+		if err != nil {
+			return nil, fmt.Errorf("failed to export transcript of run %s: %v", a.runId, err)
+		}
+		return exported, nil
+	}
+}
+
+// openAIMessage is a message of the OpenAI chat completions API.
+type openAIMessage struct {
+	Role string `json:"role"`
+	// Content is null for assistant messages with only tool calls.
+	Content    *string          `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// openAIMessages converts the messages of a run to OpenAI chat messages. Human and template
+// messages are the user's, supervisor messages the system's, and agent messages the assistant's,
+// with their result as the content once the agent is done. Messages the agent produced without
+// following its schema are left out.
+func openAIMessages(messages []runMessage) []openAIMessage {
+	converted := []openAIMessage{}
+	text := func(role string, content string) openAIMessage {
+		return openAIMessage{Role: role, Content: &content}
+	}
+	for n, message := range messages {
+		switch message.Type {
+		case "human", "template":
+			converted = append(converted, text("user", message.Data.Message))
+		case "supervisor":
+			converted = append(converted, text("system", message.Data.Message))
+		case "agent":
+			assistant := openAIMessage{Role: "assistant"}
+			if message.Data.Message != "" {
+				assistant.Content = &message.Data.Message
+			} else if message.Data.Result != nil {
+				result := compactJSON(message.Data.Result)
+				assistant.Content = &result
+			}
+			for m, invocation := range message.Data.Invocations {
+				call := openAIToolCall{ID: invocation.ID, Type: "function"}
+				// Older runs did not record the IDs of tool calls
+				if call.ID == "" {
+					call.ID = fmt.Sprintf("call_%d_%d", n, m)
+				}
+				call.Function.Name = invocation.ToolName
+				call.Function.Arguments = compactJSON(invocation.Input)
+				assistant.ToolCalls = append(assistant.ToolCalls, call)
+			}
+			if assistant.Content == nil && len(assistant.ToolCalls) == 0 {
+				continue
+			}
+			converted = append(converted, assistant)
+		case "invocation-result":
+			tool := text("tool", compactJSON(message.Data.Result))
+			tool.ToolCallID = message.Data.ID
+			converted = append(converted, tool)
+		}
+	}
+	return converted
+}
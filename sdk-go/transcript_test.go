@@ -0,0 +1,54 @@
+package inferable
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportTranscript(t *testing.T) {
+	messages := []map[string]interface{}{
+		{"id": "01A", "type": "human", "data": map[string]interface{}{"message": "Where is order A-1?"}},
+		{"id": "01B", "type": "agent", "data": map[string]interface{}{"invocations": []map[string]interface{}{
+			{"id": "call-1", "toolName": "getOrder", "input": map[string]interface{}{"id": "A-1"}},
+		}}},
+		{"id": "01C", "type": "invocation-result", "data": map[string]interface{}{"id": "call-1", "toolName": "getOrder", "resultType": "resolution", "result": map[string]interface{}{"status": "shipped"}}},
+		{"id": "01D", "type": "agent-invalid", "data": map[string]interface{}{"message": "not json"}},
+		{"id": "01E", "type": "agent", "data": map[string]interface{}{"done": true, "result": map[string]interface{}{"status": "shipped"}}},
+	}
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/runs/run-1/messages": func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "0", r.URL.Query().Get("after"))
+			json.NewEncoder(w).Encode(messages)
+		},
+	})
+	agent, err := i.Agent("run-1")
+	require.NoError(t, err)
+
+	jsonl, err := agent.ExportTranscript(TranscriptJSONL)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(jsonl), "\n"), "\n")
+	require.Len(t, lines, len(messages))
+	for n, line := range lines {
+		expected, _ := json.Marshal(messages[n])
+		assert.JSONEq(t, string(expected), line)
+	}
+
+	openai, err := agent.ExportTranscript(TranscriptOpenAI)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"role": "user", "content": "Where is order A-1?"},
+		{"role": "assistant", "content": null, "tool_calls": [
+			{"id": "call-1", "type": "function", "function": {"name": "getOrder", "arguments": "{\"id\":\"A-1\"}"}}
+		]},
+		{"role": "tool", "tool_call_id": "call-1", "content": "{\"status\":\"shipped\"}"},
+		{"role": "assistant", "content": "{\"status\":\"shipped\"}"}
+	]`, string(openai))
+
+	_, err = agent.ExportTranscript("csv")
+	assert.ErrorContains(t, err, `unknown transcript format "csv"`)
+}
@@ -0,0 +1,89 @@
+package inferable
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// clusterTransport returns the transport that sends requests to the cluster, before chaos mode
+// wraps it: InferableOptions.Transport, or the transport of InferableOptions.HTTPClient, routed
+// through InferableOptions.Proxy and verifying certificates with InferableOptions.TLSConfig and
+// CAFile if set. A nil transport is http.DefaultTransport, which routes requests through the
+// proxies of HTTP_PROXY, HTTPS_PROXY and NO_PROXY.
+func clusterTransport(options InferableOptions) (http.RoundTripper, error) {
+	transport := options.Transport
+	if transport == nil && options.HTTPClient != nil {
+		transport = options.HTTPClient.Transport
+	}
+	if options.Proxy == "" && options.TLSConfig == nil && options.CAFile == "" {
+		return transport, nil
+	}
+
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	base, ok := transport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("a proxy or TLS configuration can only be set for an *http.Transport, got %T", transport)
+	}
+	// The caller's transport is cloned, so that the options do not apply to its other requests
+	base = base.Clone()
+
+	if options.Proxy != "" {
+		proxyURL, err := url.Parse(options.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %v", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid proxy URL %s: scheme must be http, https or socks5", options.Proxy)
+		}
+		base.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if options.TLSConfig != nil || options.CAFile != "" {
+		tlsConfig, err := clusterTLSConfig(base.TLSClientConfig, options)
+		if err != nil {
+			return nil, err
+		}
+		base.TLSClientConfig = tlsConfig
+	}
+	return base, nil
+}
+
+// clusterTLSConfig returns InferableOptions.TLSConfig, or else the transport's current
+// configuration, trusting the certificates of InferableOptions.CAFile in addition to its roots.
+func clusterTLSConfig(current *tls.Config, options InferableOptions) (*tls.Config, error) {
+	config := &tls.Config{}
+	if options.TLSConfig != nil {
+		config = options.TLSConfig.Clone()
+	} else if current != nil {
+		config = current.Clone()
+	}
+	if options.CAFile == "" {
+		return config, nil
+	}
+
+	bundle, err := os.ReadFile(options.CAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+	}
+	roots := config.RootCAs
+	if roots == nil {
+		if roots, err = x509.SystemCertPool(); err != nil {
+			roots = x509.NewCertPool()
+		}
+	} else {
+		roots = roots.Clone()
+	}
+	if !roots.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", options.CAFile)
+	}
+	config.RootCAs = roots
+	return config, nil
+}
@@ -0,0 +1,138 @@
+package inferable
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// TypedWorkflow is a workflow whose handlers take a TInput, checked by the compiler instead of
+// when the handler is defined. It is created with CreateTyped, and is otherwise used like the
+// Workflow it embeds.
+type TypedWorkflow[TInput any] struct {
+	*Workflow
+}
+
+// TypedWorkflowVersionBuilder builds a version of a TypedWorkflow.
+type TypedWorkflowVersionBuilder[TInput any] struct {
+	builder *WorkflowVersionBuilder
+}
+
+// CreateTyped creates a workflow taking a TInput, like Workflows.Create. The input schema is
+// reflected from TInput, which must be a struct with an ExecutionId field with json tag
+// "executionId". A config.InputSchema of another type panics.
+//
+//	workflow := inferable.CreateTyped[RefundInput](client.Workflows, inferable.WorkflowConfig{Name: "refunds"})
+//	workflow.Version(1).Define(func(ctx inferable.WorkflowContext, input RefundInput) (interface{}, error) {
+//		...
+//	})
+func CreateTyped[TInput any](workflows *Workflows, config WorkflowConfig) *TypedWorkflow[TInput] {
+	var input TInput
+	inputType := reflect.TypeOf(input)
+	if inputType == nil || inputType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("typed workflow input must be a struct, got %v", inputType))
+	}
+	if config.InputSchema != nil && reflect.TypeOf(config.InputSchema) != inputType {
+		panic(fmt.Sprintf("WorkflowConfig.InputSchema of type %T does not match the workflow input %v", config.InputSchema, inputType))
+	}
+	config.InputSchema = input
+
+	return &TypedWorkflow[TInput]{Workflow: workflows.Create(config)}
+}
+
+// Version sets the version for the workflow, like Workflow.Version.
+func (w *TypedWorkflow[TInput]) Version(version int) *TypedWorkflowVersionBuilder[TInput] {
+	return &TypedWorkflowVersionBuilder[TInput]{builder: w.Workflow.Version(version)}
+}
+
+// Define defines the handler for the workflow version.
+func (b *TypedWorkflowVersionBuilder[TInput]) Define(handler func(ctx WorkflowContext, input TInput) (interface{}, error)) {
+	b.builder.Define(handler)
+}
+
+// Structured is LLMClient.Structured with the result decoded into a T. The schema is reflected
+// from T unless input.Schema is set.
+//
+//	summary, err := inferable.Structured[Summary](ctx.LLM, inferable.StructuredInput{Input: input.Text})
+//	if err != nil {
+//		return nil, err
+//	}
+//	fmt.Println(summary.Topics)
+func Structured[T any](llm LLMClient, input StructuredInput) (T, error) {
+	var result T
+	resultType := reflect.TypeOf((*T)(nil)).Elem()
+	if input.Schema == nil {
+		input.Schema = schemaOf(resultType)
+	}
+
+	value, err := llm.Structured(input)
+	if err != nil {
+		return result, err
+	}
+
+	decoded, err := decodeInto(resultType, value)
+	if err != nil {
+		return result, fmt.Errorf("failed to decode structured result into %v: %v", resultType, err)
+	}
+	return decoded.(T), nil
+}
+
+// React is AgentRunner.React with the run's result decoded into a T. The result schema is
+// reflected from T unless config.Schema is set. While the agent is not done, the zero T is
+// returned with the interrupt.
+//
+//	triage, interrupt, err := inferable.React[Triage](ctx.Agents, inferable.ReactAgentConfig{
+//		Name:  "triage",
+//		Input: ticket.Body,
+//		Tools: []string{"getOrder"},
+//	})
+//	if err != nil || interrupt != nil {
+//		return interrupt, err
+//	}
+func React[T any](agents AgentRunner, config ReactAgentConfig) (T, *Interrupt, error) {
+	var result T
+	resultType := reflect.TypeOf((*T)(nil)).Elem()
+	if config.Schema == nil {
+		config.Schema = schemaOf(resultType)
+	}
+
+	value, interrupt, err := agents.React(config)
+	if err != nil || interrupt != nil {
+		return result, interrupt, err
+	}
+
+	decoded, err := decodeInto(resultType, value)
+	if err != nil {
+		return result, nil, fmt.Errorf("failed to decode result of agent %s into %v: %v", config.Name, resultType, err)
+	}
+	return decoded.(T), nil, nil
+}
+
+// WaitForResultAs is Execution.WaitForResult with the result decoded into a T.
+//
+//	execution, err := client.Workflows.Trigger("refunds", executionId, input)
+//	...
+//	refund, err := inferable.WaitForResultAs[Refund](ctx, execution, time.Minute)
+func WaitForResultAs[T any](ctx context.Context, execution *Execution, timeout time.Duration) (T, error) {
+	var result T
+	value, err := execution.WaitForResult(ctx, timeout)
+	if err != nil {
+		return result, err
+	}
+
+	resultType := reflect.TypeOf((*T)(nil)).Elem()
+	decoded, err := decodeInto(resultType, value)
+	if err != nil {
+		return result, fmt.Errorf("failed to decode result of workflow execution %s into %v: %v", execution.ID(), resultType, err)
+	}
+	return decoded.(T), nil
+}
+
+// schemaOf returns the zero value of a result type to reflect its schema from.
+func schemaOf(resultType reflect.Type) interface{} {
+	if resultType.Kind() == reflect.Ptr {
+		resultType = resultType.Elem()
+	}
+	return reflect.Zero(resultType).Interface()
+}
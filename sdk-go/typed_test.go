@@ -0,0 +1,108 @@
+package inferable
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type typedRefundInput struct {
+	ExecutionID string `json:"executionId"`
+	Amount      int    `json:"amount"`
+}
+
+func TestCreateTyped(t *testing.T) {
+	i, err := New(InferableOptions{KVStore: NewMemoryStore(), Offline: true})
+	require.NoError(t, err)
+
+	workflow := CreateTyped[typedRefundInput](i.Workflows, WorkflowConfig{Name: "refunds"})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input typedRefundInput) (interface{}, error) {
+		return map[string]interface{}{"refunded": input.Amount}, nil
+	})
+	assert.Equal(t, typedRefundInput{}, workflow.inputSchema)
+
+	result, err := workflow.Execute(1, map[string]interface{}{"executionId": "exec-1", "amount": 30}, ContextInput{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"refunded": 30}, result)
+
+	assert.PanicsWithValue(t, "typed workflow input must be a struct, got string", func() {
+		CreateTyped[string](i.Workflows, WorkflowConfig{Name: "strings"})
+	})
+	assert.Panics(t, func() {
+		CreateTyped[typedRefundInput](i.Workflows, WorkflowConfig{Name: "other", InputSchema: WorkflowInput{}})
+	})
+}
+
+type typedStructuredLLM struct {
+	LLMClient
+	schema interface{}
+	result interface{}
+}
+
+func (f *typedStructuredLLM) Structured(input StructuredInput) (interface{}, error) {
+	f.schema = input.Schema
+	return f.result, nil
+}
+
+func TestStructured(t *testing.T) {
+	type receipt struct {
+		Merchant string  `json:"merchant"`
+		Total    float64 `json:"total"`
+	}
+
+	llm := &typedStructuredLLM{result: map[string]interface{}{"merchant": "ACME", "total": 12.5}}
+	result, err := Structured[receipt](llm, StructuredInput{Input: "Receipt from ACME"})
+	require.NoError(t, err)
+	assert.Equal(t, receipt{Merchant: "ACME", Total: 12.5}, result)
+	assert.Equal(t, receipt{}, llm.schema)
+
+	pointer, err := Structured[*receipt](llm, StructuredInput{Input: "Receipt from ACME"})
+	require.NoError(t, err)
+	assert.Equal(t, "ACME", pointer.Merchant)
+	assert.Equal(t, receipt{}, llm.schema)
+
+	llm.result = map[string]interface{}{"merchant": 42}
+	_, err = Structured[receipt](llm, StructuredInput{Input: "Receipt from ACME"})
+	assert.ErrorContains(t, err, "failed to decode structured result into inferable.receipt")
+}
+
+type typedAgents struct {
+	schema    interface{}
+	result    interface{}
+	interrupt *Interrupt
+}
+
+func (f *typedAgents) React(config ReactAgentConfig) (interface{}, *Interrupt, error) {
+	f.schema = config.Schema
+	return f.result, f.interrupt, nil
+}
+
+func TestReact(t *testing.T) {
+	type triage struct {
+		Priority string   `json:"priority"`
+		Labels   []string `json:"labels"`
+	}
+
+	agents := &typedAgents{result: map[string]interface{}{"priority": "high", "labels": []interface{}{"billing"}}}
+	result, interrupt, err := React[triage](agents, ReactAgentConfig{Name: "triage"})
+	require.NoError(t, err)
+	assert.Nil(t, interrupt)
+	assert.Equal(t, triage{Priority: "high", Labels: []string{"billing"}}, result)
+	assert.Equal(t, triage{}, agents.schema)
+
+	// An explicit schema is kept
+	_, _, err = React[map[string]interface{}](agents, ReactAgentConfig{Name: "triage", Schema: triage{}})
+	require.NoError(t, err)
+	assert.Equal(t, triage{}, agents.schema)
+
+	agents.result, agents.interrupt = nil, GeneralInterrupt("Agent triage is not done")
+	result, interrupt, err = React[triage](agents, ReactAgentConfig{Name: "triage"})
+	require.NoError(t, err)
+	assert.Equal(t, agents.interrupt, interrupt)
+	assert.Equal(t, triage{}, result)
+
+	agents.result, agents.interrupt = map[string]interface{}{"priority": 1}, nil
+	_, _, err = React[triage](agents, ReactAgentConfig{Name: "triage"})
+	assert.ErrorContains(t, err, "failed to decode result of agent triage")
+}
@@ -0,0 +1,104 @@
+package inferable
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+)
+
+// SchemaValidationError is returned when a value does not conform to its JSON schema.
+type SchemaValidationError struct {
+	// Errors describes each violation, prefixed with the JSON path of the offending value.
+	Errors []string
+	// Value is the value that failed validation.
+	Value interface{}
+}
+
+// Error implements the error interface.
+func (e *SchemaValidationError) Error() string {
+	return fmt.Sprintf("value does not match schema: %s", strings.Join(e.Errors, "; "))
+}
+
+// validateSchema checks a decoded JSON value against a reflected schema and returns the violations found.
+// It covers the subset of JSON schema produced by the reflector: types, required properties, enums, and nesting.
+func validateSchema(schema *jsonschema.Schema, value interface{}, path string) []string {
+	if schema == nil {
+		return nil
+	}
+
+	if len(schema.Enum) > 0 && !enumContains(schema.Enum, value) {
+		return []string{fmt.Sprintf("%s: expected one of %v, got %v", path, schema.Enum, value)}
+	}
+
+	if schema.Type == "" {
+		return nil
+	}
+
+	if actual := jsonType(value); !typeMatches(schema.Type, value, actual) {
+		return []string{fmt.Sprintf("%s: expected %s, got %s", path, schema.Type, actual)}
+	}
+
+	var violations []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s.%s: is required", path, name))
+			}
+		}
+		if schema.Properties != nil {
+			for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+				if property, ok := v[pair.Key]; ok && property != nil {
+					violations = append(violations, validateSchema(pair.Value, property, path+"."+pair.Key)...)
+				}
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			violations = append(violations, validateSchema(schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return violations
+}
+
+// jsonType returns the JSON schema type name of a decoded JSON value.
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+// typeMatches reports whether a value of the given JSON type satisfies the expected schema type.
+func typeMatches(expected string, value interface{}, actual string) bool {
+	if expected == "integer" {
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	}
+	return expected == actual
+}
+
+// enumContains reports whether value is one of the allowed enum values.
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		if reflect.DeepEqual(allowed, value) || fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
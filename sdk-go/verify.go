@@ -0,0 +1,230 @@
+package inferable
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+
+	"github.com/inferablehq/inferable/sdk-go/internal/client"
+)
+
+// VerifyOptions configures Workflow.Verify.
+type VerifyOptions struct {
+	// Inputs are example inputs the workflow's consumers trigger it with. Each must be accepted by
+	// every version of the workflow.
+	Inputs []interface{}
+	// ToolInputs are example inputs for the workflow's tools, keyed by tool name.
+	ToolInputs map[string][]interface{}
+}
+
+// ContractError is returned by Verify when the local schemas would break existing callers.
+type ContractError struct {
+	Workflow string
+	// Problems describes each incompatibility, prefixed with the tool name and JSON path.
+	Problems []string
+}
+
+// Error implements the error interface.
+func (e *ContractError) Error() string {
+	return fmt.Sprintf("workflow %s has breaking schema changes: %s", e.Workflow, strings.Join(e.Problems, "; "))
+}
+
+// clusterTool is a tool as listed by the cluster.
+type clusterTool struct {
+	Name   string  `json:"name"`
+	Schema *string `json:"schema"`
+}
+
+// Verify checks that the workflow's local version and tool schemas are compatible with the ones
+// already registered in the cluster, and that they accept the example inputs in options. It
+// registers no tools and does not poll, so it can run in CI before a deploy:
+//
+//	err := workflow.Verify(inferable.VerifyOptions{
+//		Inputs: []interface{}{map[string]interface{}{"executionId": "1", "ticket": "..."}},
+//	})
+//	var contractErr *inferable.ContractError
+//	if errors.As(err, &contractErr) {
+//		// Breaking drift, fail the build
+//	}
+//
+// A change is breaking when a property is removed, changes type, loses enum values, or becomes
+// required, or when a version registered in the cluster is no longer defined locally.
+func (w *Workflow) Verify(options VerifyOptions) error {
+	local := map[string]*jsonschema.Schema{}
+	for _, tool := range w.clusterTools() {
+		schema, err := reflectToolSchema(tool)
+		if err != nil {
+			return err
+		}
+		local[tool.Name] = schema
+	}
+
+	problems := []string{}
+
+	// Consumers' expectations are checked locally
+	for name, schema := range local {
+		inputs := options.ToolInputs[strings.TrimPrefix(name, fmt.Sprintf("tool_%s_", w.name))]
+		if strings.HasPrefix(name, "workflows_") {
+			inputs = options.Inputs
+		}
+		for i, input := range inputs {
+			value, err := decodeJSONValue(input)
+			if err != nil {
+				return fmt.Errorf("failed to marshal example input: %v", err)
+			}
+			for _, violation := range validateSchema(schema, value, "$") {
+				problems = append(problems, fmt.Sprintf("%s: example input %d: %s", name, i, violation))
+			}
+		}
+	}
+	for name := range options.ToolInputs {
+		if _, ok := local[fmt.Sprintf("tool_%s_%s", w.name, name)]; !ok {
+			problems = append(problems, fmt.Sprintf("tool_%s_%s: expected by consumers but not defined", w.name, name))
+		}
+	}
+
+	remote, err := w.inferable.listClusterTools()
+	if err != nil {
+		return err
+	}
+
+	for _, tool := range remote {
+		schema, ok := local[tool.Name]
+		if !ok {
+			// Executions of a removed version can no longer be resumed
+			version := strings.TrimPrefix(tool.Name, fmt.Sprintf("workflows_%s_", w.name))
+			if _, err := strconv.Atoi(version); err == nil && version != tool.Name {
+				problems = append(problems, fmt.Sprintf("%s: registered in the cluster but not defined locally", tool.Name))
+			}
+			continue
+		}
+		if tool.Schema == nil || *tool.Schema == "" {
+			continue
+		}
+
+		var before map[string]interface{}
+		if err := json.Unmarshal([]byte(*tool.Schema), &before); err != nil {
+			return fmt.Errorf("failed to parse cluster schema for %s: %v", tool.Name, err)
+		}
+		after, err := decodeJSONValue(schema)
+		if err != nil {
+			return fmt.Errorf("failed to marshal schema for %s: %v", tool.Name, err)
+		}
+
+		afterMap, _ := after.(map[string]interface{})
+		for _, problem := range breakingSchemaChanges(before, afterMap, "$") {
+			problems = append(problems, fmt.Sprintf("%s: %s", tool.Name, problem))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return &ContractError{Workflow: w.name, Problems: problems}
+	}
+
+	return nil
+}
+
+// listClusterTools returns the tools registered in the cluster.
+func (i *Inferable) listClusterTools() ([]clusterTool, error) {
+	clusterId, err := i.getClusterId()
+	if err != nil {
+		return nil, err
+	}
+
+	result, _, err, status := i.client.FetchData(client.FetchDataOptions{
+		Path:       fmt.Sprintf("/clusters/%s/tools", clusterId),
+		Method:     "GET",
+		Idempotent: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster tools: %w", err)
+	}
+	if status != 200 {
+		return nil, fmt.Errorf("failed to list cluster tools: %w", client.UnexpectedStatus(status))
+	}
+
+	var tools []clusterTool
+	if err := json.Unmarshal([]byte(result), &tools); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cluster tools: %v", err)
+	}
+
+	return tools, nil
+}
+
+// breakingSchemaChanges compares JSON schemas and describes the changes that would reject values
+// the previous schema accepted.
+func breakingSchemaChanges(before map[string]interface{}, after map[string]interface{}, path string) []string {
+	if before == nil || after == nil {
+		return nil
+	}
+
+	problems := []string{}
+
+	if beforeType, afterType := before["type"], after["type"]; beforeType != nil && !reflect.DeepEqual(beforeType, afterType) {
+		// Narrowing a number to an integer rejects fractional values, widening is fine
+		if !(beforeType == "integer" && afterType == "number") {
+			return []string{fmt.Sprintf("%s: type changed from %v to %v", path, beforeType, afterType)}
+		}
+	}
+
+	if beforeEnum, ok := before["enum"].([]interface{}); ok {
+		afterEnum, _ := after["enum"].([]interface{})
+		for _, value := range beforeEnum {
+			if len(afterEnum) > 0 && !enumContains(afterEnum, value) {
+				problems = append(problems, fmt.Sprintf("%s: enum value %v removed", path, value))
+			}
+		}
+	}
+
+	beforeProperties, _ := before["properties"].(map[string]interface{})
+	afterProperties, _ := after["properties"].(map[string]interface{})
+	for name, property := range beforeProperties {
+		afterProperty, ok := afterProperties[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s.%s: property removed", path, name))
+			continue
+		}
+		beforeSchema, _ := property.(map[string]interface{})
+		afterSchema, _ := afterProperty.(map[string]interface{})
+		problems = append(problems, breakingSchemaChanges(beforeSchema, afterSchema, path+"."+name)...)
+	}
+
+	required := map[string]bool{}
+	if beforeRequired, ok := before["required"].([]interface{}); ok {
+		for _, name := range beforeRequired {
+			required[fmt.Sprint(name)] = true
+		}
+	}
+	if afterRequired, ok := after["required"].([]interface{}); ok {
+		for _, name := range afterRequired {
+			if !required[fmt.Sprint(name)] {
+				problems = append(problems, fmt.Sprintf("%s.%v: became required", path, name))
+			}
+		}
+	}
+
+	beforeItems, _ := before["items"].(map[string]interface{})
+	afterItems, _ := after["items"].(map[string]interface{})
+	problems = append(problems, breakingSchemaChanges(beforeItems, afterItems, path+"[*]")...)
+
+	return problems
+}
+
+// decodeJSONValue round trips v through JSON into maps, slices, and primitives.
+func decodeJSONValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
@@ -0,0 +1,111 @@
+package inferable
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type orderInputV1 struct {
+	ExecutionId string   `json:"executionId"`
+	OrderId     string   `json:"orderId"`
+	Quantity    int      `json:"quantity"`
+	Notes       string   `json:"notes,omitempty"`
+	Status      string   `json:"status" jsonschema:"enum=open,enum=closed"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+type orderInputV2 struct {
+	ExecutionId string `json:"executionId"`
+	OrderId     int    `json:"orderId"`
+	Quantity    int    `json:"quantity"`
+	Status      string `json:"status" jsonschema:"enum=open"`
+	Tags        []int  `json:"tags,omitempty"`
+	Region      string `json:"region"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+// newVerifyClient creates a client against a cluster that already has the given tools registered.
+func newVerifyClient(t *testing.T, tools map[string]interface{}) *Inferable {
+	t.Helper()
+
+	listed := []map[string]interface{}{}
+	for name, fn := range tools {
+		schema, err := reflectToolSchema(Tool{Name: name, Func: fn})
+		require.NoError(t, err)
+		serialized, err := json.Marshal(schema)
+		require.NoError(t, err)
+		listed = append(listed, map[string]interface{}{"name": name, "schema": string(serialized)})
+	}
+
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/tools": func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewEncoder(w).Encode(listed)
+		},
+	})
+	return i
+}
+
+func TestVerifyCompatible(t *testing.T) {
+	v1 := func(ctx WorkflowContext, input orderInputV1) (interface{}, error) { return nil, nil }
+	wrapped := func(input orderInputV1, ctx ContextInput) (interface{}, error) { return nil, nil }
+
+	i := newVerifyClient(t, map[string]interface{}{
+		"workflows_orders_1": wrapped,
+		"workflows_other_1":  wrapped,
+		"tool_orders_lookup": func(input struct {
+			Id string `json:"id"`
+		}, ctx ContextInput) (string, error) {
+			return "", nil
+		},
+	})
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: orderInputV1{}})
+	workflow.Version(1).Define(v1)
+	workflow.Tools.Register(WorkflowTool{
+		Name: "lookup",
+		Func: func(input struct {
+			Id     string `json:"id"`
+			Detail bool   `json:"detail,omitempty"`
+		}, ctx ContextInput) (string, error) {
+			return "", nil
+		},
+	})
+
+	err := workflow.Verify(VerifyOptions{
+		Inputs:     []interface{}{orderInputV1{ExecutionId: "1", OrderId: "A", Quantity: 2, Status: "open"}},
+		ToolInputs: map[string][]interface{}{"lookup": {map[string]interface{}{"id": "A"}}},
+	})
+	assert.NoError(t, err)
+}
+
+func TestVerifyBreakingChanges(t *testing.T) {
+	i := newVerifyClient(t, map[string]interface{}{
+		"workflows_orders_1": func(input orderInputV1, ctx ContextInput) (interface{}, error) { return nil, nil },
+		"workflows_orders_2": func(input orderInputV1, ctx ContextInput) (interface{}, error) { return nil, nil },
+	})
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: orderInputV2{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input orderInputV2) (interface{}, error) { return nil, nil })
+
+	err := workflow.Verify(VerifyOptions{
+		Inputs:     []interface{}{map[string]interface{}{"executionId": "1", "orderId": "A", "quantity": 1, "status": "open", "region": "eu"}},
+		ToolInputs: map[string][]interface{}{"missing": {map[string]interface{}{}}},
+	})
+
+	var contractErr *ContractError
+	require.ErrorAs(t, err, &contractErr)
+	assert.Equal(t, []string{
+		"tool_orders_missing: expected by consumers but not defined",
+		"workflows_orders_1: $.notes: property removed",
+		"workflows_orders_1: $.orderId: type changed from string to integer",
+		"workflows_orders_1: $.region: became required",
+		"workflows_orders_1: $.status: enum value closed removed",
+		"workflows_orders_1: $.tags[*]: type changed from string to integer",
+		"workflows_orders_1: example input 0: $.orderId: expected integer, got string",
+		"workflows_orders_2: registered in the cluster but not defined locally",
+	}, contractErr.Problems)
+}
@@ -0,0 +1,121 @@
+package inferable
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultVisionToolName is the name of the vision extraction tool when none is configured.
+const DefaultVisionToolName = "extractFromImage"
+
+// visionMimeTypes are the image and document types the structured endpoint accepts as vision input.
+var visionMimeTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// VisionToolConfig configures the built-in vision extraction tool.
+type VisionToolConfig struct {
+	// Name of the tool. Defaults to DefaultVisionToolName.
+	Name string
+	// Description of the tool shown to agents. Defaults to a generic description of the tool.
+	Description string
+	// Schema describes the fields to extract. Defaults to the full text of the image.
+	Schema interface{}
+	// Instructions guide every extraction, e.g. "Amounts are in EUR".
+	Instructions string
+}
+
+// VisionToolInput is the input of the vision extraction tool.
+type VisionToolInput struct {
+	// Image is base64-encoded image or PDF data, optionally as a data URL (data:image/png;base64,...).
+	Image string `json:"image" jsonschema:"description=Base64-encoded PNG/JPEG/GIF/WebP image or PDF document or a data URL of one"`
+	// Instructions are added to the configured instructions for this extraction.
+	Instructions string `json:"instructions,omitempty" jsonschema:"description=Additional instructions for this extraction"`
+}
+
+// visionTextSchema is the default schema of the vision extraction tool.
+type visionTextSchema struct {
+	Text string `json:"text" jsonschema:"description=All text in the image, in reading order"`
+}
+
+// RegisterVisionTool registers a tool that extracts text or structured fields from images using the
+// cluster's vision model, so document-processing workflows don't each reimplement the plumbing.
+// The tool can then be given to agents like any other workflow tool.
+//
+//	workflow.Tools.RegisterVisionTool(VisionToolConfig{
+//		Name: "extractReceipt",
+//		Schema: struct {
+//			Merchant string  `json:"merchant"`
+//			Total    float64 `json:"total"`
+//		}{},
+//	})
+//
+// PDF documents are passed to the cluster's default provider, whose models read their text and
+// images. Custom providers do not accept them.
+func (t *WorkflowTools) RegisterVisionTool(config VisionToolConfig) {
+	if config.Name == "" {
+		config.Name = DefaultVisionToolName
+	}
+	if config.Description == "" {
+		config.Description = "Extracts text and fields from a base64-encoded image or PDF document"
+	}
+	if config.Schema == nil {
+		config.Schema = visionTextSchema{}
+	}
+
+	t.Register(WorkflowTool{
+		Name:        config.Name,
+		Description: config.Description,
+		InputSchema: VisionToolInput{},
+		Func: func(input VisionToolInput, ctx ContextInput) (interface{}, error) {
+			image, err := normalizeVisionImage(input.Image)
+			if err != nil {
+				return nil, err
+			}
+
+			llm, err := t.workflow.newLLM("", ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			instructions := strings.TrimSpace(config.Instructions + "\n" + input.Instructions)
+
+			return llm.Structured(StructuredInput{
+				Input:        image,
+				Instructions: instructions,
+				Schema:       config.Schema,
+			})
+		},
+	})
+}
+
+// normalizeVisionImage strips any data URL prefix and checks that the data is a supported image
+// or PDF document.
+func normalizeVisionImage(image string) (string, error) {
+	image = strings.TrimSpace(image)
+	if strings.HasPrefix(image, "data:") {
+		comma := strings.Index(image, ",")
+		if comma == -1 {
+			return "", fmt.Errorf("invalid data URL")
+		}
+		image = image[comma+1:]
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(image)
+	if err != nil {
+		return "", fmt.Errorf("image must be base64-encoded: %v", err)
+	}
+
+	mimeType := http.DetectContentType(decoded)
+	if !visionMimeTypes[mimeType] {
+		return "", fmt.Errorf("unsupported image type %s, expected one of PNG, JPEG, GIF, WebP or PDF", mimeType)
+	}
+
+	return image, nil
+}
@@ -0,0 +1,46 @@
+package inferable
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeVisionImage(t *testing.T) {
+	png := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+
+	image, err := normalizeVisionImage(png)
+	require.NoError(t, err)
+	assert.Equal(t, png, image)
+
+	image, err = normalizeVisionImage("data:image/png;base64," + png)
+	require.NoError(t, err)
+	assert.Equal(t, png, image)
+
+	_, err = normalizeVisionImage("not base64!")
+	assert.Error(t, err)
+
+	pdf := base64.StdEncoding.EncodeToString([]byte("%PDF-1.7\n1 0 obj\n<< /Type /Catalog >>\nendobj\n"))
+	image, err = normalizeVisionImage("data:application/pdf;base64," + pdf)
+	require.NoError(t, err)
+	assert.Equal(t, pdf, image)
+
+	_, err = normalizeVisionImage(base64.StdEncoding.EncodeToString([]byte("plain text")))
+	assert.Error(t, err)
+}
+
+func TestRegisterVisionTool(t *testing.T) {
+	i, err := New(InferableOptions{APIEndpoint: DefaultAPIEndpoint, APISecret: "test-secret"})
+	require.NoError(t, err)
+
+	workflow := i.Workflows.Create(WorkflowConfig{
+		Name:        "documents",
+		InputSchema: WorkflowInput{},
+	})
+	workflow.Tools.RegisterVisionTool(VisionToolConfig{})
+
+	require.Len(t, workflow.tools, 1)
+	assert.Equal(t, DefaultVisionToolName, workflow.tools[0].Name)
+}
@@ -0,0 +1,234 @@
+package inferable
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSignature is returned by a WebhookVerifier for a delivery that is not signed with the
+// webhook's secret.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+const (
+	// DefaultWebhookMaxBodyBytes bounds the size of a delivery when WebhookOptions.MaxBodyBytes is
+	// unset.
+	DefaultWebhookMaxBodyBytes = 1 << 20
+	// DefaultStripeTolerance is how old a Stripe delivery's signature may be, to reject replays.
+	DefaultStripeTolerance = 5 * time.Minute
+)
+
+// WebhookVerifier verifies the signature of a webhook delivery, returning an error wrapping
+// ErrInvalidSignature if it does not match, and returns the ID of the delivery. Deliveries with
+// the same ID trigger one execution, so that a provider's retries are not run twice.
+type WebhookVerifier func(r *http.Request, body []byte) (string, error)
+
+// WebhookOptions configures Workflows.WebhookHandler.
+type WebhookOptions struct {
+	// Workflow is the name of the workflow triggered by the deliveries.
+	Workflow string
+	// Verify verifies the deliveries, e.g. StripeVerifier, GitHubVerifier or HMACVerifier.
+	Verify WebhookVerifier
+	// Map converts the body of a delivery into the input of the execution, or returns nil to
+	// acknowledge the delivery without triggering the workflow, e.g. for events it does not handle.
+	// Defaults to the body decoded as a JSON object.
+	Map func(body []byte, r *http.Request) (interface{}, error)
+	// TriggerOptions are passed on to Trigger, e.g. a TenantKey.
+	TriggerOptions TriggerOptions
+	// MaxBodyBytes bounds the size of a delivery. Defaults to DefaultWebhookMaxBodyBytes.
+	MaxBodyBytes int64
+}
+
+// WebhookHandler receives webhook deliveries, e.g. from Stripe or GitHub, and triggers the
+// workflow with each verified delivery. The execution ID is derived from the delivery's ID, so a
+// redelivery does not start a second execution. Deliveries with an invalid signature are rejected
+// with 401, and those that cannot be mapped with 400.
+//
+//	http.Handle("/webhooks/stripe", client.Workflows.WebhookHandler(inferable.WebhookOptions{
+//		Workflow: "payments",
+//		Verify:   inferable.StripeVerifier(os.Getenv("STRIPE_WEBHOOK_SECRET")),
+//		Map: func(body []byte, r *http.Request) (interface{}, error) {
+//			var event stripe.Event
+//			if err := json.Unmarshal(body, &event); err != nil || event.Type != "payment_intent.succeeded" {
+//				return nil, err
+//			}
+//			return map[string]interface{}{"paymentIntent": event.Data.Object["id"]}, nil
+//		},
+//	}))
+func (w *Workflows) WebhookHandler(options WebhookOptions) http.Handler {
+	if options.Workflow == "" || options.Verify == nil {
+		panic("webhook handler requires a Workflow and a Verify function")
+	}
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = DefaultWebhookMaxBodyBytes
+	}
+	if options.Map == nil {
+		options.Map = func(body []byte, r *http.Request) (interface{}, error) {
+			input := map[string]interface{}{}
+			if err := json.Unmarshal(body, &input); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal webhook body: %v", err)
+			}
+			return input, nil
+		}
+	}
+
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		fail := func(status int, err error) {
+			rw.WriteHeader(status)
+			json.NewEncoder(rw).Encode(map[string]string{"error": err.Error()})
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, options.MaxBodyBytes))
+		if err != nil {
+			fail(http.StatusRequestEntityTooLarge, fmt.Errorf("failed to read webhook body: %v", err))
+			return
+		}
+
+		deliveryId, err := options.Verify(r, body)
+		if err != nil {
+			fail(http.StatusUnauthorized, err)
+			return
+		}
+
+		input, err := options.Map(body, r)
+		if err != nil {
+			fail(http.StatusBadRequest, err)
+			return
+		}
+		if input == nil {
+			rw.WriteHeader(http.StatusOK)
+			json.NewEncoder(rw).Encode(map[string]interface{}{"ignored": true})
+			return
+		}
+
+		executionId := webhookExecutionID(options.Workflow, deliveryId)
+		if _, err := w.TriggerContext(r.Context(), options.Workflow, executionId, input, options.TriggerOptions); err != nil {
+			var quotaErr *QuotaExceededError
+			if errors.As(err, &quotaErr) {
+				rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(quotaErr.RetryAfter.Seconds()))))
+				fail(http.StatusTooManyRequests, err)
+				return
+			}
+			// Providers retry failed deliveries, which trigger the same execution
+			fail(http.StatusBadGateway, err)
+			return
+		}
+
+		rw.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(rw).Encode(map[string]string{"executionId": executionId})
+	})
+}
+
+// webhookExecutionID derives the ID of the execution triggered by a delivery.
+func webhookExecutionID(workflow string, deliveryId string) string {
+	hash := sha256.Sum256([]byte(workflow + "\n" + deliveryId))
+	return fmt.Sprintf("webhook_%x", hash[:16])
+}
+
+// signatureOf returns the hex encoded HMAC-SHA256 of payload.
+func signatureOf(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// requireSecret panics on an empty secret, as anyone could sign deliveries with it, e.g. when the
+// secret's environment variable is unset.
+func requireSecret(verifier string, secret string) {
+	if secret == "" {
+		panic(verifier + " requires a non-empty secret")
+	}
+}
+
+// signatureMatches compares a hex encoded signature to the expected one in constant time.
+func signatureMatches(expected string, signature string) bool {
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+// StripeVerifier verifies the Stripe-Signature header of Stripe deliveries with the endpoint's
+// signing secret, rejecting signatures older than DefaultStripeTolerance. The delivery ID is the
+// event's ID. It panics if secret is empty.
+func StripeVerifier(secret string) WebhookVerifier {
+	requireSecret("StripeVerifier", secret)
+	return func(r *http.Request, body []byte) (string, error) {
+		timestamp := ""
+		signatures := []string{}
+		for _, part := range strings.Split(r.Header.Get("Stripe-Signature"), ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch key {
+			case "t":
+				timestamp = value
+			case "v1":
+				signatures = append(signatures, value)
+			}
+		}
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || len(signatures) == 0 {
+			return "", fmt.Errorf("%w: malformed Stripe-Signature header", ErrInvalidSignature)
+		}
+		if age := time.Since(time.Unix(seconds, 0)); age > DefaultStripeTolerance {
+			return "", fmt.Errorf("%w: signed %s ago", ErrInvalidSignature, age.Round(time.Second))
+		}
+
+		expected := signatureOf(secret, []byte(timestamp+"."+string(body)))
+		for _, signature := range signatures {
+			if signatureMatches(expected, signature) {
+				var event struct {
+					ID string `json:"id"`
+				}
+				if err := json.Unmarshal(body, &event); err != nil || event.ID == "" {
+					return "", fmt.Errorf("Stripe event has no ID")
+				}
+				return event.ID, nil
+			}
+		}
+		return "", ErrInvalidSignature
+	}
+}
+
+// GitHubVerifier verifies the X-Hub-Signature-256 header of GitHub deliveries with the webhook's
+// secret. The delivery ID is the X-GitHub-Delivery header, which redeliveries keep. It panics if
+// secret is empty.
+func GitHubVerifier(secret string) WebhookVerifier {
+	requireSecret("GitHubVerifier", secret)
+	return func(r *http.Request, body []byte) (string, error) {
+		signature, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256=")
+		if !ok || !signatureMatches(signatureOf(secret, body), signature) {
+			return "", ErrInvalidSignature
+		}
+		delivery := r.Header.Get("X-GitHub-Delivery")
+		if delivery == "" {
+			return "", fmt.Errorf("GitHub delivery has no X-GitHub-Delivery header")
+		}
+		return delivery, nil
+	}
+}
+
+// HMACVerifier verifies deliveries signed with the hex encoded HMAC-SHA256 of their body in
+// header, optionally prefixed with "sha256=", as many providers sign them. The delivery ID is a
+// hash of the body, so that identical deliveries trigger one execution. It panics if secret is
+// empty.
+func HMACVerifier(secret string, header string) WebhookVerifier {
+	requireSecret("HMACVerifier", secret)
+	return func(r *http.Request, body []byte) (string, error) {
+		signature := strings.TrimPrefix(r.Header.Get(header), "sha256=")
+		if signature == "" || !signatureMatches(signatureOf(secret, body), signature) {
+			return "", ErrInvalidSignature
+		}
+		return fmt.Sprintf("%x", sha256.Sum256(body)), nil
+	}
+}
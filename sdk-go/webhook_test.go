@@ -0,0 +1,125 @@
+package inferable
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	var mu sync.Mutex
+	triggered := []map[string]interface{}{}
+	i := newTestClient(t, InferableOptions{}, map[string]http.HandlerFunc{
+		"/clusters/test-cluster/workflows/deploys/executions": func(w http.ResponseWriter, r *http.Request) {
+			var input map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&input)
+			mu.Lock()
+			triggered = append(triggered, input)
+			mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		},
+	})
+
+	handler := i.Workflows.WebhookHandler(WebhookOptions{
+		Workflow: "deploys",
+		Verify:   GitHubVerifier("github-secret"),
+		Map: func(body []byte, r *http.Request) (interface{}, error) {
+			if r.Header.Get("X-GitHub-Event") != "push" {
+				return nil, nil
+			}
+			var push struct {
+				Ref string `json:"ref"`
+			}
+			if err := json.Unmarshal(body, &push); err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"ref": push.Ref}, nil
+		},
+	})
+	deliver := func(event string, delivery string, body string, signature string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(body))
+		r.Header.Set("X-GitHub-Event", event)
+		r.Header.Set("X-GitHub-Delivery", delivery)
+		r.Header.Set("X-Hub-Signature-256", "sha256="+signature)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	body := `{"ref":"refs/heads/main"}`
+	signature := signatureOf("github-secret", []byte(body))
+
+	response := deliver("push", "delivery-1", body, signature)
+	require.Equal(t, http.StatusAccepted, response.Code)
+	var accepted map[string]string
+	require.NoError(t, json.Unmarshal(response.Body.Bytes(), &accepted))
+	executionId := webhookExecutionID("deploys", "delivery-1")
+	assert.Equal(t, executionId, accepted["executionId"])
+
+	// A redelivery triggers the same execution, which the cluster deduplicates
+	assert.Equal(t, http.StatusAccepted, deliver("push", "delivery-1", body, signature).Code)
+	// Forged deliveries and unhandled events do not trigger the workflow
+	assert.Equal(t, http.StatusUnauthorized, deliver("push", "delivery-2", body, signatureOf("other-secret", []byte(body))).Code)
+	assert.Equal(t, http.StatusOK, deliver("ping", "delivery-3", body, signature).Code)
+	assert.Equal(t, http.StatusBadRequest, deliver("push", "delivery-4", "not json", signatureOf("github-secret", []byte("not json"))).Code)
+
+	require.Len(t, triggered, 2)
+	assert.Equal(t, map[string]interface{}{"ref": "refs/heads/main", "executionId": executionId}, triggered[0])
+	assert.Equal(t, triggered[0], triggered[1])
+}
+
+func TestStripeVerifier(t *testing.T) {
+	verify := StripeVerifier("whsec_test")
+	body := []byte(`{"id":"evt_1","type":"payment_intent.succeeded"}`)
+	sign := func(at time.Time, secret string) *http.Request {
+		timestamp := fmt.Sprint(at.Unix())
+		r := httptest.NewRequest(http.MethodPost, "/webhooks/stripe", nil)
+		r.Header.Set("Stripe-Signature", fmt.Sprintf("t=%s,v1=%s,v0=ignored", timestamp, signatureOf(secret, []byte(timestamp+"."+string(body)))))
+		return r
+	}
+
+	delivery, err := verify(sign(time.Now(), "whsec_test"), body)
+	require.NoError(t, err)
+	assert.Equal(t, "evt_1", delivery)
+
+	_, err = verify(sign(time.Now(), "whsec_other"), body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	// Replayed deliveries are rejected
+	_, err = verify(sign(time.Now().Add(-time.Hour), "whsec_test"), body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	_, err = verify(httptest.NewRequest(http.MethodPost, "/webhooks/stripe", nil), body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestHMACVerifier(t *testing.T) {
+	verify := HMACVerifier("secret", "X-Signature")
+	body := []byte(`{"order":42}`)
+
+	for _, signature := range []string{signatureOf("secret", body), "sha256=" + strings.ToUpper(signatureOf("secret", body))} {
+		r := httptest.NewRequest(http.MethodPost, "/webhooks/orders", nil)
+		r.Header.Set("X-Signature", signature)
+		delivery, err := verify(r, body)
+		require.NoError(t, err)
+		// Identical deliveries have the same ID
+		assert.Equal(t, fmt.Sprintf("%x", sha256.Sum256(body)), delivery)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/webhooks/orders", nil)
+	_, err := verify(r, body)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestVerifiersRequireSecret(t *testing.T) {
+	assert.PanicsWithValue(t, "StripeVerifier requires a non-empty secret", func() { StripeVerifier("") })
+	assert.PanicsWithValue(t, "GitHubVerifier requires a non-empty secret", func() { GitHubVerifier("") })
+	assert.PanicsWithValue(t, "HMACVerifier requires a non-empty secret", func() { HMACVerifier("", "X-Signature") })
+}
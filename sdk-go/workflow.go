@@ -1,12 +1,18 @@
 package inferable
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"math/rand"
 	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/inferablehq/inferable/sdk-go/internal/client"
+	"github.com/inferablehq/inferable/sdk-go/kv"
 	"github.com/invopop/jsonschema"
 )
 
@@ -19,8 +25,12 @@ type WorkflowInput struct {
 // Logger interface for workflow logging.
 // Implementations of this interface can be used to log workflow events.
 type Logger interface {
+	// Debug logs a verbose tracing message with associated metadata.
+	Debug(message string, meta map[string]interface{})
 	// Info logs an informational message with associated metadata.
 	Info(message string, meta map[string]interface{})
+	// Warn logs a warning message with associated metadata.
+	Warn(message string, meta map[string]interface{})
 	// Error logs an error message with associated metadata.
 	Error(message string, meta map[string]interface{})
 }
@@ -36,6 +46,53 @@ type WorkflowConfig struct {
 	InputSchema interface{}
 	// Logger is used for logging workflow events.
 	Logger Logger
+	// Model overrides InferableOptions.DefaultModel for LLM and agent calls made by this workflow.
+	Model string
+	// ProviderResolver overrides InferableOptions.ProviderResolver for this workflow.
+	ProviderResolver ProviderResolver
+	// ModelPolicy overrides InferableOptions.ModelPolicy for this workflow.
+	ModelPolicy ModelPolicyResolver
+	// SemanticCache overrides InferableOptions.SemanticCache for this workflow's LLM calls.
+	SemanticCache *SemanticCache
+	// LLMFactory overrides InferableOptions.LLMFactory for this workflow.
+	LLMFactory LLMFactory
+	// AgentRunnerFactory overrides InferableOptions.AgentRunnerFactory for this workflow.
+	AgentRunnerFactory AgentRunnerFactory
+	// KVStore overrides InferableOptions.KVStore for this workflow's memoized results.
+	KVStore KVStore
+	// ArtifactThreshold, when set, is the serialized size in bytes above which ctx.Memo results and
+	// the handler's result are stored in client.Artifacts, leaving only a reference in the
+	// execution's payloads. References are resolved transparently when the values are read back
+	// by ctx.Memo, GetExecution, and Execute.
+	ArtifactThreshold int
+	// CompressionThreshold, when set, is the serialized size in bytes above which ctx.Memo results
+	// and ctx.State values are stored gzip-compressed. Compressed values are decompressed
+	// transparently on read, but are opaque in the cluster's execution timeline.
+	CompressionThreshold int
+	// KeyProvider, when set, encrypts the workflow's ctx.Memo results and ctx.State values with the
+	// workflow's key before they are stored, so that cluster-level access to the key-value store
+	// does not expose them. Only ciphertext is shown in the execution timeline.
+	KeyProvider KeyProvider
+	// MemoVersion, when set, is part of the key of every ctx.Memo result, e.g. a build hash or
+	// release tag, so that deploying changed handler code recomputes results cached by earlier
+	// builds instead of reusing them. Executions in flight during a deploy recompute their memoized
+	// steps once.
+	MemoVersion string
+	// Partitions, when set, splits the workflow into this many partitions. Executions triggered
+	// with a TriggerOptions.PartitionKey go to the partition owning the key, and each machine
+	// consumes the partitions given in ListenOptions.Partitions. With one machine per partition,
+	// the handler runs for the same key are processed serially. Serialization covers one run of
+	// the handler: an execution paused by an interrupt or a sleep lets the key's next execution
+	// run, and continues when it resumes.
+	Partitions int
+	// ExposeAsTool registers the workflow, when it listens, as a tool that agents in other
+	// workflows can delegate to with ReactAgentConfig.Workflows. The tool is described by
+	// Description and takes the input schema without the executionId. A call triggers an
+	// execution and waits, interrupted, until it is done, to return its result to the agent.
+	ExposeAsTool bool
+	// LogLimits bounds the depth and size of the metadata recorded by ctx.Log, replacing cycles
+	// and whatever is cut off with markers. Zero fields use the Default* limits.
+	LogLimits LogLimits
 }
 
 // WorkflowContext provides context for workflow execution.
@@ -45,8 +102,13 @@ type WorkflowContext struct {
 	Input interface{}
 	// Approved indicates if the workflow is approved
 	Approved bool
+	// InterruptTimedOut indicates that the workflow resumed because nobody resolved its interrupt
+	// before the deadline, see Interrupt.WithTimeout.
+	InterruptTimedOut bool
+	// ApprovalOption is the option chosen to approve the workflow, see Interrupt.WithOptions.
+	ApprovalOption string
 	// LLM functionality for the workflow
-	LLM *LLM
+	LLM LLMClient
 	// Memo caches results for the workflow. It provides a way to store and retrieve
 	// computation results across workflow executions. The function takes a name to
 	// identify the cached result and a function that computes the result if not cached.
@@ -54,16 +116,174 @@ type WorkflowContext struct {
 	// executing the function. Otherwise, the function is executed and its result is
 	// stored in the cache before being returned.
 	Memo func(name string, fn func() (interface{}, error)) (interface{}, error)
+	// MemoWithOptions is Memo with a lifetime for the cached result. Once it expires, the next
+	// call computes and caches the result again. It requires a store that implements
+	// ExpiringKVStore when an expiry is set.
+	//
+	//	rates, err := ctx.MemoWithOptions("exchange-rates", inferable.MemoOptions{TTL: time.Hour}, fetchRates)
+	MemoWithOptions func(name string, options MemoOptions, fn func() (interface{}, error)) (interface{}, error)
+	// State holds named, mutable values for the execution that persist across resumes, backed by
+	// the same store as Memo. See GetState and SetState.
+	State *State
 	// Log logs information for the workflow. It records a status message and associated
 	// metadata for the current workflow execution. This information can be used for
 	// monitoring, debugging, and auditing workflow executions. The status parameter
 	// indicates the current state or event being logged, and the meta parameter provides
 	// additional context or data related to the status.
 	Log func(status string, meta map[string]interface{}) error
+	// Logger is the workflow's Logger with the workflow name and execution ID added to every
+	// entry. Unlike Log, it does not record anything in the cluster. It discards entries if the
+	// workflow has no Logger.
+	Logger Logger
 	// Agents provides agent functionality for the workflow
-	Agents *Agents
+	Agents AgentRunner
+	// Random is seeded per execution, so a handler that is re-executed after an interrupt draws the
+	// same values as long as it draws them in the same order. Use it instead of math/rand for any
+	// random choice that affects side effects.
+	Random *rand.Rand
+	// NewUUID returns a UUID that is stable for the given name across re-executions of the
+	// execution, e.g. for idempotency keys or records created by the handler.
+	//
+	//	orderId := ctx.NewUUID("order")
+	NewUUID func(name string) string
+	// Now returns the current time from the client's Clock. Use it for deadlines so that tests with
+	// a controllable clock can skip ahead.
+	Now func() time.Time
+	// Sleep pauses the handler for d. The wake-up time is memoized under name, so an execution that
+	// is re-executed mid-sleep (e.g. after a restart) only waits for the remainder. The handler holds
+	// its job while sleeping, so prefer interrupts for waits longer than a few minutes.
+	//
+	//	if err := ctx.Sleep("cooldown", 30*time.Second); err != nil {
+	//		return nil, err
+	//	}
+	Sleep func(name string, d time.Duration) error
+	// Lock acquires a lock shared by all executions using the workflow's store, e.g. to let one
+	// execution per customer run a step at a time. The execution is the holder, so a handler that
+	// is re-executed acquires a lock it still holds again.
+	//
+	//	lock, err := ctx.Lock("customer-"+input.CustomerID, inferable.LockOptions{Wait: time.Minute})
+	//	if err != nil {
+	//		return nil, err
+	//	}
+	//	defer lock.Release()
+	Lock func(name string, options ...LockOptions) (*Lock, error)
+	// CreateResumeToken returns a signed token that an external system, e.g. an e-signature
+	// callback, redeems to resume the execution with a payload, see Inferable.RedeemResumeToken.
+	// The token is stable for the given name across re-executions.
+	//
+	//	token, err := ctx.CreateResumeToken("contractSigned")
+	CreateResumeToken func(name string) (string, error)
+	// ResumePayload decodes the payload of the redeemed resume token for name into target. It
+	// reports false while the token has not been redeemed, in which case the handler returns an
+	// interrupt to wait.
+	//
+	//	var signed SignedEvent
+	//	if ok, err := ctx.ResumePayload("contractSigned", &signed); err != nil || !ok {
+	//		return inferable.GeneralInterrupt("Waiting for signature"), err
+	//	}
+	ResumePayload func(name string, target interface{}) (bool, error)
+	// Attempt is the number of the current attempt of the execution's handler, starting at 1. An
+	// attempt ends when the handler fails, panics, or does not finish, e.g. because the machine
+	// stopped; resuming the execution after an interrupt continues the attempt. Use it for
+	// attempt-aware logic, e.g. to switch to a fallback provider on the third attempt.
+	Attempt int
+	// PreviousFailures are the failures of the earlier attempts, oldest first.
+	PreviousFailures []AttemptFailure
+	// FirstAttemptAt is when the first attempt started, see SinceFirstAttempt.
+	FirstAttemptAt time.Time
+	// Locale is the execution's locale, see InferableOptions.Locale, e.g. "de-CH".
+	Locale string
+	// Message renders the message template of key in the execution's locale, see
+	// InferableOptions.Messages, e.g. for interrupt messages and agent instructions. It returns
+	// key if the template cannot be rendered, so that a missing translation does not fail the
+	// handler.
+	//
+	//	return nil, inferable.ApprovalInterrupt(ctx.Message("refund.approval", input))
+	Message func(key string, data interface{}) string
+	// Publish triggers an execution of every workflow subscribed to topic, see Workflow.Subscribe,
+	// with payload as its input. The payload must marshal to a JSON object. Publishing the same
+	// payload to a topic again from the execution, e.g. after a resume, does not trigger the
+	// subscribers twice.
+	//
+	//	err := ctx.Publish("order.placed", map[string]interface{}{"orderId": input.OrderID})
+	Publish func(topic string, payload interface{}) error
+
+	// withContext recreates the context with its cluster requests bound to requestCtx
+	withContext func(requestCtx context.Context) WorkflowContext
+}
+
+// WithContext returns a copy of the context whose requests, made by Log, Memo, State, Sleep,
+// CreateResumeToken, ResumePayload, Publish, LLM and Agents, are bound to requestCtx. Cancelling
+// requestCtx aborts them, so a hung call cannot hold the handler past a deadline.
+//
+//	requestCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//
+//	customer, err := ctx.WithContext(requestCtx).Memo("customer", fetchCustomer)
+func (ctx WorkflowContext) WithContext(requestCtx context.Context) WorkflowContext {
+	if ctx.withContext == nil {
+		return ctx
+	}
+
+	bound := ctx.withContext(requestCtx)
+	// Random draws continue where the handler left off
+	bound.Random = ctx.Random
+	bound.Attempt = ctx.Attempt
+	bound.PreviousFailures = ctx.PreviousFailures
+	bound.FirstAttemptAt = ctx.FirstAttemptAt
+	// Injected backends are kept, and bound if they support it
+	bound.LLM = ctx.LLM
+	if bound.LLM != nil {
+		bound.LLM = ctx.LLM.WithContext(requestCtx)
+	}
+	bound.Agents = ctx.Agents
+	if agents, ok := ctx.Agents.(interface {
+		WithContext(ctx context.Context) AgentRunner
+	}); ok {
+		bound.Agents = agents.WithContext(requestCtx)
+	}
+	return bound
+}
+
+// Memo scopes, see MemoOptions.Scope.
+const (
+	// MemoScopeExecution caches a result for one execution.
+	MemoScopeExecution = "execution"
+	// MemoScopeWorkflow shares a result between the executions of a workflow, e.g. reference data
+	// that is expensive to fetch. Each execution keeps the value it first read, even after the
+	// shared result expires and is recomputed.
+	MemoScopeWorkflow = "workflow"
+)
+
+// MemoOptions configures ctx.MemoWithOptions.
+type MemoOptions struct {
+	// TTL is how long the result is cached for.
+	TTL time.Duration
+	// ExpiresAt is when the result expires, and takes precedence over TTL.
+	ExpiresAt time.Time
+	// Scope is MemoScopeExecution, the default, or MemoScopeWorkflow to share the result between
+	// the workflow's executions until it expires. Shared results require a TTL or ExpiresAt, and
+	// must not depend on the execution's input.
+	Scope string
+	// Version identifies the code computing the result and is part of the memo key, so bumping it
+	// when the step's logic changes stops results computed by the old code from being reused. It
+	// is combined with WorkflowConfig.MemoVersion.
+	Version string
+	// unversioned ignores WorkflowConfig.MemoVersion, for memos that record facts about the
+	// execution, such as sleep deadlines, rather than computed results.
+	unversioned bool
+}
+
+// AgentRunner runs agents for a workflow and is exposed to handlers as ctx.Agents.
+// *Agents, which creates runs in the cluster, is the default implementation.
+type AgentRunner interface {
+	React(config ReactAgentConfig) (interface{}, *Interrupt, error)
 }
 
+// AgentRunnerFactory creates the AgentRunner for a workflow execution. It receives the default
+// runner for the execution, which it may wrap or replace.
+type AgentRunnerFactory func(executionId string, defaultAgents AgentRunner) AgentRunner
+
 // LLM provides LLM (Large Language Model) functionality for workflows.
 // It enables workflows to interact with language models for text generation and processing.
 type LLM struct {
@@ -71,15 +291,54 @@ type LLM struct {
 	apiSecret   string
 	clusterId   string
 	executionId string
+	model       string
+	provider    *Provider
+	// policy restricts the models and providers of the calls. Nil does not restrict them.
+	policy *ModelPolicy
+	ctx    context.Context
+	// semanticCache reuses results for similar inputs when configured
+	semanticCache *SemanticCache
+	// store records the results of the execution's calls made with the semantic cache, and the
+	// calls counted by the budget. Nil when neither is configured.
+	store KVStore
+	// tokenizer counts prompt sizes for the pre-flight context window check, which warns on
+	// logger when only estimated
+	tokenizer Tokenizer
+	logger    Logger
+	// events publishes retries of the execution's calls
+	events *eventBus
+	// budget counts the spend of the calls of workflowName against the client's TokenBudget, and
+	// approved exempts the calls of an execution approved past it
+	budget       *tokenBudgeter
+	workflowName string
+	approved     bool
 }
 
 // StructuredInput represents input for structured LLM generation.
 // It includes the input text and a schema defining the expected output structure.
 type StructuredInput struct {
-	// Input is the text prompt for the LLM.
+	// Input is the text prompt for the LLM, or base64-encoded image data for vision extraction.
 	Input string `json:"input"`
+	// Instructions optionally guide how the input should be interpreted.
+	Instructions string `json:"instructions,omitempty"`
 	// Schema defines the expected structure of the LLM output.
 	Schema interface{} `json:"schema"`
+	// Generation tunes sampling and length for this call. Nil uses the provider defaults.
+	// It is only supported with the default provider, not with a custom Provider.
+	Generation *GenerationOptions `json:"generation,omitempty"`
+	// Model overrides the workflow and client default model for this call.
+	Model string `json:"-"`
+	// Provider overrides the provider resolved for the execution for this call.
+	Provider *Provider `json:"-"`
+	// Timeout bounds the call. Zero means the call is only bounded by the LLM's context.
+	// When the timeout elapses the call fails with ErrLLMTimeout.
+	Timeout time.Duration `json:"-"`
+	// ValidationAttempts is the maximum number of attempts made when the response does not
+	// match the schema. Each retry includes the validation errors in the prompt so the model
+	// can correct itself. Defaults to DefaultValidationAttempts; set to 1 to disable retries.
+	ValidationAttempts int `json:"-"`
+	// SkipSemanticCache bypasses the configured SemanticCache for this call.
+	SkipSemanticCache bool `json:"-"`
 }
 
 // Structured generates structured output from the LLM based on the provided input.
@@ -98,45 +357,172 @@ type StructuredInput struct {
 //
 //	return result, nil
 func (l *LLM) Structured(input StructuredInput) (interface{}, error) {
+	if err := input.Generation.validate(); err != nil {
+		return nil, err
+	}
+
 	// Convert schema to JSON schema if needed
-	if input.Schema != nil {
-		reflector := jsonschema.Reflector{DoNotReference: true}
-		schema := reflector.Reflect(input.Schema)
+	if input.Schema == nil {
+		return l.structured(input)
+	}
+
+	schema := reflectStructuredSchema(input.Schema)
+	input.Schema = schema
 
-		// Remove the schema version that gives errors with ajv
-		schema.Version = ""
+	// Reuse a result for a semantically similar input if a cache is configured.
+	// Embedding failures only skip the cache, since it is an optimization.
+	var cacheKey string
+	var cacheVector []float64
+	var memoKey string
+	if l.semanticCache != nil && !input.SkipSemanticCache {
+		key, err := semanticCacheKey(schema, resolveModel(input.Model, l.model, DefaultModel), input.Instructions, input.Generation)
+		if err != nil {
+			return nil, err
+		}
+
+		// A replay of the handler gets the result of the call's first run, whether it came from
+		// the cache or the cluster, rather than a similar input's result cached since
+		memoKey = semanticMemoKey(l.executionId, key, input.Input)
+		if result, ok := l.semanticMemo(memoKey); ok {
+			return result, nil
+		}
 
-		input.Schema = schema
+		if vector, err := l.semanticCache.embedder.Embed(input.Input); err == nil {
+			if cached, ok := l.semanticCache.lookup(key, vector); ok {
+				l.memoizeSemantic(memoKey, cached)
+				return cached, nil
+			}
+			cacheKey, cacheVector = key, vector
+		}
 	}
 
+	attempts := input.ValidationAttempts
+	if attempts <= 0 {
+		attempts = DefaultValidationAttempts
+	}
+
+	prompt := input.Input
+	var validationErr *SchemaValidationError
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if validationErr != nil {
+			input.Input = validationFeedbackPrompt(prompt, validationErr)
+			l.events.publish(Event{Type: EventRetry, ExecutionID: l.executionId, Operation: "structured", Attempt: attempt, Err: validationErr})
+		}
+
+		result, err := l.structured(input)
+		if err != nil {
+			return nil, err
+		}
+
+		violations := validateSchema(schema, result, "$")
+		if len(violations) == 0 {
+			if cacheVector != nil {
+				l.semanticCache.store(cacheKey, cacheVector, result)
+			}
+			l.memoizeSemantic(memoKey, result)
+			return result, nil
+		}
+
+		validationErr = &SchemaValidationError{Errors: violations, Value: result}
+	}
+
+	return nil, fmt.Errorf("structured LLM response failed schema validation after %d attempts: %w", attempts, validationErr)
+}
+
+// validationFeedbackPrompt appends the validation errors of a previous attempt to the prompt.
+func validationFeedbackPrompt(prompt string, validationErr *SchemaValidationError) string {
+	feedback := "\n\nYour previous response did not match the required schema:\n"
+	for _, violation := range validationErr.Errors {
+		feedback += "- " + violation + "\n"
+	}
+	feedback += "Respond again with a value that conforms to the schema."
+	return prompt + feedback
+}
+
+// reflectStructuredSchema converts a Go value into the JSON schema expected by the structured endpoint.
+func reflectStructuredSchema(v interface{}) *jsonschema.Schema {
+	reflector := jsonschema.Reflector{DoNotReference: true}
+	schema := reflector.Reflect(v)
+
+	// Remove the schema version that gives errors with ajv
+	schema.Version = ""
+
+	return schema
+}
+
+// structured sends a structured generation request and returns the "data" field of the response.
+// The schema of the input must already be converted to a JSON schema.
+func (l *LLM) structured(input StructuredInput) (interface{}, error) {
 	payload, err := json.Marshal(input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal structured input: %v", err)
 	}
 
+	// Fail fast instead of surfacing an opaque provider error mid-execution
+	if err := checkContextWindow(l.tokenizer, l.logger, resolveModel(input.Model, l.model, DefaultModel), string(payload)); err != nil {
+		return nil, err
+	}
+
+	model := resolveModel(input.Model, l.model, DefaultModel)
+	provider := resolveProvider(input.Provider, l.provider)
+	if err := l.policy.check(model, provider); err != nil {
+		return nil, err
+	}
+	if input.Generation != nil && provider != nil {
+		return nil, fmt.Errorf("generation options are only supported with the default provider")
+	}
+	counted, countedKey := l.budget.counted(l.store, l.executionId, model+"\n"+string(payload))
+	if !counted {
+		if err := l.budget.admit(l.ctx, l.workflowName, estimateTokens(l.tokenizer, model, string(payload)), l.approved); err != nil {
+			return nil, err
+		}
+	}
+
 	headers := map[string]string{
 		"Authorization":           "Bearer " + l.apiSecret,
 		"X-Workflow-Execution-Id": l.executionId,
 		"Content-Type":            "application/json",
-		"X-Provider-Model":        "claude-3-5-sonnet",
+		"X-Provider-Model":        model,
 		"X-Provider-Url":          "",
 		"X-Provider-Key":          "",
 	}
 
+	if provider != nil {
+		headers["X-Provider-Url"] = provider.URL
+		headers["X-Provider-Key"] = provider.Key
+	}
+
+	ctx := l.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if input.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, input.Timeout)
+		defer cancel()
+	}
+
 	options := client.FetchDataOptions{
 		Path:    fmt.Sprintf("/clusters/%s/l1m/structured", l.clusterId),
 		Method:  "POST",
 		Headers: headers,
 		Body:    string(payload),
+		Context: ctx,
 	}
 
 	result, _, err, status := l.client.FetchData(options)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call structured LLM: %v", err)
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return nil, fmt.Errorf("%w: %v", ErrLLMTimeout, err)
+		case context.Canceled:
+			return nil, fmt.Errorf("structured LLM call cancelled: %w", context.Canceled)
+		}
+		return nil, fmt.Errorf("failed to call structured LLM: %w", err)
 	}
 
 	if status != 200 {
-		return nil, fmt.Errorf("failed to call structured LLM, status: %d", status)
+		return nil, fmt.Errorf("failed to call structured LLM: %w", client.UnexpectedStatus(status))
 	}
 
 	var response map[string]interface{}
@@ -144,6 +530,11 @@ func (l *LLM) Structured(input StructuredInput) (interface{}, error) {
 		return nil, fmt.Errorf("failed to unmarshal structured LLM response: %v", err)
 	}
 
+	if l.budget != nil && !counted {
+		data, _ := json.Marshal(response["data"])
+		l.budget.record(l.workflowName, estimateTokens(l.tokenizer, model, string(data)))
+		markCounted(l.store, countedKey)
+	}
 	return response["data"], nil
 }
 
@@ -156,6 +547,24 @@ type Agents struct {
 	workflowName string
 	version      int
 	executionId  string
+	model        string
+	provider     *Provider
+	policy       *ModelPolicy
+	tokenizer    Tokenizer
+	logger       Logger
+	events       *eventBus
+	budget       *tokenBudgeter
+	budgetStore  KVStore
+	approved     bool
+	// ctx bounds the runner's requests. Nil does not bound them.
+	ctx context.Context
+}
+
+// WithContext returns a copy of the runner whose requests are bound to ctx.
+func (a *Agents) WithContext(ctx context.Context) AgentRunner {
+	bound := *a
+	bound.ctx = ctx
+	return &bound
 }
 
 // ReactAgentConfig holds the configuration for a React agent.
@@ -171,6 +580,18 @@ type ReactAgentConfig struct {
 	Schema interface{}
 	// Tools for the agent
 	Tools []string
+	// DeniedTools are withheld from the agent even if listed in Tools
+	DeniedTools []string
+	// Capabilities limits the agent to tools declaring one of them, e.g. ToolCapabilityRead for
+	// a run that must not change state. Tools without a capability are treated as mutating.
+	Capabilities []string
+	// Workflows are workflows exposed as tools, see WorkflowConfig.ExposeAsTool, that the agent
+	// can delegate to in addition to Tools
+	Workflows []string
+	// Model overrides the workflow and client default model for this agent run
+	Model string
+	// Provider overrides the provider resolved for the execution for this agent run
+	Provider *Provider
 }
 
 // Agent represents an AI agent that can interact with users and perform tasks.
@@ -209,11 +630,11 @@ func (a *Agent) SendMessage(message string) error {
 
 	_, _, err, status := a.client.FetchData(options)
 	if err != nil {
-		return fmt.Errorf("failed to send message: %v", err)
+		return fmt.Errorf("failed to send message: %w", err)
 	}
 
 	if status != 201 {
-		return fmt.Errorf("failed to send message, status: %d", status)
+		return fmt.Errorf("failed to send message: %w", client.UnexpectedStatus(status))
 	}
 
 	return nil
@@ -242,47 +663,24 @@ func (a *Agent) SendMessage(message string) error {
 //
 // return result, nil
 func (a *Agents) React(config ReactAgentConfig) (interface{}, *Interrupt, error) {
-	// Convert schema to JSON schema if needed
-	var resultSchema interface{}
-	if config.Schema != nil {
-		reflector := jsonschema.Reflector{DoNotReference: true}
-		schema := reflector.Reflect(config.Schema)
-		resultSchema = schema
-	}
+	resultSchema := reactResultSchema(config.Schema)
 
-	// Create the run
-	payload := map[string]interface{}{
-		"name":         fmt.Sprintf("%s_%s", a.workflowName, config.Name),
-		"systemPrompt": config.Instructions,
-		"resultSchema": resultSchema,
-		"tools":        prefixToolNames(config.Tools, a.workflowName),
-		"onStatusChange": map[string]interface{}{
-			"type":     "workflow",
-			"statuses": []string{"failed", "done"},
-			"workflow": map[string]interface{}{
-				"executionId": a.executionId,
-			},
-		},
-		"tags": map[string]interface{}{
-			"workflow.name":        a.workflowName,
-			"workflow.version":     fmt.Sprintf("%d", a.version),
-			"workflow.executionId": a.executionId,
-		},
-		"initialPrompt": config.Input,
-		"interactive":   true,
+	// Fail fast if the prompt cannot fit the model's context window
+	schemaJSON, err := json.Marshal(resultSchema)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal result schema: %v", err)
+	}
+	model := resolveModel(config.Model, a.model, DefaultModel)
+	if err := checkContextWindow(a.tokenizer, a.logger, model, config.Instructions, config.Input, string(schemaJSON)); err != nil {
+		return nil, nil, err
 	}
 
-	hashable, err := json.Marshal(payload)
+	// Create the run
+	payload, err := a.runPayload(config, resultSchema)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to marshal run payload: %v", err)
+		return nil, nil, err
 	}
 
-	hash := sha256.New()
-	hash.Write(hashable)
-	runId := fmt.Sprintf("%s_%s_%x", a.executionId, config.Name, hash.Sum(nil))
-
-	payload["id"] = runId
-
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to marshal run payload: %v", err)
@@ -293,20 +691,57 @@ func (a *Agents) React(config ReactAgentConfig) (interface{}, *Interrupt, error)
 		"Content-Type":  "application/json",
 	}
 
+	// Without an explicit model the cluster picks its default for agent runs. A policy restricting
+	// the models is checked against, and sends, DefaultModel instead.
+	runModel := resolveModel(config.Model, a.model)
+	if runModel == "" && a.policy != nil && len(a.policy.AllowedModels) > 0 {
+		runModel = DefaultModel
+	}
+	provider := resolveProvider(config.Provider, a.provider)
+	if err := a.policy.check(resolveModel(runModel, DefaultModel), provider); err != nil {
+		return nil, nil, err
+	}
+	// Only the prompt of an agent run is known to the machine. Replays of the handler get the
+	// existing run, so the prompt is counted once.
+	prompt := config.Instructions + "\n" + config.Input + "\n" + string(schemaJSON)
+	counted, countedKey := a.budget.counted(a.budgetStore, a.executionId, string(jsonPayload))
+	if !counted {
+		if err := a.budget.admit(a.ctx, a.workflowName, estimateTokens(a.tokenizer, model, prompt), a.approved); err != nil {
+			return nil, nil, err
+		}
+	}
+	if runModel != "" {
+		headers["X-Provider-Model"] = runModel
+	}
+
+	if provider != nil {
+		if provider.URL != "" {
+			headers["X-Provider-Url"] = provider.URL
+		}
+		headers["X-Provider-Key"] = provider.Key
+	}
+
 	options := client.FetchDataOptions{
 		Path:    fmt.Sprintf("/clusters/%s/runs", a.clusterId),
 		Method:  "POST",
 		Headers: headers,
 		Body:    string(jsonPayload),
+		Context: a.ctx,
 	}
 
 	result, _, err, status := a.client.FetchData(options)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create run: %v", err)
+		if a.ctx != nil && a.ctx.Err() != nil {
+			return nil, nil, fmt.Errorf("failed to create run: %w", a.ctx.Err())
+		}
+		return nil, nil, fmt.Errorf("failed to create run: %w", err)
 	}
 
 	if status != 201 {
-		return nil, nil, fmt.Errorf("failed to create run, status: %d", status)
+		return nil, nil, fmt.Errorf("failed to create run: %w", client.UnexpectedStatus(status))
+	}
+	if !counted {
+		markCounted(a.budgetStore, countedKey)
 	}
 
 	var response struct {
@@ -318,6 +753,8 @@ func (a *Agents) React(config ReactAgentConfig) (interface{}, *Interrupt, error)
 		return nil, nil, fmt.Errorf("failed to unmarshal run response: %v", err)
 	}
 
+	a.events.publish(Event{Type: EventAgentRun, Workflow: a.workflowName, Version: a.version, ExecutionID: a.executionId, Agent: config.Name, Message: response.Status})
+
 	if response.Status == "done" {
 		return response.Result, nil, nil
 	} else if response.Status == "failed" {
@@ -328,17 +765,88 @@ func (a *Agents) React(config ReactAgentConfig) (interface{}, *Interrupt, error)
 	}
 }
 
+// runPayload returns the payload creating the run of a React agent. Its ID is derived from the
+// execution and the configuration, so that a re-executed handler gets the same run.
+func (a *Agents) runPayload(config ReactAgentConfig, resultSchema interface{}) (map[string]interface{}, error) {
+	payload := map[string]interface{}{
+		"name":         fmt.Sprintf("%s_%s", a.workflowName, config.Name),
+		"systemPrompt": config.Instructions,
+		"resultSchema": resultSchema,
+		"tools":        append(prefixToolNames(config.Tools, a.workflowName), workflowToolNames(config.Workflows)...),
+		"onStatusChange": map[string]interface{}{
+			"type":     "workflow",
+			"statuses": []string{"failed", "done"},
+			"workflow": map[string]interface{}{
+				"executionId": a.executionId,
+			},
+		},
+		"tags": map[string]interface{}{
+			"workflow.name":        a.workflowName,
+			"workflow.version":     fmt.Sprintf("%d", a.version),
+			"workflow.executionId": a.executionId,
+		},
+		"initialPrompt": config.Input,
+		"interactive":   true,
+	}
+	if len(config.DeniedTools) > 0 {
+		payload["deniedTools"] = prefixToolNames(config.DeniedTools, a.workflowName)
+	}
+	if len(config.Capabilities) > 0 {
+		payload["toolCapabilities"] = config.Capabilities
+	}
+
+	hashable, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal run payload: %v", err)
+	}
+
+	hash := sha256.New()
+	hash.Write(hashable)
+	runId := fmt.Sprintf("%s_%s_%x", a.executionId, config.Name, hash.Sum(nil))
+
+	payload["id"] = runId
+	return payload, nil
+}
+
+// reactResultSchema converts the result schema of a React agent to a JSON schema, if it is set.
+func reactResultSchema(schema interface{}) interface{} {
+	if schema == nil {
+		return nil
+	}
+	reflector := jsonschema.Reflector{DoNotReference: true}
+	return reflector.Reflect(schema)
+}
+
 // Workflow represents a workflow in the Inferable system.
 // It contains the workflow's configuration, handlers, and tools.
 type Workflow struct {
-	name            string
-	description     string
-	inputSchema     interface{}
-	versionHandlers map[int]interface{}
-	logger          Logger
-	inferable       *Inferable
-	tools           []Tool
-	Tools           *WorkflowTools
+	name               string
+	description        string
+	inputSchema        interface{}
+	versionHandlers    map[int]interface{}
+	logger             Logger
+	model              string
+	providerResolver   ProviderResolver
+	modelPolicy        ModelPolicyResolver
+	semanticCache      *SemanticCache
+	llmFactory         LLMFactory
+	agentRunnerFactory AgentRunnerFactory
+	store              KVStore
+	artifactThreshold  int
+	compressThreshold  int
+	memoVersion        string
+	encryption         *encryption
+	partitions         int
+	ownedPartitions    []int
+	servedTools        []string
+	partitionLocks     partitionLocks
+	inferable          *Inferable
+	tools              []Tool
+	topics             []string
+	onCancel           func(state *State, info CancellationInfo) error
+	exposeAsTool       bool
+	logLimits          LogLimits
+	Tools              *WorkflowTools
 }
 
 // WorkflowTool represents a tool that can be used within a workflow.
@@ -354,6 +862,31 @@ type WorkflowTool struct {
 	Func interface{}
 	// Config provides additional configuration for the tool.
 	Config interface{}
+	// Dedupe makes a redelivered call return the recorded result instead of running the tool
+	// again, see Tool.Dedupe.
+	Dedupe bool
+	// Delivery is DeliveryAtLeastOnce (the default) or DeliveryAtMostOnce, see Tool.Delivery.
+	Delivery string
+	// Coerce converts mistyped input values instead of rejecting the call, see Tool.Coerce.
+	Coerce bool
+	// Examples, Tags and UsageHints describe the tool to the model and to catalog tooling, see
+	// Tool.Examples.
+	Examples   []ToolExample
+	Tags       []string
+	UsageHints string
+	// Capability declares whether the tool only reads state, see Tool.Capability.
+	Capability string
+	// PostProcessors transform the tool's results before they reach the agent, see Tool.PostProcessors.
+	PostProcessors []ResultProcessor
+}
+
+// workflowToolNames returns the names of the tools exposing workflows.
+func workflowToolNames(workflows []string) []string {
+	result := make([]string, len(workflows))
+	for i, workflow := range workflows {
+		result[i] = WorkflowToolName(workflow)
+	}
+	return result
 }
 
 // prefixToolNames prefixes tool names with the workflow name.
@@ -366,6 +899,11 @@ func prefixToolNames(tools []string, workflowName string) []string {
 	return result
 }
 
+// Name returns the name of the workflow.
+func (w *Workflow) Name() string {
+	return w.name
+}
+
 // Version sets the version for the workflow.
 // It returns a WorkflowVersionBuilder that can be used to define the handler for this version.
 func (w *Workflow) Version(version int) *WorkflowVersionBuilder {
@@ -437,140 +975,505 @@ func (b *WorkflowVersionBuilder) Define(handler interface{}) {
 				}
 			}
 
-			// Get clusterId from the workflow
-			clusterId := b.workflow.inferable.clusterID
-
-			// Create a WorkflowContext with proper implementations
-			ctx := WorkflowContext{
-				Input:    input.Interface(),
-				Approved: contextInput.Approved,
-				// Set up Log function
-				//
-				//	ctx.Log("info", map[string]interface{}{
-				//		"message": "Starting workflow",
-				//	})
-				Log: func(status string, meta map[string]interface{}) error {
-					// Log to the workflow logger if available
-					if b.workflow.logger != nil {
-						b.workflow.logger.Info(fmt.Sprintf("Workflow log: %s", status), meta)
-					}
+			// Executions for the same partition key never overlap on this machine
+			if b.workflow.partitions > 0 {
+				if key := partitionKeyOf(input); key != "" {
+					defer b.workflow.partitionLocks.lock(key)()
+				}
+			}
 
-					// Create a workflow log entry in the cluster
-					body, err := json.Marshal(map[string]interface{}{
-						"status": status,
-						"data":   meta,
-					})
-					if err != nil {
-						return err
-					}
+			llm, err := b.workflow.newLLM(executionId, contextInput)
+			if err != nil {
+				return []reflect.Value{
+					reflect.Zero(reflect.TypeOf((*interface{})(nil)).Elem()),
+					reflect.ValueOf(&err).Elem(),
+				}
+			}
 
-					path := fmt.Sprintf("/clusters/%s/workflow-executions/%s/logs", clusterId, executionId)
-					_, _, err, _ = b.workflow.inferable.client.FetchData(client.FetchDataOptions{
-						Path:   path,
-						Method: "POST",
-						Body:   string(body),
-					})
+			ctx := b.newWorkflowContext(context.Background(), input, contextInput, executionId, llm)
 
-					return err
-				},
-				// Set up Memo function for caching results
-				//
-				//	result, err := ctx.Memo("unique-cache-key", func() (interface{}, error) {
-				//		// This expensive operation will only be executed once for the given key
-				//		// Subsequent calls with the same key will return the cached result
-				//		return map[string]interface{}{
-				//			"data": "Expensive computation result",
-				//		}, nil
-				//	})
-				Memo: func(name string, fn func() (interface{}, error)) (interface{}, error) {
-					// Create a key for the memo cache
-					key := fmt.Sprintf("%s_memo_%s", executionId, name)
-
-					// Try to get existing value from cluster KV store
-					path := fmt.Sprintf("/clusters/%s/keys/%s/value", clusterId, key)
-					respBody, _, err, statusCode := b.workflow.inferable.client.FetchData(client.FetchDataOptions{
-						Path:   path,
-						Method: "GET",
-					})
+			// Swap in injected backends, e.g. fakes in tests
+			if factory := b.workflow.resolveLLMFactory(); factory != nil {
+				ctx.LLM = factory(executionId, ctx.LLM)
+			}
+			if factory := b.workflow.resolveAgentRunnerFactory(); factory != nil {
+				ctx.Agents = factory(executionId, ctx.Agents)
+			}
 
-					// If we successfully retrieved a value, deserialize and return it
-					if err == nil && statusCode == 200 && respBody != "" {
-						var kvResponse struct {
-							Value string `json:"value"`
-						}
-
-						if err := json.Unmarshal([]byte(respBody), &kvResponse); err == nil && kvResponse.Value != "" {
-							var result struct {
-								Value interface{} `json:"value"`
-							}
-
-							if err := json.Unmarshal([]byte(kvResponse.Value), &result); err == nil && result.Value != nil {
-								return result.Value, nil
-							}
-						}
-					}
+			// Attempts are counted across runs of the handler, so that it can act on earlier failures
+			clock := b.workflow.inferable.clock
+			startAttempt := func() *attemptTracker {
+				attempts, err := startAttempt(b.workflow.kvStore(nil), executionId, clock.Now())
+				if err != nil {
+					ctx.Logger.Error("Failed to track workflow handler attempts", map[string]interface{}{"error": err.Error()})
+				}
+				attempts.report(&ctx)
+				return attempts
+			}
+			finishAttempt := func(attempts *attemptTracker, failed bool, reason string) {
+				if err := attempts.finish(failed, reason, clock.Now()); err != nil {
+					ctx.Logger.Error("Failed to track workflow handler attempts", map[string]interface{}{"error": err.Error()})
+				}
+			}
+			attempts := startAttempt()
+			defer func() {
+				if r := recover(); r != nil {
+					finishAttempt(attempts, true, fmt.Sprintf("panic: %v", r))
+					panic(r)
+				}
+			}()
 
-					// If no cached value exists or there was an error, execute the function
-					result, err := fn()
+			// Call the original handler, again from the top if chaos mode restarts it
+			handlerValue := reflect.ValueOf(handler)
+			events := b.workflow.inferable.events
+			started := time.Now()
+			events.publish(Event{Type: EventHandlerStarted, Workflow: b.workflow.name, Version: b.version, ExecutionID: executionId})
+			ctx.Logger.Debug("Workflow handler started", map[string]interface{}{"version": b.version})
+			for attempt := 2; ; attempt++ {
+				results, restarted := callWithChaosRestart(handlerValue, ctx, input)
+				if !restarted {
+					results = b.workflow.inferable.budget.interruptOnExceeded(results)
+					resultType, err := handlerResultType(results)
+					reason := ""
 					if err != nil {
-						return nil, err
+						reason = err.Error()
 					}
-
-					// Serialize the result
-					serialized, err := json.Marshal(struct {
-						Value interface{} `json:"value"`
-					}{
-						Value: result,
+					finishAttempt(attempts, resultType == "rejection", reason)
+					events.publish(Event{
+						Type:        EventHandlerFinished,
+						Workflow:    b.workflow.name,
+						Version:     b.version,
+						ExecutionID: executionId,
+						ResultType:  resultType,
+						Message:     interruptMessage(results),
+						Duration:    time.Since(started),
+						Err:         err,
 					})
-					if err != nil {
-						return result, err
-					}
-
-					// Store the result in the cluster KV store
-					body, err := json.Marshal(map[string]interface{}{
-						"value":      string(serialized),
-						"onConflict": "doNothing",
+					ctx.Logger.Debug("Workflow handler finished", map[string]interface{}{
+						"version":    b.version,
+						"resultType": resultType,
+						"duration":   time.Since(started).String(),
 					})
-					if err != nil {
-						return result, err
-					}
+					return b.workflow.offloadResult(redactResult(results))
+				}
+				events.publish(Event{Type: EventRetry, Workflow: b.workflow.name, ExecutionID: executionId, Operation: "handler", Attempt: attempt})
+				finishAttempt(attempts, true, "restarted by chaos mode")
+				attempts = startAttempt()
+				ctx.Random = newExecutionRandom(executionId)
+				ctx.Logger.Warn("Chaos mode restarted workflow handler", map[string]interface{}{
+					"name":    b.workflow.name,
+					"version": b.version,
+				})
+			}
+		},
+	)
 
-					path = fmt.Sprintf("/clusters/%s/keys/%s", clusterId, key)
-					_, _, err, _ = b.workflow.inferable.client.FetchData(client.FetchDataOptions{
-						Path:   path,
-						Method: "PUT",
-						Body:   string(body),
-					})
+	b.workflow.versionHandlers[b.version] = wrapperFunc.Interface()
+}
+
+// newWorkflowContext creates the WorkflowContext of an execution, with its cluster requests bound
+// to requestCtx.
+func (b *WorkflowVersionBuilder) newWorkflowContext(requestCtx context.Context, input reflect.Value, contextInput ContextInput, executionId string, llm *LLM) WorkflowContext {
+	// Get clusterId from the workflow
+	clusterId := b.workflow.inferable.clusterID
+
+	logger := b.workflow.executionLogger(executionId)
+
+	// Create a WorkflowContext with proper implementations
+	// Approving a budget interrupt does not approve the handler's own interrupts
+	approved := contextInput.Approved && contextInput.ApprovalOption != BudgetApprovalOption
+
+	ctx := WorkflowContext{
+		Input:    input.Interface(),
+		Approved: approved,
+		Logger:   logger,
+		// Set up Log function
+		//
+		//	ctx.Log("info", map[string]interface{}{
+		//		"message": "Starting workflow",
+		//	})
+		Log: func(status string, meta map[string]interface{}) error {
+			// Log to the workflow logger if available
+			meta = serializeLogMeta(redactMeta(meta), b.workflow.logLimits)
+			logger.Info(fmt.Sprintf("Workflow log: %s", status), meta)
+			b.workflow.inferable.events.publish(Event{Type: EventLog, Workflow: b.workflow.name, Version: b.version, ExecutionID: executionId, Message: status})
+
+			// Create a workflow log entry in the cluster
+			if b.workflow.inferable.offline {
+				return nil
+			}
+			body, err := json.Marshal(map[string]interface{}{
+				"status": status,
+				"data":   meta,
+			})
+			if err != nil {
+				return err
+			}
+
+			path := fmt.Sprintf("/clusters/%s/workflow-executions/%s/logs", clusterId, executionId)
+			_, _, err, _ = b.workflow.inferable.client.FetchData(client.FetchDataOptions{
+				Path:    path,
+				Method:  "POST",
+				Body:    string(body),
+				Context: requestCtx,
+			})
+
+			return err
+		},
+		// Set up Memo function for caching results
+		//
+		//	result, err := ctx.Memo("unique-cache-key", func() (interface{}, error) {
+		//		// This expensive operation will only be executed once for the given key
+		//		// Subsequent calls with the same key will return the cached result
+		//		return map[string]interface{}{
+		//			"data": "Expensive computation result",
+		//		}, nil
+		//	})
+		MemoWithOptions: func(name string, options MemoOptions, fn func() (interface{}, error)) (interface{}, error) {
+			store := b.workflow.kvStore(requestCtx)
+			workflowVersion := b.workflow.memoVersion
+			if options.unversioned {
+				workflowVersion = ""
+			}
+			versioned := versionedMemoName(name, workflowVersion, options.Version)
+			key := memoKey(executionId, versioned)
+
+			shared := options.Scope == MemoScopeWorkflow
+			if options.Scope != "" && options.Scope != MemoScopeExecution && !shared {
+				return nil, fmt.Errorf("memo %s has an unknown scope %q", name, options.Scope)
+			}
+
+			expiresAt := expiryOf(options.TTL, options.ExpiresAt)
+			expiring, canExpire := store.(ExpiringKVStore)
+			if !expiresAt.IsZero() && !canExpire {
+				return nil, fmt.Errorf("memo %s has an expiry, but the KVStore does not implement ExpiringKVStore", name)
+			}
+			if shared && expiresAt.IsZero() {
+				return nil, fmt.Errorf("memo %s is shared by the workflow's executions and needs a TTL or ExpiresAt", name)
+			}
+
+			artifacts := b.workflow.inferable.Artifacts
+			memo := kv.NewTyped[interface{}](store, kv.Options{CompressionThreshold: b.workflow.compressThreshold})
+			read := func(key string) (interface{}, bool) {
+				value, ok, err := memo.Get(key)
+				if err != nil || !ok || value == nil {
+					return nil, false
+				}
+				if value, err = artifacts.rehydrate(value); err != nil {
+					return nil, false
+				}
+				return value, true
+			}
+			write := func(key string, result interface{}, expiresAt time.Time) error {
+				// Large results are cached by reference
+				stored, err := artifacts.offload(result, b.workflow.artifactThreshold)
+				if err != nil {
+					return fmt.Errorf("failed to store memo %s as an artifact: %v", name, err)
+				}
+				if expiresAt.IsZero() {
+					return memo.Set(key, stored)
+				}
+				serialized, err := kv.Encode(stored)
+				if err == nil {
+					serialized, err = kv.Compress(serialized, b.workflow.compressThreshold)
+				}
+				if err != nil {
+					return err
+				}
+				return expiring.SetIfAbsentUntil(key, serialized, expiresAt)
+			}
 
+			// Return the cached result if there is a usable one
+			if value, ok := read(key); ok {
+				return value, nil
+			}
+
+			// A shared result is also recorded for the execution, so that a re-executed
+			// handler sees the same value after the shared entry expires
+			sharedKey := sharedMemoKey(b.workflow.name, versioned)
+			if shared {
+				if value, ok := read(sharedKey); ok {
+					return value, write(key, value, time.Time{})
+				}
+			}
+
+			// If no cached value exists or there was an error, execute the function
+			b.workflow.inferable.events.publish(Event{Type: EventStep, Workflow: b.workflow.name, Version: b.version, ExecutionID: executionId, Step: name})
+			result, err := fn()
+			if err != nil {
+				return nil, err
+			}
+
+			if shared {
+				if err := write(sharedKey, result, expiresAt); err != nil {
 					return result, err
-				},
-				// Set up LLM for structured generation
-				LLM: &LLM{
-					client:      b.workflow.inferable.client,
-					apiSecret:   b.workflow.inferable.apiSecret,
-					clusterId:   clusterId,
-					executionId: executionId,
-				},
-				// Set up Agents for agent functionality
-				Agents: &Agents{
-					client:       b.workflow.inferable.client,
-					apiSecret:    b.workflow.inferable.apiSecret,
-					clusterId:    clusterId,
-					workflowName: b.workflow.name,
-					version:      b.version,
-					executionId:  executionId,
-				},
+				}
+				// Another execution may have shared its result first
+				if value, ok := read(sharedKey); ok {
+					result = value
+				}
+				expiresAt = time.Time{}
+			}
+			if err := write(key, result, expiresAt); err != nil {
+				return result, err
 			}
 
-			// Call the original handler
-			handlerValue := reflect.ValueOf(handler)
-			results := handlerValue.Call([]reflect.Value{reflect.ValueOf(ctx), input})
+			b.workflow.inferable.chaos.maybeRestart(name)
 
-			return results
+			return result, nil
 		},
-	)
+		State:  newState(b.workflow.kvStore(requestCtx), executionId),
+		Random: newExecutionRandom(executionId),
+		NewUUID: func(name string) string {
+			return newExecutionUUID(executionId, name)
+		},
+		// Set up LLM for structured generation
+		LLM: llm.WithContext(requestCtx),
+		// Set up Agents for agent functionality
+		Agents: &Agents{
+			client:       b.workflow.inferable.client,
+			apiSecret:    b.workflow.inferable.apiSecret,
+			clusterId:    clusterId,
+			workflowName: b.workflow.name,
+			version:      b.version,
+			executionId:  executionId,
+			model:        llm.model,
+			provider:     llm.provider,
+			policy:       llm.policy,
+			tokenizer:    llm.tokenizer,
+			logger:       llm.logger,
+			events:       b.workflow.inferable.events,
+			budget:       llm.budget,
+			budgetStore:  llm.store,
+			approved:     llm.approved,
+			ctx:          requestCtx,
+		},
+	}
 
-	b.workflow.versionHandlers[b.version] = wrapperFunc.Interface()
+	ctx.State.compressionThreshold = b.workflow.compressThreshold
+	ctx.InterruptTimedOut = contextInput.InterruptTimedOut
+	if approved {
+		ctx.ApprovalOption = contextInput.ApprovalOption
+	}
+	ctx.Memo = func(name string, fn func() (interface{}, error)) (interface{}, error) {
+		return ctx.MemoWithOptions(name, MemoOptions{}, fn)
+	}
+
+	clock := b.workflow.inferable.clock
+	ctx.Now = clock.Now
+	ctx.Sleep = func(name string, d time.Duration) error {
+		// Deadlines survive deploys, so sleeps are not restarted by a new MemoVersion
+		memo := func(name string, fn func() (interface{}, error)) (interface{}, error) {
+			return ctx.MemoWithOptions(name, MemoOptions{unversioned: true}, fn)
+		}
+		return durableSleep(clock, memo, name, d)
+	}
+	ctx.Lock = func(name string, options ...LockOptions) (*Lock, error) {
+		return acquireLock(b.workflow.kvStore(requestCtx), clock, name, executionId, mergeLockOptions(options))
+	}
+	ctx.CreateResumeToken = func(name string) (string, error) {
+		// Tokens name the execution rather than a version, so they survive deploys
+		token, err := ctx.MemoWithOptions(resumeTokenMemoName(name), MemoOptions{unversioned: true}, func() (interface{}, error) {
+			return b.workflow.createResumeToken(requestCtx, executionId, name)
+		})
+		if err != nil {
+			return "", err
+		}
+		return token.(string), nil
+	}
+	ctx.ResumePayload = func(name string, target interface{}) (bool, error) {
+		return b.workflow.resumePayload(requestCtx, executionId, name, target)
+	}
+	ctx.Publish = func(topic string, payload interface{}) error {
+		return b.workflow.publish(requestCtx, executionId, topic, payload)
+	}
+
+	ctx.Locale = b.workflow.inferable.resolveLocale(contextInput)
+	ctx.Message = func(key string, data interface{}) string {
+		return b.workflow.inferable.message(ctx.Locale, key, data)
+	}
+
+	ctx.withContext = func(requestCtx context.Context) WorkflowContext {
+		return b.newWorkflowContext(requestCtx, input, contextInput, executionId, llm)
+	}
+
+	return ctx
+}
+
+// offloadResult replaces a handler's successful result with an artifact reference if it is larger
+// than the workflow's ArtifactThreshold.
+func (w *Workflow) offloadResult(results []reflect.Value) []reflect.Value {
+	if w.artifactThreshold <= 0 || !results[1].IsNil() {
+		return results
+	}
+
+	result, err := w.inferable.Artifacts.offload(results[0].Interface(), w.artifactThreshold)
+	if err != nil {
+		err = fmt.Errorf("failed to store result as an artifact: %v", err)
+		return []reflect.Value{
+			reflect.Zero(reflect.TypeOf((*interface{})(nil)).Elem()),
+			reflect.ValueOf(&err).Elem(),
+		}
+	}
+	return []reflect.Value{reflect.ValueOf(&result).Elem(), results[1]}
+}
+
+// callWithChaosRestart calls a workflow handler, reporting whether chaos mode aborted it to
+// simulate a restart. Other panics are propagated.
+func callWithChaosRestart(handler reflect.Value, ctx WorkflowContext, input reflect.Value) (results []reflect.Value, restarted bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(chaosRestart); !ok {
+				panic(r)
+			}
+			restarted = true
+		}
+	}()
+
+	return handler.Call([]reflect.Value{reflect.ValueOf(ctx), input}), false
+}
+
+// Execute runs a version's handler in-process with the given input, without the cluster dispatching it.
+// The input may be a value of the handler's input type, or anything that marshals to it as JSON
+// (e.g. a map). LLM, agent, memo, and log calls are still made against the configured endpoint.
+// It is primarily used to test handlers, see the testkit package.
+func (w *Workflow) Execute(version int, input interface{}, contextInput ContextInput) (interface{}, error) {
+	handler, ok := w.versionHandlers[version]
+	if !ok {
+		return nil, fmt.Errorf("workflow %s has no handler for version %d", w.name, version)
+	}
+
+	if _, err := w.inferable.getClusterId(); err != nil {
+		return nil, err
+	}
+
+	handlerValue := reflect.ValueOf(handler)
+	inputType := handlerValue.Type().In(0)
+
+	inputValue := reflect.ValueOf(input)
+	if !inputValue.IsValid() || inputValue.Type() != inputType {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal input: %v", err)
+		}
+		converted := reflect.New(inputType)
+		if err := json.Unmarshal(data, converted.Interface()); err != nil {
+			return nil, fmt.Errorf("input does not match %s: %v", inputType, err)
+		}
+		inputValue = converted.Elem()
+	}
+
+	results := handlerValue.Call([]reflect.Value{inputValue, reflect.ValueOf(contextInput)})
+
+	err, _ := results[1].Interface().(error)
+	if err != nil {
+		return results[0].Interface(), err
+	}
+	return w.inferable.Artifacts.rehydrate(results[0].Interface())
+}
+
+// newLLM creates the LLM used by an execution of the workflow, applying the workflow's
+// overrides on top of the client defaults.
+func (w *Workflow) newLLM(executionId string, contextInput ContextInput) (*LLM, error) {
+	// Derive the provider (e.g. the tenant's own API key) from the call context
+	resolver := w.providerResolver
+	if resolver == nil {
+		resolver = w.inferable.providerResolver
+	}
+
+	var provider *Provider
+	if resolver != nil {
+		var err error
+		provider, err = resolver(contextInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve provider: %v", err)
+		}
+	}
+
+	// The policy routes calls the resolver does not, and restricts all of them
+	policyResolver := w.modelPolicy
+	if policyResolver == nil {
+		policyResolver = w.inferable.modelPolicy
+	}
+
+	var policy *ModelPolicy
+	if policyResolver != nil {
+		var err error
+		policy, err = policyResolver(contextInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve model policy: %v", err)
+		}
+	}
+	policyModel := ""
+	if policy != nil {
+		policyModel = policy.Model
+		provider = resolveProvider(provider, policy.Provider)
+	}
+
+	semanticCache := w.semanticCache
+	if semanticCache == nil {
+		semanticCache = w.inferable.semanticCache
+	}
+
+	var store KVStore
+	if w.inferable.budget != nil || semanticCache != nil {
+		store = w.kvStore(nil)
+	}
+	approved, err := w.inferable.budget.approvedPastBudget(store, executionId, contextInput)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LLM{
+		client:      w.inferable.client,
+		apiSecret:   w.inferable.apiSecret,
+		clusterId:   w.inferable.clusterID,
+		executionId: executionId,
+		// The workflow model takes precedence over the client default
+		model:         resolveModel(w.model, policyModel, w.inferable.defaultModel),
+		provider:      provider,
+		policy:        policy,
+		semanticCache: semanticCache,
+		store:         store,
+		tokenizer:     w.inferable.tokenizer,
+		logger:        w.executionLogger(executionId),
+		events:        w.inferable.events,
+		budget:        w.inferable.budget,
+		workflowName:  w.name,
+		approved:      approved,
+	}, nil
+}
+
+// kvStore returns the store backing ctx.Memo: the workflow's, else the client's, else the cluster,
+// whose requests are bound to ctx. Values are encrypted if the workflow has a KeyProvider.
+func (w *Workflow) kvStore(ctx context.Context) KVStore {
+	store := w.store
+	if store == nil {
+		store = w.inferable.store()
+	}
+	if cluster, ok := store.(*clusterKVStore); ok {
+		store = &clusterKVStore{inferable: cluster.inferable, ctx: ctx}
+	}
+	if w.encryption != nil {
+		return &encryptedStore{store: store, encryption: w.encryption}
+	}
+	return store
+}
+
+// resolveLLMFactory returns the workflow's LLM factory, falling back to the client's.
+func (w *Workflow) resolveLLMFactory() LLMFactory {
+	if w.llmFactory != nil {
+		return w.llmFactory
+	}
+	return w.inferable.llmFactory
+}
+
+// resolveAgentRunnerFactory returns the workflow's agent runner factory, falling back to the client's.
+func (w *Workflow) resolveAgentRunnerFactory() AgentRunnerFactory {
+	if w.agentRunnerFactory != nil {
+		return w.agentRunnerFactory
+	}
+	return w.inferable.agentRunnerFactory
 }
 
 // WorkflowTools provides tool registration functionality for workflows.
@@ -596,17 +1499,90 @@ func (t *WorkflowTools) Register(tool WorkflowTool) {
 		schema:      tool.InputSchema,
 		Config:      tool.Config,
 		Func:        tool.Func,
+		Dedupe:      tool.Dedupe,
+		Delivery:    tool.Delivery,
+		Coerce:      tool.Coerce,
+		Examples:    tool.Examples,
+		Tags:        tool.Tags,
+		UsageHints:  tool.UsageHints,
+		Capability:  tool.Capability,
+
+		PostProcessors: tool.PostProcessors,
 	})
 }
 
+// ListenOptions configures Workflow.Listen.
+type ListenOptions struct {
+	// DryRun validates the workflow without registering or polling: handler and tool signatures,
+	// schema reflection, tool name collisions, and authentication against the cluster.
+	// Problems are returned as a *DryRunError.
+	DryRun bool
+	// Partitions lists the partitions of a partitioned workflow this machine consumes, see
+	// PartitionsFor. Defaults to all of them.
+	Partitions []int
+	// Tools declares the workflow tools this machine implements, by the names they were
+	// registered with, when machines running the same workflow implement different tools. Only
+	// these are registered and polled for, so that the cluster routes calls to the others to the
+	// machines that implement them. Defaults to all of the workflow's tools.
+	Tools []string
+	// Poll tunes the machine's polling loop: the interval between polls, the calls acknowledged
+	// per poll, and the number of concurrent pollers.
+	Poll PollOptions
+}
+
+// DryRunError is returned by a dry run of Listen that found problems.
+type DryRunError struct {
+	Workflow string
+	Problems []string
+}
+
+// Error implements the error interface.
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("workflow %s failed validation: %s", e.Workflow, strings.Join(e.Problems, "; "))
+}
+
 // Listen starts listening for workflow executions.
 // It registers the workflow and its tools with the Inferable service and begins
 // processing incoming workflow execution requests.
-func (w *Workflow) Listen() error {
+//
+// Pass ListenOptions{DryRun: true} to only validate the workflow, e.g. as a pre-deploy smoke check:
+//
+//	if err := workflow.Listen(inferable.ListenOptions{DryRun: true}); err != nil {
+//		log.Fatal(err)
+//	}
+func (w *Workflow) Listen(options ...ListenOptions) error {
 	if w.inferable == nil {
 		return fmt.Errorf("inferable instance is required")
 	}
 
+	for _, option := range options {
+		if option.Partitions != nil {
+			if w.partitions <= 0 {
+				return fmt.Errorf("workflow %s is not partitioned", w.name)
+			}
+			for _, partition := range option.Partitions {
+				if partition < 0 || partition >= w.partitions {
+					return fmt.Errorf("partition %d is out of range for workflow %s with %d partitions", partition, w.name, w.partitions)
+				}
+			}
+			w.ownedPartitions = option.Partitions
+		}
+		if option.Tools != nil {
+			for _, name := range option.Tools {
+				if !w.hasTool(name) {
+					return fmt.Errorf("workflow %s has no tool %s to serve", w.name, name)
+				}
+			}
+			w.servedTools = option.Tools
+		}
+	}
+
+	for _, option := range options {
+		if option.DryRun {
+			return w.dryRun()
+		}
+	}
+
 	if w.logger != nil {
 		w.logger.Info("Starting workflow listeners", map[string]interface{}{
 			"name":     w.name,
@@ -614,45 +1590,34 @@ func (w *Workflow) Listen() error {
 		})
 	}
 
-	// Register tools for the workflow
-	tools := make([]Tool, 0)
-
-	// Add workflow tools
-	for _, tool := range w.tools {
-		prefixedTool := Tool{
-			Name:        fmt.Sprintf("tool_%s_%s", w.name, tool.Name),
-			Description: tool.Description,
-			schema:      tool.schema,
-			Config:      tool.Config,
-			Func:        tool.Func,
-		}
-		tools = append(tools, prefixedTool)
-	}
-
-	// Add version handlers as tools
-	for version, handler := range w.versionHandlers {
-		tools = append(tools, Tool{
-			Name:        fmt.Sprintf("workflows_%s_%d", w.name, version),
-			Description: w.description,
-			schema:      w.inputSchema,
-			Config:      map[string]interface{}{"private": true},
-			Func:        handler,
-		})
+	if err := w.validateExposure(); err != nil {
+		return err
 	}
 
 	// Register tools with the inferable instance
-	for _, tool := range tools {
+	for _, tool := range w.clusterTools() {
 		err := w.inferable.Tools.Register(tool)
 		if err != nil {
 			return fmt.Errorf("failed to register tool: %v", err)
 		}
 	}
 
+	if err := w.recordSubscriptions(); err != nil {
+		return err
+	}
+
 	// Start listening
-	err := w.inferable.Tools.Listen()
+	pollOptions := make([]PollOptions, 0, len(options))
+	for _, option := range options {
+		pollOptions = append(pollOptions, option.Poll)
+	}
+	err := w.inferable.Tools.Listen(pollOptions...)
 	if err != nil {
 		return fmt.Errorf("failed to start workflow listeners: %v", err)
 	}
+	if w.onCancel != nil {
+		go w.sweepCancellations(w.inferable.Tools.ctx)
+	}
 
 	if w.logger != nil {
 		w.logger.Info("Workflow listeners started", map[string]interface{}{
@@ -663,9 +1628,148 @@ func (w *Workflow) Listen() error {
 	return nil
 }
 
+// dryRun performs the validation of Listen and checks authentication, without registering anything.
+func (w *Workflow) dryRun() error {
+	problems := []string{}
+
+	if len(w.versionHandlers) == 0 {
+		problems = append(problems, "no versions defined")
+	}
+
+	for version, handler := range w.versionHandlers {
+		inputType := reflect.TypeOf(handler).In(0)
+		hasExecutionId := false
+		for i := 0; i < inputType.NumField(); i++ {
+			if inputType.Field(i).Tag.Get("json") == "executionId" {
+				hasExecutionId = true
+				break
+			}
+		}
+		if !hasExecutionId {
+			problems = append(problems, fmt.Sprintf("version %d input must contain an ExecutionId field with json tag \"executionId\"", version))
+		}
+	}
+
+	if err := w.validateExposure(); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	seen := map[string]bool{}
+	for _, tool := range w.clusterTools() {
+		if seen[tool.Name] {
+			problems = append(problems, fmt.Sprintf("tool with name '%s' is defined more than once", tool.Name))
+		}
+		seen[tool.Name] = true
+
+		if _, exists := w.inferable.Tools.Tools[tool.Name]; exists {
+			problems = append(problems, fmt.Sprintf("tool with name '%s' already registered", tool.Name))
+		}
+
+		if _, err := reflectToolSchema(tool); err != nil {
+			problems = append(problems, err.Error())
+		}
+
+		if err := validateDelivery(tool); err != nil {
+			problems = append(problems, err.Error())
+		}
+
+		if err := validateToolCapability(tool); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	// Listing tools requires a valid API secret for the cluster
+	if _, err := w.inferable.listClusterTools(); err != nil {
+		problems = append(problems, fmt.Sprintf("failed to authenticate with the cluster: %v", err))
+	}
+
+	if w.logger != nil {
+		w.logger.Info("Workflow dry run complete", map[string]interface{}{
+			"name":     w.name,
+			"problems": problems,
+		})
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return &DryRunError{Workflow: w.name, Problems: problems}
+	}
+
+	return nil
+}
+
+// clusterTools returns the workflow's tools and version handlers as they are registered in the cluster.
+func (w *Workflow) clusterTools() []Tool {
+	tools := make([]Tool, 0)
+
+	// Add the workflow tools this machine serves
+	for _, tool := range w.tools {
+		if !w.serves(tool.Name) {
+			continue
+		}
+		prefixedTool := tool
+		prefixedTool.Name = fmt.Sprintf("tool_%s_%s", w.name, tool.Name)
+		tools = append(tools, prefixedTool)
+	}
+
+	if w.exposeAsTool && w.validateExposure() == nil {
+		tools = append(tools, w.delegationTool())
+	}
+
+	// Add version handlers as tools, once per consumed partition of a partitioned workflow
+	names := []string{w.name}
+	if w.partitions > 0 {
+		partitions := w.ownedPartitions
+		if partitions == nil {
+			partitions = PartitionsFor(0, 1, w.partitions)
+		}
+		names = []string{}
+		for _, partition := range partitions {
+			names = append(names, PartitionWorkflowName(w.name, partition))
+		}
+	}
+	for _, name := range names {
+		for version, handler := range w.versionHandlers {
+			tools = append(tools, Tool{
+				Name:        fmt.Sprintf("workflows_%s_%d", name, version),
+				Description: w.description,
+				schema:      w.inputSchema,
+				Config:      map[string]interface{}{"private": true},
+				Func:        handler,
+			})
+		}
+	}
+
+	return tools
+}
+
+// hasTool reports whether a tool was registered with the workflow under name.
+func (w *Workflow) hasTool(name string) bool {
+	for _, tool := range w.tools {
+		if tool.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// serves reports whether this machine implements the workflow tool, see ListenOptions.Tools.
+func (w *Workflow) serves(name string) bool {
+	if w.servedTools == nil {
+		return true
+	}
+	for _, served := range w.servedTools {
+		if served == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Unlisten stops listening for workflow executions.
-// It unregisters the workflow from the Inferable service and stops processing
-// incoming workflow execution requests.
+// It unregisters the workflow from the Inferable service, stops accepting
+// incoming workflow execution requests, and waits up to InferableOptions.DrainTimeout
+// for the executions in flight to finish. It returns ErrDrainTimeout if they did not.
 func (w *Workflow) Unlisten() error {
 	if w.logger != nil {
 		w.logger.Info("Stopping workflow listeners", map[string]interface{}{
@@ -673,7 +1777,9 @@ func (w *Workflow) Unlisten() error {
 		})
 	}
 
-	w.inferable.Tools.Unlisten()
+	if err := w.inferable.Tools.Unlisten(); err != nil {
+		return err
+	}
 
 	if w.logger != nil {
 		w.logger.Info("Workflow listeners stopped", map[string]interface{}{
@@ -688,6 +1794,10 @@ func (w *Workflow) Unlisten() error {
 // It allows creating and triggering workflows.
 type Workflows struct {
 	inferable *Inferable
+	// Executions lists and cancels workflow executions along with the work they started.
+	Executions *WorkflowExecutions
+	// created holds the workflows created with this client, for Doctor to inspect.
+	created []*Workflow
 }
 
 // Create creates a new workflow with the provided configuration.
@@ -714,13 +1824,30 @@ func (w *Workflows) Create(config WorkflowConfig) *Workflow {
 	}
 
 	workflow := &Workflow{
-		name:            config.Name,
-		description:     config.Description,
-		inputSchema:     config.InputSchema,
-		versionHandlers: make(map[int]interface{}),
-		logger:          config.Logger,
-		inferable:       w.inferable,
-		tools:           make([]Tool, 0),
+		name:               config.Name,
+		description:        config.Description,
+		inputSchema:        config.InputSchema,
+		versionHandlers:    make(map[int]interface{}),
+		logger:             config.Logger,
+		model:              config.Model,
+		providerResolver:   config.ProviderResolver,
+		modelPolicy:        config.ModelPolicy,
+		semanticCache:      config.SemanticCache,
+		llmFactory:         config.LLMFactory,
+		agentRunnerFactory: config.AgentRunnerFactory,
+		store:              config.KVStore,
+		artifactThreshold:  config.ArtifactThreshold,
+		compressThreshold:  config.CompressionThreshold,
+		memoVersion:        config.MemoVersion,
+		partitions:         config.Partitions,
+		exposeAsTool:       config.ExposeAsTool,
+		logLimits:          config.LogLimits,
+		inferable:          w.inferable,
+		tools:              make([]Tool, 0),
+	}
+
+	if config.KeyProvider != nil {
+		workflow.encryption = newEncryption(config.Name, config.KeyProvider)
 	}
 
 	// Initialize the Tools field
@@ -728,30 +1855,87 @@ func (w *Workflows) Create(config WorkflowConfig) *Workflow {
 		workflow: workflow,
 	}
 
+	w.created = append(w.created, workflow)
+
 	return workflow
 }
 
 // Trigger triggers a workflow execution with the provided input.
 // It sends a request to the Inferable service to start a new execution of the specified workflow.
 // The executionId uniquely identifies this execution instance.
-func (w *Workflows) Trigger(workflowName string, executionId string, input interface{}) error {
+// The input is a map or a struct, such as the workflow's InputSchema, and its executionId field is
+// set to executionId.
+// For a partitioned workflow, pass TriggerOptions with the execution's partition key.
+// The returned Execution waits for the execution's result.
+//
+//	execution, err := client.Workflows.Trigger("orders", executionId, input)
+//	if err != nil {
+//		return err
+//	}
+//	result, err := execution.WaitForResult(ctx, time.Minute)
+func (w *Workflows) Trigger(workflowName string, executionId string, input interface{}, triggerOptions ...TriggerOptions) (*Execution, error) {
+	return w.TriggerContext(context.Background(), workflowName, executionId, input, triggerOptions...)
+}
+
+// TriggerContext is Trigger with the request bound to ctx, so that a caller can bound how long it
+// waits for the cluster.
+func (w *Workflows) TriggerContext(ctx context.Context, workflowName string, executionId string, input interface{}, triggerOptions ...TriggerOptions) (*Execution, error) {
 	clusterId, err := w.inferable.getClusterId()
 	if err != nil {
-		return fmt.Errorf("failed to get cluster id: %v", err)
+		return nil, fmt.Errorf("failed to get cluster id: %w", err)
 	}
 
-	// Extract the input fields
-	inputMap, ok := input.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("input must be a map[string]interface{}")
+	inputMap, err := triggerInput(input)
+	if err != nil {
+		return nil, err
 	}
 
 	// add the executionId to the input
 	inputMap["executionId"] = executionId
 
+	tenantKey := ""
+	for _, option := range triggerOptions {
+		if option.TenantKey != "" {
+			tenantKey = option.TenantKey
+		}
+	}
+	release, err := w.inferable.triggers.allow(workflowName, tenantKey)
+	if err != nil {
+		return nil, err
+	}
+	// Only triggers that reach the cluster count against the quota
+	triggered := false
+	defer func() {
+		if !triggered {
+			release()
+		}
+	}()
+
+	queryParams := map[string]string{}
+	for _, option := range triggerOptions {
+		if option.ParentExecutionID != "" {
+			queryParams["parentExecutionId"] = option.ParentExecutionID
+		}
+		if option.ResumeCallID != "" {
+			queryParams["resumeJobId"] = option.ResumeCallID
+		}
+		if option.PartitionKey == "" {
+			continue
+		}
+		partitions := option.Partitions
+		if partitions <= 0 {
+			partitions = w.partitionsOf(workflowName)
+		}
+		if partitions <= 0 {
+			return nil, fmt.Errorf("partition count of workflow %s is unknown, set TriggerOptions.Partitions", workflowName)
+		}
+		inputMap[PartitionKeyField] = option.PartitionKey
+		workflowName = PartitionWorkflowName(workflowName, PartitionFor(option.PartitionKey, partitions))
+	}
+
 	jsonPayload, err := json.Marshal(inputMap)
 	if err != nil {
-		return fmt.Errorf("failed to marshal input: %v", err)
+		return nil, fmt.Errorf("failed to marshal input: %v", err)
 	}
 
 	headers := map[string]string{
@@ -760,22 +1944,63 @@ func (w *Workflows) Trigger(workflowName string, executionId string, input inter
 	}
 
 	options := client.FetchDataOptions{
-		Path:    fmt.Sprintf("/clusters/%s/workflows/%s/executions", clusterId, workflowName),
-		Method:  "POST",
-		Headers: headers,
-		Body:    string(jsonPayload),
+		Path:        fmt.Sprintf("/clusters/%s/workflows/%s/executions", clusterId, workflowName),
+		Method:      "POST",
+		Headers:     headers,
+		Body:        string(jsonPayload),
+		QueryParams: queryParams,
+		Context:     ctx,
+		// Executions are deduplicated by ID
+		Idempotent: true,
 	}
 
 	_, _, err, status := w.inferable.fetchData(options)
 	if err != nil {
-		return fmt.Errorf("failed to trigger workflow: %v", err)
+		return nil, fmt.Errorf("failed to trigger workflow: %w", err)
 	}
 
 	if status != 201 {
-		return fmt.Errorf("failed to trigger workflow, status: %d", status)
+		return nil, fmt.Errorf("failed to trigger workflow: %w", client.UnexpectedStatus(status))
 	}
 
-	return nil
+	triggered = true
+	return &Execution{workflows: w, workflowName: workflowName, id: executionId}, nil
+}
+
+// triggerInput returns the fields of a Trigger input, either a map or a value that marshals to a
+// JSON object, such as the workflow's InputSchema struct. A map is copied, so that the fields the
+// trigger adds are not written to the caller's map.
+func triggerInput(input interface{}) (map[string]interface{}, error) {
+	if input == nil {
+		return map[string]interface{}{}, nil
+	}
+	if inputMap, ok := input.(map[string]interface{}); ok {
+		copied := make(map[string]interface{}, len(inputMap)+1)
+		for key, value := range inputMap {
+			copied[key] = value
+		}
+		return copied, nil
+	}
+
+	serialized, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %v", err)
+	}
+	var inputMap map[string]interface{}
+	if err := json.Unmarshal(serialized, &inputMap); err != nil || inputMap == nil {
+		return nil, fmt.Errorf("input must be a map or a struct, got %T", input)
+	}
+	return inputMap, nil
+}
+
+// partitionsOf returns the partition count of a workflow created with this client, or 0.
+func (w *Workflows) partitionsOf(workflowName string) int {
+	for _, workflow := range w.created {
+		if workflow.name == workflowName {
+			return workflow.partitions
+		}
+	}
+	return 0
 }
 
 // Helpers provides helper functions for workflows
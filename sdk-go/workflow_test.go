@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Global random source for test randomization
@@ -198,7 +200,7 @@ func TestWorkflow(t *testing.T) {
 	executionId := randomString(10)
 
 	// Trigger the workflow
-	err = inferable.Workflows.Trigger(workflowName, executionId, map[string]interface{}{
+	_, err = inferable.Workflows.Trigger(workflowName, executionId, map[string]interface{}{
 		"someOtherInput": "foo",
 	})
 	if err != nil {
@@ -243,6 +245,48 @@ func TestWorkflow(t *testing.T) {
 	}
 }
 
+func TestListenServedTools(t *testing.T) {
+	i := newTestClient(t, InferableOptions{}, nil)
+
+	workflow := i.Workflows.Create(WorkflowConfig{Name: "orders", InputSchema: WorkflowInput{}})
+	workflow.Version(1).Define(func(ctx WorkflowContext, input WorkflowInput) (interface{}, error) {
+		return nil, nil
+	})
+	for _, name := range []string{"lookup", "refund"} {
+		workflow.Tools.Register(WorkflowTool{
+			Name: name,
+			Func: func(input chargeInput, ctx ContextInput) (interface{}, error) { return nil, nil },
+		})
+	}
+
+	assert.ErrorContains(t, workflow.Listen(ListenOptions{Tools: []string{"ship"}}), "has no tool ship")
+
+	names := func() []string {
+		names := []string{}
+		for _, tool := range workflow.clusterTools() {
+			names = append(names, tool.Name)
+		}
+		return names
+	}
+	assert.ElementsMatch(t, []string{"tool_orders_lookup", "tool_orders_refund", "workflows_orders_1"}, names())
+
+	workflow.servedTools = []string{"lookup"}
+	assert.ElementsMatch(t, []string{"tool_orders_lookup", "workflows_orders_1"}, names())
+
+	workflow.servedTools = []string{}
+	assert.ElementsMatch(t, []string{"workflows_orders_1"}, names())
+}
+
+func TestCallForUnknownTool(t *testing.T) {
+	i, results := newResultRecorder(t, InferableOptions{MachineID: "machine-1"})
+
+	require.NoError(t, i.Tools.handleMessage(callMessage{Id: "job-1", Function: "tool_orders_refund"}))
+
+	require.Len(t, results(), 1)
+	assert.Equal(t, "rejection", results()[0].ResultType)
+	assert.Equal(t, "machine machine-1 does not implement tool tool_orders_refund", results()[0].Result)
+}
+
 // Helper function to get an environment variable or skip the test
 func getEnvOrSkip(t *testing.T, name string) string {
 	t.Helper()